@@ -0,0 +1,143 @@
+//go:build aac
+// +build aac
+
+package waveform
+
+/*
+#cgo LDFLAGS: -lfaad
+#include <stdlib.h>
+#include <neaacdec.h>
+*/
+import "C"
+
+import (
+	"io"
+	"runtime"
+
+	"azul3d.org/engine/audio"
+)
+
+// AAC decoding uses libfaad2 via cgo, so it is only built when the aac build
+// tag is set.  The default build remains free of cgo.
+func init() {
+	mp4Codecs["mp4a"] = newAACDecoder
+}
+
+// aacDecoder is an audio.Decoder which decodes the AAC samples of an MP4
+// audio track to float64 PCM samples.
+type aacDecoder struct {
+	h      C.NeAACDecHandle
+	track  *mp4Track
+	config audio.Config
+
+	// Decoded samples not yet read
+	pending []float64
+}
+
+// newAACDecoder opens an AAC decoder for the input MP4 audio track.
+func newAACDecoder(t *mp4Track) (audio.Decoder, error) {
+	if len(t.config) == 0 {
+		return nil, audio.ErrInvalidData
+	}
+
+	h := C.NeAACDecOpen()
+
+	// Decode directly to double precision samples in [-1, 1]
+	cfg := C.NeAACDecGetCurrentConfiguration(h)
+	cfg.outputFormat = C.FAAD_FMT_DOUBLE
+	C.NeAACDecSetConfiguration(h, cfg)
+
+	// Initialize using the AudioSpecificConfig, which determines the actual
+	// output sample rate and channels, including for HE-AAC streams
+	var sampleRate C.ulong
+	var channels C.uchar
+	config := C.CBytes(t.config)
+	defer C.free(config)
+	if C.NeAACDecInit2(h, (*C.uchar)(config), C.ulong(len(t.config)), &sampleRate, &channels) < 0 {
+		C.NeAACDecClose(h)
+		return nil, audio.ErrInvalidData
+	}
+
+	d := &aacDecoder{
+		h:     h,
+		track: t,
+		config: audio.Config{
+			SampleRate: int(sampleRate),
+			Channels:   int(channels),
+		},
+	}
+	runtime.SetFinalizer(d, (*aacDecoder).close)
+
+	return d, nil
+}
+
+// Config returns the audio configuration of the decoded stream.
+func (d *aacDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read decodes samples into b, returning the number of samples read.  On the
+// final read, audio.EOS is returned along with any remaining samples.
+func (d *aacDecoder) Read(b audio.Slice) (int, error) {
+	var n int
+	for n < b.Len() {
+		// Decode the next frame when all pending samples are read
+		if len(d.pending) == 0 {
+			s, err := d.track.next()
+			if err == io.EOF {
+				d.close()
+				return n, audio.EOS
+			}
+			if err == nil {
+				err = d.decode(s)
+			}
+			if err != nil {
+				d.close()
+				return n, err
+			}
+			continue
+		}
+
+		b.Set(n, d.pending[0])
+		d.pending = d.pending[1:]
+		n++
+	}
+
+	return n, nil
+}
+
+// decode decodes a single AAC frame into the pending samples.
+func (d *aacDecoder) decode(frame []byte) error {
+	if d.h == nil || len(frame) == 0 {
+		return audio.ErrInvalidData
+	}
+
+	buf := C.CBytes(frame)
+	defer C.free(buf)
+
+	var info C.NeAACDecFrameInfo
+	out := C.NeAACDecDecode(d.h, &info, (*C.uchar)(buf), C.ulong(len(frame)))
+	if info.error != 0 {
+		return audio.ErrInvalidData
+	}
+	if out == nil || info.samples == 0 {
+		return nil
+	}
+
+	// Copy out of decoder memory, which is reused by the next frame
+	samples := (*[1 << 28]C.double)(out)[:info.samples:info.samples]
+	d.pending = make([]float64, len(samples))
+	for i, s := range samples {
+		d.pending[i] = float64(s)
+	}
+
+	return nil
+}
+
+// close releases the underlying decoder.  It is safe to call more than once.
+func (d *aacDecoder) close() {
+	if d.h != nil {
+		C.NeAACDecClose(d.h)
+		d.h = nil
+	}
+}
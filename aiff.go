@@ -0,0 +1,295 @@
+package waveform
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"azul3d.org/engine/audio"
+)
+
+// aiffMagics are the magic strings which begin AIFF and AIFF-C streams.
+var aiffMagics = []string{"FORM????AIFF", "FORM????AIFC"}
+
+// aiffMaxHeaderSize is the largest common chunk which is read into memory.
+const aiffMaxHeaderSize = 1 << 16
+
+func init() {
+	// Register AIFF streams with the audio package, so they are detected by the
+	// same format sniffing used for WAV and FLAC
+	for _, magic := range aiffMagics {
+		audio.RegisterFormat(FormatAIFF, magic, newAIFFDecoder)
+	}
+}
+
+// aiffCodec describes how the samples of an AIFF stream are encoded.
+type aiffCodec struct {
+	// Size in bytes of a single sample
+	size int
+
+	// Converts a single encoded sample to a float64 value in [-1, 1]
+	sample func(b []byte) float64
+}
+
+// aiffCodecs is the set of AIFF-C compression types which can be decoded,
+// in addition to uncompressed PCM.  Each returns the codec for samples with
+// the input bit depth, or false if the depth is not valid for the type.
+var aiffCodecs = map[string]func(bits int) (aiffCodec, bool){
+	"NONE": aiffPCMCodec(binary.BigEndian),
+	"twos": aiffPCMCodec(binary.BigEndian),
+	"sowt": aiffPCMCodec(binary.LittleEndian),
+	"fl32": aiffFloatCodec(32),
+	"FL32": aiffFloatCodec(32),
+	"fl64": aiffFloatCodec(64),
+	"FL64": aiffFloatCodec(64),
+	"ulaw": aiffG711Codec(ulawSample),
+	"ULAW": aiffG711Codec(ulawSample),
+	"alaw": aiffG711Codec(alawSample),
+	"ALAW": aiffG711Codec(alawSample),
+}
+
+// aiffPCMCodec returns a function which creates a codec for signed integer
+// PCM samples in the input byte order.  Samples with a bit depth which is not
+// a multiple of 8 are stored left-justified in whole bytes.
+func aiffPCMCodec(order binary.ByteOrder) func(bits int) (aiffCodec, bool) {
+	return func(bits int) (aiffCodec, bool) {
+		if bits < 1 || bits > 32 {
+			return aiffCodec{}, false
+		}
+
+		size := (bits + 7) / 8
+		return aiffCodec{
+			size: size,
+			sample: func(b []byte) float64 {
+				return rawSample(b, uint(size*8), order)
+			},
+		}, true
+	}
+}
+
+// aiffFloatCodec returns a function which creates a codec for big-endian IEEE
+// floating point samples with the input bit depth.
+func aiffFloatCodec(depth int) func(bits int) (aiffCodec, bool) {
+	return func(int) (aiffCodec, bool) {
+		if depth == 32 {
+			return aiffCodec{size: 4, sample: func(b []byte) float64 {
+				return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+			}}, true
+		}
+
+		return aiffCodec{size: 8, sample: func(b []byte) float64 {
+			return math.Float64frombits(binary.BigEndian.Uint64(b))
+		}}, true
+	}
+}
+
+// aiffG711Codec returns a function which creates a codec for 8-bit G.711
+// samples, converted by the input function.
+func aiffG711Codec(fn func(b byte) float64) func(bits int) (aiffCodec, bool) {
+	return func(int) (aiffCodec, bool) {
+		return aiffCodec{size: 1, sample: func(b []byte) float64 {
+			return fn(b[0])
+		}}, true
+	}
+}
+
+// aiffDecoder is an audio.Decoder which decodes the samples of an AIFF or
+// AIFF-C stream.
+type aiffDecoder struct {
+	r      io.Reader
+	config audio.Config
+	codec  aiffCodec
+	bits   int
+
+	// Bytes of sample data remaining
+	remaining int64
+
+	buf []byte
+}
+
+// newAIFFDecoder reads the header of an AIFF or AIFF-C stream, and returns a
+// decoder which is positioned at the beginning of its sample data.
+//
+// The common chunk must precede the sound data chunk, so that the stream can
+// be decoded without buffering.  If an AIFF-C stream uses a compression type
+// which cannot be decoded, ErrFormat is returned.
+func newAIFFDecoder(r interface{}) (audio.Decoder, error) {
+	rr, ok := r.(io.Reader)
+	if !ok {
+		return nil, audio.ErrInvalidData
+	}
+
+	var header [12]byte
+	if _, err := io.ReadFull(rr, header[:]); err != nil {
+		return nil, audio.ErrUnexpectedEOS
+	}
+	if string(header[0:4]) != "FORM" {
+		return nil, audio.ErrInvalidData
+	}
+	compressed := string(header[8:12]) == "AIFC"
+
+	d := &aiffDecoder{r: rr}
+	var haveCommon bool
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(rr, chunk[:]); err != nil {
+			return nil, audio.ErrUnexpectedEOS
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.BigEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "COMM":
+			if size > aiffMaxHeaderSize {
+				return nil, audio.ErrInvalidData
+			}
+
+			b := make([]byte, size+size&1)
+			if _, err := io.ReadFull(rr, b); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+
+			if err := d.readCommon(b, compressed); err != nil {
+				return nil, err
+			}
+			haveCommon = true
+		case "SSND":
+			// Sample data must be described by a common chunk
+			if !haveCommon || size < 8 {
+				return nil, audio.ErrInvalidData
+			}
+
+			// Skip to the first sample frame, aligned by the data offset
+			var ssnd [8]byte
+			if _, err := io.ReadFull(rr, ssnd[:]); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+			offset := int64(binary.BigEndian.Uint32(ssnd[0:4]))
+			if offset > size-8 {
+				return nil, audio.ErrInvalidData
+			}
+			if _, err := io.CopyN(ioutil.Discard, rr, offset); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+
+			d.remaining = size - 8 - offset
+			return d, nil
+		default:
+			// Skip all other chunks, which are padded to an even size
+			if _, err := io.CopyN(ioutil.Discard, rr, size+size&1); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+		}
+	}
+}
+
+// readCommon parses the body of a common chunk into the receiving decoder.
+func (d *aiffDecoder) readCommon(b []byte, compressed bool) error {
+	if len(b) < 18 {
+		return audio.ErrInvalidData
+	}
+
+	channels := int(binary.BigEndian.Uint16(b[0:2]))
+	bits := int(binary.BigEndian.Uint16(b[6:8]))
+	sampleRate := extendedFloat(b[8:18])
+	if channels == 0 || channels > channelsMax || !(sampleRate >= 1 && sampleRate <= sampleRateMax) {
+		return audio.ErrInvalidData
+	}
+
+	// Uncompressed AIFF streams always contain big-endian PCM samples
+	compression := "NONE"
+	if compressed {
+		if len(b) < 22 {
+			return audio.ErrInvalidData
+		}
+		compression = string(b[18:22])
+	}
+
+	newCodec, ok := aiffCodecs[compression]
+	if !ok {
+		return audio.ErrFormat
+	}
+	codec, ok := newCodec(bits)
+	if !ok {
+		return audio.ErrInvalidData
+	}
+
+	d.codec = codec
+	d.bits = bits
+	d.config = audio.Config{
+		SampleRate: int(sampleRate + 0.5),
+		Channels:   channels,
+	}
+
+	return nil
+}
+
+// extendedFloat converts an 80-bit IEEE 754 extended precision floating point
+// number, as used for the sample rate of an AIFF stream, to a float64.
+func extendedFloat(b []byte) float64 {
+	exp := int(binary.BigEndian.Uint16(b[0:2]))
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+
+	sign := 1.0
+	if exp&0x8000 != 0 {
+		sign = -1
+		exp &= 0x7fff
+	}
+	if exp == 0 && mantissa == 0 {
+		return 0
+	}
+
+	// The mantissa contains an explicit integer bit
+	return sign * math.Ldexp(float64(mantissa), exp-16383-63)
+}
+
+// Config returns the audio configuration of the AIFF stream.
+func (d *aiffDecoder) Config() audio.Config {
+	return d.config
+}
+
+// bitDepth returns the bit depth of the AIFF stream, as stored in its common
+// chunk.
+func (d *aiffDecoder) bitDepth() int {
+	return d.bits
+}
+
+// Read decodes samples into b, returning the number of samples read.  When
+// the end of the sample data is reached, audio.EOS is returned along with any
+// remaining samples.
+func (d *aiffDecoder) Read(b audio.Slice) (int, error) {
+	size := d.codec.size
+
+	// Read as many whole samples as fit in b, or remain in the stream
+	count := int64(b.Len())
+	if whole := d.remaining / int64(size); count > whole {
+		count = whole
+	}
+
+	need := int(count) * size
+	if cap(d.buf) < need {
+		d.buf = make([]byte, need)
+	}
+	buf := d.buf[:need]
+
+	read, err := io.ReadFull(d.r, buf)
+	d.remaining -= int64(read)
+
+	n := read / size
+	for i := 0; i < n; i++ {
+		b.Set(i, d.codec.sample(buf[i*size:]))
+	}
+
+	// Stream ended before all sample data was read
+	if err != nil {
+		return n, audio.ErrUnexpectedEOS
+	}
+
+	// No whole samples remain
+	if d.remaining < int64(size) {
+		return n, audio.EOS
+	}
+
+	return n, nil
+}
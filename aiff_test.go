@@ -0,0 +1,237 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestExtendedFloat verifies that extendedFloat converts 80-bit extended
+// precision numbers to float64 values.
+func TestExtendedFloat(t *testing.T) {
+	for _, v := range []uint32{0, 1, 8000, 22050, 44100, 48000, 96000} {
+		if f := extendedFloat(testExtendedFloat(v)); f != float64(v) {
+			t.Fatalf("unexpected extended float: %v != %v", f, v)
+		}
+	}
+}
+
+// TestAIFFDecoder verifies that an aiffDecoder decodes the samples of AIFF and
+// AIFF-C streams, for each supported sample encoding.
+func TestAIFFDecoder(t *testing.T) {
+	f32 := make([]byte, 8)
+	binary.BigEndian.PutUint32(f32[0:], math.Float32bits(0.5))
+	binary.BigEndian.PutUint32(f32[4:], math.Float32bits(-0.25))
+
+	f64 := make([]byte, 16)
+	binary.BigEndian.PutUint64(f64[0:], math.Float64bits(0.5))
+	binary.BigEndian.PutUint64(f64[8:], math.Float64bits(-0.25))
+
+	var tests = []struct {
+		compression string
+		bits        uint16
+		data        []byte
+		samples     []float64
+	}{
+		{"", 8, []byte{0x40, 0xe0}, []float64{0.5, -0.25}},
+		{"", 16, []byte{0x40, 0x00, 0xe0, 0x00}, []float64{0.5, -0.25}},
+		{"", 12, []byte{0x40, 0x00, 0xe0, 0x00}, []float64{0.5, -0.25}},
+		{"", 24, []byte{0x40, 0x00, 0x00, 0xe0, 0x00, 0x00}, []float64{0.5, -0.25}},
+		{"NONE", 32, []byte{0x40, 0, 0, 0, 0xe0, 0, 0, 0}, []float64{0.5, -0.25}},
+		{"sowt", 16, []byte{0x00, 0x40, 0x00, 0xe0}, []float64{0.5, -0.25}},
+		{"fl32", 32, f32, []float64{0.5, -0.25}},
+		{"fl64", 64, f64, []float64{0.5, -0.25}},
+		{"ulaw", 16, []byte{0xff, 0x7f}, []float64{0, 0}},
+		{"alaw", 16, []byte{0xd5, 0x55}, []float64{8.0 / (1 << 15), -8.0 / (1 << 15)}},
+	}
+
+	for i, test := range tests {
+		d, err := newAIFFDecoder(bytes.NewReader(testAIFF(test.compression, 1, test.bits, 8000, test.data)))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if c := d.Config(); c.SampleRate != 8000 || c.Channels != 1 {
+			t.Fatalf("[%02d] unexpected config: %v", i, c)
+		}
+
+		samples := make(audio.Float64, 8)
+		n, err := d.Read(samples)
+		if err != audio.EOS {
+			t.Fatalf("[%02d] unexpected Read error: %v", i, err)
+		}
+		if n != len(test.samples) {
+			t.Fatalf("[%02d] unexpected samples length: %v != %v", i, n, len(test.samples))
+		}
+		for j, s := range test.samples {
+			if samples[j] != s {
+				t.Fatalf("[%02d] unexpected sample at %d: %v != %v", i, j, samples[j], s)
+			}
+		}
+	}
+}
+
+// TestG711Samples verifies that ulawSample and alawSample convert the extremes
+// of each encoding to the expected values.
+func TestG711Samples(t *testing.T) {
+	var tests = []struct {
+		fn func(b byte) float64
+		b  byte
+		v  float64
+	}{
+		{ulawSample, 0x00, -32124.0 / (1 << 15)},
+		{ulawSample, 0x80, 32124.0 / (1 << 15)},
+		{alawSample, 0x2a, -32256.0 / (1 << 15)},
+		{alawSample, 0xaa, 32256.0 / (1 << 15)},
+	}
+
+	for i, test := range tests {
+		if v := test.fn(test.b); v != test.v {
+			t.Fatalf("[%02d] unexpected sample: %v != %v", i, v, test.v)
+		}
+	}
+}
+
+// TestWaveformComputeAIFFOK verifies that the Waveform.Compute method produces
+// the same values from an AIFF stream as from the WAV stream with the same
+// samples.
+func TestWaveformComputeAIFFOK(t *testing.T) {
+	want, err := testComputeValues(bytes.NewReader(wav8File))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(wav8File)
+	f, size, err := readWAVHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Convert unsigned 8-bit WAV samples to signed 8-bit AIFF samples
+	offset := len(wav8File) - r.Len()
+	data := append([]byte(nil), wav8File[offset:offset+int(size)]...)
+	for i := range data {
+		data[i] ^= 0x80
+	}
+
+	got, err := testComputeValues(bytes.NewReader(testAIFF("", f.channels, 8, f.sampleRate, data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("unexpected Compute values length: %v != %v", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected Compute value at index %d: %v != %v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestWaveformComputeAIFFErrors verifies that the Waveform.Compute method
+// returns appropriate errors for invalid or unsupported AIFF streams.
+func TestWaveformComputeAIFFErrors(t *testing.T) {
+	valid := testAIFF("", 1, 16, 8000, []byte{0, 1, 0, 2})
+
+	// Common chunk which is too large to be read into memory
+	large := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint32(large[16:20], 0xfffffffe)
+
+	var tests = []struct {
+		data []byte
+		err  error
+	}{
+		// Unsupported compression type
+		{testAIFF("ima4", 1, 16, 8000, []byte{0, 1}), ErrFormat},
+		// Truncated header
+		{valid[:20], ErrUnexpectedEOS},
+		// Truncated sample data
+		{valid[:len(valid)-2], ErrUnexpectedEOS},
+		// Invalid bit depth
+		{testAIFF("", 1, 33, 8000, []byte{0, 1}), ErrInvalidData},
+		{large, ErrInvalidData},
+		// Implausible number of channels or sample rate
+		{testAIFF("", channelsMax+1, 8, 8000, make([]byte, channelsMax+1)), ErrInvalidData},
+		{testAIFF("", 1, 16, sampleRateMax+1, []byte{0, 1}), ErrInvalidData},
+	}
+
+	for i, test := range tests {
+		if _, err := testComputeValues(bytes.NewReader(test.data)); err != test.err {
+			t.Fatalf("[%02d] unexpected Compute error: %v != %v", i, err, test.err)
+		}
+	}
+}
+
+// testAIFF is a test helper which generates an AIFF stream with the input
+// channels, bit depth, sample rate, and encoded sample data.  If compression
+// is not empty, an AIFF-C stream with the compression type is generated.
+func testAIFF(compression string, channels uint16, bits uint16, sampleRate uint32, data []byte) []byte {
+	// Number of sample frames, which is informational only
+	var frames int
+	name := compression
+	if name == "" {
+		name = "NONE"
+	}
+	if newCodec, ok := aiffCodecs[name]; ok {
+		if codec, ok := newCodec(int(bits)); ok {
+			frames = len(data) / codec.size / int(channels)
+		}
+	}
+
+	comm := binary.BigEndian.AppendUint16(nil, channels)
+	comm = binary.BigEndian.AppendUint32(comm, uint32(frames))
+	comm = binary.BigEndian.AppendUint16(comm, bits)
+	comm = append(comm, testExtendedFloat(sampleRate)...)
+
+	form := "AIFF"
+	var out []byte
+	if compression != "" {
+		form = "AIFC"
+		comm = append(comm, compression...)
+		comm = append(comm, 0, 0)
+		out = append(out, testAIFFChunk("FVER", []byte{0xa2, 0x80, 0x51, 0x40})...)
+	}
+	out = append(out, testAIFFChunk("COMM", comm)...)
+	out = append(out, testAIFFChunk("SSND", append(make([]byte, 8), data...))...)
+
+	header := append([]byte("FORM"), binary.BigEndian.AppendUint32(nil, uint32(4+len(out)))...)
+	header = append(header, form...)
+	return append(header, out...)
+}
+
+// testAIFFChunk is a test helper which generates an AIFF chunk with the input
+// ID and body, padded to an even size.
+func testAIFFChunk(id string, body []byte) []byte {
+	out := append([]byte(id), binary.BigEndian.AppendUint32(nil, uint32(len(body)))...)
+	out = append(out, body...)
+	if len(body)%2 != 0 {
+		out = append(out, 0)
+	}
+
+	return out
+}
+
+// testExtendedFloat is a test helper which encodes an integer as an 80-bit
+// extended precision floating point number.
+func testExtendedFloat(v uint32) []byte {
+	b := make([]byte, 10)
+	if v == 0 {
+		return b
+	}
+
+	// Normalize the mantissa so that its integer bit is set
+	mantissa := uint64(v)
+	exp := 16383 + 63
+	for mantissa&(1<<63) == 0 {
+		mantissa <<= 1
+		exp--
+	}
+
+	binary.BigEndian.PutUint16(b[0:2], uint16(exp))
+	binary.BigEndian.PutUint64(b[2:10], mantissa)
+	return b
+}
@@ -0,0 +1,651 @@
+package waveform
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+
+	"azul3d.org/engine/audio"
+)
+
+// ALAC decoding is implemented entirely in Go, so it is always available.
+func init() {
+	mp4Codecs["alac"] = newALACDecoder
+}
+
+// ALAC element types, which begin each element of a frame.
+const (
+	alacElementSCE = 0 // single channel element
+	alacElementCPE = 1 // channel pair element
+	alacElementCCE = 2 // coupling channel element, which is unsupported
+	alacElementLFE = 3 // low frequency effects element, decoded as an SCE
+	alacElementDSE = 4 // data stream element, which is ignored
+	alacElementPCE = 5 // program config element, which is unsupported
+	alacElementFIL = 6 // fill element, which is ignored
+	alacElementEND = 7 // end of the frame
+)
+
+// Constants used by the adaptive Golomb decoder, as defined by the ALAC
+// reference implementation.
+const (
+	alacQBShift   = 9
+	alacQB        = 1 << alacQBShift
+	alacMMulShift = 2
+	alacMDenShift = alacQBShift - alacMMulShift - 1
+	alacMOff      = 1 << (alacMDenShift - 2)
+	alacBitOff    = 24
+
+	// Prefixes of this many bits escape a value, which follows in full
+	alacMaxPrefix = 9
+
+	// Values which exceed the clamp value reset the mean
+	alacMeanClamp = 0xffff
+
+	// Length of the ALACSpecificConfig
+	alacConfigLen = 24
+)
+
+// alacConfig is the ALACSpecificConfig stored in the sample entry of an ALAC
+// track, which applies to all frames.
+type alacConfig struct {
+	frameLength uint32
+	bitDepth    uint8
+	pb          uint8
+	mb          uint8
+	kb          uint8
+	channels    uint8
+	maxRun      uint16
+	sampleRate  uint32
+}
+
+// parseALACConfig parses an ALACSpecificConfig.
+func parseALACConfig(b []byte) (alacConfig, error) {
+	if len(b) < alacConfigLen {
+		return alacConfig{}, audio.ErrInvalidData
+	}
+
+	c := alacConfig{
+		frameLength: binary.BigEndian.Uint32(b[0:4]),
+		bitDepth:    b[5],
+		pb:          b[6],
+		mb:          b[7],
+		kb:          b[8],
+		channels:    b[9],
+		maxRun:      binary.BigEndian.Uint16(b[10:12]),
+		sampleRate:  binary.BigEndian.Uint32(b[20:24]),
+	}
+
+	switch c.bitDepth {
+	case 16, 20, 24, 32:
+	default:
+		return alacConfig{}, audio.ErrInvalidData
+	}
+	if c.frameLength == 0 || c.channels == 0 || c.kb > 32 {
+		return alacConfig{}, audio.ErrInvalidData
+	}
+
+	return c, nil
+}
+
+// alacDecoder is an audio.Decoder which decodes the ALAC samples of an MP4
+// audio track to float64 PCM samples.
+type alacDecoder struct {
+	track  *mp4Track
+	alac   alacConfig
+	config audio.Config
+
+	// Decoded samples not yet read
+	pending []float64
+
+	// Buffers reused by each frame: the decoded samples of each channel of an
+	// element, the low order bits of each channel which are stored separately,
+	// and all interleaved samples of the frame
+	predictor [2][]int32
+	mix       [2][]int32
+	shift     [2][]int32
+	frame     []float64
+}
+
+// newALACDecoder opens an ALAC decoder for the input MP4 audio track.
+func newALACDecoder(t *mp4Track) (audio.Decoder, error) {
+	c, err := parseALACConfig(t.config)
+	if err != nil {
+		return nil, err
+	}
+
+	// The sample rate of the sample entry cannot exceed 65535Hz, so prefer the
+	// sample rate of the ALAC configuration
+	sampleRate := int(c.sampleRate)
+	if sampleRate == 0 {
+		sampleRate = t.sampleRate
+	}
+	if sampleRate <= 0 || sampleRate > sampleRateMax {
+		return nil, audio.ErrInvalidData
+	}
+
+	d := &alacDecoder{
+		track: t,
+		alac:  c,
+		config: audio.Config{
+			SampleRate: sampleRate,
+			Channels:   int(c.channels),
+		},
+	}
+	for i := range d.predictor {
+		d.predictor[i] = make([]int32, c.frameLength)
+		d.mix[i] = make([]int32, c.frameLength)
+		d.shift[i] = make([]int32, c.frameLength)
+	}
+
+	return d, nil
+}
+
+// Config returns the audio configuration of the decoded stream.
+func (d *alacDecoder) Config() audio.Config {
+	return d.config
+}
+
+// bitDepth returns the bit depth of the ALAC stream.
+func (d *alacDecoder) bitDepth() int {
+	return int(d.alac.bitDepth)
+}
+
+// Read decodes samples into b, returning the number of samples read.  On the
+// final read, audio.EOS is returned along with any remaining samples.
+func (d *alacDecoder) Read(b audio.Slice) (int, error) {
+	var n int
+	for n < b.Len() {
+		// Decode the next frame when all pending samples are read
+		if len(d.pending) == 0 {
+			s, err := d.track.next()
+			if err == io.EOF {
+				return n, audio.EOS
+			}
+			if err != nil {
+				return n, err
+			}
+
+			if err := d.decode(s); err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		b.Set(n, d.pending[0])
+		d.pending = d.pending[1:]
+		n++
+	}
+
+	return n, nil
+}
+
+// alacElement describes a single decoded element of an ALAC frame, whose
+// samples are stored in the mix and shift buffers of an alacDecoder.
+type alacElement struct {
+	samples int
+	pair    bool
+
+	// Parameters used to reconstruct the left and right channels of a pair
+	mixBits uint32
+	mixRes  int32
+
+	// Number of low order bits stored separately from each sample
+	shift uint
+}
+
+// decode decodes a single ALAC frame into the pending samples.  Each element
+// of the frame decodes one or two channels, in order, until all channels of
+// the stream are decoded.
+func (d *alacDecoder) decode(frame []byte) error {
+	br := &alacBitReader{b: frame}
+	channels := int(d.alac.channels)
+
+	// All elements of a frame have the same number of samples
+	samples := -1
+	var channel int
+	for {
+		tag := br.read(3)
+		if br.overrun() {
+			return audio.ErrInvalidData
+		}
+
+		switch tag {
+		case alacElementSCE, alacElementLFE, alacElementCPE:
+			pair := tag == alacElementCPE
+			if channel >= channels || (pair && channel+1 >= channels) {
+				return audio.ErrInvalidData
+			}
+
+			e, err := d.decodeElement(br, pair)
+			if err != nil {
+				return err
+			}
+			if samples < 0 {
+				samples = e.samples
+				d.frame = append(d.frame[:0], make([]float64, samples*channels)...)
+			}
+			if e.samples != samples {
+				return audio.ErrInvalidData
+			}
+
+			d.output(channel, e)
+			channel++
+			if pair {
+				channel++
+			}
+		case alacElementDSE:
+			// Element instance tag, followed by the data, which is ignored
+			br.read(4)
+			align := br.read(1) == 1
+			count := br.read(8)
+			if count == 255 {
+				count += br.read(8)
+			}
+			if align {
+				br.align()
+			}
+			br.skip(uint(count) * 8)
+		case alacElementFIL:
+			count := br.read(4)
+			if count == 15 {
+				count += br.read(8) - 1
+			}
+			br.skip(uint(count) * 8)
+		case alacElementEND:
+			if channel != channels {
+				return audio.ErrInvalidData
+			}
+
+			d.pending = d.frame
+			return nil
+		default:
+			// Coupling channel and program config elements are not used by
+			// any known encoder
+			return audio.ErrInvalidData
+		}
+
+		if br.overrun() {
+			return audio.ErrInvalidData
+		}
+	}
+}
+
+// decodeElement decodes a single channel element, or a channel pair element
+// if pair is true, into the mix and shift buffers.
+func (d *alacDecoder) decodeElement(br *alacBitReader, pair bool) (alacElement, error) {
+	c := d.alac
+	e := alacElement{pair: pair}
+	channels := 1
+	if pair {
+		channels = 2
+	}
+
+	// Element instance tag and unused header bits, followed by the flags of
+	// the element
+	br.read(4)
+	if br.read(12) != 0 {
+		return e, audio.ErrInvalidData
+	}
+	flags := br.read(4)
+	partial := flags&0x8 != 0
+	bytesShifted := uint(flags>>1) & 0x3
+	escape := flags&0x1 != 0
+	if bytesShifted == 3 {
+		return e, audio.ErrInvalidData
+	}
+
+	e.samples = int(c.frameLength)
+	if partial {
+		e.samples = int(br.read(32))
+		if e.samples > int(c.frameLength) {
+			return e, audio.ErrInvalidData
+		}
+	}
+
+	// Uncompressed samples are stored directly, at the full bit depth
+	if escape {
+		depth := uint(c.bitDepth)
+		for i := 0; i < e.samples; i++ {
+			for ch := 0; ch < channels; ch++ {
+				d.mix[ch][i] = signExtend(br.read(depth), depth)
+			}
+		}
+
+		return e, nil
+	}
+
+	// Channels of a pair are stored with one additional bit, to hold the
+	// difference between the channels
+	e.shift = bytesShifted * 8
+	chanBits := uint(c.bitDepth) - e.shift
+	if pair {
+		chanBits++
+	}
+	if chanBits > 32 {
+		return e, audio.ErrInvalidData
+	}
+
+	e.mixBits = br.read(8)
+	e.mixRes = int32(int8(br.read(8)))
+
+	// Prediction parameters of each channel
+	var params [2]struct {
+		mode     uint32
+		denShift uint
+		pbFactor uint32
+		coefs    []int16
+	}
+	for ch := 0; ch < channels; ch++ {
+		p := &params[ch]
+		b := br.read(8)
+		p.mode, p.denShift = b>>4, uint(b&0xf)
+		b = br.read(8)
+		p.pbFactor = b >> 5
+		p.coefs = make([]int16, b&0x1f)
+		for i := range p.coefs {
+			p.coefs[i] = int16(br.read(16))
+		}
+	}
+
+	// Low order bits of each sample are stored before the compressed samples,
+	// and are read separately
+	shiftBits := *br
+	br.skip(e.shift * uint(e.samples*channels))
+
+	for ch := 0; ch < channels; ch++ {
+		p := &params[ch]
+		pc := d.predictor[ch][:e.samples]
+		if err := br.decompress(pc, c, uint32(c.pb)*p.pbFactor/4, chanBits); err != nil {
+			return e, err
+		}
+
+		// Residuals of a non-zero mode are first integrated with a first
+		// order predictor
+		if p.mode != 0 {
+			unpredict(pc, pc, nil, 31, chanBits, 0)
+		}
+		unpredict(pc, d.mix[ch][:e.samples], p.coefs, len(p.coefs), chanBits, p.denShift)
+	}
+
+	if e.shift > 0 {
+		for i := 0; i < e.samples; i++ {
+			for ch := 0; ch < channels; ch++ {
+				d.shift[ch][i] = int32(shiftBits.read(e.shift))
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// output stores the samples of a decoded element in the frame, beginning at
+// the input channel, normalized to the range [-1, 1].
+func (d *alacDecoder) output(channel int, e alacElement) {
+	bitDepth := uint(d.alac.bitDepth)
+	channels := int(d.alac.channels)
+	scale := 1 / float64(uint64(1)<<(bitDepth-1))
+
+	// sample restores the low order bits of a sample, which is truncated
+	// to the bit depth of the stream
+	sample := func(v int32, ch int, i int) float64 {
+		if e.shift > 0 {
+			v = v<<e.shift | d.shift[ch][i]
+		}
+
+		return float64(signExtend(uint32(v), bitDepth)) * scale
+	}
+
+	for i := 0; i < e.samples; i++ {
+		frame := d.frame[i*channels+channel:]
+		if !e.pair {
+			frame[0] = sample(d.mix[0][i], 0, i)
+			continue
+		}
+
+		// Reconstruct the left and right channels from the mixed channels
+		l, r := d.mix[0][i], d.mix[1][i]
+		if e.mixRes != 0 {
+			v := r
+			l = l + v - (e.mixRes*v)>>e.mixBits
+			r = l - v
+		}
+
+		frame[0] = sample(l, 0, i)
+		frame[1] = sample(r, 1, i)
+	}
+}
+
+// unpredict reconstructs a channel of samples from the input residuals, using
+// the adaptive linear predictor of ALAC with the input coefficients, which
+// are updated as samples are reconstructed.  Samples are truncated to chanBits
+// bits.  A numActive of 31 selects a fixed first order predictor.  The
+// residuals and output may be the same slice.
+func unpredict(pc []int32, out []int32, coefs []int16, numActive int, chanBits uint, denShift uint) {
+	if len(pc) == 0 {
+		return
+	}
+
+	chanShift := 32 - chanBits
+	truncate := func(v int32) int32 {
+		return v << chanShift >> chanShift
+	}
+
+	out[0] = pc[0]
+	if numActive == 0 {
+		copy(out[1:], pc[1:])
+		return
+	}
+	if numActive == 31 {
+		prev := out[0]
+		for j := 1; j < len(pc); j++ {
+			prev = truncate(pc[j] + prev)
+			out[j] = prev
+		}
+		return
+	}
+
+	// The first samples are predicted from the previous sample only
+	for j := 1; j <= numActive && j < len(pc); j++ {
+		out[j] = truncate(pc[j] + out[j-1])
+	}
+
+	var denHalf int32
+	if denShift > 0 {
+		denHalf = 1 << (denShift - 1)
+	}
+
+	lim := numActive + 1
+	for j := lim; j < len(pc); j++ {
+		top := out[j-lim]
+		prev := out[j-numActive : j]
+
+		var sum int32
+		for k, c := range coefs {
+			sum += int32(c) * (prev[numActive-1-k] - top)
+		}
+
+		del := pc[j]
+		del0 := del
+		sg := sign(del)
+		out[j] = truncate(del + top + (sum+denHalf)>>denShift)
+
+		// Adapt the coefficients toward the sign of the residual, starting
+		// with the coefficient of the oldest sample
+		switch {
+		case sg > 0:
+			for k := numActive - 1; k >= 0; k-- {
+				dd := top - prev[numActive-1-k]
+				sgn := sign(dd)
+				coefs[k] -= int16(sgn)
+				del0 -= int32(numActive-k) * ((sgn * dd) >> denShift)
+				if del0 <= 0 {
+					break
+				}
+			}
+		case sg < 0:
+			for k := numActive - 1; k >= 0; k-- {
+				dd := top - prev[numActive-1-k]
+				sgn := sign(dd)
+				coefs[k] += int16(sgn)
+				del0 -= int32(numActive-k) * ((-sgn * dd) >> denShift)
+				if del0 >= 0 {
+					break
+				}
+			}
+		}
+	}
+}
+
+// sign returns 1 for positive values, -1 for negative values, and 0 for 0.
+func sign(v int32) int32 {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+
+	return 0
+}
+
+// signExtend interprets the low bits of v as a signed integer.
+func signExtend(v uint32, bits uint) int32 {
+	shift := 32 - bits
+	return int32(v<<shift) >> shift
+}
+
+// alacBitReader reads big-endian bit fields from an ALAC frame.  Reads beyond
+// the end of the frame return zero bits, and are reported by overrun.
+type alacBitReader struct {
+	b   []byte
+	pos uint
+}
+
+// peek returns the next n bits, where n is at most 32, without consuming them.
+func (br *alacBitReader) peek(n uint) uint32 {
+	if n == 0 {
+		return 0
+	}
+
+	// Load the 40 bits which contain the next 32 bits
+	i := br.pos / 8
+	var v uint64
+	for j := uint(0); j < 5; j++ {
+		v <<= 8
+		if i+j < uint(len(br.b)) {
+			v |= uint64(br.b[i+j])
+		}
+	}
+
+	return uint32(v>>(8-br.pos%8)) >> (32 - n)
+}
+
+// read consumes and returns the next n bits, where n is at most 32.
+func (br *alacBitReader) read(n uint) uint32 {
+	v := br.peek(n)
+	br.pos += n
+	return v
+}
+
+// skip consumes the next n bits.
+func (br *alacBitReader) skip(n uint) {
+	br.pos += n
+}
+
+// align consumes any bits which remain in the current byte.
+func (br *alacBitReader) align() {
+	br.pos = (br.pos + 7) &^ 7
+}
+
+// overrun reports whether any bits were read beyond the end of the frame.
+func (br *alacBitReader) overrun() bool {
+	return br.pos > uint(len(br.b))*8
+}
+
+// decompress decodes residuals into pc using the adaptive Golomb coding of
+// ALAC, with the input rate of adaptation.  Escaped residuals are stored
+// using maxBits bits.
+func (br *alacBitReader) decompress(pc []int32, c alacConfig, pb uint32, maxBits uint) error {
+	mb := uint32(c.mb)
+	kb := uint(c.kb)
+	wb := uint32(1)<<kb - 1
+
+	var zmode uint32
+	for i := 0; i < len(pc); {
+		if br.overrun() {
+			return audio.ErrInvalidData
+		}
+
+		// Parameter of the Golomb code, from the running mean
+		k := uint(31 - bits.LeadingZeros32(mb>>alacQBShift+3))
+		if k > kb {
+			k = kb
+		}
+
+		// The least significant bit is the sign of the residual
+		n := br.golomb(uint32(1)<<k-1, k, maxBits)
+		v := n + zmode
+		del := int32((v + 1) >> 1)
+		if v&1 != 0 {
+			del = -del
+		}
+		pc[i] = del
+		i++
+
+		// Update the running mean
+		mb = pb*(n+zmode) + mb - (pb*mb)>>alacQBShift
+		if n > alacMeanClamp {
+			mb = alacMeanClamp
+		}
+		zmode = 0
+
+		// A small mean is followed by a run of zero residuals
+		if mb<<alacMMulShift < alacQB && i < len(pc) {
+			zmode = 1
+			k := uint(bits.LeadingZeros32(mb)) - alacBitOff + uint((mb+alacMOff)>>alacMDenShift)
+			n := int(br.golomb((uint32(1)<<k-1)&wb, k, 16))
+			if i+n > len(pc) {
+				return audio.ErrInvalidData
+			}
+
+			for j := 0; j < n; j++ {
+				pc[i] = 0
+				i++
+			}
+
+			if n >= 65535 {
+				zmode = 0
+			}
+			mb = 0
+		}
+	}
+
+	return nil
+}
+
+// golomb decodes a single value using a Golomb code with parameter k, whose
+// divisor is m.  A prefix of alacMaxPrefix bits escapes a value, which follows
+// using escapeBits bits.
+func (br *alacBitReader) golomb(m uint32, k uint, escapeBits uint) uint32 {
+	pre := uint32(bits.LeadingZeros32(^br.peek(32)))
+	if pre >= alacMaxPrefix {
+		br.skip(alacMaxPrefix)
+		return br.read(escapeBits)
+	}
+
+	br.skip(uint(pre) + 1)
+	result := pre * m
+	if k <= 1 {
+		return result
+	}
+
+	// A remainder of 0 is stored using one fewer bit
+	v := br.peek(k)
+	if v < 2 {
+		br.skip(k - 1)
+		return result
+	}
+
+	br.skip(k)
+	return result + v - 1
+}
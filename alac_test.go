@@ -0,0 +1,174 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestALACDecoder verifies that an alacDecoder decodes the samples of ALAC
+// frames in an MP4 container, for both uncompressed and compressed elements.
+func TestALACDecoder(t *testing.T) {
+	var tests = []struct {
+		channels int
+		frame    []byte
+		samples  []float64
+	}{
+		// Uncompressed single channel element
+		{1, testALACFrame(func(w *testBitWriter) {
+			w.write(alacElementSCE, 3)
+			w.write(0, 4+12)
+			w.write(0x9, 4)
+			w.write(2, 32)
+			w.write(0x4000, 16)
+			w.write(0xe000, 16)
+		}), []float64{0.5, -0.25}},
+		// Uncompressed channel pair element, with interleaved samples
+		{2, testALACFrame(func(w *testBitWriter) {
+			w.write(alacElementCPE, 3)
+			w.write(0, 4+12)
+			w.write(0x9, 4)
+			w.write(2, 32)
+			w.write(0x4000, 16)
+			w.write(0xe000, 16)
+			w.write(0x2000, 16)
+			w.write(0xc000, 16)
+		}), []float64{0.5, -0.25, 0.25, -0.5}},
+		// Compressed single channel element, with a residual of 1 followed by
+		// a run of one zero residual
+		{1, testALACFrame(func(w *testBitWriter) {
+			w.write(alacElementSCE, 3)
+			w.write(0, 4+12)
+			w.write(0x8, 4)
+			w.write(2, 32)
+			w.write(0, 8+8)
+			w.write(0, 8)
+			w.write(0x80, 8)
+			w.write(0x6, 3)
+			w.write(0x0, 1)
+			w.write(0x2, 2)
+		}), []float64{1.0 / (1 << 15), 0}},
+	}
+
+	for i, test := range tests {
+		d, err := newMP4Decoder(bytes.NewReader(testALAC(test.channels, [][]byte{test.frame})))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if c := d.Config(); c.SampleRate != 96000 || c.Channels != test.channels {
+			t.Fatalf("[%02d] unexpected config: %v", i, c)
+		}
+
+		samples := make(audio.Float64, 8)
+		n, err := d.Read(samples)
+		if err != audio.EOS {
+			t.Fatalf("[%02d] unexpected Read error: %v", i, err)
+		}
+		if n != len(test.samples) {
+			t.Fatalf("[%02d] unexpected samples length: %v != %v", i, n, len(test.samples))
+		}
+		for j, s := range test.samples {
+			if samples[j] != s {
+				t.Fatalf("[%02d] unexpected sample at %d: %v != %v", i, j, samples[j], s)
+			}
+		}
+	}
+}
+
+// TestALACDecoderErrInvalidData verifies that an alacDecoder returns
+// ErrInvalidData for truncated frames, and for frames which do not decode
+// every channel of the stream.
+func TestALACDecoderErrInvalidData(t *testing.T) {
+	sce := testALACFrame(func(w *testBitWriter) {
+		w.write(alacElementSCE, 3)
+		w.write(0, 4+12)
+		w.write(0x9, 4)
+		w.write(1, 32)
+		w.write(0x4000, 16)
+	})
+
+	for i, test := range []struct {
+		channels int
+		frame    []byte
+	}{
+		{1, sce[:4]},
+		{2, sce},
+	} {
+		d, err := newMP4Decoder(bytes.NewReader(testALAC(test.channels, [][]byte{test.frame})))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if _, err := d.Read(make(audio.Float64, 8)); err != ErrInvalidData {
+			t.Fatalf("[%02d] unexpected Read error: %v != %v", i, err, ErrInvalidData)
+		}
+	}
+}
+
+// TestParseALACConfigErrInvalidData verifies that parseALACConfig returns
+// ErrInvalidData for a short or unsupported ALACSpecificConfig.
+func TestParseALACConfigErrInvalidData(t *testing.T) {
+	config := testALACConfig(2)
+	config[5] = 12
+
+	for i, b := range [][]byte{testALACConfig(2)[:20], config} {
+		if _, err := parseALACConfig(b); err != ErrInvalidData {
+			t.Fatalf("[%02d] unexpected parseALACConfig error: %v != %v", i, err, ErrInvalidData)
+		}
+	}
+}
+
+// testALAC is a test helper which generates an M4A container with a single
+// 16-bit, 96kHz ALAC audio track, using the input encoded frames.
+func testALAC(channels int, frames [][]byte) []byte {
+	alac := testMP4Box("alac", make([]byte, 4), testALACConfig(channels))
+	return testMP4Track("alac", 0, uint16(channels), alac, frames)
+}
+
+// testALACConfig is a test helper which generates a 16-bit, 96kHz
+// ALACSpecificConfig with the input number of channels.
+func testALACConfig(channels int) []byte {
+	b := make([]byte, alacConfigLen)
+	binary.BigEndian.PutUint32(b[0:4], 4096)
+	b[5] = 16
+	b[6] = 40
+	b[7] = 10
+	b[8] = 14
+	b[9] = byte(channels)
+	binary.BigEndian.PutUint16(b[10:12], 255)
+	binary.BigEndian.PutUint32(b[20:24], 96000)
+
+	return b
+}
+
+// testALACFrame is a test helper which generates an ALAC frame from the
+// elements written by fn, followed by the end of the frame.
+func testALACFrame(fn func(w *testBitWriter)) []byte {
+	w := new(testBitWriter)
+	fn(w)
+	w.write(alacElementEND, 3)
+
+	return w.b
+}
+
+// testBitWriter is a test helper which writes big-endian bit fields.
+type testBitWriter struct {
+	b   []byte
+	pos uint
+}
+
+// write writes the low n bits of v.
+func (w *testBitWriter) write(v uint32, n uint) {
+	for i := n; i > 0; i-- {
+		if w.pos%8 == 0 {
+			w.b = append(w.b, 0)
+		}
+		if v>>(i-1)&1 != 0 {
+			w.b[len(w.b)-1] |= 0x80 >> (w.pos % 8)
+		}
+		w.pos++
+	}
+}
@@ -0,0 +1,88 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// antiAliasImage draws a waveform image from a slice of computed values at a
+// multiple of its size, set by the AntiAlias option, and reduces it to the
+// size of the image created without anti-aliasing, by averaging each block of
+// pixels.  The edges of the waveform are drawn partially covering pixels,
+// rather than as hard steps.
+func (w *Waveform) antiAliasImage(computed []float64) draw.Image {
+	f := int(w.antiAlias)
+
+	// Draw a larger image, by scaling every dimension measured in pixels
+	large := *w
+	large.antiAlias = 1
+	large.palette = nil
+	large.scaleX *= w.antiAlias
+	if large.height > 0 {
+		large.height *= w.antiAlias
+	} else {
+		large.scaleY *= w.antiAlias
+	}
+	large.width *= w.antiAlias
+	large.maxWidth *= w.antiAlias
+	large.padTop *= w.antiAlias
+	large.padRight *= w.antiAlias
+	large.padBottom *= w.antiAlias
+	large.padLeft *= w.antiAlias
+	large.barRadius *= w.antiAlias
+	large.strokeWidth *= w.antiAlias
+	large.dotRadius *= w.antiAlias
+
+	l := large.newLayout(computed)
+	src := image.NewRGBA(l.bounds)
+	l.draw(src)
+
+	b := l.bounds
+	img := w.newImage(image.Rect(b.Min.X/f, b.Min.Y/f, b.Max.X/f, b.Max.Y/f))
+	downsample(img, src, f, w.gammaCorrect)
+
+	return img
+}
+
+// downsample draws src into dst, whose bounds are f times smaller, setting
+// each pixel of dst to the average of the corresponding f by f block of
+// pixels of src.  If gamma is true, pixels are averaged in linear light.
+func downsample(dst draw.Image, src *image.RGBA, f int, gamma bool) {
+	b := dst.Bounds()
+	n := float64(f * f)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var r, g, bl, a float64
+			for sy := y * f; sy < (y+1)*f; sy++ {
+				for sx := x * f; sx < (x+1)*f; sx++ {
+					c := src.At(sx, sy)
+					if gamma {
+						// Weight linear components by alpha
+						lr, lg, lb, la := linearRGBA(c)
+						r, g, bl, a = r+lr*la, g+lg*la, bl+lb*la, a+la
+						continue
+					}
+
+					cr, cg, cb, ca := c.RGBA()
+					r, g, bl, a = r+float64(cr), g+float64(cg), bl+float64(cb), a+float64(ca)
+				}
+			}
+
+			if !gamma {
+				avg := func(v float64) uint16 {
+					return uint16(v/n + 0.5)
+				}
+				dst.Set(x, y, color.RGBA64{avg(r), avg(g), avg(bl), avg(a)})
+				continue
+			}
+
+			if a == 0 {
+				dst.Set(x, y, color.RGBA64{})
+				continue
+			}
+			dst.Set(x, y, fromLinearRGBA(r/a, g/a, bl/a, a/n))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestWaveformDrawAntiAlias verifies that the Waveform.Draw method creates an
+// image of the same size when AntiAlias is set, whose edges are partially
+// covered pixels, rather than only the foreground and background colors.
+func TestWaveformDrawAntiAlias(t *testing.T) {
+	values := []float64{0.1, 0.5, 0.3}
+	options := []OptionsFunc{Style(AreaFill), Scale(4, 1)}
+
+	w, err := New(nil, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := w.Draw(values)
+
+	w, err = New(nil, append(options, AntiAlias(4))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := w.Draw(values)
+
+	if img.Bounds() != want.Bounds() {
+		t.Fatalf("unexpected image bounds: %v != %v", img.Bounds(), want.Bounds())
+	}
+
+	// The center of the waveform is covered, and the corners are not
+	if c := img.At(0, 64); c != black {
+		t.Fatalf("unexpected foreground color: %v", c)
+	}
+	if c := img.At(0, 0); c != white {
+		t.Fatalf("unexpected background color: %v", c)
+	}
+
+	var partial bool
+	b := img.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			if c := img.At(x, y); c != black && c != white {
+				partial = true
+			}
+		}
+	}
+	if !partial {
+		t.Fatal("no partially covered pixels drawn")
+	}
+}
+
+// TestDownsample verifies that downsample averages each block of pixels.
+func TestDownsample(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 2; y++ {
+			src.Set(x, y, color.White)
+		}
+	}
+	src.Set(0, 0, color.Black)
+	src.Set(1, 1, color.Black)
+	src.Set(2, 0, color.Transparent)
+
+	dst := image.NewRGBA64(image.Rect(0, 0, 2, 1))
+	downsample(dst, src, 2, false)
+
+	for x, want := range []color.RGBA64{
+		{0x8000, 0x8000, 0x8000, 0xffff},
+		{0xbfff, 0xbfff, 0xbfff, 0xbfff},
+	} {
+		if c := dst.RGBA64At(x, 0); c != want {
+			t.Fatalf("unexpected color at x=%d: %v != %v", x, c, want)
+		}
+	}
+}
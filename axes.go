@@ -0,0 +1,157 @@
+package waveform
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// axisTickLength is the length in pixels of each tick mark of a time axis.
+	axisTickLength = 4
+
+	// decibelGridRange is the range in decibels, below full scale, in which
+	// gridlines are drawn by DecibelGrid: the dynamic range of 16-bit audio.
+	decibelGridRange = 96
+)
+
+// drawsAnnotations reports whether decibel gridlines, markers set by the
+// Markers option, or a time axis are drawn in front of the waveform.
+func (w *Waveform) drawsAnnotations() bool {
+	return w.axisColor != nil || w.gridColor != nil || len(w.markers) > 0
+}
+
+// drawAnnotations draws any decibel gridlines, markers, and time axis, in
+// that order, in front of the waveform area of img.  n is the number of
+// values computed from the audio, before any resampling by Dimensions.
+func (l *layout) drawAnnotations(img draw.Image, n int) {
+	if l.w.gridColor != nil {
+		l.drawDecibelGrid(img)
+	}
+	if len(l.w.markers) > 0 {
+		l.drawMarkers(img, n)
+	}
+	if l.w.axisColor != nil {
+		l.drawTimeAxis(img, n)
+	}
+}
+
+// drawDecibelGrid draws a pair of horizontal gridlines at each step set by
+// DecibelGrid, at the extent of a value at that level below full scale.
+// Levels which are drawn less than two pixels high are skipped.
+func (l *layout) drawDecibelGrid(img draw.Image) {
+	for db := 0.0; db >= -decibelGridRange; db -= l.w.gridStep {
+		top, bottom := l.valueExtent(l.w.amplitude(math.Pow(10, db/20)))
+		if bottom-top < 2 {
+			continue
+		}
+
+		for _, y := range []int{top, bottom - 1} {
+			if y >= 0 && y < l.maxY {
+				l.fillOver(img, image.Rect(0, y, l.maxX, y+1), l.w.gridColor)
+			}
+		}
+	}
+}
+
+// drawTimeAxis draws a tick mark at the bottom of the waveform area at each
+// interval set by TimeAxis, labeled with its timestamp.  n is the number of
+// values computed from the audio.
+func (l *layout) drawTimeAxis(img draw.Image, n int) {
+	start, slice := l.timing(n)
+	if slice <= 0 {
+		return
+	}
+
+	face := l.w.font()
+	src := image.NewUniform(l.w.axisColor)
+	descent := face.Metrics().Descent.Ceil()
+
+	// Include fractions of a second in labels, only if the interval does
+	interval := l.w.axisInterval
+	precise := interval%time.Second != 0
+
+	// Begin at the first multiple of the interval within the computed values
+	for t := (start + interval - 1) / interval * interval; ; t += interval {
+		x := int(float64(t-start) / float64(slice) * float64(l.period))
+		if x >= l.maxX {
+			break
+		}
+
+		l.fillOver(img, image.Rect(x, l.maxY-axisTickLength, x+1, l.maxY), l.w.axisColor)
+
+		// Label the tick to its right, above the bottom of the waveform area
+		d := &font.Drawer{
+			Dst:  img,
+			Src:  src,
+			Face: face,
+			Dot:  fixed.P(l.offset.X+x+2, l.offset.Y+l.maxY-descent-1),
+		}
+		d.DrawString(formatTimestamp(t, precise))
+	}
+}
+
+// font returns the font face set by AxisFont, or the default font face.
+func (w *Waveform) font() font.Face {
+	if w.axisFace == nil {
+		return basicfont.Face7x13
+	}
+
+	return w.axisFace
+}
+
+// timing returns the timestamp of the first value drawn by a layout, and the
+// duration of audio drawn by each value, given the number of values computed
+// from the audio.  If the values were computed by the last call to Compute,
+// its metadata is used, and otherwise, each computed value is assumed to
+// cover one slice of audio at the configured resolution.
+func (l *layout) timing(n int) (time.Duration, time.Duration) {
+	if l.maxN == 0 {
+		return 0, 0
+	}
+
+	m := l.w.metadata
+	if m.SampleRate > 0 && m.values() == n {
+		rate := time.Duration(m.SampleRate)
+		start := time.Duration(m.StartSample) * time.Second / rate
+		slice := time.Duration(m.SliceSamples) * time.Second / rate
+
+		return start, slice * time.Duration(n) / time.Duration(l.maxN)
+	}
+
+	if l.w.resolution <= 0 {
+		return 0, 0
+	}
+
+	slice := time.Duration(float64(time.Second) / l.w.resolution)
+	return 0, slice * time.Duration(n) / time.Duration(l.maxN)
+}
+
+// fillOver composites color c over the rectangle r of the waveform area of
+// img.
+func (l *layout) fillOver(img draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r.Add(l.offset), image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// formatTimestamp formats a timestamp as minutes and seconds, with hours if
+// needed, and with milliseconds if precise is true.
+func formatTimestamp(t time.Duration, precise bool) string {
+	h, m, s := int(t/time.Hour), int(t/time.Minute)%60, int(t/time.Second)%60
+
+	out := fmt.Sprintf("%d:%02d", m, s)
+	if h > 0 {
+		out = fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	if precise {
+		out += fmt.Sprintf(".%03d", int(t/time.Millisecond)%1000)
+	}
+
+	return out
+}
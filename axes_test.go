@@ -0,0 +1,105 @@
+package waveform
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestWaveformDrawTimeAxis verifies that the Waveform.Draw method draws a tick
+// mark at the bottom of the waveform at each interval set by TimeAxis.
+func TestWaveformDrawTimeAxis(t *testing.T) {
+	w, err := New(nil, TimeAxis(time.Second, red), Scale(40, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One value per second, and labels drawn to the right of each tick
+	img := w.Draw(make([]float64, 3))
+	for _, x := range []int{0, 40, 80} {
+		if c := img.At(x, 127); c != red {
+			t.Fatalf("unexpected tick color at x=%d: %v", x, c)
+		}
+		if c := img.At(x, 127-axisTickLength); c != white {
+			t.Fatalf("unexpected color above tick at x=%d: %v", x, c)
+		}
+		if x > 0 {
+			if c := img.At(x-1, 127); c != white {
+				t.Fatalf("unexpected color before tick at x=%d: %v", x, c)
+			}
+		}
+	}
+}
+
+// TestLayoutTiming verifies that timestamps are measured using the metadata
+// of the last computation, when it corresponds to the drawn values.
+func TestLayoutTiming(t *testing.T) {
+	w, err := New(nil, Resolution(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without metadata, each value covers one slice at the resolution
+	start, slice := w.newLayout(make([]float64, 4)).timing(4)
+	if start != 0 || slice != 250*time.Millisecond {
+		t.Fatalf("unexpected timing: %v, %v", start, slice)
+	}
+
+	// Four slices of half a second, after one second of trimmed silence
+	w.metadata = Metadata{
+		SampleRate:   100,
+		SliceSamples: 50,
+		StartSample:  100,
+		EndSample:    300,
+		TotalSamples: 400,
+	}
+
+	start, slice = w.newLayout(make([]float64, 4)).timing(4)
+	if start != time.Second || slice != 500*time.Millisecond {
+		t.Fatalf("unexpected timing: %v, %v", start, slice)
+	}
+
+	// Resampled values each cover a longer duration
+	start, slice = w.newLayout(make([]float64, 2)).timing(4)
+	if start != time.Second || slice != time.Second {
+		t.Fatalf("unexpected resampled timing: %v, %v", start, slice)
+	}
+}
+
+// TestWaveformDrawDecibelGrid verifies that the Waveform.Draw method draws
+// gridlines at the extent of each level set by DecibelGrid.
+func TestWaveformDrawDecibelGrid(t *testing.T) {
+	w, err := New(nil, DecibelGrid(12, red))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.Draw([]float64{0})
+	top, bottom := w.newLayout([]float64{0}).valueExtent(math.Pow(10, -24.0/20))
+
+	for y, want := range map[int]interface{}{top: red, bottom - 1: red, top + 1: white, 64: white} {
+		if c := img.At(0, y); c != want {
+			t.Fatalf("unexpected color at y=%d: %v != %v", y, c, want)
+		}
+	}
+}
+
+// TestFormatTimestamp verifies the format of time axis labels.
+func TestFormatTimestamp(t *testing.T) {
+	var tests = []struct {
+		t       time.Duration
+		precise bool
+		want    string
+	}{
+		{0, false, "0:00"},
+		{75 * time.Second, false, "1:15"},
+		{time.Hour + 2*time.Minute + 3*time.Second, false, "1:02:03"},
+		{1500 * time.Millisecond, true, "0:01.500"},
+	}
+
+	for i, test := range tests {
+		if got := formatTimestamp(test.t, test.precise); got != test.want {
+			t.Fatalf("[%02d] unexpected timestamp: %q != %q", i, got, test.want)
+		}
+	}
+}
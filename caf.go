@@ -0,0 +1,248 @@
+package waveform
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"azul3d.org/engine/audio"
+)
+
+const (
+	// cafMagic is the magic string which begins a CAF stream: its file type,
+	// followed by file version 1
+	cafMagic = "caff\x00\x01"
+
+	// cafFlagFloat and cafFlagLittleEndian are the format flags of linear
+	// PCM samples which are floating point, or in little-endian byte order
+	cafFlagFloat        = 1 << 0
+	cafFlagLittleEndian = 1 << 1
+
+	// cafDescSize is the size of the body of an audio description chunk
+	cafDescSize = 32
+)
+
+func init() {
+	audio.RegisterFormat(FormatCAF, cafMagic, newCAFDecoder)
+}
+
+// cafDecoder is an audio.Decoder which decodes the samples of a CAF stream.
+type cafDecoder struct {
+	r      io.Reader
+	config audio.Config
+	codec  aiffCodec
+	bits   int
+
+	// Bytes of sample data remaining, and whether the size of the sample data
+	// is unknown, in which case the sample data continues until the end of
+	// the stream
+	remaining int64
+	streaming bool
+
+	buf []byte
+}
+
+// newCAFDecoder reads the header of a CAF stream, and returns a decoder which
+// is positioned at the beginning of its sample data.
+//
+// Linear PCM samples, in either byte order, and G.711 samples are decoded.  If
+// a stream uses any other format, such as AAC or ALAC, ErrFormat is returned.
+func newCAFDecoder(r interface{}) (audio.Decoder, error) {
+	rr, ok := r.(io.Reader)
+	if !ok {
+		return nil, audio.ErrInvalidData
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(rr, header[:]); err != nil {
+		return nil, audio.ErrUnexpectedEOS
+	}
+	if string(header[0:6]) != cafMagic {
+		return nil, audio.ErrInvalidData
+	}
+
+	d := &cafDecoder{r: rr}
+	var haveDesc bool
+	for {
+		var chunk [12]byte
+		if _, err := io.ReadFull(rr, chunk[:]); err != nil {
+			return nil, audio.ErrUnexpectedEOS
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.BigEndian.Uint64(chunk[4:12]))
+
+		switch id {
+		case "desc":
+			if size != cafDescSize {
+				return nil, audio.ErrInvalidData
+			}
+
+			b := make([]byte, size)
+			if _, err := io.ReadFull(rr, b); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+
+			if err := d.readDesc(b); err != nil {
+				return nil, err
+			}
+			haveDesc = true
+		case "data":
+			// Sample data must be described by the audio description chunk,
+			// and begins with an edit count
+			if !haveDesc || size != -1 && size < 4 {
+				return nil, audio.ErrInvalidData
+			}
+			if _, err := io.CopyN(ioutil.Discard, rr, 4); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+
+			// Only the final chunk may have an unknown size
+			if size == -1 {
+				d.remaining = math.MaxInt64
+				d.streaming = true
+			} else {
+				d.remaining = size - 4
+			}
+
+			return d, nil
+		default:
+			// Skip all other chunks, which are not padded
+			if size < 0 {
+				return nil, audio.ErrInvalidData
+			}
+			if _, err := io.CopyN(ioutil.Discard, rr, size); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+		}
+	}
+}
+
+// readDesc parses the body of an audio description chunk into the receiving
+// decoder.
+func (d *cafDecoder) readDesc(b []byte) error {
+	sampleRate := math.Float64frombits(binary.BigEndian.Uint64(b[0:8]))
+	format := string(b[8:12])
+	flags := binary.BigEndian.Uint32(b[12:16])
+	bytesPerPacket := int(binary.BigEndian.Uint32(b[16:20]))
+	framesPerPacket := int(binary.BigEndian.Uint32(b[20:24]))
+	channels := int(binary.BigEndian.Uint32(b[24:28]))
+	bits := int(binary.BigEndian.Uint32(b[28:32]))
+	if channels <= 0 || channels > channelsMax || !(sampleRate >= 1 && sampleRate <= sampleRateMax) {
+		return audio.ErrInvalidData
+	}
+
+	var newCodec func(bits int) (aiffCodec, bool)
+	switch format {
+	case "lpcm":
+		newCodec = cafPCMCodec(flags)
+	case "ulaw":
+		newCodec = aiffG711Codec(ulawSample)
+	case "alaw":
+		newCodec = aiffG711Codec(alawSample)
+	default:
+		return audio.ErrFormat
+	}
+
+	codec, ok := newCodec(bits)
+	if !ok {
+		return audio.ErrInvalidData
+	}
+
+	// Each packet must contain a single frame of packed samples
+	if framesPerPacket != 1 || bytesPerPacket != channels*codec.size {
+		return audio.ErrInvalidData
+	}
+
+	d.codec = codec
+	d.bits = bits
+	d.config = audio.Config{
+		SampleRate: int(sampleRate + 0.5),
+		Channels:   channels,
+	}
+
+	return nil
+}
+
+// cafPCMCodec returns a function which creates a codec for linear PCM samples
+// described by the input format flags.
+func cafPCMCodec(flags uint32) func(bits int) (aiffCodec, bool) {
+	var order binary.ByteOrder = binary.BigEndian
+	if flags&cafFlagLittleEndian != 0 {
+		order = binary.LittleEndian
+	}
+
+	if flags&cafFlagFloat == 0 {
+		return aiffPCMCodec(order)
+	}
+
+	return func(bits int) (aiffCodec, bool) {
+		if bits != 32 && bits != 64 {
+			return aiffCodec{}, false
+		}
+
+		// Big-endian floating point samples are shared with AIFF-C
+		if order == binary.BigEndian {
+			return aiffFloatCodec(bits)(bits)
+		}
+
+		return aiffCodec{size: bits / 8, sample: func(b []byte) float64 {
+			return floatSample(b, uint16(bits))
+		}}, true
+	}
+}
+
+// Config returns the audio configuration of the CAF stream.
+func (d *cafDecoder) Config() audio.Config {
+	return d.config
+}
+
+// bitDepth returns the bit depth of the CAF stream, as stored in its audio
+// description chunk.
+func (d *cafDecoder) bitDepth() int {
+	return d.bits
+}
+
+// Read decodes samples into b, returning the number of samples read.  When
+// the end of the sample data is reached, audio.EOS is returned along with any
+// remaining samples.
+func (d *cafDecoder) Read(b audio.Slice) (int, error) {
+	size := d.codec.size
+
+	// Read as many whole samples as fit in b, or remain in the stream
+	count := int64(b.Len())
+	if whole := d.remaining / int64(size); count > whole {
+		count = whole
+	}
+
+	need := int(count) * size
+	if cap(d.buf) < need {
+		d.buf = make([]byte, need)
+	}
+	buf := d.buf[:need]
+
+	read, err := io.ReadFull(d.r, buf)
+	d.remaining -= int64(read)
+
+	n := read / size
+	for i := 0; i < n; i++ {
+		b.Set(i, d.codec.sample(buf[i*size:]))
+	}
+
+	// Sample data of an unknown size ends with the stream, and otherwise the
+	// stream ended before all sample data was read
+	if err != nil {
+		if d.streaming {
+			return n, audio.EOS
+		}
+
+		return n, audio.ErrUnexpectedEOS
+	}
+
+	// No whole samples remain
+	if d.remaining < int64(size) {
+		return n, audio.EOS
+	}
+
+	return n, nil
+}
@@ -0,0 +1,170 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestCAFDecoder verifies that a cafDecoder decodes the samples of CAF streams,
+// for each supported sample encoding.
+func TestCAFDecoder(t *testing.T) {
+	f32 := make([]byte, 8)
+	binary.LittleEndian.PutUint32(f32[0:], math.Float32bits(0.5))
+	binary.LittleEndian.PutUint32(f32[4:], math.Float32bits(-0.25))
+
+	f64 := make([]byte, 16)
+	binary.BigEndian.PutUint64(f64[0:], math.Float64bits(0.5))
+	binary.BigEndian.PutUint64(f64[8:], math.Float64bits(-0.25))
+
+	var tests = []struct {
+		format  string
+		flags   uint32
+		bits    uint32
+		data    []byte
+		samples []float64
+	}{
+		{"lpcm", 0, 8, []byte{0x40, 0xe0}, []float64{0.5, -0.25}},
+		{"lpcm", 0, 16, []byte{0x40, 0x00, 0xe0, 0x00}, []float64{0.5, -0.25}},
+		{"lpcm", cafFlagLittleEndian, 16, []byte{0x00, 0x40, 0x00, 0xe0}, []float64{0.5, -0.25}},
+		{"lpcm", cafFlagLittleEndian, 24, []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0xe0}, []float64{0.5, -0.25}},
+		{"lpcm", 0, 32, []byte{0x40, 0, 0, 0, 0xe0, 0, 0, 0}, []float64{0.5, -0.25}},
+		{"lpcm", cafFlagFloat | cafFlagLittleEndian, 32, f32, []float64{0.5, -0.25}},
+		{"lpcm", cafFlagFloat, 64, f64, []float64{0.5, -0.25}},
+		{"ulaw", 0, 8, []byte{0xff, 0x7f}, []float64{0, 0}},
+		{"alaw", 0, 8, []byte{0xd5, 0x55}, []float64{8.0 / (1 << 15), -8.0 / (1 << 15)}},
+	}
+
+	for i, test := range tests {
+		// Sample data of both known and unknown sizes ends with the stream
+		for _, streaming := range []bool{false, true} {
+			data := testCAF(test.format, test.flags, 1, test.bits, 8000, test.data, streaming)
+			d, err := newCAFDecoder(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("[%02d] %v", i, err)
+			}
+
+			if c := d.Config(); c.SampleRate != 8000 || c.Channels != 1 {
+				t.Fatalf("[%02d] unexpected config: %v", i, c)
+			}
+
+			samples := make(audio.Float64, 8)
+			n, err := d.Read(samples)
+			if err != audio.EOS {
+				t.Fatalf("[%02d] unexpected Read error: %v", i, err)
+			}
+			if n != len(test.samples) {
+				t.Fatalf("[%02d] unexpected samples length: %v != %v", i, n, len(test.samples))
+			}
+			for j, s := range test.samples {
+				if samples[j] != s {
+					t.Fatalf("[%02d] unexpected sample at %d: %v != %v", i, j, samples[j], s)
+				}
+			}
+		}
+	}
+}
+
+// TestWaveformComputeCAFOK verifies that the Waveform.Compute method produces
+// the same values from a CAF stream as from the AIFF stream with the same
+// samples.
+func TestWaveformComputeCAFOK(t *testing.T) {
+	data := make([]byte, 2*2*300)
+	for i := range data {
+		data[i] = byte(i * 31)
+	}
+
+	want, err := testComputeValues(bytes.NewReader(testAIFF("", 2, 16, 100, data)), Resolution(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := testComputeValues(bytes.NewReader(testCAF("lpcm", 0, 2, 16, 100, data, false)), Resolution(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("unexpected Compute values length: %v != %v", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected Compute value at index %d: %v != %v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestWaveformComputeCAFErrors verifies that the Waveform.Compute method
+// returns appropriate errors for invalid or unsupported CAF streams.
+func TestWaveformComputeCAFErrors(t *testing.T) {
+	valid := testCAF("lpcm", 0, 1, 16, 8000, []byte{0, 1, 0, 2}, false)
+
+	// Sample data before the audio description chunk
+	noDesc := append([]byte(nil), valid[:8]...)
+	noDesc = append(noDesc, valid[8+12+cafDescSize:]...)
+
+	var tests = []struct {
+		data []byte
+		err  error
+	}{
+		// Unsupported formats
+		{testCAF("aac ", 0, 1, 0, 8000, []byte{0, 1}, false), ErrFormat},
+		{testCAF("alac", 0, 1, 16, 8000, []byte{0, 1}, false), ErrFormat},
+		// Truncated header
+		{valid[:20], ErrUnexpectedEOS},
+		// Truncated sample data
+		{valid[:len(valid)-2], ErrUnexpectedEOS},
+		// Invalid bit depth
+		{testCAF("lpcm", 0, 1, 33, 8000, []byte{0, 1}, false), ErrInvalidData},
+		{testCAF("lpcm", cafFlagFloat, 1, 16, 8000, []byte{0, 1}, false), ErrInvalidData},
+		// Implausible number of channels or sample rate
+		{testCAF("lpcm", 0, channelsMax+1, 8, 8000, make([]byte, channelsMax+1), false), ErrInvalidData},
+		{testCAF("lpcm", 0, 1, 16, 1e12, []byte{0, 1}, false), ErrInvalidData},
+		// Missing audio description chunk
+		{noDesc, ErrInvalidData},
+	}
+
+	for i, test := range tests {
+		if _, err := testComputeValues(bytes.NewReader(test.data)); err != test.err {
+			t.Fatalf("[%02d] unexpected Compute error: %v != %v", i, err, test.err)
+		}
+	}
+}
+
+// testCAF is a test helper which generates a CAF stream with the input format,
+// format flags, channels, bit depth, sample rate, and encoded sample data.  If
+// streaming is true, the size of the data chunk is unknown.
+func testCAF(format string, flags uint32, channels uint32, bits uint32, sampleRate float64, data []byte, streaming bool) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(cafMagic)
+	binary.Write(buf, binary.BigEndian, uint16(0))
+
+	buf.WriteString("desc")
+	binary.Write(buf, binary.BigEndian, int64(cafDescSize))
+	binary.Write(buf, binary.BigEndian, math.Float64bits(sampleRate))
+	buf.WriteString(format)
+	binary.Write(buf, binary.BigEndian, flags)
+	binary.Write(buf, binary.BigEndian, channels*((bits+7)/8))
+	binary.Write(buf, binary.BigEndian, uint32(1))
+	binary.Write(buf, binary.BigEndian, channels)
+	binary.Write(buf, binary.BigEndian, bits)
+
+	// A chunk which is skipped
+	buf.WriteString("info")
+	binary.Write(buf, binary.BigEndian, int64(4))
+	binary.Write(buf, binary.BigEndian, uint32(0))
+
+	size := int64(4 + len(data))
+	if streaming {
+		size = -1
+	}
+	buf.WriteString("data")
+	binary.Write(buf, binary.BigEndian, size)
+	binary.Write(buf, binary.BigEndian, uint32(0))
+	buf.Write(data)
+
+	return buf.Bytes()
+}
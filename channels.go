@@ -0,0 +1,194 @@
+package waveform
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"azul3d.org/engine/audio"
+)
+
+// ChannelMode is the mode used to compute values from the channels of an audio
+// stream with more than one channel.
+type ChannelMode int
+
+// Modes which may be used with the Channels option.
+const (
+	// Downmix averages all channels to mono before computing values.
+	Downmix ChannelMode = iota
+
+	// Left and Right compute values from only the first or second channel,
+	// as if set by the Channel option.
+	Left
+	Right
+
+	// Separate computes values from each channel independently, in addition
+	// to the down-mixed values, so that each channel may be drawn separately.
+	Separate
+)
+
+// UnsupportedChannelsError is returned when an input audio stream contains
+// more channels than permitted by the MaxChannels option.  It reports the
+// number of channels in the stream, and matches ErrUnsupportedChannels when
+// used with errors.Is.
+type UnsupportedChannelsError struct {
+	Channels int
+}
+
+// Error returns the string representation of an UnsupportedChannelsError.
+func (e *UnsupportedChannelsError) Error() string {
+	return fmt.Sprintf("%s: %d", ErrUnsupportedChannels.Error(), e.Channels)
+}
+
+// Is reports whether target is ErrUnsupportedChannels.
+func (e *UnsupportedChannelsError) Is(target error) bool {
+	return target == ErrUnsupportedChannels
+}
+
+// downmix averages each frame of interleaved samples, and stores the resulting
+// mono samples in dst, which must be large enough to contain one sample for
+// each frame.  The mono sample for each frame is:
+//
+//	m = (s[0] + s[1] + ... + s[channels-1]) / channels
+//
+// A trailing, incomplete frame is averaged over the samples it contains.  If
+// there is only a single channel, samples are returned unmodified.
+func downmix(dst audio.Float64, samples audio.Float64, channels int) audio.Float64 {
+	if channels <= 1 {
+		return samples
+	}
+
+	var n int
+	for i := 0; i < len(samples); i += channels {
+		frame := samples[i:minInt(i+channels, len(samples))]
+
+		var sum float64
+		for _, s := range frame {
+			sum += s
+		}
+
+		dst[n] = sum / float64(len(frame))
+		n++
+	}
+
+	return dst[:n]
+}
+
+// extractChannel stores the samples of a single, zero-based channel of each
+// frame of interleaved samples in dst, which must be large enough to contain
+// one sample for each frame.  A trailing, incomplete frame which does not
+// contain the channel is discarded.  If there is only a single channel,
+// samples are returned unmodified.
+func extractChannel(dst audio.Float64, samples audio.Float64, channels int, channel int) audio.Float64 {
+	if channels <= 1 {
+		return samples
+	}
+
+	var n int
+	for i := channel; i < len(samples); i += channels {
+		dst[n] = samples[i]
+		n++
+	}
+
+	return dst[:n]
+}
+
+// ChannelValues returns the values of each channel, in channel order, computed
+// by the last call to Compute while the Separate channel mode was set.  If
+// Compute has not been called with the Separate channel mode, nil is returned.
+func (w *Waveform) ChannelValues() [][]float64 {
+	return w.channelValues
+}
+
+// DrawChannels creates a new image.Image from the values of each channel
+// returned by ChannelValues, in which the waveform of each channel is drawn
+// below the waveform of the previous channel, as if drawn by Draw.  If there
+// are no channel values, nil is returned.
+//
+// If the Paletted option is set, the image is an *image.Paletted.
+func (w *Waveform) DrawChannels() image.Image {
+	if len(w.channelValues) == 0 {
+		return nil
+	}
+
+	// Draw each channel using its own statistics, rather than those of the
+	// down-mixed values
+	stats := w.stats
+	defer func() {
+		w.stats = stats
+	}()
+
+	images := make([]image.Image, len(w.channelValues))
+	var height int
+	for c, values := range w.channelValues {
+		w.stats = nil
+		if w.channelStats != nil {
+			w.stats = w.channelStats[c]
+		}
+
+		images[c] = w.Draw(values)
+		height += images[c].Bounds().Dy()
+	}
+
+	// All channels have the same number of values, and so the same width
+	bounds := image.Rect(0, 0, images[0].Bounds().Dx(), height)
+	img := w.newImage(bounds)
+
+	var y int
+	for _, src := range images {
+		b := src.Bounds()
+		draw.Draw(img, image.Rect(0, y, b.Dx(), y+b.Dy()), src, b.Min, draw.Src)
+		y += b.Dy()
+	}
+
+	return img
+}
+
+// DrawStereo creates a new image.Image from the values of the first two
+// channels returned by ChannelValues, in which the upper half of the waveform
+// of the left channel is drawn above the center of the image, and the lower
+// half of the waveform of the right channel is drawn below it, using the
+// ColorFuncs set by the StereoSplit option in place of the foreground
+// ColorFunc.  The image has the same dimensions as the image created by Draw.
+// A mono stream is drawn in both halves.  If there are no channel values, nil
+// is returned.
+func (w *Waveform) DrawStereo() image.Image {
+	if len(w.channelValues) == 0 {
+		return nil
+	}
+
+	right := 0
+	if len(w.channelValues) > 1 {
+		right = 1
+	}
+
+	upper := w.drawChannel(0, w.leftColorFn)
+	lower := w.drawChannel(right, w.rightColorFn)
+
+	// Split the images at the center of the waveform area, below any padding
+	l := w.newLayout(w.channelValues[0])
+	b := upper.Bounds()
+	mid := b.Min.Y + l.offset.Y + l.imgHalfY
+
+	img := w.newImage(b)
+	draw.Draw(img, image.Rect(b.Min.X, b.Min.Y, b.Max.X, mid), upper, b.Min, draw.Src)
+	draw.Draw(img, image.Rect(b.Min.X, mid, b.Max.X, b.Max.Y), lower, image.Pt(b.Min.X, mid), draw.Src)
+
+	return img
+}
+
+// drawChannel draws the values of channel c using its own statistics, rather
+// than those of the down-mixed values, and the input foreground ColorFunc, if
+// it is not nil.
+func (w *Waveform) drawChannel(c int, fn ColorFunc) image.Image {
+	cw := *w
+	cw.stats = nil
+	if w.channelStats != nil {
+		cw.stats = w.channelStats[c]
+	}
+	if fn != nil {
+		cw.fgColorFn = fn
+	}
+
+	return cw.Draw(w.channelValues[c])
+}
@@ -0,0 +1,352 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestDownmix verifies that downmix averages each frame of interleaved samples,
+// including a trailing, incomplete frame.
+func TestDownmix(t *testing.T) {
+	var tests = []struct {
+		samples  audio.Float64
+		channels int
+		out      audio.Float64
+	}{
+		{audio.Float64{0.5, -0.5}, 1, audio.Float64{0.5, -0.5}},
+		{audio.Float64{0.5, -0.5, 1, 0}, 2, audio.Float64{0, 0.5}},
+		{audio.Float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, -0.3}, 6, audio.Float64{0.35, -0.3}},
+	}
+
+	for i, test := range tests {
+		out := downmix(make(audio.Float64, len(test.samples)), test.samples, test.channels)
+		if len(out) != len(test.out) {
+			t.Fatalf("[%02d] unexpected length: %v != %v", i, len(out), len(test.out))
+		}
+
+		for j := range out {
+			if math.Abs(out[j]-test.out[j]) > 1e-9 {
+				t.Fatalf("[%02d] unexpected sample at %d: %v != %v", i, j, out[j], test.out[j])
+			}
+		}
+	}
+}
+
+// TestExtractChannel verifies that extractChannel selects a single channel of
+// each frame of interleaved samples, discarding a trailing, incomplete frame
+// which does not contain the channel.
+func TestExtractChannel(t *testing.T) {
+	var tests = []struct {
+		samples  audio.Float64
+		channels int
+		channel  int
+		out      audio.Float64
+	}{
+		{audio.Float64{0.5, -0.5}, 1, 0, audio.Float64{0.5, -0.5}},
+		{audio.Float64{0.5, -0.5, 1, 0}, 2, 0, audio.Float64{0.5, 1}},
+		{audio.Float64{0.5, -0.5, 1, 0}, 2, 1, audio.Float64{-0.5, 0}},
+		{audio.Float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, -0.3}, 3, 1, audio.Float64{0.2, 0.5}},
+	}
+
+	for i, test := range tests {
+		out := extractChannel(make(audio.Float64, len(test.samples)), test.samples, test.channels, test.channel)
+		if len(out) != len(test.out) {
+			t.Fatalf("[%02d] unexpected length: %v != %v", i, len(out), len(test.out))
+		}
+
+		for j := range out {
+			if out[j] != test.out[j] {
+				t.Fatalf("[%02d] unexpected sample at %d: %v != %v", i, j, out[j], test.out[j])
+			}
+		}
+	}
+}
+
+// TestWaveformComputeSurroundOK verifies that the Waveform.Compute method
+// down-mixes a 6-channel WAV stream, so that its values equal the values
+// computed from the average of all channels.
+func TestWaveformComputeSurroundOK(t *testing.T) {
+	file, err := ioutil.ReadFile("./test/surround8bit.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode and average all channels of the stream directly
+	d, err := newWAVDecoder(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := d.Config()
+	if config.Channels != 6 {
+		t.Fatalf("unexpected fixture channels: %v != %v", config.Channels, 6)
+	}
+
+	samples := make(audio.Float64, len(file))
+	n, err := d.Read(samples)
+	if err != audio.EOS {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+
+	var mono []float64
+	for i := 0; i < n; i += config.Channels {
+		var sum float64
+		for c := 0; c < config.Channels; c++ {
+			sum += samples[i+c]
+		}
+
+		mono = append(mono, sum/float64(config.Channels))
+	}
+
+	// Values computed from the average must match one per second of audio
+	want, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantValues, err := want.computeSamples(newSamplesDecoder(mono, config.SampleRate, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != len(wantValues) {
+		t.Fatalf("unexpected Compute values length: %v != %v", len(values), len(wantValues))
+	}
+	for i := range wantValues {
+		if math.Abs(values[i]-wantValues[i]) > 1e-9 {
+			t.Fatalf("unexpected Compute value at index %d: %v != %v", i, values[i], wantValues[i])
+		}
+	}
+}
+
+// TestWaveformComputeErrUnsupportedChannels verifies that the Waveform.Compute
+// method rejects a stream with more channels than permitted by MaxChannels,
+// reporting the number of channels.
+func TestWaveformComputeErrUnsupportedChannels(t *testing.T) {
+	w, err := New(nil, MaxChannels(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make([]float64, 600)
+	values, err := w.computeSamples(newSamplesDecoder(samples, 100, 6), nil)
+	if !errors.Is(err, ErrUnsupportedChannels) {
+		t.Fatalf("unexpected Compute error: %v != %v", err, ErrUnsupportedChannels)
+	}
+	if values != nil {
+		t.Fatalf("unexpected values: %v", values)
+	}
+
+	if e, ok := err.(*UnsupportedChannelsError); !ok || e.Channels != 6 {
+		t.Fatalf("unexpected error channels: %#v", err)
+	}
+}
+
+// TestWaveformComputeChannelOK verifies that the Waveform.Compute method only
+// uses the samples of a selected channel, so that its values equal the values
+// computed from a mono stream of that channel.
+func TestWaveformComputeChannelOK(t *testing.T) {
+	// Left channel is silent, right channel alternates in amplitude
+	stereo := make([]float64, 800)
+	var right []float64
+	for i := 1; i < len(stereo); i += 2 {
+		v := float64((i/2)%100) / 100
+		stereo[i] = v
+		right = append(right, v)
+	}
+
+	w, err := New(nil, Channel(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := w.computeSamples(newSamplesDecoder(stereo, 100, 2), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantValues, err := want.computeSamples(newSamplesDecoder(right, 100, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != len(wantValues) {
+		t.Fatalf("unexpected Compute values length: %v != %v", len(values), len(wantValues))
+	}
+	for i := range wantValues {
+		if values[i] != wantValues[i] {
+			t.Fatalf("unexpected Compute value at index %d: %v != %v", i, values[i], wantValues[i])
+		}
+	}
+}
+
+// TestWaveformComputeErrChannelRange verifies that the Waveform.Compute method
+// rejects a selected channel which does not exist in the stream.
+func TestWaveformComputeErrChannelRange(t *testing.T) {
+	w, err := New(nil, Channel(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make([]float64, 200)
+	values, err := w.computeSamples(newSamplesDecoder(samples, 100, 2), nil)
+	if err != ErrChannelRange {
+		t.Fatalf("unexpected Compute error: %v != %v", err, ErrChannelRange)
+	}
+	if values != nil {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+// TestWaveformComputeChannelsModes verifies that each ChannelMode computes the
+// same values as down-mixing or selecting a channel, and that the Separate mode
+// retains the values of each channel in addition to the down-mixed values.
+func TestWaveformComputeChannelsModes(t *testing.T) {
+	// Left channel rises, right channel falls
+	stereo := make([]float64, 800)
+	for i := 0; i < len(stereo); i += 2 {
+		stereo[i] = float64((i/2)%100) / 100
+		stereo[i+1] = 1 - stereo[i]
+	}
+
+	compute := func(options ...OptionsFunc) (*Waveform, []float64) {
+		w, err := New(nil, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values, err := w.computeSamples(newSamplesDecoder(stereo, 100, 2), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return w, values
+	}
+
+	_, downmixed := compute()
+	_, left := compute(Channel(0))
+	_, right := compute(Channel(1))
+
+	var tests = []struct {
+		mode   ChannelMode
+		values []float64
+	}{
+		{Downmix, downmixed},
+		{Left, left},
+		{Right, right},
+		{Separate, downmixed},
+	}
+
+	for i, test := range tests {
+		w, values := compute(Channel(1), Channels(test.mode))
+		if !reflect.DeepEqual(values, test.values) {
+			t.Fatalf("[%02d] unexpected values: %v != %v", i, values, test.values)
+		}
+
+		var want [][]float64
+		if test.mode == Separate {
+			want = [][]float64{left, right}
+		}
+		if got := w.ChannelValues(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("[%02d] unexpected channel values: %v != %v", i, got, want)
+		}
+	}
+}
+
+// TestGenerateChannelsSeparate verifies that Generate draws the waveform of
+// each channel below the previous channel in the Separate mode, and that each
+// channel is drawn as if it were drawn alone.
+func TestGenerateChannelsSeparate(t *testing.T) {
+	// Left channel is loud, right channel is silent
+	stereo := make([]float64, 800)
+	for i := 0; i < len(stereo); i += 2 {
+		stereo[i] = 0.8
+	}
+	mono := make([]float64, 400)
+	for i := range mono {
+		mono[i] = 0.8
+	}
+
+	img, err := GenerateFromSamples(stereo, 100, 2, Channels(Separate), MinMaxEnvelope())
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, err := GenerateFromSamples(mono, 100, 1, MinMaxEnvelope())
+	if err != nil {
+		t.Fatal(err)
+	}
+	right, err := GenerateFromSamples(make([]float64, 400), 100, 1, MinMaxEnvelope())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lb := left.Bounds()
+	if img.Bounds() != image.Rect(0, 0, lb.Dx(), 2*lb.Dy()) {
+		t.Fatalf("unexpected image bounds: %v", img.Bounds())
+	}
+
+	for y := 0; y < lb.Dy(); y++ {
+		for x := 0; x < lb.Dx(); x++ {
+			if a, b := img.At(x, y), left.At(x, y); !reflect.DeepEqual(a, b) {
+				t.Fatalf("unexpected left channel color at (%d, %d): %v != %v", x, y, a, b)
+			}
+			if a, b := img.At(x, lb.Dy()+y), right.At(x, y); !reflect.DeepEqual(a, b) {
+				t.Fatalf("unexpected right channel color at (%d, %d): %v != %v", x, y, a, b)
+			}
+		}
+	}
+}
+
+// TestGenerateStereoSplit verifies that Generate draws the left channel above
+// the center of the image, and the right channel below it, using separate
+// ColorFuncs, when StereoSplit is set.
+func TestGenerateStereoSplit(t *testing.T) {
+	// Left channel is loud, right channel is quiet
+	stereo := make([]float64, 800)
+	for i := 0; i < len(stereo); i += 2 {
+		stereo[i] = 0.8
+		stereo[i+1] = 0.1
+	}
+
+	img, err := GenerateFromSamples(stereo, 100, 2, StereoSplit(SolidColor(red), SolidColor(blue)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b != image.Rect(0, 0, 4, imgYDefault) {
+		t.Fatalf("unexpected image bounds: %v", b)
+	}
+
+	// The left channel fills the upper half, and the right channel extends 19
+	// pixels below the center
+	for y := 0; y < imgYDefault; y++ {
+		want := white
+		switch {
+		case y < imgYDefault/2:
+			want = red
+		case y < imgYDefault/2+19:
+			want = blue
+		}
+
+		if c := img.At(0, y); c != want {
+			t.Fatalf("unexpected color at y=%d: %v != %v", y, c, want)
+		}
+	}
+}
@@ -1,24 +1,38 @@
-// Command waveform is a simple utility which reads an audio file from stdin,
-// processes it into a waveform image using input flags, and writes a PNG image
-// of the generated waveform to stdout.
+// Command waveform is a simple utility which reads audio files from JSON
+// requests on stdin, processes them into waveform images using input flags,
+// and writes JSON responses containing the base64-encoded images to stdout.
+//
+// The tiles subcommand instead reads a single audio file, and writes a
+// directory of waveform tiles at multiple zoom levels.
 package main
 
 import (
 	"bufio"
 	"flag"
 	"fmt"
-	"image/color"
 	"log"
 	"os"
-	"strconv"
 
 	"bytes"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"image"
+	"image/color"
 	"io"
+	"io/ioutil"
+
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/mdlayher/waveform"
-	"golang.org/x/image/tiff"
 )
 
 type Request struct {
@@ -36,6 +50,17 @@ type Response struct {
 	Id string `json:"id"`
 	Result string `json:"result"`
 	Error string `json:"error"`
+	Info *Info `json:"info,omitempty"`
+}
+
+// Info describes the properties of an input audio stream, returned in place
+// of an image when -info is set
+type Info struct {
+	SampleRate int `json:"sampleRate"`
+	Channels int `json:"channels"`
+	BitDepth int `json:"bitDepth"`
+	Samples int64 `json:"samples"`
+	Duration float64 `json:"duration"`
 }
 
 type Responses struct
@@ -43,31 +68,76 @@ type Responses struct
 	Responses []Response `json:"responses"`
 }
 
+// TileIndex describes a directory of waveform tiles written by the tiles
+// subcommand, where each tile is stored at zoom/index.ext
+type TileIndex struct {
+	TileWidth uint `json:"tileWidth"`
+	Ext string `json:"ext"`
+	Tiles []int `json:"tiles"`
+}
+
 const (
 	// app is the name of this application
 	app = "waveform"
 
+	// cmdTiles is the name of the subcommand which writes a directory of tiles
+	cmdTiles = "tiles"
+
 	// Names of available color functions
+	fnAmplitude = "amplitude"
 	fnChecker  = "checker"
 	fnFuzz     = "fuzz"
 	fnGradient = "gradient"
+	fnLevel    = "level"
 	fnSolid    = "solid"
 	fnStripe   = "stripe"
+
+	// Names of available spectrogram colormaps
+	colormapGray = "gray"
+	colormapHeat = "heat"
+
+	// Names of available drawing styles
+	styleArea = "area"
+	styleBars = "bars"
+	styleLine = "line"
+
+	// Names of available sample reduce functions
+	reduceMean   = "mean"
+	reduceMedian = "median"
+	reducePeak   = "peak"
+	reduceRMS    = "rms"
+
+	// Names of available output formats
+	formatImage     = "image"
+	formatPeaksJSON = "peaks-json"
+	formatPeaksDAT  = "peaks-dat"
 )
 
 var (
 	// strBGColor is the hex color value used to color the background of the waveform image
 	strBGColor = flag.String("bg", "#FFFFFF", "hex background color of output waveform image")
 
+	// transparent indicates that the background of the waveform image is
+	// transparent, in place of the background color
+	transparent = flag.Bool("transparent", false, "draw a transparent background, ignoring -bg, for compositing over other artwork")
+
 	// strFGColor is the hex color value used to color the foreground of the waveform image
 	strFGColor = flag.String("fg", "#000000", "hex foreground color of output waveform image")
 
 	// strAltColor is the hex color value used to set the alternate color of the waveform image
-	strAltColor = flag.String("alt", "", "hex alternate color of output waveform image")
+	strAltColor = flag.String("alt", "", "hex alternate color of output waveform image (default: foreground color)")
+
+	// strRMSColor is the hex color value used to draw the RMS envelope over the
+	// peak envelope of the waveform image
+	strRMSColor = flag.String("rms", "", "hex color of RMS envelope drawn over peak envelope, which is drawn using -fn (default: no RMS envelope)")
 
 	// resolution is the number of times audio is read and the waveform is drawn,
 	// per second of audio
-	resolution = flag.Uint("resolution", 1, "number of times audio is read and drawn per second of audio")
+	resolution = flag.Float64("resolution", 1, "number of times audio is read and drawn per second of audio, which may be fractional, such as 0.5")
+
+	// sliceSamples is the exact number of samples of input audio from which
+	// each value is computed, replacing the resolution if set
+	sliceSamples = flag.Uint("slicesamples", 0, "number of samples of input audio drawn as each value, replacing -resolution (0 uses -resolution)")
 
 	// scaleX is the scaling factor for the output waveform file's X-axis
 	scaleX = flag.Uint("x", 1, "scaling factor for image X-axis")
@@ -75,49 +145,202 @@ var (
 	// scaleY is the scaling factor for the output waveform file's Y-axis
 	scaleY = flag.Uint("y", 1, "scaling factor for image Y-axis")
 
+	// width and height are the exact dimensions of the output waveform image,
+	// in place of dimensions derived from the duration of input audio
+	width  = flag.Uint("width", 0, "exact width of output waveform image, resampling computed values to fit (requires -height)")
+	height = flag.Uint("height", 0, "exact height of output waveform image (requires -width)")
+
+	// maxWidth is the maximum width of output waveform images, which merges
+	// computed values into fewer bars if they would otherwise be wider
+	maxWidth = flag.Uint("maxwidth", 0, "maximum width of output waveform image, merging computed values to fit (0 disables)")
+
 	// sharpness is the factor used to add curvature to a scaled image, preventing
 	// "blocky" images at higher scaling
-	sharpness = flag.Uint("sharpness", 1, "sharpening factor used to add curvature to a scaled image")
+	sharpness = flag.Uint("sharpness", 1, "sharpening factor used to add curvature to a scaled image (0 disables)")
+
+	// antiAlias is the supersampling factor used to smooth the edges of the
+	// waveform
+	antiAlias = flag.Uint("antialias", 1, "supersampling factor used to smooth edges of output waveform image, from 1 to 8 (1 disables)")
+
+	// barWidth is the width of each bar drawn for a computed value, before
+	// scaling on the X-axis
+	barWidth = flag.Uint("barwidth", 1, "width of each bar of output waveform image, before X-axis scaling")
+
+	// barGap is the width of the gap following each bar, before scaling on
+	// the X-axis
+	barGap = flag.Uint("bargap", 0, "width of gap between bars of output waveform image, before X-axis scaling")
+
+	// barRadius is the radius used to round the corners of each bar, after
+	// scaling on the X-axis
+	barRadius = flag.Uint("radius", 0, "radius of rounded corners of bars of output waveform image (0 draws square corners)")
+
+	// minMax indicates that the minimum and maximum samples of each slice of
+	// audio are drawn, rather than a computed value mirrored about the center
+	minMax = flag.Bool("minmax", false, "draw minimum and maximum samples of each slice of audio, rather than a symmetrical computed value")
+
+	// normalize indicates that values are scaled so that the peak of the
+	// input audio reaches the full height of the image
+	normalize = flag.Bool("normalize", false, "scale output waveform image so that the peak of input audio reaches its full height")
+
+	// strReduce is an identifier which selects the SampleReduceFunc used to
+	// compute each value from a slice of audio
+	strReduce = flag.String("reduce", reduceRMS, "function used to reduce each slice of input audio to a single value "+reduceOptions)
+
+	// stereo indicates that the left channel is drawn above the center of the
+	// image, and the right channel below it
+	stereo = flag.Bool("stereo", false, "draw left channel of input audio above center, colored by -fg, and right channel below, colored by -alt")
+
+	// strStyle is an identifier which selects the DrawStyle of the waveform image
+	strStyle = flag.String("style", styleBars, "style used to draw output waveform image, filled or outlined "+styleOptions)
+
+	// spectrogram indicates that a spectrogram of input audio is drawn, rather
+	// than a waveform
+	spectrogram = flag.Bool("spectrogram", false, "draw a frequency spectrogram of input audio, colored by -colormap, rather than a waveform")
+
+	// fftSize is the number of samples in each window used to compute the
+	// frequency spectrum of a spectrogram
+	fftSize = flag.Uint("fftsize", 1024, "FFT window size of spectrogram, a power of two from 16 to 65536")
+
+	// strColormap is an identifier which selects the Colormap used to color
+	// a spectrogram image
+	strColormap = flag.String("colormap", colormapHeat, "colormap used to color output spectrogram image "+colormapOptions)
 
 	// strFn is an identifier which selects the ColorFunc used to color the waveform image
 	strFn = flag.String("fn", fnSolid, "function used to color output waveform image "+fnOptions)
+
+	// raw indicates that input audio is raw, little-endian, signed integer PCM
+	// samples, described by the rate, bits, and channels flags
+	raw = flag.Bool("raw", false, "treat input audio as raw little-endian PCM samples, described by -rate, -bits, and -channels")
+
+	// rate is the sample rate of raw input audio
+	rate = flag.Int("rate", 44100, "sample rate of raw input audio")
+
+	// bits is the bit depth of raw input audio
+	bits = flag.Uint("bits", 16, "bit depth of raw input audio [options: 8, 16, 24, 32]")
+
+	// channels is the number of channels of raw input audio
+	channels = flag.Int("channels", 2, "number of channels of raw input audio")
+
+	// info indicates that the properties of input audio are returned as JSON,
+	// rather than a waveform image
+	info = flag.Bool("info", false, "return sample rate, channels, bit depth, and duration of input audio as JSON, instead of an image")
+
+	// track is the zero-based index of the audio track decoded from an MKV,
+	// WebM, or MP4 container
+	track = flag.Int("track", 0, "zero-based index of the audio track of MKV, WebM, or MP4 input audio")
+
+	// execName is the name of an external decoder command, such as ffmpeg,
+	// used to convert input audio of an unsupported format to WAV
+	execName = flag.String("exec", "", "external decoder, such as ffmpeg, used to convert input audio of an unsupported format to WAV")
+
+	// start and duration select a window of input audio, which is drawn in
+	// place of the entire stream
+	start    = flag.Duration("start", 0, "offset of the window of input audio which is drawn, such as 1m30s")
+	duration = flag.Duration("duration", 0, "duration of the window of input audio which is drawn (0 draws to the end)")
+
+	// strURL is the URL of remote input audio, which is read in place of
+	// requests from stdin
+	strURL = flag.String("url", "", "http(s):// or s3://bucket/key URL of input audio, read instead of stdin")
+
+	// encode is the name of the image format used to encode output waveform images
+	encode = flag.String("encode", waveform.FormatTIFF, "image format of output waveform image "+encodeOptions)
+
+	// outDir is the directory to which the tiles subcommand writes tiles
+	outDir = flag.String("out", "tiles", "directory to which tiles are written by the tiles subcommand")
+
+	// tileWidth is the width of each tile written by the tiles subcommand
+	tileWidth = flag.Uint("tilewidth", 256, "width of each tile written by the tiles subcommand")
+
+	// quality is the quality of output waveform images encoded in a lossy format
+	quality = flag.Uint("quality", 75, "quality of output waveform image encoded as jpeg or webp-lossy, from 1 to 100")
+
+	// format is the name of the output format, either an image, or peaks
+	// read by web waveform players
+	format = flag.String("format", formatImage, "output format, a waveform image encoded by -encode, or peaks for web players "+formatOptions)
+
+	// samplesPerPixel is the number of samples of input audio spanned by
+	// each pixel of output peaks
+	samplesPerPixel = flag.Uint("spp", 256, "number of samples of input audio per pixel of output peaks")
+
+	// peakBits is the bit precision of output peaks
+	peakBits = flag.Uint("peakbits", 16, "bit precision of output peaks [options: 8, 16]")
+
+	// datVersion is the version of the audiowaveform binary format of output
+	// peaks
+	datVersion = flag.Int("datversion", 2, "version of the audiowaveform .dat format of output peaks [options: 1, 2]")
 )
 
 // fnOptions is the help string which lists available options
-var fnOptions = fmt.Sprintf("[options: %s, %s, %s, %s, %s]", fnChecker, fnFuzz, fnGradient, fnSolid, fnStripe)
+var fnOptions = fmt.Sprintf("[options: %s, %s, %s, %s, %s, %s, %s]", fnAmplitude, fnChecker, fnFuzz, fnGradient, fnLevel, fnSolid, fnStripe)
+
+// colormapOptions is the help string which lists available colormaps
+var colormapOptions = fmt.Sprintf("[options: %s, %s]", colormapGray, colormapHeat)
+
+// styleOptions is the help string which lists available drawing styles
+var styleOptions = fmt.Sprintf("[options: %s, %s, %s]", styleArea, styleBars, styleLine)
+
+// reduceOptions is the help string which lists available sample reduce functions
+var reduceOptions = fmt.Sprintf("[options: %s, %s, %s, %s]", reduceMean, reduceMedian, reducePeak, reduceRMS)
+
+// formatOptions is the help string which lists available output formats
+var formatOptions = fmt.Sprintf("[options: %s, %s, %s]", formatImage, formatPeaksDAT, formatPeaksJSON)
+
+// encodeOptions is the help string which lists available image formats
+var encodeOptions = fmt.Sprintf("[options: %s, %s, %s, %s, %s, %s]", waveform.FormatPNG, waveform.FormatTIFF, waveform.FormatJPEG, waveform.FormatBMP, waveform.FormatWebP, waveform.FormatWebPLossy)
 
 func main() {
-	// Parse flags
-	flag.Parse()
+	// Parse flags, following the tiles subcommand, if it is named
+	tiles := len(os.Args) > 1 && os.Args[1] == cmdTiles
+	if tiles {
+		flag.CommandLine.Parse(os.Args[2:])
+	} else {
+		flag.Parse()
+	}
 
 	// Move all logging output to stderr, as output image will occupy
 	// the stdout stream
 	//log.SetOutput(os.Stderr)
 	log.SetPrefix(app + ": ")
 
-	// Create image background color from input hex color string, or default
-	// to black if invalid
-	colorR, colorG, colorB := hexToRGB(*strBGColor)
-	bgColor := color.RGBA{colorR, colorG, colorB, 255}
+	// Create image background color from input hex color string
+	bgColor, err := waveform.ParseHexColor(*strBGColor)
+	if err != nil {
+		log.Fatalf("-bg: %v", err)
+	}
 
-	// Create image foreground color from input hex color string, or default
-	// to black if invalid
-	colorR, colorG, colorB = hexToRGB(*strFGColor)
-	fgColor := color.RGBA{colorR, colorG, colorB, 255}
+	// Create image foreground color from input hex color string
+	fgColor, err := waveform.ParseHexColor(*strFGColor)
+	if err != nil {
+		log.Fatalf("-fg: %v", err)
+	}
 
 	// Create image alternate color from input hex color string, or default
-	// to foreground color if empty
+	// to foreground color if unset.  An alternate color which is set to be
+	// fully transparent is honored, rather than replaced.
 	altColor := fgColor
 	if *strAltColor != "" {
-		colorR, colorG, colorB = hexToRGB(*strAltColor)
-		altColor = color.RGBA{colorR, colorG, colorB, 255}
+		altColor, err = waveform.ParseHexColor(*strAltColor)
+		if err != nil {
+			log.Fatalf("-alt: %v", err)
+		}
+	}
+
+	// Create image RMS envelope color from input hex color string, if set
+	var rmsColor color.Color
+	if *strRMSColor != "" {
+		rmsColor, err = waveform.ParseHexColor(*strRMSColor)
+		if err != nil {
+			log.Fatalf("-rms: %v", err)
+		}
 	}
 
 	// Set of available functions
 	fnSet := map[string]waveform.ColorFunc{
+		fnAmplitude: waveform.AmplitudeColor(fgColor, altColor),
 		fnChecker:  waveform.CheckerColor(fgColor, altColor, 10),
 		fnFuzz:     waveform.FuzzColor(fgColor, altColor),
 		fnGradient: waveform.GradientColor(fgColor, altColor),
+		fnLevel:    waveform.LevelColor(fgColor, altColor),
 		fnSolid:    waveform.SolidColor(fgColor),
 		fnStripe:   waveform.StripeColor(fgColor, altColor),
 	}
@@ -128,6 +351,185 @@ func main() {
 		log.Fatalf("unknown function: %q %s", *strFn, fnOptions)
 	}	
 
+	// Set of available colormaps
+	colormapSet := map[string]waveform.Colormap{
+		colormapGray: waveform.GrayColormap,
+		colormapHeat: waveform.HeatColormap,
+	}
+
+	// Validate user-selected colormap
+	colormap, ok := colormapSet[*strColormap]
+	if !ok {
+		log.Fatalf("unknown colormap: %q %s", *strColormap, colormapOptions)
+	}
+
+	// Set of available drawing styles
+	styleSet := map[string]waveform.DrawStyle{
+		styleArea: waveform.AreaFill,
+		styleBars: waveform.Bars,
+		styleLine: waveform.Line,
+	}
+
+	// Validate user-selected drawing style
+	style, ok := styleSet[*strStyle]
+	if !ok {
+		log.Fatalf("unknown style: %q %s", *strStyle, styleOptions)
+	}
+
+	// Set of available sample reduce functions
+	reduceSet := map[string]waveform.SampleReduceFunc{
+		reduceMean:   waveform.MeanF64Samples,
+		reduceMedian: waveform.MedianF64Samples,
+		reducePeak:   waveform.PeakF64Samples,
+		reduceRMS:    waveform.RMSF64Samples,
+	}
+
+	// Validate user-selected sample reduce function
+	reduceFn, ok := reduceSet[*strReduce]
+	if !ok {
+		log.Fatalf("unknown reduce function: %q %s", *strReduce, reduceOptions)
+	}
+
+	// Validate user-selected image format, which may not be available in
+	// the current build
+	if err := waveform.EncodeTo(ioutil.Discard, *encode, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		log.Fatalf("-encode: %v: %q %s", err, *encode, encodeOptions)
+	}
+
+	// Validate user-selected output format, and the precision of any peaks
+	switch *format {
+	case formatImage:
+	case formatPeaksJSON, formatPeaksDAT:
+		if err := (&waveform.Peaks{}).WriteJSON(ioutil.Discard, *peakBits); err != nil {
+			log.Fatalf("-peakbits: %v", err)
+		}
+		if err := (&waveform.Peaks{}).WriteDAT(ioutil.Discard, 16, *datVersion); err != nil {
+			log.Fatalf("-datversion: %v", err)
+		}
+		if *samplesPerPixel == 0 {
+			log.Fatalf("-spp: %v", waveform.ErrSamplesPerPixelZero)
+		}
+	default:
+		log.Fatalf("unknown format: %q %s", *format, formatOptions)
+	}
+
+	// Options used to generate each waveform image, from values passed in flags
+	options := []waveform.OptionsFunc{
+		waveform.BGColorFunction(waveform.SolidColor(bgColor)),
+		waveform.FGColorFunction(colorFn),
+		waveform.Resolution(*resolution),
+		waveform.SampleFunction(reduceFn),
+		waveform.Scale(*scaleX, *scaleY),
+		waveform.ScaleClipping(),
+		waveform.Sharpness(*sharpness),
+		waveform.AntiAlias(*antiAlias),
+		waveform.DiscreteBars(*barWidth, *barGap),
+		waveform.BarRadius(*barRadius),
+		waveform.Style(style),
+		waveform.Quality(*quality),
+		waveform.FFTSize(*fftSize),
+		waveform.SpectrogramColormap(colormap),
+		waveform.TileWidth(*tileWidth),
+	}
+
+	// Draw the extent between the minimum and maximum samples, which is
+	// filled, or outlined by the line style
+	if *minMax {
+		options = append(options, waveform.MinMaxEnvelope())
+	}
+
+	// Split the left and right channels about the center, if requested
+	if *stereo {
+		options = append(options, waveform.StereoSplit(waveform.SolidColor(fgColor), waveform.SolidColor(altColor)))
+	}
+
+	// Scale quiet and loud audio to the same height, if requested
+	if *normalize {
+		options = append(options, waveform.Normalize())
+	}
+
+	// Compute each value from an exact number of samples, if requested
+	if *sliceSamples > 0 {
+		options = append(options, waveform.SliceSamples(*sliceSamples))
+	}
+
+	// Resize the image to exact dimensions, if requested
+	if *width > 0 || *height > 0 {
+		options = append(options, waveform.Dimensions(*width, *height))
+	}
+
+	// Limit the width of the image, however long the input audio, if requested
+	if *maxWidth > 0 {
+		options = append(options, waveform.MaxWidth(*maxWidth))
+	}
+
+	// Replace the background color, if requested
+	if *transparent {
+		options = append(options, waveform.TransparentBackground())
+	}
+
+	// Draw the RMS envelope over the peak envelope, which is colored by the
+	// selected function
+	if rmsColor != nil {
+		options = append(options, waveform.DualEnvelope(colorFn, waveform.SolidColor(rmsColor)))
+	}
+
+	// Skip format detection for raw PCM input
+	if *raw {
+		options = append(options, waveform.RawPCM(*rate, *bits, *channels, binary.LittleEndian))
+	}
+
+	// Select an audio track other than the first
+	if *track != 0 {
+		options = append(options, waveform.TrackIndex(*track))
+	}
+
+	// Draw only a window of the input audio, if requested
+	if *start > 0 || *duration > 0 {
+		options = append(options, waveform.Window(*start, *duration))
+	}
+
+	// Fall back to an external decoder for unsupported formats
+	if *execName != "" {
+		options = append(options, waveform.ExecDecoder(*execName))
+	}
+
+	// Validate all options before reading any input
+	if _, err := waveform.New(nil, options...); err != nil {
+		log.Fatal(err)
+	}
+
+	// Write a directory of tiles from a single input audio file, read from a
+	// remote URL or from stdin
+	if tiles {
+		var r io.Reader = bufio.NewReader(os.Stdin)
+		if *strURL != "" {
+			body, err := openURL(*strURL)
+			if err != nil {
+				log.Fatalf("-url: %v", err)
+			}
+			defer body.Close()
+			r = bufio.NewReader(body)
+		}
+
+		if err := writeTiles(r, options); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Stream input audio from a remote URL, rather than from requests on stdin
+	if *strURL != "" {
+		body, err := openURL(*strURL)
+		if err != nil {
+			log.Fatalf("-url: %v", err)
+		}
+
+		respond(*strURL, bufio.NewReader(body), options)
+		body.Close()
+		return
+	}
+
 	reader := bufio.NewReader(os.Stdin)
 	var buf bytes.Buffer
 	for {
@@ -147,52 +549,11 @@ func main() {
 					if request.Function == "waveform" {
 						unbased, err := base64.StdEncoding.DecodeString(request.Params[0])
 
-						flacReader := bytes.NewReader(unbased)
-
-						// Generate a waveform image from stdin, using values passed from
-						// flags as options
-						img, err := waveform.Generate(flacReader,
-							waveform.BGColorFunction(waveform.SolidColor(bgColor)),
-							waveform.FGColorFunction(colorFn),
-							waveform.Resolution(*resolution),
-							waveform.Scale(*scaleX, *scaleY),
-							waveform.ScaleClipping(),
-							waveform.Sharpness(*sharpness),
-						)
 						if err != nil {
-							// Set of known errors
-							knownErr := map[error]struct{}{
-								waveform.ErrFormat:        struct{}{},
-								waveform.ErrInvalidData:   struct{}{},
-								waveform.ErrUnexpectedEOS: struct{}{},
-							}
-
-							// On known error, fatal log
-							if _, ok := knownErr[err]; ok {
-								log.Fatal(err)
-							}
-
-							// Unknown errors, panic
-							panic(err)
-						}
-
-						// In-memory buffer to store TIFF image
-						// before we base 64 encode it
-						var buff bytes.Buffer
-
-						// Encode results as TIFF to temp buffer
-						if err := tiff.Encode(&buff, img, nil); err != nil {
-							panic(err)
+							log.Fatal(err)
 						}
 
-						// Encode the bytes in the buffer to a base64 string
-						encodedString := base64.StdEncoding.EncodeToString(buff.Bytes())
-
-						responses := Responses{[]Response{Response{request.Id, encodedString, "false"}}}
-
-						b, err := json.Marshal(responses)
-						
-						fmt.Println(string(b))
+						respond(request.Id, bytes.NewReader(unbased), options)
 					}
 				}
 				
@@ -208,19 +569,286 @@ func main() {
 	}
 }
 
-// hexToRGB converts a hex string to a RGB triple.
-// Credit: https://code.google.com/p/gorilla/source/browse/color/hex.go?r=ef489f63418265a7249b1d53bdc358b09a4a2ea0
-func hexToRGB(h string) (uint8, uint8, uint8) {
-	if len(h) > 0 && h[0] == '#' {
-		h = h[1:]
+// respond generates a waveform image, or reports the properties of the input
+// audio if -info is set, from the audio stream r, and writes the response for
+// the request with the input ID to stdout
+func respond(id string, r io.Reader, options []waveform.OptionsFunc) {
+	// Report properties of the input audio only, if requested
+	if *info {
+		si, err := waveform.Info(r, options...)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		responses := Responses{[]Response{Response{Id: id, Error: "false", Info: &Info{
+			SampleRate: si.SampleRate,
+			Channels: si.Channels,
+			BitDepth: si.BitDepth,
+			Samples: si.Samples,
+			Duration: si.Duration.Seconds(),
+		}}}}
+
+		b, err := json.Marshal(responses)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println(string(b))
+		return
+	}
+
+	// In-memory buffer to store the encoded image
+	// before we base 64 encode it
+	var buff bytes.Buffer
+
+	// Generate a waveform or spectrogram image from the input stream, using
+	// values passed from flags as options, and encode it in the selected
+	// format to temp buffer
+	if err := generate(&buff, r, options); err != nil {
+		// Set of known errors
+		knownErr := map[error]struct{}{
+			waveform.ErrFormat:        struct{}{},
+			waveform.ErrInvalidData:   struct{}{},
+			waveform.ErrUnexpectedEOS: struct{}{},
+		}
+
+		// On known error, fatal log
+		if _, ok := knownErr[err]; ok {
+			log.Fatal(err)
+		}
+
+		// Unknown errors, panic
+		panic(err)
 	}
-	if len(h) == 3 {
-		h = h[:1] + h[:1] + h[1:2] + h[1:2] + h[2:] + h[2:]
+
+	// Encode the bytes in the buffer to a base64 string
+	encodedString := base64.StdEncoding.EncodeToString(buff.Bytes())
+
+	responses := Responses{[]Response{Response{Id: id, Result: encodedString, Error: "false"}}}
+
+	b, err := json.Marshal(responses)
+	if err != nil {
+		log.Fatal(err)
 	}
-	if len(h) == 6 {
-		if rgb, err := strconv.ParseUint(string(h), 16, 32); err == nil {
-			return uint8(rgb >> 16), uint8((rgb >> 8) & 0xFF), uint8(rgb & 0xFF)
+
+	fmt.Println(string(b))
+}
+
+// generate generates a waveform image, or a spectrogram image if -spectrogram
+// is set, from the audio stream r, and encodes it in the selected format to out.
+// If -format selects peaks, the peaks of the audio are written instead
+func generate(out io.Writer, r io.Reader, options []waveform.OptionsFunc) error {
+	if *format == formatPeaksJSON || *format == formatPeaksDAT {
+		p, err := waveform.ComputePeaks(r, *samplesPerPixel, options...)
+		if err != nil {
+			return err
+		}
+
+		if *format == formatPeaksDAT {
+			return p.WriteDAT(out, *peakBits, *datVersion)
 		}
+
+		return p.WriteJSON(out, *peakBits)
+	}
+
+	if !*spectrogram {
+		return waveform.GenerateTo(out, *encode, r, options...)
 	}
-	return 0, 0, 0
-}
\ No newline at end of file
+
+	img, err := waveform.GenerateSpectrogram(r, options...)
+	if err != nil {
+		return err
+	}
+
+	return waveform.EncodeTo(out, *encode, img)
+}
+
+// writeTiles draws a pyramid of waveform tiles from the audio stream r, and
+// writes each tile, encoded in the selected format, to the -out directory,
+// along with an index.json file which describes the tiles
+func writeTiles(r io.Reader, options []waveform.OptionsFunc) error {
+	index := TileIndex{TileWidth: *tileWidth, Ext: strings.TrimSuffix(*encode, "-lossy")}
+
+	err := waveform.GenerateTiles(r, func(t waveform.Tile) error {
+		if t.Zoom == len(index.Tiles) {
+			index.Tiles = append(index.Tiles, 0)
+		}
+		index.Tiles[t.Zoom]++
+
+		path := filepath.Join(*outDir, filepath.FromSlash(t.Name(index.Ext)))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := waveform.EncodeTo(f, *encode, t.Image); err != nil {
+			f.Close()
+			return err
+		}
+
+		return f.Close()
+	}, options...)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(*outDir, "index.json"), b, 0644)
+}
+
+// openURL opens the remote audio stream at an http(s):// URL, or at an
+// s3://bucket/key URL, signed using AWS credentials from the environment
+func openURL(rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var req *http.Request
+	switch u.Scheme {
+	case "http", "https":
+		req, err = http.NewRequest(http.MethodGet, u.String(), nil)
+	case "s3":
+		req, err = newS3Request(u, time.Now())
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme: %q [options: http, https, s3]", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", rawURL, res.Status)
+	}
+
+	return res.Body, nil
+}
+
+// newS3Request creates a request for the object at an s3://bucket/key URL,
+// signed with AWS Signature Version 4.  Credentials are read from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and optional AWS_SESSION_TOKEN
+// environment variables, and the region from AWS_REGION, AWS_DEFAULT_REGION,
+// or us-east-1 if neither is set
+func newS3Request(u *url.URL, now time.Time) (*http.Request, error) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("s3: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("s3: URL must be of the form s3://bucket/key: %q", u.String())
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	// Buckets with dots in their names do not match the wildcard certificate
+	// of virtual-hosted URLs, so they are addressed using path-style URLs
+	host := u.Host + ".s3." + region + ".amazonaws.com"
+	path := s3Escape(u.Path)
+	if strings.Contains(u.Host, ".") {
+		host = "s3." + region + ".amazonaws.com"
+		path = "/" + u.Host + path
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://"+host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	signS3Request(req, region, accessKey, secretKey, os.Getenv("AWS_SESSION_TOKEN"), now)
+	return req, nil
+}
+
+// signS3Request signs a GET request to S3 with AWS Signature Version 4, using
+// the input credentials and an optional session token.  The host, Range, and
+// x-amz-* headers of the request are signed
+func signS3Request(req *http.Request, region, accessKey, secretKey, token string, now time.Time) {
+	// The body of a GET request is always empty
+	emptyHash := sha256.Sum256(nil)
+	payload := hex.EncodeToString(emptyHash[:])
+
+	date := now.UTC().Format("20060102T150405Z")
+	scope := date[:8] + "/" + region + "/s3/aws4_request"
+
+	req.Header.Set("x-amz-content-sha256", payload)
+	req.Header.Set("x-amz-date", date)
+	if token != "" {
+		req.Header.Set("x-amz-security-token", token)
+	}
+
+	// Canonical headers are lowercase and sorted by name
+	values := map[string]string{"host": req.URL.Host}
+	names := []string{"host"}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if k == "range" || strings.HasPrefix(k, "x-amz-") {
+			values[k] = strings.TrimSpace(strings.Join(v, ","))
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var headers string
+	for _, k := range names {
+		headers += k + ":" + values[k] + "\n"
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{http.MethodGet, req.URL.EscapedPath(), req.URL.RawQuery, headers, signed, payload}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + date + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	// Derive the signing key from the secret key and each part of the scope
+	key := []byte("AWS4" + secretKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign)),
+	))
+}
+
+// s3Escape percent-encodes all bytes of an object path, other than slashes and
+// unreserved characters, as required by the canonical request of a signature
+func s3Escape(path string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+
+	return buf.String()
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data using the input key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
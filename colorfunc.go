@@ -1,8 +1,12 @@
 package waveform
 
 import (
+	"fmt"
 	"image/color"
+	"math"
 	"math/rand"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -14,12 +18,112 @@ import (
 // A ColorFunc is applied during each image drawing iteration, and will
 // return the appropriate color which should be drawn at the specified value
 // for n, x, and y; possibly taking into account their maximum values.
+//
+// All ColorFunc generated by this package are safe for concurrent use, so the
+// same ColorFunc may be used by multiple goroutines generating images at once.
+// Custom ColorFunc used concurrently must not modify shared state without
+// synchronization.
 type ColorFunc func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color
 
+// AmplitudeColor generates a ColorFunc which applies one color from the input,
+// variadic palette to each column of the waveform, selected by the value drawn
+// in that column, rather than by its position.  The palette is ordered from
+// quietest to loudest: values are divided evenly between its colors, from 0
+// (silent) to 1 (full scale), so louder sections of audio stand out.
+//
+// Values are those which are drawn, after any amplitude transforms, such as
+// NoiseFloor, have been applied.  When the ColorFunc is evaluated without a
+// value, such as by BlendColor or DrawSVG, the first color is used.
+func AmplitudeColor(palette ...color.Color) ColorFunc {
+	// Filter any nil values
+	palette = filterNilColors(palette)
+	c := &amplitudeColor{palette: palette}
+
+	// The color is selected when drawn, once the value of its column is known
+	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
+		return c
+	}
+}
+
+// amplitudeColor is a color produced by AmplitudeColor, which is selected from
+// a palette by the value drawn in its column.
+type amplitudeColor struct {
+	palette []color.Color
+}
+
+// RGBA returns the alpha-premultiplied components of the first color of the
+// palette, used when no value is available.
+func (c *amplitudeColor) RGBA() (uint32, uint32, uint32, uint32) {
+	return c.palette[0].RGBA()
+}
+
+// at returns the color of the palette selected by value v.
+func (c *amplitudeColor) at(v float64) color.Color {
+	i := int(clampUnit(v) * float64(len(c.palette)))
+	if i >= len(c.palette) {
+		i = len(c.palette) - 1
+	}
+
+	return c.palette[i]
+}
+
+// resolveAmplitude returns the color of the palette selected by value v, if c
+// is produced by AmplitudeColor, or c otherwise.
+func resolveAmplitude(c color.Color, v float64) color.Color {
+	if ac, ok := c.(*amplitudeColor); ok {
+		return ac.at(v)
+	}
+
+	return c
+}
+
+// BlendColor generates a ColorFunc which evaluates two input ColorFunc at
+// each pixel, and linearly blends the resulting colors by alpha.  An alpha of 0
+// produces only the colors of colorA, and an alpha of 1 produces only the
+// colors of colorB.  Alpha values outside of [0, 1] are clamped.
+//
+// This can be used to layer effects, such as applying a FuzzColor texture
+// over a GradientColor.
+func BlendColor(colorA ColorFunc, colorB ColorFunc, alpha float64) ColorFunc {
+	if alpha < 0 {
+		alpha = 0
+	}
+	if alpha > 1 {
+		alpha = 1
+	}
+
+	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
+		r1, g1, b1, a1 := colorA(n, x, y, maxN, maxX, maxY).RGBA()
+		r2, g2, b2, a2 := colorB(n, x, y, maxN, maxX, maxY).RGBA()
+
+		// Blend alpha-premultiplied components, rounding to nearest
+		blend := func(c1 uint32, c2 uint32) uint16 {
+			return uint16(float64(c1)*(1-alpha) + float64(c2)*alpha + 0.5)
+		}
+
+		return color.RGBA64{
+			R: blend(r1, r2),
+			G: blend(g1, g2),
+			B: blend(b1, b2),
+			A: blend(a1, a2),
+		}
+	}
+}
+
 // CheckerColor generates a ColorFunc which produces a checkerboard pattern,
 // using the two input colors.  Each square is drawn to the size specified by
 // the size parameter.
+//
+// The size parameter is measured in pixels of the final output image, after
+// any scaling has been applied.  The grid is computed from output coordinates
+// only, so squares remain square regardless of the factors passed to Scale.
+// A size of 0 is treated as 1.
 func CheckerColor(colorA color.Color, colorB color.Color, size uint) ColorFunc {
+	// Avoid division by zero on an empty size
+	if size == 0 {
+		size = 1
+	}
+
 	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
 		if ((uint(x)/size)+(uint(y)/size))%2 == 0 {
 			return colorA
@@ -36,12 +140,18 @@ func FuzzColor(colors ...color.Color) ColorFunc {
 	// Filter any nil values
 	colors = filterNilColors(colors)
 
-	// Seed RNG
-	rand.Seed(time.Now().UnixNano())
+	// Seed an RNG owned by this ColorFunc, which is not safe for concurrent
+	// use, so it is guarded by a mutex
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var mu sync.Mutex
 
 	// Select a color at random on each call
 	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
-		return colors[rand.Intn(len(colors))]
+		mu.Lock()
+		i := rng.Intn(len(colors))
+		mu.Unlock()
+
+		return colors[i]
 	}
 }
 
@@ -49,23 +159,25 @@ func FuzzColor(colors ...color.Color) ColorFunc {
 // RGBA input colors.  The gradient attempts to gradually reduce the distance between
 // two colors, creating a sweeping color change effect in the resulting waveform
 // image.
+//
+// The alpha of each color is honored, so a gradient towards a transparent
+// color gradually fades out.  If the GammaCorrect option is set, the gradient
+// is interpolated in linear light.
 func GradientColor(start color.RGBA, end color.RGBA) ColorFunc {
 	// Float equivalents of color values
 	startFR, endFR := float64(start.R), float64(end.R)
 	startFG, endFG := float64(start.G), float64(end.G)
 	startFB, endFB := float64(start.B), float64(end.B)
 
-	// Values used for RGBA and percentage
-	var r, g, b, p float64
 	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
 		// Calculate percentage across waveform image
-		p = float64((float64(n) / float64(maxN)) * 100)
+		p := float64((float64(n) / float64(maxN)) * 100)
 
 		// Calculate new values for RGB using gradient algorithm
 		// Thanks: http://stackoverflow.com/questions/27532/generating-gradients-programmatically
-		r = (endFR * p) + (startFR * (1 - p))
-		g = (endFG * p) + (startFG * (1 - p))
-		b = (endFB * p) + (startFB * (1 - p))
+		r := (endFR * p) + (startFR * (1 - p))
+		g := (endFG * p) + (startFG * (1 - p))
+		b := (endFB * p) + (startFB * (1 - p))
 
 		// Correct overflow when moving from lighter to darker gradients
 		if start.R > end.R && r > -255.00 {
@@ -78,12 +190,93 @@ func GradientColor(start color.RGBA, end color.RGBA) ColorFunc {
 			b = -255.00
 		}
 
-		// Generate output color
-		return &color.RGBA{
-			R: uint8(r / 100),
-			G: uint8(g / 100),
-			B: uint8(b / 100),
-			A: 255,
+		// Blend alpha linearly, and keep premultiplied components within it
+		a := uint8(float64(start.A) + (float64(end.A)-float64(start.A))*p/100)
+
+		// Generate output color, and retain the inputs in case it is blended
+		// again in linear light
+		return &gradientColor{
+			srgb: color.RGBA{
+				R: minUint8(uint8(r/100), a),
+				G: minUint8(uint8(g/100), a),
+				B: minUint8(uint8(b/100), a),
+				A: a,
+			},
+			start: start,
+			end:   end,
+			p:     p / 100,
+		}
+	}
+}
+
+// gradientColor is a color produced by GradientColor or LevelColor, which is
+// blended in sRGB space, but can also be blended in linear light.
+type gradientColor struct {
+	// Color blended in sRGB space
+	srgb color.RGBA
+
+	// Input colors, and the fraction of the distance between them
+	start color.RGBA
+	end   color.RGBA
+	p     float64
+}
+
+// RGBA returns the alpha-premultiplied components of the gradient color,
+// blended in sRGB space.
+func (c *gradientColor) RGBA() (uint32, uint32, uint32, uint32) {
+	return c.srgb.RGBA()
+}
+
+// linear returns the gradient color, blended in linear light.
+func (c *gradientColor) linear() color.Color {
+	sr, sg, sb, sa := linearRGBA(c.start)
+	er, eg, eb, ea := linearRGBA(c.end)
+
+	mix := func(start float64, end float64) float64 {
+		return start + (end-start)*c.p
+	}
+
+	return fromLinearRGBA(mix(sr, er), mix(sg, eg), mix(sb, eb), mix(sa, ea))
+}
+
+// LevelColor generates a ColorFunc which produces a vertical color gradient
+// within each bar, from the low color at the center of the image, to the peak
+// color at its top and bottom edges.  The color of each pixel varies with the
+// amplitude it represents, rather than its position in time, so louder values
+// reach further into the peak color, in the style of a level meter.
+//
+// The alpha of each color is honored, and if the GammaCorrect option is set,
+// the gradient is interpolated in linear light, in the same way as
+// GradientColor.
+func LevelColor(low color.RGBA, peak color.RGBA) ColorFunc {
+	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
+		if maxY <= 1 {
+			return low
+		}
+
+		// Calculate the distance of the center of the pixel from the center
+		// of the image, as a fraction of half of the image height
+		p := math.Abs(float64(2*y+1-maxY)) / float64(maxY-1)
+		if p > 1 {
+			p = 1
+		}
+
+		mix := func(low uint8, peak uint8) uint8 {
+			return uint8(float64(low) + (float64(peak)-float64(low))*p + 0.5)
+		}
+
+		// Premultiplied components are blended linearly, so they always remain
+		// within the blended alpha
+		return &gradientColor{
+			srgb: color.RGBA{
+				R: mix(low.R, peak.R),
+				G: mix(low.G, peak.G),
+				B: mix(low.B, peak.B),
+				A: mix(low.A, peak.A),
+			},
+			start: low,
+			end:   peak,
+			p:     p,
 		}
 	}
 }
@@ -102,22 +295,32 @@ func SolidColor(inColor color.Color) ColorFunc {
 // variadic slice at each computed value.  Each color is used in order, and
 // the rotation will repeat until the image is complete. This creates a stripe
 // effect in the resulting waveform image.
+//
+// The color is selected using only the computed value count, so the result
+// does not depend on the order in which pixels are drawn.
 func StripeColor(colors ...color.Color) ColorFunc {
 	// Filter any nil values
 	colors = filterNilColors(colors)
 
-	var lastN int
 	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
-		// For each new n value, use the next color in the slice
-		if n > lastN {
-			lastN = n
-		}
+		// For each n value, use the next color in the slice
+		return colors[n%len(colors)]
+	}
+}
 
-		return colors[lastN%len(colors)]
+// minUint8 returns the smaller of two uint8 values.
+func minUint8(a uint8, b uint8) uint8 {
+	if a < b {
+		return a
 	}
+
+	return b
 }
 
 // filterNilColors strips any nil color.Color values from the input slice.
+//
+// A nil color is treated as unset, and is never drawn.  A color which is set
+// to be fully transparent, such as color.RGBA{}, is not nil, and is honored.
 func filterNilColors(colors []color.Color) []color.Color {
 	var cleanColors []color.Color
 	for _, c := range colors {
@@ -128,3 +331,53 @@ func filterNilColors(colors []color.Color) []color.Color {
 
 	return cleanColors
 }
+
+// ParseHexColor parses a hex color string into a color.RGBA.  The leading '#'
+// is optional, and the following forms are supported:
+//   - #RGB: each digit is repeated, with full opacity
+//   - #RRGGBB: full opacity
+//   - #RRGGBBAA: explicit alpha
+//
+// Hex colors are not alpha-premultiplied, so the color components are
+// premultiplied by alpha, as required by color.RGBA.  An alpha of 0 always
+// produces a fully transparent color, color.RGBA{}.
+//
+// An error is returned if the input string is not in one of these forms.
+func ParseHexColor(s string) (color.RGBA, error) {
+	h := s
+	if len(h) > 0 && h[0] == '#' {
+		h = h[1:]
+	}
+
+	// Expand shorthand form by repeating each digit
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+
+	// Add full opacity if no alpha is specified
+	if len(h) == 6 {
+		h += "ff"
+	}
+
+	if len(h) != 8 {
+		return color.RGBA{}, fmt.Errorf("waveform: invalid hex color %q: must be #RGB, #RRGGBB, or #RRGGBBAA", s)
+	}
+
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("waveform: invalid hex color %q: %v", s, err.(*strconv.NumError).Err)
+	}
+
+	// Premultiply each component by alpha, rounding to nearest
+	a := uint32(uint8(v))
+	premultiply := func(c uint8) uint8 {
+		return uint8((uint32(c)*a + 127) / 255)
+	}
+
+	return color.RGBA{
+		R: premultiply(uint8(v >> 24)),
+		G: premultiply(uint8(v >> 16)),
+		B: premultiply(uint8(v >> 8)),
+		A: uint8(a),
+	}, nil
+}
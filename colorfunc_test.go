@@ -14,6 +14,54 @@ var (
 	blue  = color.RGBA{0, 0, 255, 255}
 )
 
+// TestAmplitudeColor verifies that AmplitudeColor colors each column using
+// the palette color selected by the value drawn in that column.
+func TestAmplitudeColor(t *testing.T) {
+	w, err := New(nil,
+		BGColorFunction(SolidColor(white)),
+		FGColorFunction(AmplitudeColor(black, nil, red, blue)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.Draw([]float64{0.1, 0.4, 0.7, 1})
+	for x, want := range []color.Color{black, red, blue, blue} {
+		if c := img.At(x, imgYDefault/2); c != want {
+			t.Fatalf("unexpected color in column %d: %v != %v", x, c, want)
+		}
+	}
+
+	// Without a value, the first color is used
+	r, g, b, a := AmplitudeColor(black, red)(0, 0, 0, 1, 1, 1).RGBA()
+	if c := (color.RGBA64{uint16(r), uint16(g), uint16(b), uint16(a)}); c != color.RGBA64Model.Convert(black) {
+		t.Fatalf("unexpected color without value: %v != %v", c, black)
+	}
+}
+
+// TestBlendColor verifies that BlendColor produces the averaged color of two
+// solid colors at the midpoint, and only one color at either extreme.
+func TestBlendColor(t *testing.T) {
+	var tests = []struct {
+		alpha float64
+		color color.Color
+	}{
+		{0, color.RGBA64{R: 0xffff, A: 0xffff}},
+		{0.5, color.RGBA64{R: 0x8000, B: 0x8000, A: 0xffff}},
+		{1, color.RGBA64{B: 0xffff, A: 0xffff}},
+		// Clamped to [0, 1]
+		{-1, color.RGBA64{R: 0xffff, A: 0xffff}},
+		{2, color.RGBA64{B: 0xffff, A: 0xffff}},
+	}
+
+	for i, test := range tests {
+		fn := BlendColor(SolidColor(red), SolidColor(blue), test.alpha)
+		if out := fn(0, 0, 0, 0, 0, 0); out != test.color {
+			t.Fatalf("[%02d] unexpected BlendColor color: %v != %v", i, out, test.color)
+		}
+	}
+}
+
 // TestCheckerColorOneColor verifies that CheckerColor produces only the single
 // color used in its input.
 func TestCheckerColorOneColor(t *testing.T) {
@@ -26,6 +74,55 @@ func TestCheckerColorTwoColors(t *testing.T) {
 	testCheckerColor(t, black, white)
 }
 
+// TestCheckerColorSizeZero verifies that CheckerColor treats a size of 0 as 1,
+// rather than panicking on division by zero.
+func TestCheckerColorSizeZero(t *testing.T) {
+	fn := CheckerColor(black, white, 0)
+	for x := 0; x < 4; x++ {
+		want := black
+		if x%2 != 0 {
+			want = white
+		}
+
+		if c := fn(0, x, 0, 0, 0, 0); c != want {
+			t.Fatalf("[%02d] unexpected color: %v != %v", x, c, want)
+		}
+	}
+}
+
+// TestCheckerColorScaled verifies that CheckerColor computes its grid in output
+// pixel space, so that cell boundaries are unaffected by a Scale factor.
+func TestCheckerColorScaled(t *testing.T) {
+	const size = 4
+
+	w, err := New(nil,
+		BGColorFunction(SolidColor(red)),
+		FGColorFunction(CheckerColor(black, white, size)),
+		Scale(3, 1),
+		Sharpness(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Full height values, so the center row is entirely foreground
+	img := w.Draw([]float64{1, 1, 1, 1, 1, 1, 1, 1})
+	maxX := img.Bounds().Max.X
+	if maxX != 24 {
+		t.Fatalf("unexpected image width: %v != %v", maxX, 24)
+	}
+
+	// Color must only change at multiples of size in the output image, not at
+	// multiples of the X scaling factor
+	y := img.Bounds().Max.Y / 2
+	for x := 1; x < maxX; x++ {
+		changed := img.At(x, y) != img.At(x-1, y)
+		if boundary := x%size == 0; changed != boundary {
+			t.Fatalf("unexpected cell boundary at x=%d: changed=%v, boundary=%v", x, changed, boundary)
+		}
+	}
+}
+
 // TestFuzzColorOneColor verifies that FuzzColor produces only the single
 // color used in its input.
 func TestFuzzColorOneColor(t *testing.T) {
@@ -38,6 +135,43 @@ func TestFuzzColorMultipleColors(t *testing.T) {
 	testFuzzColor(t, []color.Color{black, white, red, green, blue})
 }
 
+// TestColorFuncConcurrent verifies that the same ColorFunc values may be used
+// by multiple goroutines generating images at once.  It is most useful when
+// run with the race detector enabled.
+func TestColorFuncConcurrent(t *testing.T) {
+	fuzz := FuzzColor(black, white, red, green, blue)
+	gradient := GradientColor(red, blue)
+	blend := BlendColor(fuzz, gradient, 0.5)
+
+	// Three seconds of a quiet square wave
+	samples := make([]float64, 300)
+	for i := range samples {
+		samples[i] = 0.1
+		if i%2 == 1 {
+			samples[i] = -0.1
+		}
+	}
+
+	const workers = 16
+	errC := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			_, err := GenerateFromSamples(samples, 100, 1,
+				BGColorFunction(fuzz),
+				FGColorFunction(blend),
+				Scale(4, 1),
+			)
+			errC <- err
+		}()
+	}
+
+	for i := 0; i < workers; i++ {
+		if err := <-errC; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
 // TestGradientColorOneColor verifies that GradientColor produces only the single
 // color used in its input.
 func TestGradientColorOneColor(t *testing.T) {
@@ -50,6 +184,45 @@ func TestGradientColorTwoColors(t *testing.T) {
 	testGradientColor(t, black, white)
 }
 
+// TestGradientColorTransparent verifies that GradientColor honors a fully
+// transparent end color, rather than drawing it as opaque.
+func TestGradientColorTransparent(t *testing.T) {
+	fn := GradientColor(black, color.RGBA{})
+	if _, _, _, a := fn(0, 0, 0, 100, 0, 0).RGBA(); a != 0xffff {
+		t.Fatalf("unexpected start alpha: %v != %v", a, 0xffff)
+	}
+	if _, _, _, a := fn(100, 0, 0, 100, 0, 0).RGBA(); a != 0 {
+		t.Fatalf("unexpected end alpha: %v != %v", a, 0)
+	}
+}
+
+// TestLevelColor verifies that LevelColor produces the low color at the
+// center of the image, the peak color at its top and bottom edges, and blends
+// between them by distance from the center.
+func TestLevelColor(t *testing.T) {
+	fn := LevelColor(black, color.RGBA{200, 100, 0, 255})
+
+	var tests = []struct {
+		y    int
+		want color.RGBA
+	}{
+		{y: 0, want: color.RGBA{200, 100, 0, 255}},
+		{y: 100, want: color.RGBA{200, 100, 0, 255}},
+		{y: 50, want: color.RGBA{0, 0, 0, 255}},
+		{y: 25, want: color.RGBA{100, 50, 0, 255}},
+		{y: 75, want: color.RGBA{100, 50, 0, 255}},
+		{y: 90, want: color.RGBA{160, 80, 0, 255}},
+	}
+
+	for i, test := range tests {
+		r, g, b, a := fn(0, 0, test.y, 1, 1, 101).RGBA()
+		out := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		if out != test.want {
+			t.Fatalf("[%02d] unexpected LevelColor color at y=%d: %v != %v", i, test.y, out, test.want)
+		}
+	}
+}
+
 // TestSolidColor verifies that SolidColor always returns the same input
 // color, for all input values.
 func TestSolidColor(t *testing.T) {
@@ -74,6 +247,27 @@ func TestStripeColorOneColor(t *testing.T) {
 	})
 }
 
+// TestStripeColorUnsetColor verifies that StripeColor treats a nil color as
+// unset, using only the remaining colors.
+func TestStripeColorUnsetColor(t *testing.T) {
+	testStripeColor(t, []color.Color{black, nil}, []color.Color{
+		black, black, black, black,
+	})
+}
+
+// TestStripeColorTransparentColor verifies that StripeColor honors a color
+// explicitly set to be fully transparent.
+func TestStripeColorTransparentColor(t *testing.T) {
+	transparent, err := ParseHexColor("#FFFFFF00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStripeColor(t, []color.Color{black, transparent}, []color.Color{
+		black, color.RGBA{}, black, color.RGBA{},
+	})
+}
+
 // TestStripeColorMultipleColors verifies that StripeColor produces a correct
 // color sequence with multiple input colors.
 func TestStripeColorMultipleColors(t *testing.T) {
@@ -170,3 +364,48 @@ func testStripeColor(t *testing.T, in []color.Color, out []color.Color) {
 		}
 	}
 }
+
+// TestParseHexColor verifies that ParseHexColor parses each supported form of
+// hex color string, and returns an error for invalid input.
+func TestParseHexColor(t *testing.T) {
+	var tests = []struct {
+		in    string
+		color color.RGBA
+		ok    bool
+	}{
+		// #RGB
+		{"#000", black, true},
+		{"#fff", white, true},
+		{"#F30", color.RGBA{255, 51, 0, 255}, true},
+		{"0f0", green, true},
+		// #RRGGBB
+		{"#FF0000", red, true},
+		{"#0000ff", blue, true},
+		{"0099CC", color.RGBA{0, 153, 204, 255}, true},
+		// #RRGGBBAA
+		{"#FF000080", color.RGBA{128, 0, 0, 128}, true},
+		{"#FFFFFF40", color.RGBA{64, 64, 64, 64}, true},
+		{"#00000000", color.RGBA{}, true},
+		// Fully transparent, regardless of color components
+		{"#FF990000", color.RGBA{}, true},
+		// Invalid input
+		{"", color.RGBA{}, false},
+		{"#", color.RGBA{}, false},
+		{"#ff", color.RGBA{}, false},
+		{"#ffff", color.RGBA{}, false},
+		{"#fffffff", color.RGBA{}, false},
+		{"#GG0000", color.RGBA{}, false},
+		{"#-12345", color.RGBA{}, false},
+		{"##fff", color.RGBA{}, false},
+	}
+
+	for i, test := range tests {
+		c, err := ParseHexColor(test.in)
+		if (err == nil) != test.ok {
+			t.Fatalf("[%02d] unexpected error for %q: %v", i, test.in, err)
+		}
+		if c != test.color {
+			t.Fatalf("[%02d] unexpected color for %q: %v != %v", i, test.in, c, test.color)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package waveform
+
+import (
+	"image"
+	"io"
+)
+
+// A ColumnFunc receives each column of a waveform image drawn by Stream, in
+// order from left to right.  n is the index of the computed value drawn in the
+// column, and the bounds of img are its position within the complete image:
+// the width of one bar and its gap, and the full height of the image.
+//
+// If a ColumnFunc returns an error, streaming stops, and the error is returned
+// by Stream.
+type ColumnFunc func(n int, img image.Image) error
+
+// GenerateStream opens and reads an input audio stream incrementally, and
+// draws a waveform image column by column, passing each column to fn as soon
+// as its value is computed.  The image is customized by zero or more,
+// variadic, OptionsFunc parameters.
+//
+// GenerateStream is equivalent to calling New, followed by the Stream method
+// of a Waveform struct.
+func GenerateStream(r io.Reader, fn ColumnFunc, options ...OptionsFunc) error {
+	w, err := New(r, options...)
+	if err != nil {
+		return err
+	}
+
+	return w.Stream(fn)
+}
+
+// Stream reads the input audio stream incrementally, and draws a waveform
+// image column by column, passing each column to fn as soon as its value is
+// computed.  Only a single slice of audio samples and a single column are
+// held in memory at once, and no computed values are retained, so memory use
+// does not grow with the duration of the stream.  Stream is useful for very
+// long streams, or to display a waveform while it is being generated.
+//
+// Because the total number of values is not known until the stream ends, the
+// maxN and maxX parameters of each ColorFunc are the number of values and
+// pixels on the X-axis drawn so far, including the current column.  Options
+// which require all computed values before any column can be drawn cannot be
+// used, and cause ErrStreamUnsupported to be returned before any audio is
+// read: ScaleClipping, TrimSilence, Overlay, DrawMarkers, Padding, MaxWidth,
+// the AreaFill and Line styles, and the Separate channel mode.
+//
+// Columns passed to fn before an error occurs are not withdrawn, so any error
+// leaves a partial image, regardless of the PartialOnError option.
+func (w *Waveform) Stream(fn ColumnFunc) error {
+	if err := w.validateStream(); err != nil {
+		return err
+	}
+
+	l := w.newLayout([]float64{0})
+
+	// Statistics are only used by drawing modes which require them
+	if w.dualEnvelope || w.minMaxEnvelope || w.clipColorFn != nil {
+		l.stats = make([]sliceStats, 1)
+	}
+
+	var n int
+	w.valueFn = func(value float64, stats sliceStats) error {
+		img := l.drawColumn(n, value, stats)
+		if err := fn(n, img); err != nil {
+			return err
+		}
+
+		n++
+		return nil
+	}
+	defer func() {
+		w.valueFn = nil
+	}()
+
+	_, err := w.readAndComputeSamples()
+	return err
+}
+
+// validateStream verifies that no options are set which prevent drawing a
+// waveform image one column at a time.
+func (w *Waveform) validateStream() error {
+	if w.scaleClipping || w.normalize || w.trimThreshold > 0 || w.overlayValues != nil ||
+		w.markerColorFn != nil || w.style.continuous() || w.separate || w.width > 0 || w.maxWidth > 0 ||
+		w.antiAlias > 1 || w.drawsAnnotations() {
+		return ErrStreamUnsupported
+	}
+	if w.padTop > 0 || w.padRight > 0 || w.padBottom > 0 || w.padLeft > 0 {
+		return ErrStreamUnsupported
+	}
+
+	return nil
+}
+
+// drawColumn draws the column of a waveform image for the computed value at
+// index n, using a layout of a single value, which is replaced by the input
+// value and statistics.  The returned image is positioned at the column's X
+// coordinate within the complete image.
+func (l *layout) drawColumn(n int, value float64, stats sliceStats) image.Image {
+	l.computed[0] = value
+	l.values[0] = l.w.amplitude(value)
+	if l.stats != nil {
+		l.stats[0] = stats
+	}
+
+	// Each ColorFunc is evaluated at the position of the column within the
+	// complete image, which extends only to this column so far
+	x0 := n * l.period
+	maxN, maxX := n+1, x0+l.period
+
+	bounds := image.Rect(x0, 0, maxX, l.maxY)
+	img := l.w.newImage(bounds)
+
+	var spans []span
+	for i := 0; i < l.period; i++ {
+		spans = l.spans(i, spans[:0])
+		for _, s := range spans {
+			for y := s.y0; y < s.y1; y++ {
+				c := resolveAmplitude(s.fn(n, x0+i, y, maxN, maxX, l.maxY), l.values[0])
+				img.Set(x0+i, y, resolveColor(c, l.w.gammaCorrect))
+			}
+		}
+	}
+
+	return img
+}
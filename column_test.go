@@ -0,0 +1,138 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+	"time"
+)
+
+// TestWaveformStreamMatchesDraw verifies that the columns drawn by Stream
+// produce the same image as Generate, for options which do not depend on
+// all computed values.
+func TestWaveformStreamMatchesDraw(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+
+	var tests = [][]OptionsFunc{
+		nil,
+		{Resolution(4), BarWidth(3), BarGap(2), Sharpness(2)},
+		{Resolution(4), Scale(2, 1), BarRadius(1)},
+		{Resolution(4), Height(64), DualEnvelope(SolidColor(red), SolidColor(color.Black))},
+		{Resolution(4), MinMaxEnvelope(), ClipIndicator(red, 0.5)},
+		{Resolution(4), Paletted([]color.Color{color.White, color.Black})},
+		{Resolution(4), GammaCorrect(true), FGColorFunction(CheckerColor(red, color.Black, 2))},
+	}
+
+	for i, options := range tests {
+		want, err := Generate(bytes.NewReader(wavFile), options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var columns int
+		got := image.NewRGBA(want.Bounds())
+		err = GenerateStream(bytes.NewReader(wavFile), func(n int, img image.Image) error {
+			if n != columns {
+				t.Fatalf("[%02d] unexpected column index: %v != %v", i, n, columns)
+			}
+			columns++
+
+			draw.Draw(got, img.Bounds(), img, img.Bounds().Min, draw.Src)
+			return nil
+		}, options...)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected Stream error: %v", i, err)
+		}
+
+		if columns == 0 {
+			t.Fatalf("[%02d] no columns streamed", i)
+		}
+
+		testImagesEqual(t, got, want)
+	}
+}
+
+// TestWaveformStreamErrStreamUnsupported verifies that Stream returns
+// ErrStreamUnsupported for options which require all computed values.
+func TestWaveformStreamErrStreamUnsupported(t *testing.T) {
+	var tests = []OptionsFunc{
+		ScaleClipping(),
+		TrimSilence(0.1),
+		Overlay([]float64{0.5}, color.RGBA{255, 0, 0, 128}),
+		DrawMarkers(color.RGBA{255, 0, 0, 255}),
+		Padding(1, 0, 0, 0),
+		Style(AreaFill),
+		Style(Line),
+		Channels(Separate),
+		Dimensions(800, 160),
+		AntiAlias(2),
+		TimeAxis(time.Second, color.RGBA{255, 0, 0, 255}),
+		Markers([]Marker{{Time: time.Second, Color: color.Black}}),
+		Normalize(),
+		MaxWidth(800),
+	}
+
+	for i, option := range tests {
+		err := GenerateStream(bytes.NewReader(wavFile), func(n int, img image.Image) error {
+			t.Fatalf("[%02d] unexpected column streamed", i)
+			return nil
+		}, option)
+		if err != ErrStreamUnsupported {
+			t.Fatalf("[%02d] unexpected Stream error: %v != %v", i, err, ErrStreamUnsupported)
+		}
+	}
+}
+
+// TestWaveformStreamColumnFuncError verifies that an error returned by a
+// ColumnFunc stops streaming, and is returned by Stream.
+func TestWaveformStreamColumnFuncError(t *testing.T) {
+	errStop := errors.New("stop")
+
+	w, err := New(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var columns int
+	err = w.Stream(func(n int, img image.Image) error {
+		columns++
+		if n == 1 {
+			return errStop
+		}
+
+		return nil
+	})
+	if err != errStop {
+		t.Fatalf("unexpected Stream error: %v != %v", err, errStop)
+	}
+	if columns != 2 {
+		t.Fatalf("unexpected number of columns streamed: %v != %v", columns, 2)
+	}
+
+	// The Waveform computes values normally after streaming
+	w, err = New(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Stream(func(n int, img image.Image) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if w.valueFn != nil {
+		t.Fatal("value function retained after streaming")
+	}
+}
+
+// TestWaveformStreamErrNoSamples verifies that Stream reports an empty input
+// stream without streaming any columns.
+func TestWaveformStreamErrNoSamples(t *testing.T) {
+	err := GenerateStream(bytes.NewReader(nil), func(n int, img image.Image) error {
+		t.Fatal("unexpected column streamed")
+		return nil
+	})
+	if err != ErrNoSamples {
+		t.Fatalf("unexpected Stream error: %v != %v", err, ErrNoSamples)
+	}
+}
@@ -0,0 +1,60 @@
+package waveform
+
+import (
+	"image"
+	"image/draw"
+	"io"
+)
+
+// Compose immediately opens and reads an input audio stream, computes the
+// values required for waveform generation, and draws a waveform image into
+// a region of an existing image, such as album art or a video thumbnail.  The
+// waveform is customized by zero or more, variadic, OptionsFunc parameters.
+//
+// Compose is equivalent to New and Compute, followed by the Compose method of
+// a Waveform struct, and handles errors in the same way as Generate.
+func Compose(dst draw.Image, rect image.Rectangle, r io.Reader, options ...OptionsFunc) error {
+	w, err := New(r, options...)
+	if err != nil {
+		return err
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		// Draw any partial results, if requested
+		if w.partialOnError && len(values) > 0 {
+			w.Compose(dst, rect, values)
+		}
+
+		return err
+	}
+
+	w.Compose(dst, rect, values)
+	return nil
+}
+
+// Compose draws a waveform image from a slice of float64 values into the
+// region rect of dst, composited over its existing pixels.
+//
+// The waveform is drawn as if the Dimensions option were set to the size of
+// rect, less any padding, so that it exactly fills the region.  The
+// background is drawn over dst as well, so TransparentBackground, or a
+// background color with alpha, is typically used to let dst show through.
+// Any part of rect outside of the bounds of dst is not drawn.
+func (w *Waveform) Compose(dst draw.Image, rect image.Rectangle, values []float64) {
+	width := rect.Dx() - int(w.padLeft+w.padRight)
+	height := rect.Dy() - int(w.padTop+w.padBottom)
+	if width <= 0 || height <= 0 || len(values) == 0 {
+		return
+	}
+
+	// Draw the waveform at the size of the region, which replaces any Y-axis
+	// scaling, as Dimensions would
+	c := *w
+	c.scaleY = 1
+	c.width = uint(width)
+	c.height = uint(height)
+
+	img := c.Draw(values)
+	draw.Draw(dst, rect, img, img.Bounds().Min, draw.Over)
+}
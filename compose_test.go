@@ -0,0 +1,68 @@
+package waveform
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// TestWaveformCompose verifies that the Waveform.Compose method draws a
+// waveform which fills a region of an existing image, composited over its
+// pixels, and leaves the rest of the image unchanged.
+func TestWaveformCompose(t *testing.T) {
+	var tests = []struct {
+		options []OptionsFunc
+		corner  color.Color
+	}{
+		{corner: white},
+		{options: []OptionsFunc{TransparentBackground()}, corner: red},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, test.options...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		dst := image.NewRGBA(image.Rect(0, 0, 20, 140))
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+
+		rect := image.Rect(5, 6, 15, 134)
+		w.Compose(dst, rect, []float64{0.1, 0.2})
+
+		for _, p := range []struct {
+			x, y int
+			want color.Color
+		}{
+			{0, 0, red},
+			{15, 70, red},
+			{5, 6, test.corner},
+			{14, 133, test.corner},
+			{5, 70, black},
+			{14, 70, black},
+		} {
+			if c := dst.At(p.x, p.y); c != p.want {
+				t.Fatalf("[%02d] unexpected color at (%d, %d): %v != %v", i, p.x, p.y, c, p.want)
+			}
+		}
+	}
+}
+
+// TestCompose verifies that Compose draws a waveform from an input audio
+// stream into a region of an existing image.
+func TestCompose(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	if err := Compose(dst, image.Rect(2, 2, 6, 6), bytes.NewReader(wav), Resolution(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := dst.At(0, 0); c != (color.RGBA{}) {
+		t.Fatalf("unexpected color outside of region: %v", c)
+	}
+	if c := dst.At(5, 5); c == (color.RGBA{}) {
+		t.Fatal("no waveform drawn in region")
+	}
+}
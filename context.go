@@ -0,0 +1,54 @@
+package waveform
+
+import (
+	"context"
+	"image"
+	"io"
+)
+
+// GenerateContext is the same as Generate, but stops reading the input audio
+// stream once the input context is done, such as when the client of an HTTP
+// handler disconnects, or a deadline passes.  The error of the context is
+// returned, and any values computed so far are drawn only if the
+// PartialOnError option is set.
+//
+// GenerateContext is equivalent to calling New, followed by the ComputeContext
+// and Draw methods of a Waveform struct.
+func GenerateContext(ctx context.Context, r io.Reader, options ...OptionsFunc) (image.Image, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.drawComputed(w.ComputeContext(ctx))
+}
+
+// ComputeContext is the same as Compute, but stops reading the input audio
+// stream once the input context is done, and returns the error of the context.
+// If the PartialOnError option is set, any values computed before the context
+// was done are returned along with its error.
+//
+// The context is checked before each slice of audio is decoded, so a
+// computation stops within one slice of audio, as set by the Resolution
+// option, of the context being done.  A read from the input stream which is
+// blocked is not interrupted, so network streams should also be closed when
+// the context is done.  An external decoder process set by ExecDecoder is
+// stopped.
+func (w *Waveform) ComputeContext(ctx context.Context) ([]float64, error) {
+	w.ctx = ctx
+	defer func() {
+		w.ctx = nil
+	}()
+
+	return w.Compute()
+}
+
+// ctxErr returns the error of the context used by ComputeContext, if it is
+// done, or nil otherwise.
+func (w *Waveform) ctxErr() error {
+	if w.ctx == nil {
+		return nil
+	}
+
+	return w.ctx.Err()
+}
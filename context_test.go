@@ -0,0 +1,72 @@
+package waveform
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestGenerateContextOK verifies that GenerateContext generates an image when
+// the input context is not done.
+func TestGenerateContextOK(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	img, err := GenerateContext(context.Background(), bytes.NewReader(wav), Resolution(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b.Dx() != 4 {
+		t.Fatalf("unexpected image width: %v", b.Dx())
+	}
+}
+
+// TestGenerateContextCanceled verifies that GenerateContext returns the error
+// of a context which is done, without any image.
+func TestGenerateContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	img, err := GenerateContext(ctx, bytes.NewReader(wav), Resolution(2))
+	if err != context.Canceled {
+		t.Fatalf("unexpected error: %v != %v", err, context.Canceled)
+	}
+	if img != nil {
+		t.Fatalf("unexpected image: %v", img.Bounds())
+	}
+}
+
+// TestWaveformComputeContextPartial verifies that the Waveform.ComputeContext
+// method stops reading once its context is done, and returns any values
+// computed so far when PartialOnError is set.
+func TestWaveformComputeContextPartial(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel the computation once the first value is computed
+	sampleFn := func(samples audio.Float64) float64 {
+		cancel()
+		return 0.5
+	}
+
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	w, err := New(bytes.NewReader(wav), Resolution(2), SampleFunction(sampleFn), PartialOnError())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.ComputeContext(ctx)
+	if err != context.Canceled {
+		t.Fatalf("unexpected error: %v != %v", err, context.Canceled)
+	}
+	if len(values) != 1 || values[0] != 0.5 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+
+	// The context is not retained for later computations
+	if w.ctx != nil {
+		t.Fatal("context retained after computation")
+	}
+}
@@ -0,0 +1,239 @@
+package waveform
+
+import (
+	"bufio"
+	"io"
+	"math"
+
+	"azul3d.org/engine/audio"
+)
+
+// Decoder computes the values of an input audio stream incrementally, a number
+// of seconds of audio at a time, so that very long streams can be processed in
+// pieces, and processing can be resumed from a previous position.
+//
+// The values computed by a Decoder are the same as those returned by Compute
+// with the same options.  Options which require all values before any can be
+// returned, such as TrimSilence and ScaleClipping, do not apply to a Decoder,
+// and the values it returns may be drawn using Draw once all are computed.
+type Decoder struct {
+	w       *Waveform
+	decoder audio.Decoder
+	config  audio.Config
+
+	// Buffers for a single slice of decoded and down-mixed audio samples
+	samples audio.Float64
+	mono    audio.Float64
+
+	// Number of samples per channel read from the stream, and whether the
+	// stream has ended
+	position int64
+	done     bool
+}
+
+// NewDecoder opens an input audio stream, and returns a Decoder which computes
+// its values from the beginning of the stream.  The values are customized by
+// zero or more, variadic, OptionsFunc parameters.
+func NewDecoder(r io.Reader, options ...OptionsFunc) (*Decoder, error) {
+	return ResumeDecoder(r, 0, options...)
+}
+
+// ResumeDecoder opens an input audio stream, and returns a Decoder which
+// computes its values from the input position, as returned by the Position
+// method of a Decoder for the same stream and options.
+//
+// If the stream is an io.ReadSeeker containing integer PCM, floating point, or
+// G.711 WAV samples, or raw PCM samples set by the RawPCM option, the stream is
+// seeked directly to the position.  Otherwise, all samples which precede the
+// position are decoded and discarded.
+func ResumeDecoder(r io.Reader, position int64, options ...OptionsFunc) (*Decoder, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.validateCompute(); err != nil {
+		return nil, err
+	}
+
+	// Seek directly to the position of uncompressed streams, if possible
+	var decoder audio.Decoder
+	var skipped int64
+	if rs, ok := r.(io.ReadSeeker); ok && position > 0 && w.resampleRate == 0 && w.trackIndex == 0 {
+		if decoder, skipped, err = w.seekDecoder(rs, position); err != nil {
+			return nil, err
+		}
+	}
+	if decoder == nil {
+		if decoder, err = w.openDecoder(bufio.NewReader(r)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resample decoded samples to the target sample rate, if needed
+	if w.resampleRate > 0 && decoder.Config().SampleRate != w.resampleRate {
+		decoder = newResampleDecoder(decoder, w.resampleRate)
+	}
+
+	config := decoder.Config()
+	if err := w.checkChannels(config); err != nil {
+		return nil, err
+	}
+
+	d := &Decoder{
+		w:        w,
+		decoder:  decoder,
+		config:   config,
+		position: skipped,
+	}
+	d.samples, d.mono = w.sliceBuffers(config)
+
+	// Decode and discard all remaining samples before the position
+	for d.position < position && !d.done {
+		want := (position - d.position) * int64(config.Channels)
+		if want > int64(len(d.samples)) {
+			want = int64(len(d.samples))
+		}
+
+		n, err := d.fill(d.samples[:want])
+		if err != nil {
+			return nil, err
+		}
+		d.position += int64(n / config.Channels)
+	}
+
+	return d, nil
+}
+
+// seekDecoder seeks a WAV or raw PCM stream directly to the input position,
+// and returns a decoder for the remainder of the stream, along with the
+// position seeked to, which is limited to the end of the stream.  If the
+// stream cannot be seeked directly, it is returned to its original position,
+// and a nil decoder is returned.
+func (w *Waveform) seekDecoder(rs io.ReadSeeker, position int64) (audio.Decoder, int64, error) {
+	if w.rawPCM != nil {
+		f := *w.rawPCM
+		frame := int64(f.channels) * int64(f.bits/8)
+		if _, err := rs.Seek(position*frame, io.SeekCurrent); err != nil {
+			return nil, 0, err
+		}
+
+		return newRawDecoder(rs, f), position, nil
+	}
+
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	f, size, err := readWAVHeader(rs)
+	if err != nil || size == wavUnknownSize || !f.pcm() && !f.float() && !f.g711() {
+		_, err := rs.Seek(start, io.SeekStart)
+		return nil, 0, err
+	}
+
+	frame := int64(f.channels) * int64(f.bits/8)
+	if position > size/frame {
+		position = size / frame
+	}
+	if _, err := rs.Seek(position*frame, io.SeekCurrent); err != nil {
+		return nil, 0, err
+	}
+
+	return &wavDecoder{
+		r:         rs,
+		format:    f,
+		size:      int(f.bits / 8),
+		remaining: size - position*frame,
+	}, position, nil
+}
+
+// Next decodes up to the next input number of seconds of audio, and returns
+// the values computed from it, one for each slice of audio at the resolution
+// set by the Resolution option, or of the size set by SliceSamples.  A
+// fractional number of slices is rounded up.
+//
+// When the stream ends, Next returns any remaining values along with io.EOF,
+// and all subsequent calls return no values and io.EOF.  If an error occurs
+// while decoding, the values computed before the error are returned along
+// with it.
+func (d *Decoder) Next(seconds uint) ([]float64, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+
+	var values []float64
+	for i := int64(0); i < d.slices(seconds) && !d.done; i++ {
+		n, err := d.fill(d.samples)
+		if err != nil {
+			return values, err
+		}
+		if n == 0 {
+			break
+		}
+
+		// Down-mix all channels to mono, or use only the selected channel
+		samples := d.samples[:n]
+		if d.w.selectChannel {
+			samples = extractChannel(d.mono, samples, d.config.Channels, d.w.channel)
+		} else {
+			samples = downmix(d.mono, samples, d.config.Channels)
+		}
+		d.position += int64(len(samples))
+
+		values = append(values, d.w.sampleFn(samples))
+	}
+
+	if d.done {
+		return values, io.EOF
+	}
+
+	return values, nil
+}
+
+// slices returns the number of slices of audio in the input number of
+// seconds, rounded up, so that fractional resolutions still make progress.
+func (d *Decoder) slices(seconds uint) int64 {
+	var n float64
+	if d.w.sliceSamples > 0 {
+		n = float64(seconds) * float64(d.config.SampleRate) / float64(d.w.sliceSamples)
+	} else {
+		n = float64(seconds) * d.w.resolution
+	}
+
+	return int64(math.Ceil(n))
+}
+
+// Position returns the number of samples per channel which have been read
+// from the stream.  Until the stream ends, the position is always at the end
+// of a slice of audio, so that a Decoder resumed at the position computes the
+// same values as one which was never interrupted.
+func (d *Decoder) Position() int64 {
+	return d.position
+}
+
+// fill decodes samples until the buffer is full, or the stream ends, and
+// returns the number of samples decoded.  At the end of the stream, the
+// Decoder is marked as done.
+func (d *Decoder) fill(samples audio.Float64) (int, error) {
+	var n int
+	for n < len(samples) {
+		read, err := d.decoder.Read(samples[n:])
+		n += read
+		if err == audio.EOS {
+			d.done = true
+			break
+		}
+		if err != nil {
+			d.done = true
+			return n, err
+		}
+
+		// Avoid looping forever on a decoder which makes no progress
+		if read == 0 {
+			d.done = true
+			break
+		}
+	}
+
+	return n, nil
+}
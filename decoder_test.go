@@ -0,0 +1,140 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// TestDecoderNext verifies that the values returned by a Decoder, in any
+// number of seconds at a time, are identical to those returned by Compute.
+func TestDecoderNext(t *testing.T) {
+	data := make([]byte, 250)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	var tests = []struct {
+		data    []byte
+		seconds uint
+		options []OptionsFunc
+	}{
+		{testWAV(1, 1, 100, 8, data), 1, []OptionsFunc{Resolution(2)}},
+		{testWAV(1, 2, 50, 8, data), 2, []OptionsFunc{Resolution(3)}},
+		{testWAV(1, 2, 20, 24, data[:240]), 1, []OptionsFunc{Resolution(3)}},
+		{testAIFF("", 1, 16, 50, data), 3, []OptionsFunc{Resolution(4)}},
+		{data, 1, []OptionsFunc{RawPCM(25, 16, 1, binary.LittleEndian), Resolution(2)}},
+	}
+
+	for i, test := range tests {
+		want, err := testComputeValues(bytes.NewReader(test.data), test.options...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		d, err := NewDecoder(bytes.NewReader(test.data), test.options...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		var values []float64
+		for {
+			next, err := d.Next(test.seconds)
+			values = append(values, next...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("[%02d] %v", i, err)
+			}
+			if len(next) == 0 {
+				t.Fatalf("[%02d] no values returned before end of stream", i)
+			}
+		}
+
+		if !reflect.DeepEqual(values, want) {
+			t.Fatalf("[%02d] unexpected values:\n- want: %v\n-  got: %v", i, want, values)
+		}
+
+		// All calls after the end of the stream return io.EOF
+		if next, err := d.Next(1); next != nil || err != io.EOF {
+			t.Fatalf("[%02d] unexpected Next after end of stream: %v, %v", i, next, err)
+		}
+	}
+}
+
+// TestResumeDecoder verifies that a Decoder resumed at the position of another
+// Decoder computes the same remaining values, whether or not the stream can be
+// seeked directly to the position.
+func TestResumeDecoder(t *testing.T) {
+	data := make([]byte, 300)
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+
+	var tests = []struct {
+		data    []byte
+		options []OptionsFunc
+	}{
+		{testWAV(1, 1, 100, 8, data), []OptionsFunc{Resolution(4)}},
+		{testWAV(1, 2, 25, 24, data), []OptionsFunc{Resolution(2)}},
+		{testWAV(3, 1, 20, 32, data), []OptionsFunc{Resolution(1)}},
+		{testAIFF("", 2, 8, 30, data), []OptionsFunc{Resolution(4)}},
+		{data, []OptionsFunc{RawPCM(50, 16, 2, binary.BigEndian), Resolution(5)}},
+	}
+
+	for i, test := range tests {
+		d, err := NewDecoder(bytes.NewReader(test.data), test.options...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+		if _, err := d.Next(1); err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+		position := d.Position()
+
+		want, err := d.Next(100)
+		if err != io.EOF {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, io.EOF)
+		}
+
+		// Resume from both a seekable and a non-seekable stream
+		readers := []io.Reader{
+			bytes.NewReader(test.data),
+			struct{ io.Reader }{bytes.NewReader(test.data)},
+		}
+		for _, r := range readers {
+			d, err := ResumeDecoder(r, position, test.options...)
+			if err != nil {
+				t.Fatalf("[%02d] %v", i, err)
+			}
+
+			values, err := d.Next(100)
+			if err != io.EOF {
+				t.Fatalf("[%02d] unexpected error: %v != %v", i, err, io.EOF)
+			}
+			if !reflect.DeepEqual(values, want) {
+				t.Fatalf("[%02d] unexpected values:\n- want: %v\n-  got: %v", i, want, values)
+			}
+		}
+	}
+}
+
+// TestResumeDecoderEnd verifies that a Decoder resumed at or beyond the end of
+// a stream returns no values.
+func TestResumeDecoderEnd(t *testing.T) {
+	data := testWAV(1, 1, 100, 8, make([]byte, 100))
+
+	for i, position := range []int64{100, 1000} {
+		d, err := ResumeDecoder(bytes.NewReader(data), position, Resolution(2))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if values, err := d.Next(1); len(values) != 0 || err != io.EOF {
+			t.Fatalf("[%02d] unexpected Next at end of stream: %v, %v", i, values, err)
+		}
+	}
+}
@@ -0,0 +1,518 @@
+package waveform
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/bits"
+
+	"azul3d.org/engine/audio"
+)
+
+// dsdMagics are the magic strings which begin DSF and DSDIFF streams.
+var dsdMagics = []string{"DSD ", "FRM8????????DSD "}
+
+func init() {
+	// Register DSD streams with the audio package, so they are detected by the
+	// same format sniffing used for WAV and FLAC
+	audio.RegisterFormat(FormatDSD, dsdMagics[0], newDSFDecoder)
+	audio.RegisterFormat(FormatDSD, dsdMagics[1], newDFFDecoder)
+}
+
+const (
+	// dsdPCMRate is the highest rate of the PCM samples decimated from a DSD
+	// stream.  DSD64 at 2.8224MHz is decimated by 64, to 44.1kHz.
+	dsdPCMRate = 44100
+
+	// dsdFilterPeriods is the length of the decimation filter, in PCM samples
+	dsdFilterPeriods = 4
+
+	// dsdSilence is the idle pattern of a DSD stream, which has an average
+	// value of 0
+	dsdSilence = 0x69
+
+	// dsdMaxHeaderSize is the largest format or property chunk which is read
+	// into memory
+	dsdMaxHeaderSize = 1 << 16
+)
+
+// dsdDecoder is an audio.Decoder which converts the 1-bit samples of a DSD
+// stream to PCM samples, by decimating each channel using a low-pass FIR
+// filter.
+//
+// The input is read as bytes of 8 DSD samples, with the earliest sample in the
+// most significant bit, interleaved by channel.  Each PCM sample is produced
+// from a whole number of bytes of each channel, so the filter is applied using
+// a lookup table for each byte position, rather than for each bit.
+type dsdDecoder struct {
+	r      io.Reader
+	config audio.Config
+
+	// Bytes of each channel per PCM sample, and the filter response of each
+	// byte value at each position of the filter, beginning with the earliest
+	step  int
+	table [][256]float64
+
+	// Most recent bytes of each channel, which span the filter, and the bytes
+	// of all channels read for a single PCM sample
+	history [][]byte
+	group   []byte
+}
+
+// newDSDDecoder creates a dsdDecoder which reads interleaved DSD bytes from r,
+// with the input DSD sample rate and channels.  Each byte holds 8 DSD samples,
+// so the DSD sample rate may be up to 8 times the highest PCM sample rate.
+func newDSDDecoder(r io.Reader, sampleRate int, channels int) (audio.Decoder, error) {
+	if sampleRate < 8 || sampleRate > 8*sampleRateMax || channels <= 0 || channels > channelsMax {
+		return nil, audio.ErrInvalidData
+	}
+
+	// Decimate by whole bytes, to at most the target PCM sample rate
+	step := sampleRate / (8 * dsdPCMRate)
+	if step < 1 {
+		step = 1
+	}
+
+	d := &dsdDecoder{
+		r: r,
+		config: audio.Config{
+			SampleRate: sampleRate / (8 * step),
+			Channels:   channels,
+		},
+		step:    step,
+		table:   dsdFilterTable(step),
+		history: make([][]byte, channels),
+		group:   make([]byte, step*channels),
+	}
+
+	// Begin from silence, so the filter does not produce a transient
+	for ch := range d.history {
+		d.history[ch] = make([]byte, len(d.table))
+		for i := range d.history[ch] {
+			d.history[ch][i] = dsdSilence
+		}
+	}
+
+	return d, nil
+}
+
+// dsdFilterTable returns the lookup tables of a Hann windowed sinc low-pass
+// filter, which decimates DSD bytes by the input step.  The filter spans
+// dsdFilterPeriods PCM samples, and its cutoff is 90% of the PCM Nyquist
+// frequency.  Coefficients are normalized, so that a constant input of 1
+// produces an output of 1.
+func dsdFilterTable(step int) [][256]float64 {
+	taps := dsdFilterPeriods * step * 8
+	cutoff := 0.9 * 0.5 / float64(step*8)
+	center := float64(taps-1) / 2
+
+	coefs := make([]float64, taps)
+	var sum float64
+	for i := range coefs {
+		x := float64(i) - center
+		sinc := 2 * cutoff
+		if x != 0 {
+			sinc = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(taps-1))
+
+		coefs[i] = sinc * window
+		sum += coefs[i]
+	}
+
+	// Each bit contributes its coefficient when set, or its negation when clear
+	table := make([][256]float64, taps/8)
+	for j := range table {
+		for v := 0; v < 256; v++ {
+			var s float64
+			for b := 0; b < 8; b++ {
+				c := coefs[j*8+b] / sum
+				if v&(0x80>>uint(b)) == 0 {
+					c = -c
+				}
+				s += c
+			}
+			table[j][v] = s
+		}
+	}
+
+	return table
+}
+
+// Config returns the audio configuration of the decimated PCM samples.
+func (d *dsdDecoder) Config() audio.Config {
+	return d.config
+}
+
+// bitDepth returns the bit depth of the DSD stream, which is always 1, even
+// though its samples are decimated to PCM.
+func (d *dsdDecoder) bitDepth() int {
+	return 1
+}
+
+// Read decodes samples into b, returning the number of samples read.  When
+// the end of the DSD data is reached, audio.EOS is returned along with any
+// remaining samples.  Any DSD bytes which do not fill a whole PCM sample at
+// the end of the stream are discarded.
+func (d *dsdDecoder) Read(b audio.Slice) (int, error) {
+	channels := d.config.Channels
+
+	var n int
+	for n+channels <= b.Len() {
+		if _, err := io.ReadFull(d.r, d.group); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return n, audio.EOS
+			}
+
+			return n, err
+		}
+
+		for ch, h := range d.history {
+			// Shift the bytes of this sample into the history of the channel
+			copy(h, h[d.step:])
+			tail := h[len(h)-d.step:]
+			for i := range tail {
+				tail[i] = d.group[i*channels+ch]
+			}
+
+			var v float64
+			for j, c := range h {
+				v += d.table[j][c]
+			}
+			b.Set(n+ch, v)
+		}
+		n += channels
+	}
+
+	return n, nil
+}
+
+// dsdDataReader is an io.Reader which reads a fixed number of bytes of DSD
+// data.  If the underlying stream ends before all bytes are read,
+// audio.ErrUnexpectedEOS is returned.
+type dsdDataReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+// Read reads DSD data into p.
+func (r *dsdDataReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+	if err == io.EOF && r.remaining > 0 {
+		return n, audio.ErrUnexpectedEOS
+	}
+	if err == io.EOF {
+		err = nil
+	}
+
+	return n, err
+}
+
+// newDSFDecoder reads the header of a DSF stream, and returns a decoder which
+// is positioned at the beginning of its sample data.
+//
+// Only uncompressed DSD data can be decoded.  The metadata chunk, which
+// follows the sample data, is never read.
+func newDSFDecoder(r interface{}) (audio.Decoder, error) {
+	rr, ok := r.(io.Reader)
+	if !ok {
+		return nil, audio.ErrInvalidData
+	}
+
+	var header [28]byte
+	if _, err := io.ReadFull(rr, header[:]); err != nil {
+		return nil, audio.ErrUnexpectedEOS
+	}
+	if string(header[0:4]) != "DSD " {
+		return nil, audio.ErrInvalidData
+	}
+
+	var f *dsfFormat
+	for {
+		var chunk [12]byte
+		if _, err := io.ReadFull(rr, chunk[:]); err != nil {
+			return nil, audio.ErrUnexpectedEOS
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint64(chunk[4:12])) - 12
+		if size < 0 {
+			return nil, audio.ErrInvalidData
+		}
+
+		switch id {
+		case "fmt ":
+			if size > dsdMaxHeaderSize {
+				return nil, audio.ErrInvalidData
+			}
+
+			b := make([]byte, size)
+			if _, err := io.ReadFull(rr, b); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+
+			var err error
+			if f, err = readDSFFormat(b); err != nil {
+				return nil, err
+			}
+		case "data":
+			// Sample data must be described by a format chunk
+			if f == nil {
+				return nil, audio.ErrInvalidData
+			}
+
+			data := &dsfReader{
+				r:         &dsdDataReader{r: rr, remaining: size},
+				format:    f,
+				remaining: (f.samples + 7) / 8,
+			}
+			return newDSDDecoder(data, f.sampleRate, f.channels)
+		default:
+			if _, err := io.CopyN(ioutil.Discard, rr, size); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+		}
+	}
+}
+
+// dsfFormat describes the sample data of a DSF stream.
+type dsfFormat struct {
+	sampleRate int
+	channels   int
+
+	// Whether the earliest sample is stored in the least significant bit
+	// of each byte
+	lsbFirst bool
+
+	// Number of samples per channel, and bytes per channel in each block
+	samples   int64
+	blockSize int
+}
+
+// readDSFFormat parses the body of a DSF format chunk.
+func readDSFFormat(b []byte) (*dsfFormat, error) {
+	if len(b) < 40 {
+		return nil, audio.ErrInvalidData
+	}
+
+	// Only raw DSD data is defined
+	if binary.LittleEndian.Uint32(b[4:8]) != 0 {
+		return nil, audio.ErrFormat
+	}
+
+	f := &dsfFormat{
+		channels:   int(binary.LittleEndian.Uint32(b[12:16])),
+		sampleRate: int(binary.LittleEndian.Uint32(b[16:20])),
+		samples:    int64(binary.LittleEndian.Uint64(b[24:32])),
+		blockSize:  int(binary.LittleEndian.Uint32(b[32:36])),
+	}
+
+	switch binary.LittleEndian.Uint32(b[20:24]) {
+	case 1:
+		f.lsbFirst = true
+	case 8:
+	default:
+		return nil, audio.ErrInvalidData
+	}
+	if f.channels <= 0 || f.channels > 6 || f.blockSize <= 0 || f.samples < 0 {
+		return nil, audio.ErrInvalidData
+	}
+
+	return f, nil
+}
+
+// dsfReader is an io.Reader which converts the blocks of a DSF stream, each of
+// which contains the bytes of a single channel, to interleaved bytes with the
+// earliest sample in the most significant bit.
+type dsfReader struct {
+	r      io.Reader
+	format *dsfFormat
+
+	// Bytes of each channel which remain to be read, excluding the padding
+	// of the final block
+	remaining int64
+
+	// Blocks of all channels, their interleaved bytes, and the interleaved
+	// bytes not yet read
+	block []byte
+	out   []byte
+	buf   []byte
+}
+
+// Read reads interleaved DSD bytes into p.
+func (r *dsfReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// fill reads the next block of each channel, and interleaves their bytes.
+func (r *dsfReader) fill() error {
+	if r.remaining <= 0 {
+		return io.EOF
+	}
+
+	f := r.format
+	if r.block == nil {
+		r.block = make([]byte, f.blockSize*f.channels)
+		r.out = make([]byte, f.blockSize*f.channels)
+	}
+	if _, err := io.ReadFull(r.r, r.block); err != nil {
+		return audio.ErrUnexpectedEOS
+	}
+
+	valid := f.blockSize
+	if int64(valid) > r.remaining {
+		valid = int(r.remaining)
+	}
+	r.remaining -= int64(valid)
+
+	for ch := 0; ch < f.channels; ch++ {
+		block := r.block[ch*f.blockSize:]
+		for i := 0; i < valid; i++ {
+			v := block[i]
+			if f.lsbFirst {
+				v = bits.Reverse8(v)
+			}
+			r.out[i*f.channels+ch] = v
+		}
+	}
+
+	r.buf = r.out[:valid*f.channels]
+	return nil
+}
+
+// newDFFDecoder reads the header of a DSDIFF stream, and returns a decoder
+// which is positioned at the beginning of its sample data.
+//
+// The property chunk must precede the DSD sound data chunk, so that the stream
+// can be decoded without buffering.  DST compressed streams cannot be decoded,
+// and ErrFormat is returned.
+func newDFFDecoder(r interface{}) (audio.Decoder, error) {
+	rr, ok := r.(io.Reader)
+	if !ok {
+		return nil, audio.ErrInvalidData
+	}
+
+	var header [16]byte
+	if _, err := io.ReadFull(rr, header[:]); err != nil {
+		return nil, audio.ErrUnexpectedEOS
+	}
+	if string(header[0:4]) != "FRM8" || string(header[12:16]) != "DSD " {
+		return nil, audio.ErrInvalidData
+	}
+
+	var sampleRate, channels int
+	for {
+		var chunk [12]byte
+		if _, err := io.ReadFull(rr, chunk[:]); err != nil {
+			return nil, audio.ErrUnexpectedEOS
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.BigEndian.Uint64(chunk[4:12]))
+		if size < 0 {
+			return nil, audio.ErrInvalidData
+		}
+
+		switch id {
+		case "PROP":
+			if size > dsdMaxHeaderSize {
+				return nil, audio.ErrInvalidData
+			}
+
+			b := make([]byte, size+size&1)
+			if _, err := io.ReadFull(rr, b); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+
+			var err error
+			if sampleRate, channels, err = readDFFProperties(b[:size]); err != nil {
+				return nil, err
+			}
+		case "DSD ":
+			// Sample data must be described by a property chunk
+			if sampleRate == 0 {
+				return nil, audio.ErrInvalidData
+			}
+
+			return newDSDDecoder(&dsdDataReader{r: rr, remaining: size}, sampleRate, channels)
+		case "DST ":
+			return nil, audio.ErrFormat
+		default:
+			// Skip all other chunks, which are padded to an even size
+			if _, err := io.CopyN(ioutil.Discard, rr, size+size&1); err != nil {
+				return nil, audio.ErrUnexpectedEOS
+			}
+		}
+	}
+}
+
+// readDFFProperties parses the body of a DSDIFF sound property chunk, and
+// returns the sample rate and channels of the stream.
+func readDFFProperties(b []byte) (int, int, error) {
+	if len(b) < 4 || string(b[0:4]) != "SND " {
+		return 0, 0, audio.ErrInvalidData
+	}
+	b = b[4:]
+
+	var sampleRate, channels int
+	for len(b) > 0 {
+		if len(b) < 12 {
+			return 0, 0, audio.ErrInvalidData
+		}
+		id := string(b[0:4])
+		size := binary.BigEndian.Uint64(b[4:12])
+		b = b[12:]
+		if size > uint64(len(b)) {
+			return 0, 0, audio.ErrInvalidData
+		}
+		body := b[:size]
+
+		switch id {
+		case "FS  ":
+			if len(body) < 4 {
+				return 0, 0, audio.ErrInvalidData
+			}
+			sampleRate = int(binary.BigEndian.Uint32(body[0:4]))
+		case "CHNL":
+			if len(body) < 2 {
+				return 0, 0, audio.ErrInvalidData
+			}
+			channels = int(binary.BigEndian.Uint16(body[0:2]))
+		case "CMPR":
+			// Only uncompressed DSD data can be decoded
+			if len(body) < 4 {
+				return 0, 0, audio.ErrInvalidData
+			}
+			if string(body[0:4]) != "DSD " {
+				return 0, 0, audio.ErrFormat
+			}
+		}
+
+		// Skip to the next chunk, which is padded to an even size
+		b = b[size:]
+		if size&1 != 0 && len(b) > 0 {
+			b = b[1:]
+		}
+	}
+
+	if sampleRate == 0 || channels == 0 {
+		return 0, 0, audio.ErrInvalidData
+	}
+
+	return sampleRate, channels, nil
+}
@@ -0,0 +1,207 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestDSDDecoder verifies that DSF and DSDIFF streams with the same DSD data
+// are decimated to the same PCM samples, for each DSF bit order.
+func TestDSDDecoder(t *testing.T) {
+	// 40 PCM samples of full scale positive and negative DC, in the left and
+	// right channels
+	data := make([]byte, 2*8*40)
+	for i := 0; i < len(data); i += 2 {
+		data[i] = 0xff
+	}
+
+	var want audio.Float64
+	for i, stream := range [][]byte{
+		testDFF("DSD ", 2, 2822400, data),
+		testDSF(2, 2822400, 1, data),
+		testDSF(2, 2822400, 8, data),
+	} {
+		d, err := openDecoder(bufio.NewReader(bytes.NewReader(stream)))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if c := d.Config(); c.SampleRate != 44100 || c.Channels != 2 {
+			t.Fatalf("[%02d] unexpected config: %v", i, c)
+		}
+
+		samples := make(audio.Float64, 100)
+		n, err := d.Read(samples)
+		if err != audio.EOS {
+			t.Fatalf("[%02d] unexpected Read error: %v", i, err)
+		}
+		if n != 80 {
+			t.Fatalf("[%02d] unexpected samples length: %v != %v", i, n, 80)
+		}
+		samples = samples[:n]
+
+		// Once the filter spans only DC, its output is exactly DC
+		if l, r := samples[n-2], samples[n-1]; math.Abs(l-1) > 1e-9 || math.Abs(r+1) > 1e-9 {
+			t.Fatalf("[%02d] unexpected final samples: %v, %v", i, l, r)
+		}
+
+		if want == nil {
+			want = samples
+			continue
+		}
+		for j := range want {
+			if samples[j] != want[j] {
+				t.Fatalf("[%02d] unexpected sample at %d: %v != %v", i, j, samples[j], want[j])
+			}
+		}
+	}
+}
+
+// TestDSDDecoderSampleRate verifies that DSD streams are decimated by a whole
+// number of bytes, to at most 44.1kHz.
+func TestDSDDecoderSampleRate(t *testing.T) {
+	var tests = []struct {
+		dsd int
+		pcm int
+	}{
+		{2822400, 44100},
+		{5644800, 44100},
+		{3072000, 48000},
+		{352800, 44100},
+		{64000, 8000},
+	}
+
+	for i, test := range tests {
+		d, err := newDSDDecoder(bytes.NewReader(nil), test.dsd, 1)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if rate := d.Config().SampleRate; rate != test.pcm {
+			t.Fatalf("[%02d] unexpected sample rate: %v != %v", i, rate, test.pcm)
+		}
+	}
+}
+
+// TestWaveformComputeDSDErrors verifies that the Waveform.Compute method
+// returns appropriate errors for invalid or unsupported DSD streams.
+func TestWaveformComputeDSDErrors(t *testing.T) {
+	data := make([]byte, 8*100)
+	valid := testDFF("DSD ", 1, 2822400, data)
+
+	// DSF stream using an undefined format ID
+	unknown := testDSF(1, 2822400, 1, data)
+	binary.LittleEndian.PutUint32(unknown[28+12+4:], 1)
+
+	var tests = []struct {
+		data []byte
+		err  error
+	}{
+		// DST compressed data
+		{testDFF("DST ", 1, 2822400, data), ErrFormat},
+		{unknown, ErrFormat},
+		// Truncated header
+		{valid[:30], ErrUnexpectedEOS},
+		// Truncated sample data
+		{valid[:len(valid)-2], ErrUnexpectedEOS},
+		{testDSF(1, 2822400, 1, data)[:100], ErrUnexpectedEOS},
+		// Invalid bits per sample
+		{testDSF(1, 2822400, 4, data), ErrInvalidData},
+		// Implausible number of channels or sample rate
+		{testDFF("DSD ", channelsMax+1, 2822400, make([]byte, channelsMax+1)), ErrInvalidData},
+		{testDFF("DSD ", 1, 1<<31, data), ErrInvalidData},
+	}
+
+	for i, test := range tests {
+		if _, err := testComputeValues(bytes.NewReader(test.data)); err != test.err {
+			t.Fatalf("[%02d] unexpected Compute error: %v != %v", i, err, test.err)
+		}
+	}
+}
+
+// testDSF is a test helper which generates a DSF stream with the input
+// channels, DSD sample rate, and bits per sample, from DSD data which is
+// interleaved by channel, with the earliest sample in the most significant
+// bit of each byte.  Small blocks are used, so that the data spans multiple
+// blocks, and the final block is padded.
+func testDSF(channels uint32, sampleRate uint32, bitsPerSample uint32, data []byte) []byte {
+	const blockSize = 48
+
+	// Split interleaved data into a block for each channel, in the bit order
+	// of the stream
+	perChannel := len(data) / int(channels)
+	blocks := (perChannel + blockSize - 1) / blockSize
+	body := make([]byte, blocks*blockSize*int(channels))
+	for i, v := range data {
+		ch, j := i%int(channels), i/int(channels)
+		if bitsPerSample == 1 {
+			v = bits.Reverse8(v)
+		}
+
+		block := j / blockSize
+		body[(block*int(channels)+ch)*blockSize+j%blockSize] = v
+	}
+
+	fmtChunk := binary.LittleEndian.AppendUint32(nil, 1)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, 0)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, channels)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, channels)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, sampleRate)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, bitsPerSample)
+	fmtChunk = binary.LittleEndian.AppendUint64(fmtChunk, uint64(perChannel*8))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, blockSize)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, 0)
+
+	chunk := func(id string, body []byte) []byte {
+		out := append([]byte(id), binary.LittleEndian.AppendUint64(nil, uint64(12+len(body)))...)
+		return append(out, body...)
+	}
+
+	out := chunk("fmt ", fmtChunk)
+	out = append(out, chunk("data", body)...)
+
+	header := append([]byte("DSD "), binary.LittleEndian.AppendUint64(nil, 28)...)
+	header = binary.LittleEndian.AppendUint64(header, uint64(28+len(out)))
+	header = binary.LittleEndian.AppendUint64(header, 0)
+	return append(header, out...)
+}
+
+// testDFF is a test helper which generates a DSDIFF stream with the input
+// compression type, channels, and DSD sample rate, from DSD data which is
+// interleaved by channel.
+func testDFF(compression string, channels uint16, sampleRate uint32, data []byte) []byte {
+	prop := []byte("SND ")
+	prop = append(prop, testDFFChunk("FS  ", binary.BigEndian.AppendUint32(nil, sampleRate))...)
+	chnl := binary.BigEndian.AppendUint16(nil, channels)
+	for i := uint16(0); i < channels; i++ {
+		chnl = append(chnl, "C   "...)
+	}
+	prop = append(prop, testDFFChunk("CHNL", chnl)...)
+	prop = append(prop, testDFFChunk("CMPR", append([]byte(compression), 0))...)
+
+	out := testDFFChunk("FVER", []byte{1, 5, 0, 0})
+	out = append(out, testDFFChunk("PROP", prop)...)
+	out = append(out, testDFFChunk(compression, data)...)
+
+	header := append([]byte("FRM8"), binary.BigEndian.AppendUint64(nil, uint64(4+len(out)))...)
+	header = append(header, "DSD "...)
+	return append(header, out...)
+}
+
+// testDFFChunk is a test helper which generates a DSDIFF chunk with the input
+// ID and body, padded to an even size.
+func testDFFChunk(id string, body []byte) []byte {
+	out := append([]byte(id), binary.BigEndian.AppendUint64(nil, uint64(len(body)))...)
+	out = append(out, body...)
+	if len(body)%2 != 0 {
+		out = append(out, 0)
+	}
+
+	return out
+}
@@ -0,0 +1,165 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"azul3d.org/engine/audio"
+)
+
+// ErrExecDecoder is matched by the error returned when an external decoder
+// set by the ExecDecoder option fails.  The returned error is an *ExecError,
+// which reports the command and its error output.
+var ErrExecDecoder = errors.New("waveform: external decoder failed")
+
+// execDefaultArgs are the arguments used when ExecDecoder is called with only
+// a command name, which instruct ffmpeg to convert its standard input to a WAV
+// stream of 32-bit floating point samples on its standard output.
+var execDefaultArgs = []string{
+	"-hide_banner", "-loglevel", "error",
+	"-i", "pipe:0",
+	"-f", "wav", "-acodec", "pcm_f32le",
+	"pipe:1",
+}
+
+// ExecError is returned when an external decoder set by the ExecDecoder option
+// cannot be started, exits with an error, or does not write a WAV stream.
+type ExecError struct {
+	Name   string
+	Err    error
+	Stderr string
+}
+
+// Error returns the string representation of an ExecError.
+func (e *ExecError) Error() string {
+	s := fmt.Sprintf("%s: %s: %v", ErrExecDecoder.Error(), e.Name, e.Err)
+	if e.Stderr != "" {
+		s += ": " + e.Stderr
+	}
+
+	return s
+}
+
+// Is reports whether target is ErrExecDecoder.
+func (e *ExecError) Is(target error) bool {
+	return target == ErrExecDecoder
+}
+
+// execCommand describes an external decoder, as set by the ExecDecoder option.
+type execCommand struct {
+	name string
+	args []string
+}
+
+// execDecoder is an audio.Decoder which decodes the WAV stream written by an
+// external decoder process to its standard output.  The process must be
+// stopped using Close if the stream is not read to its end.
+type execDecoder struct {
+	audio.Decoder
+
+	name   string
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+}
+
+// newExecDecoder starts an external decoder process, which reads the input
+// stream from its standard input, and returns a decoder for the WAV stream it
+// writes to its standard output.
+func newExecDecoder(r io.Reader, c execCommand) (audio.Decoder, error) {
+	args := c.args
+	if len(args) == 0 {
+		args = execDefaultArgs
+	}
+
+	d := &execDecoder{
+		name: c.name,
+		cmd:  exec.Command(c.name, args...),
+	}
+	d.cmd.Stdin = r
+	d.cmd.Stderr = &d.stderr
+
+	stdout, err := d.cmd.StdoutPipe()
+	if err != nil {
+		return nil, &ExecError{Name: c.name, Err: err}
+	}
+	d.stdout = stdout
+
+	if err := d.cmd.Start(); err != nil {
+		return nil, &ExecError{Name: c.name, Err: err}
+	}
+	runtime.SetFinalizer(d, (*execDecoder).Close)
+
+	// If no WAV stream is written, the process most likely failed, and its
+	// exit status and error output describe why
+	wav, err := newWAVDecoder(stdout)
+	if err != nil {
+		if werr := d.wait(); werr != nil {
+			return nil, werr
+		}
+
+		return nil, &ExecError{Name: c.name, Err: err}
+	}
+	d.Decoder = wav
+
+	return d, nil
+}
+
+// Read decodes samples into b, returning the number of samples read.  When the
+// WAV stream ends, the process must exit successfully, or its error is
+// returned in place of audio.EOS.
+func (d *execDecoder) Read(b audio.Slice) (int, error) {
+	n, err := d.Decoder.Read(b)
+	if err == nil {
+		return n, nil
+	}
+
+	if werr := d.wait(); werr != nil {
+		return n, werr
+	}
+
+	return n, err
+}
+
+// wait waits for the process to exit, and returns an *ExecError if it failed.
+// It is safe to call more than once.
+func (d *execDecoder) wait() error {
+	if d.cmd == nil {
+		return nil
+	}
+
+	// Discard any output which was not read, so that the process does not
+	// block while writing it
+	io.Copy(ioutil.Discard, d.stdout)
+
+	err := d.cmd.Wait()
+	d.cmd = nil
+	if err == nil {
+		return nil
+	}
+
+	return &ExecError{
+		Name:   d.name,
+		Err:    err,
+		Stderr: strings.TrimSpace(d.stderr.String()),
+	}
+}
+
+// Close stops the process, if it has not already exited.  It is safe to call
+// more than once.
+func (d *execDecoder) Close() error {
+	if d.cmd == nil {
+		return nil
+	}
+
+	d.cmd.Process.Kill()
+	d.cmd.Wait()
+	d.cmd = nil
+	return nil
+}
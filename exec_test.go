@@ -0,0 +1,155 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestWaveformComputeExecDecoder verifies that streams of an unknown format
+// are decoded by the command set by ExecDecoder, including WAV streams of an
+// unknown size, as written to a pipe.
+func TestWaveformComputeExecDecoder(t *testing.T) {
+	testExecShell(t)
+
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	streamed := append([]byte(nil), wav...)
+	binary.LittleEndian.PutUint32(streamed[40:44], wavUnknownSize)
+
+	var tests = []struct {
+		data []byte
+	}{
+		{append([]byte("XXXX"), wav...)},
+		{append([]byte("XXXX"), streamed...)},
+	}
+
+	want, err := testComputeValues(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range tests {
+		// The command strips the unknown header from the stream
+		values, err := testComputeValues(
+			bytes.NewReader(test.data),
+			ExecDecoder("sh", "-c", "tail -c +5"),
+		)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if !reflect.DeepEqual(values, want) {
+			t.Fatalf("[%02d] unexpected values: %v != %v", i, values, want)
+		}
+	}
+}
+
+// TestWaveformComputeExecDecoderNotUsed verifies that the command set by
+// ExecDecoder is not used for streams of a known format.
+func TestWaveformComputeExecDecoderNotUsed(t *testing.T) {
+	values, err := testComputeValues(
+		bytes.NewReader(testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64})),
+		ExecDecoder("waveform-no-such-command"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+// TestWaveformComputeExecDecoderErrors verifies that an *ExecError is returned
+// when the command set by ExecDecoder cannot be started, or fails.
+func TestWaveformComputeExecDecoderErrors(t *testing.T) {
+	testExecShell(t)
+
+	var tests = []struct {
+		name   string
+		args   []string
+		stderr string
+	}{
+		{name: "waveform-no-such-command"},
+		{name: "sh", args: []string{"-c", "cat >/dev/null; echo unsupported codec >&2; exit 1"}, stderr: "unsupported codec"},
+		{name: "sh", args: []string{"-c", "cat >/dev/null; echo not audio"}},
+	}
+
+	for i, test := range tests {
+		_, err := testComputeValues(
+			bytes.NewReader([]byte("not audio")),
+			ExecDecoder(test.name, test.args...),
+		)
+		if !errors.Is(err, ErrExecDecoder) {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, ErrExecDecoder)
+		}
+
+		var eerr *ExecError
+		if !errors.As(err, &eerr) || eerr.Name != test.name || eerr.Stderr != test.stderr {
+			t.Fatalf("[%02d] unexpected ExecError: %#v", i, err)
+		}
+		if !strings.HasPrefix(err.Error(), ErrExecDecoder.Error()) {
+			t.Fatalf("[%02d] unexpected error string: %v", i, err)
+		}
+	}
+}
+
+// TestWaveformComputeExecDecoderMaxSamples verifies that the process of the
+// command set by ExecDecoder is stopped when the Waveform.Compute method stops
+// at the MaxSamples limit, before the process has exited.
+func TestWaveformComputeExecDecoderMaxSamples(t *testing.T) {
+	testExecShell(t)
+
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	binary.LittleEndian.PutUint32(wav[40:44], wavUnknownSize)
+	data := append([]byte("XXXX"), wav...)
+
+	// The command records its process ID, strips the unknown header from the
+	// stream, and then keeps running, as a decoder would on a long stream
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	for i, truncate := range []bool{false, true} {
+		_, err := testComputeValues(
+			bytes.NewReader(data),
+			ExecDecoder("sh", "-c", `echo $$ > "$0"; tail -c +5; exec sleep 60`, pidFile),
+			MaxSamples(4, truncate),
+		)
+		if !truncate && err != ErrLimitExceeded {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, ErrLimitExceeded)
+		}
+		if truncate && err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		b, err := ioutil.ReadFile(pidFile)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		// The process has exited and been waited for, so it no longer exists
+		p, err := os.FindProcess(pid)
+		if err == nil && p.Signal(syscall.Signal(0)) == nil {
+			p.Kill()
+			t.Fatalf("[%02d] process %d is still running", i, pid)
+		}
+	}
+}
+
+// testExecShell skips the current test if no shell is available to run
+// external decoder commands.
+func testExecShell(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+}
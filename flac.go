@@ -0,0 +1,241 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// FLAC metadata block types which are parsed by this package.
+const (
+	flacBlockStreamInfo = 0
+	flacBlockSeekTable  = 3
+	flacBlockCueSheet   = 5
+)
+
+const (
+	// flacSeekPointPlaceholder is the sample number of a placeholder seek point,
+	// which does not describe a position in the stream
+	flacSeekPointPlaceholder = 1<<64 - 1
+
+	// flacCueSheetHeaderLen is the length of the CUESHEET block fields which
+	// precede its number of tracks
+	flacCueSheetHeaderLen = 128 + 8 + 1 + 258
+
+	// flacCueSheetTrackLen is the length of the CUESHEET track fields which
+	// precede its number of index points
+	flacCueSheetTrackLen = 8 + 1 + 12 + 1 + 13
+
+	// flacLeadOutCD and flacLeadOut are the track numbers of the lead-out
+	// track of a CUESHEET, for CD-DA and all other streams
+	flacLeadOutCD = 170
+	flacLeadOut   = 255
+
+	// flacMaxBlockSize is the largest metadata block which is read into
+	// memory to be parsed
+	flacMaxBlockSize = 1 << 20
+)
+
+// markerDecoder is an audio.Decoder which reports the timestamps of markers
+// embedded in its stream, such as chapters, and the tracks of its stream.
+type markerDecoder struct {
+	audio.Decoder
+	markers []time.Duration
+	tracks  []Track
+}
+
+// flacCueTrack is a track of a CUESHEET block.
+type flacCueTrack struct {
+	number int
+	sample uint64
+}
+
+// newFLACDecoder reads all metadata blocks of a FLAC stream, and opens an
+// audio decoder on the stream.  If the metadata contains any markers, a
+// markerDecoder is returned.
+//
+// Markers are read from the CUESHEET block, as the start of each track, if
+// one is present.  Otherwise, they are read from the SEEKTABLE block, as the
+// position of each seek point.
+func newFLACDecoder(br *bufio.Reader) (audio.Decoder, error) {
+	// Retain all bytes of the metadata, so that they can be read again by
+	// the audio decoder.  Errors in the metadata are reported by the audio
+	// decoder instead, so markers are simply omitted.
+	buf := bytes.NewBuffer(nil)
+	markers, tracks, err := readFLACMarkers(io.TeeReader(br, buf))
+	if err != nil {
+		markers, tracks = nil, nil
+	}
+
+	decoder, _, err := audio.NewDecoder(io.MultiReader(buf, br))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(markers) == 0 {
+		return decoder, nil
+	}
+
+	return &markerDecoder{
+		Decoder: decoder,
+		markers: markers,
+		tracks:  tracks,
+	}, nil
+}
+
+// readFLACMarkers reads the metadata blocks of a FLAC stream, and returns the
+// timestamps of any markers they contain, in order, along with the tracks of
+// its CUESHEET block, if any.
+func readFLACMarkers(r io.Reader) ([]time.Duration, []Track, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || string(magic[:]) != "fLaC" {
+		return nil, nil, audio.ErrInvalidData
+	}
+
+	var sampleRate uint32
+	var seekPoints []uint64
+	var cueTracks []flacCueTrack
+	for last := false; !last; {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, nil, audio.ErrUnexpectedEOS
+		}
+		last = header[0]&0x80 != 0
+		typ := header[0] & 0x7f
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		// Only the blocks which are parsed are read into memory, and all
+		// others, such as pictures, are skipped
+		switch typ {
+		case flacBlockStreamInfo, flacBlockSeekTable, flacBlockCueSheet:
+		default:
+			if _, err := io.CopyN(ioutil.Discard, r, int64(size)); err != nil {
+				return nil, nil, audio.ErrUnexpectedEOS
+			}
+			continue
+		}
+		if size > flacMaxBlockSize {
+			return nil, nil, audio.ErrInvalidData
+		}
+
+		b := make([]byte, size)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, nil, audio.ErrUnexpectedEOS
+		}
+
+		switch typ {
+		case flacBlockStreamInfo:
+			if len(b) < 13 {
+				return nil, nil, audio.ErrInvalidData
+			}
+			sampleRate = uint32(b[10])<<12 | uint32(b[11])<<4 | uint32(b[12])>>4
+		case flacBlockSeekTable:
+			seekPoints = parseFLACSeekTable(b)
+		case flacBlockCueSheet:
+			cueTracks = parseFLACCueSheet(b)
+		}
+	}
+
+	// Markers cannot be converted to timestamps without a sample rate
+	if sampleRate == 0 {
+		return nil, nil, nil
+	}
+	timestamp := func(s uint64) time.Duration {
+		return time.Duration(s) * time.Second / time.Duration(sampleRate)
+	}
+
+	// Tracks are ordered by their start, regardless of their numbers
+	var tracks []Track
+	for _, t := range cueTracks {
+		tracks = append(tracks, Track{
+			Number:      t.number,
+			StartSample: int64(t.sample),
+			Start:       timestamp(t.sample),
+		})
+	}
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return tracks[i].StartSample < tracks[j].StartSample
+	})
+
+	// Markers are the start of each track, if there is a CUESHEET block, and
+	// otherwise each seek point
+	markers := make([]time.Duration, 0, len(seekPoints))
+	if cueTracks != nil {
+		for _, t := range tracks {
+			markers = append(markers, t.Start)
+		}
+	} else {
+		for _, s := range seekPoints {
+			markers = append(markers, timestamp(s))
+		}
+	}
+
+	sort.Slice(markers, func(i, j int) bool {
+		return markers[i] < markers[j]
+	})
+	return markers, tracks, nil
+}
+
+// parseFLACSeekTable returns the sample number of each seek point in the body
+// of a SEEKTABLE block, skipping any placeholders.
+func parseFLACSeekTable(b []byte) []uint64 {
+	var samples []uint64
+	for ; len(b) >= 18; b = b[18:] {
+		s := binary.BigEndian.Uint64(b[0:8])
+		if s == flacSeekPointPlaceholder {
+			continue
+		}
+
+		samples = append(samples, s)
+	}
+
+	return samples
+}
+
+// parseFLACCueSheet returns the number and the sample number of the start of
+// each track in the body of a CUESHEET block, skipping the lead-out track.  A
+// track starts at its index point 1, if it has one, and otherwise at its first
+// index point.
+func parseFLACCueSheet(b []byte) []flacCueTrack {
+	if len(b) <= flacCueSheetHeaderLen {
+		return nil
+	}
+
+	count := int(b[flacCueSheetHeaderLen])
+	b = b[flacCueSheetHeaderLen+1:]
+
+	tracks := []flacCueTrack{}
+	for i := 0; i < count && len(b) > flacCueSheetTrackLen; i++ {
+		offset := binary.BigEndian.Uint64(b[0:8])
+		number := b[8]
+		indices := int(b[flacCueSheetTrackLen])
+		b = b[flacCueSheetTrackLen+1:]
+
+		// Find the index point at which the track starts
+		var start uint64
+		for j := 0; j < indices && len(b) >= 12; j++ {
+			index := binary.BigEndian.Uint64(b[0:8])
+			if j == 0 || b[8] == 1 {
+				start = index
+			}
+			b = b[12:]
+		}
+
+		if number == flacLeadOutCD || number == flacLeadOut {
+			continue
+		}
+
+		tracks = append(tracks, flacCueTrack{
+			number: int(number),
+			sample: offset + start,
+		})
+	}
+
+	return tracks
+}
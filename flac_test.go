@@ -0,0 +1,305 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestReadFLACMarkersSeekTable verifies that readFLACMarkers reads the seek
+// points of a FLAC stream as markers.
+func TestReadFLACMarkersSeekTable(t *testing.T) {
+	markers, _, err := readFLACMarkers(bytes.NewReader(flacFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test file contains a single seek point, at the beginning of the stream
+	if want := []time.Duration{0}; !reflect.DeepEqual(markers, want) {
+		t.Fatalf("unexpected markers: %v != %v", markers, want)
+	}
+}
+
+// TestReadFLACMarkersCueSheet verifies that readFLACMarkers prefers the tracks
+// of a CUESHEET block over seek points, skipping the lead-out track and
+// placeholder seek points.
+func TestReadFLACMarkersCueSheet(t *testing.T) {
+	seekTable := testFLACSeekTable(0, 441000, flacSeekPointPlaceholder)
+
+	// Second track starts at its index point 1, following a pregap
+	cueSheet := testFLACCueSheet([][3]uint64{
+		{441000, 2, 44100},
+		{0, 1, 0},
+		{882000, flacLeadOut, 0},
+	})
+
+	data := testFLACMetadata(44100, [][]byte{seekTable, cueSheet}, []byte{flacBlockSeekTable, flacBlockCueSheet})
+	markers, tracks, err := readFLACMarkers(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []time.Duration{0, 11 * time.Second}; !reflect.DeepEqual(markers, want) {
+		t.Fatalf("unexpected markers: %v != %v", markers, want)
+	}
+
+	// Tracks are ordered by their start
+	wantTracks := []Track{
+		{Number: 1, StartSample: 0, Start: 0},
+		{Number: 2, StartSample: 485100, Start: 11 * time.Second},
+	}
+	if !reflect.DeepEqual(tracks, wantTracks) {
+		t.Fatalf("unexpected tracks: %v != %v", tracks, wantTracks)
+	}
+
+	// Without a CUESHEET block, seek points are used
+	data = testFLACMetadata(44100, [][]byte{seekTable}, []byte{flacBlockSeekTable})
+	markers, tracks, err = readFLACMarkers(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tracks != nil {
+		t.Fatalf("unexpected tracks: %v", tracks)
+	}
+
+	if want := []time.Duration{0, 10 * time.Second}; !reflect.DeepEqual(markers, want) {
+		t.Fatalf("unexpected markers: %v != %v", markers, want)
+	}
+}
+
+// TestReadFLACMarkersErrUnexpectedEOS verifies that readFLACMarkers returns
+// ErrUnexpectedEOS when the stream ends within its metadata.
+func TestReadFLACMarkersErrUnexpectedEOS(t *testing.T) {
+	data := testFLACMetadata(44100, nil, nil)
+	if _, _, err := readFLACMarkers(bytes.NewReader(data[:len(data)-1])); err != ErrUnexpectedEOS {
+		t.Fatalf("unexpected error: %v != %v", err, ErrUnexpectedEOS)
+	}
+}
+
+// TestReadFLACMarkersLargeBlock verifies that readFLACMarkers skips large
+// metadata blocks which are not parsed, such as pictures, and returns
+// ErrInvalidData for a parsed block which is too large to be read into memory.
+func TestReadFLACMarkersLargeBlock(t *testing.T) {
+	picture := testFLACMetadata(44100, [][]byte{make([]byte, flacMaxBlockSize+1)}, []byte{6})
+	if _, _, err := readFLACMarkers(bytes.NewReader(picture)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seekTable := testFLACMetadata(44100, nil, nil)
+	seekTable = append(seekTable, 0x80|flacBlockSeekTable, 0xff, 0xff, 0xff)
+	seekTable[4] &^= 0x80
+	if _, _, err := readFLACMarkers(bytes.NewReader(seekTable)); err != ErrInvalidData {
+		t.Fatalf("unexpected error: %v != %v", err, ErrInvalidData)
+	}
+}
+
+// TestWaveformDrawMarkers verifies that the Waveform.Draw method draws a line
+// at each marker reported by Metadata, when DrawMarkers is set.
+func TestWaveformDrawMarkers(t *testing.T) {
+	w, err := New(nil, DrawMarkers(red), Scale(4, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three seconds of silence, with markers at 1.5 and 5 seconds
+	d := &markerDecoder{
+		Decoder: newSamplesDecoder(make([]float64, 300), 100, 1),
+		markers: []time.Duration{1500 * time.Millisecond, 5 * time.Second},
+	}
+	values, err := w.computeSamples(d, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := w.Metadata(); !reflect.DeepEqual(m.Markers, d.markers) {
+		t.Fatalf("unexpected markers: %v != %v", m.Markers, d.markers)
+	}
+
+	// Only the marker within the audio is drawn, at 1.5 seconds
+	img := w.Draw(values)
+	for x := 0; x < img.Bounds().Max.X; x++ {
+		want := color.RGBA(white)
+		if x == 6 {
+			want = red
+		}
+
+		for _, y := range []int{0, imgYDefault - 1} {
+			if c := img.At(x, y); c != want {
+				t.Fatalf("unexpected color at (%d,%d): %v != %v", x, y, c, want)
+			}
+		}
+	}
+}
+
+// TestWaveformDrawMarkersMaxWidth verifies that the Waveform.Draw method draws
+// each marker at its time within the merged bars, when values are merged to
+// fit the MaxWidth option.
+func TestWaveformDrawMarkersMaxWidth(t *testing.T) {
+	w, err := New(nil, DrawMarkers(red), MaxWidth(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Twenty seconds of silence, with markers at 5 and 15 seconds
+	d := &markerDecoder{
+		Decoder: newSamplesDecoder(make([]float64, 2000), 100, 1),
+		markers: []time.Duration{5 * time.Second, 15 * time.Second},
+	}
+	values, err := w.computeSamples(d, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each of the four bars spans five seconds
+	img := w.Draw(values)
+	if b := img.Bounds(); b.Dx() != 4 {
+		t.Fatalf("unexpected image width: %d", b.Dx())
+	}
+	for x := 0; x < 4; x++ {
+		want := color.RGBA(white)
+		if x == 1 || x == 3 {
+			want = red
+		}
+
+		if c := img.At(x, 0); c != want {
+			t.Fatalf("unexpected color at (%d,0): %v != %v", x, c, want)
+		}
+	}
+}
+
+// TestWaveformSplitTracks verifies that the Waveform.SplitTracks method splits
+// computed values at the start of each track reported by Metadata, including
+// when values are trimmed by TrimSilence.
+func TestWaveformSplitTracks(t *testing.T) {
+	// Five seconds of audio with a silent first second, with tracks starting
+	// at 0, 2.5, and 4 seconds
+	samples := make([]float64, 500)
+	for i := 100; i < len(samples); i++ {
+		samples[i] = 0.5
+	}
+	tracks := []Track{
+		{Number: 1, StartSample: 0},
+		{Number: 2, StartSample: 250, Start: 2500 * time.Millisecond},
+		{Number: 3, StartSample: 400, Start: 4 * time.Second},
+	}
+
+	var tests = []struct {
+		options []OptionsFunc
+		lengths []int
+	}{
+		{nil, []int{2, 2, 1}},
+		{[]OptionsFunc{TrimSilence(0.1)}, []int{1, 2, 1}},
+		{[]OptionsFunc{Resolution(2)}, []int{5, 3, 2}},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, test.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d := &markerDecoder{
+			Decoder: newSamplesDecoder(samples, 100, 1),
+			tracks:  tracks,
+		}
+		values, err := w.computeSamples(d, nil)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+		if m := w.Metadata(); !reflect.DeepEqual(m.Tracks, tracks) {
+			t.Fatalf("[%02d] unexpected tracks: %v != %v", i, m.Tracks, tracks)
+		}
+
+		split := w.SplitTracks(values)
+		var lengths []int
+		var joined []float64
+		for _, s := range split {
+			lengths = append(lengths, len(s))
+			joined = append(joined, s...)
+		}
+		if !reflect.DeepEqual(lengths, test.lengths) {
+			t.Fatalf("[%02d] unexpected track lengths: %v != %v", i, lengths, test.lengths)
+		}
+		if !reflect.DeepEqual(joined, values) {
+			t.Fatalf("[%02d] split values do not match values: %v != %v", i, joined, values)
+		}
+	}
+
+	// Without tracks, all values are a single track
+	w := &Waveform{}
+	if split := w.SplitTracks([]float64{1, 2}); !reflect.DeepEqual(split, [][]float64{{1, 2}}) {
+		t.Fatalf("unexpected split values: %v", split)
+	}
+}
+
+// testFLACMetadata is a test helper which generates the metadata of a FLAC
+// stream with the input sample rate, followed by metadata blocks with the
+// input bodies and types.
+func testFLACMetadata(sampleRate uint32, blocks [][]byte, types []byte) []byte {
+	streamInfo := make([]byte, 34)
+	streamInfo[10] = byte(sampleRate >> 12)
+	streamInfo[11] = byte(sampleRate >> 4)
+	streamInfo[12] = byte(sampleRate << 4)
+
+	blocks = append([][]byte{streamInfo}, blocks...)
+	types = append([]byte{flacBlockStreamInfo}, types...)
+
+	b := []byte("fLaC")
+	for i, block := range blocks {
+		typ := types[i]
+		if i == len(blocks)-1 {
+			typ |= 0x80
+		}
+
+		b = append(b, typ, byte(len(block)>>16), byte(len(block)>>8), byte(len(block)))
+		b = append(b, block...)
+	}
+
+	return b
+}
+
+// testFLACSeekTable is a test helper which generates the body of a SEEKTABLE
+// block with the input seek point sample numbers.
+func testFLACSeekTable(samples ...uint64) []byte {
+	b := make([]byte, 18*len(samples))
+	for i, s := range samples {
+		binary.BigEndian.PutUint64(b[i*18:], s)
+	}
+
+	return b
+}
+
+// testFLACCueSheet is a test helper which generates the body of a CUESHEET
+// block with the input tracks, each described by its offset, number, and the
+// offset of its index point 1.  Tracks with a non-zero index point 1 also
+// contain an index point 0, at offset 0.
+func testFLACCueSheet(tracks [][3]uint64) []byte {
+	b := make([]byte, flacCueSheetHeaderLen+1)
+	b[flacCueSheetHeaderLen] = byte(len(tracks))
+
+	for _, track := range tracks {
+		t := make([]byte, flacCueSheetTrackLen+1)
+		binary.BigEndian.PutUint64(t[0:8], track[0])
+		t[8] = byte(track[1])
+
+		var indices [][2]uint64
+		if track[2] != 0 {
+			indices = append(indices, [2]uint64{0, 0})
+		}
+		indices = append(indices, [2]uint64{track[2], 1})
+
+		t[flacCueSheetTrackLen] = byte(len(indices))
+		for _, index := range indices {
+			ib := make([]byte, 12)
+			binary.BigEndian.PutUint64(ib[0:8], index[0])
+			ib[8] = byte(index[1])
+			t = append(t, ib...)
+		}
+
+		b = append(b, t...)
+	}
+
+	return b
+}
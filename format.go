@@ -0,0 +1,220 @@
+package waveform
+
+import (
+	"errors"
+	"io"
+)
+
+// Audio format identifiers, as returned by SupportedFormats and DetectFormat.
+const (
+	FormatWAV      = "wav"
+	FormatFLAC     = "flac"
+	FormatMP3      = "mp3"
+	FormatMP4      = "mp4"
+	FormatVorbis   = "vorbis"
+	FormatOpus     = "opus"
+	FormatAIFF     = "aiff"
+	FormatCAF      = "caf"
+	FormatDSD      = "dsd"
+	FormatWavPack  = "wavpack"
+	FormatMatroska = "matroska"
+)
+
+// opusMagic is the magic string which begins an Ogg Opus stream: the first
+// Ogg page, containing a single segment with the Opus identification header.
+const opusMagic = "OggS" + "????????????????????????" + "OpusHead"
+
+// opusDecodable reports whether the current build can decode Opus streams.
+// It is set when the Opus decoder is built into the package.
+var opusDecodable bool
+
+// wavpackMagic is the magic string which begins each block of a WavPack stream.
+const wavpackMagic = "wvpk"
+
+// wavpackDecodable reports whether the current build can decode WavPack
+// streams.  It is set when the WavPack decoder is built into the package.
+var wavpackDecodable bool
+
+// ErrNotPeekable is returned by DetectFormat when the input stream cannot be
+// inspected without consuming the bytes needed to decode it.
+var ErrNotPeekable = errors.New("waveform: input stream cannot be peeked")
+
+// audioFormat describes an audio format which can be detected from one of the
+// magic strings at the beginning of a stream.  Any '?' in a magic string
+// matches any single byte.
+type audioFormat struct {
+	name   string
+	magics []string
+
+	// decodable reports whether the current build can decode the format
+	decodable func() bool
+}
+
+// audioFormats is the set of detectable audio formats, in order of detection.
+// It must be kept in sync with the formats registered with the audio package.
+var audioFormats = []audioFormat{
+	{name: FormatWAV, magics: wavMagics, decodable: alwaysDecodable},
+	{name: FormatFLAC, magics: []string{"fLaC"}, decodable: alwaysDecodable},
+	{name: FormatAIFF, magics: aiffMagics, decodable: alwaysDecodable},
+	{name: FormatCAF, magics: []string{cafMagic}, decodable: alwaysDecodable},
+	{name: FormatDSD, magics: dsdMagics, decodable: alwaysDecodable},
+	{name: FormatMP3, magics: mp3Magics, decodable: alwaysDecodable},
+	{name: FormatVorbis, magics: []string{vorbisMagic}, decodable: alwaysDecodable},
+	{name: FormatOpus, magics: []string{opusMagic}, decodable: func() bool {
+		return opusDecodable
+	}},
+	{name: FormatWavPack, magics: []string{wavpackMagic}, decodable: func() bool {
+		return wavpackDecodable
+	}},
+	{name: FormatMatroska, magics: []string{mkvMagic}, decodable: alwaysDecodable},
+	{name: FormatMP4, magics: []string{mp4Magic}, decodable: func() bool {
+		// Only tracks with an available codec can be decoded
+		return len(mp4Codecs) > 0
+	}},
+}
+
+// alwaysDecodable is a decodable function for formats which are always built
+// into this package.
+func alwaysDecodable() bool {
+	return true
+}
+
+// SupportedFormats returns the identifiers of all audio formats which can be
+// decoded by the current build of this package, such as "wav" and "flac".
+func SupportedFormats() []string {
+	var names []string
+	for _, f := range audioFormats {
+		if f.decodable() {
+			names = append(names, f.name)
+		}
+	}
+
+	return names
+}
+
+// DetectFormat inspects the beginning of an input audio stream, and returns
+// the identifier of its format, as returned by SupportedFormats, or
+// FormatCustom for a stream matched by a registered decoder.  If the
+// format is not recognized, or cannot be decoded by the current build,
+// ErrFormat is returned.  If the stream is empty, ErrNoSamples is returned.
+//
+// DetectFormat does not consume any bytes needed by a subsequent call to
+// Generate or New with the same stream.  To guarantee this, the stream must
+// be a *bufio.Reader, which is peeked, or an io.Seeker, which is returned to
+// its original position.  For all other streams, ErrNotPeekable is returned.
+// Generate and New use a *bufio.Reader directly, without wrapping it again.
+//
+// Any ID3v2 or APE tags at the beginning of the stream are skipped, so that
+// the format of the audio which follows them is detected.  If the tags are
+// larger than the buffer of a *bufio.Reader, the stream is assumed to be MP3,
+// where such tags are most common.
+func DetectFormat(r io.Reader) (string, error) {
+	header, err := peekPastTags(r, maxMagicLen())
+	if err != nil {
+		return "", err
+	}
+	if len(header) == 0 {
+		return "", ErrNoSamples
+	}
+
+	// Registered decoders take priority over all other formats
+	if findRegisteredDecoder(header) != nil {
+		return FormatCustom, nil
+	}
+
+	for _, f := range audioFormats {
+		if !f.match(header) {
+			continue
+		}
+
+		if !f.decodable() {
+			return "", ErrFormat
+		}
+
+		return f.name, nil
+	}
+
+	return "", ErrFormat
+}
+
+// peekHeader returns up to n bytes from the beginning of an input stream,
+// without consuming them.
+func peekHeader(r io.Reader, n int) ([]byte, error) {
+	// Prefer peeking, which is supported by *bufio.Reader
+	if p, ok := r.(interface {
+		Peek(n int) ([]byte, error)
+	}); ok {
+		b, err := p.Peek(n)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		return b, nil
+	}
+
+	// Otherwise, read and return to the original position
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return nil, ErrNotPeekable
+	}
+
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, n)
+	read, err := io.ReadFull(r, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+
+	if _, err := s.Seek(cur, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return b[:read], nil
+}
+
+// maxMagicLen returns the length of the longest magic string of all
+// detectable audio formats, including registered decoders.
+func maxMagicLen() int {
+	n := maxRegisteredMagicLen()
+	for _, f := range audioFormats {
+		for _, magic := range f.magics {
+			if len(magic) > n {
+				n = len(magic)
+			}
+		}
+	}
+
+	return n
+}
+
+// match reports whether the input header begins with any of the magic strings
+// of an audio format.
+func (f audioFormat) match(header []byte) bool {
+	for _, magic := range f.magics {
+		if matchMagic(header, magic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchMagic reports whether the input header begins with the input magic
+// string, where any '?' in the magic string matches any single byte.
+func matchMagic(header []byte, magic string) bool {
+	if len(header) < len(magic) {
+		return false
+	}
+
+	for i := 0; i < len(magic); i++ {
+		if magic[i] != '?' && magic[i] != header[i] {
+			return false
+		}
+	}
+
+	return true
+}
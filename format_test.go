@@ -0,0 +1,231 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// TestSupportedFormats verifies that SupportedFormats returns the formats
+// decodable by the current build.
+func TestSupportedFormats(t *testing.T) {
+	want := []string{FormatWAV, FormatFLAC, FormatAIFF, FormatCAF, FormatDSD, FormatMP3, FormatVorbis}
+	if opusDecodable {
+		want = append(want, FormatOpus)
+	}
+	if wavpackDecodable {
+		want = append(want, FormatWavPack)
+	}
+	want = append(want, FormatMatroska)
+	if len(mp4Codecs) > 0 {
+		want = append(want, FormatMP4)
+	}
+
+	if formats := SupportedFormats(); !reflect.DeepEqual(formats, want) {
+		t.Fatalf("unexpected SupportedFormats: %v != %v", formats, want)
+	}
+}
+
+// TestDetectFormat verifies that DetectFormat correctly identifies input
+// audio streams, or returns an appropriate error.
+func TestDetectFormat(t *testing.T) {
+	var tests = []struct {
+		data   []byte
+		format string
+		err    error
+	}{
+		{wavFile, FormatWAV, nil},
+		{testRF64("RF64", 1, 1, 8000, 8, nil, true), FormatWAV, nil},
+		{testRF64("BW64", 1, 1, 8000, 8, nil, true), FormatWAV, nil},
+		{flacFile, FormatFLAC, nil},
+		{mp3File, FormatMP3, nil},
+		{[]byte{0xff, 0xfb, 0x90, 0x64}, FormatMP3, nil},
+		{oggVorbisFile, FormatVorbis, nil},
+		{testAIFF("", 1, 16, 8000, nil), FormatAIFF, nil},
+		{testAIFF("sowt", 1, 16, 8000, nil), FormatAIFF, nil},
+		{testCAF("lpcm", 0, 1, 16, 8000, nil, false), FormatCAF, nil},
+		{testDSF(1, 2822400, 1, nil), FormatDSD, nil},
+		{testDFF("DSD ", 1, 2822400, nil), FormatDSD, nil},
+		{testMKV(testMKVAudioTrack(1, "A_PCM/INT/LIT", 8000, 1, 16)), FormatMatroska, nil},
+		{[]byte("OggS"), "", ErrFormat},
+		{testOggOpus(1, 0, 48000), testOpusFormat(), testOpusErr()},
+		{testWavPackBlock(0x410, 0), testWavPackFormat(), testWavPackErr()},
+		{[]byte("RIFF"), "", ErrFormat},
+		{nil, "", ErrNoSamples},
+	}
+
+	for i, test := range tests {
+		format, err := DetectFormat(bufio.NewReader(bytes.NewReader(test.data)))
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected DetectFormat error: %v != %v", i, err, test.err)
+		}
+		if format != test.format {
+			t.Fatalf("[%02d] unexpected DetectFormat format: %v != %v", i, format, test.format)
+		}
+	}
+}
+
+// TestDetectFormatNotConsumed verifies that DetectFormat does not consume any
+// bytes from peekable and seekable streams, so they may be used by Generate.
+func TestDetectFormatNotConsumed(t *testing.T) {
+	for i, r := range []io.Reader{
+		bufio.NewReader(bytes.NewReader(wavFile)),
+		bytes.NewReader(wavFile),
+	} {
+		if _, err := DetectFormat(r); err != nil {
+			t.Fatalf("[%02d] unexpected DetectFormat error: %v", i, err)
+		}
+
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, wavFile) {
+			t.Fatalf("[%02d] stream consumed by DetectFormat: %v != %v bytes", i, len(data), len(wavFile))
+		}
+	}
+}
+
+// TestDetectFormatErrNotPeekable verifies that DetectFormat returns
+// ErrNotPeekable for a stream which can neither be peeked nor seeked.
+func TestDetectFormatErrNotPeekable(t *testing.T) {
+	r := struct{ io.Reader }{bytes.NewReader(wavFile)}
+	if _, err := DetectFormat(r); err != ErrNotPeekable {
+		t.Fatalf("unexpected DetectFormat error: %v != %v", err, ErrNotPeekable)
+	}
+}
+
+// testOpusFormat returns the format DetectFormat returns for an Ogg Opus
+// stream in the current build.
+func testOpusFormat() string {
+	if opusDecodable {
+		return FormatOpus
+	}
+
+	return ""
+}
+
+// testOpusErr returns the error DetectFormat returns for an Ogg Opus stream
+// in the current build.
+func testOpusErr() error {
+	if opusDecodable {
+		return nil
+	}
+
+	return ErrFormat
+}
+
+// testWavPackFormat returns the format DetectFormat returns for a WavPack
+// stream in the current build.
+func testWavPackFormat() string {
+	if wavpackDecodable {
+		return FormatWavPack
+	}
+
+	return ""
+}
+
+// testWavPackErr returns the error DetectFormat returns for a WavPack stream
+// in the current build.
+func testWavPackErr() error {
+	if wavpackDecodable {
+		return nil
+	}
+
+	return ErrFormat
+}
+
+// testWavPackBlock is a test helper which generates the header of a WavPack
+// block with the input stream version and number of samples, and no
+// metadata sub-blocks.
+func testWavPackBlock(version uint16, samples uint32) []byte {
+	b := []byte(wavpackMagic)
+	b = binary.LittleEndian.AppendUint32(b, 24)
+	b = binary.LittleEndian.AppendUint16(b, version)
+	b = append(b, 0, 0)
+	b = binary.LittleEndian.AppendUint32(b, samples)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, samples)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	return binary.LittleEndian.AppendUint32(b, 0xffffffff)
+}
+
+// testOggOpus is a test helper which generates a mono Ogg Opus stream with
+// the input number of 20ms packets, pre-skip, and informational input sample
+// rate.  Each packet contains only a TOC byte, which is decoded as silence.
+func testOggOpus(packets int, preSkip uint16, inputRate uint32) []byte {
+	head := []byte("OpusHead\x01\x01")
+	head = binary.LittleEndian.AppendUint16(head, preSkip)
+	head = binary.LittleEndian.AppendUint32(head, inputRate)
+	head = append(head, 0, 0, 0)
+
+	tags := append([]byte("OpusTags"), 4, 0, 0, 0)
+	tags = append(tags, "test"...)
+	tags = append(tags, 0, 0, 0, 0)
+
+	out := testOggPage(0x02, 0, 0, [][]byte{head})
+	out = append(out, testOggPage(0, 0, 1, [][]byte{tags})...)
+
+	// CELT fullband 20ms frames, with 960 samples each at 48kHz
+	const perPage = 25
+	var granule int64
+	for seq := uint32(2); packets > 0; seq++ {
+		n := packets
+		if n > perPage {
+			n = perPage
+		}
+		packets -= n
+		granule += int64(n) * 960
+
+		var typ byte
+		if packets == 0 {
+			typ = 0x04
+		}
+
+		page := make([][]byte, n)
+		for i := range page {
+			page[i] = []byte{0xf8}
+		}
+
+		out = append(out, testOggPage(typ, granule, seq, page)...)
+	}
+
+	return out
+}
+
+// testOggPage is a test helper which generates an Ogg page with the input
+// header type, granule position, sequence number, and packets, each of which
+// must be smaller than 255 bytes.
+func testOggPage(typ byte, granule int64, seq uint32, packets [][]byte) []byte {
+	page := append([]byte("OggS"), 0, typ)
+	page = binary.LittleEndian.AppendUint64(page, uint64(granule))
+	page = binary.LittleEndian.AppendUint32(page, 1)
+	page = binary.LittleEndian.AppendUint32(page, seq)
+	page = append(page, 0, 0, 0, 0, byte(len(packets)))
+	for _, p := range packets {
+		page = append(page, byte(len(p)))
+	}
+	for _, p := range packets {
+		page = append(page, p...)
+	}
+
+	// Ogg uses a non-reflected CRC-32 over the page, with a zeroed checksum
+	var crc uint32
+	for _, b := range page {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	binary.LittleEndian.PutUint32(page[22:], crc)
+
+	return page
+}
@@ -0,0 +1,41 @@
+package waveform
+
+// ulawSample converts a single G.711 mu-law encoded sample to a float64 value
+// in [-1, 1].
+func ulawSample(u byte) float64 {
+	u = ^u
+
+	// Expand the 4-bit mantissa by the 3-bit exponent, removing the bias
+	t := (int(u&0x0f) << 3) + 0x84
+	t <<= (u & 0x70) >> 4
+
+	if u&0x80 != 0 {
+		return float64(0x84-t) / (1 << 15)
+	}
+
+	return float64(t-0x84) / (1 << 15)
+}
+
+// alawSample converts a single G.711 A-law encoded sample to a float64 value
+// in [-1, 1].
+func alawSample(a byte) float64 {
+	a ^= 0x55
+
+	// Expand the 4-bit mantissa by the 3-bit segment
+	t := int(a&0x0f) << 4
+	switch seg := (a & 0x70) >> 4; seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+
+	if a&0x80 != 0 {
+		return float64(t) / (1 << 15)
+	}
+
+	return float64(-t) / (1 << 15)
+}
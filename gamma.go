@@ -0,0 +1,78 @@
+package waveform
+
+import (
+	"image/color"
+	"math"
+)
+
+// linearColor is a color.Color which is blended from other colors, and can
+// be blended again in linear light, rather than in sRGB space.
+type linearColor interface {
+	color.Color
+
+	// linear returns the color blended in linear light.
+	linear() color.Color
+}
+
+// resolveColor returns the input color, blended in linear light if gamma is
+// true and the color supports it.
+func resolveColor(c color.Color, gamma bool) color.Color {
+	if !gamma {
+		return c
+	}
+
+	if lc, ok := c.(linearColor); ok {
+		return lc.linear()
+	}
+
+	return c
+}
+
+// srgbToLinear converts an sRGB component in [0, 1] to linear light.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts a linear light component in [0, 1] to sRGB.
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// linearRGBA returns the unpremultiplied components of a color in linear
+// light, and its alpha, all in [0, 1].
+func linearRGBA(c color.Color) (float64, float64, float64, float64) {
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return 0, 0, 0, 0
+	}
+
+	unpremultiply := func(v uint32) float64 {
+		return srgbToLinear(float64(v) / float64(a))
+	}
+
+	return unpremultiply(r), unpremultiply(g), unpremultiply(b), float64(a) / 0xffff
+}
+
+// fromLinearRGBA converts unpremultiplied components in linear light and an
+// alpha, all in [0, 1], to an alpha-premultiplied sRGB color.
+func fromLinearRGBA(r float64, g float64, b float64, a float64) color.RGBA64 {
+	premultiply := func(v float64) uint16 {
+		v = math.Min(math.Max(v, 0), 1)
+		return uint16(linearToSRGB(v)*a*0xffff + 0.5)
+	}
+
+	return color.RGBA64{
+		R: premultiply(r),
+		G: premultiply(g),
+		B: premultiply(b),
+		A: uint16(math.Min(math.Max(a, 0), 1)*0xffff + 0.5),
+	}
+}
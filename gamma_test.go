@@ -0,0 +1,88 @@
+package waveform
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestSRGBLinearRoundTrip verifies that srgbToLinear and linearToSRGB
+// produce known values, and are inverses of each other.
+func TestSRGBLinearRoundTrip(t *testing.T) {
+	var tests = []struct {
+		srgb   float64
+		linear float64
+	}{
+		{0, 0},
+		{0.04, 0.04 / 12.92},
+		{0.5, 0.21404114048223255},
+		{0.7353569830524495, 0.5},
+		{1, 1},
+	}
+
+	for _, test := range tests {
+		if l := srgbToLinear(test.srgb); math.Abs(l-test.linear) > 1e-9 {
+			t.Fatalf("unexpected linear value for %v: %v != %v", test.srgb, l, test.linear)
+		}
+		if s := linearToSRGB(test.linear); math.Abs(s-test.srgb) > 1e-9 {
+			t.Fatalf("unexpected sRGB value for %v: %v != %v", test.linear, s, test.srgb)
+		}
+	}
+}
+
+// TestWaveformDrawGammaCorrectGradient verifies that the GammaCorrect option
+// interpolates the midpoint of a gradient in linear light, rather than in sRGB
+// space.
+func TestWaveformDrawGammaCorrectGradient(t *testing.T) {
+	// The second of two values is halfway across the gradient
+	values := []float64{1, 1}
+
+	var tests = []struct {
+		gamma bool
+		color color.RGBA
+	}{
+		// Halfway between 0 and 255 in sRGB space
+		{false, color.RGBA{127, 127, 127, 255}},
+		// Half intensity in linear light, which is 0.735 in sRGB
+		{true, color.RGBA{188, 188, 188, 255}},
+	}
+
+	for _, test := range tests {
+		w, err := New(nil,
+			FGColorFunction(GradientColor(black, white)),
+			GammaCorrect(test.gamma),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img := w.Draw(values)
+		if c := img.At(1, imgYDefault/2); c != test.color {
+			t.Fatalf("unexpected midpoint color with gamma %v: %v != %v", test.gamma, c, test.color)
+		}
+	}
+}
+
+// TestWaveformDrawGammaCorrectOverlay verifies that the GammaCorrect option
+// composites an overlay in linear light.
+func TestWaveformDrawGammaCorrectOverlay(t *testing.T) {
+	// Half-transparent red, with alpha-premultiplied components
+	w, err := New(nil,
+		Overlay([]float64{0.2}, color.RGBA{128, 0, 0, 128}),
+		Sharpness(0),
+		GammaCorrect(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Overlay extends beyond the primary waveform over the white background
+	img := w.Draw([]float64{0.1})
+
+	// Nearly half intensity in linear light, since the alpha of the overlay is
+	// 128/255, rather than 127 in sRGB space
+	want := color.RGBA{255, 187, 187, 255}
+	if c := img.At(0, 30); c != want {
+		t.Fatalf("unexpected overlay color: %v != %v", c, want)
+	}
+}
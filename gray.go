@@ -0,0 +1,74 @@
+package waveform
+
+import (
+	"image"
+	"io"
+)
+
+// GenerateGray immediately opens and reads an input audio stream, computes
+// the values required for waveform generation, and returns a grayscale waveform
+// image which is customized by zero or more, variadic, OptionsFunc parameters.
+//
+// GenerateGray is equivalent to Generate, followed by the DrawGray method of a
+// Waveform struct, and handles errors in the same way.
+func GenerateGray(r io.Reader, options ...OptionsFunc) (*image.Gray, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		// Draw any partial results, if requested
+		if w.partialOnError && len(values) > 0 {
+			return w.DrawGray(values), err
+		}
+
+		return nil, err
+	}
+
+	return w.DrawGray(values), nil
+}
+
+// DrawGray creates a new, single channel *image.Gray from a slice of float64
+// values.
+//
+// The shape of the waveform is the same as the image created by Draw, but all
+// ColorFunc are ignored: each foreground pixel has an intensity equal to the
+// amplitude of its computed value, from 0 to 255, and the background is 0.
+// This is useful when pixel intensities are used directly as data.
+func (w *Waveform) DrawGray(values []float64) *image.Gray {
+	l := w.newLayout(values)
+
+	img := image.NewGray(l.bounds)
+
+	// Draw all spans after the background of each column, which is left as 0,
+	// along with any padding
+	var spans []span
+	for x := 0; x < l.maxX; x++ {
+		n := l.column(x)
+		intensity := grayIntensity(l.values[n])
+
+		spans = l.spans(x, spans[:0])
+		for _, s := range spans[1:] {
+			for y := s.y0; y < s.y1; y++ {
+				img.Pix[img.PixOffset(x+l.offset.X, y+l.offset.Y)] = intensity
+			}
+		}
+	}
+
+	return img
+}
+
+// grayIntensity converts an amplitude to a pixel intensity, clamping it to
+// the range [0, 1].
+func grayIntensity(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+
+	return uint8(v*255 + 0.5)
+}
@@ -0,0 +1,56 @@
+package waveform
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestWaveformDrawGray verifies that the Waveform.DrawGray method draws the same
+// waveform shape as Draw, with each foreground pixel's intensity equal to the
+// amplitude of its computed value, and a background of 0.
+func TestWaveformDrawGray(t *testing.T) {
+	w, err := New(nil, Scale(2, 1), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.1, 0.2}
+	gray := w.DrawGray(values)
+	img := w.Draw(values)
+
+	if gray.Bounds() != img.Bounds() {
+		t.Fatalf("unexpected image bounds: %v != %v", gray.Bounds(), img.Bounds())
+	}
+
+	b := img.Bounds()
+	for x := 0; x < b.Max.X; x++ {
+		want := []uint8{26, 51}[x/2]
+		for y := 0; y < b.Max.Y; y++ {
+			// Foreground is drawn in black by default
+			if r, _, _, _ := img.At(x, y).RGBA(); r != 0 {
+				want := uint8(0)
+				if c := gray.GrayAt(x, y).Y; c != want {
+					t.Fatalf("unexpected background intensity at (%d, %d): %v != %v", x, y, c, want)
+				}
+				continue
+			}
+
+			if c := gray.GrayAt(x, y).Y; c != want {
+				t.Fatalf("unexpected foreground intensity at (%d, %d): %v != %v", x, y, c, want)
+			}
+		}
+	}
+}
+
+// TestGenerateGray verifies that GenerateGray produces a grayscale image from
+// an input audio stream.
+func TestGenerateGray(t *testing.T) {
+	img, err := GenerateGray(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b.Dx() == 0 || b.Dy() != imgYDefault {
+		t.Fatalf("unexpected image bounds: %v", b)
+	}
+}
@@ -0,0 +1,121 @@
+package waveform
+
+import (
+	"bufio"
+	"io"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// StreamInfo describes the properties of an audio stream, as returned by Info.
+type StreamInfo struct {
+	// Sample rate and number of channels of the decoded audio
+	SampleRate int
+	Channels   int
+
+	// Number of bits per encoded sample, or 0 if the format does not encode
+	// samples with a fixed bit depth, such as MP3
+	BitDepth int
+
+	// Number of samples per channel, and the duration of the audio
+	Samples  int64
+	Duration time.Duration
+}
+
+// bitDepthDecoder is an audio.Decoder which reports the bit depth of the
+// encoded samples of its stream.
+type bitDepthDecoder interface {
+	audio.Decoder
+	bitDepth() int
+}
+
+// Info opens and reads an input audio stream, and returns its properties,
+// without computing any values or generating an image.  Options which describe
+// the input stream, such as RawPCM, are applied, and all other options are
+// ignored.
+//
+// The entire stream is decoded, so that its duration is accurate even for
+// formats which do not store their length.  If the stream is empty, or
+// contains no samples, ErrNoSamples is returned.
+func Info(r io.Reader, options ...OptionsFunc) (StreamInfo, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+
+	br := bufio.NewReader(r)
+
+	// The bit depths of WAV and FLAC streams which are decoded by the audio
+	// package are read from their headers
+	var depth int
+	if w.rawPCM == nil {
+		if err := skipLeadingTags(br); err != nil {
+			return StreamInfo{}, err
+		}
+		depth = peekBitDepth(br)
+	}
+
+	decoder, err := w.openDecoder(br)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+	if c, ok := decoder.(io.Closer); ok {
+		defer c.Close()
+	}
+	if d, ok := decoder.(bitDepthDecoder); ok {
+		depth = d.bitDepth()
+	}
+
+	config := decoder.Config()
+	if config.SampleRate <= 0 || config.Channels <= 0 {
+		return StreamInfo{}, ErrInvalidData
+	}
+
+	// Count all samples in the stream
+	var total int64
+	samples := make(audio.Float64, config.SampleRate*config.Channels)
+	for {
+		n, err := decoder.Read(samples)
+		total += int64(n)
+		if err == audio.EOS {
+			break
+		}
+		if err != nil {
+			return StreamInfo{}, err
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	frames := total / int64(config.Channels)
+	if frames == 0 {
+		return StreamInfo{}, ErrNoSamples
+	}
+
+	return StreamInfo{
+		SampleRate: config.SampleRate,
+		Channels:   config.Channels,
+		BitDepth:   depth,
+		Samples:    frames,
+		Duration:   time.Duration(frames) * time.Second / time.Duration(config.SampleRate),
+	}, nil
+}
+
+// peekBitDepth returns the bit depth of a WAV or FLAC stream from its header,
+// without consuming any input, or 0 if it is not known.
+func peekBitDepth(br *bufio.Reader) int {
+	if f, ok := peekWAVFormat(br); ok {
+		return int(f.bits)
+	}
+
+	// Bits per sample are stored in the STREAMINFO block, which is always
+	// the first metadata block, minus one
+	b, _ := br.Peek(4 + 4 + 14)
+	if len(b) == 22 && string(b[0:4]) == "fLaC" && b[4]&0x7f == flacBlockStreamInfo {
+		return int((b[20]&0x01)<<4|b[21]>>4) + 1
+	}
+
+	return 0
+}
@@ -0,0 +1,97 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// TestInfo verifies that Info reports the properties of audio streams in
+// each format which reports its bit depth.
+func TestInfo(t *testing.T) {
+	var tests = []struct {
+		data    []byte
+		options []OptionsFunc
+		info    StreamInfo
+	}{
+		{
+			data: testWAV(1, 2, 100, 8, make([]byte, 300)),
+			info: StreamInfo{SampleRate: 100, Channels: 2, BitDepth: 8, Samples: 150, Duration: 1500 * time.Millisecond},
+		},
+		{
+			data: testAIFF("", 1, 24, 8000, make([]byte, 3*2000)),
+			info: StreamInfo{SampleRate: 8000, Channels: 1, BitDepth: 24, Samples: 2000, Duration: 250 * time.Millisecond},
+		},
+		{
+			data: testCAF("lpcm", cafFlagLittleEndian, 2, 24, 48000, make([]byte, 6*4800), false),
+			info: StreamInfo{SampleRate: 48000, Channels: 2, BitDepth: 24, Samples: 4800, Duration: 100 * time.Millisecond},
+		},
+		{
+			data:    make([]byte, 4*400),
+			options: []OptionsFunc{RawPCM(400, 16, 2, binary.LittleEndian)},
+			info:    StreamInfo{SampleRate: 400, Channels: 2, BitDepth: 16, Samples: 400, Duration: time.Second},
+		},
+		{
+			data: testDFF("DSD ", 1, 2822400, make([]byte, 8*441)),
+			info: StreamInfo{SampleRate: 44100, Channels: 1, BitDepth: 1, Samples: 441, Duration: 10 * time.Millisecond},
+		},
+	}
+
+	for i, test := range tests {
+		info, err := Info(bytes.NewReader(test.data), test.options...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if info != test.info {
+			t.Fatalf("[%02d] unexpected info: %+v != %+v", i, info, test.info)
+		}
+	}
+}
+
+// TestInfoErrors verifies that Info returns appropriate errors for empty or
+// invalid audio streams.
+func TestInfoErrors(t *testing.T) {
+	var tests = []struct {
+		data []byte
+		err  error
+	}{
+		{nil, ErrNoSamples},
+		{testWAV(1, 1, 100, 8, nil), ErrNoSamples},
+		{[]byte("not audio"), ErrFormat},
+	}
+
+	for i, test := range tests {
+		if _, err := Info(bytes.NewReader(test.data)); err != test.err {
+			t.Fatalf("[%02d] unexpected Info error: %v != %v", i, err, test.err)
+		}
+	}
+}
+
+// TestPeekBitDepth verifies that peekBitDepth reads the bit depth of WAV and
+// FLAC streams from their headers, without consuming any input.
+func TestPeekBitDepth(t *testing.T) {
+	var tests = []struct {
+		data  []byte
+		depth int
+	}{
+		{wavFile, 16},
+		{flacFile, 16},
+		{testWAV(1, 1, 8000, 24, []byte{0, 0, 0}), 24},
+		{mp3File, 0},
+	}
+
+	for i, test := range tests {
+		br := bufio.NewReader(bytes.NewReader(test.data))
+		if depth := peekBitDepth(br); depth != test.depth {
+			t.Fatalf("[%02d] unexpected bit depth: %v != %v", i, depth, test.depth)
+		}
+
+		// No input may be consumed
+		if b, _ := br.Peek(4); !bytes.Equal(b, test.data[:4]) {
+			t.Fatalf("[%02d] input consumed by peekBitDepth", i)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// A Marker is a vertical line drawn in front of a waveform by the Markers
+// option, such as at the start of a chapter, a cue point, or a detected
+// silence.
+type Marker struct {
+	// Time is the timestamp of the marker, from the beginning of the audio.
+	Time time.Duration
+
+	// Color is the color of the line and its label, which must not be nil.
+	Color color.Color
+
+	// Label is optional text drawn at the top of the line, to its right.
+	Label string
+}
+
+// drawMarkers draws a vertical line over the entire height of the waveform
+// area of img at the timestamp of each marker set by the Markers option, and
+// labels it, if it has a label.  Markers outside of the drawn values are not
+// drawn.  n is the number of values computed from the audio.
+func (l *layout) drawMarkers(img draw.Image, n int) {
+	start, slice := l.timing(n)
+	if slice <= 0 {
+		return
+	}
+
+	face := l.w.font()
+	ascent := face.Metrics().Ascent.Ceil()
+
+	for _, m := range l.w.markers {
+		if m.Time < start {
+			continue
+		}
+
+		x := int(float64(m.Time-start) / float64(slice) * float64(l.period))
+		if x >= l.maxX {
+			continue
+		}
+
+		l.fillOver(img, image.Rect(x, 0, x+1, l.maxY), m.Color)
+		if m.Label == "" {
+			continue
+		}
+
+		d := &font.Drawer{
+			Dst:  img,
+			Src:  image.NewUniform(m.Color),
+			Face: face,
+			Dot:  fixed.P(l.offset.X+x+2, l.offset.Y+ascent+1),
+		}
+		d.DrawString(m.Label)
+	}
+}
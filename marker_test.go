@@ -0,0 +1,50 @@
+package waveform
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWaveformDrawMarkerLines verifies that the Waveform.Draw method draws a
+// vertical line at the timestamp of each marker set by the Markers option,
+// and skips markers outside of the drawn values.
+func TestWaveformDrawMarkerLines(t *testing.T) {
+	w, err := New(nil, Scale(40, 1), Markers([]Marker{
+		{Time: 1500 * time.Millisecond, Color: red},
+		{Time: 2 * time.Second, Color: blue, Label: "Chapter 2"},
+		{Time: 10 * time.Second, Color: red},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One value per second, so markers are drawn at 40 pixels per second
+	img := w.Draw(make([]float64, 3))
+	for _, p := range []struct {
+		x, y int
+		want interface{}
+	}{
+		{60, 0, red},
+		{60, 127, red},
+		{80, 127, blue},
+		{59, 127, white},
+		{119, 127, white},
+	} {
+		if c := img.At(p.x, p.y); c != p.want {
+			t.Fatalf("unexpected color at (%d, %d): %v != %v", p.x, p.y, c, p.want)
+		}
+	}
+
+	// The label is drawn at the top of the line, to its right
+	var labeled bool
+	for x := 82; x < 120; x++ {
+		for y := 0; y < 20; y++ {
+			if img.At(x, y) == blue {
+				labeled = true
+			}
+		}
+	}
+	if !labeled {
+		t.Fatal("marker label not drawn")
+	}
+}
@@ -0,0 +1,151 @@
+package waveform
+
+import "time"
+
+// Metadata describes the audio from which the values returned by the last call
+// to Compute were computed, so that the columns of a waveform image can be
+// mapped back to timestamps in the original audio stream.
+type Metadata struct {
+	// Sample rate and number of channels of the audio, after any resampling
+	SampleRate int
+	Channels   int
+
+	// Number of samples per channel for each computed value, except the
+	// final value, which may be computed from fewer samples
+	SliceSamples int64
+
+	// Offsets of the samples per channel from which the first and last computed
+	// values were computed, where StartSample is inclusive and EndSample is
+	// exclusive.  Unless TrimSilence removes values, or Window is set,
+	// StartSample is 0 and EndSample is TotalSamples.
+	StartSample int64
+	EndSample   int64
+
+	// Total number of samples per channel which were read, including any
+	// before the start of the Window
+	TotalSamples int64
+
+	// Timestamps of markers embedded in the audio stream, such as chapters,
+	// relative to the beginning of the stream, in order.  Markers are read
+	// from the CUESHEET or SEEKTABLE metadata of FLAC streams, or mark the
+	// beginning of each stream read by GenerateMulti after the first.
+	Markers []time.Duration
+
+	// Tracks of the audio stream, in order, as read from the CUESHEET
+	// metadata of FLAC streams, such as single-file album rips.  When a
+	// stream has tracks, Markers contains the start of each track.
+	Tracks []Track
+}
+
+// Track describes a single track of an audio stream which contains several
+// tracks.
+type Track struct {
+	// Track number, as stored in the stream
+	Number int
+
+	// Offset of the first sample per channel of the track, and its timestamp,
+	// relative to the beginning of the stream
+	StartSample int64
+	Start       time.Duration
+}
+
+// Metadata returns the Metadata of the audio from which the values returned by
+// the last call to Compute were computed.  If Compute has not been called, the
+// zero value is returned.
+func (w *Waveform) Metadata() Metadata {
+	return w.metadata
+}
+
+// SplitTracks splits the input values, as returned by the last call to Compute,
+// at the start of each track reported by Metadata, and returns the values of
+// each track, so that a separate waveform image can be drawn for each track.
+//
+// One slice of values is returned for each track, in order.  Values computed
+// before the start of the first track, such as a pregap, belong to the first
+// track, and the value which contains the start of a track belongs to that
+// track.  A track which starts after the last value has no values.  If there
+// are no tracks, all values are returned as a single slice.
+func (w *Waveform) SplitTracks(values []float64) [][]float64 {
+	m := w.metadata
+	if len(m.Tracks) == 0 || m.SliceSamples == 0 {
+		return [][]float64{values}
+	}
+
+	tracks := make([][]float64, len(m.Tracks))
+	start := 0
+	for i := range m.Tracks {
+		end := len(values)
+		if i+1 < len(m.Tracks) {
+			// Find the value which contains the start of the next track
+			n := (m.Tracks[i+1].StartSample - m.StartSample) / m.SliceSamples
+			if n < int64(start) {
+				n = int64(start)
+			}
+			end = int(minInt64(n, int64(len(values))))
+		}
+
+		tracks[i] = values[start:end]
+		start = end
+	}
+
+	return tracks
+}
+
+// values returns the number of computed values described by a Metadata.
+func (m Metadata) values() int {
+	if m.SliceSamples == 0 {
+		return 0
+	}
+
+	return int((m.EndSample - m.StartSample + m.SliceSamples - 1) / m.SliceSamples)
+}
+
+// markerColumns returns whether a marker is drawn at each X coordinate of an
+// image with the input width, where all computed values are drawn over span
+// pixels, whether or not they are resampled.  Markers outside of the computed
+// values are not drawn.
+func (w *Waveform) markerColumns(maxX int, span int) []bool {
+	m := w.metadata
+	columns := make([]bool, maxX)
+	for _, t := range m.Markers {
+		// Find the sample at the marker, relative to the first computed value
+		sample := int64(t*time.Duration(m.SampleRate)/time.Second) - m.StartSample
+		if sample < 0 || sample >= m.EndSample-m.StartSample {
+			continue
+		}
+
+		x := int(sample * int64(span) / (m.SliceSamples * int64(m.values())))
+		if x < maxX {
+			columns[x] = true
+		}
+	}
+
+	return columns
+}
+
+// trimSilence removes all leading and trailing computed values below the
+// TrimSilence threshold, along with their statistics, if any.  It returns the
+// remaining values and statistics, and the indices of the first and last
+// values which remain, where first is inclusive and last is exclusive.
+//
+// If all values are below the threshold, no values are removed.
+func (w *Waveform) trimSilence(computed []float64, stats []sliceStats) ([]float64, []sliceStats, int, int) {
+	first, last := 0, len(computed)
+	for first < last && computed[first] < w.trimThreshold {
+		first++
+	}
+	for last > first && computed[last-1] < w.trimThreshold {
+		last--
+	}
+
+	// Never remove all values
+	if first == last {
+		return computed, stats, 0, len(computed)
+	}
+
+	if stats != nil {
+		stats = stats[first:last]
+	}
+
+	return computed[first:last], stats, first, last
+}
@@ -0,0 +1,79 @@
+package waveform
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestWaveformComputeTrimSilence verifies that the Waveform.Compute method
+// removes leading and trailing silence when TrimSilence is set, and reports
+// the offsets of the remaining audio using Metadata.
+func TestWaveformComputeTrimSilence(t *testing.T) {
+	// Six seconds of audio, where only the third, fourth, and fifth seconds
+	// are not silent, and the fourth second is quieter than the threshold
+	levels := []float64{0, 0.01, 0.5, 0.05, 0.5, 0}
+	var samples []float64
+	for _, level := range levels {
+		for i := 0; i < 100; i++ {
+			samples = append(samples, level)
+		}
+	}
+
+	w, err := New(nil, TrimSilence(0.1), DualEnvelope(SolidColor(red), SolidColor(blue)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.computeSamples(newSamplesDecoder(samples, 100, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Silence between active regions is never removed
+	want := []float64{0.5, 0.05, 0.5}
+	if len(values) != len(want) {
+		t.Fatalf("unexpected values length: %v != %v", len(values), len(want))
+	}
+	for i := range want {
+		if math.Abs(values[i]-want[i]) > 1e-9 {
+			t.Fatalf("unexpected value at index %d: %v != %v", i, values[i], want[i])
+		}
+	}
+	if len(w.stats) != len(values) {
+		t.Fatalf("unexpected statistics length: %v != %v", len(w.stats), len(values))
+	}
+
+	wantMetadata := Metadata{
+		SampleRate:   100,
+		Channels:     1,
+		SliceSamples: 100,
+		StartSample:  200,
+		EndSample:    500,
+		TotalSamples: 600,
+	}
+	if m := w.Metadata(); !reflect.DeepEqual(m, wantMetadata) {
+		t.Fatalf("unexpected metadata: %+v != %+v", m, wantMetadata)
+	}
+}
+
+// TestWaveformComputeTrimSilenceAll verifies that the Waveform.Compute method
+// removes no values when TrimSilence is set, if all values are silent.
+func TestWaveformComputeTrimSilenceAll(t *testing.T) {
+	w, err := New(nil, TrimSilence(0.1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.computeSamples(newSamplesDecoder(make([]float64, 300), 100, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 3 {
+		t.Fatalf("unexpected values length: %v != %v", len(values), 3)
+	}
+
+	if m := w.Metadata(); m.StartSample != 0 || m.EndSample != 300 || m.TotalSamples != 300 {
+		t.Fatalf("unexpected metadata: %+v", m)
+	}
+}
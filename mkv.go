@@ -0,0 +1,955 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+
+	"azul3d.org/engine/audio"
+	"github.com/jfreymuth/vorbis"
+)
+
+// mkvMagic is the magic string which begins a Matroska or WebM container: the
+// ID of its EBML header element.
+const mkvMagic = "\x1a\x45\xdf\xa3"
+
+func init() {
+	// Register Matroska and WebM containers with the audio package, so they are
+	// detected by the same format sniffing used for WAV and FLAC.  Audio tracks
+	// are only decoded if a decoder for the track's codec is available.
+	audio.RegisterFormat(FormatMatroska, mkvMagic, newMKVDecoder)
+}
+
+// Matroska element IDs which are parsed by this package.
+const (
+	mkvIDEBML        = 0x1a45dfa3
+	mkvIDDocType     = 0x4282
+	mkvIDSegment     = 0x18538067
+	mkvIDTracks      = 0x1654ae6b
+	mkvIDTrackEntry  = 0xae
+	mkvIDTrackNumber = 0xd7
+	mkvIDTrackType   = 0x83
+	mkvIDCodecID     = 0x86
+	mkvIDCodecPriv   = 0x63a2
+	mkvIDAudio       = 0xe1
+	mkvIDSampleRate  = 0xb5
+	mkvIDChannels    = 0x9f
+	mkvIDBitDepth    = 0x6264
+	mkvIDEncodings   = 0x6d80
+	mkvIDEncoding    = 0x6240
+	mkvIDEncType     = 0x5033
+	mkvIDCompression = 0x5034
+	mkvIDCompAlgo    = 0x4254
+	mkvIDCompSetting = 0x4255
+	mkvIDCluster     = 0x1f43b675
+	mkvIDBlockGroup  = 0xa0
+	mkvIDBlock       = 0xa1
+	mkvIDSimpleBlock = 0xa3
+)
+
+const (
+	// mkvTrackTypeAudio is the track type of audio tracks
+	mkvTrackTypeAudio = 2
+
+	// mkvCompZlib and mkvCompHeaderStrip are the content compression
+	// algorithms which can be reversed by this package
+	mkvCompZlib        = 0
+	mkvCompHeaderStrip = 3
+
+	// mkvUnknownSize is the size of an element whose size is not known, such
+	// as a segment or cluster of a live stream
+	mkvUnknownSize = 1<<56 - 1
+
+	// mkvMaxElementSize is the maximum size of an element which is read into
+	// memory to be parsed, including a block
+	mkvMaxElementSize = 1 << 24
+)
+
+// mkvCodecs is the set of codecs which can be decoded from a Matroska audio
+// track, keyed by the track's codec ID.  Codec decoders which are only built
+// with a build tag add themselves to this set.
+var mkvCodecs = map[string]func(t *mkvTrack) (audio.Decoder, error){
+	"A_PCM/INT/LIT":    newMKVPCMDecoder,
+	"A_PCM/INT/BIG":    newMKVPCMDecoder,
+	"A_PCM/FLOAT/IEEE": newMKVPCMDecoder,
+	"A_FLAC":           newMKVFLACDecoder,
+	"A_MPEG/L3":        newMKVMP3Decoder,
+	"A_VORBIS":         newMKVVorbisDecoder,
+	"A_ALAC":           newMKVMP4Decoder("alac"),
+	"A_AAC":            newMKVMP4Decoder("mp4a"),
+}
+
+// mkvTrack describes the selected audio track of a Matroska container, and the
+// stream from which its encoded frames are read.
+type mkvTrack struct {
+	number uint64
+	codec  string
+
+	sampleRate int
+	channels   int
+	bits       int
+
+	// Codec private data, such as the FLAC metadata blocks or the Vorbis
+	// headers
+	private []byte
+
+	// Content compression of each frame, or -1 if frames are not compressed
+	compAlgo    int
+	compSetting []byte
+
+	// Stream positioned after the track list, from which each block of the
+	// track is read as its frames are decoded, and the frames of the last
+	// block which have not yet been decoded
+	br      *bufio.Reader
+	pending [][]byte
+}
+
+// newMKVDecoder reads a Matroska or WebM container from the input stream, and
+// opens a decoder for its first audio track.
+func newMKVDecoder(r interface{}) (audio.Decoder, error) {
+	rr, ok := r.(io.Reader)
+	if !ok {
+		return nil, audio.ErrInvalidData
+	}
+
+	return newMKVTrackDecoder(rr, 0)
+}
+
+// newMKVTrackDecoder reads a Matroska or WebM container from the input stream,
+// and opens a decoder for the audio track with the input zero-based index.
+//
+// The container is read up to its track list before decoding begins, and each
+// block of the selected track is read as its frames are decoded, so that
+// decoding may stop early without reading the remainder of the stream.
+func newMKVTrackDecoder(r io.Reader, index int) (audio.Decoder, error) {
+	t, err := readMKVTrack(bufio.NewReader(r), index)
+	if err != nil {
+		return nil, err
+	}
+
+	// Track is valid, but its codec cannot be decoded
+	newCodec, ok := mkvCodecs[t.codec]
+	if !ok {
+		return nil, audio.ErrFormat
+	}
+
+	return newCodec(t)
+}
+
+// readMKVTrack reads a Matroska container up to its track list, and returns
+// the audio track with the input zero-based index, whose frames are read from
+// the remainder of the stream.  If the container has fewer audio tracks, but
+// at least one, ErrTrackRange is returned.
+//
+// Elements are read in order, without regard to the nesting of the segment,
+// clusters, and block groups, so that elements of an unknown size, as written
+// by live encoders, are handled in the same way as all others.
+func readMKVTrack(br *bufio.Reader, index int) (*mkvTrack, error) {
+	id, size, err := readMKVElementHeader(br)
+	if err != nil {
+		if err == io.EOF {
+			return nil, audio.ErrUnexpectedEOS
+		}
+		return nil, err
+	}
+	if id != mkvIDEBML {
+		return nil, audio.ErrInvalidData
+	}
+
+	// Only Matroska and WebM documents are decoded
+	header, err := readMKVElement(br, size)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := findMKVElement(header, mkvIDDocType)
+	if err != nil {
+		return nil, err
+	}
+	if string(doc) != "matroska" && string(doc) != "webm" {
+		return nil, audio.ErrFormat
+	}
+
+	for {
+		id, size, err := readMKVElementHeader(br)
+		if err == io.EOF {
+			return nil, audio.ErrInvalidData
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch id {
+		case mkvIDSegment, mkvIDCluster, mkvIDBlockGroup:
+			// Descend into all children
+			continue
+		case mkvIDTracks:
+			b, err := readMKVElement(br, size)
+			if err != nil {
+				return nil, err
+			}
+
+			t, err := readMKVTracks(b, index)
+			if err != nil {
+				return nil, err
+			}
+
+			t.br = br
+			return t, nil
+		case mkvIDBlock, mkvIDSimpleBlock:
+			// Blocks cannot be assigned to a track before the track list
+			return nil, audio.ErrInvalidData
+		default:
+			if err := skipMKVElement(br, size); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// next returns the next encoded frame of the receiving track, reading blocks
+// from the stream until one which belongs to the track is found.  Once the
+// stream ends, io.EOF is returned.
+func (t *mkvTrack) next() ([]byte, error) {
+	for len(t.pending) == 0 {
+		id, size, err := readMKVElementHeader(t.br)
+		if err != nil {
+			return nil, err
+		}
+
+		switch id {
+		case mkvIDSegment, mkvIDCluster, mkvIDBlockGroup:
+			// Descend into all children
+			continue
+		case mkvIDBlock, mkvIDSimpleBlock:
+			if size == mkvUnknownSize {
+				return nil, audio.ErrInvalidData
+			}
+
+			if err := t.readBlock(size); err != nil {
+				return nil, err
+			}
+		default:
+			// Only the first track list is used
+			if err := skipMKVElement(t.br, size); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	f := t.pending[0]
+	t.pending = t.pending[1:]
+	return f, nil
+}
+
+// skipMKVElement discards the body of an element with the input size.
+func skipMKVElement(br *bufio.Reader, size uint64) error {
+	if size == mkvUnknownSize {
+		return audio.ErrInvalidData
+	}
+	if _, err := io.CopyN(ioutil.Discard, br, int64(size)); err != nil {
+		return audio.ErrUnexpectedEOS
+	}
+
+	return nil
+}
+
+// readMKVElementHeader reads the ID and size of the next element of the input
+// stream.  If the stream ends before the element, io.EOF is returned.
+func readMKVElementHeader(br *bufio.Reader) (uint32, uint64, error) {
+	first, err := br.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// IDs retain their length marker, and are at most 4 bytes long
+	n := mkvVintLen(first)
+	if n == 0 || n > 4 {
+		return 0, 0, audio.ErrInvalidData
+	}
+	id := uint32(first)
+	for i := 1; i < n; i++ {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, 0, audio.ErrUnexpectedEOS
+		}
+		id = id<<8 | uint32(b)
+	}
+
+	first, err = br.ReadByte()
+	if err != nil {
+		return 0, 0, audio.ErrUnexpectedEOS
+	}
+	n = mkvVintLen(first)
+	if n == 0 {
+		return 0, 0, audio.ErrInvalidData
+	}
+	buf := []byte{first}
+	for i := 1; i < n; i++ {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, 0, audio.ErrUnexpectedEOS
+		}
+		buf = append(buf, b)
+	}
+
+	size, _, _ := readMKVVint(buf)
+	return id, size, nil
+}
+
+// readMKVElement reads the body of an element with the input size.
+func readMKVElement(r io.Reader, size uint64) ([]byte, error) {
+	if size > mkvMaxElementSize {
+		return nil, audio.ErrInvalidData
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, audio.ErrUnexpectedEOS
+	}
+
+	return b, nil
+}
+
+// mkvVintLen returns the length in bytes of a variable length integer with the
+// input first byte, or 0 if it is invalid.
+func mkvVintLen(first byte) int {
+	for n := 1; n <= 8; n++ {
+		if first&(0x80>>uint(n-1)) != 0 {
+			return n
+		}
+	}
+
+	return 0
+}
+
+// readMKVVint parses a variable length integer from the beginning of b, and
+// returns its value, without its length marker, and its length.  A value with
+// all bits set is returned as mkvUnknownSize.
+func readMKVVint(b []byte) (uint64, int, error) {
+	if len(b) == 0 {
+		return 0, 0, audio.ErrInvalidData
+	}
+	n := mkvVintLen(b[0])
+	if n == 0 || len(b) < n {
+		return 0, 0, audio.ErrInvalidData
+	}
+
+	v := uint64(b[0] & (0xff >> uint(n)))
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	if v == 1<<uint(7*n)-1 {
+		return mkvUnknownSize, n, nil
+	}
+
+	return v, n, nil
+}
+
+// mkvElement is a single element parsed from the body of a master element.
+type mkvElement struct {
+	id   uint32
+	body []byte
+}
+
+// readMKVElements parses all child elements of a master element.
+func readMKVElements(data []byte) ([]mkvElement, error) {
+	var elements []mkvElement
+	for len(data) > 0 {
+		n := mkvVintLen(data[0])
+		if n == 0 || n > 4 || len(data) < n {
+			return nil, audio.ErrInvalidData
+		}
+		var id uint32
+		for _, b := range data[:n] {
+			id = id<<8 | uint32(b)
+		}
+		data = data[n:]
+
+		size, n, err := readMKVVint(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[n:]
+		if size > uint64(len(data)) {
+			return nil, audio.ErrInvalidData
+		}
+
+		elements = append(elements, mkvElement{id: id, body: data[:size]})
+		data = data[size:]
+	}
+
+	return elements, nil
+}
+
+// findMKVElement returns the body of the first child element of a master
+// element with the input ID, or nil if none exists.
+func findMKVElement(data []byte, id uint32) ([]byte, error) {
+	elements, err := readMKVElements(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range elements {
+		if e.id == id {
+			return e.body, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// mkvUint parses the body of an unsigned integer element.
+func mkvUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+
+	return v
+}
+
+// mkvFloat parses the body of a floating point element, which is either 4 or 8
+// bytes long.
+func mkvFloat(b []byte) float64 {
+	switch len(b) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+
+	return 0
+}
+
+// readMKVTracks parses the body of a track list, and returns the audio track
+// with the input zero-based index.
+func readMKVTracks(data []byte, index int) (*mkvTrack, error) {
+	entries, err := readMKVElements(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var found int
+	for _, e := range entries {
+		if e.id != mkvIDTrackEntry {
+			continue
+		}
+
+		t, err := readMKVTrackEntry(e.body)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			continue
+		}
+
+		// Skip audio tracks which precede the selected track
+		if found++; found <= index {
+			continue
+		}
+
+		return t, nil
+	}
+
+	// No audio track present
+	if found > 0 {
+		return nil, ErrTrackRange
+	}
+
+	return nil, audio.ErrFormat
+}
+
+// readMKVTrackEntry parses the body of a track entry, and returns its track, or
+// nil if it is not an audio track.
+func readMKVTrackEntry(data []byte) (*mkvTrack, error) {
+	elements, err := readMKVElements(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Default values of all elements which may be omitted
+	t := &mkvTrack{
+		sampleRate: 8000,
+		channels:   1,
+		compAlgo:   -1,
+	}
+
+	var audioTrack bool
+	for _, e := range elements {
+		switch e.id {
+		case mkvIDTrackNumber:
+			t.number = mkvUint(e.body)
+		case mkvIDTrackType:
+			audioTrack = mkvUint(e.body) == mkvTrackTypeAudio
+		case mkvIDCodecID:
+			t.codec = string(bytes.TrimRight(e.body, "\x00"))
+		case mkvIDCodecPriv:
+			t.private = e.body
+		case mkvIDAudio:
+			if err := t.readAudio(e.body); err != nil {
+				return nil, err
+			}
+		case mkvIDEncodings:
+			if err := t.readEncodings(e.body); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !audioTrack {
+		return nil, nil
+	}
+	if t.number == 0 {
+		return nil, audio.ErrInvalidData
+	}
+
+	// PCM samples are decoded using the bit depth of the track, which must
+	// be supported by the PCM decoders
+	if strings.HasPrefix(t.codec, "A_PCM/") {
+		switch t.bits {
+		case 8, 16, 24, 32, 64:
+		default:
+			return nil, audio.ErrInvalidData
+		}
+	}
+
+	return t, nil
+}
+
+// readAudio parses the audio settings of a track entry into the receiving
+// track.  An implausible sample rate or number of channels, which cannot be
+// decoded, is rejected.
+func (t *mkvTrack) readAudio(data []byte) error {
+	elements, err := readMKVElements(data)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range elements {
+		switch e.id {
+		case mkvIDSampleRate:
+			// Compare before converting, so that a rate which cannot be
+			// represented by an int, or NaN, is also rejected
+			rate := mkvFloat(e.body)
+			if !(rate >= 1 && rate <= sampleRateMax) {
+				return audio.ErrInvalidData
+			}
+			t.sampleRate = int(rate)
+		case mkvIDChannels:
+			channels := mkvUint(e.body)
+			if channels == 0 || channels > channelsMax {
+				return audio.ErrInvalidData
+			}
+			t.channels = int(channels)
+		case mkvIDBitDepth:
+			bits := mkvUint(e.body)
+			if bits > 64 {
+				return audio.ErrInvalidData
+			}
+			t.bits = int(bits)
+		}
+	}
+
+	return nil
+}
+
+// readEncodings parses the content encodings of a track entry into the
+// receiving track.  Only a single zlib or header stripping compression is
+// supported, and encrypted tracks cannot be decoded.
+func (t *mkvTrack) readEncodings(data []byte) error {
+	elements, err := readMKVElements(data)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range elements {
+		if e.id != mkvIDEncoding {
+			continue
+		}
+		if t.compAlgo != -1 {
+			return audio.ErrFormat
+		}
+
+		encoding, err := readMKVElements(e.body)
+		if err != nil {
+			return err
+		}
+
+		t.compAlgo = mkvCompZlib
+		for _, c := range encoding {
+			switch c.id {
+			case mkvIDEncType:
+				if mkvUint(c.body) != 0 {
+					return audio.ErrFormat
+				}
+			case mkvIDCompression:
+				comp, err := readMKVElements(c.body)
+				if err != nil {
+					return err
+				}
+
+				for _, cc := range comp {
+					switch cc.id {
+					case mkvIDCompAlgo:
+						t.compAlgo = int(mkvUint(cc.body))
+					case mkvIDCompSetting:
+						t.compSetting = cc.body
+					}
+				}
+			}
+		}
+
+		if t.compAlgo != mkvCompZlib && t.compAlgo != mkvCompHeaderStrip {
+			return audio.ErrFormat
+		}
+	}
+
+	return nil
+}
+
+// readBlock reads a block with the input size from the stream of the receiving
+// track, and stores its frames as pending if the block belongs to the track.
+func (t *mkvTrack) readBlock(size uint64) error {
+	if size > mkvMaxElementSize {
+		return audio.ErrInvalidData
+	}
+
+	// Only the track number is read from blocks of other tracks
+	br := t.br
+	first, err := br.Peek(1)
+	if err != nil {
+		return audio.ErrUnexpectedEOS
+	}
+	n := mkvVintLen(first[0])
+	if n == 0 || uint64(n) > size {
+		return audio.ErrInvalidData
+	}
+	header, err := br.Peek(n)
+	if err != nil {
+		return audio.ErrUnexpectedEOS
+	}
+	if number, _, _ := readMKVVint(header); number != t.number {
+		if _, err := io.CopyN(ioutil.Discard, br, int64(size)); err != nil {
+			return audio.ErrUnexpectedEOS
+		}
+
+		return nil
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return audio.ErrUnexpectedEOS
+	}
+
+	frames, err := readMKVFrames(b[n:])
+	if err != nil {
+		return err
+	}
+
+	for i, f := range frames {
+		if frames[i], err = t.decompress(f); err != nil {
+			return err
+		}
+	}
+
+	t.pending = frames
+	return nil
+}
+
+// readMKVFrames parses the timecode, flags, and laced frames of a block, which
+// follow its track number.
+func readMKVFrames(b []byte) ([][]byte, error) {
+	if len(b) < 3 {
+		return nil, audio.ErrInvalidData
+	}
+	lacing := b[2] & 0x06
+	b = b[3:]
+
+	if lacing == 0 {
+		return [][]byte{b}, nil
+	}
+
+	if len(b) < 1 {
+		return nil, audio.ErrInvalidData
+	}
+	count := int(b[0]) + 1
+	b = b[1:]
+
+	// Sizes of all frames but the last, which fills the rest of the block
+	sizes := make([]uint64, count-1)
+	switch lacing {
+	case 0x02:
+		// Xiph lacing: each size is a sum of bytes, ending with a byte less
+		// than 255
+		for i := range sizes {
+			for {
+				if len(b) == 0 {
+					return nil, audio.ErrInvalidData
+				}
+				v := b[0]
+				b = b[1:]
+				sizes[i] += uint64(v)
+				if v < 255 {
+					break
+				}
+			}
+		}
+	case 0x04:
+		// Fixed-size lacing: all frames are the same size
+		if len(b)%count != 0 {
+			return nil, audio.ErrInvalidData
+		}
+		for i := range sizes {
+			sizes[i] = uint64(len(b) / count)
+		}
+	case 0x06:
+		// EBML lacing: the first size, followed by signed differences from
+		// the previous size
+		var prev int64
+		for i := range sizes {
+			v, n, err := readMKVVint(b)
+			if err != nil {
+				return nil, err
+			}
+			b = b[n:]
+
+			if i == 0 {
+				prev = int64(v)
+			} else {
+				prev += int64(v) - (1<<uint(7*n-1) - 1)
+			}
+			if prev < 0 {
+				return nil, audio.ErrInvalidData
+			}
+			sizes[i] = uint64(prev)
+		}
+	}
+
+	frames := make([][]byte, 0, count)
+	for _, size := range sizes {
+		if size > uint64(len(b)) {
+			return nil, audio.ErrInvalidData
+		}
+
+		frames = append(frames, b[:size])
+		b = b[size:]
+	}
+
+	return append(frames, b), nil
+}
+
+// decompress reverses the content compression of a single frame of the
+// receiving track.
+func (t *mkvTrack) decompress(f []byte) ([]byte, error) {
+	switch t.compAlgo {
+	case mkvCompZlib:
+		zr, err := zlib.NewReader(bytes.NewReader(f))
+		if err != nil {
+			return nil, audio.ErrInvalidData
+		}
+
+		b, err := ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, audio.ErrInvalidData
+		}
+
+		return b, nil
+	case mkvCompHeaderStrip:
+		return append(append([]byte(nil), t.compSetting...), f...), nil
+	}
+
+	return f, nil
+}
+
+// reader returns an io.Reader which reads the concatenation of all frames of
+// the receiving track.
+func (t *mkvTrack) reader() io.Reader {
+	return &mkvFrameReader{t: t}
+}
+
+// mkvFrameReader is an io.Reader which reads the concatenation of all frames of
+// a Matroska track, as each frame is read from the stream.
+type mkvFrameReader struct {
+	t     *mkvTrack
+	frame []byte
+}
+
+// Read reads the remainder of the current frame into b, reading the next frame
+// once it is exhausted.
+func (r *mkvFrameReader) Read(b []byte) (int, error) {
+	for len(r.frame) == 0 {
+		f, err := r.t.next()
+		if err != nil {
+			return 0, err
+		}
+		r.frame = f
+	}
+
+	n := copy(b, r.frame)
+	r.frame = r.frame[n:]
+	return n, nil
+}
+
+// newMKVPCMDecoder opens a decoder for a track of interleaved integer or
+// floating point PCM samples.
+//
+// 8-bit integer samples are unsigned, as in WAV, so all little-endian and
+// floating point samples are decoded by a wavDecoder.  Big-endian samples
+// are decoded by a rawDecoder.
+func newMKVPCMDecoder(t *mkvTrack) (audio.Decoder, error) {
+	f := wavFormat{
+		tag:        wavFormatPCM,
+		channels:   uint16(t.channels),
+		sampleRate: uint32(t.sampleRate),
+		bits:       uint16(t.bits),
+	}
+	if t.codec == "A_PCM/FLOAT/IEEE" {
+		f.tag = wavFormatFloat
+	}
+	if !f.pcm() && !f.float() {
+		return nil, audio.ErrFormat
+	}
+
+	if t.codec == "A_PCM/INT/BIG" && f.bits != 8 {
+		return newRawDecoder(t.reader(), rawPCMFormat{
+			sampleRate: int(f.sampleRate),
+			bits:       uint(f.bits),
+			channels:   int(f.channels),
+			order:      binary.BigEndian,
+		}), nil
+	}
+
+	// The size of the sample data is not known until the stream ends
+	return &wavDecoder{
+		r:         t.reader(),
+		format:    f,
+		size:      int(f.bits / 8),
+		remaining: math.MaxInt64,
+		streaming: true,
+	}, nil
+}
+
+// newMKVFLACDecoder opens a decoder for a FLAC track.  The codec private data
+// contains the FLAC stream marker and metadata blocks, which are followed by
+// the frames of the track to form a native FLAC stream.
+func newMKVFLACDecoder(t *mkvTrack) (audio.Decoder, error) {
+	if !bytes.HasPrefix(t.private, []byte("fLaC")) {
+		return nil, audio.ErrInvalidData
+	}
+
+	return newFLACDecoder(bufio.NewReader(io.MultiReader(bytes.NewReader(t.private), t.reader())))
+}
+
+// newMKVMP3Decoder opens a decoder for an MP3 track, whose frames form a
+// native MP3 stream.
+func newMKVMP3Decoder(t *mkvTrack) (audio.Decoder, error) {
+	return newMP3Decoder(t.reader())
+}
+
+// newMKVMP4Decoder returns a function which opens a decoder for a track whose
+// codec can be decoded from an MP4 audio track with the input sample entry
+// type, using the codec private data as the decoder specific configuration.
+func newMKVMP4Decoder(format string) func(t *mkvTrack) (audio.Decoder, error) {
+	return func(t *mkvTrack) (audio.Decoder, error) {
+		// Codec decoders may only be built with a build tag
+		newCodec, ok := mp4Codecs[format]
+		if !ok {
+			return nil, audio.ErrFormat
+		}
+
+		// ALAC configuration may be stored along with the version and flags
+		// of its MP4 box, as in an MP4 sample entry
+		config := t.private
+		if format == "alac" && len(config) >= 12+alacConfigLen && string(config[4:8]) == "alac" {
+			config = config[12:]
+		}
+
+		return newCodec(&mp4Track{
+			format:     format,
+			sampleRate: t.sampleRate,
+			channels:   t.channels,
+			config:     config,
+			read:       t.next,
+		})
+	}
+}
+
+// mkvVorbisDecoder is an audio.Decoder which decodes the packets of a Vorbis
+// track to float64 PCM samples.
+type mkvVorbisDecoder struct {
+	d      vorbis.Decoder
+	track  *mkvTrack
+	config audio.Config
+
+	// Decoded samples not yet read
+	pending []float32
+}
+
+// newMKVVorbisDecoder opens a decoder for a Vorbis track.  The codec private
+// data contains the three Vorbis header packets, using Xiph lacing.
+func newMKVVorbisDecoder(t *mkvTrack) (audio.Decoder, error) {
+	if len(t.private) < 1 || t.private[0] != 2 {
+		return nil, audio.ErrInvalidData
+	}
+
+	// The private data is laced in the same way as a block, without the
+	// track number, timecode, and flags
+	headers, err := readMKVFrames(append([]byte{0, 0, 0x02}, t.private...))
+	if err != nil {
+		return nil, err
+	}
+
+	d := &mkvVorbisDecoder{track: t}
+	for _, h := range headers {
+		if err := d.d.ReadHeader(h); err != nil {
+			return nil, audio.ErrInvalidData
+		}
+	}
+
+	d.config = audio.Config{
+		SampleRate: d.d.SampleRate(),
+		Channels:   d.d.Channels(),
+	}
+	return d, nil
+}
+
+// Config returns the audio configuration of the decoded stream.
+func (d *mkvVorbisDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read decodes samples into b, returning the number of samples read.  On the
+// final read, audio.EOS is returned along with any remaining samples.
+func (d *mkvVorbisDecoder) Read(b audio.Slice) (int, error) {
+	var n int
+	for n < b.Len() {
+		// Decode the next packet when all pending samples are read
+		if len(d.pending) == 0 {
+			f, err := d.track.next()
+			if err == io.EOF {
+				return n, audio.EOS
+			}
+			if err != nil {
+				return n, err
+			}
+
+			samples, err := d.d.Decode(f)
+			if err != nil {
+				return n, audio.ErrInvalidData
+			}
+			d.pending = samples
+			continue
+		}
+
+		b.Set(n, float64(d.pending[0]))
+		d.pending = d.pending[1:]
+		n++
+	}
+
+	return n, nil
+}
@@ -0,0 +1,367 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"testing/iotest"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestMKVDecoderPCM verifies that an MKV decoder decodes integer and floating
+// point PCM tracks, with frames split across laced and unlaced blocks.
+func TestMKVDecoderPCM(t *testing.T) {
+	var tests = []struct {
+		codec   string
+		bits    uint64
+		frames  [][]byte
+		samples []float64
+	}{
+		{"A_PCM/INT/LIT", 16, [][]byte{{0x00, 0x40}, {0x00, 0xc0}, {0x00, 0x20}}, []float64{0.5, -0.5, 0.25}},
+		{"A_PCM/INT/BIG", 24, [][]byte{{0x40, 0, 0}, {0xc0, 0, 0}, {0x20, 0, 0}}, []float64{0.5, -0.5, 0.25}},
+		{"A_PCM/INT/LIT", 8, [][]byte{{0xc0}, {0x40}, {0xa0}}, []float64{0.5, -0.5, 0.25}},
+		{"A_PCM/FLOAT/IEEE", 32, [][]byte{
+			binary.LittleEndian.AppendUint32(nil, math.Float32bits(0.5)),
+			binary.LittleEndian.AppendUint32(nil, math.Float32bits(-0.5)),
+			binary.LittleEndian.AppendUint32(nil, math.Float32bits(0.25)),
+		}, []float64{0.5, -0.5, 0.25}},
+	}
+
+	for i, test := range tests {
+		data := testMKV(
+			testMKVAudioTrack(1, test.codec, 8000, 1, test.bits),
+			testMKVSimpleBlock(1, test.frames[0]),
+			testMKVSimpleBlock(1, test.frames[1:]...),
+		)
+
+		d, err := openDecoder(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if c := d.Config(); c.SampleRate != 8000 || c.Channels != 1 {
+			t.Fatalf("[%02d] unexpected config: %v", i, c)
+		}
+
+		samples := make(audio.Float64, 8)
+		n, err := d.Read(samples)
+		if err != audio.EOS {
+			t.Fatalf("[%02d] unexpected Read error: %v", i, err)
+		}
+		if !reflect.DeepEqual([]float64(samples[:n]), test.samples) {
+			t.Fatalf("[%02d] unexpected samples: %v != %v", i, samples[:n], test.samples)
+		}
+	}
+}
+
+// TestMKVDecoderALAC verifies that an MKV decoder decodes an ALAC track, whose
+// codec private data is either a bare ALACSpecificConfig, or an MP4 box.
+func TestMKVDecoderALAC(t *testing.T) {
+	frame := testALACFrame(func(w *testBitWriter) {
+		w.write(alacElementSCE, 3)
+		w.write(0, 4+12)
+		w.write(0x9, 4)
+		w.write(2, 32)
+		w.write(0x4000, 16)
+		w.write(0xe000, 16)
+	})
+
+	for i, private := range [][]byte{
+		testALACConfig(1),
+		testMP4Box("alac", make([]byte, 4), testALACConfig(1)),
+	} {
+		data := testMKV(
+			testMKVAudioTrack(1, "A_ALAC", 96000, 1, 16, testMKVElement(mkvIDCodecPriv, private)),
+			testMKVSimpleBlock(1, frame),
+		)
+
+		info, err := Info(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+		if info.SampleRate != 96000 || info.Channels != 1 || info.Samples != 2 {
+			t.Fatalf("[%02d] unexpected info: %+v", i, info)
+		}
+	}
+}
+
+// TestMKVDecoderHeaderStripping verifies that an MKV decoder restores the bytes
+// removed from each frame by header stripping compression.
+func TestMKVDecoderHeaderStripping(t *testing.T) {
+	compression := testMKVElement(mkvIDCompression,
+		testMKVUint(mkvIDCompAlgo, mkvCompHeaderStrip),
+		testMKVElement(mkvIDCompSetting, []byte{0x00}),
+	)
+	encodings := testMKVElement(mkvIDEncodings, testMKVElement(mkvIDEncoding, compression))
+
+	data := testMKV(
+		testMKVAudioTrack(1, "A_PCM/INT/LIT", 8000, 1, 16, encodings),
+		testMKVSimpleBlock(1, []byte{0x40}, []byte{0xc0}),
+	)
+
+	d, err := openDecoder(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make(audio.Float64, 4)
+	n, err := d.Read(samples)
+	if err != audio.EOS {
+		t.Fatalf("unexpected Read error: %v", err)
+	}
+	if want := []float64{0.5, -0.5}; !reflect.DeepEqual([]float64(samples[:n]), want) {
+		t.Fatalf("unexpected samples: %v != %v", samples[:n], want)
+	}
+}
+
+// TestMKVDecoderStreaming verifies that an MKV decoder reads each block as its
+// frames are decoded, rather than reading the entire stream before decoding
+// begins, so that errors which follow the decoded blocks are only returned
+// once they are reached.
+func TestMKVDecoderStreaming(t *testing.T) {
+	data := testMKV(
+		testMKVAudioTrack(1, "A_PCM/INT/LIT", 8000, 1, 16),
+		testMKVSimpleBlock(1, []byte{0x00, 0x40, 0x00, 0xc0}),
+	)
+
+	errStop := errors.New("stop")
+	r := io.MultiReader(bytes.NewReader(data), iotest.ErrReader(errStop))
+
+	d, err := openDecoder(bufio.NewReader(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make(audio.Float64, 2)
+	n, err := d.Read(samples)
+	if err != nil {
+		t.Fatalf("unexpected Read error: %v", err)
+	}
+	if want := []float64{0.5, -0.5}; !reflect.DeepEqual([]float64(samples[:n]), want) {
+		t.Fatalf("unexpected samples: %v != %v", samples[:n], want)
+	}
+
+	if _, err := d.Read(samples); err != errStop {
+		t.Fatalf("unexpected Read error: %v != %v", err, errStop)
+	}
+}
+
+// TestReadMKVFrames verifies that readMKVFrames splits the frames of a block
+// for each type of lacing.
+func TestReadMKVFrames(t *testing.T) {
+	var tests = []struct {
+		block  []byte
+		frames [][]byte
+	}{
+		// No lacing
+		{[]byte{0, 0, 0x80, 1, 2, 3}, [][]byte{{1, 2, 3}}},
+		// Xiph lacing, with a size of 255 which requires a second byte
+		{
+			append([]byte{0, 0, 0x82, 1, 255, 0}, append(make([]byte, 255), 9)...),
+			[][]byte{make([]byte, 255), {9}},
+		},
+		// Fixed-size lacing
+		{[]byte{0, 0, 0x84, 2, 1, 2, 3, 4, 5, 6}, [][]byte{{1, 2}, {3, 4}, {5, 6}}},
+		// EBML lacing, with sizes 3, 1, and the remaining 2
+		{[]byte{0, 0, 0x86, 2, 0x83, 0xbd, 1, 2, 3, 4, 5, 6}, [][]byte{{1, 2, 3}, {4}, {5, 6}}},
+	}
+
+	for i, test := range tests {
+		frames, err := readMKVFrames(test.block)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+		if !reflect.DeepEqual(frames, test.frames) {
+			t.Fatalf("[%02d] unexpected frames: %v != %v", i, frames, test.frames)
+		}
+	}
+}
+
+// TestInfoTrackIndex verifies that the TrackIndex option selects an audio track
+// of MKV containers, ignoring tracks of other types, and returns ErrTrackRange
+// for a track which does not exist.
+func TestInfoTrackIndex(t *testing.T) {
+	video := testMKVElement(mkvIDTrackEntry,
+		testMKVUint(mkvIDTrackNumber, 1),
+		testMKVUint(mkvIDTrackType, 1),
+		testMKVElement(mkvIDCodecID, []byte("V_VP9")),
+	)
+	tracks := append(video, testMKVAudioTrack(2, "A_PCM/INT/LIT", 8000, 1, 16)...)
+	tracks = append(tracks, testMKVAudioTrack(3, "A_PCM/INT/LIT", 4000, 2, 16)...)
+
+	data := testMKV(tracks,
+		testMKVSimpleBlock(1, make([]byte, 100)),
+		testMKVSimpleBlock(2, make([]byte, 2*8)),
+		testMKVSimpleBlock(3, make([]byte, 4*4)),
+		testMKVSimpleBlock(2, make([]byte, 2*8)),
+	)
+
+	var tests = []struct {
+		data  []byte
+		index int
+		info  StreamInfo
+		err   error
+	}{
+		{data: data, index: 0, info: StreamInfo{SampleRate: 8000, Channels: 1, Samples: 16, Duration: 2000000}},
+		{data: data, index: 1, info: StreamInfo{SampleRate: 4000, Channels: 2, Samples: 4, Duration: 1000000}},
+		{data: data, index: 2, err: ErrTrackRange},
+		{data: testMP4(44100, 2, []byte{0x12, 0x10}, nil), index: 1, err: ErrTrackRange},
+		{data: testWAV(1, 1, 8000, 16, make([]byte, 2)), index: 1, err: ErrTrackRange},
+	}
+
+	for i, test := range tests {
+		info, err := Info(bytes.NewReader(test.data), TrackIndex(test.index))
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected Info error: %v != %v", i, err, test.err)
+		}
+		if info != test.info {
+			t.Fatalf("[%02d] unexpected info: %+v != %+v", i, info, test.info)
+		}
+	}
+}
+
+// TestWaveformComputeMKVErrors verifies that the Waveform.Compute method
+// returns appropriate errors for invalid or unsupported MKV containers.
+func TestWaveformComputeMKVErrors(t *testing.T) {
+	pcm := testMKVAudioTrack(1, "A_PCM/INT/LIT", 8000, 1, 16)
+	valid := testMKV(pcm, testMKVSimpleBlock(1, make([]byte, 8)))
+
+	// Document type which is neither Matroska nor WebM
+	doc := append(testMKVElement(mkvIDEBML, testMKVElement(mkvIDDocType, []byte("other"))), valid[len(testMKV(nil)):]...)
+
+	var tests = []struct {
+		data []byte
+		err  error
+	}{
+		{doc, ErrFormat},
+		// Unknown codec
+		{testMKV(testMKVAudioTrack(1, "A_UNKNOWN", 8000, 1, 16)), ErrFormat},
+		// No audio tracks
+		{testMKV(nil), ErrInvalidData},
+		// Block which precedes the track list
+		{append(testMKV(nil), testMKVSimpleBlock(1, []byte{0, 0})...), ErrInvalidData},
+		// Truncated block
+		{valid[:len(valid)-2], ErrUnexpectedEOS},
+		// Bit depths which are truncated, or unsupported, by the PCM decoders
+		{testMKV(testMKVAudioTrack(1, "A_PCM/INT/BIG", 8000, 1, 0x10010), testMKVSimpleBlock(1, make([]byte, 8))), ErrInvalidData},
+		{testMKV(testMKVAudioTrack(1, "A_PCM/INT/LIT", 8000, 1, 12), testMKVSimpleBlock(1, make([]byte, 8))), ErrInvalidData},
+		// Implausible sample rates and numbers of channels
+		{testMKV(testMKVAudioTrack(1, "A_PCM/INT/LIT", 1<<40, 1, 16)), ErrInvalidData},
+		{testMKV(testMKVAudioTrack(1, "A_PCM/INT/LIT", math.NaN(), 1, 16)), ErrInvalidData},
+		{testMKV(testMKVAudioTrack(1, "A_PCM/INT/LIT", 8000, 1<<32, 16)), ErrInvalidData},
+		{testMKV(testMKVAudioTrack(1, "A_PCM/INT/LIT", 8000, channelsMax+1, 16)), ErrInvalidData},
+		// Block whose size exceeds the maximum element size
+		{append(testMKV(pcm), append(testMKVID(mkvIDSimpleBlock), 0x01, 0x10, 0, 0, 0, 0, 0, 0, 0x81, 0, 0, 0x80)...), ErrInvalidData},
+	}
+
+	for i, test := range tests {
+		if _, err := testComputeValues(bytes.NewReader(test.data)); err != test.err {
+			t.Fatalf("[%02d] unexpected Compute error: %v != %v", i, err, test.err)
+		}
+	}
+}
+
+// testMKV is a test helper which generates a WebM container with the input
+// track entries, followed by a single cluster containing the input blocks.
+// The segment and cluster have an unknown size, as written by live encoders.
+// If tracks is nil, no track list is written.
+func testMKV(tracks []byte, blocks ...[]byte) []byte {
+	out := testMKVElement(mkvIDEBML, testMKVElement(mkvIDDocType, []byte("webm")))
+	out = append(out, testMKVUnknownSize(mkvIDSegment)...)
+	if tracks == nil {
+		return out
+	}
+
+	out = append(out, testMKVElement(mkvIDTracks, tracks)...)
+	out = append(out, testMKVUnknownSize(mkvIDCluster)...)
+	for _, b := range blocks {
+		out = append(out, b...)
+	}
+
+	return out
+}
+
+// testMKVAudioTrack is a test helper which generates an audio track entry with
+// the input track number, codec ID, and audio settings, followed by any
+// additional child elements.
+func testMKVAudioTrack(number uint64, codec string, sampleRate float64, channels uint64, bits uint64, children ...[]byte) []byte {
+	settings := testMKVElement(mkvIDAudio,
+		testMKVElement(mkvIDSampleRate, binary.BigEndian.AppendUint64(nil, math.Float64bits(sampleRate))),
+		testMKVUint(mkvIDChannels, channels),
+		testMKVUint(mkvIDBitDepth, bits),
+	)
+
+	body := [][]byte{
+		testMKVUint(mkvIDTrackNumber, number),
+		testMKVUint(mkvIDTrackType, mkvTrackTypeAudio),
+		testMKVElement(mkvIDCodecID, []byte(codec)),
+		settings,
+	}
+	return testMKVElement(mkvIDTrackEntry, append(body, children...)...)
+}
+
+// testMKVSimpleBlock is a test helper which generates a simple block for the
+// input track number, containing the input frames.  Multiple frames are stored
+// using Xiph lacing.
+func testMKVSimpleBlock(track byte, frames ...[]byte) []byte {
+	body := []byte{0x80 | track, 0, 0, 0x80}
+	if len(frames) > 1 {
+		body[3] |= 0x02
+		body = append(body, byte(len(frames)-1))
+		for _, f := range frames[:len(frames)-1] {
+			n := len(f)
+			for ; n >= 255; n -= 255 {
+				body = append(body, 255)
+			}
+			body = append(body, byte(n))
+		}
+	}
+	for _, f := range frames {
+		body = append(body, f...)
+	}
+
+	return testMKVElement(mkvIDSimpleBlock, body)
+}
+
+// testMKVUint is a test helper which generates an unsigned integer element.
+func testMKVUint(id uint32, v uint64) []byte {
+	return testMKVElement(id, binary.BigEndian.AppendUint64(nil, v))
+}
+
+// testMKVElement is a test helper which generates an element with the input ID,
+// and a body formed from the concatenation of all input byte slices.  Sizes
+// are always written in 8 bytes.
+func testMKVElement(id uint32, body ...[]byte) []byte {
+	var b []byte
+	for _, p := range body {
+		b = append(b, p...)
+	}
+
+	out := testMKVID(id)
+	out = append(out, 0x01)
+	out = append(out, binary.BigEndian.AppendUint64(nil, uint64(len(b)))[1:]...)
+	return append(out, b...)
+}
+
+// testMKVUnknownSize is a test helper which generates the header of an element
+// with the input ID and an unknown size.
+func testMKVUnknownSize(id uint32) []byte {
+	return append(testMKVID(id), 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+}
+
+// testMKVID is a test helper which encodes an element ID in its minimal number
+// of bytes.
+func testMKVID(id uint32) []byte {
+	b := binary.BigEndian.AppendUint32(nil, id)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+
+	return b
+}
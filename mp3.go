@@ -0,0 +1,103 @@
+package waveform
+
+import (
+	"io"
+
+	"azul3d.org/engine/audio"
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// mp3Magics are the magic strings which begin an MP3 stream: an ID3v2 tag, or
+// the frame sync and header of an MPEG-1, MPEG-2, or MPEG-2.5 Layer III frame,
+// with or without CRC protection.
+var mp3Magics = []string{
+	"ID3",
+	"\xff\xfb", "\xff\xfa",
+	"\xff\xf3", "\xff\xf2",
+	"\xff\xe3", "\xff\xe2",
+}
+
+func init() {
+	// Register MP3 streams with the audio package, so they are detected by
+	// the same format sniffing used for WAV and FLAC
+	for _, magic := range mp3Magics {
+		audio.RegisterFormat(FormatMP3, magic, newMP3Decoder)
+	}
+}
+
+// mp3Decoder is an audio.Decoder which decodes an MP3 stream to float64 PCM
+// samples, using a pure Go MP3 decoder.
+type mp3Decoder struct {
+	d      *mp3.Decoder
+	config audio.Config
+
+	// Reused buffer of 16-bit samples
+	buf []byte
+}
+
+// newMP3Decoder opens a decoder on an input MP3 stream.  If no valid MP3 frame
+// is found, ErrFormat is returned.
+func newMP3Decoder(r interface{}) (_ audio.Decoder, err error) {
+	rr, ok := r.(io.Reader)
+	if !ok {
+		return nil, audio.ErrInvalidData
+	}
+
+	// The first frame is decoded immediately, so a corrupt frame may panic
+	defer recoverMP3(&err)
+
+	d, err := mp3.NewDecoder(rr)
+	if err != nil {
+		return nil, audio.ErrFormat
+	}
+
+	// Samples are always decoded as 16-bit stereo, with a mono stream
+	// duplicated in both channels
+	return &mp3Decoder{
+		d: d,
+		config: audio.Config{
+			SampleRate: d.SampleRate(),
+			Channels:   2,
+		},
+	}, nil
+}
+
+// Config returns the audio configuration of the decoded stream.
+func (d *mp3Decoder) Config() audio.Config {
+	return d.config
+}
+
+// Read decodes samples into b, returning the number of samples read.  On the
+// final read, audio.EOS is returned along with any remaining samples.
+func (d *mp3Decoder) Read(b audio.Slice) (n int, err error) {
+	defer recoverMP3(&err)
+
+	if size := b.Len() * 2; len(d.buf) < size {
+		d.buf = make([]byte, size)
+	}
+
+	read, err := io.ReadFull(d.d, d.buf[:b.Len()*2])
+
+	n = read / 2
+	for i := 0; i < n; i++ {
+		b.Set(i, pcmSample(d.buf[i*2:], 16))
+	}
+
+	switch err {
+	case nil:
+		return n, nil
+	case io.EOF, io.ErrUnexpectedEOF:
+		return n, audio.EOS
+	default:
+		return n, audio.ErrInvalidData
+	}
+}
+
+// recoverMP3 recovers from a panic of the MP3 decoder, which indexes out of
+// range on some corrupt frames rather than returning an error, and reports it
+// in err as invalid data.  It must be deferred directly.
+func recoverMP3(err *error) {
+	if recover() != nil {
+		*err = audio.ErrInvalidData
+	}
+}
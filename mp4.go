@@ -0,0 +1,811 @@
+package waveform
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"azul3d.org/engine/audio"
+)
+
+func init() {
+	// Register MP4 containers (M4A, MP4) with the audio package, so they are
+	// detected by the same format sniffing used for WAV and FLAC.  Audio tracks
+	// are only decoded if a decoder for the track's codec is available.
+	audio.RegisterFormat("mp4", mp4Magic, newMP4Decoder)
+}
+
+// mp4Magic is the magic string which begins an MP4 container: its file type box.
+const mp4Magic = "????ftyp"
+
+// mp4Codecs is the set of codecs which can be decoded from an MP4 audio track,
+// keyed by the track's sample entry type.  Codec decoders add themselves to
+// this set when they are built into the package.
+var mp4Codecs = map[string]func(t *mp4Track) (audio.Decoder, error){}
+
+// newMP4Decoder reads an MP4 container from the input stream, and opens a
+// decoder for its first audio track.
+func newMP4Decoder(r interface{}) (audio.Decoder, error) {
+	rr, ok := r.(io.Reader)
+	if !ok {
+		return nil, audio.ErrInvalidData
+	}
+
+	return newMP4TrackDecoder(rr, 0)
+}
+
+// newMP4TrackDecoder reads an MP4 container from the input stream, and opens a
+// decoder for the audio track with the input zero-based index.
+//
+// MP4 sample tables may refer to any offset in the container, so the entire
+// stream is read into memory before decoding begins.
+func newMP4TrackDecoder(r io.Reader, index int) (audio.Decoder, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := readMP4Track(data, index)
+	if err != nil {
+		return nil, err
+	}
+
+	// Track is valid, but its codec cannot be decoded
+	newCodec, ok := mp4Codecs[t.format]
+	if !ok {
+		return nil, audio.ErrFormat
+	}
+
+	return newCodec(t)
+}
+
+// mp4Track describes the first audio track of an MP4 container, and the
+// encoded samples which belong to it.
+type mp4Track struct {
+	// Sample entry type, such as "mp4a" for AAC or "alac" for ALAC
+	format string
+
+	sampleRate int
+	channels   int
+
+	// Decoder specific configuration, such as the AAC AudioSpecificConfig
+	// or the ALACSpecificConfig
+	config []byte
+
+	// Encoded samples, in decoding order, followed by any samples returned by
+	// read, such as the frames of a Matroska track which are read from its
+	// stream as they are decoded
+	samples [][]byte
+	read    func() ([]byte, error)
+}
+
+// next returns the next encoded sample of the receiving track.  Once all
+// samples have been returned, io.EOF is returned.
+func (t *mp4Track) next() ([]byte, error) {
+	if len(t.samples) > 0 {
+		s := t.samples[0]
+		t.samples = t.samples[1:]
+		return s, nil
+	}
+	if t.read != nil {
+		return t.read()
+	}
+
+	return nil, io.EOF
+}
+
+// mp4Box is a single box parsed from an MP4 container.
+type mp4Box struct {
+	typ  string
+	body []byte
+
+	// Offset of the beginning of the box, relative to the data from which it
+	// was parsed
+	offset int
+}
+
+// readMP4Boxes parses all boxes which are direct children of the input data.
+func readMP4Boxes(data []byte) ([]mp4Box, error) {
+	var boxes []mp4Box
+	var offset int
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, audio.ErrInvalidData
+		}
+
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		typ := string(data[4:8])
+		header := uint64(8)
+
+		switch size {
+		case 0:
+			// Box extends to end of data
+			size = uint64(len(data))
+		case 1:
+			// 64-bit box size follows type
+			if len(data) < 16 {
+				return nil, audio.ErrInvalidData
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			header = 16
+		}
+
+		if size < header || size > uint64(len(data)) {
+			return nil, audio.ErrInvalidData
+		}
+
+		boxes = append(boxes, mp4Box{typ: typ, body: data[header:size], offset: offset})
+		data = data[size:]
+		offset += int(size)
+	}
+
+	return boxes, nil
+}
+
+// findMP4Box returns the body of the first child box of data with the input
+// type, or nil if none exists.
+func findMP4Box(data []byte, typ string) ([]byte, error) {
+	boxes, err := readMP4Boxes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b.body, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findMP4Path returns the body of the first box found by descending through
+// the input path of box types, or nil if none exists.
+func findMP4Path(data []byte, path ...string) ([]byte, error) {
+	var err error
+	for _, typ := range path {
+		if data, err = findMP4Box(data, typ); err != nil || data == nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// readMP4Track parses an entire MP4 container, and returns the audio track with
+// the input zero-based index.  If the container has fewer audio tracks, but at
+// least one, ErrTrackRange is returned.
+//
+// If the container is fragmented, such as a sequence of fMP4 or DASH segments
+// which follow a single initialization segment, the samples of each movie
+// fragment which belong to the track are appended to the track, in order.
+func readMP4Track(data []byte, index int) (*mp4Track, error) {
+	top, err := readMP4Boxes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the movie box, which describes all tracks
+	var moov []byte
+	for _, b := range top {
+		if b.typ == "moov" {
+			moov = b.body
+			break
+		}
+	}
+	if moov == nil {
+		return nil, audio.ErrInvalidData
+	}
+
+	traks, err := readMP4Boxes(moov)
+	if err != nil {
+		return nil, err
+	}
+
+	var found int
+	for _, b := range traks {
+		if b.typ != "trak" {
+			continue
+		}
+
+		// Only sound tracks are considered
+		hdlr, err := findMP4Path(b.body, "mdia", "hdlr")
+		if err != nil {
+			return nil, err
+		}
+		if len(hdlr) < 12 || string(hdlr[8:12]) != "soun" {
+			continue
+		}
+
+		// Skip audio tracks which precede the selected track
+		if found++; found <= index {
+			continue
+		}
+
+		stbl, err := findMP4Path(b.body, "mdia", "minf", "stbl")
+		if err != nil {
+			return nil, err
+		}
+		if stbl == nil {
+			return nil, audio.ErrInvalidData
+		}
+
+		t, err := readMP4SampleTable(data, stbl)
+		if err != nil {
+			return nil, err
+		}
+
+		// Movie fragments refer to the track by the ID in its header
+		tkhd, err := findMP4Box(b.body, "tkhd")
+		if err != nil || tkhd == nil {
+			return t, err
+		}
+		id, err := readMP4TrackID(tkhd)
+		if err != nil {
+			return nil, err
+		}
+
+		// Default sample sizes of movie fragments, if any
+		defaultSize, err := findMP4Trex(moov, id)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range top {
+			if f.typ != "moof" {
+				continue
+			}
+
+			if err := t.readFragment(data, f, id, defaultSize); err != nil {
+				return nil, err
+			}
+		}
+
+		return t, nil
+	}
+
+	// No audio track present
+	if found > 0 {
+		return nil, ErrTrackRange
+	}
+
+	return nil, audio.ErrFormat
+}
+
+// readMP4SampleTable parses a sample table box, producing a track with its
+// sample description and encoded samples.
+func readMP4SampleTable(data []byte, stbl []byte) (*mp4Track, error) {
+	t := new(mp4Track)
+
+	// Sample description: the first entry describes the codec
+	stsd, err := findMP4Box(stbl, "stsd")
+	if err != nil {
+		return nil, err
+	}
+	if len(stsd) < 8 {
+		return nil, audio.ErrInvalidData
+	}
+	if err := t.readSampleEntry(stsd[8:]); err != nil {
+		return nil, err
+	}
+
+	// Sample sizes
+	sizes, err := readMP4SampleSizes(stbl, len(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Chunk offsets, either 32-bit or 64-bit
+	offsets, err := readMP4ChunkOffsets(stbl)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mapping of samples to chunks
+	stsc, err := findMP4Box(stbl, "stsc")
+	if err != nil {
+		return nil, err
+	}
+	if len(stsc) < 8 {
+		return nil, audio.ErrInvalidData
+	}
+	count := int(binary.BigEndian.Uint32(stsc[4:8]))
+	if len(stsc) < 8+count*12 {
+		return nil, audio.ErrInvalidData
+	}
+
+	// Walk each chunk, assigning consecutive samples from each run of chunks
+	var n int
+	for i := 0; i < count; i++ {
+		entry := stsc[8+i*12:]
+		first := int(binary.BigEndian.Uint32(entry[0:4])) - 1
+		perChunk := int(binary.BigEndian.Uint32(entry[4:8]))
+
+		// The run continues until the next entry's first chunk, or the last chunk
+		last := len(offsets)
+		if i+1 < count {
+			last = int(binary.BigEndian.Uint32(stsc[8+(i+1)*12:])) - 1
+		}
+		if first < 0 || last > len(offsets) {
+			return nil, audio.ErrInvalidData
+		}
+
+		for c := first; c < last; c++ {
+			offset := offsets[c]
+			for s := 0; s < perChunk && n < len(sizes); s++ {
+				end := offset + uint64(sizes[n])
+				if end > uint64(len(data)) || end < offset {
+					return nil, audio.ErrUnexpectedEOS
+				}
+
+				t.samples = append(t.samples, data[offset:end])
+				offset = end
+				n++
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// readSampleEntry parses the first audio sample entry of a sample description
+// box into the receiving track.
+func (t *mp4Track) readSampleEntry(entries []byte) error {
+	boxes, err := readMP4Boxes(entries)
+	if err != nil {
+		return err
+	}
+	if len(boxes) == 0 {
+		return audio.ErrInvalidData
+	}
+
+	// Reserved fields and data reference index, followed by the
+	// audio sample entry fields
+	b := boxes[0]
+	if len(b.body) < 28 {
+		return audio.ErrInvalidData
+	}
+	t.format = b.typ
+	t.channels = int(binary.BigEndian.Uint16(b.body[16:18]))
+	t.sampleRate = int(binary.BigEndian.Uint32(b.body[24:28]) >> 16)
+	if t.channels > channelsMax {
+		return audio.ErrInvalidData
+	}
+
+	// QuickTime sound description versions carry additional fields before
+	// any child boxes
+	children := b.body[28:]
+	switch binary.BigEndian.Uint16(b.body[8:10]) {
+	case 1:
+		if len(children) < 16 {
+			return audio.ErrInvalidData
+		}
+		children = children[16:]
+	case 2:
+		if len(children) < 36 {
+			return audio.ErrInvalidData
+		}
+		children = children[36:]
+	}
+
+	// Codec specific configuration
+	switch t.format {
+	case "mp4a":
+		esds, err := findMP4Box(children, "esds")
+		if err != nil {
+			return err
+		}
+		if len(esds) < 4 {
+			return audio.ErrInvalidData
+		}
+		t.config = readESDSConfig(esds[4:])
+	case "alac":
+		// Version and flags precede the ALACSpecificConfig
+		alac, err := findMP4Box(children, "alac")
+		if err != nil {
+			return err
+		}
+		if len(alac) < 4 {
+			return audio.ErrInvalidData
+		}
+		t.config = alac[4:]
+	}
+
+	return nil
+}
+
+// readESDSConfig finds the decoder specific information in the descriptors
+// of an elementary stream descriptor box, or returns nil if none exists.
+func readESDSConfig(d []byte) []byte {
+	for len(d) > 1 {
+		tag := d[0]
+		d = d[1:]
+
+		// Descriptor length is encoded using 7 bits per byte
+		var length int
+		for i := 0; i < 4 && len(d) > 0; i++ {
+			c := d[0]
+			d = d[1:]
+			length = length<<7 | int(c&0x7f)
+			if c&0x80 == 0 {
+				break
+			}
+		}
+
+		switch tag {
+		case 0x03:
+			// ES descriptor: skip ES ID and flags, along with optional fields,
+			// then continue into nested descriptors
+			if len(d) < 3 {
+				return nil
+			}
+			flags := d[2]
+			d = d[3:]
+			if flags&0x80 != 0 && len(d) >= 2 {
+				d = d[2:]
+			}
+			if flags&0x40 != 0 && len(d) >= 1 {
+				if n := int(d[0]) + 1; len(d) >= n {
+					d = d[n:]
+				}
+			}
+			if flags&0x20 != 0 && len(d) >= 2 {
+				d = d[2:]
+			}
+		case 0x04:
+			// Decoder config descriptor: skip fixed fields, then continue
+			// into nested descriptors
+			if len(d) < 13 {
+				return nil
+			}
+			d = d[13:]
+		case 0x05:
+			// Decoder specific information
+			if length > len(d) {
+				return nil
+			}
+			return d[:length]
+		default:
+			if length > len(d) {
+				return nil
+			}
+			d = d[length:]
+		}
+	}
+
+	return nil
+}
+
+// readMP4SampleSizes parses the sample size box of a sample table, or its
+// compact sample size box if no sample size box exists.  The samples must fit
+// within a container of the input size in bytes.
+func readMP4SampleSizes(stbl []byte, size int) ([]uint32, error) {
+	stsz, err := findMP4Box(stbl, "stsz")
+	if err != nil {
+		return nil, err
+	}
+	if stsz == nil {
+		return readMP4CompactSampleSizes(stbl)
+	}
+	if len(stsz) < 12 {
+		return nil, audio.ErrInvalidData
+	}
+
+	sampleSize := binary.BigEndian.Uint32(stsz[4:8])
+	count := int(binary.BigEndian.Uint32(stsz[8:12]))
+
+	// The number of samples is bounded by the size of each sample, or by the
+	// number of sizes which are stored
+	if sampleSize != 0 && uint64(count)*uint64(sampleSize) > uint64(size) {
+		return nil, audio.ErrInvalidData
+	}
+	if sampleSize == 0 && len(stsz) < 12+count*4 {
+		return nil, audio.ErrInvalidData
+	}
+
+	sizes := make([]uint32, count)
+	for i := range sizes {
+		// All samples share the same size
+		if sampleSize != 0 {
+			sizes[i] = sampleSize
+			continue
+		}
+
+		off := 12 + i*4
+		sizes[i] = binary.BigEndian.Uint32(stsz[off : off+4])
+	}
+
+	return sizes, nil
+}
+
+// readMP4CompactSampleSizes parses the compact sample size box of a sample
+// table, which stores each sample size using 4, 8, or 16 bits.
+func readMP4CompactSampleSizes(stbl []byte) ([]uint32, error) {
+	stz2, err := findMP4Box(stbl, "stz2")
+	if err != nil {
+		return nil, err
+	}
+	if len(stz2) < 12 {
+		return nil, audio.ErrInvalidData
+	}
+
+	width := int(stz2[7])
+	count := int(binary.BigEndian.Uint32(stz2[8:12]))
+	fields := stz2[12:]
+
+	switch width {
+	case 4, 8, 16:
+	default:
+		return nil, audio.ErrInvalidData
+	}
+	if len(fields)*8 < count*width {
+		return nil, audio.ErrInvalidData
+	}
+
+	sizes := make([]uint32, count)
+	for i := range sizes {
+		switch width {
+		case 4:
+			// Two sizes per byte, beginning with the high nibble
+			b := fields[i/2]
+			if i%2 == 0 {
+				b >>= 4
+			}
+			sizes[i] = uint32(b & 0x0f)
+		case 8:
+			sizes[i] = uint32(fields[i])
+		case 16:
+			sizes[i] = uint32(binary.BigEndian.Uint16(fields[i*2:]))
+		}
+	}
+
+	return sizes, nil
+}
+
+// readMP4ChunkOffsets parses the 32-bit or 64-bit chunk offset box of a
+// sample table.
+func readMP4ChunkOffsets(stbl []byte) ([]uint64, error) {
+	width := 4
+	box, err := findMP4Box(stbl, "stco")
+	if err != nil {
+		return nil, err
+	}
+	if box == nil {
+		width = 8
+		if box, err = findMP4Box(stbl, "co64"); err != nil {
+			return nil, err
+		}
+	}
+	if len(box) < 8 {
+		return nil, audio.ErrInvalidData
+	}
+
+	count := int(binary.BigEndian.Uint32(box[4:8]))
+	if len(box) < 8+count*width {
+		return nil, audio.ErrInvalidData
+	}
+
+	offsets := make([]uint64, count)
+	for i := range offsets {
+		off := 8 + i*width
+		if width == 4 {
+			offsets[i] = uint64(binary.BigEndian.Uint32(box[off:]))
+		} else {
+			offsets[i] = binary.BigEndian.Uint64(box[off:])
+		}
+	}
+
+	return offsets, nil
+}
+
+// readMP4TrackID parses the track ID of a track header box.
+func readMP4TrackID(tkhd []byte) (uint32, error) {
+	// Creation and modification times precede the track ID, and are 64-bit
+	// in version 1
+	off := 12
+	if len(tkhd) > 0 && tkhd[0] == 1 {
+		off = 20
+	}
+	if len(tkhd) < off+4 {
+		return 0, audio.ErrInvalidData
+	}
+
+	return binary.BigEndian.Uint32(tkhd[off : off+4]), nil
+}
+
+// findMP4Trex returns the default sample size of movie fragments for the
+// input track, as set by the track extends box of a movie box, or 0 if none
+// is set.
+func findMP4Trex(moov []byte, id uint32) (uint32, error) {
+	mvex, err := findMP4Box(moov, "mvex")
+	if err != nil || mvex == nil {
+		return 0, err
+	}
+
+	boxes, err := readMP4Boxes(mvex)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, b := range boxes {
+		if b.typ != "trex" {
+			continue
+		}
+		if len(b.body) < 24 {
+			return 0, audio.ErrInvalidData
+		}
+
+		if binary.BigEndian.Uint32(b.body[4:8]) == id {
+			return binary.BigEndian.Uint32(b.body[16:20]), nil
+		}
+	}
+
+	return 0, nil
+}
+
+// Flags of track fragment header boxes.
+const (
+	mp4TfhdBaseDataOffset   = 0x000001
+	mp4TfhdDescriptionIndex = 0x000002
+	mp4TfhdDefaultDuration  = 0x000008
+	mp4TfhdDefaultSize      = 0x000010
+	mp4TfhdDefaultFlags     = 0x000020
+)
+
+// Flags of track fragment run boxes.
+const (
+	mp4TrunDataOffset       = 0x000001
+	mp4TrunFirstSampleFlags = 0x000004
+	mp4TrunSampleDuration   = 0x000100
+	mp4TrunSampleSize       = 0x000200
+	mp4TrunSampleFlags      = 0x000400
+	mp4TrunSampleCTO        = 0x000800
+)
+
+// readFragment parses a movie fragment box, and appends the samples of each
+// of its track fragments which belong to the track with the input ID.
+// defaultSize is the default sample size of the track, from its track
+// extends box.
+func (t *mp4Track) readFragment(data []byte, moof mp4Box, id uint32, defaultSize uint32) error {
+	trafs, err := readMP4Boxes(moof.body)
+	if err != nil {
+		return err
+	}
+
+	for _, traf := range trafs {
+		if traf.typ != "traf" {
+			continue
+		}
+
+		boxes, err := readMP4Boxes(traf.body)
+		if err != nil {
+			return err
+		}
+
+		// Track fragment header, which must precede all runs
+		var tfhd []byte
+		for _, b := range boxes {
+			if b.typ == "tfhd" {
+				tfhd = b.body
+				break
+			}
+		}
+		if len(tfhd) < 8 {
+			return audio.ErrInvalidData
+		}
+		if binary.BigEndian.Uint32(tfhd[4:8]) != id {
+			continue
+		}
+
+		// Sample data is relative to the beginning of the movie fragment,
+		// unless an explicit base offset is set
+		flags := binary.BigEndian.Uint32(tfhd[0:4]) & 0xffffff
+		base := uint64(moof.offset)
+		size := defaultSize
+		fields := tfhd[8:]
+		for _, f := range []struct {
+			flag uint32
+			len  int
+		}{
+			{mp4TfhdBaseDataOffset, 8},
+			{mp4TfhdDescriptionIndex, 4},
+			{mp4TfhdDefaultDuration, 4},
+			{mp4TfhdDefaultSize, 4},
+			{mp4TfhdDefaultFlags, 4},
+		} {
+			if flags&f.flag == 0 {
+				continue
+			}
+			if len(fields) < f.len {
+				return audio.ErrInvalidData
+			}
+
+			switch f.flag {
+			case mp4TfhdBaseDataOffset:
+				base = binary.BigEndian.Uint64(fields[0:8])
+			case mp4TfhdDefaultSize:
+				size = binary.BigEndian.Uint32(fields[0:4])
+			}
+			fields = fields[f.len:]
+		}
+
+		// Runs without a data offset continue from the end of the previous run
+		offset := base
+		for _, b := range boxes {
+			if b.typ != "trun" {
+				continue
+			}
+
+			if offset, err = t.readTrackRun(data, b.body, base, offset, size); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readTrackRun parses a track fragment run box, and appends its samples to the
+// receiving track.  Samples begin at offset, unless the run sets a data offset
+// relative to base.  Samples without an explicit size have size defaultSize.
+// The offset of the end of the run's sample data is returned.
+func (t *mp4Track) readTrackRun(data []byte, trun []byte, base uint64, offset uint64, defaultSize uint32) (uint64, error) {
+	if len(trun) < 8 {
+		return 0, audio.ErrInvalidData
+	}
+
+	flags := binary.BigEndian.Uint32(trun[0:4]) & 0xffffff
+	count := int(binary.BigEndian.Uint32(trun[4:8]))
+	fields := trun[8:]
+
+	if flags&mp4TrunDataOffset != 0 {
+		if len(fields) < 4 {
+			return 0, audio.ErrInvalidData
+		}
+		offset = base + uint64(int64(int32(binary.BigEndian.Uint32(fields[0:4]))))
+		fields = fields[4:]
+	}
+	if flags&mp4TrunFirstSampleFlags != 0 {
+		if len(fields) < 4 {
+			return 0, audio.ErrInvalidData
+		}
+		fields = fields[4:]
+	}
+
+	// Each sample has a field for each optional value which is present
+	var stride int
+	for _, f := range []uint32{mp4TrunSampleDuration, mp4TrunSampleSize, mp4TrunSampleFlags, mp4TrunSampleCTO} {
+		if flags&f != 0 {
+			stride += 4
+		}
+	}
+	if len(fields) < count*stride {
+		return 0, audio.ErrInvalidData
+	}
+
+	// Every sample occupies at least one byte of the container
+	if uint64(count) > uint64(len(data)) {
+		return 0, audio.ErrInvalidData
+	}
+
+	for i := 0; i < count; i++ {
+		size := defaultSize
+		if flags&mp4TrunSampleSize != 0 {
+			off := i * stride
+			if flags&mp4TrunSampleDuration != 0 {
+				off += 4
+			}
+			size = binary.BigEndian.Uint32(fields[off : off+4])
+		}
+
+		end := offset + uint64(size)
+		if end > uint64(len(data)) || end < offset {
+			return 0, audio.ErrUnexpectedEOS
+		}
+
+		t.samples = append(t.samples, data[offset:end])
+		offset = end
+	}
+
+	return offset, nil
+}
@@ -0,0 +1,330 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// TestReadMP4Track verifies that readMP4Track finds the first audio track of
+// an MP4 container, along with its configuration and encoded samples.
+func TestReadMP4Track(t *testing.T) {
+	samples := [][]byte{{1, 2, 3}, {4, 5}, {6, 7, 8, 9}}
+	config := []byte{0x12, 0x10}
+
+	track, err := readMP4Track(testMP4(44100, 2, config, samples), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if track.format != "mp4a" {
+		t.Fatalf("unexpected track format: %v != %v", track.format, "mp4a")
+	}
+	if track.sampleRate != 44100 {
+		t.Fatalf("unexpected sample rate: %v != %v", track.sampleRate, 44100)
+	}
+	if track.channels != 2 {
+		t.Fatalf("unexpected channels: %v != %v", track.channels, 2)
+	}
+	if !bytes.Equal(track.config, config) {
+		t.Fatalf("unexpected config: %v != %v", track.config, config)
+	}
+
+	if len(track.samples) != len(samples) {
+		t.Fatalf("unexpected samples length: %v != %v", len(track.samples), len(samples))
+	}
+	for i := range samples {
+		if !bytes.Equal(track.samples[i], samples[i]) {
+			t.Fatalf("[%02d] unexpected sample: %v != %v", i, track.samples[i], samples[i])
+		}
+	}
+}
+
+// TestReadMP4TrackFragmented verifies that readMP4Track appends the samples of
+// each movie fragment in a sequence of fMP4 segments to the track, using
+// explicit sample sizes and data offsets, or the defaults of the track.
+func TestReadMP4TrackFragmented(t *testing.T) {
+	segments := [][][]byte{
+		{{1, 2, 3}, {4, 5}},
+		{{6, 7}, {8, 9}},
+		{{10}},
+	}
+
+	track, err := readMP4Track(testFMP4(segments), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if track.format != "mp4a" || track.sampleRate != 48000 || track.channels != 2 {
+		t.Fatalf("unexpected track: %v, %v, %v", track.format, track.sampleRate, track.channels)
+	}
+
+	var samples [][]byte
+	for _, s := range segments {
+		samples = append(samples, s...)
+	}
+	if !reflect.DeepEqual(track.samples, samples) {
+		t.Fatalf("unexpected samples: %v != %v", track.samples, samples)
+	}
+}
+
+// TestReadMP4TrackFragmentedErrUnexpectedEOS verifies that readMP4Track returns
+// ErrUnexpectedEOS for a movie fragment whose media data is missing.
+func TestReadMP4TrackFragmentedErrUnexpectedEOS(t *testing.T) {
+	data := testFMP4([][][]byte{{{1, 2, 3}, {4, 5}}})
+	if _, err := readMP4Track(data[:len(data)-len(testMP4Box("mdat", make([]byte, 5)))], 0); err != ErrUnexpectedEOS {
+		t.Fatalf("unexpected readMP4Track error: %v != %v", err, ErrUnexpectedEOS)
+	}
+}
+
+// TestReadMP4CompactSampleSizes verifies that readMP4SampleSizes parses a
+// compact sample size box, for each of its field sizes.
+func TestReadMP4CompactSampleSizes(t *testing.T) {
+	var tests = []struct {
+		width  byte
+		fields []byte
+		sizes  []uint32
+	}{
+		{4, []byte{0x3a, 0xf0}, []uint32{3, 10, 15}},
+		{8, []byte{3, 200, 255}, []uint32{3, 200, 255}},
+		{16, []byte{0, 3, 0x12, 0x34, 0xff, 0xff}, []uint32{3, 0x1234, 0xffff}},
+	}
+
+	for i, test := range tests {
+		body := append([]byte{0, 0, 0, 0, 0, 0, 0, test.width}, 0, 0, 0, 3)
+		stbl := testMP4Box("stz2", body, test.fields)
+
+		sizes, err := readMP4SampleSizes(stbl, 100)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+		if !reflect.DeepEqual(sizes, test.sizes) {
+			t.Fatalf("[%02d] unexpected sample sizes: %v != %v", i, sizes, test.sizes)
+		}
+	}
+}
+
+// TestReadMP4CompactSampleSizesErrInvalidData verifies that readMP4SampleSizes
+// returns ErrInvalidData for a compact sample size box with an unknown field
+// size, or with too few fields.
+func TestReadMP4CompactSampleSizesErrInvalidData(t *testing.T) {
+	for i, body := range [][]byte{
+		{0, 0, 0, 0, 0, 0, 0, 12, 0, 0, 0, 1, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 16, 0, 0, 0, 2, 0, 0},
+	} {
+		if _, err := readMP4SampleSizes(testMP4Box("stz2", body), 100); err != ErrInvalidData {
+			t.Fatalf("[%02d] unexpected readMP4SampleSizes error: %v != %v", i, err, ErrInvalidData)
+		}
+	}
+}
+
+// TestReadMP4SampleSizesErrInvalidData verifies that readMP4SampleSizes returns
+// ErrInvalidData for a sample size box whose samples cannot fit within the
+// container, or with too few sizes, rather than allocating a size for each
+// sample.
+func TestReadMP4SampleSizesErrInvalidData(t *testing.T) {
+	for i, body := range [][]byte{
+		// Constant size, with a count larger than the container
+		{0, 0, 0, 0, 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff},
+		{0, 0, 0, 0, 0, 0, 0, 10, 0, 0, 0, 11},
+		// Sizes of each sample, with too few sizes
+		{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 1},
+	} {
+		if _, err := readMP4SampleSizes(testMP4Box("stsz", body), 100); err != ErrInvalidData {
+			t.Fatalf("[%02d] unexpected readMP4SampleSizes error: %v != %v", i, err, ErrInvalidData)
+		}
+	}
+}
+
+// TestReadMP4SampleTableErrUnexpectedEOS verifies that readMP4SampleTable
+// returns ErrUnexpectedEOS for a 64-bit chunk offset whose samples would end
+// past the end of the container, including when their end overflows.
+func TestReadMP4SampleTableErrUnexpectedEOS(t *testing.T) {
+	data := testMP4(44100, 2, []byte{0x12, 0x10}, nil)
+	body, err := findMP4Path(data, "moov", "trak", "mdia", "minf", "stbl", "stsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stsd := testMP4Box("stsd", body)
+	stsz := testMP4Box("stsz", []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 16})
+	stsc := testMP4Box("stsc", []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1})
+
+	for i, offset := range []uint64{1 << 40, 1<<64 - 8} {
+		co64 := binary.BigEndian.AppendUint64([]byte{0, 0, 0, 0, 0, 0, 0, 1}, offset)
+		stbl := testMP4Box("stbl", stsd, stsz, stsc, testMP4Box("co64", co64))[8:]
+
+		if _, err := readMP4SampleTable(data, stbl); err != ErrUnexpectedEOS {
+			t.Fatalf("[%02d] unexpected readMP4SampleTable error: %v != %v", i, err, ErrUnexpectedEOS)
+		}
+	}
+}
+
+// TestReadMP4TrackErrInvalidData verifies that readMP4Track returns ErrInvalidData
+// for a truncated MP4 container.
+func TestReadMP4TrackErrInvalidData(t *testing.T) {
+	data := testMP4(44100, 2, []byte{0x12, 0x10}, [][]byte{{1, 2, 3}})
+	if _, err := readMP4Track(data[:40], 0); err != ErrInvalidData {
+		t.Fatalf("unexpected readMP4Track error: %v != %v", err, ErrInvalidData)
+	}
+}
+
+// TestWaveformComputeM4AErrFormat verifies that the Waveform.Compute method returns
+// ErrFormat for an M4A stream, when no AAC decoder is built into the package.
+func TestWaveformComputeM4AErrFormat(t *testing.T) {
+	if _, ok := mp4Codecs["mp4a"]; ok {
+		t.Skip("AAC decoder is available")
+	}
+
+	data := testMP4(44100, 2, []byte{0x12, 0x10}, [][]byte{{1, 2, 3}})
+	testWaveformCompute(t, bytes.NewReader(data), ErrFormat, nil, nil)
+}
+
+// testMP4 is a test helper which generates an M4A container with a single AAC
+// audio track, using the input configuration and encoded samples.
+func testMP4(sampleRate uint32, channels uint16, config []byte, samples [][]byte) []byte {
+	// Elementary stream descriptor, with nested decoder config and
+	// decoder specific information
+	dsi := append([]byte{0x05, byte(len(config))}, config...)
+	dcd := append([]byte{0x04, byte(13 + len(dsi)), 0x40, 0x15}, make([]byte, 11)...)
+	dcd = append(dcd, dsi...)
+	esd := append([]byte{0x03, byte(3 + len(dcd)), 0, 1, 0}, dcd...)
+	esds := testMP4Box("esds", make([]byte, 4), esd)
+
+	return testMP4Track("mp4a", sampleRate, channels, esds, samples)
+}
+
+// testMP4Track is a test helper which generates an MP4 container with a single
+// audio track, whose sample entry has the input type and child boxes.
+func testMP4Track(format string, sampleRate uint32, channels uint16, children []byte, samples [][]byte) []byte {
+	// Audio sample entry
+	entry := make([]byte, 28)
+	binary.BigEndian.PutUint16(entry[6:8], 1)
+	binary.BigEndian.PutUint16(entry[16:18], channels)
+	binary.BigEndian.PutUint16(entry[18:20], 16)
+	binary.BigEndian.PutUint32(entry[24:28], sampleRate<<16)
+	stsd := testMP4Box("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1}, testMP4Box(format, entry, children))
+
+	// Sample sizes, and a single chunk containing all samples
+	stsz := []byte{0, 0, 0, 0, 0, 0, 0, 0}
+	stsz = binary.BigEndian.AppendUint32(stsz, uint32(len(samples)))
+	var mdat []byte
+	for _, s := range samples {
+		stsz = binary.BigEndian.AppendUint32(stsz, uint32(len(s)))
+		mdat = append(mdat, s...)
+	}
+	stsc := []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1}
+	stsc = binary.BigEndian.AppendUint32(stsc, uint32(len(samples)))
+	stsc = binary.BigEndian.AppendUint32(stsc, 1)
+
+	ftyp := testMP4Box("ftyp", []byte("M4A \x00\x00\x00\x00M4A mp42isom"))
+	hdlr := testMP4Box("hdlr", make([]byte, 8), []byte("soun"), make([]byte, 13))
+
+	// Chunk offset is known once the size of all preceding boxes is known
+	build := func(offset uint32) []byte {
+		stco := binary.BigEndian.AppendUint32([]byte{0, 0, 0, 0, 0, 0, 0, 1}, offset)
+		stbl := testMP4Box("stbl", stsd, testMP4Box("stsz", stsz), testMP4Box("stsc", stsc), testMP4Box("stco", stco))
+		mdia := testMP4Box("mdia", hdlr, testMP4Box("minf", stbl))
+		moov := testMP4Box("moov", testMP4Box("trak", mdia))
+
+		return append(append(ftyp, moov...), testMP4Box("mdat", mdat)...)
+	}
+
+	return build(uint32(len(build(0)) - len(mdat)))
+}
+
+// testMP4Box is a test helper which generates an MP4 box with the input type,
+// and a body formed from the concatenation of all input byte slices.
+func testMP4Box(typ string, body ...[]byte) []byte {
+	var b []byte
+	for _, p := range body {
+		b = append(b, p...)
+	}
+
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(b)))
+	out = append(out, typ...)
+	return append(out, b...)
+}
+
+// testFMP4 is a test helper which generates an fMP4 initialization segment for
+// a single 48kHz stereo AAC audio track, followed by a media segment for each
+// input group of encoded samples.  Even segments store explicit sample sizes
+// and a data offset relative to their movie fragment, and odd segments use an
+// absolute base data offset and the track's default sample size of 2.
+func testFMP4(segments [][][]byte) []byte {
+	const id = 7
+
+	// Initialization segment, with an empty sample table
+	entry := make([]byte, 28)
+	binary.BigEndian.PutUint16(entry[6:8], 1)
+	binary.BigEndian.PutUint16(entry[16:18], 2)
+	binary.BigEndian.PutUint16(entry[18:20], 16)
+	binary.BigEndian.PutUint32(entry[24:28], 48000<<16)
+	stsd := testMP4Box("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1}, testMP4Box("mp4a", entry, testMP4Box("esds", make([]byte, 4))))
+	stbl := testMP4Box("stbl", stsd,
+		testMP4Box("stsz", make([]byte, 12)),
+		testMP4Box("stsc", make([]byte, 8)),
+		testMP4Box("stco", make([]byte, 8)),
+	)
+
+	tkhd := binary.BigEndian.AppendUint32(make([]byte, 12), id)
+	hdlr := testMP4Box("hdlr", make([]byte, 8), []byte("soun"), make([]byte, 13))
+	trak := testMP4Box("trak", testMP4Box("tkhd", tkhd, make([]byte, 64)), testMP4Box("mdia", hdlr, testMP4Box("minf", stbl)))
+
+	trex := binary.BigEndian.AppendUint32(make([]byte, 4), id)
+	trex = binary.BigEndian.AppendUint32(trex, 1)
+	trex = binary.BigEndian.AppendUint32(trex, 1024)
+	trex = binary.BigEndian.AppendUint32(trex, 2)
+	trex = binary.BigEndian.AppendUint32(trex, 0)
+
+	out := testMP4Box("ftyp", []byte("iso6\x00\x00\x00\x00iso6dash"))
+	out = append(out, testMP4Box("moov", trak, testMP4Box("mvex", testMP4Box("trex", trex)))...)
+
+	// Media segments
+	for i, samples := range segments {
+		out = append(out, testMP4Box("styp", []byte("msdh\x00\x00\x00\x00msdh"))...)
+
+		var mdat []byte
+		for _, s := range samples {
+			mdat = append(mdat, s...)
+		}
+
+		// Data offsets are known once the size of the movie fragment is known
+		build := func(offset uint64) []byte {
+			tfhd := []byte{0, 0, 0, 0}
+			trun := []byte{0, 0, 0, 0}
+			if i%2 == 0 {
+				tfhd = []byte{0, 0x02, 0, 0}
+				trun = []byte{0, 0, 0x02, 0x01}
+			}
+
+			tfhd = binary.BigEndian.AppendUint32(tfhd, id)
+			trun = binary.BigEndian.AppendUint32(trun, uint32(len(samples)))
+			if i%2 == 0 {
+				trun = binary.BigEndian.AppendUint32(trun, uint32(offset))
+				for _, s := range samples {
+					trun = binary.BigEndian.AppendUint32(trun, uint32(len(s)))
+				}
+			} else {
+				tfhd[3] = 0x01
+				tfhd = binary.BigEndian.AppendUint64(tfhd, offset)
+			}
+
+			mfhd := testMP4Box("mfhd", binary.BigEndian.AppendUint32(make([]byte, 4), uint32(i+1)))
+			return testMP4Box("moof", mfhd, testMP4Box("traf", testMP4Box("tfhd", tfhd), testMP4Box("trun", trun)))
+		}
+
+		moof := build(0)
+		if i%2 == 0 {
+			moof = build(uint64(len(moof) + 8))
+		} else {
+			moof = build(uint64(len(out) + len(moof) + 8))
+		}
+
+		out = append(out, moof...)
+		out = append(out, testMP4Box("mdat", mdat)...)
+	}
+
+	return out
+}
@@ -0,0 +1,195 @@
+package waveform
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"io"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// ErrSegmentMismatch is returned when multiple input audio streams are treated
+// as a single timeline, but their sample rates or channels do not match.
+var ErrSegmentMismatch = errors.New("waveform: audio segments have different sample rates or channels")
+
+// GenerateMulti opens and reads multiple input audio streams in sequence, and
+// treats them as a single, continuous timeline.  It computes the values required
+// for waveform generation, and returns a waveform image which is customized by
+// zero or more, variadic, OptionsFunc parameters.
+//
+// The width of the image reflects the total duration of all streams, and slices
+// of audio may span the boundary between streams.  All streams must have the
+// same sample rate and channels, or ErrSegmentMismatch is returned.  If the
+// Resample option is set, each stream is resampled to the target sample rate,
+// so only the channels must match.  Streams are opened only when the preceding
+// stream has been read entirely.
+//
+// The beginning of each stream after the first is reported as a marker by
+// Metadata, so if the DrawMarkers option is set, a line is drawn at each
+// boundary between streams.
+//
+// If no streams are provided, ErrNoSamples is returned.  Error handling is
+// otherwise the same as Generate.
+func GenerateMulti(readers []io.Reader, options ...OptionsFunc) (image.Image, error) {
+	if len(readers) == 0 {
+		return nil, ErrNoSamples
+	}
+
+	w, err := New(nil, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate struct members before reading any input
+	if err := w.validateCompute(); err != nil {
+		return nil, err
+	}
+
+	d, err := newMultiDecoder(readers, w.resampleRate, w.openDecoder)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.drawComputed(w.computeSamples(d, d.fraction))
+}
+
+// multiDecoder is an audio.Decoder which decodes multiple input audio streams
+// in sequence, as a single stream.
+type multiDecoder struct {
+	readers []io.Reader
+	config  audio.Config
+
+	// Target sample rate of all streams, or 0 if streams are not resampled
+	sampleRate int
+
+	// Opens a decoder on each stream
+	openDecoder func(br *bufio.Reader) (audio.Decoder, error)
+
+	// Index of the current stream, its decoder, and the decoder which must be
+	// closed when it is no longer read, if any, such as an external decoder
+	index   int
+	current audio.Decoder
+	closer  io.Closer
+
+	// Number of samples read from all streams, and the timestamp at which
+	// each stream after the first begins
+	read       int64
+	boundaries []time.Duration
+}
+
+// newMultiDecoder opens a decoder on the first of the input audio streams,
+// which determines the configuration of all streams.  If sampleRate is not 0,
+// all streams are resampled to it.  Each decoder is opened using the input
+// function.
+func newMultiDecoder(readers []io.Reader, sampleRate int, open func(br *bufio.Reader) (audio.Decoder, error)) (*multiDecoder, error) {
+	d := &multiDecoder{
+		readers:     readers,
+		sampleRate:  sampleRate,
+		openDecoder: open,
+	}
+
+	decoder, err := d.open(readers[0])
+	if err != nil {
+		return nil, err
+	}
+
+	d.config = decoder.Config()
+	d.current = decoder
+	return d, nil
+}
+
+// open opens a decoder on an input audio stream, resampling it to the target
+// sample rate if needed.
+func (d *multiDecoder) open(r io.Reader) (audio.Decoder, error) {
+	decoder, err := d.openDecoder(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	d.closer, _ = decoder.(io.Closer)
+
+	if d.sampleRate > 0 && decoder.Config().SampleRate != d.sampleRate {
+		return newResampleDecoder(decoder, d.sampleRate), nil
+	}
+
+	return decoder, nil
+}
+
+// Close closes the decoder of the current stream, if it must be closed, such
+// as an external decoder whose process has not exited.
+func (d *multiDecoder) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+
+	return d.closer.Close()
+}
+
+// Config returns the audio configuration shared by all streams.
+func (d *multiDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read decodes samples into b, returning the number of samples read.  When one
+// stream ends, decoding continues with the next stream, so that b is filled
+// without a gap.  When the final stream ends, audio.EOS is returned along with
+// any remaining samples.
+func (d *multiDecoder) Read(b audio.Slice) (int, error) {
+	var n int
+	for n < b.Len() {
+		read, err := d.current.Read(b.Slice(n, b.Len()))
+		n += read
+		d.read += int64(read)
+		if err == nil {
+			continue
+		}
+		if err != audio.EOS {
+			return n, err
+		}
+
+		// Current stream is complete, so continue with the next stream
+		if d.index+1 >= len(d.readers) {
+			return n, audio.EOS
+		}
+		if err := d.next(); err != nil {
+			return n, err
+		}
+		d.boundaries = append(d.boundaries, d.elapsed())
+	}
+
+	return n, nil
+}
+
+// next opens a decoder on the next input audio stream, verifying that its
+// configuration matches the first stream.
+func (d *multiDecoder) next() error {
+	d.index++
+
+	decoder, err := d.open(d.readers[d.index])
+	if err != nil {
+		return err
+	}
+	if decoder.Config() != d.config {
+		return ErrSegmentMismatch
+	}
+
+	d.current = decoder
+	return nil
+}
+
+// elapsed returns the timestamp of the next sample to be read from all
+// streams.  It is rounded up to the nearest nanosecond, so that converting it
+// back to a sample offset produces the same sample.
+func (d *multiDecoder) elapsed() time.Duration {
+	frames := d.read / int64(d.config.Channels)
+	rate := int64(d.config.SampleRate)
+
+	return time.Duration((frames*int64(time.Second) + rate - 1) / rate)
+}
+
+// fraction returns the fraction of input audio streams which have been read
+// entirely.
+func (d *multiDecoder) fraction() float64 {
+	return float64(d.index) / float64(len(d.readers))
+}
@@ -0,0 +1,113 @@
+package waveform
+
+import (
+	"bytes"
+	"image/color"
+	"io"
+	"testing"
+)
+
+// TestGenerateMulti verifies that GenerateMulti treats multiple input audio
+// streams as a single timeline, computing slices which span the boundary
+// between streams.
+func TestGenerateMulti(t *testing.T) {
+	// 1.5 seconds of a square wave at 0.5 amplitude, followed by 1.5 seconds
+	// at 0.25 amplitude, in 8-bit unsigned PCM
+	segment := func(hi byte, lo byte) []byte {
+		data := make([]byte, 150)
+		for i := range data {
+			data[i] = hi
+			if i%2 == 1 {
+				data[i] = lo
+			}
+		}
+
+		return data
+	}
+	a, b := segment(192, 64), segment(160, 96)
+
+	img, err := GenerateMulti([]io.Reader{
+		bytes.NewReader(testWAV(1, 1, 100, 8, a)),
+		bytes.NewReader(testWAV(1, 1, 100, 8, b)),
+	}, Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Output must match the same audio as a single stream
+	want, err := Generate(bytes.NewReader(testWAV(1, 1, 100, 8, append(a, b...))), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxX := img.Bounds().Max.X; maxX != 3 {
+		t.Fatalf("unexpected image width: %v != %v", maxX, 3)
+	}
+	testImagesEqual(t, img, want)
+}
+
+// TestGenerateMultiErrors verifies that GenerateMulti returns appropriate
+// errors for missing or mismatched input audio streams.
+func TestGenerateMultiErrors(t *testing.T) {
+	data := make([]byte, 100)
+
+	var tests = []struct {
+		readers []io.Reader
+		err     error
+	}{
+		{nil, ErrNoSamples},
+		// Sample rate mismatch
+		{[]io.Reader{
+			bytes.NewReader(testWAV(1, 1, 100, 8, data)),
+			bytes.NewReader(testWAV(1, 1, 200, 8, data)),
+		}, ErrSegmentMismatch},
+		// Channels mismatch
+		{[]io.Reader{
+			bytes.NewReader(testWAV(1, 1, 100, 8, data)),
+			bytes.NewReader(testWAV(1, 2, 100, 8, data)),
+		}, ErrSegmentMismatch},
+		// Invalid second stream
+		{[]io.Reader{
+			bytes.NewReader(testWAV(1, 1, 100, 8, data)),
+			bytes.NewReader([]byte("not audio")),
+		}, ErrFormat},
+	}
+
+	for i, test := range tests {
+		img, err := GenerateMulti(test.readers)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected GenerateMulti error: %v != %v", i, err, test.err)
+		}
+		if img != nil {
+			t.Fatalf("[%02d] unexpected non-nil image: %v", i, img.Bounds())
+		}
+	}
+}
+
+// TestGenerateMultiDrawMarkers verifies that GenerateMulti draws a line at the
+// boundary between streams, when DrawMarkers is set.
+func TestGenerateMultiDrawMarkers(t *testing.T) {
+	data := make([]byte, 150)
+	for i := range data {
+		data[i] = 128
+	}
+
+	img, err := GenerateMulti([]io.Reader{
+		bytes.NewReader(testWAV(1, 1, 100, 8, data)),
+		bytes.NewReader(testWAV(1, 1, 100, 8, data)),
+	}, DrawMarkers(red), Scale(4, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Second stream begins at 1.5 seconds
+	for x := 0; x < img.Bounds().Max.X; x++ {
+		want := color.RGBA(white)
+		if x == 6 {
+			want = red
+		}
+
+		if c := img.At(x, 0); c != want {
+			t.Fatalf("unexpected color at (%d,%d): %v != %v", x, 0, c, want)
+		}
+	}
+}
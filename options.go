@@ -1,6 +1,14 @@
 package waveform
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+
+	"golang.org/x/image/font"
+)
 
 var (
 	// errBGColorFunctionNil is returned when a nil ColorFunc is used in
@@ -24,6 +32,48 @@ var (
 		Reason: "function cannot be nil",
 	}
 
+	// errPeakColorFunctionNil is returned when a nil ColorFunc is used as the
+	// peak color in a call to DualEnvelope.
+	errPeakColorFunctionNil = &OptionsError{
+		Option: "dualEnvelope",
+		Reason: "peak color function cannot be nil",
+	}
+
+	// errRMSColorFunctionNil is returned when a nil ColorFunc is used as the
+	// RMS color in a call to DualEnvelope.
+	errRMSColorFunctionNil = &OptionsError{
+		Option: "dualEnvelope",
+		Reason: "RMS color function cannot be nil",
+	}
+
+	// errLeftColorFunctionNil is returned when a nil ColorFunc is used as the
+	// left color in a call to StereoSplit.
+	errLeftColorFunctionNil = &OptionsError{
+		Option: "stereoSplit",
+		Reason: "left color function cannot be nil",
+	}
+
+	// errRightColorFunctionNil is returned when a nil ColorFunc is used as the
+	// right color in a call to StereoSplit.
+	errRightColorFunctionNil = &OptionsError{
+		Option: "stereoSplit",
+		Reason: "right color function cannot be nil",
+	}
+
+	// errProgressTimeFunctionNil is returned when a nil ProgressTimeFunc is
+	// used in a call to Progress.
+	errProgressTimeFunctionNil = &OptionsError{
+		Option: "progress",
+		Reason: "function cannot be nil",
+	}
+
+	// errProgressFunctionNil is returned when a nil ProgressFunc is used in
+	// a call to OnProgress.
+	errProgressFunctionNil = &OptionsError{
+		Option: "onProgress",
+		Reason: "function cannot be nil",
+	}
+
 	// errResolutionZero is returned when integer 0 is used in a call
 	// to Resolution.
 	errResolutionZero = &OptionsError{
@@ -31,6 +81,181 @@ var (
 		Reason: "resolution cannot be 0",
 	}
 
+	// errResolutionRange is returned when a negative, infinite, or NaN
+	// value, or a value below the minimum resolution, is used in a call to
+	// Resolution.
+	errResolutionRange = &OptionsError{
+		Option: "resolution",
+		Reason: "resolution must be a finite number, of at least 0.01",
+	}
+
+	// errBarWidthZero is returned when integer 0 is used in a call
+	// to BarWidth.
+	errBarWidthZero = &OptionsError{
+		Option: "barWidth",
+		Reason: "bar width cannot be 0",
+	}
+
+	// errSilenceThresholdRange is returned when a value outside of [0, 1]
+	// is used in a call to SilenceThreshold.
+	errSilenceThresholdRange = &OptionsError{
+		Option: "silenceThreshold",
+		Reason: "threshold must be between 0 and 1",
+	}
+
+	// errNoiseFloorRange is returned when a value outside of [0, 1] is
+	// used in a call to NoiseFloor.
+	errNoiseFloorRange = &OptionsError{
+		Option: "noiseFloor",
+		Reason: "noise floor must be between 0 and 1",
+	}
+
+	// errTrimSilenceRange is returned when a value outside of [0, 1] is
+	// used in a call to TrimSilence.
+	errTrimSilenceRange = &OptionsError{
+		Option: "trimSilence",
+		Reason: "threshold must be between 0 and 1",
+	}
+
+	// errMaxSamplesZero is returned when a value less than 1 is used in a
+	// call to MaxSamples.
+	errMaxSamplesZero = &OptionsError{
+		Option: "maxSamples",
+		Reason: "maximum samples must be greater than 0",
+	}
+
+	// errHeightZero is returned when integer 0 is used in a call to Height.
+	errHeightZero = &OptionsError{
+		Option: "height",
+		Reason: "height cannot be 0",
+	}
+
+	// errHeightScaleY is returned when Height is used along with a Y scale
+	// other than 1.
+	errHeightScaleY = &OptionsError{
+		Option: "height",
+		Reason: "height cannot be used with Y scale",
+	}
+
+	// errScaleYHeight is returned when Scale is used with a Y value other
+	// than 1, along with Height.
+	errScaleYHeight = &OptionsError{
+		Option: "scale",
+		Reason: "Y scale cannot be used with height",
+	}
+
+	// errDPIRange is returned when a value less than or equal to 0 is used
+	// in a call to DPI.
+	errDPIRange = &OptionsError{
+		Option: "dpi",
+		Reason: "DPI must be greater than 0",
+	}
+
+	// errStyleUnknown is returned when an unknown DrawStyle is used in a
+	// call to Style.
+	errStyleUnknown = &OptionsError{
+		Option: "style",
+		Reason: "unknown draw style",
+	}
+
+	// errClipThresholdRange is returned when a value outside of [0, 1] is
+	// used in a call to ClipIndicator.
+	errClipThresholdRange = &OptionsError{
+		Option: "clipIndicator",
+		Reason: "threshold must be between 0 and 1",
+	}
+
+	// errOverlayValuesEmpty is returned when an empty slice of values is used
+	// in a call to Overlay.
+	errOverlayValuesEmpty = &OptionsError{
+		Option: "overlay",
+		Reason: "values cannot be empty",
+	}
+
+	// errResampleRateZero is returned when a value less than 1 is used in a
+	// call to Resample.
+	errResampleRateZero = &OptionsError{
+		Option: "resample",
+		Reason: "sample rate must be greater than 0",
+	}
+
+	// errMaxChannelsZero is returned when integer 0 is used in a call to
+	// MaxChannels.
+	errMaxChannelsZero = &OptionsError{
+		Option: "maxChannels",
+		Reason: "maximum channels cannot be 0",
+	}
+
+	// errRawPCMSampleRate is returned when a sample rate less than or equal
+	// to 0 is used in a call to RawPCM.
+	errRawPCMSampleRate = &OptionsError{
+		Option: "rawPCM",
+		Reason: "sample rate must be greater than 0",
+	}
+
+	// errRawPCMBits is returned when a bit depth other than 8, 16, 24, or 32
+	// is used in a call to RawPCM.
+	errRawPCMBits = &OptionsError{
+		Option: "rawPCM",
+		Reason: "bit depth must be 8, 16, 24, or 32",
+	}
+
+	// errRawPCMChannels is returned when a channel count less than or equal
+	// to 0 is used in a call to RawPCM.
+	errRawPCMChannels = &OptionsError{
+		Option: "rawPCM",
+		Reason: "channels must be greater than 0",
+	}
+
+	// errRawPCMByteOrderNil is returned when a nil byte order is used in a
+	// call to RawPCM.
+	errRawPCMByteOrderNil = &OptionsError{
+		Option: "rawPCM",
+		Reason: "byte order cannot be nil",
+	}
+
+	// errChannelNegative is returned when a negative integer is used in a
+	// call to Channel.
+	errChannelNegative = &OptionsError{
+		Option: "channel",
+		Reason: "channel index cannot be negative",
+	}
+
+	// errChannelModeUnknown is returned when an unknown ChannelMode is used in
+	// a call to Channels.
+	errChannelModeUnknown = &OptionsError{
+		Option: "channels",
+		Reason: "unknown channel mode",
+	}
+
+	// errTrackIndexNegative is returned when a negative integer is used in a
+	// call to TrackIndex.
+	errTrackIndexNegative = &OptionsError{
+		Option: "trackIndex",
+		Reason: "track index cannot be negative",
+	}
+
+	// errExecDecoderName is returned when an empty command name is used in a
+	// call to ExecDecoder.
+	errExecDecoderName = &OptionsError{
+		Option: "execDecoder",
+		Reason: "command name cannot be empty",
+	}
+
+	// errPaletteSize is returned when an empty palette, or a palette with
+	// more than 256 colors, is used in a call to Paletted.
+	errPaletteSize = &OptionsError{
+		Option: "paletted",
+		Reason: "palette must contain between 1 and 256 colors",
+	}
+
+	// errPaletteColorNil is returned when a nil color is used in a call to
+	// Paletted.
+	errPaletteColorNil = &OptionsError{
+		Option: "paletted",
+		Reason: "palette colors cannot be nil",
+	}
+
 	// errScaleXZero is returned when integer 0 is used as the X value
 	// in a call to Scale.
 	errScaleXZero = &OptionsError{
@@ -44,6 +269,145 @@ var (
 		Option: "scale",
 		Reason: "Y scale cannot be 0",
 	}
+
+	// errSweepFramesZero is returned when integer 0 is used as the number of
+	// frames in a call to Sweep.
+	errSweepFramesZero = &OptionsError{
+		Option: "sweep",
+		Reason: "frames cannot be 0",
+	}
+
+	// errSweepDelayRange is returned when a frame delay shorter than 10ms, the
+	// shortest delay of a GIF frame, is used in a call to Sweep.
+	errSweepDelayRange = &OptionsError{
+		Option: "sweep",
+		Reason: "frame delay must be at least 10ms",
+	}
+
+	// errStrokeWidthZero is returned when integer 0 is used in a call to
+	// StrokeWidth.
+	errStrokeWidthZero = &OptionsError{
+		Option: "strokeWidth",
+		Reason: "stroke width cannot be 0",
+	}
+
+	// errDotRadiusZero is returned when integer 0 is used in a call to Dots.
+	errDotRadiusZero = &OptionsError{
+		Option: "dots",
+		Reason: "dot radius cannot be 0",
+	}
+
+	// errQualityRange is returned when a value outside of [1, 100] is used
+	// in a call to Quality.
+	errQualityRange = &OptionsError{
+		Option: "quality",
+		Reason: "quality must be between 1 and 100",
+	}
+
+	// errFFTSizeRange is returned when a value which is not a power of two
+	// between 16 and 65536 is used in a call to FFTSize.
+	errFFTSizeRange = &OptionsError{
+		Option: "fftSize",
+		Reason: "FFT size must be a power of two between 16 and 65536",
+	}
+
+	// errColormapNil is returned when a nil Colormap is used in a call to
+	// SpectrogramColormap.
+	errColormapNil = &OptionsError{
+		Option: "spectrogramColormap",
+		Reason: "colormap cannot be nil",
+	}
+
+	// errRadialRotationRange is returned when a rotation outside of [0, 360)
+	// is used in a call to Radial.
+	errRadialRotationRange = &OptionsError{
+		Option: "radial",
+		Reason: "rotation must be at least 0 and less than 360 degrees",
+	}
+
+	// errDimensionsZero is returned when integer 0 is used as the width or
+	// height in a call to Dimensions.
+	errDimensionsZero = &OptionsError{
+		Option: "dimensions",
+		Reason: "width and height cannot be 0",
+	}
+
+	// errAntiAliasRange is returned when a value outside of [1, 8] is used in
+	// a call to AntiAlias.
+	errAntiAliasRange = &OptionsError{
+		Option: "antiAlias",
+		Reason: "factor must be between 1 and 8",
+	}
+
+	// errTimeAxisIntervalRange is returned when an interval of 0 or less is
+	// used in a call to TimeAxis.
+	errTimeAxisIntervalRange = &OptionsError{
+		Option: "timeAxis",
+		Reason: "interval must be greater than 0",
+	}
+
+	// errAxisFontNil is returned when a nil font.Face is used in a call to
+	// AxisFont.
+	errAxisFontNil = &OptionsError{
+		Option: "axisFont",
+		Reason: "font face cannot be nil",
+	}
+
+	// errDecibelGridStepRange is returned when a step of 0 or less is used in
+	// a call to DecibelGrid.
+	errDecibelGridStepRange = &OptionsError{
+		Option: "decibelGrid",
+		Reason: "step must be greater than 0",
+	}
+
+	// errMarkerColorNil is returned when a Marker with a nil color is used in
+	// a call to Markers.
+	errMarkerColorNil = &OptionsError{
+		Option: "markers",
+		Reason: "marker color cannot be nil",
+	}
+
+	// errLaneSeparatorZero is returned when a height of 0 is used in a call
+	// to LaneSeparator.
+	errLaneSeparatorZero = &OptionsError{
+		Option: "laneSeparator",
+		Reason: "height must be greater than 0",
+	}
+
+	// errTileWidthZero is returned when integer 0 is used in a call to
+	// TileWidth.
+	errTileWidthZero = &OptionsError{
+		Option: "tileWidth",
+		Reason: "tile width cannot be 0",
+	}
+
+	// errSliceSamplesZero is returned when integer 0 is used in a call to
+	// SliceSamples.
+	errSliceSamplesZero = &OptionsError{
+		Option: "sliceSamples",
+		Reason: "samples per slice cannot be 0",
+	}
+
+	// errSliceSamplesRange is returned when a value above the maximum number
+	// of samples per slice is used in a call to SliceSamples.
+	errSliceSamplesRange = &OptionsError{
+		Option: "sliceSamples",
+		Reason: fmt.Sprintf("samples per slice cannot exceed %d", sliceSamplesMax),
+	}
+
+	// errMaxWidthZero is returned when integer 0 is used in a call to
+	// MaxWidth.
+	errMaxWidthZero = &OptionsError{
+		Option: "maxWidth",
+		Reason: "maximum width cannot be 0",
+	}
+
+	// errWindowNegative is returned when a negative start or duration is
+	// used in a call to Window.
+	errWindowNegative = &OptionsError{
+		Option: "window",
+		Reason: "start and duration cannot be negative",
+	}
 )
 
 // OptionsError is an error which is returned when invalid input
@@ -145,37 +509,53 @@ func (w *Waveform) setFGColorFunction(function ColorFunc) error {
 // value to an input Waveform struct.
 //
 // This value indicates the number of times audio is read and drawn
-// as a waveform, per second of audio.
-func Resolution(resolution uint) OptionsFunc {
+// as a waveform, per second of audio.  Fractional resolutions are allowed,
+// such as 0.5 to draw one value for every two seconds of a very long
+// recording, down to 0.01, for one value every 100 seconds.  Each slice of
+// audio contains a whole number of samples, and at least one, so resolutions
+// above the sample rate draw one value per sample.  A slice never contains
+// more than 33554432 (2^25) samples across all channels, so a very low
+// resolution of audio with a high sample rate or many channels draws shorter
+// slices than requested.
+func Resolution(resolution float64) OptionsFunc {
 	return func(w *Waveform) error {
 		return w.setResolution(resolution)
 	}
 }
 
 // SetResolution applies the input resolution to the receiving Waveform struct.
-func (w *Waveform) SetResolution(resolution uint) error {
+func (w *Waveform) SetResolution(resolution float64) error {
 	return w.SetOptions(Resolution(resolution))
 }
 
 // setResolution directly sets the resolution member of the receiving Waveform
 // struct.
-func (w *Waveform) setResolution(resolution uint) error {
+func (w *Waveform) setResolution(resolution float64) error {
 	// Resolution cannot be zero
 	if resolution == 0 {
 		return errResolutionZero
 	}
 
+	// Resolution must be finite, and high enough that the number of samples
+	// in each slice cannot overflow or exhaust memory
+	if !(resolution >= resolutionMin) || math.IsInf(resolution, 1) {
+		return errResolutionRange
+	}
+
 	w.resolution = resolution
 
 	return nil
 }
 
-// SampleFunc generates an OptionsFunc which applies the input SampleReduceFunc
-// to an input Waveform struct.
+// SampleFunction generates an OptionsFunc which applies the input
+// SampleReduceFunc to an input Waveform struct.
 //
 // This function is used to compute values from audio samples, for use in
 // waveform generation.  The function is applied over a slice of float64
-// audio samples, reducing them to a single value.
+// audio samples, reducing them to a single value.  By default, RMSF64Samples
+// is used.  PeakF64Samples, MeanF64Samples, and MedianF64Samples compute the
+// peak, mean, and median magnitude of the samples, and any custom function
+// may be used to match values computed elsewhere, such as by a web player.
 func SampleFunction(function SampleReduceFunc) OptionsFunc {
 	return func(w *Waveform) error {
 		return w.setSampleFunction(function)
@@ -201,6 +581,226 @@ func (w *Waveform) setSampleFunction(function SampleReduceFunc) error {
 	return nil
 }
 
+// Resample generates an OptionsFunc which applies the input target sample rate
+// to an input Waveform struct.
+//
+// When set, decoded audio samples are resampled to the target sample rate before
+// values are computed, so Resolution produces the same density of values for
+// inputs with different sample rates.  Resampling uses linear interpolation,
+// which is fast, but does not filter high frequencies when downsampling.  This
+// has little effect on computed values, which measure magnitude over many
+// samples, but resampled audio is not suitable for playback.
+func Resample(targetRate int) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setResample(targetRate)
+	}
+}
+
+// SetResample applies the input target sample rate to the receiving Waveform
+// struct.
+func (w *Waveform) SetResample(targetRate int) error {
+	return w.SetOptions(Resample(targetRate))
+}
+
+// setResample directly sets the resampleRate member of the receiving Waveform
+// struct.
+func (w *Waveform) setResample(targetRate int) error {
+	// Sample rate must be positive
+	if targetRate < 1 {
+		return errResampleRateZero
+	}
+
+	w.resampleRate = targetRate
+
+	return nil
+}
+
+// MaxChannels generates an OptionsFunc which applies the input maximum number
+// of channels to an input Waveform struct.
+//
+// By default, audio streams with any number of channels are down-mixed to mono
+// by averaging the samples of each frame, so that each channel contributes
+// equally to the waveform.  When set, an input audio stream with more than n
+// channels is rejected before any samples are read, and an error matching
+// ErrUnsupportedChannels is returned.
+func MaxChannels(n uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setMaxChannels(n)
+	}
+}
+
+// SetMaxChannels applies the input maximum number of channels to the receiving
+// Waveform struct.
+func (w *Waveform) SetMaxChannels(n uint) error {
+	return w.SetOptions(MaxChannels(n))
+}
+
+// setMaxChannels directly sets the maxChannels member of the receiving
+// Waveform struct.
+func (w *Waveform) setMaxChannels(n uint) error {
+	// Maximum channels cannot be zero
+	if n == 0 {
+		return errMaxChannelsZero
+	}
+
+	w.maxChannels = n
+
+	return nil
+}
+
+// Channel generates an OptionsFunc which applies the input zero-based channel
+// index to an input Waveform struct.
+//
+// When set, only the samples of the selected channel are used to compute
+// values, as if the input audio stream were mono, and all other channels are
+// ignored, rather than being down-mixed.  If the input audio stream does not
+// contain the selected channel, ErrChannelRange is returned before any samples
+// are read.
+func Channel(index int) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setChannel(index)
+	}
+}
+
+// SetChannel applies the input zero-based channel index to the receiving
+// Waveform struct.
+func (w *Waveform) SetChannel(index int) error {
+	return w.SetOptions(Channel(index))
+}
+
+// setChannel directly sets the selectChannel and channel members of the
+// receiving Waveform struct.
+func (w *Waveform) setChannel(index int) error {
+	// Channel index cannot be negative
+	if index < 0 {
+		return errChannelNegative
+	}
+
+	w.selectChannel = true
+	w.channel = index
+	w.separate = false
+
+	return nil
+}
+
+// Channels generates an OptionsFunc which applies the input ChannelMode to an
+// input Waveform struct.
+//
+// This value indicates how values are computed from an audio stream with more
+// than one channel.  By default, all channels are down-mixed to mono.  The Left
+// and Right modes are equivalent to Channel(0) and Channel(1).
+//
+// In the Separate mode, Compute returns the down-mixed values, and the values
+// of each channel are also computed, and returned by ChannelValues.  Generate
+// draws the waveform of each channel separately, using DrawChannels.  The
+// SampleReduceFunc is called for each channel in turn, so a SampleReduceFunc
+// which retains state between calls, such as PeakHold, cannot be used.
+func Channels(mode ChannelMode) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setChannels(mode)
+	}
+}
+
+// SetChannels applies the input ChannelMode to the receiving Waveform struct.
+func (w *Waveform) SetChannels(mode ChannelMode) error {
+	return w.SetOptions(Channels(mode))
+}
+
+// setChannels directly sets the selectChannel, channel, and separate members
+// of the receiving Waveform struct.
+func (w *Waveform) setChannels(mode ChannelMode) error {
+	switch mode {
+	case Downmix:
+		w.selectChannel = false
+		w.separate = false
+	case Left:
+		return w.setChannel(0)
+	case Right:
+		return w.setChannel(1)
+	case Separate:
+		w.selectChannel = false
+		w.separate = true
+	default:
+		return errChannelModeUnknown
+	}
+
+	return nil
+}
+
+// TrackIndex generates an OptionsFunc which applies the input zero-based audio
+// track index to an input Waveform struct.
+//
+// When set, the selected audio track of a Matroska, WebM, or MP4 container is
+// decoded, rather than its first audio track.  Tracks of other types, such as
+// video and subtitles, are not counted.  If the container does not contain the
+// selected track, or the input audio stream is not a container and the index
+// is not 0, ErrTrackRange is returned before any samples are read.
+func TrackIndex(index int) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setTrackIndex(index)
+	}
+}
+
+// SetTrackIndex applies the input zero-based audio track index to the
+// receiving Waveform struct.
+func (w *Waveform) SetTrackIndex(index int) error {
+	return w.SetOptions(TrackIndex(index))
+}
+
+// setTrackIndex directly sets the trackIndex member of the receiving Waveform
+// struct.
+func (w *Waveform) setTrackIndex(index int) error {
+	// Track index cannot be negative
+	if index < 0 {
+		return errTrackIndexNegative
+	}
+
+	w.trackIndex = index
+
+	return nil
+}
+
+// ExecDecoder generates an OptionsFunc which applies the input external
+// decoder command to an input Waveform struct.
+//
+// When set, input audio streams of a format which cannot be decoded by the
+// current build are piped to the standard input of the command, which must
+// write a WAV stream to its standard output, such as:
+//
+//	ExecDecoder("ffmpeg", "-i", "pipe:0", "-f", "wav", "pipe:1")
+//
+// If no arguments are given, arguments for ffmpeg which convert the stream to
+// 32-bit floating point WAV samples are used.  WAV streams of an unknown size,
+// as written to a pipe, are read until the command exits.  If the command
+// fails, an *ExecError is returned, which includes its error output.
+func ExecDecoder(name string, args ...string) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setExecDecoder(name, args)
+	}
+}
+
+// SetExecDecoder applies the input external decoder command to the receiving
+// Waveform struct.
+func (w *Waveform) SetExecDecoder(name string, args ...string) error {
+	return w.SetOptions(ExecDecoder(name, args...))
+}
+
+// setExecDecoder directly sets the execCmd member of the receiving Waveform
+// struct.
+func (w *Waveform) setExecDecoder(name string, args []string) error {
+	// Command name cannot be empty
+	if name == "" {
+		return errExecDecoderName
+	}
+
+	w.execCmd = &execCommand{
+		name: name,
+		args: append([]string(nil), args...),
+	}
+
+	return nil
+}
+
 // Scale generates an OptionsFunc which applies the input X and Y axis scaling
 // factors to an input Waveform struct.
 //
@@ -232,12 +832,115 @@ func (w *Waveform) setScale(x uint, y uint) error {
 
 	}
 
+	// Y scale cannot be combined with an explicit height
+	if y != 1 && w.height > 0 {
+		return errScaleYHeight
+	}
+
 	w.scaleX = x
 	w.scaleY = y
 
 	return nil
 }
 
+// Height generates an OptionsFunc which applies the input height in pixels
+// to an input Waveform struct.
+//
+// This value sets the exact height of a generated waveform image, and computed
+// values are mapped into that height.  Height replaces the Y-axis factor of
+// Scale, so it cannot be used along with a Y scale other than 1.  The X-axis
+// factor of Scale is unaffected.
+func Height(px uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setHeight(px)
+	}
+}
+
+// SetHeight applies the input height to the receiving Waveform struct.
+func (w *Waveform) SetHeight(px uint) error {
+	return w.SetOptions(Height(px))
+}
+
+// setHeight directly sets the height member of the receiving Waveform struct.
+func (w *Waveform) setHeight(px uint) error {
+	// Height cannot be zero
+	if px == 0 {
+		return errHeightZero
+	}
+
+	// Height cannot be combined with Y scale
+	if w.scaleY > 1 {
+		return errHeightScaleY
+	}
+
+	w.height = px
+
+	return nil
+}
+
+// DPI generates an OptionsFunc which applies the input X and Y DPI values to
+// an input Waveform struct.
+//
+// These values indicate the physical resolution of the output image in dots
+// per inch, so that it is printed at the correct size.  DPI does not change the
+// dimensions of the image in pixels.  It is only written by GenerateTo and
+// DrawTo, in the pHYs chunk of a PNG image, as pixels per meter.
+func DPI(x float64, y float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDPI(x, y)
+	}
+}
+
+// SetDPI applies the input X and Y DPI values to the receiving Waveform struct.
+func (w *Waveform) SetDPI(x float64, y float64) error {
+	return w.SetOptions(DPI(x, y))
+}
+
+// setDPI directly sets the dpiX and dpiY members of the receiving Waveform
+// struct.
+func (w *Waveform) setDPI(x float64, y float64) error {
+	// DPI must be positive, and NaN is rejected by the same comparison
+	if !(x > 0) || !(y > 0) {
+		return errDPIRange
+	}
+
+	w.dpiX = x
+	w.dpiY = y
+
+	return nil
+}
+
+// Padding generates an OptionsFunc which applies the input padding values to
+// an input Waveform struct.
+//
+// These values indicate the margins in pixels which are added to the top, right,
+// bottom, and left of the output image, after any scaling has been applied.  The
+// image is enlarged by the margins, which are drawn using the background
+// ColorFunc, and the waveform is drawn within the inner rectangle.  All ColorFunc
+// are evaluated relative to the inner rectangle, so margins receive the
+// background color of the nearest pixel within it.  The default padding is 0.
+func Padding(top uint, right uint, bottom uint, left uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setPadding(top, right, bottom, left)
+	}
+}
+
+// SetPadding applies the input padding values to the receiving Waveform struct.
+func (w *Waveform) SetPadding(top uint, right uint, bottom uint, left uint) error {
+	return w.SetOptions(Padding(top, right, bottom, left))
+}
+
+// setPadding directly sets the padTop, padRight, padBottom, and padLeft members
+// of the receiving Waveform struct.
+func (w *Waveform) setPadding(top uint, right uint, bottom uint, left uint) error {
+	w.padTop = top
+	w.padRight = right
+	w.padBottom = bottom
+	w.padLeft = left
+
+	return nil
+}
+
 // ScaleClipping generates an OptionsFunc which sets the scaleClipping member
 // to true on an input Waveform struct.
 //
@@ -270,6 +973,13 @@ func (w *Waveform) setScaleClipping(scaleClipping bool) error {
 // This value indicates the amount of curvature which is applied to a
 // waveform image, scaled on its X-axis.  A higher value results in steeper
 // curves, and a lower value results in more "blocky" curves.
+//
+// Curvature is applied using a linear, triangular kernel across the width of
+// each bar: the pixel column at the center of a bar is drawn at full height,
+// and each pixel column at distance d from the center is shortened by
+// d*sharpness pixels at both its top and bottom.  A sharpness of 0 disables
+// curvature entirely, drawing each bar as a flat rectangle.  Curvature has no
+// effect on bars which are only 1 pixel wide, such as unscaled images.
 func Sharpness(sharpness uint) OptionsFunc {
 	return func(w *Waveform) error {
 		return w.setSharpness(sharpness)
@@ -288,3 +998,1516 @@ func (w *Waveform) setSharpness(sharpness uint) error {
 
 	return nil
 }
+
+// DualEnvelope generates an OptionsFunc which enables dual envelope drawing
+// on an input Waveform struct, using the input peak and RMS ColorFuncs.
+//
+// When enabled, both the peak and the root mean square of each slice of audio
+// samples are computed, in addition to the value computed by the SampleReduceFunc.
+// The peak extent of each slice is drawn using the peak ColorFunc, and the shorter
+// RMS extent is drawn on top of it using the RMS ColorFunc, producing a filled
+// RMS body with peak outlines.  The foreground ColorFunc is not used.
+//
+// Peak and RMS statistics are retained from the most recent call to Compute.  If
+// Draw is called with values of a different length, the input values are used
+// for both extents.
+func DualEnvelope(peakColor ColorFunc, rmsColor ColorFunc) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDualEnvelope(peakColor, rmsColor)
+	}
+}
+
+// SetDualEnvelope applies the input peak and RMS ColorFuncs to the receiving
+// Waveform struct, enabling dual envelope drawing.
+func (w *Waveform) SetDualEnvelope(peakColor ColorFunc, rmsColor ColorFunc) error {
+	return w.SetOptions(DualEnvelope(peakColor, rmsColor))
+}
+
+// setDualEnvelope directly sets the dualEnvelope, peakColorFn, and rmsColorFn
+// members of the receiving Waveform struct.
+func (w *Waveform) setDualEnvelope(peakColor ColorFunc, rmsColor ColorFunc) error {
+	// Peak function cannot be nil
+	if peakColor == nil {
+		return errPeakColorFunctionNil
+	}
+
+	// RMS function cannot be nil
+	if rmsColor == nil {
+		return errRMSColorFunctionNil
+	}
+
+	w.dualEnvelope = true
+	w.peakColorFn = peakColor
+	w.rmsColorFn = rmsColor
+
+	return nil
+}
+
+// InvertAmplitude generates an OptionsFunc which sets the invertAmplitude member
+// to true on an input Waveform struct.
+//
+// This value indicates if the amplitude of each value should be inverted when
+// drawn, so that silence is drawn tall and peaks are drawn short.  Each value is
+// clamped to [0, 1] and mapped to 1-v at draw time only; values returned by
+// Compute are not modified.  Inversion is always the last amplitude transform
+// applied before scaling.
+func InvertAmplitude() OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setInvertAmplitude(true)
+	}
+}
+
+// SetInvertAmplitude sets the invertAmplitude member true for the receiving
+// Waveform struct.
+func (w *Waveform) SetInvertAmplitude() error {
+	return w.SetOptions(InvertAmplitude())
+}
+
+// setInvertAmplitude directly sets the invertAmplitude member of the receiving
+// Waveform struct.
+func (w *Waveform) setInvertAmplitude(invertAmplitude bool) error {
+	w.invertAmplitude = invertAmplitude
+
+	return nil
+}
+
+// BarWidth generates an OptionsFunc which applies the input bar width
+// value to an input Waveform struct.
+//
+// This value indicates the width of the bar drawn for each computed value,
+// in multiples of the X scaling factor.  Used with BarGap, this draws each
+// computed value as a discrete bar followed by a gap.  The default width is 1.
+func BarWidth(width uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setBarWidth(width)
+	}
+}
+
+// SetBarWidth applies the input bar width to the receiving Waveform struct.
+func (w *Waveform) SetBarWidth(width uint) error {
+	return w.SetOptions(BarWidth(width))
+}
+
+// setBarWidth directly sets the barWidth member of the receiving Waveform
+// struct.
+func (w *Waveform) setBarWidth(width uint) error {
+	// Bar width cannot be zero
+	if width == 0 {
+		return errBarWidthZero
+	}
+
+	w.barWidth = width
+
+	return nil
+}
+
+// BarGap generates an OptionsFunc which applies the input bar gap value to
+// an input Waveform struct.
+//
+// This value indicates the width of the gap drawn using the background ColorFunc
+// after each bar, in multiples of the X scaling factor.  The width of the output
+// image is the number of computed values multiplied by the sum of the bar width
+// and gap, and by the X scaling factor.  The default gap is 0, which produces a
+// solid waveform.
+func BarGap(gap uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setBarGap(gap)
+	}
+}
+
+// SetBarGap applies the input bar gap to the receiving Waveform struct.
+func (w *Waveform) SetBarGap(gap uint) error {
+	return w.SetOptions(BarGap(gap))
+}
+
+// setBarGap directly sets the barGap member of the receiving Waveform struct.
+func (w *Waveform) setBarGap(gap uint) error {
+	w.barGap = gap
+
+	return nil
+}
+
+// DiscreteBars generates an OptionsFunc which applies the input bar width and
+// gap values to an input Waveform struct.
+//
+// This draws each computed value as a discrete bar followed by a gap, like
+// the waveforms of modern podcast players, and is equivalent to BarWidth and
+// BarGap.  Bars are square, unless rounded by BarRadius.  The Bars draw style
+// of the Style option is used by default, so it need not be set.
+func DiscreteBars(width uint, gap uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDiscreteBars(width, gap)
+	}
+}
+
+// SetDiscreteBars applies the input bar width and gap to the receiving Waveform
+// struct.
+func (w *Waveform) SetDiscreteBars(width uint, gap uint) error {
+	return w.SetOptions(DiscreteBars(width, gap))
+}
+
+// setDiscreteBars directly sets the barWidth and barGap members of the
+// receiving Waveform struct.
+func (w *Waveform) setDiscreteBars(width uint, gap uint) error {
+	if err := w.setBarWidth(width); err != nil {
+		return err
+	}
+
+	return w.setBarGap(gap)
+}
+
+// MinMaxEnvelope generates an OptionsFunc which sets the minMaxEnvelope member
+// to true on an input Waveform struct.
+//
+// This value indicates that the minimum and maximum samples of each slice of
+// audio should be drawn, rather than a single computed value mirrored about
+// the center of the image.  The upper extent is drawn to the maximum sample,
+// and the lower extent to the minimum sample, so signals with a DC offset or
+// asymmetrical excursions are drawn accurately.  Full scale samples reach the
+// edges of the image.  The foreground ColorFunc is used, unless DualEnvelope
+// is also set, in which case the min/max extent replaces the peak extent.
+//
+// Values returned by Compute are unchanged.  When Draw is used with values
+// which were not computed by the same Waveform, they are drawn as normal.
+func MinMaxEnvelope() OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setMinMaxEnvelope(true)
+	}
+}
+
+// SetMinMaxEnvelope sets the minMaxEnvelope member true for the receiving
+// Waveform struct.
+func (w *Waveform) SetMinMaxEnvelope() error {
+	return w.SetOptions(MinMaxEnvelope())
+}
+
+// setMinMaxEnvelope directly sets the minMaxEnvelope member of the receiving
+// Waveform struct.
+func (w *Waveform) setMinMaxEnvelope(minMaxEnvelope bool) error {
+	w.minMaxEnvelope = minMaxEnvelope
+
+	return nil
+}
+
+// Mirrored generates an OptionsFunc which sets the mirrored value to true on
+// an input Waveform struct.
+//
+// Computed values are always drawn symmetrically about the center of the
+// image.  This value indicates that the MinMaxEnvelope is also drawn
+// symmetrically, with both extents reaching the larger magnitude of the
+// minimum and maximum samples, in the style of SoundCloud waveforms, rather
+// than drawing each extent independently.  It has no effect otherwise.
+func Mirrored() OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setMirrored(true)
+	}
+}
+
+// SetMirrored sets the mirrored member true for the receiving Waveform struct.
+func (w *Waveform) SetMirrored() error {
+	return w.SetOptions(Mirrored())
+}
+
+// setMirrored directly sets the mirrored member of the receiving Waveform
+// struct.
+func (w *Waveform) setMirrored(mirrored bool) error {
+	w.mirrored = mirrored
+
+	return nil
+}
+
+// ClipIndicator generates an OptionsFunc which applies the input clip indicator
+// color and threshold to an input Waveform struct.
+//
+// When set, any slice of audio samples whose peak magnitude exceeds threshold is
+// drawn entirely in color c, in place of the foreground ColorFunc and any other
+// drawing mode colors.  This can be used to highlight columns which reach
+// 0 dBFS.  A threshold of 0 uses the default threshold of 0.99.  When Draw is
+// used with values which were not computed by the same Waveform, each computed
+// value is compared against threshold instead.
+func ClipIndicator(c color.RGBA, threshold float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setClipIndicator(c, threshold)
+	}
+}
+
+// SetClipIndicator applies the input clip indicator color and threshold to the
+// receiving Waveform struct.
+func (w *Waveform) SetClipIndicator(c color.RGBA, threshold float64) error {
+	return w.SetOptions(ClipIndicator(c, threshold))
+}
+
+// setClipIndicator directly sets the clipColorFn and clipThreshold members of
+// the receiving Waveform struct.
+func (w *Waveform) setClipIndicator(c color.RGBA, threshold float64) error {
+	// Threshold must be within range of normalized values
+	if threshold < 0 || threshold > 1 {
+		return errClipThresholdRange
+	}
+
+	// Use default threshold just below full scale
+	if threshold == 0 {
+		threshold = clipThresholdDefault
+	}
+
+	w.clipColorFn = SolidColor(c)
+	w.clipThreshold = threshold
+
+	return nil
+}
+
+// Overlay generates an OptionsFunc which applies the input overlay values and
+// color to an input Waveform struct.
+//
+// When set, a second waveform is drawn from values, such as those returned by
+// an earlier call to Compute, in front of the primary waveform.  The overlay is
+// composited using the alpha of color c, so that a semi-transparent color shows
+// the primary waveform beneath it.  This can be used to compare an original
+// and a processed version of the same audio.  The same amplitude transforms
+// are applied to both waveforms.  If the number of overlay values differs from
+// the number of values drawn, the overlay values are linearly interpolated to
+// match.  The input slice must not be modified while the option is in use.
+func Overlay(values []float64, c color.RGBA) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setOverlay(values, c)
+	}
+}
+
+// SetOverlay applies the input overlay values and color to the receiving
+// Waveform struct.
+func (w *Waveform) SetOverlay(values []float64, c color.RGBA) error {
+	return w.SetOptions(Overlay(values, c))
+}
+
+// setOverlay directly sets the overlayValues and overlayColor members of the
+// receiving Waveform struct.
+func (w *Waveform) setOverlay(values []float64, c color.RGBA) error {
+	// Overlay values cannot be empty
+	if len(values) == 0 {
+		return errOverlayValuesEmpty
+	}
+
+	w.overlayValues = values
+	w.overlayColor = c
+
+	return nil
+}
+
+// Style generates an OptionsFunc which applies the input DrawStyle to an input
+// Waveform struct.
+//
+// This value indicates how computed values are drawn: as separate bars, which
+// is the default, as a continuous, filled area connecting adjacent values, or
+// as only the outlines of that area.
+// All drawing modes, such as DualEnvelope, MinMaxEnvelope, and Overlay, are
+// drawn using the same style.
+func Style(style DrawStyle) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setStyle(style)
+	}
+}
+
+// SetStyle applies the input DrawStyle to the receiving Waveform struct.
+func (w *Waveform) SetStyle(style DrawStyle) error {
+	return w.SetOptions(Style(style))
+}
+
+// setStyle directly sets the style member of the receiving Waveform struct.
+func (w *Waveform) setStyle(style DrawStyle) error {
+	// Style must be known
+	if !style.valid() {
+		return errStyleUnknown
+	}
+
+	w.style = style
+
+	return nil
+}
+
+// DrawMarkers generates an OptionsFunc which applies the input marker color to
+// an input Waveform struct.
+//
+// When set, a vertical line is drawn in color c, in front of the waveform, at
+// the timestamp of each marker reported by Metadata, such as the chapters of a
+// FLAC stream, or the boundaries between streams read by GenerateMulti.  If
+// the stream contains no markers, nothing is drawn.  Markers are only drawn
+// using the values returned by the last call to Compute.
+func DrawMarkers(c color.RGBA) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDrawMarkers(c)
+	}
+}
+
+// SetDrawMarkers applies the input marker color to the receiving Waveform
+// struct.
+func (w *Waveform) SetDrawMarkers(c color.RGBA) error {
+	return w.SetOptions(DrawMarkers(c))
+}
+
+// setDrawMarkers directly sets the markerColorFn member of the receiving
+// Waveform struct.
+func (w *Waveform) setDrawMarkers(c color.RGBA) error {
+	w.markerColorFn = SolidColor(c)
+
+	return nil
+}
+
+// BarRadius generates an OptionsFunc which applies the input bar radius value
+// to an input Waveform struct.
+//
+// This value indicates the radius in pixels of the output image, after any
+// scaling has been applied, used to round the outer corners of each bar: both
+// the top and bottom corners, as the waveform is symmetrical.  Corners are
+// rasterized as stepped arcs, using the foreground ColorFunc.  The radius is
+// clamped to half the width of a bar, so bars which are too narrow receive
+// the largest radius which fits, and any radius of at least half the width
+// draws fully rounded caps.  The default radius is 0, which draws square
+// corners.  BarRadius is typically used with Sharpness(0), and with AntiAlias
+// to draw smooth arcs.
+func BarRadius(radius uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setBarRadius(radius)
+	}
+}
+
+// SetBarRadius applies the input bar radius to the receiving Waveform struct.
+func (w *Waveform) SetBarRadius(radius uint) error {
+	return w.SetOptions(BarRadius(radius))
+}
+
+// setBarRadius directly sets the barRadius member of the receiving Waveform
+// struct.
+func (w *Waveform) setBarRadius(radius uint) error {
+	w.barRadius = radius
+
+	return nil
+}
+
+// SilenceThreshold generates an OptionsFunc which applies the input silence
+// threshold value to an input Waveform struct.
+//
+// This value indicates the level below which a computed value is considered
+// silence, and is removed from the waveform image entirely.  The threshold is
+// applied at draw time, before NoiseFloor, and is compared against the
+// computed value.  Values returned by Compute are not modified.
+func SilenceThreshold(threshold float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setSilenceThreshold(threshold)
+	}
+}
+
+// SetSilenceThreshold applies the input silence threshold to the receiving
+// Waveform struct.
+func (w *Waveform) SetSilenceThreshold(threshold float64) error {
+	return w.SetOptions(SilenceThreshold(threshold))
+}
+
+// setSilenceThreshold directly sets the silenceThreshold member of the receiving
+// Waveform struct.
+func (w *Waveform) setSilenceThreshold(threshold float64) error {
+	// Threshold must be within range of normalized values
+	if threshold < 0 || threshold > 1 {
+		return errSilenceThresholdRange
+	}
+
+	w.silenceThreshold = threshold
+
+	return nil
+}
+
+// TrimSilence generates an OptionsFunc which applies the input silence trimming
+// threshold to an input Waveform struct.
+//
+// When set, all leading and trailing computed values below threshold are
+// removed once all values are computed, so that only the active region of the
+// audio fills the width of the waveform image.  Unlike SilenceThreshold, the
+// values returned by Compute are trimmed.  The offsets of the remaining audio
+// are reported by Metadata, so that the image can be mapped back to timestamps
+// in the original stream.  If all values are below threshold, no values are
+// removed.  The default threshold is 0, which disables trimming.
+func TrimSilence(threshold float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setTrimSilence(threshold)
+	}
+}
+
+// SetTrimSilence applies the input silence trimming threshold to the receiving
+// Waveform struct.
+func (w *Waveform) SetTrimSilence(threshold float64) error {
+	return w.SetOptions(TrimSilence(threshold))
+}
+
+// setTrimSilence directly sets the trimThreshold member of the receiving
+// Waveform struct.
+func (w *Waveform) setTrimSilence(threshold float64) error {
+	// Threshold must be within range of normalized values
+	if threshold < 0 || threshold > 1 {
+		return errTrimSilenceRange
+	}
+
+	w.trimThreshold = threshold
+
+	return nil
+}
+
+// NoiseFloor generates an OptionsFunc which applies the input noise floor
+// value to an input Waveform struct.
+//
+// This value is subtracted from each computed value at draw time, and the
+// result is clamped to [0, 1].  Unlike SilenceThreshold, which removes quiet
+// values entirely, this acts as a soft gate: low-level noise flattens to the
+// baseline, while louder content is lowered by the same amount and remains
+// visible.  When both are used, SilenceThreshold is applied first.  Values
+// returned by Compute are not modified.
+func NoiseFloor(floor float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setNoiseFloor(floor)
+	}
+}
+
+// SetNoiseFloor applies the input noise floor to the receiving Waveform struct.
+func (w *Waveform) SetNoiseFloor(floor float64) error {
+	return w.SetOptions(NoiseFloor(floor))
+}
+
+// setNoiseFloor directly sets the noiseFloor member of the receiving Waveform
+// struct.
+func (w *Waveform) setNoiseFloor(floor float64) error {
+	// Floor must be within range of normalized values
+	if floor < 0 || floor > 1 {
+		return errNoiseFloorRange
+	}
+
+	w.noiseFloor = floor
+
+	return nil
+}
+
+// PartialOnError generates an OptionsFunc which sets the partialOnError member
+// to true on an input Waveform struct.
+//
+// This value indicates if values computed before a decoding error, such as
+// ErrUnexpectedEOS from a truncated stream, should be kept.  When set, Generate
+// returns an image drawn from all audio decoded before the error, along with
+// the error, so callers may choose to use it.  By default, a nil image is
+// returned on any error.
+func PartialOnError() OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setPartialOnError(true)
+	}
+}
+
+// SetPartialOnError sets the partialOnError member true for the receiving
+// Waveform struct.
+func (w *Waveform) SetPartialOnError() error {
+	return w.SetOptions(PartialOnError())
+}
+
+// setPartialOnError directly sets the partialOnError member of the receiving
+// Waveform struct.
+func (w *Waveform) setPartialOnError(partialOnError bool) error {
+	w.partialOnError = partialOnError
+
+	return nil
+}
+
+// FastThumbnail generates an OptionsFunc which enables approximate decoding
+// for an input Waveform struct.
+//
+// When set, a seekable input stream which contains integer PCM or floating
+// point WAV audio is not decoded in full.  Instead, each slice of audio is
+// divided into 8 strides, and only a short window at the beginning of each
+// stride is decoded.  The stream is seeked past the remaining frames of the
+// stride, which are approximated by repeating the window.  Only 1/8 of the input is read, so small preview images
+// of large files are generated much faster.
+//
+// The resulting values are approximate: transients between windows are missed,
+// so the peaks of the waveform may be lower than the true peaks.  FastThumbnail
+// should not be used for metering or quality assurance.  All other streams are
+// decoded in full, as if the option were not set.
+func FastThumbnail() OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setFastThumbnail(true)
+	}
+}
+
+// SetFastThumbnail sets the fastThumbnail member true for the receiving
+// Waveform struct.
+func (w *Waveform) SetFastThumbnail() error {
+	return w.SetOptions(FastThumbnail())
+}
+
+// setFastThumbnail directly sets the fastThumbnail member of the receiving
+// Waveform struct.
+func (w *Waveform) setFastThumbnail(fastThumbnail bool) error {
+	w.fastThumbnail = fastThumbnail
+
+	return nil
+}
+
+// OnProgress generates an OptionsFunc which applies the input ProgressFunc
+// to an input Waveform struct.
+//
+// This function is called each time a value is computed from the input audio
+// stream, with the fraction of the stream consumed so far, in the range [0, 1].
+// When the stream's total length is unknown, such as when it is not an
+// io.Seeker, -1 is passed instead.  Progress is measured by bytes consumed,
+// so it is approximate, and decoders which buffer an entire stream, such as
+// the MP4 decoder, report all progress at once.  When computation completes
+// successfully, 1 is always passed.
+//
+// The function is called synchronously, and is never called concurrently.
+func OnProgress(function ProgressFunc) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setProgressFunction(function)
+	}
+}
+
+// SetOnProgress applies the input ProgressFunc to the receiving Waveform
+// struct.
+func (w *Waveform) SetOnProgress(function ProgressFunc) error {
+	return w.SetOptions(OnProgress(function))
+}
+
+// setProgressFunction directly sets the progressFn member of the receiving
+// Waveform struct.
+func (w *Waveform) setProgressFunction(function ProgressFunc) error {
+	// Function cannot be nil
+	if function == nil {
+		return errProgressFunctionNil
+	}
+
+	w.progressFn = function
+
+	return nil
+}
+
+// Progress generates an OptionsFunc which applies the input ProgressTimeFunc
+// to an input Waveform struct.
+//
+// This function is called each time a value is computed from the input audio
+// stream, which is once per second of audio at the default resolution, with
+// the number of seconds of audio decoded so far, and the estimated total
+// number of seconds of audio in the stream, so that progress through very long
+// streams can be reported in familiar units.  The total is estimated in the
+// same way as the fraction passed to the ProgressFunc set by OnProgress, and
+// is -1 when the stream's total length is unknown.  When computation completes
+// successfully, the total is always equal to the number of seconds decoded.
+//
+// The function is called synchronously, and is never called concurrently.
+// Progress and OnProgress may be used together.
+func Progress(function ProgressTimeFunc) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setProgressTimeFunction(function)
+	}
+}
+
+// SetProgress applies the input ProgressTimeFunc to the receiving Waveform
+// struct.
+func (w *Waveform) SetProgress(function ProgressTimeFunc) error {
+	return w.SetOptions(Progress(function))
+}
+
+// setProgressTimeFunction directly sets the progressTimeFn member of the
+// receiving Waveform struct.
+func (w *Waveform) setProgressTimeFunction(function ProgressTimeFunc) error {
+	// Function cannot be nil
+	if function == nil {
+		return errProgressTimeFunctionNil
+	}
+
+	w.progressTimeFn = function
+
+	return nil
+}
+
+// MaxSamples generates an OptionsFunc which sets the maximum number of audio
+// samples decoded from an input stream, for an input Waveform struct.
+//
+// Samples are counted across all channels.  Once the limit is reached, decoding
+// stops immediately, and ErrLimitExceeded is returned.  If truncate is true,
+// values computed from the samples within the limit are returned instead, and
+// no error occurs.  This option should be used to bound the memory and time
+// spent on untrusted input.  By default, there is no limit.
+func MaxSamples(n int64, truncate bool) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setMaxSamples(n, truncate)
+	}
+}
+
+// SetMaxSamples sets the maxSamples and truncateAtLimit members of the
+// receiving Waveform struct.
+func (w *Waveform) SetMaxSamples(n int64, truncate bool) error {
+	return w.SetOptions(MaxSamples(n, truncate))
+}
+
+// setMaxSamples directly sets the maxSamples and truncateAtLimit members of
+// the receiving Waveform struct.
+func (w *Waveform) setMaxSamples(n int64, truncate bool) error {
+	// Limit must be positive
+	if n < 1 {
+		return errMaxSamplesZero
+	}
+
+	w.maxSamples = n
+	w.truncateAtLimit = truncate
+
+	return nil
+}
+
+// GammaCorrect generates an OptionsFunc which applies the input gamma
+// correction setting to an input Waveform struct.
+//
+// If enabled is true, colors are blended in linear light, and converted back
+// to sRGB, rather than being blended directly in sRGB space.  This affects
+// the gradients produced by GradientColor, and the compositing of the Overlay
+// option, so that blended midtones appear as bright as expected.  All other
+// colors are unchanged.  By default, colors are blended in sRGB space.
+func GammaCorrect(enabled bool) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setGammaCorrect(enabled)
+	}
+}
+
+// SetGammaCorrect sets the gammaCorrect member of the receiving Waveform
+// struct.
+func (w *Waveform) SetGammaCorrect(enabled bool) error {
+	return w.SetOptions(GammaCorrect(enabled))
+}
+
+// setGammaCorrect directly sets the gammaCorrect member of the receiving
+// Waveform struct.
+func (w *Waveform) setGammaCorrect(enabled bool) error {
+	w.gammaCorrect = enabled
+
+	return nil
+}
+
+// Paletted generates an OptionsFunc which applies the input palette of colors
+// to an input Waveform struct.
+//
+// When set, waveform images are drawn as an *image.Paletted, and each color
+// produced by a ColorFunc is replaced by the nearest color in the palette.
+// Waveform images use very few distinct colors, so a palette containing the
+// foreground, background, and any other colors in use produces much smaller
+// PNG and GIF images.  The palette must contain between 1 and 256 colors.
+func Paletted(colors []color.Color) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setPaletted(colors)
+	}
+}
+
+// SetPaletted applies the input palette of colors to the receiving Waveform
+// struct.
+func (w *Waveform) SetPaletted(colors []color.Color) error {
+	return w.SetOptions(Paletted(colors))
+}
+
+// setPaletted directly sets the palette member of the receiving Waveform
+// struct.
+func (w *Waveform) setPaletted(colors []color.Color) error {
+	// Palette must be encodable as an indexed image
+	if len(colors) == 0 || len(colors) > 256 {
+		return errPaletteSize
+	}
+	for _, c := range colors {
+		if c == nil {
+			return errPaletteColorNil
+		}
+	}
+
+	// Copy the palette, so that it cannot be modified by the caller
+	w.palette = append(color.Palette(nil), colors...)
+
+	return nil
+}
+
+// RawPCM generates an OptionsFunc which applies the input raw PCM sample
+// format to an input Waveform struct.
+//
+// When set, format detection is skipped, and the entire input audio stream is
+// treated as raw, interleaved, signed integer PCM samples with the input
+// sample rate, bit depth, channels, and byte order, such as binary.LittleEndian.
+// The bit depth must be 8, 16, 24, or 32.  A trailing, incomplete sample at
+// the end of the stream is discarded.
+func RawPCM(sampleRate int, bits uint, channels int, order binary.ByteOrder) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setRawPCM(sampleRate, bits, channels, order)
+	}
+}
+
+// SetRawPCM applies the input raw PCM sample format to the receiving Waveform
+// struct.
+func (w *Waveform) SetRawPCM(sampleRate int, bits uint, channels int, order binary.ByteOrder) error {
+	return w.SetOptions(RawPCM(sampleRate, bits, channels, order))
+}
+
+// setRawPCM directly sets the rawPCM member of the receiving Waveform struct.
+func (w *Waveform) setRawPCM(sampleRate int, bits uint, channels int, order binary.ByteOrder) error {
+	if sampleRate <= 0 {
+		return errRawPCMSampleRate
+	}
+	switch bits {
+	case 8, 16, 24, 32:
+	default:
+		return errRawPCMBits
+	}
+	if channels <= 0 {
+		return errRawPCMChannels
+	}
+	if order == nil {
+		return errRawPCMByteOrderNil
+	}
+
+	w.rawPCM = &rawPCMFormat{
+		sampleRate: sampleRate,
+		bits:       bits,
+		channels:   channels,
+		order:      order,
+	}
+
+	return nil
+}
+
+// Sweep generates an OptionsFunc which applies the input number of frames and
+// frame delay to an input Waveform struct.
+//
+// These values indicate the number of frames of an animated GIF created by
+// DrawSweep, and the time for which each frame is displayed.  The delay is
+// rounded down to a multiple of 10ms, the precision of a GIF frame delay.  The
+// default is 20 frames, displayed for 100ms each.
+func Sweep(frames uint, delay time.Duration) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setSweep(frames, delay)
+	}
+}
+
+// SetSweep applies the input number of frames and frame delay to the
+// receiving Waveform struct.
+func (w *Waveform) SetSweep(frames uint, delay time.Duration) error {
+	return w.SetOptions(Sweep(frames, delay))
+}
+
+// setSweep directly sets the sweepFrames and sweepDelay members of the
+// receiving Waveform struct.
+func (w *Waveform) setSweep(frames uint, delay time.Duration) error {
+	if frames == 0 {
+		return errSweepFramesZero
+	}
+	if delay < 10*time.Millisecond {
+		return errSweepDelayRange
+	}
+
+	w.sweepFrames = frames
+	w.sweepDelay = delay
+
+	return nil
+}
+
+// SweepPlayhead generates an OptionsFunc which applies the input playhead
+// color to an input Waveform struct.
+//
+// When set, each frame of an animated GIF created by DrawSweep contains the
+// entire waveform, and a vertical playhead line is drawn in color c, in front
+// of the waveform, moving from left to right.  Otherwise, the waveform fills
+// in from left to right.
+func SweepPlayhead(c color.RGBA) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setSweepPlayhead(c)
+	}
+}
+
+// SetSweepPlayhead applies the input playhead color to the receiving Waveform
+// struct.
+func (w *Waveform) SetSweepPlayhead(c color.RGBA) error {
+	return w.SetOptions(SweepPlayhead(c))
+}
+
+// setSweepPlayhead directly sets the playheadColorFn member of the receiving
+// Waveform struct.
+func (w *Waveform) setSweepPlayhead(c color.RGBA) error {
+	w.playheadColorFn = SolidColor(c)
+
+	return nil
+}
+
+// Quality generates an OptionsFunc which applies the input image quality to an
+// input Waveform struct.
+//
+// This value indicates the quality, from 1 to 100, used by GenerateTo and
+// DrawTo to encode images in lossy formats, such as JPEG.  Lower values
+// produce smaller images, with more visible artifacts.  The default quality
+// is 75.  Lossless formats, such as PNG, ignore this value.
+func Quality(quality uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setQuality(quality)
+	}
+}
+
+// SetQuality applies the input image quality to the receiving Waveform struct.
+func (w *Waveform) SetQuality(quality uint) error {
+	return w.SetOptions(Quality(quality))
+}
+
+// setQuality directly sets the quality member of the receiving Waveform
+// struct.
+func (w *Waveform) setQuality(quality uint) error {
+	if quality < 1 || quality > 100 {
+		return errQualityRange
+	}
+
+	w.quality = int(quality)
+
+	return nil
+}
+
+// StrokeWidth generates an OptionsFunc which applies the input stroke width to
+// an input Waveform struct.
+//
+// This value indicates the width in pixels of the outlines drawn by the Line
+// style, after any scaling has been applied.  The default width is 1.
+func StrokeWidth(px uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setStrokeWidth(px)
+	}
+}
+
+// SetStrokeWidth applies the input stroke width to the receiving Waveform
+// struct.
+func (w *Waveform) SetStrokeWidth(px uint) error {
+	return w.SetOptions(StrokeWidth(px))
+}
+
+// setStrokeWidth directly sets the strokeWidth member of the receiving
+// Waveform struct.
+func (w *Waveform) setStrokeWidth(px uint) error {
+	if px == 0 {
+		return errStrokeWidthZero
+	}
+
+	w.strokeWidth = px
+
+	return nil
+}
+
+// Dots generates an OptionsFunc which applies the dot style, with the input
+// dot radius, to an input Waveform struct.
+//
+// When set, each computed value is drawn as a single dot, centered on its bar,
+// at the height reached by the top of the bar.  The radius is in pixels of the
+// output image, after any scaling has been applied, and each dot is clipped to
+// the width of its bar and the gap which follows it, so BarGap may be used to
+// make room for wide dots.  DualEnvelope and MinMaxEnvelope are not drawn.
+// Setting another style with the Style option replaces the dot style.
+func Dots(radius uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDots(radius)
+	}
+}
+
+// SetDots applies the dot style, with the input dot radius, to the receiving
+// Waveform struct.
+func (w *Waveform) SetDots(radius uint) error {
+	return w.SetOptions(Dots(radius))
+}
+
+// setDots directly sets the style and dotRadius members of the receiving
+// Waveform struct.
+func (w *Waveform) setDots(radius uint) error {
+	if radius == 0 {
+		return errDotRadiusZero
+	}
+
+	w.style = dotStyle
+	w.dotRadius = radius
+
+	return nil
+}
+
+// FFTSize generates an OptionsFunc which applies the input FFT window size to
+// an input Waveform struct.
+//
+// This value indicates the number of samples in each window used to compute
+// the frequency spectrum of a slice of audio, for ComputeSpectrogram.  Larger
+// windows resolve more frequencies, at the cost of time resolution within
+// each slice.  The size must be a power of two between 16 and 65536, and the
+// default size is 1024.
+func FFTSize(size uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setFFTSize(size)
+	}
+}
+
+// SetFFTSize applies the input FFT window size to the receiving Waveform
+// struct.
+func (w *Waveform) SetFFTSize(size uint) error {
+	return w.SetOptions(FFTSize(size))
+}
+
+// setFFTSize directly sets the fftSize member of the receiving Waveform
+// struct.
+func (w *Waveform) setFFTSize(size uint) error {
+	if size < fftSizeMin || size > fftSizeMax || size&(size-1) != 0 {
+		return errFFTSizeRange
+	}
+
+	w.fftSize = size
+
+	return nil
+}
+
+// SpectrogramColormap generates an OptionsFunc which applies the input
+// Colormap to an input Waveform struct.
+//
+// This function is used by DrawSpectrogram to color each frequency by its
+// magnitude.  The default Colormap is HeatColormap.
+func SpectrogramColormap(colormap Colormap) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setSpectrogramColormap(colormap)
+	}
+}
+
+// SetSpectrogramColormap applies the input Colormap to the receiving Waveform
+// struct.
+func (w *Waveform) SetSpectrogramColormap(colormap Colormap) error {
+	return w.SetOptions(SpectrogramColormap(colormap))
+}
+
+// setSpectrogramColormap directly sets the colormap member of the receiving
+// Waveform struct.
+func (w *Waveform) setSpectrogramColormap(colormap Colormap) error {
+	if colormap == nil {
+		return errColormapNil
+	}
+
+	w.colormap = colormap
+
+	return nil
+}
+
+// Radial generates an OptionsFunc which applies the input inner radius and
+// rotation to an input Waveform struct.
+//
+// These values indicate the radius in pixels of the inner circle of the ring
+// drawn by DrawRadial, and the angle in degrees, clockwise from the top of the
+// circle, at which the waveform begins.  The rotation must be at least 0 and
+// less than 360.  By default, the inner radius is 0, and the waveform begins
+// at the top of the circle.
+func Radial(innerRadius uint, rotation float64) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setRadial(innerRadius, rotation)
+	}
+}
+
+// SetRadial applies the input inner radius and rotation to the receiving
+// Waveform struct.
+func (w *Waveform) SetRadial(innerRadius uint, rotation float64) error {
+	return w.SetOptions(Radial(innerRadius, rotation))
+}
+
+// setRadial directly sets the radialInner and radialRotation members of the
+// receiving Waveform struct.
+func (w *Waveform) setRadial(innerRadius uint, rotation float64) error {
+	// Also rejects NaN
+	if !(rotation >= 0 && rotation < 360) {
+		return errRadialRotationRange
+	}
+
+	w.radialInner = innerRadius
+	w.radialRotation = rotation
+
+	return nil
+}
+
+// TransparentBackground generates an OptionsFunc which applies a fully
+// transparent background to an input Waveform struct.
+//
+// When set, the background ColorFunc is replaced by a transparent color, and
+// waveform images are drawn as an *image.NRGBA, which stores colors without
+// premultiplied alpha, so that semi-transparent foreground colors keep their
+// full precision when composited over other artwork.  Background colors with
+// alpha, such as those parsed by ParseHexColor from #RRGGBBAA strings, may be
+// used without this option, but are drawn as an *image.RGBA.  The Paletted
+// option takes priority over the image type.
+func TransparentBackground() OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setTransparentBackground()
+	}
+}
+
+// SetTransparentBackground applies a fully transparent background to the
+// receiving Waveform struct.
+func (w *Waveform) SetTransparentBackground() error {
+	return w.SetOptions(TransparentBackground())
+}
+
+// setTransparentBackground directly sets the bgColorFn and nrgba members of
+// the receiving Waveform struct.
+func (w *Waveform) setTransparentBackground() error {
+	w.bgColorFn = SolidColor(color.NRGBA{})
+	w.nrgba = true
+
+	return nil
+}
+
+// Dimensions generates an OptionsFunc which applies the input width and height
+// in pixels to an input Waveform struct.
+//
+// These values set the exact dimensions of a generated waveform image,
+// regardless of the resolution and duration of the audio.  The computed
+// values are resampled to the number of bars which fill the width, taking the
+// maximum of the values covered by each bar when there are more values than
+// bars, and interpolating between values otherwise.  If the bars do not fill
+// the width exactly, the last bar is clipped.  The height is applied as by
+// Height, and padding is added around the waveform.
+//
+// Values returned by Compute are unchanged, and any markers are drawn at their
+// times within the resampled bars.  Dimensions cannot be used with Stream.
+func Dimensions(width uint, height uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDimensions(width, height)
+	}
+}
+
+// SetDimensions applies the input width and height to the receiving Waveform
+// struct.
+func (w *Waveform) SetDimensions(width uint, height uint) error {
+	return w.SetOptions(Dimensions(width, height))
+}
+
+// setDimensions directly sets the width and height members of the receiving
+// Waveform struct.
+func (w *Waveform) setDimensions(width uint, height uint) error {
+	if width == 0 || height == 0 {
+		return errDimensionsZero
+	}
+
+	// Height cannot be combined with Y scale
+	if err := w.setHeight(height); err != nil {
+		return err
+	}
+
+	w.width = width
+
+	return nil
+}
+
+// AntiAlias generates an OptionsFunc which applies the input supersampling
+// factor to an input Waveform struct.
+//
+// When set to a factor greater than 1, waveform images are drawn at that
+// multiple of their size on both axes, and reduced by averaging each block of
+// pixels, so that the edges of the waveform are smooth at any scale, rather
+// than blocky.  The dimensions of the image are unchanged, as are all options
+// measured in pixels, such as BarRadius and StrokeWidth.  Larger factors
+// produce smoother edges, but take longer to draw.  If GammaCorrect is set,
+// pixels are averaged in linear light.
+//
+// ColorFunc which draw patterns of a fixed size in pixels, such as
+// CheckerColor, are drawn at the larger size, so their patterns are reduced in
+// size by the factor.  AntiAlias cannot be used with Stream, and GenerateTo
+// draws the complete image before encoding it.  The factor must be between 1
+// and 8, and the default factor is 1, which disables anti-aliasing.
+func AntiAlias(factor uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setAntiAlias(factor)
+	}
+}
+
+// SetAntiAlias applies the input supersampling factor to the receiving
+// Waveform struct.
+func (w *Waveform) SetAntiAlias(factor uint) error {
+	return w.SetOptions(AntiAlias(factor))
+}
+
+// setAntiAlias directly sets the antiAlias member of the receiving Waveform
+// struct.
+func (w *Waveform) setAntiAlias(factor uint) error {
+	if factor < 1 || factor > 8 {
+		return errAntiAliasRange
+	}
+
+	w.antiAlias = factor
+
+	return nil
+}
+
+// TimeAxis generates an OptionsFunc which applies the input tick interval and
+// axis color to an input Waveform struct.
+//
+// When set, a tick mark is drawn in color c at the bottom of the waveform, in
+// front of it, at each multiple of the interval, and labeled with its
+// timestamp, using the font set by AxisFont.  Timestamps are measured from
+// the beginning of the audio, using the metadata of the last call to Compute,
+// so they remain accurate when silence is trimmed.  Labels include
+// milliseconds only if the interval is not a whole number of seconds.
+func TimeAxis(interval time.Duration, c color.RGBA) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setTimeAxis(interval, c)
+	}
+}
+
+// SetTimeAxis applies the input tick interval and axis color to the receiving
+// Waveform struct.
+func (w *Waveform) SetTimeAxis(interval time.Duration, c color.RGBA) error {
+	return w.SetOptions(TimeAxis(interval, c))
+}
+
+// setTimeAxis directly sets the axisInterval and axisColor members of the
+// receiving Waveform struct.
+func (w *Waveform) setTimeAxis(interval time.Duration, c color.RGBA) error {
+	if interval <= 0 {
+		return errTimeAxisIntervalRange
+	}
+
+	w.axisInterval = interval
+	w.axisColor = c
+
+	return nil
+}
+
+// AxisFont generates an OptionsFunc which applies the input font face to an
+// input Waveform struct.
+//
+// This font is used to draw the timestamp labels of the time axis set by
+// TimeAxis.  The default font is basicfont.Face7x13.
+func AxisFont(face font.Face) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setAxisFont(face)
+	}
+}
+
+// SetAxisFont applies the input font face to the receiving Waveform struct.
+func (w *Waveform) SetAxisFont(face font.Face) error {
+	return w.SetOptions(AxisFont(face))
+}
+
+// setAxisFont directly sets the axisFace member of the receiving Waveform
+// struct.
+func (w *Waveform) setAxisFont(face font.Face) error {
+	if face == nil {
+		return errAxisFontNil
+	}
+
+	w.axisFace = face
+
+	return nil
+}
+
+// DecibelGrid generates an OptionsFunc which applies the input gridline step
+// and color to an input Waveform struct.
+//
+// When set, horizontal gridlines are drawn in color c, in front of the
+// waveform, at the extent of a computed value at every step in decibels below
+// full scale, such as every 6dB, down to -96dB.  Amplitude transforms, such as
+// NoiseFloor, are applied to the level of each gridline, as they are to
+// computed values.
+func DecibelGrid(step float64, c color.RGBA) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setDecibelGrid(step, c)
+	}
+}
+
+// SetDecibelGrid applies the input gridline step and color to the receiving
+// Waveform struct.
+func (w *Waveform) SetDecibelGrid(step float64, c color.RGBA) error {
+	return w.SetOptions(DecibelGrid(step, c))
+}
+
+// setDecibelGrid directly sets the gridStep and gridColor members of the
+// receiving Waveform struct.
+func (w *Waveform) setDecibelGrid(step float64, c color.RGBA) error {
+	// Also rejects NaN
+	if !(step > 0) {
+		return errDecibelGridStepRange
+	}
+
+	w.gridStep = step
+	w.gridColor = c
+
+	return nil
+}
+
+// Markers generates an OptionsFunc which applies the input markers to an input
+// Waveform struct.
+//
+// When set, a vertical line is drawn in the color of each marker, in front of
+// the waveform, at its timestamp, and labeled with its label, if any, using
+// the font set by AxisFont.  Timestamps are measured from the beginning of
+// the audio, in the same way as TimeAxis, and markers outside of the drawn
+// values are not drawn.  Unlike DrawMarkers, which draws the markers embedded
+// in a stream, these markers are supplied by the caller, such as chapters or
+// cue points stored elsewhere.
+func Markers(markers []Marker) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setMarkers(markers)
+	}
+}
+
+// SetMarkers applies the input markers to the receiving Waveform struct.
+func (w *Waveform) SetMarkers(markers []Marker) error {
+	return w.SetOptions(Markers(markers))
+}
+
+// setMarkers directly sets the markers member of the receiving Waveform
+// struct, copying the input markers.
+func (w *Waveform) setMarkers(markers []Marker) error {
+	for _, m := range markers {
+		if m.Color == nil {
+			return errMarkerColorNil
+		}
+	}
+
+	w.markers = append([]Marker(nil), markers...)
+
+	return nil
+}
+
+// Normalize generates an OptionsFunc which sets the normalize member to true
+// on an input Waveform struct.
+//
+// This value indicates that all values should be scaled so that the peak of
+// the track reaches the full height of the image, so that quiet recordings are
+// drawn at the same size as loud ones.  The largest value drawn symmetrically
+// about the center of the image, or the largest sample magnitude drawn by a
+// MinMaxEnvelope, is scaled to the full height.  Normalize replaces the
+// scaling factor applied by ScaleClipping, if both are set.
+//
+// Values returned by Compute are unchanged, and since all values are needed
+// to find the peak, Normalize cannot be used with Stream.
+func Normalize() OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setNormalize(true)
+	}
+}
+
+// SetNormalize sets the normalize member true for the receiving Waveform
+// struct.
+func (w *Waveform) SetNormalize() error {
+	return w.SetOptions(Normalize())
+}
+
+// setNormalize directly sets the normalize member of the receiving Waveform
+// struct.
+func (w *Waveform) setNormalize(normalize bool) error {
+	w.normalize = normalize
+
+	return nil
+}
+
+// StereoSplit generates an OptionsFunc which enables stereo split drawing on
+// an input Waveform struct, using the input left and right ColorFuncs.
+//
+// When enabled, the Separate channel mode is set, and Generate draws the left
+// channel above the center of the image, and the right channel below it, using
+// DrawStereo, in the style of professional audio editors.  The left and right
+// ColorFuncs are used in place of the foreground ColorFunc.
+func StereoSplit(leftColor ColorFunc, rightColor ColorFunc) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setStereoSplit(leftColor, rightColor)
+	}
+}
+
+// SetStereoSplit applies the input left and right ColorFuncs to the receiving
+// Waveform struct, enabling stereo split drawing.
+func (w *Waveform) SetStereoSplit(leftColor ColorFunc, rightColor ColorFunc) error {
+	return w.SetOptions(StereoSplit(leftColor, rightColor))
+}
+
+// setStereoSplit directly sets the stereoSplit, leftColorFn, and rightColorFn
+// members of the receiving Waveform struct, and sets the Separate channel
+// mode.
+func (w *Waveform) setStereoSplit(leftColor ColorFunc, rightColor ColorFunc) error {
+	// Left function cannot be nil
+	if leftColor == nil {
+		return errLeftColorFunctionNil
+	}
+
+	// Right function cannot be nil
+	if rightColor == nil {
+		return errRightColorFunctionNil
+	}
+
+	w.stereoSplit = true
+	w.leftColorFn = leftColor
+	w.rightColorFn = rightColor
+
+	return w.setChannels(Separate)
+}
+
+// LaneSeparator generates an OptionsFunc which applies the input height in
+// pixels and color to an input Waveform struct.
+//
+// When set, GenerateStacked draws a separator of the input height and color
+// between each pair of adjacent lanes.  It has no effect otherwise.
+func LaneSeparator(height uint, c color.RGBA) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setLaneSeparator(height, c)
+	}
+}
+
+// SetLaneSeparator applies the input height and color to the receiving
+// Waveform struct.
+func (w *Waveform) SetLaneSeparator(height uint, c color.RGBA) error {
+	return w.SetOptions(LaneSeparator(height, c))
+}
+
+// setLaneSeparator directly sets the laneSepHeight and laneSepColor members of
+// the receiving Waveform struct.
+func (w *Waveform) setLaneSeparator(height uint, c color.RGBA) error {
+	// Height cannot be zero
+	if height == 0 {
+		return errLaneSeparatorZero
+	}
+
+	w.laneSepHeight = height
+	w.laneSepColor = c
+
+	return nil
+}
+
+// Window generates an OptionsFunc which applies the input start and duration
+// to an input Waveform struct.
+//
+// When set, only the audio from start, relative to the beginning of the
+// stream, to start plus duration is decoded and drawn, such as for a zoomed
+// in view of a long recording.  A duration of 0 continues to the end of the
+// stream.  The Metadata of the computation records the window's offsets in
+// the stream, so that any time axis and markers are drawn at their times in
+// the full recording.
+//
+// If the stream is an io.ReadSeeker containing integer PCM, floating point,
+// or G.711 WAV samples, or raw PCM samples set by the RawPCM option, the
+// stream is seeked directly to the start of the window.  Otherwise, all
+// samples which precede it are decoded and discarded.  Window has no effect
+// on a Decoder, which is positioned using ResumeDecoder.
+func Window(start time.Duration, duration time.Duration) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setWindow(start, duration)
+	}
+}
+
+// SetWindow applies the input start and duration to the receiving Waveform
+// struct.
+func (w *Waveform) SetWindow(start time.Duration, duration time.Duration) error {
+	return w.SetOptions(Window(start, duration))
+}
+
+// setWindow directly sets the windowStart and windowDuration members of the
+// receiving Waveform struct.
+func (w *Waveform) setWindow(start time.Duration, duration time.Duration) error {
+	// Start and duration cannot be negative
+	if start < 0 || duration < 0 {
+		return errWindowNegative
+	}
+
+	w.windowStart = start
+	w.windowDuration = duration
+
+	return nil
+}
+
+// TileWidth generates an OptionsFunc which applies the input width in pixels
+// to an input Waveform struct.
+//
+// This value sets the width of each tile drawn by GenerateTiles, which holds
+// as many whole bars, including their gaps, as fit within it, or at least one.
+// Tiles are 256 pixels wide by default.
+func TileWidth(px uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setTileWidth(px)
+	}
+}
+
+// SetTileWidth applies the input tile width to the receiving Waveform struct.
+func (w *Waveform) SetTileWidth(px uint) error {
+	return w.SetOptions(TileWidth(px))
+}
+
+// setTileWidth directly sets the tileWidth member of the receiving Waveform
+// struct.
+func (w *Waveform) setTileWidth(px uint) error {
+	// Width cannot be zero
+	if px == 0 {
+		return errTileWidthZero
+	}
+
+	w.tileWidth = px
+
+	return nil
+}
+
+// SliceSamples generates an OptionsFunc which applies the input number of
+// samples per slice to an input Waveform struct.
+//
+// This value sets the exact number of samples per channel from which each
+// value is computed, in place of the number set by Resolution, such as 1 for
+// a sample-accurate view of a short clip, or 44100 for one value per second of
+// audio at 44.1kHz, up to 33554432 (2^25).  The slice is shortened if it would
+// contain more than 2^25 samples across all channels.  By default, the number
+// of samples per slice is set by the resolution and the sample rate of the
+// audio.
+func SliceSamples(n uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setSliceSamples(n)
+	}
+}
+
+// SetSliceSamples applies the input number of samples per slice to the
+// receiving Waveform struct.
+func (w *Waveform) SetSliceSamples(n uint) error {
+	return w.SetOptions(SliceSamples(n))
+}
+
+// setSliceSamples directly sets the sliceSamples member of the receiving
+// Waveform struct.
+func (w *Waveform) setSliceSamples(n uint) error {
+	// Samples per slice cannot be zero
+	if n == 0 {
+		return errSliceSamplesZero
+	}
+	if n > sliceSamplesMax {
+		return errSliceSamplesRange
+	}
+
+	w.sliceSamples = n
+
+	return nil
+}
+
+// MaxWidth generates an OptionsFunc which applies the input maximum width in
+// pixels to an input Waveform struct.
+//
+// When set, images are never wider than the maximum width, including any
+// padding, however long the audio, unless the padding alone is as wide.  If
+// there are too many computed values to draw each as its own bar, adjacent
+// values are merged into fewer bars, in the same way as by Dimensions,
+// reducing the effective resolution so that loud peaks are still drawn, and
+// markers are drawn at their times within the merged bars.  At least one bar
+// is always drawn, and is clipped if it is wider than the maximum width.
+// MaxWidth has no effect when Dimensions sets an exact width, and cannot be
+// used with Stream.  By default, there is no maximum width.
+func MaxWidth(px uint) OptionsFunc {
+	return func(w *Waveform) error {
+		return w.setMaxWidth(px)
+	}
+}
+
+// SetMaxWidth applies the input maximum width to the receiving Waveform
+// struct.
+func (w *Waveform) SetMaxWidth(px uint) error {
+	return w.SetOptions(MaxWidth(px))
+}
+
+// setMaxWidth directly sets the maxWidth member of the receiving Waveform
+// struct.
+func (w *Waveform) setMaxWidth(px uint) error {
+	// Width cannot be zero
+	if px == 0 {
+		return errMaxWidthZero
+	}
+
+	w.maxWidth = px
+
+	return nil
+}
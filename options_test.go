@@ -1,9 +1,13 @@
 package waveform
 
 import (
+	"encoding/binary"
 	"fmt"
 	"image/color"
+	"math"
+	"reflect"
 	"testing"
+	"time"
 )
 
 // TestOptionsError verifies that the format of OptionsError.Error does
@@ -78,6 +82,408 @@ func TestOptionResolutionZero(t *testing.T) {
 	testWaveformOptionFunc(t, Resolution(0), errResolutionZero)
 }
 
+// TestOptionResolutionFractional verifies that Resolution accepts fractional
+// values.
+func TestOptionResolutionFractional(t *testing.T) {
+	testWaveformOptionFunc(t, Resolution(0.5), nil)
+	testWaveformOptionFunc(t, Resolution(44100.5), nil)
+}
+
+// TestOptionResolutionRange verifies that Resolution does not accept negative,
+// infinite, or NaN values.
+func TestOptionResolutionRange(t *testing.T) {
+	testWaveformOptionFunc(t, Resolution(-1), errResolutionRange)
+	testWaveformOptionFunc(t, Resolution(math.Inf(1)), errResolutionRange)
+	testWaveformOptionFunc(t, Resolution(math.NaN()), errResolutionRange)
+
+	// Resolutions whose slices would overflow or exhaust memory
+	testWaveformOptionFunc(t, Resolution(1e-20), errResolutionRange)
+	testWaveformOptionFunc(t, Resolution(resolutionMin/2), errResolutionRange)
+	testWaveformOptionFunc(t, Resolution(resolutionMin), nil)
+	testWaveformOptionFunc(t, Resolution(math.MaxFloat64), nil)
+}
+
+// TestOptionSliceSamplesOK verifies that SliceSamples returns no error with
+// acceptable input.
+func TestOptionSliceSamplesOK(t *testing.T) {
+	testWaveformOptionFunc(t, SliceSamples(1), nil)
+}
+
+// TestOptionSliceSamplesZero verifies that SliceSamples does not accept
+// integer 0.
+func TestOptionSliceSamplesZero(t *testing.T) {
+	testWaveformOptionFunc(t, SliceSamples(0), errSliceSamplesZero)
+}
+
+// TestOptionSliceSamplesRange verifies that SliceSamples does not accept more
+// than the maximum number of samples per slice.
+func TestOptionSliceSamplesRange(t *testing.T) {
+	testWaveformOptionFunc(t, SliceSamples(sliceSamplesMax), nil)
+	testWaveformOptionFunc(t, SliceSamples(sliceSamplesMax+1), errSliceSamplesRange)
+}
+
+// TestOptionResampleOK verifies that Resample returns no error with
+// acceptable input.
+func TestOptionResampleOK(t *testing.T) {
+	testWaveformOptionFunc(t, Resample(44100), nil)
+}
+
+// TestOptionResampleZero verifies that Resample does not accept a sample
+// rate less than 1.
+func TestOptionResampleZero(t *testing.T) {
+	testWaveformOptionFunc(t, Resample(0), errResampleRateZero)
+}
+
+// TestOptionMaxChannelsOK verifies that MaxChannels returns no error with
+// acceptable input.
+func TestOptionMaxChannelsOK(t *testing.T) {
+	testWaveformOptionFunc(t, MaxChannels(2), nil)
+}
+
+// TestOptionMaxChannelsZero verifies that MaxChannels does not accept an
+// integer 0.
+func TestOptionMaxChannelsZero(t *testing.T) {
+	testWaveformOptionFunc(t, MaxChannels(0), errMaxChannelsZero)
+}
+
+// TestOptionRawPCMOK verifies that RawPCM returns no error with acceptable
+// input.
+func TestOptionRawPCMOK(t *testing.T) {
+	testWaveformOptionFunc(t, RawPCM(44100, 16, 2, binary.LittleEndian), nil)
+}
+
+// TestOptionRawPCMSampleRate verifies that RawPCM does not accept a sample
+// rate less than or equal to 0.
+func TestOptionRawPCMSampleRate(t *testing.T) {
+	testWaveformOptionFunc(t, RawPCM(0, 16, 2, binary.LittleEndian), errRawPCMSampleRate)
+}
+
+// TestOptionRawPCMBits verifies that RawPCM does not accept an unsupported
+// bit depth.
+func TestOptionRawPCMBits(t *testing.T) {
+	testWaveformOptionFunc(t, RawPCM(44100, 12, 2, binary.LittleEndian), errRawPCMBits)
+}
+
+// TestOptionRawPCMChannels verifies that RawPCM does not accept a channel
+// count less than or equal to 0.
+func TestOptionRawPCMChannels(t *testing.T) {
+	testWaveformOptionFunc(t, RawPCM(44100, 16, 0, binary.LittleEndian), errRawPCMChannels)
+}
+
+// TestOptionRawPCMByteOrderNil verifies that RawPCM does not accept a nil
+// byte order.
+func TestOptionRawPCMByteOrderNil(t *testing.T) {
+	testWaveformOptionFunc(t, RawPCM(44100, 16, 2, nil), errRawPCMByteOrderNil)
+}
+
+// TestOptionChannelOK verifies that Channel returns no error with acceptable
+// input.
+func TestOptionChannelOK(t *testing.T) {
+	testWaveformOptionFunc(t, Channel(0), nil)
+}
+
+// TestOptionChannelNegative verifies that Channel does not accept a negative
+// integer.
+func TestOptionChannelNegative(t *testing.T) {
+	testWaveformOptionFunc(t, Channel(-1), errChannelNegative)
+}
+
+// TestOptionChannelsOK verifies that Channels returns no error with each
+// ChannelMode.
+func TestOptionChannelsOK(t *testing.T) {
+	for _, mode := range []ChannelMode{Downmix, Left, Right, Separate} {
+		testWaveformOptionFunc(t, Channels(mode), nil)
+	}
+}
+
+// TestOptionChannelsUnknown verifies that Channels does not accept an unknown
+// ChannelMode.
+func TestOptionChannelsUnknown(t *testing.T) {
+	testWaveformOptionFunc(t, Channels(Separate+1), errChannelModeUnknown)
+}
+
+// TestOptionTrackIndexOK verifies that TrackIndex returns no error with
+// acceptable input.
+func TestOptionTrackIndexOK(t *testing.T) {
+	testWaveformOptionFunc(t, TrackIndex(1), nil)
+}
+
+// TestOptionTrackIndexNegative verifies that TrackIndex does not accept a
+// negative integer.
+func TestOptionTrackIndexNegative(t *testing.T) {
+	testWaveformOptionFunc(t, TrackIndex(-1), errTrackIndexNegative)
+}
+
+// TestOptionExecDecoderOK verifies that ExecDecoder returns no error with
+// acceptable input.
+func TestOptionExecDecoderOK(t *testing.T) {
+	testWaveformOptionFunc(t, ExecDecoder("ffmpeg"), nil)
+}
+
+// TestOptionExecDecoderNameEmpty verifies that ExecDecoder does not accept an
+// empty command name.
+func TestOptionExecDecoderNameEmpty(t *testing.T) {
+	testWaveformOptionFunc(t, ExecDecoder("", "-i", "pipe:0"), errExecDecoderName)
+}
+
+// TestOptionPalettedOK verifies that Paletted returns no error with
+// acceptable input.
+func TestOptionPalettedOK(t *testing.T) {
+	testWaveformOptionFunc(t, Paletted([]color.Color{white, black}), nil)
+}
+
+// TestOptionPalettedSize verifies that Paletted does not accept an empty
+// palette, or a palette with more than 256 colors.
+func TestOptionPalettedSize(t *testing.T) {
+	testWaveformOptionFunc(t, Paletted(nil), errPaletteSize)
+	testWaveformOptionFunc(t, Paletted(make([]color.Color, 257)), errPaletteSize)
+}
+
+// TestOptionPalettedColorNil verifies that Paletted does not accept a nil
+// color.
+func TestOptionPalettedColorNil(t *testing.T) {
+	testWaveformOptionFunc(t, Paletted([]color.Color{white, nil}), errPaletteColorNil)
+}
+
+// TestOptionSweepOK verifies that Sweep returns no error with acceptable
+// input.
+func TestOptionSweepOK(t *testing.T) {
+	testWaveformOptionFunc(t, Sweep(1, 10*time.Millisecond), nil)
+}
+
+// TestOptionSweepFramesZero verifies that Sweep does not accept 0 frames.
+func TestOptionSweepFramesZero(t *testing.T) {
+	testWaveformOptionFunc(t, Sweep(0, time.Second), errSweepFramesZero)
+}
+
+// TestOptionSweepDelayRange verifies that Sweep does not accept a frame delay
+// shorter than 10ms.
+func TestOptionSweepDelayRange(t *testing.T) {
+	testWaveformOptionFunc(t, Sweep(10, 9*time.Millisecond), errSweepDelayRange)
+}
+
+// TestOptionStrokeWidthOK verifies that StrokeWidth returns no error with
+// acceptable input.
+func TestOptionStrokeWidthOK(t *testing.T) {
+	testWaveformOptionFunc(t, StrokeWidth(2), nil)
+}
+
+// TestOptionStrokeWidthZero verifies that StrokeWidth does not accept integer
+// 0.
+func TestOptionStrokeWidthZero(t *testing.T) {
+	testWaveformOptionFunc(t, StrokeWidth(0), errStrokeWidthZero)
+}
+
+// TestOptionDotsOK verifies that Dots returns no error with acceptable input.
+func TestOptionDotsOK(t *testing.T) {
+	testWaveformOptionFunc(t, Dots(3), nil)
+}
+
+// TestOptionDotsRadiusZero verifies that Dots does not accept integer 0.
+func TestOptionDotsRadiusZero(t *testing.T) {
+	testWaveformOptionFunc(t, Dots(0), errDotRadiusZero)
+}
+
+// TestOptionFFTSizeOK verifies that FFTSize returns no error with acceptable
+// input.
+func TestOptionFFTSizeOK(t *testing.T) {
+	testWaveformOptionFunc(t, FFTSize(2048), nil)
+}
+
+// TestOptionFFTSizeRange verifies that FFTSize does not accept sizes which are
+// not powers of two, or are outside of [16, 65536].
+func TestOptionFFTSizeRange(t *testing.T) {
+	for _, size := range []uint{0, 8, 1000, 131072} {
+		testWaveformOptionFunc(t, FFTSize(size), errFFTSizeRange)
+	}
+}
+
+// TestOptionSpectrogramColormapOK verifies that SpectrogramColormap returns no
+// error with acceptable input.
+func TestOptionSpectrogramColormapOK(t *testing.T) {
+	testWaveformOptionFunc(t, SpectrogramColormap(GrayColormap), nil)
+}
+
+// TestOptionSpectrogramColormapNil verifies that SpectrogramColormap does not
+// accept a nil Colormap.
+func TestOptionSpectrogramColormapNil(t *testing.T) {
+	testWaveformOptionFunc(t, SpectrogramColormap(nil), errColormapNil)
+}
+
+// TestOptionRadialOK verifies that Radial returns no error with acceptable
+// input.
+func TestOptionRadialOK(t *testing.T) {
+	testWaveformOptionFunc(t, Radial(64, 90), nil)
+}
+
+// TestOptionRadialRotationRange verifies that Radial does not accept rotations
+// outside of [0, 360).
+func TestOptionRadialRotationRange(t *testing.T) {
+	for _, rotation := range []float64{-1, 360, math.NaN()} {
+		testWaveformOptionFunc(t, Radial(0, rotation), errRadialRotationRange)
+	}
+}
+
+// TestOptionTransparentBackgroundOK verifies that TransparentBackground
+// returns no error.
+func TestOptionTransparentBackgroundOK(t *testing.T) {
+	testWaveformOptionFunc(t, TransparentBackground(), nil)
+}
+
+// TestOptionDimensionsOK verifies that Dimensions returns no error with
+// acceptable input.
+func TestOptionDimensionsOK(t *testing.T) {
+	testWaveformOptionFunc(t, Dimensions(800, 160), nil)
+}
+
+// TestOptionDimensionsZero verifies that Dimensions does not accept integer 0
+// as the width or height.
+func TestOptionDimensionsZero(t *testing.T) {
+	testWaveformOptionFunc(t, Dimensions(0, 160), errDimensionsZero)
+	testWaveformOptionFunc(t, Dimensions(800, 0), errDimensionsZero)
+}
+
+// TestOptionAntiAliasOK verifies that AntiAlias returns no error with
+// acceptable input.
+func TestOptionAntiAliasOK(t *testing.T) {
+	testWaveformOptionFunc(t, AntiAlias(4), nil)
+}
+
+// TestOptionAntiAliasRange verifies that AntiAlias does not accept factors
+// outside of [1, 8].
+func TestOptionAntiAliasRange(t *testing.T) {
+	testWaveformOptionFunc(t, AntiAlias(0), errAntiAliasRange)
+	testWaveformOptionFunc(t, AntiAlias(9), errAntiAliasRange)
+}
+
+// TestOptionTimeAxisOK verifies that TimeAxis returns no error with acceptable
+// input.
+func TestOptionTimeAxisOK(t *testing.T) {
+	testWaveformOptionFunc(t, TimeAxis(10*time.Second, color.RGBA{0, 0, 0, 255}), nil)
+}
+
+// TestOptionTimeAxisIntervalRange verifies that TimeAxis does not accept an
+// interval of 0 or less.
+func TestOptionTimeAxisIntervalRange(t *testing.T) {
+	testWaveformOptionFunc(t, TimeAxis(0, color.RGBA{0, 0, 0, 255}), errTimeAxisIntervalRange)
+}
+
+// TestOptionAxisFontNil verifies that AxisFont does not accept a nil font.Face.
+func TestOptionAxisFontNil(t *testing.T) {
+	testWaveformOptionFunc(t, AxisFont(nil), errAxisFontNil)
+}
+
+// TestOptionDecibelGridOK verifies that DecibelGrid returns no error with
+// acceptable input.
+func TestOptionDecibelGridOK(t *testing.T) {
+	testWaveformOptionFunc(t, DecibelGrid(6, color.RGBA{0, 0, 0, 64}), nil)
+}
+
+// TestOptionDecibelGridStepRange verifies that DecibelGrid does not accept a
+// step of 0 or less.
+func TestOptionDecibelGridStepRange(t *testing.T) {
+	for _, step := range []float64{0, -6, math.NaN()} {
+		testWaveformOptionFunc(t, DecibelGrid(step, color.RGBA{0, 0, 0, 64}), errDecibelGridStepRange)
+	}
+}
+
+// TestOptionMarkersOK verifies that Markers returns no error with acceptable
+// input.
+func TestOptionMarkersOK(t *testing.T) {
+	testWaveformOptionFunc(t, Markers([]Marker{{Time: time.Second, Color: color.Black, Label: "Intro"}}), nil)
+}
+
+// TestOptionMarkersColorNil verifies that Markers does not accept a Marker
+// with a nil color.
+func TestOptionMarkersColorNil(t *testing.T) {
+	testWaveformOptionFunc(t, Markers([]Marker{{Time: time.Second}}), errMarkerColorNil)
+}
+
+// TestOptionNormalizeOK verifies that Normalize returns no error.
+func TestOptionNormalizeOK(t *testing.T) {
+	testWaveformOptionFunc(t, Normalize(), nil)
+}
+
+// TestOptionStereoSplitOK verifies that StereoSplit returns no error with
+// acceptable input.
+func TestOptionStereoSplitOK(t *testing.T) {
+	testWaveformOptionFunc(t, StereoSplit(SolidColor(color.Black), SolidColor(color.White)), nil)
+}
+
+// TestOptionStereoSplitLeftNil verifies that StereoSplit does not accept a nil
+// left ColorFunc.
+func TestOptionStereoSplitLeftNil(t *testing.T) {
+	testWaveformOptionFunc(t, StereoSplit(nil, SolidColor(color.White)), errLeftColorFunctionNil)
+}
+
+// TestOptionStereoSplitRightNil verifies that StereoSplit does not accept a nil
+// right ColorFunc.
+func TestOptionStereoSplitRightNil(t *testing.T) {
+	testWaveformOptionFunc(t, StereoSplit(SolidColor(color.Black), nil), errRightColorFunctionNil)
+}
+
+// TestOptionLaneSeparatorOK verifies that LaneSeparator returns no error with
+// acceptable input.
+func TestOptionLaneSeparatorOK(t *testing.T) {
+	testWaveformOptionFunc(t, LaneSeparator(2, color.RGBA{0, 0, 0, 255}), nil)
+}
+
+// TestOptionLaneSeparatorZero verifies that LaneSeparator does not accept a
+// height of 0.
+func TestOptionLaneSeparatorZero(t *testing.T) {
+	testWaveformOptionFunc(t, LaneSeparator(0, color.RGBA{0, 0, 0, 255}), errLaneSeparatorZero)
+}
+
+// TestOptionMaxWidthOK verifies that MaxWidth returns no error with acceptable
+// input.
+func TestOptionMaxWidthOK(t *testing.T) {
+	testWaveformOptionFunc(t, MaxWidth(4096), nil)
+}
+
+// TestOptionMaxWidthZero verifies that MaxWidth does not accept integer 0.
+func TestOptionMaxWidthZero(t *testing.T) {
+	testWaveformOptionFunc(t, MaxWidth(0), errMaxWidthZero)
+}
+
+// TestOptionTileWidthOK verifies that TileWidth returns no error with
+// acceptable input.
+func TestOptionTileWidthOK(t *testing.T) {
+	testWaveformOptionFunc(t, TileWidth(512), nil)
+}
+
+// TestOptionTileWidthZero verifies that TileWidth does not accept integer 0.
+func TestOptionTileWidthZero(t *testing.T) {
+	testWaveformOptionFunc(t, TileWidth(0), errTileWidthZero)
+}
+
+// TestOptionWindowOK verifies that Window returns no error with acceptable
+// input.
+func TestOptionWindowOK(t *testing.T) {
+	testWaveformOptionFunc(t, Window(0, 0), nil)
+	testWaveformOptionFunc(t, Window(time.Minute, time.Second), nil)
+}
+
+// TestOptionWindowNegative verifies that Window does not accept a negative
+// start or duration.
+func TestOptionWindowNegative(t *testing.T) {
+	testWaveformOptionFunc(t, Window(-time.Second, 0), errWindowNegative)
+	testWaveformOptionFunc(t, Window(0, -time.Second), errWindowNegative)
+}
+
+// TestOptionQualityOK verifies that Quality returns no error with acceptable
+// input.
+func TestOptionQualityOK(t *testing.T) {
+	testWaveformOptionFunc(t, Quality(1), nil)
+	testWaveformOptionFunc(t, Quality(100), nil)
+}
+
+// TestOptionQualityRange verifies that Quality does not accept values outside
+// of [1, 100].
+func TestOptionQualityRange(t *testing.T) {
+	testWaveformOptionFunc(t, Quality(0), errQualityRange)
+	testWaveformOptionFunc(t, Quality(101), errQualityRange)
+}
+
 // TestOptionScaleOK verifies that Scale returns no error with acceptable input.
 func TestOptionScaleOK(t *testing.T) {
 	testWaveformOptionFunc(t, Scale(1, 1), nil)
@@ -93,6 +499,32 @@ func TestOptionScaleYZero(t *testing.T) {
 	testWaveformOptionFunc(t, Scale(1, 0), errScaleYZero)
 }
 
+// TestOptionHeightOK verifies that Height returns no error with acceptable
+// input, including along with an X scale.
+func TestOptionHeightOK(t *testing.T) {
+	testWaveformOptionFunc(t, Height(120), nil)
+
+	if _, err := New(nil, Scale(5, 1), Height(120)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestOptionHeightZero verifies that Height does not accept integer 0.
+func TestOptionHeightZero(t *testing.T) {
+	testWaveformOptionFunc(t, Height(0), errHeightZero)
+}
+
+// TestOptionHeightScaleY verifies that Height and a Y scale cannot be used
+// together, in either order.
+func TestOptionHeightScaleY(t *testing.T) {
+	if _, err := New(nil, Scale(1, 2), Height(120)); err != errHeightScaleY {
+		t.Fatalf("unexpected error: %v != %v", err, errHeightScaleY)
+	}
+	if _, err := New(nil, Height(120), Scale(1, 2)); err != errScaleYHeight {
+		t.Fatalf("unexpected error: %v != %v", err, errScaleYHeight)
+	}
+}
+
 // TestOptionScaleClippingOK verifies that ScaleClipping returns no error.
 func TestOptionScaleClippingOK(t *testing.T) {
 	testWaveformOptionFunc(t, ScaleClipping(), nil)
@@ -103,6 +535,221 @@ func TestOptionSharpnessOK(t *testing.T) {
 	testWaveformOptionFunc(t, Sharpness(0), nil)
 }
 
+// TestOptionDualEnvelopeOK verifies that DualEnvelope returns no error with
+// acceptable input.
+func TestOptionDualEnvelopeOK(t *testing.T) {
+	testWaveformOptionFunc(t, DualEnvelope(SolidColor(color.Black), SolidColor(color.White)), nil)
+}
+
+// TestOptionDualEnvelopePeakNil verifies that DualEnvelope does not accept
+// a nil peak ColorFunc.
+func TestOptionDualEnvelopePeakNil(t *testing.T) {
+	testWaveformOptionFunc(t, DualEnvelope(nil, SolidColor(color.White)), errPeakColorFunctionNil)
+}
+
+// TestOptionDualEnvelopeRMSNil verifies that DualEnvelope does not accept
+// a nil RMS ColorFunc.
+func TestOptionDualEnvelopeRMSNil(t *testing.T) {
+	testWaveformOptionFunc(t, DualEnvelope(SolidColor(color.Black), nil), errRMSColorFunctionNil)
+}
+
+// TestOptionInvertAmplitudeOK verifies that InvertAmplitude returns no error.
+func TestOptionInvertAmplitudeOK(t *testing.T) {
+	testWaveformOptionFunc(t, InvertAmplitude(), nil)
+}
+
+// TestOptionBarWidthOK verifies that BarWidth returns no error with acceptable input.
+func TestOptionBarWidthOK(t *testing.T) {
+	testWaveformOptionFunc(t, BarWidth(1), nil)
+}
+
+// TestOptionBarWidthZero verifies that BarWidth does not accept integer 0.
+func TestOptionBarWidthZero(t *testing.T) {
+	testWaveformOptionFunc(t, BarWidth(0), errBarWidthZero)
+}
+
+// TestOptionDiscreteBarsOK verifies that DiscreteBars returns no error with acceptable input.
+func TestOptionDiscreteBarsOK(t *testing.T) {
+	testWaveformOptionFunc(t, DiscreteBars(3, 2), nil)
+}
+
+// TestOptionDiscreteBarsWidthZero verifies that DiscreteBars does not accept a width of 0.
+func TestOptionDiscreteBarsWidthZero(t *testing.T) {
+	testWaveformOptionFunc(t, DiscreteBars(0, 2), errBarWidthZero)
+}
+
+// TestOptionBarGapOK verifies that BarGap returns no error.
+func TestOptionBarGapOK(t *testing.T) {
+	testWaveformOptionFunc(t, BarGap(0), nil)
+}
+
+// TestOptionSilenceThresholdOK verifies that SilenceThreshold returns no error
+// with acceptable input.
+func TestOptionSilenceThresholdOK(t *testing.T) {
+	testWaveformOptionFunc(t, SilenceThreshold(0.1), nil)
+}
+
+// TestOptionSilenceThresholdRange verifies that SilenceThreshold does not accept
+// values outside of [0, 1].
+func TestOptionSilenceThresholdRange(t *testing.T) {
+	testWaveformOptionFunc(t, SilenceThreshold(-0.1), errSilenceThresholdRange)
+	testWaveformOptionFunc(t, SilenceThreshold(1.1), errSilenceThresholdRange)
+}
+
+// TestOptionNoiseFloorOK verifies that NoiseFloor returns no error with
+// acceptable input.
+func TestOptionNoiseFloorOK(t *testing.T) {
+	testWaveformOptionFunc(t, NoiseFloor(0.1), nil)
+}
+
+// TestOptionNoiseFloorRange verifies that NoiseFloor does not accept values
+// outside of [0, 1].
+func TestOptionNoiseFloorRange(t *testing.T) {
+	testWaveformOptionFunc(t, NoiseFloor(-0.1), errNoiseFloorRange)
+	testWaveformOptionFunc(t, NoiseFloor(1.1), errNoiseFloorRange)
+}
+
+// TestOptionTrimSilenceOK verifies that TrimSilence returns no error with
+// acceptable input.
+func TestOptionTrimSilenceOK(t *testing.T) {
+	testWaveformOptionFunc(t, TrimSilence(0.1), nil)
+}
+
+// TestOptionTrimSilenceRange verifies that TrimSilence does not accept a
+// threshold outside of [0, 1].
+func TestOptionTrimSilenceRange(t *testing.T) {
+	testWaveformOptionFunc(t, TrimSilence(-0.1), errTrimSilenceRange)
+	testWaveformOptionFunc(t, TrimSilence(1.1), errTrimSilenceRange)
+}
+
+// TestOptionPartialOnErrorOK verifies that PartialOnError returns no error.
+func TestOptionPartialOnErrorOK(t *testing.T) {
+	testWaveformOptionFunc(t, PartialOnError(), nil)
+}
+
+// TestOptionFastThumbnailOK verifies that FastThumbnail returns no error.
+func TestOptionFastThumbnailOK(t *testing.T) {
+	testWaveformOptionFunc(t, FastThumbnail(), nil)
+}
+
+// TestOptionGammaCorrectOK verifies that GammaCorrect returns no error
+// with acceptable input.
+func TestOptionGammaCorrectOK(t *testing.T) {
+	testWaveformOptionFunc(t, GammaCorrect(true), nil)
+}
+
+// TestOptionOnProgressOK verifies that OnProgress returns no error
+// with acceptable input.
+func TestOptionOnProgressOK(t *testing.T) {
+	testWaveformOptionFunc(t, OnProgress(func(float64) {}), nil)
+}
+
+// TestOptionOnProgressNil verifies that OnProgress does not accept
+// a nil ProgressFunc.
+func TestOptionOnProgressNil(t *testing.T) {
+	testWaveformOptionFunc(t, OnProgress(nil), errProgressFunctionNil)
+}
+
+// TestOptionProgressOK verifies that Progress returns no error with
+// acceptable input.
+func TestOptionProgressOK(t *testing.T) {
+	testWaveformOptionFunc(t, Progress(func(float64, float64) {}), nil)
+}
+
+// TestOptionProgressNil verifies that Progress does not accept a nil
+// ProgressTimeFunc.
+func TestOptionProgressNil(t *testing.T) {
+	testWaveformOptionFunc(t, Progress(nil), errProgressTimeFunctionNil)
+}
+
+// TestOptionMaxSamplesOK verifies that MaxSamples returns no error
+// with acceptable input.
+func TestOptionMaxSamplesOK(t *testing.T) {
+	testWaveformOptionFunc(t, MaxSamples(1, false), nil)
+}
+
+// TestOptionMaxSamplesZero verifies that MaxSamples does not accept
+// a limit less than 1.
+func TestOptionMaxSamplesZero(t *testing.T) {
+	testWaveformOptionFunc(t, MaxSamples(0, true), errMaxSamplesZero)
+}
+
+// TestOptionMinMaxEnvelopeOK verifies that MinMaxEnvelope returns no error.
+func TestOptionMinMaxEnvelopeOK(t *testing.T) {
+	testWaveformOptionFunc(t, MinMaxEnvelope(), nil)
+}
+
+// TestOptionMirroredOK verifies that Mirrored returns no error.
+func TestOptionMirroredOK(t *testing.T) {
+	testWaveformOptionFunc(t, Mirrored(), nil)
+}
+
+// TestOptionDPIOK verifies that DPI returns no error with acceptable input.
+func TestOptionDPIOK(t *testing.T) {
+	testWaveformOptionFunc(t, DPI(300, 300), nil)
+}
+
+// TestOptionDPIRange verifies that DPI does not accept a value less than or
+// equal to 0.
+func TestOptionDPIRange(t *testing.T) {
+	testWaveformOptionFunc(t, DPI(0, 300), errDPIRange)
+	testWaveformOptionFunc(t, DPI(300, -1), errDPIRange)
+}
+
+// TestOptionPaddingOK verifies that Padding returns no error.
+func TestOptionPaddingOK(t *testing.T) {
+	testWaveformOptionFunc(t, Padding(1, 2, 3, 4), nil)
+}
+
+// TestOptionClipIndicatorOK verifies that ClipIndicator returns no error
+// with acceptable input.
+func TestOptionClipIndicatorOK(t *testing.T) {
+	testWaveformOptionFunc(t, ClipIndicator(color.RGBA{255, 0, 0, 255}, 0.9), nil)
+}
+
+// TestOptionClipIndicatorThresholdRange verifies that ClipIndicator does not
+// accept a threshold outside of [0, 1].
+func TestOptionClipIndicatorThresholdRange(t *testing.T) {
+	testWaveformOptionFunc(t, ClipIndicator(color.RGBA{}, -0.1), errClipThresholdRange)
+	testWaveformOptionFunc(t, ClipIndicator(color.RGBA{}, 1.1), errClipThresholdRange)
+}
+
+// TestOptionOverlayOK verifies that Overlay returns no error with acceptable
+// input.
+func TestOptionOverlayOK(t *testing.T) {
+	testWaveformOptionFunc(t, Overlay([]float64{0.1}, color.RGBA{255, 0, 0, 255}), nil)
+}
+
+// TestOptionOverlayValuesEmpty verifies that Overlay does not accept an empty
+// slice of values.
+func TestOptionOverlayValuesEmpty(t *testing.T) {
+	testWaveformOptionFunc(t, Overlay(nil, color.RGBA{}), errOverlayValuesEmpty)
+}
+
+// TestOptionStyleOK verifies that Style returns no error with acceptable
+// input.
+func TestOptionStyleOK(t *testing.T) {
+	testWaveformOptionFunc(t, Style(Bars), nil)
+	testWaveformOptionFunc(t, Style(AreaFill), nil)
+	testWaveformOptionFunc(t, Style(Line), nil)
+}
+
+// TestOptionStyleUnknown verifies that Style does not accept an unknown
+// DrawStyle.
+func TestOptionStyleUnknown(t *testing.T) {
+	testWaveformOptionFunc(t, Style(DrawStyle(-1)), errStyleUnknown)
+}
+
+// TestOptionDrawMarkersOK verifies that DrawMarkers returns no error.
+func TestOptionDrawMarkersOK(t *testing.T) {
+	testWaveformOptionFunc(t, DrawMarkers(color.RGBA{255, 0, 0, 255}), nil)
+}
+
+// TestOptionBarRadiusOK verifies that BarRadius returns no error.
+func TestOptionBarRadiusOK(t *testing.T) {
+	testWaveformOptionFunc(t, BarRadius(0), nil)
+}
+
 // TestWaveformSetOptionsNil verifies that Waveform.SetOptions ignores any
 // nil OptionsFunc arguments.
 func TestWaveformSetOptionsNil(t *testing.T) {
@@ -158,7 +805,7 @@ func TestWaveformSetSampleFunction(t *testing.T) {
 // modifies struct members.
 func TestWaveformSetResolution(t *testing.T) {
 	// Predefined test values
-	res := uint(1)
+	res := 0.5
 
 	// Generate empty Waveform, apply parameters
 	w := &Waveform{}
@@ -227,6 +874,875 @@ func TestWaveformSetSharpness(t *testing.T) {
 	}
 }
 
+// TestWaveformSetDualEnvelope verifies that the Waveform.SetDualEnvelope method
+// properly modifies struct members.
+func TestWaveformSetDualEnvelope(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetDualEnvelope(SolidColor(color.Black), SolidColor(color.White)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.dualEnvelope {
+		t.Fatalf("SetDualEnvelope failed, false dualEnvelope member")
+	}
+	if w.peakColorFn == nil || w.rmsColorFn == nil {
+		t.Fatalf("SetDualEnvelope failed, nil function member")
+	}
+}
+
+// TestWaveformSetInvertAmplitude verifies that the Waveform.SetInvertAmplitude
+// method properly modifies struct members.
+func TestWaveformSetInvertAmplitude(t *testing.T) {
+	// Generate empty Waveform, apply function
+	w := &Waveform{}
+	if err := w.SetInvertAmplitude(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.invertAmplitude {
+		t.Fatalf("SetInvertAmplitude failed, false invertAmplitude member")
+	}
+}
+
+// TestWaveformSetBarWidth verifies that the Waveform.SetBarWidth method properly
+// modifies struct members.
+func TestWaveformSetBarWidth(t *testing.T) {
+	// Predefined test values
+	width := uint(3)
+
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetBarWidth(width); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.barWidth != width {
+		t.Fatalf("unexpected bar width: %v != %v", w.barWidth, width)
+	}
+}
+
+// TestWaveformSetDiscreteBars verifies that the Waveform.SetDiscreteBars method properly
+// modifies struct members.
+func TestWaveformSetDiscreteBars(t *testing.T) {
+	// Predefined test values
+	width, gap := uint(3), uint(2)
+
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetDiscreteBars(width, gap); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.barWidth != width || w.barGap != gap {
+		t.Fatalf("unexpected bars: %v, %v != %v, %v", w.barWidth, w.barGap, width, gap)
+	}
+}
+
+// TestWaveformSetBarGap verifies that the Waveform.SetBarGap method properly
+// modifies struct members.
+func TestWaveformSetBarGap(t *testing.T) {
+	// Predefined test values
+	gap := uint(2)
+
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetBarGap(gap); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.barGap != gap {
+		t.Fatalf("unexpected bar gap: %v != %v", w.barGap, gap)
+	}
+}
+
+// TestWaveformSetSilenceThreshold verifies that the Waveform.SetSilenceThreshold
+// method properly modifies struct members.
+func TestWaveformSetSilenceThreshold(t *testing.T) {
+	// Predefined test values
+	threshold := 0.25
+
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetSilenceThreshold(threshold); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.silenceThreshold != threshold {
+		t.Fatalf("unexpected silence threshold: %v != %v", w.silenceThreshold, threshold)
+	}
+}
+
+// TestWaveformSetNoiseFloor verifies that the Waveform.SetNoiseFloor method
+// properly modifies struct members.
+func TestWaveformSetNoiseFloor(t *testing.T) {
+	// Predefined test values
+	floor := 0.25
+
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetNoiseFloor(floor); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.noiseFloor != floor {
+		t.Fatalf("unexpected noise floor: %v != %v", w.noiseFloor, floor)
+	}
+}
+
+// TestWaveformSetTrimSilence verifies that the Waveform.SetTrimSilence method
+// properly modifies struct members.
+func TestWaveformSetTrimSilence(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetTrimSilence(0.1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.trimThreshold != 0.1 {
+		t.Fatalf("SetTrimSilence failed, unexpected trimThreshold member: %v != %v", w.trimThreshold, 0.1)
+	}
+}
+
+// TestWaveformSetPartialOnError verifies that the Waveform.SetPartialOnError
+// method properly modifies struct members.
+func TestWaveformSetPartialOnError(t *testing.T) {
+	// Generate empty Waveform, apply function
+	w := &Waveform{}
+	if err := w.SetPartialOnError(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.partialOnError {
+		t.Fatalf("SetPartialOnError failed, false partialOnError member")
+	}
+}
+
+// TestWaveformSetFastThumbnail verifies that the Waveform.SetFastThumbnail
+// method properly modifies struct members.
+func TestWaveformSetFastThumbnail(t *testing.T) {
+	// Generate empty Waveform, apply function
+	w := &Waveform{}
+	if err := w.SetFastThumbnail(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.fastThumbnail {
+		t.Fatalf("SetFastThumbnail failed, false fastThumbnail member")
+	}
+}
+
+// TestWaveformSetGammaCorrect verifies that the Waveform.SetGammaCorrect
+// method properly modifies struct members.
+func TestWaveformSetGammaCorrect(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetGammaCorrect(true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.gammaCorrect {
+		t.Fatalf("SetGammaCorrect failed, false gammaCorrect member")
+	}
+}
+
+// TestWaveformSetOnProgress verifies that the Waveform.SetOnProgress
+// method properly modifies struct members.
+func TestWaveformSetOnProgress(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetOnProgress(func(float64) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.progressFn == nil {
+		t.Fatalf("SetOnProgress failed, nil function member")
+	}
+}
+
+// TestWaveformSetProgress verifies that the Waveform.SetProgress method
+// properly modifies struct members.
+func TestWaveformSetProgress(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetProgress(func(float64, float64) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.progressTimeFn == nil {
+		t.Fatalf("SetProgress failed, nil function member")
+	}
+}
+
+// TestWaveformSetMaxSamples verifies that the Waveform.SetMaxSamples
+// method properly modifies struct members.
+func TestWaveformSetMaxSamples(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetMaxSamples(44100, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.maxSamples != 44100 {
+		t.Fatalf("SetMaxSamples failed, unexpected maxSamples member: %v != %v", w.maxSamples, 44100)
+	}
+	if !w.truncateAtLimit {
+		t.Fatalf("SetMaxSamples failed, false truncateAtLimit member")
+	}
+}
+
+// TestWaveformSetHeight verifies that the Waveform.SetHeight method properly
+// modifies struct members.
+func TestWaveformSetHeight(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetHeight(120); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.height != 120 {
+		t.Fatalf("SetHeight failed, unexpected height member: %v != %v", w.height, 120)
+	}
+}
+
+// TestWaveformSetMinMaxEnvelope verifies that the Waveform.SetMinMaxEnvelope
+// method properly modifies struct members.
+func TestWaveformSetMinMaxEnvelope(t *testing.T) {
+	// Generate empty Waveform, apply function
+	w := &Waveform{}
+	if err := w.SetMinMaxEnvelope(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.minMaxEnvelope {
+		t.Fatalf("SetMinMaxEnvelope failed, false minMaxEnvelope member")
+	}
+}
+
+// TestWaveformSetMirrored verifies that the Waveform.SetMirrored method
+// properly modifies struct members.
+func TestWaveformSetMirrored(t *testing.T) {
+	// Generate empty Waveform, apply function
+	w := &Waveform{}
+	if err := w.SetMirrored(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.mirrored {
+		t.Fatalf("SetMirrored failed, false mirrored member")
+	}
+}
+
+// TestWaveformSetClipIndicator verifies that the Waveform.SetClipIndicator
+// method properly modifies struct members, using the default threshold for 0.
+func TestWaveformSetClipIndicator(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetClipIndicator(color.RGBA{255, 0, 0, 255}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.clipColorFn == nil {
+		t.Fatalf("SetClipIndicator failed, nil function member")
+	}
+	if w.clipThreshold != clipThresholdDefault {
+		t.Fatalf("SetClipIndicator failed, unexpected clipThreshold member: %v != %v", w.clipThreshold, clipThresholdDefault)
+	}
+}
+
+// TestWaveformSetOverlay verifies that the Waveform.SetOverlay method properly
+// modifies struct members.
+func TestWaveformSetOverlay(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	c := color.RGBA{255, 0, 0, 255}
+	if err := w.SetOverlay([]float64{0.1, 0.2}, c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if len(w.overlayValues) != 2 {
+		t.Fatalf("SetOverlay failed, unexpected overlayValues length: %v != %v", len(w.overlayValues), 2)
+	}
+	if w.overlayColor != c {
+		t.Fatalf("SetOverlay failed, unexpected overlayColor member: %v != %v", w.overlayColor, c)
+	}
+}
+
+// TestWaveformSetMaxChannels verifies that the Waveform.SetMaxChannels method
+// properly modifies struct members.
+func TestWaveformSetMaxChannels(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetMaxChannels(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.maxChannels != 2 {
+		t.Fatalf("SetMaxChannels failed, unexpected maxChannels member: %v != %v", w.maxChannels, 2)
+	}
+}
+
+// TestWaveformSetRawPCM verifies that the Waveform.SetRawPCM method properly
+// modifies struct members.
+func TestWaveformSetRawPCM(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetRawPCM(8000, 24, 1, binary.BigEndian); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	want := rawPCMFormat{sampleRate: 8000, bits: 24, channels: 1, order: binary.BigEndian}
+	if w.rawPCM == nil || *w.rawPCM != want {
+		t.Fatalf("SetRawPCM failed, unexpected rawPCM member: %v != %v", w.rawPCM, want)
+	}
+}
+
+// TestWaveformSetChannel verifies that the Waveform.SetChannel method
+// properly modifies struct members.
+func TestWaveformSetChannel(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetChannel(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.selectChannel || w.channel != 1 {
+		t.Fatalf("SetChannel failed, unexpected channel members: %v, %v", w.selectChannel, w.channel)
+	}
+}
+
+// TestWaveformSetChannels verifies that the Waveform.SetChannels method
+// properly modifies struct members.
+func TestWaveformSetChannels(t *testing.T) {
+	var tests = []struct {
+		mode     ChannelMode
+		selected bool
+		channel  int
+		separate bool
+	}{
+		{Downmix, false, 0, false},
+		{Left, true, 0, false},
+		{Right, true, 1, false},
+		{Separate, false, 0, true},
+	}
+
+	for i, test := range tests {
+		// Generate Waveform with a channel selected, apply parameters
+		w := &Waveform{selectChannel: true, channel: 2}
+		if err := w.SetChannels(test.mode); err != nil {
+			t.Fatal(err)
+		}
+
+		// Validate that struct members are set properly
+		if w.selectChannel != test.selected || w.separate != test.separate ||
+			(test.selected && w.channel != test.channel) {
+			t.Fatalf("[%02d] SetChannels failed, unexpected channel members: %v, %v, %v",
+				i, w.selectChannel, w.channel, w.separate)
+		}
+	}
+}
+
+// TestWaveformSetTrackIndex verifies that the Waveform.SetTrackIndex method
+// properly modifies struct members.
+func TestWaveformSetTrackIndex(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetTrackIndex(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.trackIndex != 2 {
+		t.Fatalf("SetTrackIndex failed, unexpected trackIndex: %v != %v", w.trackIndex, 2)
+	}
+}
+
+// TestWaveformSetExecDecoder verifies that the Waveform.SetExecDecoder method
+// properly modifies struct members.
+func TestWaveformSetExecDecoder(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetExecDecoder("ffmpeg", "-i", "pipe:0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	want := &execCommand{name: "ffmpeg", args: []string{"-i", "pipe:0"}}
+	if !reflect.DeepEqual(w.execCmd, want) {
+		t.Fatalf("SetExecDecoder failed, unexpected execCmd: %v != %v", w.execCmd, want)
+	}
+}
+
+// TestWaveformSetPaletted verifies that the Waveform.SetPaletted method
+// properly modifies struct members.
+func TestWaveformSetPaletted(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	colors := []color.Color{white, black}
+	w := &Waveform{}
+	if err := w.SetPaletted(colors); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly, and are not shared
+	// with the input
+	colors[0] = red
+	if len(w.palette) != 2 || w.palette[0] != white || w.palette[1] != black {
+		t.Fatalf("SetPaletted failed, unexpected palette member: %v", w.palette)
+	}
+}
+
+// TestWaveformSetDPI verifies that the Waveform.SetDPI method properly
+// modifies struct members.
+func TestWaveformSetDPI(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetDPI(300, 150); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.dpiX != 300 || w.dpiY != 150 {
+		t.Fatalf("SetDPI failed, unexpected dpiX and dpiY members: %v, %v", w.dpiX, w.dpiY)
+	}
+}
+
+// TestWaveformSetStyle verifies that the Waveform.SetStyle method properly
+// modifies struct members.
+func TestWaveformSetStyle(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetStyle(AreaFill); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.style != AreaFill {
+		t.Fatalf("SetStyle failed, unexpected style member: %v != %v", w.style, AreaFill)
+	}
+}
+
+// TestWaveformSetPadding verifies that the Waveform.SetPadding method properly
+// modifies struct members.
+func TestWaveformSetPadding(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetPadding(1, 2, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.padTop != 1 || w.padRight != 2 || w.padBottom != 3 || w.padLeft != 4 {
+		t.Fatalf("SetPadding failed, unexpected padding members: %v, %v, %v, %v", w.padTop, w.padRight, w.padBottom, w.padLeft)
+	}
+}
+
+// TestWaveformSetDrawMarkers verifies that the Waveform.SetDrawMarkers method
+// properly modifies struct members.
+func TestWaveformSetDrawMarkers(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetDrawMarkers(color.RGBA{255, 0, 0, 255}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.markerColorFn == nil {
+		t.Fatalf("SetDrawMarkers failed, nil function member")
+	}
+}
+
+// TestWaveformSetResample verifies that the Waveform.SetResample method
+// properly modifies struct members.
+func TestWaveformSetResample(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetResample(48000); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.resampleRate != 48000 {
+		t.Fatalf("SetResample failed, unexpected resampleRate member: %v != %v", w.resampleRate, 48000)
+	}
+}
+
+// TestWaveformSetBarRadius verifies that the Waveform.SetBarRadius method
+// properly modifies struct members.
+func TestWaveformSetBarRadius(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetBarRadius(4); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.barRadius != 4 {
+		t.Fatalf("SetBarRadius failed, unexpected barRadius member: %v != %v", w.barRadius, 4)
+	}
+}
+
+// TestWaveformSetSweep verifies that the Waveform.SetSweep method properly
+// modifies struct members.
+func TestWaveformSetSweep(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetSweep(30, 40*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.sweepFrames != 30 || w.sweepDelay != 40*time.Millisecond {
+		t.Fatalf("SetSweep failed, unexpected sweep members: %v, %v", w.sweepFrames, w.sweepDelay)
+	}
+}
+
+// TestWaveformSetSweepPlayhead verifies that the Waveform.SetSweepPlayhead
+// method properly modifies struct members.
+func TestWaveformSetSweepPlayhead(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetSweepPlayhead(color.RGBA{255, 0, 0, 255}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.playheadColorFn == nil {
+		t.Fatalf("SetSweepPlayhead failed, nil function member")
+	}
+}
+
+// TestWaveformSetStrokeWidth verifies that the Waveform.SetStrokeWidth method
+// properly modifies struct members.
+func TestWaveformSetStrokeWidth(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetStrokeWidth(3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.strokeWidth != 3 {
+		t.Fatalf("SetStrokeWidth failed, unexpected strokeWidth member: %v != %v", w.strokeWidth, 3)
+	}
+}
+
+// TestWaveformSetDots verifies that the Waveform.SetDots method properly
+// modifies struct members.
+func TestWaveformSetDots(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetDots(3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.style != dotStyle || w.dotRadius != 3 {
+		t.Fatalf("SetDots failed, unexpected style and dotRadius members: %v, %v", w.style, w.dotRadius)
+	}
+}
+
+// TestWaveformSetFFTSize verifies that the Waveform.SetFFTSize method properly
+// modifies struct members.
+func TestWaveformSetFFTSize(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetFFTSize(256); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.fftSize != 256 {
+		t.Fatalf("SetFFTSize failed, unexpected fftSize member: %v", w.fftSize)
+	}
+}
+
+// TestWaveformSetSpectrogramColormap verifies that the
+// Waveform.SetSpectrogramColormap method properly modifies struct members.
+func TestWaveformSetSpectrogramColormap(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetSpectrogramColormap(GrayColormap); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.colormap == nil || w.colormap(1) != (color.Gray{Y: 0xff}) {
+		t.Fatalf("SetSpectrogramColormap failed, unexpected colormap member")
+	}
+}
+
+// TestWaveformSetRadial verifies that the Waveform.SetRadial method properly
+// modifies struct members.
+func TestWaveformSetRadial(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetRadial(64, 90); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.radialInner != 64 || w.radialRotation != 90 {
+		t.Fatalf("SetRadial failed, unexpected radialInner and radialRotation members: %v, %v", w.radialInner, w.radialRotation)
+	}
+}
+
+// TestWaveformSetTransparentBackground verifies that the
+// Waveform.SetTransparentBackground method properly modifies struct members.
+func TestWaveformSetTransparentBackground(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetTransparentBackground(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.nrgba || w.bgColorFn == nil {
+		t.Fatalf("SetTransparentBackground failed, unexpected nrgba and bgColorFn members: %v", w.nrgba)
+	}
+	if _, _, _, a := w.bgColorFn(0, 0, 0, 1, 1, 1).RGBA(); a != 0 {
+		t.Fatalf("SetTransparentBackground failed, unexpected background alpha: %v", a)
+	}
+}
+
+// TestWaveformSetDimensions verifies that the Waveform.SetDimensions method
+// properly modifies struct members.
+func TestWaveformSetDimensions(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetDimensions(800, 160); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.width != 800 || w.height != 160 {
+		t.Fatalf("SetDimensions failed, unexpected width and height members: %v, %v", w.width, w.height)
+	}
+}
+
+// TestWaveformSetAntiAlias verifies that the Waveform.SetAntiAlias method
+// properly modifies struct members.
+func TestWaveformSetAntiAlias(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetAntiAlias(4); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.antiAlias != 4 {
+		t.Fatalf("SetAntiAlias failed, unexpected antiAlias member: %v", w.antiAlias)
+	}
+}
+
+// TestWaveformSetTimeAxis verifies that the Waveform.SetTimeAxis method
+// properly modifies struct members.
+func TestWaveformSetTimeAxis(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	c := color.RGBA{255, 0, 0, 255}
+	if err := w.SetTimeAxis(10*time.Second, c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.axisInterval != 10*time.Second || w.axisColor != c {
+		t.Fatalf("SetTimeAxis failed, unexpected axisInterval and axisColor members: %v, %v", w.axisInterval, w.axisColor)
+	}
+}
+
+// TestWaveformSetDecibelGrid verifies that the Waveform.SetDecibelGrid method
+// properly modifies struct members.
+func TestWaveformSetDecibelGrid(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	c := color.RGBA{0, 0, 0, 64}
+	if err := w.SetDecibelGrid(6, c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.gridStep != 6 || w.gridColor != c {
+		t.Fatalf("SetDecibelGrid failed, unexpected gridStep and gridColor members: %v, %v", w.gridStep, w.gridColor)
+	}
+}
+
+// TestWaveformSetMarkers verifies that the Waveform.SetMarkers method
+// properly modifies struct members.
+func TestWaveformSetMarkers(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	markers := []Marker{{Time: time.Second, Color: color.Black, Label: "Intro"}}
+	if err := w.SetMarkers(markers); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly, and are not modified
+	// along with the input markers
+	markers[0].Label = "Outro"
+	if len(w.markers) != 1 || w.markers[0].Label != "Intro" {
+		t.Fatalf("SetMarkers failed, unexpected markers member: %v", w.markers)
+	}
+}
+
+// TestWaveformSetNormalize verifies that the Waveform.SetNormalize method
+// properly modifies struct members.
+func TestWaveformSetNormalize(t *testing.T) {
+	// Generate empty Waveform, apply function
+	w := &Waveform{}
+	if err := w.SetNormalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.normalize {
+		t.Fatalf("SetNormalize failed, false normalize member")
+	}
+}
+
+// TestWaveformSetStereoSplit verifies that the Waveform.SetStereoSplit method
+// properly modifies struct members.
+func TestWaveformSetStereoSplit(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetStereoSplit(SolidColor(color.Black), SolidColor(color.White)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if !w.stereoSplit || !w.separate {
+		t.Fatalf("SetStereoSplit failed, false stereoSplit or separate member")
+	}
+	if w.leftColorFn == nil || w.rightColorFn == nil {
+		t.Fatalf("SetStereoSplit failed, nil function member")
+	}
+}
+
+// TestWaveformSetLaneSeparator verifies that the Waveform.SetLaneSeparator
+// method properly modifies struct members.
+func TestWaveformSetLaneSeparator(t *testing.T) {
+	// Predefined test values
+	height := uint(2)
+	c := color.RGBA{255, 0, 0, 255}
+
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetLaneSeparator(height, c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.laneSepHeight != height {
+		t.Fatalf("unexpected lane separator height: %v != %v", w.laneSepHeight, height)
+	}
+	if w.laneSepColor != c {
+		t.Fatalf("unexpected lane separator color: %v != %v", w.laneSepColor, c)
+	}
+}
+
+// TestWaveformSetMaxWidth verifies that the Waveform.SetMaxWidth method
+// properly modifies struct members.
+func TestWaveformSetMaxWidth(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetMaxWidth(4096); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.maxWidth != 4096 {
+		t.Fatalf("unexpected maximum width: %v != %v", w.maxWidth, 4096)
+	}
+}
+
+// TestWaveformSetTileWidth verifies that the Waveform.SetTileWidth method
+// properly modifies struct members.
+func TestWaveformSetTileWidth(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetTileWidth(512); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.tileWidth != 512 {
+		t.Fatalf("unexpected tile width: %v != %v", w.tileWidth, 512)
+	}
+}
+
+// TestWaveformSetSliceSamples verifies that the Waveform.SetSliceSamples
+// method properly modifies struct members.
+func TestWaveformSetSliceSamples(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetSliceSamples(441); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.sliceSamples != 441 {
+		t.Fatalf("unexpected samples per slice: %v != %v", w.sliceSamples, 441)
+	}
+}
+
+// TestWaveformSetWindow verifies that the Waveform.SetWindow method properly
+// modifies struct members.
+func TestWaveformSetWindow(t *testing.T) {
+	// Predefined test values
+	start := 90 * time.Second
+	duration := 30 * time.Second
+
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetWindow(start, duration); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.windowStart != start {
+		t.Fatalf("unexpected window start: %v != %v", w.windowStart, start)
+	}
+	if w.windowDuration != duration {
+		t.Fatalf("unexpected window duration: %v != %v", w.windowDuration, duration)
+	}
+}
+
+// TestWaveformSetQuality verifies that the Waveform.SetQuality method properly
+// modifies struct members.
+func TestWaveformSetQuality(t *testing.T) {
+	// Generate empty Waveform, apply parameters
+	w := &Waveform{}
+	if err := w.SetQuality(40); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that struct members are set properly
+	if w.quality != 40 {
+		t.Fatalf("SetQuality failed, unexpected quality member: %v != %v", w.quality, 40)
+	}
+}
+
 // testWaveformOptionFunc is a test helper which verifies that applying the
 // input OptionsFunc to a new Waveform struct generates the appropriate
 // error output.
@@ -0,0 +1,301 @@
+//go:build opus
+// +build opus
+
+package waveform
+
+/*
+#cgo pkg-config: opusfile
+#include <stdlib.h>
+#include <opusfile.h>
+
+// op_ms_set_gain sets the output gain of a multistream decoder, in Q7.8 dB,
+// as the variadic control function cannot be called from Go.
+static int op_ms_set_gain(OpusMSDecoder *st, int gain) {
+	return opus_multistream_decoder_ctl(st, OPUS_SET_GAIN(gain));
+}
+*/
+import "C"
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"unsafe"
+
+	"azul3d.org/engine/audio"
+)
+
+// opusSampleRate is the sample rate of all decoded Opus streams.  Opus always
+// operates at 48kHz internally, and the input sample rate stored in a stream's
+// header is only informational.
+const opusSampleRate = 48000
+
+// opusMaxFrameSamples is the maximum number of samples per channel in a single
+// Opus packet: 120ms at 48kHz.
+const opusMaxFrameSamples = 5760
+
+// Opus decoding uses libopusfile via cgo, so it is only built when the opus
+// build tag is set.  The default build remains free of cgo.
+func init() {
+	opusDecodable = true
+	audio.RegisterFormat(FormatOpus, opusMagic, newOpusDecoder)
+	mkvCodecs["A_OPUS"] = newMKVOpusDecoder
+}
+
+// opusDecoder is an audio.Decoder which decodes an Ogg Opus stream to float64
+// PCM samples at 48kHz.
+type opusDecoder struct {
+	f      *C.OggOpusFile
+	data   unsafe.Pointer
+	config audio.Config
+
+	// Decoded samples not yet read
+	buf     []C.float
+	pending []C.float
+}
+
+// newOpusDecoder opens a decoder on an input Ogg Opus stream.
+//
+// The stream is read into memory before decoding begins, so that libopusfile
+// can seek to the end of the stream to trim any padding from its final packet.
+func newOpusDecoder(r interface{}) (audio.Decoder, error) {
+	rr, ok := r.(io.Reader)
+	if !ok {
+		return nil, audio.ErrInvalidData
+	}
+
+	b, err := ioutil.ReadAll(rr)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, audio.ErrInvalidData
+	}
+
+	// Memory must remain valid until the stream is freed
+	data := C.CBytes(b)
+
+	var cerr C.int
+	f := C.op_open_memory((*C.uchar)(data), C.size_t(len(b)), &cerr)
+	if f == nil {
+		C.free(data)
+		return nil, audio.ErrInvalidData
+	}
+
+	channels := int(C.op_channel_count(f, -1))
+	d := &opusDecoder{
+		f:    f,
+		data: data,
+		config: audio.Config{
+			SampleRate: opusSampleRate,
+			Channels:   channels,
+		},
+		buf: make([]C.float, opusMaxFrameSamples*channels),
+	}
+	runtime.SetFinalizer(d, (*opusDecoder).close)
+
+	return d, nil
+}
+
+// Config returns the audio configuration of the decoded stream.
+func (d *opusDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read decodes samples into b, returning the number of samples read.  On the
+// final read, audio.EOS is returned along with any remaining samples.
+func (d *opusDecoder) Read(b audio.Slice) (int, error) {
+	var n int
+	for n < b.Len() {
+		// Decode the next packet when all pending samples are read
+		if len(d.pending) == 0 {
+			if d.f == nil {
+				return n, audio.EOS
+			}
+
+			if err := d.decode(); err != nil {
+				d.close()
+				return n, err
+			}
+			continue
+		}
+
+		b.Set(n, float64(d.pending[0]))
+		d.pending = d.pending[1:]
+		n++
+	}
+
+	return n, nil
+}
+
+// decode decodes the next packet into the pending samples.  At the end of the
+// stream, the underlying decoder is released.
+func (d *opusDecoder) decode() error {
+	var link C.int
+	read := C.op_read_float(d.f, &d.buf[0], C.int(len(d.buf)), &link)
+	if read < 0 {
+		return audio.ErrInvalidData
+	}
+	if read == 0 {
+		d.close()
+		return nil
+	}
+
+	// Chained streams may change the number of channels, which cannot be
+	// represented by a single configuration
+	if int(C.op_channel_count(d.f, link)) != d.config.Channels {
+		return audio.ErrInvalidData
+	}
+
+	d.pending = d.buf[:int(read)*d.config.Channels]
+	return nil
+}
+
+// close releases the underlying decoder.  It is safe to call more than once.
+func (d *opusDecoder) close() {
+	if d.f != nil {
+		C.op_free(d.f)
+		d.f = nil
+	}
+	if d.data != nil {
+		C.free(d.data)
+		d.data = nil
+	}
+}
+
+// mkvOpusDecoder is an audio.Decoder which decodes the packets of an Opus track
+// in a Matroska container to float64 PCM samples at 48kHz.
+type mkvOpusDecoder struct {
+	st     *C.OpusMSDecoder
+	track  *mkvTrack
+	config audio.Config
+
+	// Samples per channel which remain to be discarded from the beginning of
+	// the stream
+	skip int
+
+	// Decoded samples not yet read
+	buf     []C.float
+	pending []C.float
+}
+
+// newMKVOpusDecoder opens a decoder for an Opus track.  The codec private data
+// contains the Opus identification header, which describes the channel mapping
+// of the packets of the track.
+func newMKVOpusDecoder(t *mkvTrack) (audio.Decoder, error) {
+	h := t.private
+	if len(h) < 19 || string(h[0:8]) != "OpusHead" {
+		return nil, audio.ErrInvalidData
+	}
+
+	channels := int(h[9])
+	preSkip := int(binary.LittleEndian.Uint16(h[10:12]))
+	gain := int(int16(binary.LittleEndian.Uint16(h[16:18])))
+
+	// Mapping family 0 is a single mono or stereo stream, and all other
+	// families describe their streams and mapping explicitly
+	streams, coupled := 1, channels-1
+	mapping := []byte{0, 1}
+	if h[18] != 0 {
+		if len(h) < 21+channels {
+			return nil, audio.ErrInvalidData
+		}
+		streams, coupled = int(h[19]), int(h[20])
+		mapping = h[21 : 21+channels]
+	}
+	if channels == 0 || (h[18] == 0 && channels > 2) {
+		return nil, audio.ErrInvalidData
+	}
+
+	var cerr C.int
+	st := C.opus_multistream_decoder_create(opusSampleRate, C.int(channels), C.int(streams),
+		C.int(coupled), (*C.uchar)(unsafe.Pointer(&mapping[0])), &cerr)
+	if st == nil || cerr != C.OPUS_OK {
+		return nil, audio.ErrInvalidData
+	}
+	C.op_ms_set_gain(st, C.int(gain))
+
+	d := &mkvOpusDecoder{
+		st:    st,
+		track: t,
+		config: audio.Config{
+			SampleRate: opusSampleRate,
+			Channels:   channels,
+		},
+		skip: preSkip,
+		buf:  make([]C.float, opusMaxFrameSamples*channels),
+	}
+	runtime.SetFinalizer(d, (*mkvOpusDecoder).close)
+
+	return d, nil
+}
+
+// Config returns the audio configuration of the decoded stream.
+func (d *mkvOpusDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read decodes samples into b, returning the number of samples read.  On the
+// final read, audio.EOS is returned along with any remaining samples.
+func (d *mkvOpusDecoder) Read(b audio.Slice) (int, error) {
+	var n int
+	for n < b.Len() {
+		// Decode the next packet when all pending samples are read
+		if len(d.pending) == 0 {
+			f, err := d.track.next()
+			if err == io.EOF {
+				d.close()
+				return n, audio.EOS
+			}
+			if err == nil {
+				err = d.decode(f)
+			}
+			if err != nil {
+				d.close()
+				return n, err
+			}
+			continue
+		}
+
+		b.Set(n, float64(d.pending[0]))
+		d.pending = d.pending[1:]
+		n++
+	}
+
+	return n, nil
+}
+
+// decode decodes a single packet into the pending samples, discarding any
+// samples of the pre-skip.
+func (d *mkvOpusDecoder) decode(f []byte) error {
+	if d.st == nil {
+		return audio.ErrInvalidData
+	}
+	if len(f) == 0 {
+		return nil
+	}
+
+	read := int(C.opus_multistream_decode_float(d.st, (*C.uchar)(unsafe.Pointer(&f[0])), C.opus_int32(len(f)),
+		&d.buf[0], opusMaxFrameSamples, 0))
+	if read < 0 {
+		return audio.ErrInvalidData
+	}
+
+	skip := d.skip
+	if skip > read {
+		skip = read
+	}
+	d.skip -= skip
+
+	d.pending = d.buf[skip*d.config.Channels : read*d.config.Channels]
+	return nil
+}
+
+// close releases the underlying decoder.  It is safe to call more than once.
+func (d *mkvOpusDecoder) close() {
+	if d.st != nil {
+		C.opus_multistream_decoder_destroy(d.st)
+		d.st = nil
+	}
+}
@@ -0,0 +1,43 @@
+//go:build opus
+// +build opus
+
+package waveform
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestWaveformComputeOpusOK verifies that the Waveform.Compute method decodes an
+// Ogg Opus stream at 48kHz, regardless of the input sample rate stored in its
+// header, and honors its pre-skip.
+func TestWaveformComputeOpusOK(t *testing.T) {
+	// 1.5 seconds of packets, less the pre-skip
+	w, err := New(bytes.NewReader(testOggOpus(75, 312, 16000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 2 {
+		t.Fatalf("unexpected Compute values length: %v != %v", len(values), 2)
+	}
+
+	m := w.Metadata()
+	if m.SampleRate != 48000 {
+		t.Fatalf("unexpected sample rate: %v != %v", m.SampleRate, 48000)
+	}
+	if want := int64(75*960 - 312); m.TotalSamples != want {
+		t.Fatalf("unexpected total samples: %v != %v", m.TotalSamples, want)
+	}
+}
+
+// TestWaveformComputeOpusErrInvalidData verifies that the Waveform.Compute
+// method returns ErrInvalidData for a truncated Ogg Opus stream.
+func TestWaveformComputeOpusErrInvalidData(t *testing.T) {
+	testWaveformCompute(t, bytes.NewReader(testOggOpus(1, 0, 48000)[:40]), ErrInvalidData, nil, nil)
+}
@@ -0,0 +1,53 @@
+package waveform
+
+import (
+	"image"
+	"image/color/palette"
+	"io"
+)
+
+// GeneratePaletted immediately opens and reads an input audio stream, computes
+// the values required for waveform generation, and returns a paletted waveform
+// image which is customized by zero or more, variadic, OptionsFunc parameters.
+//
+// GeneratePaletted is equivalent to Generate, followed by the DrawPaletted
+// method of a Waveform struct, and handles errors in the same way.
+func GeneratePaletted(r io.Reader, options ...OptionsFunc) (*image.Paletted, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		// Draw any partial results, if requested
+		if w.partialOnError && len(values) > 0 {
+			return w.DrawPaletted(values), err
+		}
+
+		return nil, err
+	}
+
+	return w.DrawPaletted(values), nil
+}
+
+// DrawPaletted creates a new *image.Paletted from a slice of float64 values.
+//
+// The image is the same as the image created by Draw, but each color produced
+// by a ColorFunc is replaced by the nearest color in the palette set by the
+// Paletted option.  If no palette is set, the Plan 9 palette is used.  A
+// paletted image is encoded using far fewer bytes than an RGBA image, such as
+// by an indexed PNG.
+func (w *Waveform) DrawPaletted(values []float64) *image.Paletted {
+	l := w.newLayout(values)
+
+	p := w.palette
+	if p == nil {
+		p = palette.Plan9
+	}
+
+	img := image.NewPaletted(l.bounds, p)
+	l.draw(img)
+
+	return img
+}
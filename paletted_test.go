@@ -0,0 +1,111 @@
+package waveform
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// TestGeneratePaletted verifies that GeneratePaletted produces an
+// *image.Paletted which uses only the colors of the input palette.
+func TestGeneratePaletted(t *testing.T) {
+	palette := []color.Color{white, black}
+
+	img, err := GeneratePaletted(bytes.NewReader(wavFile), Paletted(palette))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(img.Palette) != len(palette) {
+		t.Fatalf("unexpected palette length: %v != %v", len(img.Palette), len(palette))
+	}
+	for _, i := range img.Pix {
+		if int(i) >= len(palette) {
+			t.Fatalf("unexpected palette index: %v", i)
+		}
+	}
+}
+
+// TestWaveformDrawPalettedSnapsColors verifies that the Waveform.DrawPaletted
+// method replaces each color produced by a ColorFunc with the nearest color in
+// the palette.
+func TestWaveformDrawPalettedSnapsColors(t *testing.T) {
+	w, err := New(nil,
+		BGColorFunction(SolidColor(color.RGBA{250, 250, 240, 255})),
+		FGColorFunction(SolidColor(color.RGBA{200, 10, 20, 255})),
+		Paletted([]color.Color{white, black, red}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.DrawPaletted([]float64{0.1})
+
+	var tests = []struct {
+		y     int
+		index uint8
+	}{
+		{0, 0},
+		{imgYDefault / 2, 2},
+	}
+
+	for _, test := range tests {
+		if i := img.ColorIndexAt(0, test.y); i != test.index {
+			t.Fatalf("unexpected palette index at y=%d: %v != %v", test.y, i, test.index)
+		}
+	}
+}
+
+// TestWaveformDrawPaletted verifies that the Waveform.Draw method produces an
+// *image.Paletted with the same colors as DrawPaletted, when the Paletted
+// option is set.
+func TestWaveformDrawPaletted(t *testing.T) {
+	w, err := New(nil, Paletted([]color.Color{white, black}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.1, 0.5, 1}
+	got, ok := w.Draw(values).(*image.Paletted)
+	if !ok {
+		t.Fatalf("unexpected Draw image type: %T", w.Draw(values))
+	}
+
+	testImagesEqual(t, got, w.DrawPaletted(values))
+}
+
+// TestGenerateToPNGPalettedSmaller verifies that GenerateTo encodes an indexed
+// PNG when the Paletted option is set, which is substantially smaller than the
+// equivalent RGBA PNG.
+func TestGenerateToPNGPalettedSmaller(t *testing.T) {
+	options := []OptionsFunc{
+		Resolution(100),
+		Scale(2, 4),
+		FGColorFunction(SolidColor(red)),
+	}
+
+	rgba := bytes.NewBuffer(nil)
+	if err := GenerateTo(rgba, FormatPNG, bytes.NewReader(wavFile), options...); err != nil {
+		t.Fatal(err)
+	}
+
+	paletted := bytes.NewBuffer(nil)
+	options = append(options, Paletted([]color.Color{white, red}))
+	if err := GenerateTo(paletted, FormatPNG, bytes.NewReader(wavFile), options...); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(paletted.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := img.(*image.Paletted); !ok {
+		t.Fatalf("unexpected PNG image type: %T", img)
+	}
+
+	if p, r := paletted.Len(), rgba.Len(); p*2 > r {
+		t.Fatalf("paletted PNG is not substantially smaller: %d bytes, RGBA %d bytes", p, r)
+	}
+}
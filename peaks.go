@@ -0,0 +1,204 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+)
+
+// peaksVersion is the version of the audiowaveform peaks format written by
+// WriteJSON.
+const peaksVersion = 2
+
+// datFlag8Bit is set in the flags of the audiowaveform binary format when
+// peaks are stored as 8-bit integers, rather than 16-bit integers.
+const datFlag8Bit = 1
+
+var (
+	// ErrPeaksBits is returned when peaks are written with a bit precision
+	// other than 8 or 16.
+	ErrPeaksBits = errors.New("waveform: peak bits must be 8 or 16")
+
+	// ErrPeaksVersion is returned when peaks are written in a version of the
+	// audiowaveform binary format other than 1 or 2.
+	ErrPeaksVersion = errors.New("waveform: peaks version must be 1 or 2")
+
+	// ErrSamplesPerPixelZero is returned when peaks are computed with zero
+	// samples per pixel.
+	ErrSamplesPerPixelZero = errors.New("waveform: samples per pixel cannot be 0")
+)
+
+// Peaks stores the minimum and maximum sample of each pixel of a waveform,
+// where each pixel spans a fixed number of samples of audio, as used by web
+// waveform players, such as peaks.js and wavesurfer.js.
+type Peaks struct {
+	// Sample rate of the audio from which the peaks were computed
+	SampleRate int
+
+	// Number of samples per channel spanned by each pixel
+	SamplesPerPixel int
+
+	// Signed minimum and maximum samples of each pixel, from -1 to 1
+	Min []float64
+	Max []float64
+}
+
+// ComputePeaks immediately opens and reads an input audio stream, and
+// computes the minimum and maximum sample of each consecutive run of
+// samplesPerPixel samples, which are customized by zero or more, variadic,
+// OptionsFunc parameters, such as Channel and ResampleRate.
+//
+// All channels are down-mixed to mono, unless a single channel is selected.
+// The Resolution, SliceSamples, and SampleFunction options have no effect,
+// nor do the Separate channel mode and TrimSilence, so that each pixel of the
+// peaks lines up with the same time in the audio when it is played.
+//
+// If any error occurs, nil Peaks are returned along with the error, unless the
+// PartialOnError option is set and some peaks were computed.
+func ComputePeaks(r io.Reader, samplesPerPixel uint, options ...OptionsFunc) (*Peaks, error) {
+	if samplesPerPixel == 0 {
+		return nil, ErrSamplesPerPixelZero
+	}
+
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compute the statistics of each pixel using a copy of the Waveform, so
+	// that the decoding and down-mixing of Compute are reused
+	c := *w
+	c.separate = false
+	c.trimThreshold = 0
+	c.fastThumbnail = false
+	c.retainStats = true
+	if err := c.setSliceSamples(samplesPerPixel); err != nil {
+		return nil, err
+	}
+
+	values, err := c.Compute()
+	if err != nil && (!w.partialOnError || len(values) == 0) {
+		return nil, err
+	}
+
+	p := &Peaks{
+		SampleRate:      c.metadata.SampleRate,
+		SamplesPerPixel: int(samplesPerPixel),
+		Min:             make([]float64, len(c.stats)),
+		Max:             make([]float64, len(c.stats)),
+	}
+	for i, s := range c.stats {
+		p.Min[i], p.Max[i] = s.min, s.max
+	}
+
+	return p, err
+}
+
+// jsonPeaks is the audiowaveform JSON format of Peaks, which is read by
+// peaks.js and wavesurfer.js.
+type jsonPeaks struct {
+	Version         int   `json:"version"`
+	Channels        int   `json:"channels"`
+	SampleRate      int   `json:"sample_rate"`
+	SamplesPerPixel int   `json:"samples_per_pixel"`
+	Bits            uint  `json:"bits"`
+	Length          int   `json:"length"`
+	Data            []int `json:"data"`
+}
+
+// WriteJSON writes the peaks to an output stream in the JSON format of the
+// BBC audiowaveform tool, which may be loaded directly by peaks.js and
+// wavesurfer.js.  The minimum and maximum sample of each pixel are
+// interleaved, and scaled to signed integers of the input bit precision,
+// which must be 8 or 16.
+func (p *Peaks) WriteJSON(out io.Writer, bits uint) error {
+	data, err := p.quantize(bits)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(out).Encode(jsonPeaks{
+		Version:         peaksVersion,
+		Channels:        1,
+		SampleRate:      p.SampleRate,
+		SamplesPerPixel: p.SamplesPerPixel,
+		Bits:            bits,
+		Length:          len(p.Min),
+		Data:            data,
+	})
+}
+
+// WriteDAT writes the peaks to an output stream in the binary .dat format of
+// the BBC audiowaveform tool, which may be loaded directly by peaks.js.  Both
+// version 1 and version 2 of the format are supported, which differ only in
+// that version 2 stores the number of channels in its header.  As with
+// WriteJSON, the minimum and maximum sample of each pixel are interleaved, and
+// scaled to little-endian signed integers of the input bit precision, which
+// must be 8 or 16.
+func (p *Peaks) WriteDAT(out io.Writer, bits uint, version int) error {
+	if version != 1 && version != 2 {
+		return ErrPeaksVersion
+	}
+
+	data, err := p.quantize(bits)
+	if err != nil {
+		return err
+	}
+
+	var flags uint32
+	if bits == 8 {
+		flags |= datFlag8Bit
+	}
+
+	// Write the header, followed by the data, into a single buffer
+	buf := bytes.NewBuffer(nil)
+	header := []interface{}{
+		int32(version),
+		flags,
+		int32(p.SampleRate),
+		int32(p.SamplesPerPixel),
+		uint32(len(p.Min)),
+	}
+	if version == 2 {
+		header = append(header, int32(1))
+	}
+	for _, v := range header {
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+
+	for _, v := range data {
+		if bits == 8 {
+			buf.WriteByte(byte(int8(v)))
+			continue
+		}
+
+		binary.Write(buf, binary.LittleEndian, int16(v))
+	}
+
+	_, err = out.Write(buf.Bytes())
+	return err
+}
+
+// quantize returns the interleaved minimum and maximum sample of each pixel,
+// scaled to signed integers of the input bit precision, and clamped to their
+// range.
+func (p *Peaks) quantize(bits uint) ([]int, error) {
+	if bits != 8 && bits != 16 {
+		return nil, ErrPeaksBits
+	}
+
+	scale := float64(int(1) << (bits - 1))
+	q := func(v float64) int {
+		return int(math.Max(math.Min(math.Round(v*scale), scale-1), -scale))
+	}
+
+	data := make([]int, 0, 2*len(p.Min))
+	for i := range p.Min {
+		data = append(data, q(p.Min[i]), q(p.Max[i]))
+	}
+
+	return data, nil
+}
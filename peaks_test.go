@@ -0,0 +1,134 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestComputePeaks verifies that ComputePeaks computes the minimum and
+// maximum sample of each run of the input number of samples, regardless of
+// the resolution.
+func TestComputePeaks(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	p, err := ComputePeaks(bytes.NewReader(wav), 3, Resolution(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.SampleRate != 4 || p.SamplesPerPixel != 3 {
+		t.Fatalf("unexpected peaks: %+v", p)
+	}
+	if want := []float64{0, -0.5, -1}; !reflect.DeepEqual(p.Min, want) {
+		t.Fatalf("unexpected minimum samples: %v != %v", p.Min, want)
+	}
+	if want := []float64{0.5, 127.0 / 128, 0}; !reflect.DeepEqual(p.Max, want) {
+		t.Fatalf("unexpected maximum samples: %v != %v", p.Max, want)
+	}
+}
+
+// TestComputePeaksSamplesPerPixelZero verifies that ComputePeaks returns an
+// error, without reading any input, when samples per pixel is zero.
+func TestComputePeaksSamplesPerPixelZero(t *testing.T) {
+	if _, err := ComputePeaks(nil, 0); err != ErrSamplesPerPixelZero {
+		t.Fatalf("unexpected error: %v != %v", err, ErrSamplesPerPixelZero)
+	}
+}
+
+// TestPeaksWriteJSON verifies that the Peaks.WriteJSON method writes peaks in
+// the audiowaveform JSON format, scaled to the input bit precision.
+func TestPeaksWriteJSON(t *testing.T) {
+	p := &Peaks{
+		SampleRate:      44100,
+		SamplesPerPixel: 256,
+		Min:             []float64{0, -0.5, -1},
+		Max:             []float64{0.5, 127.0 / 128, 1},
+	}
+
+	var tests = []struct {
+		bits uint
+		data []int
+		err  error
+	}{
+		{bits: 8, data: []int{0, 64, -64, 127, -128, 127}},
+		{bits: 16, data: []int{0, 16384, -16384, 32512, -32768, 32767}},
+		{bits: 12, err: ErrPeaksBits},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		if err := p.WriteJSON(buf, test.bits); err != test.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+		if test.err != nil {
+			continue
+		}
+
+		var out jsonPeaks
+		if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		want := jsonPeaks{
+			Version:         2,
+			Channels:        1,
+			SampleRate:      44100,
+			SamplesPerPixel: 256,
+			Bits:            test.bits,
+			Length:          3,
+			Data:            test.data,
+		}
+		if !reflect.DeepEqual(out, want) {
+			t.Fatalf("[%02d] unexpected JSON peaks:\n- got: %+v\n-want: %+v", i, out, want)
+		}
+	}
+}
+
+// TestPeaksWriteDAT verifies that the Peaks.WriteDAT method writes peaks in
+// version 1 and version 2 of the audiowaveform binary format.
+func TestPeaksWriteDAT(t *testing.T) {
+	p := &Peaks{
+		SampleRate:      44100,
+		SamplesPerPixel: 256,
+		Min:             []float64{-0.5, -1},
+		Max:             []float64{0.5, 1},
+	}
+
+	var tests = []struct {
+		bits    uint
+		version int
+		out     []byte
+		err     error
+	}{
+		{
+			bits:    8,
+			version: 1,
+			out: []byte{
+				1, 0, 0, 0, 1, 0, 0, 0, 0x44, 0xac, 0, 0, 0, 1, 0, 0, 2, 0, 0, 0,
+				0xc0, 0x40, 0x80, 0x7f,
+			},
+		},
+		{
+			bits:    16,
+			version: 2,
+			out: []byte{
+				2, 0, 0, 0, 0, 0, 0, 0, 0x44, 0xac, 0, 0, 0, 1, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0,
+				0x00, 0xc0, 0x00, 0x40, 0x00, 0x80, 0xff, 0x7f,
+			},
+		},
+		{bits: 16, version: 3, err: ErrPeaksVersion},
+		{bits: 4, version: 2, err: ErrPeaksBits},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		if err := p.WriteDAT(buf, test.bits, test.version); err != test.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), test.out) {
+			t.Fatalf("[%02d] unexpected binary peaks:\n- got: %v\n-want: %v", i, buf.Bytes(), test.out)
+		}
+	}
+}
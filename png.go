@@ -0,0 +1,87 @@
+package waveform
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+const (
+	// pngHeaderLen is the length of the PNG signature and IHDR chunk, which
+	// always begin a PNG stream
+	pngHeaderLen = 8 + 8 + 13 + 4
+
+	// metersPerInch is used to convert DPI to pixels per meter
+	metersPerInch = 0.0254
+)
+
+// pngPhysWriter is an io.Writer which inserts a pHYs chunk, describing the
+// physical pixel dimensions of an image, into a PNG stream written by the
+// image/png package.
+//
+// The pHYs chunk must appear before the first IDAT chunk, so it is inserted
+// immediately after the IHDR chunk, which is always the first chunk.
+type pngPhysWriter struct {
+	w io.Writer
+
+	// Pixels per meter on the X and Y axes
+	x uint32
+	y uint32
+
+	// Number of bytes of the PNG header which have been written
+	header int
+}
+
+// newPNGPhysWriter creates a pngPhysWriter which writes a PNG stream to w,
+// with a pHYs chunk describing the input X and Y DPI values.
+func newPNGPhysWriter(w io.Writer, dpiX float64, dpiY float64) *pngPhysWriter {
+	return &pngPhysWriter{
+		w: w,
+		x: uint32(math.Floor(dpiX/metersPerInch + 0.5)),
+		y: uint32(math.Floor(dpiY/metersPerInch + 0.5)),
+	}
+}
+
+// Write writes p to the underlying io.Writer, inserting the pHYs chunk once
+// the PNG header has been written.
+func (w *pngPhysWriter) Write(p []byte) (int, error) {
+	// Header was already written, so pass all bytes through
+	if w.header >= pngHeaderLen {
+		return w.w.Write(p)
+	}
+
+	// Write any remaining bytes of the header
+	n := minInt(len(p), pngHeaderLen-w.header)
+	written, err := w.w.Write(p[:n])
+	w.header += written
+	if err != nil {
+		return written, err
+	}
+
+	if w.header < pngHeaderLen {
+		return written, nil
+	}
+
+	if _, err := w.w.Write(w.chunk()); err != nil {
+		return written, err
+	}
+
+	// Write all bytes following the header
+	rest, err := w.w.Write(p[n:])
+	return written + rest, err
+}
+
+// chunk returns the encoded pHYs chunk, with the unit specifier set to meters.
+func (w *pngPhysWriter) chunk() []byte {
+	b := make([]byte, 8+9+4)
+	binary.BigEndian.PutUint32(b[0:4], 9)
+	copy(b[4:8], "pHYs")
+	binary.BigEndian.PutUint32(b[8:12], w.x)
+	binary.BigEndian.PutUint32(b[12:16], w.y)
+	b[16] = 1
+
+	// Checksum covers the chunk type and data
+	binary.BigEndian.PutUint32(b[17:21], crc32.ChecksumIEEE(b[4:17]))
+	return b
+}
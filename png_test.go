@@ -0,0 +1,83 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image/png"
+	"testing"
+)
+
+// TestGenerateToPNGDPI verifies that GenerateTo writes a valid pHYs chunk
+// immediately after the IHDR chunk of a PNG image, when DPI is set.
+func TestGenerateToPNGDPI(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	if err := GenerateTo(buf, FormatPNG, bytes.NewReader(wavFile), DPI(300, 150)); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	// 300 DPI is 11811 pixels per meter, and 150 DPI is 5906 pixels per meter
+	testPNGPhys(t, b, 11811, 5906)
+
+	// Image must be unchanged
+	got, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Generate(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testImagesEqual(t, got, want)
+}
+
+// TestPNGPhysWriterShortWrites verifies that pngPhysWriter inserts the pHYs
+// chunk correctly, even if the PNG header is written one byte at a time.
+func TestPNGPhysWriterShortWrites(t *testing.T) {
+	in := bytes.NewBuffer(nil)
+	if err := GenerateTo(in, FormatPNG, bytes.NewReader(wavFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	w := newPNGPhysWriter(out, 72, 72)
+	for _, c := range in.Bytes() {
+		if _, err := w.Write([]byte{c}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testPNGPhys(t, out.Bytes(), 2835, 2835)
+	if _, err := png.Decode(out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testPNGPhys is a test helper which verifies that a PNG stream contains a
+// pHYs chunk with the input pixels per meter, directly after its IHDR chunk.
+func testPNGPhys(t *testing.T, b []byte, x uint32, y uint32) {
+	if len(b) < pngHeaderLen+21 {
+		t.Fatalf("PNG stream too short: %d bytes", len(b))
+	}
+
+	chunk := b[pngHeaderLen : pngHeaderLen+21]
+	if n := binary.BigEndian.Uint32(chunk[0:4]); n != 9 || string(chunk[4:8]) != "pHYs" {
+		t.Fatalf("unexpected chunk after IHDR: %q, length %d", chunk[4:8], n)
+	}
+
+	if px := binary.BigEndian.Uint32(chunk[8:12]); px != x {
+		t.Fatalf("unexpected X pixels per meter: %v != %v", px, x)
+	}
+	if py := binary.BigEndian.Uint32(chunk[12:16]); py != y {
+		t.Fatalf("unexpected Y pixels per meter: %v != %v", py, y)
+	}
+	if chunk[16] != 1 {
+		t.Fatalf("unexpected unit specifier: %v != %v", chunk[16], 1)
+	}
+
+	if crc := binary.BigEndian.Uint32(chunk[17:21]); crc != crc32.ChecksumIEEE(chunk[4:17]) {
+		t.Fatalf("unexpected pHYs checksum: %#x", crc)
+	}
+}
@@ -0,0 +1,123 @@
+package waveform
+
+import (
+	"bufio"
+	"io"
+)
+
+// ProgressFunc is a function which receives the progress of computing values
+// from an input audio stream, as a fraction in the range [0, 1].  A fraction
+// of -1 indicates that the total length of the stream is unknown.
+//
+// A ProgressFunc is always called synchronously, from the goroutine which
+// computes values, and is never called concurrently.
+type ProgressFunc func(fraction float64)
+
+// ProgressTimeFunc is a function which receives the progress of computing
+// values from an input audio stream, as the number of seconds of audio
+// decoded so far, and the estimated total number of seconds of audio in the
+// stream.  A total of -1 indicates that the total length of the stream is
+// unknown.
+//
+// A ProgressTimeFunc is always called synchronously, from the goroutine which
+// computes values, and is never called concurrently.
+type ProgressTimeFunc func(done float64, total float64)
+
+// progressReader is an io.Reader which counts the number of bytes read from
+// an input audio stream, so that progress can be reported as a fraction of
+// the stream's total size.
+type progressReader struct {
+	r io.Reader
+	n int64
+
+	// size is the total size of the stream, or -1 if unknown
+	size int64
+}
+
+// newProgressReader wraps an input audio stream in a progressReader,
+// determining the remaining size of the stream if it is an io.Seeker.
+func newProgressReader(r io.Reader) *progressReader {
+	return &progressReader{
+		r:    r,
+		size: streamSize(r),
+	}
+}
+
+// Read implements io.Reader, counting all bytes read.
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	return n, err
+}
+
+// fraction returns the fraction of the stream consumed by a decoder which
+// reads from br, excluding any bytes which are buffered but not yet consumed.
+// If the size of the stream is unknown, -1 is returned.
+func (p *progressReader) fraction(br *bufio.Reader) float64 {
+	if p.size <= 0 {
+		return -1
+	}
+
+	f := float64(p.n-int64(br.Buffered())) / float64(p.size)
+	if f < 0 {
+		return 0
+	}
+	if f > 1 {
+		return 1
+	}
+
+	return f
+}
+
+// streamSize returns the number of bytes remaining in an input stream, if it
+// is an io.Seeker.  The current position of the stream is preserved.  If the
+// size cannot be determined, -1 is returned.
+func streamSize(r io.Reader) int64 {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return -1
+	}
+
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	if _, err := s.Seek(cur, io.SeekStart); err != nil {
+		return -1
+	}
+
+	return end - cur
+}
+
+// reportsProgress reports whether a ProgressFunc or ProgressTimeFunc is set.
+func (w *Waveform) reportsProgress() bool {
+	return w.progressFn != nil || w.progressTimeFn != nil
+}
+
+// reportProgress passes the fraction of the input stream consumed so far to
+// any ProgressFunc, and the number of seconds of audio decoded in the input
+// number of frames, at the input sample rate, to any ProgressTimeFunc.  The
+// total number of seconds is estimated from the fraction.
+func (w *Waveform) reportProgress(fraction float64, frames int64, sampleRate int) {
+	if w.progressFn != nil {
+		w.progressFn(fraction)
+	}
+	if w.progressTimeFn == nil {
+		return
+	}
+
+	done := float64(frames) / float64(sampleRate)
+	total := -1.0
+	switch {
+	case fraction >= 1:
+		total = done
+	case fraction > 0:
+		total = done / fraction
+	}
+
+	w.progressTimeFn(done, total)
+}
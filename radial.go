@@ -0,0 +1,84 @@
+package waveform
+
+import (
+	"image"
+	"io"
+	"math"
+)
+
+// GenerateRadial immediately opens and reads an input audio stream, computes
+// the values required for waveform generation, and returns a radial waveform
+// image which is customized by zero or more, variadic, OptionsFunc
+// parameters.
+//
+// GenerateRadial is equivalent to New and Compute, followed by the DrawRadial
+// method of a Waveform struct, and handles errors in the same way as
+// Generate.
+func GenerateRadial(r io.Reader, options ...OptionsFunc) (image.Image, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		// Draw any partial results, if requested
+		if w.partialOnError && len(values) > 0 {
+			return w.DrawRadial(values), err
+		}
+
+		return nil, err
+	}
+
+	return w.DrawRadial(values), nil
+}
+
+// DrawRadial creates a new image.Image from a slice of float64 values, in
+// which the waveform is wrapped around a circle.
+//
+// The waveform created by Draw is wrapped clockwise around a ring, whose
+// inner radius and starting angle are set by the Radial option, so that the
+// bottom edge of the waveform lies on the inner circle, and its top edge on
+// the outer circle.  The ring is as wide as the waveform is high, and the
+// square image is just large enough to contain it.  All drawing options apply
+// to the waveform, except for padding, and any area outside of the ring is
+// filled using the background ColorFunc.
+func (w *Waveform) DrawRadial(values []float64) image.Image {
+	l := w.newLayout(values)
+
+	// Draw the linear waveform, which is sampled for each pixel of the ring
+	linear := image.NewRGBA(l.bounds)
+	l.draw(linear)
+
+	inner := float64(w.radialInner)
+	outer := inner + float64(l.maxY)
+	size := 2 * (int(w.radialInner) + l.maxY)
+	center := float64(size) / 2
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	bg := l.color(w.bgColorFn, 0, 0, 0)
+
+	for py := 0; py < size; py++ {
+		for px := 0; px < size; px++ {
+			dx, dy := float64(px)+0.5-center, float64(py)+0.5-center
+			r := math.Hypot(dx, dy)
+			if r < inner || r >= outer || l.maxX == 0 {
+				img.Set(px, py, bg)
+				continue
+			}
+
+			// Angle clockwise from the top of the circle, less the rotation,
+			// selects the column of the waveform, and the distance from the
+			// inner circle selects the row, from the bottom of the waveform
+			angle := math.Atan2(dx, -dy)*180/math.Pi - w.radialRotation
+			angle = math.Mod(math.Mod(angle, 360)+360, 360)
+
+			x := minInt(int(angle/360*float64(l.maxX)), l.maxX-1)
+			y := l.maxY - 1 - int(r-inner)
+
+			img.Set(px, py, linear.At(x+l.offset.X, y+l.offset.Y))
+		}
+	}
+
+	return img
+}
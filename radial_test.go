@@ -0,0 +1,64 @@
+package waveform
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+)
+
+// TestWaveformDrawRadial verifies that the Waveform.DrawRadial method wraps
+// the waveform clockwise around a ring, beginning at the angle set by the
+// Radial option.
+func TestWaveformDrawRadial(t *testing.T) {
+	var tests = []struct {
+		rotation float64
+		right    color.Color
+		left     color.Color
+	}{
+		{rotation: 0, right: white, left: black},
+		{rotation: 180, right: black, left: white},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, Radial(10, test.rotation), Height(20), Sharpness(0))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		// The first value is silent, and the second is full scale
+		img := w.DrawRadial([]float64{0, 1})
+		if b := img.Bounds(); b.Dx() != 60 || b.Dy() != 60 {
+			t.Fatalf("[%02d] unexpected image bounds: %v", i, b)
+		}
+
+		// Points in the middle of the ring, on the right and left of the
+		// circle, and points inside and outside of the ring
+		for _, p := range []struct {
+			x, y int
+			want color.Color
+		}{
+			{50, 29, test.right},
+			{9, 29, test.left},
+			{30, 30, white},
+			{0, 0, white},
+		} {
+			if c := img.At(p.x, p.y); c != p.want {
+				t.Fatalf("[%02d] unexpected color at (%d, %d): %v != %v", i, p.x, p.y, c, p.want)
+			}
+		}
+	}
+}
+
+// TestGenerateRadial verifies that GenerateRadial draws a radial waveform
+// image from an input audio stream.
+func TestGenerateRadial(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	img, err := GenerateRadial(bytes.NewReader(wav), Resolution(2), Radial(32, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b.Dx() != 2*(32+imgYDefault) || b.Dy() != b.Dx() {
+		t.Fatalf("unexpected image bounds: %v", b)
+	}
+}
@@ -0,0 +1,123 @@
+package waveform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"azul3d.org/engine/audio"
+)
+
+// rawPCMFormat describes the samples of a raw PCM stream, as set by the RawPCM
+// option.
+type rawPCMFormat struct {
+	sampleRate int
+	bits       uint
+	channels   int
+	order      binary.ByteOrder
+}
+
+// rawDecoder is an audio.Decoder which decodes a stream of raw, interleaved,
+// signed integer PCM samples, with no header.
+type rawDecoder struct {
+	r      io.Reader
+	format rawPCMFormat
+
+	// Size in bytes of a single sample
+	size int
+
+	buf []byte
+}
+
+// newRawDecoder returns a decoder which treats the entire input stream as raw
+// PCM samples in the input format.
+func newRawDecoder(r io.Reader, f rawPCMFormat) *rawDecoder {
+	return &rawDecoder{
+		r:      r,
+		format: f,
+		size:   int(f.bits / 8),
+	}
+}
+
+// Config returns the audio configuration of the raw PCM stream.
+func (d *rawDecoder) Config() audio.Config {
+	return audio.Config{
+		SampleRate: d.format.sampleRate,
+		Channels:   d.format.channels,
+	}
+}
+
+// bitDepth returns the bit depth of the raw PCM stream.
+func (d *rawDecoder) bitDepth() int {
+	return int(d.format.bits)
+}
+
+// Read decodes samples into b, returning the number of samples read.  When the
+// stream ends, audio.EOS is returned along with any remaining samples.  A
+// trailing, incomplete sample is discarded.
+func (d *rawDecoder) Read(b audio.Slice) (int, error) {
+	need := b.Len() * d.size
+	if cap(d.buf) < need {
+		d.buf = make([]byte, need)
+	}
+	buf := d.buf[:need]
+
+	read, err := io.ReadFull(d.r, buf)
+
+	n := read / d.size
+	for i := 0; i < n; i++ {
+		b.Set(i, rawSample(buf[i*d.size:], d.format.bits, d.format.order))
+	}
+
+	switch err {
+	case nil:
+		return n, nil
+	case io.EOF, io.ErrUnexpectedEOF:
+		return n, audio.EOS
+	default:
+		return n, err
+	}
+}
+
+// rawSample converts a single signed integer PCM sample with the input bit
+// depth and byte order to a float64 value in [-1, 1].
+func rawSample(b []byte, bits uint, order binary.ByteOrder) float64 {
+	switch bits {
+	case 8:
+		return float64(int8(b[0])) / (1 << 7)
+	case 16:
+		return float64(int16(order.Uint16(b))) / (1 << 15)
+	case 24:
+		// Sign extend from the most significant byte
+		var v int32
+		if order == binary.BigEndian {
+			v = int32(int8(b[0]))<<16 | int32(b[1])<<8 | int32(b[2])
+		} else {
+			v = int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		}
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(order.Uint32(b))) / (1 << 31)
+	}
+}
+
+// openDecoder opens an audio decoder on an input stream, treating it as raw
+// PCM samples if the RawPCM option is set, decoding the selected audio track
+// if the TrackIndex option is set, and otherwise detecting its format.  Streams
+// of an unknown format are decoded by the external decoder set by the
+// ExecDecoder option, if any.
+func (w *Waveform) openDecoder(br *bufio.Reader) (audio.Decoder, error) {
+	if w.rawPCM != nil {
+		return newRawDecoder(br, *w.rawPCM), nil
+	}
+	if w.trackIndex > 0 {
+		return openTrackDecoder(br, w.trackIndex)
+	}
+
+	decoder, err := openDecoder(br)
+	if err == ErrFormat && w.execCmd != nil {
+		return newExecDecoder(br, *w.execCmd)
+	}
+
+	return decoder, err
+}
@@ -0,0 +1,108 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// TestRawSample verifies that rawSample converts signed integer PCM samples of
+// each bit depth and byte order to float64 values.
+func TestRawSample(t *testing.T) {
+	var tests = []struct {
+		b     []byte
+		bits  uint
+		order binary.ByteOrder
+		v     float64
+	}{
+		{[]byte{0x80}, 8, binary.LittleEndian, -1},
+		{[]byte{0x40}, 8, binary.BigEndian, 0.5},
+		{[]byte{0x00, 0x40}, 16, binary.LittleEndian, 0.5},
+		{[]byte{0x40, 0x00}, 16, binary.BigEndian, 0.5},
+		{[]byte{0x00, 0x00, 0xc0}, 24, binary.LittleEndian, -0.5},
+		{[]byte{0xc0, 0x00, 0x00}, 24, binary.BigEndian, -0.5},
+		{[]byte{0x00, 0x00, 0x00, 0x80}, 32, binary.LittleEndian, -1},
+		{[]byte{0x80, 0x00, 0x00, 0x00}, 32, binary.BigEndian, -1},
+	}
+
+	for i, test := range tests {
+		if v := rawSample(test.b, test.bits, test.order); v != test.v {
+			t.Fatalf("[%02d] unexpected sample: %v != %v", i, v, test.v)
+		}
+	}
+}
+
+// TestWaveformComputeRawPCM verifies that the Waveform.Compute method produces
+// the same values from the raw samples of a WAV stream as from the WAV stream
+// itself, in either byte order, and discards a trailing, incomplete sample.
+func TestWaveformComputeRawPCM(t *testing.T) {
+	want, err := testComputeValues(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(wavFile)
+	f, size, err := readWAVHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := len(wavFile) - r.Len()
+	little := wavFile[offset : offset+int(size)]
+
+	big := make([]byte, len(little))
+	for i := 0; i+1 < len(little); i += 2 {
+		big[i], big[i+1] = little[i+1], little[i]
+	}
+
+	var tests = []struct {
+		data  []byte
+		order binary.ByteOrder
+	}{
+		{little, binary.LittleEndian},
+		{big, binary.BigEndian},
+		{append(append([]byte(nil), little...), 0x7f), binary.LittleEndian},
+	}
+
+	for i, test := range tests {
+		got, err := testComputeValues(bytes.NewReader(test.data),
+			RawPCM(int(f.sampleRate), uint(f.bits), int(f.channels), test.order),
+		)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("[%02d] unexpected Compute values length: %v != %v", i, len(got), len(want))
+		}
+		for j := range want {
+			// The audio package scales 16-bit samples by a slightly different
+			// factor than this package
+			if math.Abs(got[j]-want[j]) > 1e-4 {
+				t.Fatalf("[%02d] unexpected Compute value at index %d: %v != %v", i, j, got[j], want[j])
+			}
+		}
+	}
+}
+
+// TestWaveformComputeRawPCMSkipsDetection verifies that the Waveform.Compute
+// method does not detect the format of a raw PCM stream, even if it begins
+// with the magic string of another format.
+func TestWaveformComputeRawPCMSkipsDetection(t *testing.T) {
+	values, err := testComputeValues(bytes.NewReader([]byte("fLaC")), RawPCM(4, 8, 1, binary.LittleEndian))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 1 {
+		t.Fatalf("unexpected Compute values length: %v != %v", len(values), 1)
+	}
+}
+
+// TestWaveformComputeRawPCMErrNoSamples verifies that the Waveform.Compute
+// method returns ErrNoSamples for an empty raw PCM stream.
+func TestWaveformComputeRawPCMErrNoSamples(t *testing.T) {
+	testWaveformCompute(t, bytes.NewReader(nil), ErrNoSamples, nil, []OptionsFunc{
+		RawPCM(44100, 16, 2, binary.LittleEndian),
+	})
+}
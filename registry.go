@@ -0,0 +1,93 @@
+package waveform
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"azul3d.org/engine/audio"
+)
+
+// FormatCustom is the audio format identifier returned by DetectFormat for
+// streams which are decoded by a decoder registered using RegisterDecoder.
+const FormatCustom = "custom"
+
+// A DecoderFunc is a function which opens an audio decoder on an input audio
+// stream, such as a decoder for a proprietary codec.  The stream begins with
+// the magic string used to register the function.
+//
+// If the stream is invalid, a DecoderFunc should return ErrInvalidData,
+// ErrUnexpectedEOS, or any other error, which is returned by Generate.
+type DecoderFunc func(r io.Reader) (audio.Decoder, error)
+
+// registeredDecoder is an audio decoder registered using RegisterDecoder.
+type registeredDecoder struct {
+	magic []byte
+	fn    DecoderFunc
+}
+
+var (
+	// registryMu guards registry
+	registryMu sync.RWMutex
+
+	// registry is the set of registered decoders, in order of registration
+	registry []registeredDecoder
+)
+
+// RegisterDecoder registers an audio decoder for streams which begin with the
+// input magic string, so that applications can decode additional formats
+// without modifying this package.  It is typically called from an init
+// function.  RegisterDecoder panics if magic is empty or fn is nil.
+//
+// Each byte of magic must match exactly.  When the format of a stream is
+// detected, registered decoders are checked in the order in which they were
+// registered, and take priority over all formats built into this package,
+// so a registered decoder may also replace a built-in decoder.  The first
+// matching decoder is used, and if no decoder matches, ErrFormat is returned.
+func RegisterDecoder(magic []byte, fn DecoderFunc) {
+	if len(magic) == 0 {
+		panic("waveform: RegisterDecoder magic is empty")
+	}
+	if fn == nil {
+		panic("waveform: RegisterDecoder function is nil")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = append(registry, registeredDecoder{
+		magic: append([]byte(nil), magic...),
+		fn:    fn,
+	})
+}
+
+// findRegisteredDecoder returns the first registered decoder whose magic
+// string begins the input header, or nil if none matches.
+func findRegisteredDecoder(header []byte) DecoderFunc {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, d := range registry {
+		if bytes.HasPrefix(header, d.magic) {
+			return d.fn
+		}
+	}
+
+	return nil
+}
+
+// maxRegisteredMagicLen returns the length of the longest magic string of all
+// registered decoders.
+func maxRegisteredMagicLen() int {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var n int
+	for _, d := range registry {
+		if len(d.magic) > n {
+			n = len(d.magic)
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,163 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// testRegistryMagic is the magic string of a test audio format, which is
+// followed by one byte per sample, at 2Hz.
+const testRegistryMagic = "WFTEST"
+
+// TestRegisterDecoderCompute verifies that a registered decoder is used to
+// decode streams which begin with its magic string.
+func TestRegisterDecoderCompute(t *testing.T) {
+	defer testResetRegistry()()
+
+	RegisterDecoder([]byte(testRegistryMagic), testRegistryDecoder)
+
+	data := append([]byte(testRegistryMagic), 128, 128, 64, 64)
+	testWaveformCompute(t, bytes.NewReader(data), nil, []float64{0.5, 0.25}, nil)
+}
+
+// TestRegisterDecoderPriority verifies that registered decoders are checked in
+// order of registration, and before all built-in formats.
+func TestRegisterDecoderPriority(t *testing.T) {
+	defer testResetRegistry()()
+
+	var calls []string
+	register := func(magic string, name string) {
+		RegisterDecoder([]byte(magic), func(r io.Reader) (audio.Decoder, error) {
+			calls = append(calls, name)
+			return newSamplesDecoder([]float64{1}, 1, 1), nil
+		})
+	}
+
+	register("RIFF", "first")
+	register("RIFF", "second")
+
+	w, err := New(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []float64{1}; len(values) != len(want) || values[0] != want[0] {
+		t.Fatalf("unexpected Compute values: %v != %v", values, want)
+	}
+	if len(calls) != 1 || calls[0] != "first" {
+		t.Fatalf("unexpected registered decoder calls: %v", calls)
+	}
+}
+
+// TestRegisterDecoderErrFormat verifies that streams which match no registered
+// decoder or built-in format still return ErrFormat.
+func TestRegisterDecoderErrFormat(t *testing.T) {
+	defer testResetRegistry()()
+
+	RegisterDecoder([]byte(testRegistryMagic), testRegistryDecoder)
+
+	testWaveformCompute(t, bytes.NewReader([]byte("WFOTHER")), ErrFormat, nil, nil)
+}
+
+// TestRegisterDecoderDetectFormat verifies that DetectFormat reports streams
+// matched by a registered decoder as FormatCustom, without consuming them.
+func TestRegisterDecoderDetectFormat(t *testing.T) {
+	defer testResetRegistry()()
+
+	// A magic string longer than all built-in formats must be peeked entirely
+	magic := bytes.Repeat([]byte{'W'}, maxMagicLen()+1)
+	RegisterDecoder(magic, testRegistryDecoder)
+
+	r := bufio.NewReader(bytes.NewReader(magic))
+	format, err := DetectFormat(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != FormatCustom {
+		t.Fatalf("unexpected DetectFormat format: %q != %q", format, FormatCustom)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, magic) {
+		t.Fatalf("stream consumed by DetectFormat: %v != %v bytes", len(data), len(magic))
+	}
+
+	// Magic strings do not match a partial header
+	if _, err := DetectFormat(bytes.NewReader(magic[:len(magic)-1])); err != ErrFormat {
+		t.Fatalf("unexpected DetectFormat error: %v != %v", err, ErrFormat)
+	}
+}
+
+// TestRegisterDecoderPanics verifies that RegisterDecoder panics when its
+// magic string is empty or its function is nil.
+func TestRegisterDecoderPanics(t *testing.T) {
+	defer testResetRegistry()()
+
+	var tests = []struct {
+		magic []byte
+		fn    DecoderFunc
+	}{
+		{nil, testRegistryDecoder},
+		{[]byte{}, testRegistryDecoder},
+		{[]byte(testRegistryMagic), nil},
+	}
+
+	for i, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("[%02d] RegisterDecoder did not panic", i)
+				}
+			}()
+
+			RegisterDecoder(test.magic, test.fn)
+		}()
+	}
+
+	if n := maxRegisteredMagicLen(); n != 0 {
+		t.Fatalf("invalid decoder registered, magic length: %v", n)
+	}
+}
+
+// testRegistryDecoder is a DecoderFunc for the test audio format, which
+// decodes each byte following the magic string as a sample from 0 to 1.
+func testRegistryDecoder(r io.Reader) (audio.Decoder, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]float64, 0, len(data))
+	for _, b := range data[len(testRegistryMagic):] {
+		samples = append(samples, float64(b)/256)
+	}
+
+	return newSamplesDecoder(samples, 2, 1), nil
+}
+
+// testResetRegistry is a test helper which removes all registered decoders
+// until the returned function is called, which restores them.
+func testResetRegistry() func() {
+	registryMu.Lock()
+	saved := registry
+	registry = nil
+	registryMu.Unlock()
+
+	return func() {
+		registryMu.Lock()
+		registry = saved
+		registryMu.Unlock()
+	}
+}
@@ -0,0 +1,159 @@
+package waveform
+
+import "azul3d.org/engine/audio"
+
+// resampleChunkFrames is the number of frames read from the source decoder at
+// once by a resampleDecoder.
+const resampleChunkFrames = 4096
+
+// resampleDecoder is an audio.Decoder which resamples the samples of another
+// decoder to a target sample rate, using linear interpolation between the two
+// nearest frames of the source.
+//
+// Linear interpolation is fast and has no latency, but it does not filter
+// frequencies above the target rate's Nyquist frequency, so downsampling may
+// introduce some aliasing.  This has little effect on the computed values used
+// to draw a waveform, which measure magnitude over many samples.
+type resampleDecoder struct {
+	src    audio.Decoder
+	config audio.Config
+
+	// Source sample rate, so that frame positions are computed exactly
+	srcRate int64
+
+	// Decoded source samples which may still be interpolated, and the index
+	// of the first frame among them
+	frames []float64
+	first  int64
+	chunk  audio.Float64
+	eos    bool
+
+	// Index of the next output sample
+	next int64
+}
+
+// newResampleDecoder creates a resampleDecoder which resamples the samples of
+// the source decoder to the input sample rate.
+func newResampleDecoder(src audio.Decoder, sampleRate int) *resampleDecoder {
+	config := src.Config()
+
+	return &resampleDecoder{
+		src: src,
+		config: audio.Config{
+			SampleRate: sampleRate,
+			Channels:   config.Channels,
+		},
+		srcRate: int64(config.SampleRate),
+		chunk:   make(audio.Float64, resampleChunkFrames*config.Channels),
+	}
+}
+
+// Config returns the audio configuration of the resampled stream.
+func (d *resampleDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read decodes and resamples samples into b, returning the number of samples
+// read.  When the source stream ends, audio.EOS is returned along with any
+// remaining samples.
+func (d *resampleDecoder) Read(b audio.Slice) (int, error) {
+	channels := int64(d.config.Channels)
+
+	var n int
+	for n < b.Len() {
+		// Decode until both frames used for interpolation are available
+		i, f := d.position()
+		if err := d.decode(i + 1); err != nil {
+			return n, err
+		}
+
+		// No frames remain to interpolate from
+		if i >= d.available() {
+			return n, audio.EOS
+		}
+
+		channel := d.next % channels
+
+		// Interpolate between the two nearest frames, holding the final frame
+		// at the end of the stream
+		v := d.sample(i, channel)
+		if i+1 < d.available() {
+			v += (d.sample(i+1, channel) - v) * f
+		}
+
+		b.Set(n, v)
+		n++
+		d.next++
+
+		// Discard frames which can no longer be used
+		d.discard(i)
+	}
+
+	// Report the end of the stream along with the final samples, if no frames
+	// remain for the next sample
+	i, _ := d.position()
+	if err := d.decode(i); err != nil {
+		return n, err
+	}
+	if i >= d.available() {
+		return n, audio.EOS
+	}
+
+	return n, nil
+}
+
+// position returns the position of the next output frame within the source,
+// as a frame index and a fraction of the distance to the following frame.
+func (d *resampleDecoder) position() (int64, float64) {
+	rate := int64(d.config.SampleRate)
+	pos := (d.next / int64(d.config.Channels)) * d.srcRate
+
+	return pos / rate, float64(pos%rate) / float64(rate)
+}
+
+// decode decodes samples from the source decoder until the frame at index i
+// is available, or the source stream ends.
+func (d *resampleDecoder) decode(i int64) error {
+	for !d.eos && d.available() <= i {
+		if err := d.fill(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// available returns the index of the frame following all decoded frames.
+func (d *resampleDecoder) available() int64 {
+	return d.first + int64(len(d.frames))/int64(d.config.Channels)
+}
+
+// sample returns the sample for the input channel of the decoded frame at
+// index i.
+func (d *resampleDecoder) sample(i int64, channel int64) float64 {
+	return d.frames[(i-d.first)*int64(d.config.Channels)+channel]
+}
+
+// fill decodes the next chunk of samples from the source decoder.
+func (d *resampleDecoder) fill() error {
+	n, err := d.src.Read(d.chunk)
+	d.frames = append(d.frames, d.chunk[:n]...)
+
+	if err == audio.EOS {
+		d.eos = true
+		return nil
+	}
+
+	return err
+}
+
+// discard removes all decoded frames before the frame at index i.
+func (d *resampleDecoder) discard(i int64) {
+	channels := int64(d.config.Channels)
+
+	// Only copy once enough frames are unused, to avoid copying on every call
+	if unused := i - d.first; unused > resampleChunkFrames {
+		d.frames = append(d.frames[:0], d.frames[unused*channels:]...)
+		d.first = i
+	}
+}
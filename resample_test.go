@@ -0,0 +1,98 @@
+package waveform
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestResampleDecoderRead verifies that resampleDecoder linearly interpolates
+// between frames of each channel, holding the final frame at the end of the
+// stream.
+func TestResampleDecoderRead(t *testing.T) {
+	var tests = []struct {
+		in         []float64
+		sampleRate int
+		channels   int
+		target     int
+		out        []float64
+	}{
+		// Upsample mono
+		{[]float64{0, 1, 2}, 2, 1, 4, []float64{0, 0.5, 1, 1.5, 2, 2}},
+		// Downsample mono
+		{[]float64{0, 1, 2, 3, 4, 5}, 4, 1, 2, []float64{0, 2, 4}},
+		// Upsample stereo, with independent channels
+		{[]float64{0, 0, 1, -1}, 1, 2, 2, []float64{0, 0, 0.5, -0.5, 1, -1, 1, -1}},
+	}
+
+	for i, test := range tests {
+		d := newResampleDecoder(newSamplesDecoder(test.in, test.sampleRate, test.channels), test.target)
+		if c := d.Config(); c.SampleRate != test.target || c.Channels != test.channels {
+			t.Fatalf("[%02d] unexpected config: %v", i, c)
+		}
+
+		// Read one sample at a time, to verify state is kept between reads
+		var out []float64
+		b := make(audio.Float64, 1)
+		for {
+			n, err := d.Read(b)
+			out = append(out, b[:n]...)
+			if err == audio.EOS {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if len(out) != len(test.out) {
+			t.Fatalf("[%02d] unexpected samples length: %v != %v [%v != %v]", i, len(out), len(test.out), out, test.out)
+		}
+		for j := range out {
+			if math.Abs(out[j]-test.out[j]) > 1e-9 {
+				t.Fatalf("[%02d] unexpected samples: %v != %v", i, out, test.out)
+			}
+		}
+	}
+}
+
+// TestGenerateFromSamplesResample verifies that the Resample option produces the
+// same number of values per second of audio, regardless of input sample rate.
+func TestGenerateFromSamplesResample(t *testing.T) {
+	// 3 seconds of silence
+	samples := make([]float64, 300)
+
+	img, err := GenerateFromSamples(samples, 100, 1, Resample(44100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxX := img.Bounds().Max.X; maxX != 3 {
+		t.Fatalf("unexpected image width: %v != %v", maxX, 3)
+	}
+}
+
+// TestGenerateMultiResample verifies that GenerateMulti accepts streams with
+// different sample rates when the Resample option is set.
+func TestGenerateMultiResample(t *testing.T) {
+	readers := func() []io.Reader {
+		return []io.Reader{
+			bytes.NewReader(testWAV(1, 1, 100, 8, make([]byte, 100))),
+			bytes.NewReader(testWAV(1, 1, 200, 8, make([]byte, 200))),
+		}
+	}
+
+	if _, err := GenerateMulti(readers()); err != ErrSegmentMismatch {
+		t.Fatalf("unexpected GenerateMulti error: %v != %v", err, ErrSegmentMismatch)
+	}
+
+	img, err := GenerateMulti(readers(), Resample(200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxX := img.Bounds().Max.X; maxX != 2 {
+		t.Fatalf("unexpected image width: %v != %v", maxX, 2)
+	}
+}
@@ -2,6 +2,7 @@ package waveform
 
 import (
 	"math"
+	"sort"
 
 	"azul3d.org/engine/audio"
 )
@@ -25,3 +26,110 @@ func RMSF64Samples(samples audio.Float64) float64 {
 	// Multiply squared sum by length of samples slice, return square root
 	return math.Sqrt(sumSquare / float64(samples.Len()))
 }
+
+// PeakF64Samples is a SampleReduceFunc which calculates the peak magnitude
+// of a slice of float64 audio samples, by finding the largest absolute value
+// over the entire set of samples.
+func PeakF64Samples(samples audio.Float64) float64 {
+	// Find the largest absolute sample value
+	var peak float64
+	for i := range samples {
+		if v := math.Abs(samples.At(i)); v > peak {
+			peak = v
+		}
+	}
+
+	return peak
+}
+
+// MeanF64Samples is a SampleReduceFunc which calculates the mean magnitude of
+// a slice of float64 audio samples, by averaging the absolute value of every
+// sample.  An empty slice produces 0.
+func MeanF64Samples(samples audio.Float64) float64 {
+	if samples.Len() == 0 {
+		return 0
+	}
+
+	// Sum the absolute value of all input samples
+	var sum float64
+	for i := range samples {
+		sum += math.Abs(samples.At(i))
+	}
+
+	return sum / float64(samples.Len())
+}
+
+// MedianF64Samples is a SampleReduceFunc which calculates the median magnitude
+// of a slice of float64 audio samples, by finding the middle absolute value of
+// the sorted samples, or the mean of the two middle values if the number of
+// samples is even.  Unlike MeanF64Samples, the result is not affected by brief
+// transients.  An empty slice produces 0.
+func MedianF64Samples(samples audio.Float64) float64 {
+	if samples.Len() == 0 {
+		return 0
+	}
+
+	// Sort the absolute values of a copy of the input samples
+	sorted := make([]float64, samples.Len())
+	for i := range sorted {
+		sorted[i] = math.Abs(samples.At(i))
+	}
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	return sorted[mid]
+}
+
+// MinF64Samples is a SampleReduceFunc which finds the minimum signed value of a
+// slice of float64 audio samples.  An empty slice produces 0.
+func MinF64Samples(samples audio.Float64) float64 {
+	var min float64
+	for i := range samples {
+		if v := samples.At(i); i == 0 || v < min {
+			min = v
+		}
+	}
+
+	return min
+}
+
+// MaxF64Samples is a SampleReduceFunc which finds the maximum signed value of a
+// slice of float64 audio samples.  An empty slice produces 0.
+func MaxF64Samples(samples audio.Float64) float64 {
+	var max float64
+	for i := range samples {
+		if v := samples.At(i); i == 0 || v > max {
+			max = v
+		}
+	}
+
+	return max
+}
+
+// PeakHold generates a SampleReduceFunc which calculates the peak magnitude of
+// each slice of float64 audio samples, and holds the largest peak across
+// subsequent slices while it decays, mimicking an analog peak-hold meter.
+// This causes a sharp transient to visibly "ring" for several columns of the
+// resulting waveform image, making percussion more legible.
+//
+// On each call, the held peak is reduced by the fraction decay, and replaced
+// by the peak of the current slice if that peak is larger.  A decay of 1 holds
+// no peaks, producing the same values as PeakF64Samples, and a decay of 0
+// holds the largest peak forever.  Decay values outside of [0, 1] are clamped.
+//
+// The generated SampleReduceFunc retains the held peak between calls, so a new
+// SampleReduceFunc must be generated for each computation, and it must not be
+// shared by multiple goroutines.
+func PeakHold(decay float64) SampleReduceFunc {
+	decay = math.Min(math.Max(decay, 0), 1)
+
+	var held float64
+	return func(samples audio.Float64) float64 {
+		held = math.Max(PeakF64Samples(samples), held*(1-decay))
+		return held
+	}
+}
@@ -41,3 +41,158 @@ func TestRMSF64Samples(t *testing.T) {
 		}
 	}
 }
+
+// TestPeakF64Samples verifies that PeakF64Samples computes correct results
+func TestPeakF64Samples(t *testing.T) {
+	var tests = []struct {
+		samples audio.Float64
+		result  float64
+	}{
+		// Empty samples
+		{audio.Float64{}, 0.00},
+		// Negative samples
+		{audio.Float64{-0.10}, 0.10},
+		{audio.Float64{-0.10, -0.20, -0.30, -0.40, -0.50}, 0.50},
+		// Positive samples
+		{audio.Float64{0.10}, 0.10},
+		{audio.Float64{0.10, 0.20, 0.30, 0.40, 0.50}, 0.50},
+		// Mixed samples
+		{audio.Float64{0.10, -0.20, 0.30, -0.40, 0.05}, 0.40},
+	}
+
+	for i, test := range tests {
+		if peak := PeakF64Samples(test.samples); peak != test.result {
+			t.Fatalf("[%02d] unexpected result: %v != %v", i, peak, test.result)
+		}
+	}
+}
+
+// TestMeanF64Samples verifies that MeanF64Samples computes correct results
+func TestMeanF64Samples(t *testing.T) {
+	var tests = []struct {
+		samples audio.Float64
+		result  float64
+	}{
+		// Empty samples
+		{audio.Float64{}, 0.00},
+		// Negative samples
+		{audio.Float64{-0.25}, 0.25},
+		{audio.Float64{-0.25, -0.50, -0.75}, 0.50},
+		// Positive samples
+		{audio.Float64{0.25, 0.50, 0.75}, 0.50},
+		// Mixed samples
+		{audio.Float64{0.25, -0.50, 0.125, -0.125}, 0.25},
+	}
+
+	for i, test := range tests {
+		if mean := MeanF64Samples(test.samples); mean != test.result {
+			t.Fatalf("[%02d] unexpected result: %v != %v", i, mean, test.result)
+		}
+	}
+}
+
+// TestMedianF64Samples verifies that MedianF64Samples computes correct results
+func TestMedianF64Samples(t *testing.T) {
+	var tests = []struct {
+		samples audio.Float64
+		result  float64
+	}{
+		// Empty samples
+		{audio.Float64{}, 0.00},
+		// Odd number of samples
+		{audio.Float64{-0.25}, 0.25},
+		{audio.Float64{0.75, -0.25, 1.00}, 0.75},
+		// Even number of samples
+		{audio.Float64{0.25, -0.50}, 0.375},
+		{audio.Float64{1.00, 0.125, -0.25, 0.50}, 0.375},
+	}
+
+	for i, test := range tests {
+		samples := append(audio.Float64(nil), test.samples...)
+		if median := MedianF64Samples(test.samples); median != test.result {
+			t.Fatalf("[%02d] unexpected result: %v != %v", i, median, test.result)
+		}
+
+		// Input samples are not reordered
+		for j := range samples {
+			if samples[j] != test.samples[j] {
+				t.Fatalf("[%02d] input samples modified: %v != %v", i, test.samples, samples)
+			}
+		}
+	}
+}
+
+// TestMinMaxF64Samples verifies that MinF64Samples and MaxF64Samples compute
+// correct, signed results
+func TestMinMaxF64Samples(t *testing.T) {
+	var tests = []struct {
+		samples audio.Float64
+		min     float64
+		max     float64
+	}{
+		// Empty samples
+		{audio.Float64{}, 0.00, 0.00},
+		// Negative samples
+		{audio.Float64{-0.10, -0.20, -0.30, -0.40, -0.50}, -0.50, -0.10},
+		// Positive samples
+		{audio.Float64{0.10, 0.20, 0.30, 0.40, 0.50}, 0.10, 0.50},
+		// Mixed samples
+		{audio.Float64{0.10, -0.20, 0.30, -0.40, 0.05}, -0.40, 0.30},
+	}
+
+	for i, test := range tests {
+		if min := MinF64Samples(test.samples); min != test.min {
+			t.Fatalf("[%02d] unexpected minimum: %v != %v", i, min, test.min)
+		}
+		if max := MaxF64Samples(test.samples); max != test.max {
+			t.Fatalf("[%02d] unexpected maximum: %v != %v", i, max, test.max)
+		}
+	}
+}
+
+// TestPeakHold verifies that PeakHold holds a single sample spike across
+// several subsequent slices, decaying at the expected rate.
+func TestPeakHold(t *testing.T) {
+	// Six seconds of quiet audio, with a single full scale sample in the
+	// second second
+	samples := make([]float64, 600)
+	for i := range samples {
+		samples[i] = 0.1
+	}
+	samples[150] = -1
+
+	var tests = []struct {
+		decay  float64
+		values []float64
+	}{
+		// No decay holds the spike forever
+		{0, []float64{0.1, 1, 1, 1, 1, 1}},
+		// Held spike decays by half in each slice, until it is quieter than
+		// the audio
+		{0.5, []float64{0.1, 1, 0.5, 0.25, 0.125, 0.1}},
+		// Full decay is the same as PeakF64Samples
+		{1, []float64{0.1, 1, 0.1, 0.1, 0.1, 0.1}},
+		{2, []float64{0.1, 1, 0.1, 0.1, 0.1, 0.1}},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, SampleFunction(PeakHold(test.decay)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := w.computeSamples(newSamplesDecoder(samples, 100, 1), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(values) != len(test.values) {
+			t.Fatalf("[%02d] unexpected values length: %v != %v", i, len(values), len(test.values))
+		}
+		for j := range values {
+			if math.Abs(values[j]-test.values[j]) > 1e-9 {
+				t.Fatalf("[%02d] unexpected value at index %d: %v != %v", i, j, values[j], test.values[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package waveform
+
+import "azul3d.org/engine/audio"
+
+// samplesDecoder is an audio.Decoder which reads from a slice of interleaved,
+// pre-decoded audio samples.
+type samplesDecoder struct {
+	samples []float64
+	config  audio.Config
+
+	// Index of the next sample to be read
+	pos int
+}
+
+// newSamplesDecoder creates a samplesDecoder which reads the input samples,
+// using the input sample rate and number of channels.
+func newSamplesDecoder(samples []float64, sampleRate int, channels int) *samplesDecoder {
+	return &samplesDecoder{
+		samples: samples,
+		config: audio.Config{
+			SampleRate: sampleRate,
+			Channels:   channels,
+		},
+	}
+}
+
+// Config returns the audio configuration of the input samples.
+func (d *samplesDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read copies samples into b, returning the number of samples read.  When the
+// final samples are read, audio.EOS is returned along with them.
+func (d *samplesDecoder) Read(b audio.Slice) (int, error) {
+	var n int
+	for ; n < b.Len() && d.pos < len(d.samples); n++ {
+		b.Set(n, d.samples[d.pos])
+		d.pos++
+	}
+
+	if d.pos >= len(d.samples) {
+		return n, audio.EOS
+	}
+
+	return n, nil
+}
+
+// fraction returns the fraction of the input samples which have been read.
+func (d *samplesDecoder) fraction() float64 {
+	return float64(d.pos) / float64(len(d.samples))
+}
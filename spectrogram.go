@@ -0,0 +1,221 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"math/cmplx"
+
+	"azul3d.org/engine/audio"
+)
+
+const (
+	// spectrogramRange is the range in decibels, below the magnitude of a full
+	// scale sine wave, which is drawn by a spectrogram.  Quieter frequencies
+	// are drawn as silent.
+	spectrogramRange = 90
+
+	// fftSizeMin and fftSizeMax are the minimum and maximum FFT window sizes
+	// which may be set using the FFTSize option.
+	fftSizeMin = 16
+	fftSizeMax = 65536
+)
+
+// Colormap is a function which maps the normalized magnitude of a frequency,
+// from 0 (silent) to 1 (full scale), to the color drawn in a spectrogram.
+type Colormap func(v float64) color.Color
+
+// GrayColormap is a Colormap which draws silent frequencies in black and full
+// scale frequencies in white, with shades of gray in between.
+func GrayColormap(v float64) color.Color {
+	return color.Gray{Y: uint8(clampUnit(v)*0xff + 0.5)}
+}
+
+// HeatColormap is a Colormap which draws silent frequencies in black, blending
+// through red and yellow as magnitude increases, and full scale frequencies in
+// white.
+func HeatColormap(v float64) color.Color {
+	v = clampUnit(v) * 3
+	c := func(x float64) uint8 {
+		return uint8(clampUnit(x)*0xff + 0.5)
+	}
+
+	return color.RGBA{c(v), c(v - 1), c(v - 2), 0xff}
+}
+
+// clampUnit clamps v to the range [0, 1].
+func clampUnit(v float64) float64 {
+	return math.Min(math.Max(v, 0), 1)
+}
+
+// GenerateSpectrogram immediately opens and reads an input audio stream,
+// computes the frequency spectrum of each slice of audio, and returns a
+// spectrogram image which is customized by zero or more, variadic,
+// OptionsFunc parameters.
+//
+// GenerateSpectrogram is equivalent to New and ComputeSpectrogram, followed
+// by the DrawSpectrogram method of a Waveform struct, and handles errors in
+// the same way as Generate.
+func GenerateSpectrogram(r io.Reader, options ...OptionsFunc) (image.Image, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	spectra, err := w.ComputeSpectrogram()
+	if err != nil {
+		// Draw any partial results, if requested
+		if w.partialOnError && len(spectra) > 0 {
+			return w.DrawSpectrogram(spectra), err
+		}
+
+		return nil, err
+	}
+
+	return w.DrawSpectrogram(spectra), nil
+}
+
+// ComputeSpectrogram reads the input audio stream, and returns the frequency
+// spectrum of each slice of audio, in place of the values returned by
+// Compute.
+//
+// Audio is decoded and sliced in the same way as Compute, so the Resolution
+// option sets the number of spectra per second of audio.  Each slice is
+// divided into windows of the size set by the FFTSize option, and the
+// magnitude of each frequency is averaged over all windows in the slice.  If
+// a slice is shorter than a window, it is padded with silence.
+//
+// Each spectrum contains half as many frequency bins as the FFT size, from 0Hz
+// up to half of the sample rate, and the magnitude of each bin is normalized
+// from 0 (silent) to 1 (full scale), over a range of 90 decibels.  The
+// Separate channel mode and TrimSilence have no effect.
+func (w *Waveform) ComputeSpectrogram() ([][]float64, error) {
+	if w.fftSize == 0 {
+		return nil, errFFTSizeRange
+	}
+
+	// Compute a spectrum from each slice of samples in place of a value, using
+	// a copy of the Waveform, so that the decoding and down-mixing of Compute
+	// are reused, but no values or statistics are retained
+	var spectra [][]float64
+	c := *w
+	c.separate = false
+	c.trimThreshold = 0
+	c.valueFn = nil
+	c.sampleFn = func(samples audio.Float64) float64 {
+		spectra = append(spectra, spectrum(samples, int(w.fftSize)))
+		return 0
+	}
+
+	_, err := c.Compute()
+	return spectra, err
+}
+
+// DrawSpectrogram creates a new image.Image from a slice of spectra computed
+// by ComputeSpectrogram.
+//
+// Each spectrum is drawn as a column of the same width as a bar and the gap
+// which follows it, with frequency increasing from the bottom to the top of
+// the image, and each frequency colored by the Colormap set by the
+// SpectrogramColormap option.  The image has the same dimensions as the image
+// created by Draw for the same number of values, including any padding,
+// which is drawn using the background ColorFunc.
+func (w *Waveform) DrawSpectrogram(spectra [][]float64) image.Image {
+	l := w.newLayout(make([]float64, len(spectra)))
+
+	colormap := w.colormap
+	if colormap == nil {
+		colormap = HeatColormap
+	}
+
+	// Draw the background and any padding, and then cover the entire waveform
+	// area with the spectrogram
+	img := image.NewRGBA(l.bounds)
+	l.draw(img)
+
+	for x := 0; x < l.maxX; x++ {
+		// Spectra are stretched or squeezed to fill an explicit width
+		bins := spectra[l.column(x)*len(spectra)/l.maxN]
+		if len(bins) == 0 {
+			continue
+		}
+
+		for y := 0; y < l.maxY; y++ {
+			bin := (l.maxY - 1 - y) * len(bins) / l.maxY
+			img.Set(x+l.offset.X, y+l.offset.Y, colormap(bins[bin]))
+		}
+	}
+
+	return img
+}
+
+// spectrum returns the normalized magnitude of each frequency bin of a slice
+// of samples, averaged over consecutive windows of the input size.
+func spectrum(samples []float64, size int) []float64 {
+	power := make([]float64, size/2)
+	buf := make([]complex128, size)
+
+	var windows int
+	for start := 0; start == 0 || start+size <= len(samples); start += size {
+		// Apply a Hann window to reduce leakage between frequency bins,
+		// padding with silence past the end of the slice
+		for i := range buf {
+			var s float64
+			if start+i < len(samples) {
+				s = samples[start+i]
+			}
+
+			buf[i] = complex(s*(0.5-0.5*math.Cos(2*math.Pi*float64(i)/float64(size))), 0)
+		}
+
+		fft(buf)
+		for k := range power {
+			power[k] += real(buf[k])*real(buf[k]) + imag(buf[k])*imag(buf[k])
+		}
+		windows++
+	}
+
+	// Convert the mean power of each bin to decibels, relative to the
+	// magnitude of a full scale sine wave, which is reduced by half by the
+	// window, and split between positive and negative frequencies
+	for k := range power {
+		mag := math.Sqrt(power[k]/float64(windows)) * 4 / float64(size)
+		power[k] = clampUnit(1 + 20*math.Log10(mag)/spectrogramRange)
+	}
+
+	return power
+}
+
+// fft computes the discrete Fourier transform of x in place, using the
+// iterative radix-2 Cooley-Tukey algorithm.  The length of x must be a power
+// of two.
+func fft(x []complex128) {
+	n := len(x)
+
+	// Reorder the input by bit-reversed index
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	// Combine transforms of increasing size
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*w
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
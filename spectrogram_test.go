@@ -0,0 +1,127 @@
+package waveform
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// TestFFT verifies that fft computes the same discrete Fourier transform as a
+// direct computation.
+func TestFFT(t *testing.T) {
+	x := make([]complex128, 16)
+	for i := range x {
+		x[i] = complex(math.Sin(float64(i*i)), float64(i%3))
+	}
+
+	want := make([]complex128, len(x))
+	for k := range want {
+		for n := range x {
+			want[k] += x[n] * cmplx.Exp(complex(0, -2*math.Pi*float64(k*n)/float64(len(x))))
+		}
+	}
+
+	fft(x)
+	for k := range x {
+		if cmplx.Abs(x[k]-want[k]) > 1e-9 {
+			t.Fatalf("unexpected value at bin %d: %v != %v", k, x[k], want[k])
+		}
+	}
+}
+
+// TestSpectrum verifies that spectrum normalizes a full scale sine wave to
+// a magnitude of 1 at its frequency, and 0 at distant frequencies.
+func TestSpectrum(t *testing.T) {
+	// Two windows of a sine wave centered on bin 8
+	samples := make([]float64, 128)
+	for i := range samples {
+		samples[i] = math.Sin(2 * math.Pi * 8 * float64(i) / 64)
+	}
+
+	bins := spectrum(samples, 64)
+	if len(bins) != 32 {
+		t.Fatalf("unexpected number of bins: %d", len(bins))
+	}
+
+	// The window leaks half of the magnitude into adjacent bins
+	for k, want := range map[int]float64{8: 1, 7: 1 - 20*math.Log10(2)/spectrogramRange, 20: 0} {
+		if math.Abs(bins[k]-want) > 1e-9 {
+			t.Fatalf("unexpected magnitude at bin %d: %v != %v", k, bins[k], want)
+		}
+	}
+}
+
+// TestWaveformDrawSpectrogram verifies that the Waveform.DrawSpectrogram
+// method draws each spectrum as a column, with frequency increasing from the
+// bottom to the top of the image.
+func TestWaveformDrawSpectrogram(t *testing.T) {
+	w, err := New(nil, SpectrogramColormap(GrayColormap))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.DrawSpectrogram([][]float64{{0, 1}, {1, 0}})
+	if b := img.Bounds(); b.Dx() != 2 || b.Dy() != imgYDefault {
+		t.Fatalf("unexpected image bounds: %v", b)
+	}
+
+	for _, p := range []struct {
+		x, y int
+		want color.Color
+	}{
+		{0, 0, color.White},
+		{0, 63, color.White},
+		{0, 64, color.Black},
+		{1, 0, color.Black},
+		{1, 127, color.White},
+	} {
+		if c := color.GrayModel.Convert(img.At(p.x, p.y)); c != color.GrayModel.Convert(p.want) {
+			t.Fatalf("unexpected color at (%d, %d): %v != %v", p.x, p.y, c, p.want)
+		}
+	}
+}
+
+// TestGenerateSpectrogram verifies that GenerateSpectrogram computes one
+// spectrum per slice of audio, and draws them in a spectrogram image.
+func TestGenerateSpectrogram(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+
+	w, err := New(bytes.NewReader(wav), FFTSize(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spectra, err := w.ComputeSpectrogram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spectra) != 2 || len(spectra[0]) != 8 {
+		t.Fatalf("unexpected spectra dimensions: %d, %d", len(spectra), len(spectra[0]))
+	}
+
+	img, err := GenerateSpectrogram(bytes.NewReader(wav), FFTSize(16), Scale(2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 4 || b.Dy() != imgYDefault {
+		t.Fatalf("unexpected image bounds: %v", b)
+	}
+}
+
+// TestHeatColormap verifies that HeatColormap blends from black, through red
+// and yellow, to white.
+func TestHeatColormap(t *testing.T) {
+	for v, want := range map[float64]color.RGBA{
+		-1:      {0x00, 0x00, 0x00, 0xff},
+		0:       {0x00, 0x00, 0x00, 0xff},
+		1.0 / 3: {0xff, 0x00, 0x00, 0xff},
+		2.0 / 3: {0xff, 0xff, 0x00, 0xff},
+		1:       {0xff, 0xff, 0xff, 0xff},
+	} {
+		if c := HeatColormap(v); c != want {
+			t.Fatalf("unexpected color for %v: %v != %v", v, c, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package waveform
+
+import (
+	"image"
+	"image/draw"
+	"io"
+)
+
+// GenerateStacked immediately opens and reads multiple input audio streams,
+// each as a separate track, and returns a single image in which the waveform
+// of each track is drawn in its own lane, one below the other, such as to
+// visualize the tracks of a multitrack session.  Every lane is customized by
+// the same zero or more, variadic, OptionsFunc parameters.
+//
+// Each lane is drawn as by Draw, so the Height option sets the height of each
+// lane, and the Separate channel mode has no effect.  Lanes are aligned at the
+// beginning of each track, and tracks which are shorter than the longest track
+// are extended with silence, so the width of the image reflects the longest
+// track.  If the LaneSeparator option is set, a separator is drawn between
+// each pair of adjacent lanes.
+//
+// If no streams are provided, ErrNoSamples is returned.  If an error occurs
+// while reading any stream, it is returned, and if the PartialOnError option
+// is set, the lanes of the tracks computed so far are drawn, including any
+// partial values of the track which failed.
+func GenerateStacked(readers []io.Reader, options ...OptionsFunc) (image.Image, error) {
+	if len(readers) == 0 {
+		return nil, ErrNoSamples
+	}
+
+	var (
+		tracks []*Waveform
+		values [][]float64
+		err    error
+	)
+	for _, r := range readers {
+		w, nerr := New(r, options...)
+		if nerr != nil {
+			return nil, nerr
+		}
+
+		var v []float64
+		v, err = w.Compute()
+		if err != nil {
+			// Draw any partial results, if requested
+			if !w.partialOnError || len(tracks)+len(v) == 0 {
+				return nil, err
+			}
+			if len(v) > 0 {
+				tracks = append(tracks, w)
+				values = append(values, v)
+			}
+
+			break
+		}
+
+		tracks = append(tracks, w)
+		values = append(values, v)
+	}
+
+	return drawStacked(tracks, values), err
+}
+
+// drawStacked draws the values computed by each Waveform in its own lane, one
+// below the other, separated by the lane separator of the first Waveform, if
+// any.  The values of each track are extended with silence to the length of
+// the longest track.
+func drawStacked(tracks []*Waveform, values [][]float64) image.Image {
+	var n int
+	for _, v := range values {
+		n = maxInt(n, len(v))
+	}
+
+	lanes := make([]image.Image, len(tracks))
+	var width, height int
+	for i, w := range tracks {
+		// Statistics are extended along with the values, so that they are
+		// still drawn
+		padded := make([]float64, n)
+		copy(padded, values[i])
+		if len(w.stats) == len(values[i]) {
+			w.stats = append(w.stats, make([]sliceStats, n-len(w.stats))...)
+		}
+
+		lanes[i] = w.Draw(padded)
+		b := lanes[i].Bounds()
+		width = maxInt(width, b.Dx())
+		height += b.Dy()
+	}
+
+	w := tracks[0]
+	sep := 0
+	if w.laneSepColor != nil {
+		sep = int(w.laneSepHeight)
+	}
+	height += sep * (len(lanes) - 1)
+
+	img := w.newImage(image.Rect(0, 0, width, height))
+
+	var y int
+	for i, lane := range lanes {
+		if i > 0 && sep > 0 {
+			draw.Draw(img, image.Rect(0, y, width, y+sep), image.NewUniform(w.laneSepColor), image.Point{}, draw.Src)
+			y += sep
+		}
+
+		b := lane.Bounds()
+		draw.Draw(img, image.Rect(0, y, b.Dx(), y+b.Dy()), lane, b.Min, draw.Src)
+		y += b.Dy()
+	}
+
+	return img
+}
@@ -0,0 +1,55 @@
+package waveform
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+)
+
+// TestGenerateStacked verifies that GenerateStacked draws each track in its
+// own lane, separated by the lane separator, and extends shorter tracks with
+// silence.
+func TestGenerateStacked(t *testing.T) {
+	long := testWAV(1, 1, 4, 8, []byte{255, 0, 255, 0, 255, 0, 255, 0})
+	short := testWAV(1, 1, 4, 8, []byte{255, 0, 255, 0})
+
+	img, err := GenerateStacked([]io.Reader{bytes.NewReader(long), bytes.NewReader(short)},
+		Resolution(2), Height(10), LaneSeparator(2, red))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b != image.Rect(0, 0, 4, 22) {
+		t.Fatalf("unexpected image bounds: %v", b)
+	}
+
+	// Loud values fill each lane, the separator is drawn between them, and
+	// the short track is extended with silence
+	for _, p := range []struct {
+		x, y int
+		want color.Color
+	}{
+		{0, 0, black},
+		{3, 9, black},
+		{0, 10, red},
+		{3, 11, red},
+		{0, 12, black},
+		{1, 21, black},
+		{2, 17, white},
+		{3, 12, white},
+	} {
+		if c := img.At(p.x, p.y); c != p.want {
+			t.Fatalf("unexpected color at (%d, %d): %v != %v", p.x, p.y, c, p.want)
+		}
+	}
+}
+
+// TestGenerateStackedNoReaders verifies that GenerateStacked returns
+// ErrNoSamples when no streams are provided.
+func TestGenerateStackedNoReaders(t *testing.T) {
+	if _, err := GenerateStacked(nil); err != ErrNoSamples {
+		t.Fatalf("unexpected error: %v != %v", err, ErrNoSamples)
+	}
+}
@@ -0,0 +1,224 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// Names of output image formats which may be used with GenerateTo and EncodeTo
+const (
+	FormatBMP  = "bmp"
+	FormatGIF  = "gif"
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatTIFF = "tiff"
+
+	// Lossless and lossy WebP images are only available when built with the
+	// webp build tag, and otherwise ErrImageFormat is returned
+	FormatWebP      = "webp"
+	FormatWebPLossy = "webp-lossy"
+
+	// SVG documents are drawn from computed values by DrawSVG, so they may be
+	// used with GenerateTo and DrawTo, but not with EncodeTo
+	FormatSVG = "svg"
+)
+
+// GenerateTo immediately opens and reads an input audio stream, computes the
+// values required for waveform generation, and encodes a waveform image in the
+// named format directly to an output stream.  The image is customized by zero
+// or more, variadic, OptionsFunc parameters.
+//
+// The PNG format is streamed: each pixel is drawn only when the encoder requests
+// it, row by row, so the full RGBA image buffer is never allocated.  In that case,
+// memory use is bounded by the computed values (one float64 per slice of audio)
+// and a few rows of encoder state, rather than four bytes per output pixel.
+// Because pixels are drawn in row order rather than column order, any ColorFunc
+// used must not depend on the order in which it is called.
+//
+// Formats which cannot be streamed, such as GIF, JPEG, BMP, and TIFF, fall back
+// to drawing the complete image in memory before encoding it, with the same
+// memory use as calling Generate.  The SVG format is written by DrawSVG, which
+// streams the path of the waveform as each bar is drawn.
+//
+// If the DPI option is set, a PNG image includes a pHYs chunk describing its
+// physical dimensions.  Other formats ignore the DPI option.  Similarly, the
+// Quality option only applies to lossy formats, such as JPEG.
+//
+// If the named format is not supported, ErrImageFormat is returned before any
+// audio is read.
+func GenerateTo(out io.Writer, format string, r io.Reader, options ...OptionsFunc) error {
+	// Check for a known format before doing any work
+	if _, ok := imageEncoders[format]; !ok && format != FormatSVG {
+		return ErrImageFormat
+	}
+
+	w, err := New(r, options...)
+	if err != nil {
+		return err
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		return err
+	}
+
+	return w.DrawTo(out, format, values)
+}
+
+// DrawTo encodes a waveform image in the named format from a slice of float64
+// values directly to an output stream.
+//
+// DrawTo is the streaming equivalent of Draw, and has the same memory
+// characteristics as GenerateTo.
+func (w *Waveform) DrawTo(out io.Writer, format string, values []float64) error {
+	if format == FormatSVG {
+		return w.DrawSVG(out, values)
+	}
+
+	// Anti-aliased images are supersampled, and axes are drawn over complete
+	// images, so they cannot be streamed
+	if enc, ok := encoders[format]; ok && w.antiAlias < 2 && !w.drawsAnnotations() {
+		return enc(out, w, values)
+	}
+	enc, ok := imageEncoders[format]
+	if !ok {
+		return ErrImageFormat
+	}
+
+	return enc(out, w.Draw(values), w.quality)
+}
+
+// EncodeTo encodes an image, such as one returned by Generate or Draw, in the
+// named format to an output stream, so that the image codec may be chosen by
+// the caller.  Lossy formats, such as JPEG, are encoded with a quality of 75.
+// If the named format is not supported, ErrImageFormat is returned.
+func EncodeTo(out io.Writer, format string, img image.Image) error {
+	enc, ok := imageEncoders[format]
+	if !ok {
+		return ErrImageFormat
+	}
+
+	return enc(out, img, jpeg.DefaultQuality)
+}
+
+// imageEncoderFunc is a function which encodes an image to an output stream,
+// using the input quality, from 1 to 100, if the image format is lossy.
+type imageEncoderFunc func(out io.Writer, img image.Image, quality int) error
+
+// imageEncoders is the set of output image formats available to EncodeTo and
+// GenerateTo.
+var imageEncoders = map[string]imageEncoderFunc{
+	FormatBMP: func(out io.Writer, img image.Image, _ int) error {
+		return bmp.Encode(out, img)
+	},
+	FormatGIF: func(out io.Writer, img image.Image, _ int) error {
+		return gif.Encode(out, img, nil)
+	},
+	FormatJPEG: func(out io.Writer, img image.Image, quality int) error {
+		return jpeg.Encode(out, img, &jpeg.Options{Quality: quality})
+	},
+	FormatPNG: func(out io.Writer, img image.Image, _ int) error {
+		return png.Encode(out, img)
+	},
+	FormatTIFF: func(out io.Writer, img image.Image, _ int) error {
+		return tiff.Encode(out, img, nil)
+	},
+}
+
+// encoderFunc is a function which draws and encodes a waveform image to an
+// output stream.
+type encoderFunc func(out io.Writer, w *Waveform, values []float64) error
+
+// encoders is the set of output image formats which GenerateTo streams, rather
+// than drawing the complete image before encoding it with imageEncoders.
+var encoders = map[string]encoderFunc{
+	FormatPNG: func(out io.Writer, w *Waveform, values []float64) error {
+		// Describe the physical dimensions of the image, if requested
+		if w.dpiX > 0 {
+			out = newPNGPhysWriter(out, w.dpiX, w.dpiY)
+		}
+
+		return png.Encode(out, w.streamImage(values))
+	},
+}
+
+// streamImage is an image.Image which draws each pixel of a waveform image on
+// demand, without allocating a buffer for the entire image.
+type streamImage struct {
+	l *layout
+
+	// Reused between calls to At; a streamImage must not be used from multiple
+	// goroutines concurrently
+	spans []span
+}
+
+// streamImage creates a streamImage for drawing the input slice of computed values.
+func (w *Waveform) streamImage(values []float64) *streamImage {
+	return &streamImage{
+		l: w.newLayout(values),
+	}
+}
+
+// ColorModel returns the color model of a streamImage, which is the palette
+// set by the Paletted option, if any, or non-premultiplied alpha if the
+// TransparentBackground option is set.
+func (m *streamImage) ColorModel() color.Model {
+	if m.l.w.palette != nil {
+		return m.l.w.palette
+	}
+	if m.l.w.nrgba {
+		return color.NRGBAModel
+	}
+
+	return color.RGBAModel
+}
+
+// Bounds returns the bounds of a streamImage.
+func (m *streamImage) Bounds() image.Rectangle {
+	return m.l.bounds
+}
+
+// At returns the color of the pixel at X coordinate x and Y coordinate y,
+// which is the color of the last span drawn over that pixel.
+func (m *streamImage) At(x int, y int) color.Color {
+	l := m.l
+	if !(image.Point{x, y}.In(m.Bounds())) {
+		return color.RGBA{}
+	}
+
+	// Draw any padding using the background color
+	if !l.inWaveform(x, y) {
+		return m.ColorModel().Convert(l.paddingColor(x, y))
+	}
+	x, y = x-l.offset.X, y-l.offset.Y
+
+	m.spans = l.spans(x, m.spans[:0])
+	for i := len(m.spans) - 1; i >= 0; i-- {
+		if s := m.spans[i]; y >= s.y0 && y < s.y1 {
+			// Convert to the color model of the image, as image.RGBA,
+			// image.NRGBA, or image.Paletted would
+			return m.ColorModel().Convert(l.color(s.fn, l.column(x), x, y))
+		}
+	}
+
+	return m.ColorModel().Convert(color.RGBA{})
+}
+
+// ColorIndexAt returns the index in the palette set by the Paletted option of
+// the pixel at X coordinate x and Y coordinate y, so that a streamImage is
+// encoded as an indexed image.  If no palette is set, it returns 0.
+func (m *streamImage) ColorIndexAt(x int, y int) uint8 {
+	p := m.l.w.palette
+	if p == nil {
+		return 0
+	}
+
+	return uint8(p.Index(m.At(x, y)))
+}
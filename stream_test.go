@@ -0,0 +1,187 @@
+package waveform
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"testing"
+)
+
+// TestGenerateToPNG verifies that GenerateTo produces a PNG image identical to
+// the image produced by Generate, for the same input stream and options.
+func TestGenerateToPNG(t *testing.T) {
+	options := []OptionsFunc{
+		FGColorFunction(StripeColor(red, green, blue)),
+		BGColorFunction(CheckerColor(black, white, 4)),
+		Scale(5, 2),
+		Sharpness(2),
+	}
+
+	want, err := Generate(bytes.NewReader(wavFile), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := GenerateTo(buf, FormatPNG, bytes.NewReader(wavFile), options...); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testImagesEqual(t, got, want)
+}
+
+// TestGenerateToPNGPadding verifies that GenerateTo produces a padded PNG image
+// identical to the image produced by Generate.
+func TestGenerateToPNGPadding(t *testing.T) {
+	options := []OptionsFunc{
+		BGColorFunction(CheckerColor(black, white, 4)),
+		Padding(3, 1, 4, 2),
+	}
+
+	want, err := Generate(bytes.NewReader(wavFile), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := GenerateTo(buf, FormatPNG, bytes.NewReader(wavFile), options...); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testImagesEqual(t, got, want)
+}
+
+// TestGenerateToBuffered verifies that GenerateTo encodes formats which cannot
+// be streamed, using the buffered drawing path.
+func TestGenerateToBuffered(t *testing.T) {
+	for _, format := range []string{FormatGIF, FormatJPEG} {
+		buf := bytes.NewBuffer(nil)
+		if err := GenerateTo(buf, format, bytes.NewReader(wavFile)); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		img, name, err := image.Decode(buf)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if name != format {
+			t.Fatalf("unexpected image format: %v != %v", name, format)
+		}
+		if max := img.Bounds().Max; max.X != 5 || max.Y != imgYDefault {
+			t.Fatalf("%s: unexpected image bounds: %v", format, max)
+		}
+	}
+}
+
+// TestGenerateToErrImageFormat verifies that GenerateTo returns ErrImageFormat
+// for an unknown output image format, before reading any audio.
+func TestGenerateToErrImageFormat(t *testing.T) {
+	r := bytes.NewReader(wavFile)
+	if err := GenerateTo(ioutil.Discard, "ico", r); err != ErrImageFormat {
+		t.Fatalf("unexpected GenerateTo error: %v != %v", err, ErrImageFormat)
+	}
+
+	if r.Len() != len(wavFile) {
+		t.Fatalf("unexpected read from input stream: %d bytes", len(wavFile)-r.Len())
+	}
+}
+
+// TestEncodeTo verifies that EncodeTo encodes an image in each supported
+// format, and returns ErrImageFormat for an unknown format.
+func TestEncodeTo(t *testing.T) {
+	w, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := w.Draw([]float64{0.25, 1.0, 0.5})
+
+	var tests = []struct {
+		format string
+		magic  string
+		err    error
+	}{
+		{FormatBMP, "BM", nil},
+		{FormatGIF, "GIF8", nil},
+		{FormatJPEG, "\xff\xd8", nil},
+		{FormatPNG, "\x89PNG", nil},
+		{FormatTIFF, "II*\x00", nil},
+		{"ico", "", ErrImageFormat},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		if err := EncodeTo(buf, test.format, img); err != test.err {
+			t.Fatalf("[%02d] unexpected EncodeTo error: %v != %v", i, err, test.err)
+		}
+
+		if !bytes.HasPrefix(buf.Bytes(), []byte(test.magic)) {
+			t.Fatalf("[%02d] unexpected %s image header: %q", i, test.format, buf.Bytes())
+		}
+	}
+}
+
+// TestWaveformDrawToJPEGQuality verifies that the Waveform.DrawTo method
+// encodes JPEG images using the quality set by the Quality option.
+func TestWaveformDrawToJPEGQuality(t *testing.T) {
+	values := []float64{0.25, 1.0, 0.5, 0.75}
+	options := []OptionsFunc{
+		FGColorFunction(StripeColor(red, green, blue)),
+		Scale(10, 2),
+	}
+
+	var sizes []int
+	for _, quality := range []uint{100, 75, 10} {
+		w, err := New(nil, append(options, Quality(quality))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf := bytes.NewBuffer(nil)
+		if err := w.DrawTo(buf, FormatJPEG, values); err != nil {
+			t.Fatal(err)
+		}
+
+		sizes = append(sizes, buf.Len())
+		if _, err := jpeg.Decode(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Lower quality images are smaller
+	for i := 1; i < len(sizes); i++ {
+		if sizes[i] >= sizes[i-1] {
+			t.Fatalf("unexpected JPEG sizes: %v", sizes)
+		}
+	}
+}
+
+// testImagesEqual is a test helper which verifies that two images have the same
+// bounds and the same color at every pixel.
+func testImagesEqual(t *testing.T, got image.Image, want image.Image) {
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("unexpected image bounds: %v != %v", got.Bounds(), want.Bounds())
+	}
+
+	b := want.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			r1, g1, b1, a1 := got.At(x, y).RGBA()
+			r2, g2, b2, a2 := want.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				t.Fatalf("unexpected color at (%d,%d): %v != %v", x, y, got.At(x, y), want.At(x, y))
+			}
+		}
+	}
+}
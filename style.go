@@ -0,0 +1,62 @@
+package waveform
+
+import "math"
+
+// DrawStyle is the style used to draw the computed values of a waveform image.
+type DrawStyle int
+
+// Styles which may be used with the Style option.
+const (
+	// Bars draws each computed value as a separate, vertical bar, which
+	// is customized by options such as BarWidth, BarGap, and Sharpness.
+	Bars DrawStyle = iota
+
+	// AreaFill draws a continuous, filled area, whose outline connects the
+	// centers of adjacent computed values with straight lines.  Gaps between
+	// bars are filled, and Sharpness and BarRadius have no effect, but
+	// BarWidth and BarGap still determine the width of the image.
+	AreaFill
+
+	// Line draws only the upper and lower outlines of the AreaFill style, as
+	// connected lines of the width set by StrokeWidth, in the foreground
+	// color.  DualEnvelope is not drawn, but MinMaxEnvelope outlines the
+	// minimum and maximum samples.
+	Line
+
+	// dotStyle draws each computed value as a single dot, and is selected
+	// using the Dots option, along with the radius of each dot.
+	dotStyle
+)
+
+// valid reports whether a DrawStyle is one of the known styles.
+func (s DrawStyle) valid() bool {
+	return s == Bars || s == AreaFill || s == Line
+}
+
+// continuous reports whether a DrawStyle draws a continuous shape connecting
+// adjacent values, rather than separate bars.
+func (s DrawStyle) continuous() bool {
+	return s == AreaFill || s == Line
+}
+
+// sample returns the value drawn at X coordinate x, using fn to retrieve the
+// value at each index.  For continuous styles, the value is linearly
+// interpolated between the two nearest values, which are centered on their
+// bars.  Otherwise, the value of the bar at x is returned.
+func (l *layout) sample(x int, fn func(n int) float64) float64 {
+	if !l.w.style.continuous() || l.maxN < 2 {
+		return fn(l.column(x))
+	}
+
+	// Position of the center of the pixel column, in units of values
+	pos := (float64(x) + 0.5 - float64(l.barPx)/2) / float64(l.period)
+	pos = math.Min(math.Max(pos, 0), float64(l.maxN-1))
+
+	n := int(pos)
+	if n+1 >= l.maxN {
+		return fn(n)
+	}
+
+	v := fn(n)
+	return v + (fn(n+1)-v)*(pos-float64(n))
+}
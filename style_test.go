@@ -0,0 +1,161 @@
+package waveform
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+// TestWaveformDrawAreaFill verifies that the Waveform.Draw method draws a
+// continuous area between the centers of adjacent values, when the AreaFill
+// style is used.
+func TestWaveformDrawAreaFill(t *testing.T) {
+	w, err := New(nil, Style(AreaFill), Scale(4, 1), BarGap(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Values are centered at X coordinates 2 and 10, and the extent of every
+	// column is interpolated between them, including the gaps
+	img := w.Draw([]float64{0, 0.2})
+
+	for x, want := range []int{0, 0, 4, 14, 24, 33, 43, 52, 62, 72, 76, 76, 76, 76, 76, 76} {
+		if got := testColumnExtent(img, x); got != want {
+			t.Fatalf("unexpected extent at x=%d: %v != %v", x, got, want)
+		}
+	}
+}
+
+// TestWaveformDrawBarsDefault verifies that the Waveform.Draw method draws
+// separate bars by default, leaving gaps between them.
+func TestWaveformDrawBarsDefault(t *testing.T) {
+	w, err := New(nil, Scale(4, 1), BarGap(1), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.Draw([]float64{0.2, 0.2})
+
+	// Bars are 4 pixels wide, followed by gaps of 4 pixels
+	for x, want := range []int{76, 76, 76, 76, 0, 0, 0, 0, 76, 76, 76, 76, 0, 0, 0, 0} {
+		if got := testColumnExtent(img, x); got != want {
+			t.Fatalf("unexpected extent at x=%d: %v != %v", x, got, want)
+		}
+	}
+}
+
+// TestWaveformDrawLine verifies that the Waveform.Draw method draws only the
+// upper and lower outlines of each value, using the stroke width, when the
+// Line style is used.
+func TestWaveformDrawLine(t *testing.T) {
+	var tests = []struct {
+		stroke uint
+		rows   []int
+	}{
+		{stroke: 1, rows: []int{26, 101}},
+		{stroke: 3, rows: []int{25, 26, 27, 100, 101, 102}},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, Style(Line), StrokeWidth(test.stroke), Scale(4, 1), BarGap(1))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		// The outlines of a constant value are horizontal lines, which are
+		// drawn in the gaps between values
+		img := w.Draw([]float64{0.2, 0.2})
+		for x := 0; x < img.Bounds().Max.X; x++ {
+			var rows []int
+			for y := 0; y < img.Bounds().Max.Y; y++ {
+				if img.At(x, y) == black {
+					rows = append(rows, y)
+				}
+			}
+
+			if !reflect.DeepEqual(rows, test.rows) {
+				t.Fatalf("[%02d] unexpected rows at x=%d: %v != %v", i, x, rows, test.rows)
+			}
+		}
+	}
+}
+
+// TestWaveformDrawLineConnected verifies that the Waveform.Draw method draws
+// steep outlines as connected lines, when the Line style is used.
+func TestWaveformDrawLineConnected(t *testing.T) {
+	w, err := New(nil, Style(Line), Scale(2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.Draw([]float64{0, 0.3, 0.05, 0.3})
+
+	// The pixels of the upper outline in each column are contiguous, and touch
+	// those of the adjacent column
+	var prev [2]int
+	for x := 0; x < img.Bounds().Max.X; x++ {
+		run := [2]int{-1, -1}
+		for y := 0; y < imgYDefault/2; y++ {
+			if img.At(x, y) != black {
+				continue
+			}
+			if run[0] == -1 {
+				run[0] = y
+			} else if y != run[1]+1 {
+				t.Fatalf("unexpected gap in outline at (%d, %d)", x, y)
+			}
+			run[1] = y
+		}
+
+		if run[0] == -1 {
+			t.Fatalf("no outline at x=%d", x)
+		}
+		if x > 0 && (run[0] > prev[1]+1 || prev[0] > run[1]+1) {
+			t.Fatalf("outline not connected at x=%d: %v, %v", x, prev, run)
+		}
+		prev = run
+	}
+}
+
+// TestWaveformDrawDots verifies that the Waveform.Draw method draws each value
+// as a dot centered at the top of its extent, which extends into the gaps
+// between bars, when the Dots option is set.
+func TestWaveformDrawDots(t *testing.T) {
+	w, err := New(nil, Dots(2), Scale(2, 1), BarGap(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The top of the extent of 0.2 is at Y coordinate 26, and the dot is
+	// centered on the 2 pixel bar, with 2 pixels of gap on its right
+	img := w.Draw([]float64{0.2})
+	for x, want := range [][2]int{{24, 28}, {24, 28}, {25, 27}, {0, 0}} {
+		var got [2]int
+		for y := 0; y < img.Bounds().Max.Y; y++ {
+			if img.At(x, y) != black {
+				continue
+			}
+			if got[1] == 0 {
+				got[0] = y
+			}
+			got[1] = y + 1
+		}
+
+		if got != want {
+			t.Fatalf("unexpected dot extent at x=%d: %v != %v", x, got, want)
+		}
+	}
+}
+
+// testColumnExtent is a test helper which returns the number of foreground
+// pixels in the column at X coordinate x.
+func testColumnExtent(img image.Image, x int) int {
+	var n int
+	for y := 0; y < img.Bounds().Max.Y; y++ {
+		if img.At(x, y) == black {
+			n++
+		}
+	}
+
+	return n
+}
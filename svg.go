@@ -0,0 +1,230 @@
+package waveform
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+)
+
+// GenerateSVG immediately opens and reads an input audio stream, computes the
+// values required for waveform generation, and writes a waveform image as an
+// SVG document to an output stream.  The image is customized by zero or more,
+// variadic, OptionsFunc parameters.
+//
+// GenerateSVG is equivalent to New and Compute, followed by the DrawSVG method
+// of a Waveform struct, and handles errors in the same way as Generate.
+func GenerateSVG(out io.Writer, r io.Reader, options ...OptionsFunc) error {
+	w, err := New(r, options...)
+	if err != nil {
+		return err
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		// Draw any partial results, if requested
+		if w.partialOnError && len(values) > 0 {
+			if derr := w.DrawSVG(out, values); derr != nil {
+				return derr
+			}
+		}
+
+		return err
+	}
+
+	return w.DrawSVG(out, values)
+}
+
+// DrawSVG writes a waveform image as an SVG document from a slice of float64
+// values to an output stream.
+//
+// The document has the same dimensions as the image created by Draw, but the
+// waveform is drawn as a single vector path, so it may be scaled to any size
+// without loss of quality.  Each bar is a rectangle, rounded by BarRadius, the
+// AreaFill style is drawn as a polygon connecting the centers of adjacent
+// bars, the Line style as the outlines of that polygon, and each dot drawn by
+// the Dots option as a circle.  Sharpness has no effect, nor do options which
+// draw in front of the waveform, such as overlays and markers.  Each value is
+// drawn symmetrically about the center, so MinMaxEnvelope, DualEnvelope, and
+// Mirrored are ignored.
+//
+// Since an SVG fill cannot vary by pixel, the background and foreground
+// ColorFunc are each evaluated once, at the first pixel of the waveform, so
+// only solid colors are drawn exactly.  The background rectangle and the
+// waveform path have the waveform-background and waveform classes, so that
+// web pages may style them with CSS instead.
+//
+// The path data is written as each bar is drawn, so the document is never held
+// in memory.  DrawSVG is used by DrawTo and GenerateTo for FormatSVG.
+func (w *Waveform) DrawSVG(out io.Writer, values []float64) error {
+	l := w.newLayout(values)
+
+	// A bufio.Writer retains the first write error, which is returned by Flush
+	buf := bufio.NewWriter(out)
+	width, height := l.bounds.Dx(), l.bounds.Dy()
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		width, height, width, height)
+
+	fmt.Fprintf(buf, `<rect class="waveform-background" width="%d" height="%d"%s/>`+"\n",
+		width, height, svgPaint("fill", l.color(w.bgColorFn, 0, 0, 0)))
+
+	paint := svgPaint("fill", l.color(w.fgColorFn, 0, 0, 0))
+	buf.WriteString(`<path class="waveform" d="`)
+	switch w.style {
+	case AreaFill:
+		l.svgArea(buf)
+	case Line:
+		l.svgLine(buf)
+		paint = fmt.Sprintf(` fill="none"%s stroke-width="%d"`,
+			svgPaint("stroke", l.color(w.fgColorFn, 0, 0, 0)), w.strokeWidth)
+	case dotStyle:
+		l.svgDots(buf)
+	default:
+		l.svgBars(buf)
+	}
+
+	fmt.Fprintf(buf, `"%s/>`+"\n", paint)
+	buf.WriteString("</svg>\n")
+
+	return buf.Flush()
+}
+
+// svgExtent returns the top and bottom Y coordinates of the extent of the
+// value at index n, which is symmetrical about the center of the waveform
+// area, and clipped to its bounds.
+func (l *layout) svgExtent(n int) (float64, float64) {
+	half := float64(l.maxY) / 2
+	extent := l.values[n] * float64(l.maxY) * l.imgScale / 2
+
+	return math.Max(half-extent, 0), math.Min(half+extent, float64(l.maxY))
+}
+
+// svgBars writes the path data of a rectangle for each bar to buf.
+func (l *layout) svgBars(buf *bufio.Writer) {
+	ox, oy := float64(l.offset.X), float64(l.offset.Y)
+	for n := 0; n < l.maxN; n++ {
+		top, bottom := l.svgExtent(n)
+		if bottom <= top {
+			continue
+		}
+
+		x := ox + float64(n*l.period)
+		width, height := float64(l.barPx), bottom-top
+		r := math.Min(float64(l.radius), height/2)
+
+		if r == 0 {
+			fmt.Fprintf(buf, "M%s,%sh%sv%sh-%sz",
+				svgNum(x), svgNum(oy+top), svgNum(width), svgNum(height), svgNum(width))
+			continue
+		}
+
+		// Trace the rectangle clockwise, with an arc at each corner
+		fmt.Fprintf(buf, "M%s,%sh%sa%s,%s 0 0 1 %s,%sv%sa%s,%s 0 0 1 -%s,%sh-%sa%s,%s 0 0 1 -%s,-%sv-%sa%s,%s 0 0 1 %s,-%sz",
+			svgNum(x+r), svgNum(oy+top), svgNum(width-2*r),
+			svgNum(r), svgNum(r), svgNum(r), svgNum(r), svgNum(height-2*r),
+			svgNum(r), svgNum(r), svgNum(r), svgNum(r), svgNum(width-2*r),
+			svgNum(r), svgNum(r), svgNum(r), svgNum(r), svgNum(height-2*r),
+			svgNum(r), svgNum(r), svgNum(r), svgNum(r),
+		)
+	}
+}
+
+// svgDots writes the path data of a circle for each value to buf, centered on
+// its bar, at the top of its extent.
+func (l *layout) svgDots(buf *bufio.Writer) {
+	ox, oy := float64(l.offset.X), float64(l.offset.Y)
+	r := float64(l.w.dotRadius)
+	for n := 0; n < l.maxN; n++ {
+		top, _ := l.svgExtent(n)
+		x := ox + float64(n*l.period) + float64(l.barPx)/2
+
+		// Trace the circle as two half circles
+		fmt.Fprintf(buf, "M%s,%sa%s,%s 0 1 0 %s,0a%s,%s 0 1 0 -%s,0z",
+			svgNum(x-r), svgNum(oy+top), svgNum(r), svgNum(r), svgNum(2*r), svgNum(r), svgNum(r), svgNum(2*r))
+	}
+}
+
+// svgArea writes the path data of a polygon connecting the centers of
+// adjacent bars to buf.  The polygon extends to both edges of the waveform
+// area, as drawn by AreaFill.
+func (l *layout) svgArea(buf *bufio.Writer) {
+	if l.maxN == 0 {
+		return
+	}
+
+	// Trace the upper outline from left to right, and the lower outline from
+	// right to left
+	l.svgOutline(buf, true, false, true)
+	l.svgOutline(buf, false, true, false)
+	buf.WriteString("z")
+}
+
+// svgLine writes the path data of the upper and lower outlines of the polygon
+// written by svgArea to buf, as drawn by Line.
+func (l *layout) svgLine(buf *bufio.Writer) {
+	if l.maxN == 0 {
+		return
+	}
+
+	l.svgOutline(buf, true, false, true)
+	l.svgOutline(buf, false, false, true)
+}
+
+// svgOutline writes the path data of lines connecting the centers of adjacent
+// bars to buf, along the upper or lower outline, and extended to both edges
+// of the waveform area.  Points are written from left to right, or from right
+// to left if reverse is set.  If move is set, the outline begins a new
+// subpath, rather than continuing the current one.
+func (l *layout) svgOutline(buf *bufio.Writer, upper bool, reverse bool, move bool) {
+	ox, oy := float64(l.offset.X), float64(l.offset.Y)
+
+	// The first and last points extend the outline of the first and last
+	// bars to the edges
+	points := l.maxN + 2
+	for i := 0; i < points; i++ {
+		p := i
+		if reverse {
+			p = points - 1 - i
+		}
+
+		n, x := p-1, ox+float64((p-1)*l.period)+float64(l.barPx)/2
+		switch p {
+		case 0:
+			n, x = 0, ox
+		case points - 1:
+			n, x = l.maxN-1, ox+float64(l.maxX)
+		}
+
+		y, bottom := l.svgExtent(n)
+		if !upper {
+			y = bottom
+		}
+
+		cmd := "L"
+		if i == 0 && move {
+			cmd = "M"
+		}
+		fmt.Fprintf(buf, "%s%s,%s", cmd, svgNum(x), svgNum(oy+y))
+	}
+}
+
+// svgPaint returns the named paint attribute, fill or stroke, of an SVG
+// element drawn using color c.  Partially transparent colors also set the
+// opacity of the paint.
+func svgPaint(name string, c color.Color) string {
+	nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+	paint := fmt.Sprintf(` %s="#%02x%02x%02x"`, name, nc.R, nc.G, nc.B)
+	if nc.A != 0xff {
+		paint += fmt.Sprintf(` %s-opacity="%s"`, name, svgNum(float64(nc.A)/0xff))
+	}
+
+	return paint
+}
+
+// svgNum formats a coordinate for SVG path data, with at most two decimal
+// places, and without trailing zeros.
+func svgNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,237 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// testSVG is the structure of an SVG document written by DrawSVG.
+type testSVG struct {
+	Width  int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+	Rect   struct {
+		Class string `xml:"class,attr"`
+		Fill  string `xml:"fill,attr"`
+	} `xml:"rect"`
+	Path struct {
+		Class   string `xml:"class,attr"`
+		D       string `xml:"d,attr"`
+		Fill    string `xml:"fill,attr"`
+		Opacity string `xml:"fill-opacity,attr"`
+	} `xml:"path"`
+}
+
+// TestWaveformDrawSVG verifies that the Waveform.DrawSVG method writes an SVG
+// document with the dimensions of the image created by Draw, and a vector path
+// for the input values.
+func TestWaveformDrawSVG(t *testing.T) {
+	var tests = []struct {
+		options []OptionsFunc
+		values  []float64
+		width   int
+		height  int
+		d       string
+	}{
+		{
+			values: []float64{0.1, 0.2, 0},
+			width:  3,
+			height: 128,
+			d:      "M0,44.8h1v38.4h-1zM1,25.6h1v76.8h-1z",
+		},
+		{
+			options: []OptionsFunc{BarWidth(2), BarGap(1), Padding(1, 0, 0, 4)},
+			values:  []float64{0.1, 0.2},
+			width:   10,
+			height:  129,
+			d:       "M4,45.8h2v38.4h-2zM7,26.6h2v76.8h-2z",
+		},
+		{
+			options: []OptionsFunc{BarWidth(4), BarRadius(1)},
+			values:  []float64{0.1},
+			width:   4,
+			height:  128,
+			d:       "M1,44.8h2a1,1 0 0 1 1,1v36.4a1,1 0 0 1 -1,1h-2a1,1 0 0 1 -1,-1v-36.4a1,1 0 0 1 1,-1z",
+		},
+		{
+			options: []OptionsFunc{Style(AreaFill), BarWidth(2)},
+			values:  []float64{0.1, 0.2},
+			width:   4,
+			height:  128,
+			d:       "M0,44.8L1,44.8L3,25.6L4,25.6L4,102.4L3,102.4L1,83.2L0,83.2z",
+		},
+		{
+			options: []OptionsFunc{Style(Line), BarWidth(2)},
+			values:  []float64{0.1, 0.2},
+			width:   4,
+			height:  128,
+			d:       "M0,44.8L1,44.8L3,25.6L4,25.6M0,83.2L1,83.2L3,102.4L4,102.4",
+		},
+		{
+			options: []OptionsFunc{Dots(1), BarWidth(2)},
+			values:  []float64{0.1},
+			width:   2,
+			height:  128,
+			d:       "M0,44.8a1,1 0 1 0 2,0a1,1 0 1 0 -2,0z",
+		},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, test.options...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		buf := bytes.NewBuffer(nil)
+		if err := w.DrawSVG(buf, test.values); err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		var svg testSVG
+		if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if svg.Width != test.width || svg.Height != test.height {
+			t.Fatalf("[%02d] unexpected SVG dimensions: %dx%d != %dx%d", i, svg.Width, svg.Height, test.width, test.height)
+		}
+		if svg.Path.D != test.d {
+			t.Fatalf("[%02d] unexpected path data:\n- got: %v\n-want: %v", i, svg.Path.D, test.d)
+		}
+	}
+}
+
+// TestWaveformDrawSVGColors verifies that the Waveform.DrawSVG method fills the
+// background and waveform using the colors of their ColorFunc, and sets
+// classes which may be used to style them.
+func TestWaveformDrawSVGColors(t *testing.T) {
+	w, err := New(nil,
+		BGColorFunction(SolidColor(color.RGBA{0x00, 0x99, 0xcc, 0xff})),
+		FGColorFunction(SolidColor(color.NRGBA{0xff, 0x33, 0x00, 0x80})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := w.DrawSVG(buf, []float64{0.1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var svg testSVG
+	if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+		t.Fatal(err)
+	}
+
+	if svg.Rect.Class != "waveform-background" || svg.Rect.Fill != "#0099cc" {
+		t.Fatalf("unexpected background: %+v", svg.Rect)
+	}
+	if svg.Path.Class != "waveform" || svg.Path.Fill != "#ff3300" || svg.Path.Opacity != "0.5" {
+		t.Fatalf("unexpected waveform path: %+v", svg.Path)
+	}
+}
+
+// TestGenerateSVG verifies that GenerateSVG writes an SVG document from an
+// input audio stream.
+func TestGenerateSVG(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	if err := GenerateSVG(buf, bytes.NewReader(wav), Resolution(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	var svg testSVG
+	if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+		t.Fatal(err)
+	}
+
+	if svg.Width != 4 || svg.Height != imgYDefault {
+		t.Fatalf("unexpected SVG dimensions: %dx%d", svg.Width, svg.Height)
+	}
+	if n := strings.Count(svg.Path.D, "M"); n != 4 {
+		t.Fatalf("unexpected number of bars: %d", n)
+	}
+}
+
+// TestGenerateToSVG verifies that GenerateTo writes the same SVG document as
+// GenerateSVG, when the SVG format is named.
+func TestGenerateToSVG(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+
+	want := bytes.NewBuffer(nil)
+	if err := GenerateSVG(want, bytes.NewReader(wav), Resolution(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := bytes.NewBuffer(nil)
+	if err := GenerateTo(got, FormatSVG, bytes.NewReader(wav), Resolution(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.String() != want.String() {
+		t.Fatalf("unexpected SVG document:\n- got: %v\n-want: %v", got, want)
+	}
+}
+
+// TestWaveformDrawSVGIncremental verifies that the Waveform.DrawSVG method
+// writes a long path in pieces as it is drawn, rather than building the whole
+// document before writing it, and returns any write error.
+func TestWaveformDrawSVGIncremental(t *testing.T) {
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = float64(i%100) / 100
+	}
+
+	for _, style := range []OptionsFunc{nil, Style(AreaFill), Style(Line)} {
+		var options []OptionsFunc
+		if style != nil {
+			options = append(options, style)
+		}
+
+		w, err := New(nil, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sw := &svgWriter{}
+		if err := w.DrawSVG(sw, values); err != nil {
+			t.Fatal(err)
+		}
+		if sw.writes < 2 || sw.max > 4096 {
+			t.Fatalf("unexpected writes of %d bytes: %d writes, largest %d bytes", sw.n, sw.writes, sw.max)
+		}
+
+		// The first write error is returned
+		if err := w.DrawSVG(&svgWriter{limit: 1}, values); err != errSVGWriter {
+			t.Fatalf("unexpected DrawSVG error: %v != %v", err, errSVGWriter)
+		}
+	}
+}
+
+// errSVGWriter is returned by an svgWriter once its limit is reached.
+var errSVGWriter = errors.New("svg writer limit reached")
+
+// svgWriter is an io.Writer which records the number and size of writes, and
+// fails once limit writes are made, if limit is set.
+type svgWriter struct {
+	writes, n, max, limit int
+}
+
+// Write records a write of b, or returns errSVGWriter once the limit is
+// reached.
+func (w *svgWriter) Write(b []byte) (int, error) {
+	if w.limit > 0 && w.writes >= w.limit {
+		return 0, errSVGWriter
+	}
+
+	w.writes++
+	w.n += len(b)
+	if len(b) > w.max {
+		w.max = len(b)
+	}
+
+	return len(b), nil
+}
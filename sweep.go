@@ -0,0 +1,110 @@
+package waveform
+
+import (
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// GenerateSweep immediately opens and reads an input audio stream, computes
+// the values required for waveform generation, and returns an animated GIF
+// of a waveform image which is customized by zero or more, variadic,
+// OptionsFunc parameters.
+//
+// GenerateSweep is equivalent to Generate, followed by the DrawSweep method of
+// a Waveform struct, and handles errors in the same way.
+func GenerateSweep(r io.Reader, options ...OptionsFunc) (*gif.GIF, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		// Draw any partial results, if requested
+		if w.partialOnError && len(values) > 0 {
+			return w.DrawSweep(values), err
+		}
+
+		return nil, err
+	}
+
+	return w.DrawSweep(values), nil
+}
+
+// DrawSweep creates a new, looping, animated GIF from a slice of float64
+// values, which may be encoded using gif.EncodeAll.
+//
+// By default, the waveform fills in from left to right, and the final frame is
+// the same as the image created by DrawPaletted.  If the SweepPlayhead option
+// is set, each frame contains the entire waveform instead, and a playhead
+// sweeps across it, from the first column of the waveform to the last.  The
+// number of frames and the delay of each frame are set by the Sweep option.
+func (w *Waveform) DrawSweep(values []float64) *gif.GIF {
+	l := w.newLayout(values)
+
+	p := w.palette
+	if p == nil {
+		p = palette.Plan9
+	}
+
+	frames := int(w.sweepFrames)
+	delay := int(w.sweepDelay / (10 * time.Millisecond))
+
+	anim := &gif.GIF{
+		Image: make([]*image.Paletted, 0, frames),
+		Delay: make([]int, 0, frames),
+	}
+
+	for i := 0; i < frames; i++ {
+		img := image.NewPaletted(l.bounds, p)
+		l.draw(img)
+
+		if w.playheadColorFn != nil {
+			l.drawPlayhead(img, l.sweepColumn(i, frames))
+		} else {
+			l.clearFrom(img, l.maxX*(i+1)/frames)
+		}
+
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	return anim
+}
+
+// sweepColumn returns the X coordinate of the playhead in frame i of a sweep
+// with the input number of frames.
+func (l *layout) sweepColumn(i int, frames int) int {
+	if frames < 2 {
+		return 0
+	}
+
+	return (l.maxX - 1) * i / (frames - 1)
+}
+
+// drawPlayhead draws a playhead over the entire height of the waveform area of
+// img, at waveform X coordinate x.
+func (l *layout) drawPlayhead(img *image.Paletted, x int) {
+	if x < 0 || x >= l.maxX {
+		return
+	}
+
+	n := l.column(x)
+	for y := 0; y < l.maxY; y++ {
+		img.Set(x+l.offset.X, y+l.offset.Y, l.color(l.w.playheadColorFn, n, x, y))
+	}
+}
+
+// clearFrom redraws the background of the waveform area of img, from waveform
+// X coordinate x to the right edge, so that no values are drawn there.
+func (l *layout) clearFrom(img *image.Paletted, x int) {
+	for ; x < l.maxX; x++ {
+		n := l.column(x)
+		for y := 0; y < l.maxY; y++ {
+			img.Set(x+l.offset.X, y+l.offset.Y, l.color(l.w.bgColorFn, n, x, y))
+		}
+	}
+}
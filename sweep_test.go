@@ -0,0 +1,108 @@
+package waveform
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+// TestWaveformDrawSweep verifies that the Waveform.DrawSweep method creates
+// an animated GIF in which the waveform fills in from left to right, ending
+// with the image created by DrawPaletted.
+func TestWaveformDrawSweep(t *testing.T) {
+	w, err := New(nil,
+		Paletted([]color.Color{white, black}),
+		Sweep(4, 250*time.Millisecond),
+		Sharpness(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.1, 0.2, 0.3, 0.2, 0.1, 0.2, 0.3, 0.2}
+	anim := w.DrawSweep(values)
+	if len(anim.Image) != 4 || len(anim.Delay) != 4 {
+		t.Fatalf("unexpected number of frames: %d, %d", len(anim.Image), len(anim.Delay))
+	}
+
+	full := w.DrawPaletted(values)
+	for i, img := range anim.Image {
+		if anim.Delay[i] != 25 {
+			t.Fatalf("[%02d] unexpected frame delay: %v != %v", i, anim.Delay[i], 25)
+		}
+
+		// Columns to the right of the sweep are only background
+		for x := 0; x < len(values); x++ {
+			for y := 0; y < imgYDefault; y++ {
+				want := full.At(x, y)
+				if x >= 2*(i+1) {
+					want = white
+				}
+
+				if got := img.At(x, y); got != want {
+					t.Fatalf("[%02d] unexpected color at (%d, %d): %v != %v", i, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestWaveformDrawSweepPlayhead verifies that the Waveform.DrawSweep method
+// creates an animated GIF in which a playhead sweeps across the waveform when
+// the SweepPlayhead option is set.
+func TestWaveformDrawSweepPlayhead(t *testing.T) {
+	w, err := New(nil,
+		Paletted([]color.Color{white, black, red}),
+		Sweep(3, 100*time.Millisecond),
+		SweepPlayhead(red),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0.1, 0.2, 0.3, 0.2, 0.1}
+	anim := w.DrawSweep(values)
+	if len(anim.Image) != 3 {
+		t.Fatalf("unexpected number of frames: %d", len(anim.Image))
+	}
+
+	full := w.DrawPaletted(values)
+	for i, img := range anim.Image {
+		playhead := []int{0, 2, 4}[i]
+		for x := 0; x < len(values); x++ {
+			want := full.At(x, 0)
+			if x == playhead {
+				want = red
+			}
+
+			if got := img.At(x, 0); got != want {
+				t.Fatalf("[%02d] unexpected color at (%d, 0): %v != %v", i, x, got, want)
+			}
+		}
+	}
+}
+
+// TestGenerateSweep verifies that GenerateSweep produces an animated GIF from
+// an input audio stream, which can be encoded.
+func TestGenerateSweep(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	anim, err := GenerateSweep(bytes.NewReader(wav), Resolution(2), Sweep(2, 50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := gif.EncodeAll(buf, anim); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := gif.DecodeAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Image) != 2 || decoded.Delay[0] != 5 {
+		t.Fatalf("unexpected animated GIF: %d frames, %v delay", len(decoded.Image), decoded.Delay)
+	}
+}
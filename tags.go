@@ -0,0 +1,124 @@
+package waveform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"azul3d.org/engine/audio"
+)
+
+const (
+	// id3v2HeaderLen is the length of the header and of the optional footer of
+	// an ID3v2 tag
+	id3v2HeaderLen = 10
+
+	// apeHeaderLen is the length of the header and of the footer of an APE tag
+	apeHeaderLen = 32
+
+	// tagHeaderLen is the number of bytes needed to determine the length of
+	// any tag which may begin a stream
+	tagHeaderLen = apeHeaderLen
+)
+
+// leadingTagLen returns the length of the ID3v2 or APE tag which begins the
+// input header, or 0 if the header does not begin with a tag.  If the header is
+// too short to contain the length of the tag, or the tag is invalid,
+// ErrUnexpectedEOS or ErrInvalidData is returned.
+//
+// Tags are written before the audio data by some tagging tools, but are not
+// part of any audio format, so they hide the magic strings used to detect the
+// format of a stream.
+func leadingTagLen(header []byte) (int64, error) {
+	switch {
+	case matchMagic(header, "ID3"):
+		if len(header) < id3v2HeaderLen {
+			return 0, audio.ErrUnexpectedEOS
+		}
+
+		// The size excludes the header and footer, and is stored as a
+		// synchsafe integer, with 7 bits in each byte
+		var size int64
+		for _, b := range header[6:10] {
+			if b&0x80 != 0 {
+				return 0, audio.ErrInvalidData
+			}
+			size = size<<7 | int64(b)
+		}
+
+		size += id3v2HeaderLen
+		if header[5]&0x10 != 0 {
+			size += id3v2HeaderLen
+		}
+
+		return size, nil
+	case matchMagic(header, "APETAGEX"):
+		if len(header) < apeHeaderLen {
+			return 0, audio.ErrUnexpectedEOS
+		}
+
+		// The size excludes the header, but includes the footer, which
+		// always follows the items of the tag
+		size := int64(binary.LittleEndian.Uint32(header[12:16]))
+		if size < apeHeaderLen {
+			return 0, audio.ErrInvalidData
+		}
+
+		return size + apeHeaderLen, nil
+	}
+
+	return 0, nil
+}
+
+// skipLeadingTags discards all ID3v2 and APE tags which begin the input
+// stream, so that the format of the stream can be detected.
+func skipLeadingTags(br *bufio.Reader) error {
+	for {
+		header, _ := br.Peek(tagHeaderLen)
+		n, err := leadingTagLen(header)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+
+		if _, err := br.Discard(int(n)); err != nil {
+			return audio.ErrUnexpectedEOS
+		}
+	}
+}
+
+// peekPastTags returns up to n bytes which follow any ID3v2 and APE tags
+// at the beginning of an input stream, without consuming them, as done by
+// peekHeader.  If the tags are too long to be peeked, or extend beyond the end
+// of the stream, the beginning of the stream is returned instead.
+func peekPastTags(r io.Reader, n int) ([]byte, error) {
+	var offset int
+	for {
+		header, err := peekHeader(r, offset+maxInt(n, tagHeaderLen))
+		if err != nil {
+			// Buffered streams cannot be peeked beyond their buffer size
+			if err == bufio.ErrBufferFull && offset > 0 {
+				return peekHeader(r, n)
+			}
+
+			return nil, err
+		}
+		if len(header) < offset {
+			return peekHeader(r, n)
+		}
+
+		skip, err := leadingTagLen(header[offset:])
+		if err != nil || skip == 0 {
+			header = header[offset:]
+			if len(header) > n {
+				header = header[:n]
+			}
+
+			return header, nil
+		}
+
+		offset += int(skip)
+	}
+}
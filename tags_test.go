@@ -0,0 +1,155 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// TestLeadingTagLen verifies that leadingTagLen computes the length of ID3v2
+// and APE tags, and rejects truncated and invalid tags.
+func TestLeadingTagLen(t *testing.T) {
+	var tests = []struct {
+		header []byte
+		size   int64
+		err    error
+	}{
+		{[]byte("fLaC"), 0, nil},
+		{nil, 0, nil},
+		{testID3Tag(0, false), 10, nil},
+		{testID3Tag(300, false), 310, nil},
+		{testID3Tag(300, true), 320, nil},
+		{testID3Tag(1<<21+5, false), 1<<21 + 15, nil},
+		{testID3Tag(0, false)[:8], 0, ErrUnexpectedEOS},
+		{[]byte("ID3\x04\x00\x00\x00\x00\x80\x00"), 0, ErrInvalidData},
+		{testAPETag(nil), 64, nil},
+		{testAPETag(make([]byte, 100)), 164, nil},
+		{testAPETag(nil)[:16], 0, ErrUnexpectedEOS},
+		{append([]byte("APETAGEX\xd0\x07\x00\x00\x10"), make([]byte, 23)...), 0, ErrInvalidData},
+	}
+
+	for i, test := range tests {
+		size, err := leadingTagLen(test.header)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected leadingTagLen error: %v != %v", i, err, test.err)
+		}
+		if size != test.size {
+			t.Fatalf("[%02d] unexpected leadingTagLen size: %v != %v", i, size, test.size)
+		}
+	}
+}
+
+// TestWaveformComputeTaggedWAV verifies that ID3v2 and APE tags which precede an
+// audio stream are skipped, so that the stream is decoded normally.
+func TestWaveformComputeTaggedWAV(t *testing.T) {
+	want, err := testComputeValues(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = [][]byte{
+		testTagged(wavFile, testID3Tag(100, false)),
+		testTagged(wavFile, testID3Tag(100, true), testAPETag(make([]byte, 50))),
+		testTagged(wavFile, testAPETag(nil), testID3Tag(8000, false)),
+	}
+
+	for _, data := range tests {
+		testWaveformCompute(t, bytes.NewReader(data), nil, want, nil)
+	}
+}
+
+// TestWaveformComputeTaggedErrors verifies that streams containing only tags,
+// or truncated tags, produce appropriate errors.
+func TestWaveformComputeTaggedErrors(t *testing.T) {
+	testWaveformCompute(t, bytes.NewReader(testID3Tag(100, false)), ErrNoSamples, nil, nil)
+
+	tag := testID3Tag(100, false)
+	testWaveformCompute(t, bytes.NewReader(tag[:50]), ErrUnexpectedEOS, nil, nil)
+}
+
+// TestDetectFormatTagged verifies that DetectFormat detects the format of the
+// audio which follows any tags, without consuming the stream.
+func TestDetectFormatTagged(t *testing.T) {
+	var tests = []struct {
+		data   []byte
+		peeked bool
+		format string
+	}{
+		{testTagged(flacFile, testID3Tag(100, false)), true, FormatFLAC},
+		{testTagged(wavFile, testID3Tag(100, false), testAPETag(nil)), true, FormatWAV},
+		{testTagged(flacFile, testID3Tag(100000, false)), false, FormatFLAC},
+		// Tags larger than the buffer of a *bufio.Reader fall back to MP3
+		{testTagged(flacFile, testID3Tag(100000, false)), true, FormatMP3},
+	}
+
+	for i, test := range tests {
+		r := bytes.NewReader(test.data)
+
+		var format string
+		var err error
+		if test.peeked {
+			br := bufio.NewReader(r)
+			format, err = DetectFormat(br)
+			if br.Buffered()+r.Len() != len(test.data) {
+				t.Fatalf("[%02d] stream consumed by DetectFormat", i)
+			}
+		} else {
+			format, err = DetectFormat(r)
+			if r.Len() != len(test.data) {
+				t.Fatalf("[%02d] stream consumed by DetectFormat", i)
+			}
+		}
+		if err != nil {
+			t.Fatalf("[%02d] unexpected DetectFormat error: %v", i, err)
+		}
+
+		if format != test.format {
+			t.Fatalf("[%02d] unexpected DetectFormat format: %q != %q", i, format, test.format)
+		}
+	}
+}
+
+// testTagged prepends the input tags to an audio stream.
+func testTagged(data []byte, tags ...[]byte) []byte {
+	var b []byte
+	for _, tag := range tags {
+		b = append(b, tag...)
+	}
+
+	return append(b, data...)
+}
+
+// testID3Tag generates an ID3v2.4 tag with size bytes of padding, and an
+// optional footer.
+func testID3Tag(size int, footer bool) []byte {
+	var flags byte
+	if footer {
+		flags = 0x10
+	}
+
+	b := []byte{'I', 'D', '3', 4, 0, flags,
+		byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f),
+	}
+	b = append(b, make([]byte, size)...)
+	if footer {
+		b = append(b, '3', 'D', 'I', 4, 0, flags, b[6], b[7], b[8], b[9])
+	}
+
+	return b
+}
+
+// testAPETag generates an APEv2 tag with a header and footer, containing the
+// input item data.
+func testAPETag(items []byte) []byte {
+	header := func() []byte {
+		b := make([]byte, apeHeaderLen)
+		copy(b, "APETAGEX")
+		binary.LittleEndian.PutUint32(b[8:12], 2000)
+		binary.LittleEndian.PutUint32(b[12:16], uint32(len(items)+apeHeaderLen))
+		return b
+	}
+
+	b := append(header(), items...)
+	return append(b, header()...)
+}
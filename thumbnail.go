@@ -0,0 +1,185 @@
+package waveform
+
+import (
+	"io"
+
+	"azul3d.org/engine/audio"
+)
+
+// thumbnailWindows is the number of windows decoded by a thumbnailDecoder
+// for each slice of audio, and the inverse of the fraction of each stride
+// which is decoded as a window
+const thumbnailWindows = 8
+
+// thumbnailDecoder is an audio.Decoder which approximates the samples of a
+// seekable, integer PCM or floating point WAV stream, for use by the
+// FastThumbnail option.
+//
+// Each slice of audio is divided into strides of frames, and at the beginning
+// of each stride, a short window of frames is decoded, and the stream is then seeked to the beginning of the next stride.
+// The window is repeated to fill the remainder of the stride, so the total
+// number of samples, and therefore the width of the image, is unchanged, and
+// the magnitude of each slice of samples is approximated by its windows.
+type thumbnailDecoder struct {
+	r      io.ReadSeeker
+	format wavFormat
+
+	// Offset of the sample data in the stream, size in bytes of a single
+	// frame, and total number of frames
+	offset int64
+	frame  int64
+	frames int64
+
+	// Number of frames in each stride, and in each window
+	stride int64
+	window int64
+
+	// Samples of the current window, and buffer used to read them
+	samples []float64
+	buf     []byte
+
+	// Index of the next sample
+	pos int64
+}
+
+// newThumbnailDecoder reads the header of a WAV stream, and returns a
+// thumbnailDecoder positioned at the beginning of its sample data, which
+// decodes windows for each slice of audio, whose number of frames at the
+// stream's configuration is returned by sliceFrames.  If the stream cannot be
+// decoded by a thumbnailDecoder, the stream is returned to its original
+// position, and false is returned, so that it can be decoded in full.
+func newThumbnailDecoder(r io.ReadSeeker, sliceFrames func(config audio.Config) int64) (*thumbnailDecoder, bool, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, false, err
+	}
+
+	f, size, err := readWAVHeader(r)
+	if err != nil || f.channels == 0 || !f.pcm() && !f.float() && !f.g711() {
+		_, err := r.Seek(start, io.SeekStart)
+		return nil, false, err
+	}
+
+	offset, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Trust the length of the stream over the size of its sample data, which
+	// may be unset for streams written incrementally
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, false, err
+	}
+	if size > end-offset {
+		size = end - offset
+	}
+
+	// Divide each slice of audio into strides, each beginning with a window
+	stride := sliceFrames(audio.Config{
+		SampleRate: int(f.sampleRate),
+		Channels:   int(f.channels),
+	}) / thumbnailWindows
+	if stride < 1 {
+		stride = 1
+	}
+	window := stride / thumbnailWindows
+	if window < 1 {
+		window = 1
+	}
+
+	frame := int64(f.channels) * int64(f.bits/8)
+	return &thumbnailDecoder{
+		r:      r,
+		format: f,
+		offset: offset,
+		frame:  frame,
+		frames: size / frame,
+		stride: stride,
+		window: window,
+		buf:    make([]byte, window*frame),
+	}, true, nil
+}
+
+// Config returns the audio configuration of the WAV stream.
+func (d *thumbnailDecoder) Config() audio.Config {
+	return audio.Config{
+		SampleRate: int(d.format.sampleRate),
+		Channels:   int(d.format.channels),
+	}
+}
+
+// Read stores approximated samples in b, returning the number of samples read.
+// When the final frame is read, audio.EOS is returned along with the final
+// samples.
+func (d *thumbnailDecoder) Read(b audio.Slice) (int, error) {
+	channels := int64(d.format.channels)
+	stride := d.stride * channels
+	total := d.frames * channels
+
+	// Copy directly into a slice of float64 samples, if possible
+	dst, isFloat64 := b.(audio.Float64)
+
+	var n int
+	for n < b.Len() && d.pos < total {
+		// Decode a new window at the beginning of each stride
+		i := d.pos % stride
+		if i == 0 {
+			if err := d.decode(); err != nil {
+				return n, err
+			}
+		}
+
+		// Repeat the window to fill the remainder of the stride, copying up to
+		// the end of the window, stride, stream, or b at once
+		k := i % int64(len(d.samples))
+		run := minInt64(minInt64(int64(len(d.samples))-k, stride-i), minInt64(total-d.pos, int64(b.Len()-n)))
+		if isFloat64 {
+			copy(dst[n:], d.samples[k:k+run])
+		} else {
+			for j := int64(0); j < run; j++ {
+				b.Set(n+int(j), d.samples[k+j])
+			}
+		}
+
+		n += int(run)
+		d.pos += run
+	}
+
+	if d.pos >= total {
+		return n, audio.EOS
+	}
+
+	return n, nil
+}
+
+// decode seeks to the beginning of the next stride, and decodes its window.
+func (d *thumbnailDecoder) decode() error {
+	next := d.pos / int64(d.format.channels)
+	if _, err := d.r.Seek(d.offset+next*d.frame, io.SeekStart); err != nil {
+		return err
+	}
+
+	count := d.frames - next
+	if count > d.window {
+		count = d.window
+	}
+
+	buf := d.buf[:count*d.frame]
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return audio.ErrUnexpectedEOS
+	}
+
+	size := int(d.format.bits / 8)
+	d.samples = d.samples[:0]
+	for i := 0; i < len(buf); i += size {
+		d.samples = append(d.samples, d.format.sample(buf[i:]))
+	}
+
+	return nil
+}
+
+// fraction returns the fraction of frames which have been read.
+func (d *thumbnailDecoder) fraction() float64 {
+	return float64(d.pos) / float64(d.frames*int64(d.format.channels))
+}
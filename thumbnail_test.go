@@ -0,0 +1,99 @@
+package waveform
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+)
+
+// TestWaveformComputeFastThumbnail verifies that the Waveform.Compute method
+// approximates the values of a seekable WAV stream, producing one value for
+// each second of audio.
+func TestWaveformComputeFastThumbnail(t *testing.T) {
+	for _, file := range [][]byte{wavFile, wav8File} {
+		want, err := testComputeValues(bytes.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := testComputeValues(bytes.NewReader(file), FastThumbnail())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Determine the duration of the stream in whole seconds, rounded up
+		f, size, err := readWAVHeader(bytes.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rate := int64(f.sampleRate) * int64(f.channels) * int64(f.bits/8)
+		seconds := int((size + rate - 1) / rate)
+
+		if len(got) != seconds {
+			t.Fatalf("unexpected Compute values length: %v != %v", len(got), seconds)
+		}
+		for i := range got {
+			if math.Abs(got[i]-want[i]) > 0.01 {
+				t.Fatalf("unexpected Compute value at index %d: %v != %v", i, got[i], want[i])
+			}
+		}
+	}
+}
+
+// TestWaveformComputeFastThumbnailNotSeekable verifies that the Waveform.Compute
+// method decodes a stream in full when FastThumbnail is set, if the stream is
+// not seekable.
+func TestWaveformComputeFastThumbnailNotSeekable(t *testing.T) {
+	want, err := testComputeValues(bytes.NewReader(wavFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hide the io.Seeker implementation of the reader
+	r := struct{ io.Reader }{bytes.NewReader(wavFile)}
+	got, err := testComputeValues(r, FastThumbnail())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("unexpected Compute values length: %v != %v", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected Compute value at index %d: %v != %v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestWaveformComputeFastThumbnailFallback verifies that the Waveform.Compute
+// method decodes a seekable stream in full when FastThumbnail is set, if the
+// stream is not integer PCM WAV audio.
+func TestWaveformComputeFastThumbnailFallback(t *testing.T) {
+	want, wantErr := testComputeValues(bytes.NewReader(flacFile))
+	got, err := testComputeValues(bytes.NewReader(flacFile), FastThumbnail())
+	if err != wantErr {
+		t.Fatalf("unexpected Compute error: %v != %v", err, wantErr)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("unexpected Compute values length: %v != %v", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected Compute value at index %d: %v != %v", i, got[i], want[i])
+		}
+	}
+}
+
+// testComputeValues is a test helper which computes values from an input
+// stream, using the input options.
+func testComputeValues(r io.Reader, options ...OptionsFunc) ([]float64, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.Compute()
+}
@@ -0,0 +1,183 @@
+package waveform
+
+import (
+	"image"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Tile is a single image of a pyramid of waveform tiles, drawn by
+// GenerateTiles, so that a web page can pan and zoom a long recording
+// smoothly, by loading only the tiles which are visible, in the same way as
+// the tiles of a map.
+type Tile struct {
+	// Zoom level of the tile.  The entire recording fits within the single
+	// tile of level 0, and each following level draws twice as many values.
+	Zoom int
+
+	// Index of the tile within its zoom level, from the beginning of the
+	// recording
+	Index int
+
+	// Image of the tile
+	Image image.Image
+}
+
+// Name returns the path of the tile, relative to the root of its pyramid, of
+// the form zoom/index.ext, using the input file extension, such as png.  Tile
+// names are deterministic, so that a web page can find the tile for any zoom
+// level and time without an index.
+func (t Tile) Name(ext string) string {
+	return strconv.Itoa(t.Zoom) + "/" + strconv.Itoa(t.Index) + "." + ext
+}
+
+// TileFunc is a function which receives each tile drawn by GenerateTiles.  If
+// it returns an error, no more tiles are drawn, and the error is returned.
+type TileFunc func(t Tile) error
+
+// GenerateTiles immediately opens and reads an input audio stream, computes
+// the values required for waveform generation, and passes each tile of a
+// pyramid of waveform tiles to the input TileFunc.  The tiles are customized
+// by zero or more, variadic, OptionsFunc parameters.
+//
+// GenerateTiles is equivalent to New and Compute, followed by the DrawTiles
+// method of a Waveform struct, and handles errors in the same way as Generate.
+func GenerateTiles(r io.Reader, fn TileFunc, options ...OptionsFunc) error {
+	w, err := New(r, options...)
+	if err != nil {
+		return err
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		// Draw any partial results, if requested
+		if w.partialOnError && len(values) > 0 {
+			if derr := w.DrawTiles(values, fn); derr != nil {
+				return derr
+			}
+		}
+
+		return err
+	}
+
+	return w.DrawTiles(values, fn)
+}
+
+// DrawTiles draws a pyramid of waveform tiles from a slice of float64 values,
+// and passes each tile to the input TileFunc, from the lowest zoom level to
+// the highest, and from left to right within each level.
+//
+// Each tile is drawn as by Draw, with the width set by the TileWidth option,
+// and holds the same number of bars.  At the highest zoom level, each value is
+// drawn as a single bar, and at each lower level, each pair of adjacent bars
+// is merged into one, taking the larger value, until all values fit within a
+// single tile.  The last tile of each level is extended with silence.  Every
+// tile is drawn at the same scale, so ScaleClipping and Normalize apply to the
+// recording as a whole, and any time axis and markers are drawn at their times
+// in the recording.  The Dimensions and MaxWidth options have no effect.
+func (w *Waveform) DrawTiles(values []float64, fn TileFunc) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	// Find the number of bars in each tile, and the number of zoom levels
+	// needed to fit all values within a single tile
+	period := int(w.barWidth+w.barGap) * int(w.scaleX)
+	bars := maxInt(int(w.tileWidth)/maxInt(period, 1), 1)
+
+	var maxZoom int
+	for n := len(values); n > bars; n = (n + 1) / 2 {
+		maxZoom++
+	}
+
+	// Draw every tile using a copy of the Waveform, at the scale calculated
+	// for all values
+	c := *w
+	c.width = 0
+	c.maxWidth = 0
+	l := c.newLayout(values)
+	c.fixedScale, c.fixedPeakScale = l.imgScale, l.peakScale
+
+	stats := w.stats
+	if len(stats) != len(values) {
+		stats = nil
+	}
+
+	// Tiles only draw a time axis and markers if the values were computed by
+	// the last computation
+	m := w.metadata
+	timed := m.SampleRate > 0 && m.values() == len(values)
+
+	for zoom := 0; zoom <= maxZoom; zoom++ {
+		factor := 1 << uint(maxZoom-zoom)
+		zv, zs := mergeValues(values, stats, factor)
+
+		for index := 0; index*bars < len(zv); index++ {
+			// Extend the last tile with silence
+			lo, hi := index*bars, minInt((index+1)*bars, len(zv))
+			tv := make([]float64, bars)
+			copy(tv, zv[lo:hi])
+
+			t := c
+			t.stats = nil
+			if zs != nil {
+				t.stats = make([]sliceStats, bars)
+				copy(t.stats, zs[lo:hi])
+			}
+
+			t.metadata = Metadata{}
+			if timed {
+				slice := m.SliceSamples * int64(factor)
+				start := m.StartSample + int64(lo)*slice
+				t.metadata = Metadata{
+					SampleRate:   m.SampleRate,
+					Channels:     m.Channels,
+					SliceSamples: slice,
+					StartSample:  start,
+					EndSample:    start + int64(bars)*slice,
+					TotalSamples: m.TotalSamples,
+					Markers:      m.Markers,
+					Tracks:       m.Tracks,
+				}
+			}
+
+			if err := fn(Tile{Zoom: zoom, Index: index, Image: t.Draw(tv)}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeValues returns the input values, and any statistics which correspond
+// to them, with each consecutive group of the input number of values merged
+// into one, taking the largest value of each group.  The last group may be
+// smaller than the others.
+func mergeValues(values []float64, stats []sliceStats, factor int) ([]float64, []sliceStats) {
+	if factor == 1 {
+		return values, stats
+	}
+
+	n := (len(values) + factor - 1) / factor
+	out := make([]float64, n)
+	var outStats []sliceStats
+	if stats != nil {
+		outStats = make([]sliceStats, n)
+	}
+
+	for i := range out {
+		lo, hi := i*factor, minInt((i+1)*factor, len(values))
+		out[i] = values[lo]
+		for _, v := range values[lo+1 : hi] {
+			out[i] = math.Max(out[i], v)
+		}
+
+		if stats != nil {
+			outStats[i] = resampleStats(stats[lo:hi], 1)[0]
+		}
+	}
+
+	return out, outStats
+}
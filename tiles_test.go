@@ -0,0 +1,100 @@
+package waveform
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"reflect"
+	"testing"
+)
+
+// TestWaveformDrawTiles verifies that the Waveform.DrawTiles method draws
+// every tile of each zoom level, in order, with the same width, and that the
+// tiles of the highest zoom level match the image drawn from all values, at
+// the same scale.
+func TestWaveformDrawTiles(t *testing.T) {
+	values := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.05, 0.1, 0.15, 0.2, 0.25}
+
+	w, err := New(nil, TileWidth(4), Normalize())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var tiles []Tile
+	err = w.DrawTiles(values, func(tile Tile) error {
+		names = append(names, tile.Name("png"))
+		tiles = append(tiles, tile)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"0/0.png", "1/0.png", "1/1.png", "2/0.png", "2/1.png", "2/2.png"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("unexpected tiles:\n- got: %v\n-want: %v", names, want)
+	}
+
+	for i, tile := range tiles {
+		if b := tile.Image.Bounds(); b.Dx() != 4 || b.Dy() != imgYDefault {
+			t.Fatalf("[%02d] unexpected tile dimensions: %dx%d", i, b.Dx(), b.Dy())
+		}
+	}
+
+	// The quiet middle tile is drawn at the scale of the loudest value, rather
+	// than normalized on its own
+	full := w.Draw(values).(*image.RGBA)
+	for _, tile := range tiles[3:] {
+		img := tile.Image.(*image.RGBA)
+		for x := 0; x < 4 && tile.Index*4+x < len(values); x++ {
+			for y := 0; y < imgYDefault; y++ {
+				if got, want := img.At(x, y), full.At(tile.Index*4+x, y); got != want {
+					t.Fatalf("tile %s: unexpected color at (%d, %d): %v != %v", tile.Name("png"), x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestWaveformDrawTilesError verifies that the Waveform.DrawTiles method stops
+// drawing tiles once the TileFunc returns an error, and returns it.
+func TestWaveformDrawTilesError(t *testing.T) {
+	w, err := New(nil, TileWidth(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errStop := errors.New("stop")
+	var n int
+	err = w.DrawTiles([]float64{0.1, 0.2, 0.3}, func(Tile) error {
+		n++
+		return errStop
+	})
+	if err != errStop {
+		t.Fatalf("unexpected error: %v != %v", err, errStop)
+	}
+	if n != 1 {
+		t.Fatalf("unexpected number of tiles: %d", n)
+	}
+}
+
+// TestGenerateTiles verifies that GenerateTiles draws a pyramid of tiles from
+// an input audio stream.
+func TestGenerateTiles(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+
+	var names []string
+	err := GenerateTiles(bytes.NewReader(wav), func(tile Tile) error {
+		names = append(names, tile.Name("png"))
+		return nil
+	}, Resolution(4), TileWidth(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"0/0.png", "1/0.png", "1/1.png"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("unexpected tiles:\n- got: %v\n-want: %v", names, want)
+	}
+}
@@ -0,0 +1,480 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image"
+	"io"
+	"time"
+)
+
+// valuesVersion is the version of the binary and JSON encodings of Values
+// written by this package.  It is incremented whenever either encoding
+// changes, so that stored values written by older versions can be detected.
+const valuesVersion = 1
+
+// valuesMagic identifies the binary encoding of Values.
+const valuesMagic = "WFV"
+
+var (
+	// ErrValuesFormat is returned when encoded Values are invalid or corrupt.
+	ErrValuesFormat = errors.New("waveform: invalid encoded values")
+
+	// ErrValuesVersion is returned when encoded Values were written by an
+	// unsupported version of the encoding.
+	ErrValuesVersion = errors.New("waveform: unsupported encoded values version")
+)
+
+// Values stores the values computed from an input audio stream, along with
+// the statistics and Metadata retained by the computation, so that any number
+// of waveform images can be rendered from them, using different options, such
+// as colors, scales, or styles, without reading the audio again.
+type Values struct {
+	// Values computed by the SampleReduceFunc, one for each slice of audio
+	Values []float64
+
+	// Metadata describing the audio from which the values were computed
+	Metadata Metadata
+
+	// Statistics of each slice of audio, and the values and statistics of
+	// each channel if the Separate channel mode was set
+	stats         []sliceStats
+	channelValues [][]float64
+	channelStats  [][]sliceStats
+}
+
+// Compute immediately opens and reads an input audio stream, and computes the
+// values required for waveform generation, which are customized by zero or
+// more, variadic, OptionsFunc parameters, such as Resolution, SampleFunction,
+// and Channels.  The Render method of the returned Values draws waveform
+// images from them.
+//
+// Unlike the Compute method of a Waveform struct, the statistics of each slice
+// of audio are always retained, so that images may be rendered using options
+// which require them, such as DualEnvelope and MinMaxEnvelope, even if those
+// options were not used to compute the values.
+//
+// If any error occurs, nil Values are returned along with the error, unless
+// the PartialOnError option is set and some values were computed.
+func Compute(r io.Reader, options ...OptionsFunc) (*Values, error) {
+	w, err := New(r, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	w.retainStats = true
+	values, err := w.Compute()
+	if err != nil {
+		// Retain any partial results, if requested
+		if w.partialOnError && len(values) > 0 {
+			return w.newValues(values), err
+		}
+
+		return nil, err
+	}
+
+	return w.newValues(values), nil
+}
+
+// newValues creates Values from the input computed values, and the statistics
+// and Metadata retained by the computation.
+func (w *Waveform) newValues(values []float64) *Values {
+	return &Values{
+		Values:        values,
+		Metadata:      w.metadata,
+		stats:         w.stats,
+		channelValues: w.channelValues,
+		channelStats:  w.channelStats,
+	}
+}
+
+// Render creates a new image.Image from the values, which is customized by
+// zero or more, variadic, OptionsFunc parameters, in the same way as Generate.
+// Options which only affect how values are computed, such as Resolution and
+// SampleFunction, have no effect.  If the Separate channel mode was set when
+// the values were computed, and is also set when rendering, the waveform of
+// each channel is drawn.
+//
+// Render may be called any number of times, and is safe for concurrent use,
+// as the values are never modified.  If any option is invalid, a nil image is
+// returned along with its error.
+func (v *Values) Render(options ...OptionsFunc) (image.Image, error) {
+	w, err := New(nil, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	w.metadata = v.Metadata
+	w.stats = v.stats
+	w.channelValues = v.channelValues
+	w.channelStats = v.channelStats
+
+	return w.drawValues(v.Values), nil
+}
+
+// Flags which indicate the optional sections of the binary encoding of Values.
+const (
+	valuesHasStats = 1 << iota
+	valuesHasChannelStats
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, encoding the values,
+// Metadata, and any statistics and channel values in a compact binary form,
+// which may be stored, such as in a database, and rendered later.
+//
+// The encoding begins with the bytes "WFV" and a version number, and all
+// numbers are little-endian.  Values and statistics are stored as float64, so
+// that they are decoded exactly.
+func (v *Values) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(valuesMagic)
+	buf.WriteByte(valuesVersion)
+
+	var flags byte
+	if v.stats != nil {
+		flags |= valuesHasStats
+	}
+	if v.channelStats != nil {
+		flags |= valuesHasChannelStats
+	}
+	buf.WriteByte(flags)
+
+	m := v.Metadata
+	write := func(data interface{}) {
+		binary.Write(buf, binary.LittleEndian, data)
+	}
+	write([]int64{int64(m.SampleRate), int64(m.Channels), m.SliceSamples, m.StartSample, m.EndSample, m.TotalSamples})
+
+	write(uint32(len(m.Markers)))
+	for _, d := range m.Markers {
+		write(int64(d))
+	}
+	write(uint32(len(m.Tracks)))
+	for _, t := range m.Tracks {
+		write([]int64{int64(t.Number), t.StartSample, int64(t.Start)})
+	}
+
+	write(uint32(len(v.Values)))
+	write(v.Values)
+	if v.stats != nil {
+		writeStats(buf, v.stats)
+	}
+
+	write(uint32(len(v.channelValues)))
+	for c, values := range v.channelValues {
+		write(uint32(len(values)))
+		write(values)
+		if v.channelStats != nil {
+			writeStats(buf, v.channelStats[c])
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeStats writes the statistics of each slice of audio to buf, in the
+// binary encoding of Values.  The number of statistics is the number of values
+// which precede them.
+func writeStats(buf *bytes.Buffer, stats []sliceStats) {
+	for _, s := range stats {
+		binary.Write(buf, binary.LittleEndian, []float64{s.peak, s.rms, s.min, s.max})
+	}
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding Values
+// encoded by MarshalBinary.  If the encoding is invalid, ErrValuesFormat is
+// returned, and if it was written by an unsupported version, ErrValuesVersion
+// is returned.
+func (v *Values) UnmarshalBinary(data []byte) error {
+	if len(data) < len(valuesMagic)+2 || string(data[:len(valuesMagic)]) != valuesMagic {
+		return ErrValuesFormat
+	}
+	if data[len(valuesMagic)] != valuesVersion {
+		return ErrValuesVersion
+	}
+	flags := data[len(valuesMagic)+1]
+
+	r := bytes.NewReader(data[len(valuesMagic)+2:])
+	var err error
+	read := func(data interface{}) {
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, data)
+		}
+	}
+
+	// count reads the number of elements which follow, each of the input
+	// size in bytes, and verifies that they fit in the remaining data, so
+	// that corrupt counts never cause large allocations
+	count := func(size int) int {
+		var n uint32
+		read(&n)
+		if err == nil && int64(n)*int64(size) > int64(r.Len()) {
+			err = ErrValuesFormat
+		}
+		if err != nil {
+			return 0
+		}
+
+		return int(n)
+	}
+
+	var out Values
+	m := make([]int64, 6)
+	read(m)
+	out.Metadata = Metadata{
+		SampleRate:   int(m[0]),
+		Channels:     int(m[1]),
+		SliceSamples: m[2],
+		StartSample:  m[3],
+		EndSample:    m[4],
+		TotalSamples: m[5],
+	}
+
+	if n := count(8); n > 0 {
+		markers := make([]int64, n)
+		read(markers)
+		for _, d := range markers {
+			out.Metadata.Markers = append(out.Metadata.Markers, time.Duration(d))
+		}
+	}
+	if n := count(24); n > 0 {
+		for i := 0; i < n; i++ {
+			t := make([]int64, 3)
+			read(t)
+			out.Metadata.Tracks = append(out.Metadata.Tracks, Track{
+				Number:      int(t[0]),
+				StartSample: t[1],
+				Start:       time.Duration(t[2]),
+			})
+		}
+	}
+
+	// readValues reads a count of values, the values, and their statistics,
+	// if present
+	readValues := func(hasStats bool) ([]float64, []sliceStats) {
+		values := make([]float64, count(8))
+		read(values)
+		if !hasStats {
+			return values, nil
+		}
+
+		stats := make([]sliceStats, len(values))
+		for i := range stats {
+			s := make([]float64, 4)
+			read(s)
+			stats[i] = sliceStats{peak: s[0], rms: s[1], min: s[2], max: s[3]}
+		}
+
+		return values, stats
+	}
+
+	out.Values, out.stats = readValues(flags&valuesHasStats != 0)
+	if n := count(4); n > 0 {
+		out.channelValues = make([][]float64, n)
+		if flags&valuesHasChannelStats != 0 {
+			out.channelStats = make([][]sliceStats, n)
+		}
+
+		for c := range out.channelValues {
+			values, stats := readValues(flags&valuesHasChannelStats != 0)
+			out.channelValues[c] = values
+			if out.channelStats != nil {
+				out.channelStats[c] = stats
+			}
+		}
+	}
+
+	if err != nil {
+		return ErrValuesFormat
+	}
+	if r.Len() > 0 {
+		return ErrValuesFormat
+	}
+
+	*v = out
+	return nil
+}
+
+// jsonValues is the JSON encoding of Values.
+type jsonValues struct {
+	Version  int           `json:"version"`
+	Values   []float64     `json:"values"`
+	Stats    []jsonStats   `json:"stats,omitempty"`
+	Channels []jsonChannel `json:"channels,omitempty"`
+	Metadata jsonMetadata  `json:"metadata"`
+}
+
+// jsonChannel is the JSON encoding of the values and statistics of a single
+// channel.
+type jsonChannel struct {
+	Values []float64   `json:"values"`
+	Stats  []jsonStats `json:"stats,omitempty"`
+}
+
+// jsonStats is the JSON encoding of the statistics of a single slice of audio.
+type jsonStats struct {
+	Peak float64 `json:"peak"`
+	RMS  float64 `json:"rms"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+}
+
+// jsonMetadata is the JSON encoding of Metadata.  Timestamps are encoded in
+// nanoseconds.
+type jsonMetadata struct {
+	SampleRate   int         `json:"sampleRate"`
+	Channels     int         `json:"channels"`
+	SliceSamples int64       `json:"sliceSamples"`
+	StartSample  int64       `json:"startSample"`
+	EndSample    int64       `json:"endSample"`
+	TotalSamples int64       `json:"totalSamples"`
+	Markers      []int64     `json:"markers,omitempty"`
+	Tracks       []jsonTrack `json:"tracks,omitempty"`
+}
+
+// jsonTrack is the JSON encoding of a Track.
+type jsonTrack struct {
+	Number      int   `json:"number"`
+	StartSample int64 `json:"startSample"`
+	Start       int64 `json:"start"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the values, Metadata, and
+// any statistics and channel values as a JSON object, along with the version
+// of the encoding, which may be shipped to clients, or stored and rendered
+// later.
+func (v *Values) MarshalJSON() ([]byte, error) {
+	m := v.Metadata
+	out := jsonValues{
+		Version: valuesVersion,
+		Values:  v.Values,
+		Stats:   toJSONStats(v.stats),
+		Metadata: jsonMetadata{
+			SampleRate:   m.SampleRate,
+			Channels:     m.Channels,
+			SliceSamples: m.SliceSamples,
+			StartSample:  m.StartSample,
+			EndSample:    m.EndSample,
+			TotalSamples: m.TotalSamples,
+		},
+	}
+
+	for _, d := range m.Markers {
+		out.Metadata.Markers = append(out.Metadata.Markers, int64(d))
+	}
+	for _, t := range m.Tracks {
+		out.Metadata.Tracks = append(out.Metadata.Tracks, jsonTrack{
+			Number:      t.Number,
+			StartSample: t.StartSample,
+			Start:       int64(t.Start),
+		})
+	}
+
+	for c, values := range v.channelValues {
+		ch := jsonChannel{Values: values}
+		if v.channelStats != nil {
+			ch.Stats = toJSONStats(v.channelStats[c])
+		}
+		out.Channels = append(out.Channels, ch)
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding Values encoded by
+// MarshalJSON.  If the encoding was written by an unsupported version,
+// ErrValuesVersion is returned, and if any statistics do not correspond to
+// their values, ErrValuesFormat is returned.
+func (v *Values) UnmarshalJSON(data []byte) error {
+	var in jsonValues
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.Version != valuesVersion {
+		return ErrValuesVersion
+	}
+
+	stats, ok := fromJSONStats(in.Stats, in.Values)
+	if !ok {
+		return ErrValuesFormat
+	}
+
+	m := in.Metadata
+	out := Values{
+		Values: in.Values,
+		Metadata: Metadata{
+			SampleRate:   m.SampleRate,
+			Channels:     m.Channels,
+			SliceSamples: m.SliceSamples,
+			StartSample:  m.StartSample,
+			EndSample:    m.EndSample,
+			TotalSamples: m.TotalSamples,
+		},
+		stats: stats,
+	}
+
+	for _, d := range m.Markers {
+		out.Metadata.Markers = append(out.Metadata.Markers, time.Duration(d))
+	}
+	for _, t := range m.Tracks {
+		out.Metadata.Tracks = append(out.Metadata.Tracks, Track{
+			Number:      t.Number,
+			StartSample: t.StartSample,
+			Start:       time.Duration(t.Start),
+		})
+	}
+
+	for _, ch := range in.Channels {
+		stats, ok := fromJSONStats(ch.Stats, ch.Values)
+		if !ok {
+			return ErrValuesFormat
+		}
+
+		out.channelValues = append(out.channelValues, ch.Values)
+		if stats != nil {
+			out.channelStats = append(out.channelStats, stats)
+		}
+	}
+
+	// Statistics are retained for all channels, or none
+	if out.channelStats != nil && len(out.channelStats) != len(out.channelValues) {
+		return ErrValuesFormat
+	}
+
+	*v = out
+	return nil
+}
+
+// toJSONStats converts statistics to their JSON encoding.
+func toJSONStats(stats []sliceStats) []jsonStats {
+	if stats == nil {
+		return nil
+	}
+
+	out := make([]jsonStats, len(stats))
+	for i, s := range stats {
+		out[i] = jsonStats{Peak: s.peak, RMS: s.rms, Min: s.min, Max: s.max}
+	}
+
+	return out
+}
+
+// fromJSONStats converts statistics from their JSON encoding, and reports
+// whether there are either no statistics, or one for each value.
+func fromJSONStats(in []jsonStats, values []float64) ([]sliceStats, bool) {
+	if in == nil {
+		return nil, true
+	}
+	if len(in) != len(values) {
+		return nil, false
+	}
+
+	out := make([]sliceStats, len(in))
+	for i, s := range in {
+		out[i] = sliceStats{peak: s.Peak, rms: s.RMS, min: s.Min, max: s.Max}
+	}
+
+	return out, true
+}
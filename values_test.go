@@ -0,0 +1,156 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestComputeRender verifies that images rendered from Values are identical
+// to those generated directly from the same audio, with the same options,
+// including options which require statistics that were not requested when
+// the values were computed.
+func TestComputeRender(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	v, err := Compute(bytes.NewReader(wav), Resolution(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Values) != 4 || v.Metadata.SampleRate != 4 {
+		t.Fatalf("unexpected values: %v, %+v", v.Values, v.Metadata)
+	}
+
+	var tests = [][]OptionsFunc{
+		nil,
+		{FGColorFunction(SolidColor(red)), Scale(2, 1)},
+		{MinMaxEnvelope(), Style(AreaFill)},
+		{DualEnvelope(SolidColor(red), SolidColor(blue))},
+	}
+
+	for i, options := range tests {
+		img, err := v.Render(options...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		want, err := Generate(bytes.NewReader(wav), append([]OptionsFunc{Resolution(2)}, options...)...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if !reflect.DeepEqual(img, want) {
+			t.Fatalf("[%02d] rendered image differs from generated image", i)
+		}
+	}
+}
+
+// TestValuesRenderOptionsError verifies that the Values.Render method returns
+// the error of an invalid option, without an image.
+func TestValuesRenderOptionsError(t *testing.T) {
+	v := &Values{Values: []float64{0.1, 0.2}}
+	img, err := v.Render(BarWidth(0))
+	if err != errBarWidthZero {
+		t.Fatalf("unexpected error: %v != %v", err, errBarWidthZero)
+	}
+	if img != nil {
+		t.Fatalf("unexpected image: %v", img.Bounds())
+	}
+}
+
+// TestValuesEncoding verifies that Values are unchanged by a round trip
+// through their binary and JSON encodings, and still render the same image.
+func TestValuesEncoding(t *testing.T) {
+	mono := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	stereo := testWAV(1, 2, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128, 255, 0, 64, 192})
+
+	var tests = []struct {
+		wav     []byte
+		options []OptionsFunc
+	}{
+		{wav: mono, options: []OptionsFunc{Resolution(2)}},
+		{wav: stereo, options: []OptionsFunc{Resolution(2), Channels(Separate)}},
+	}
+
+	for i, test := range tests {
+		v, err := Compute(bytes.NewReader(test.wav), test.options...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+		v.Metadata.Markers = []time.Duration{500 * time.Millisecond}
+		v.Metadata.Tracks = []Track{{Number: 1}, {Number: 2, StartSample: 2, Start: 500 * time.Millisecond}}
+
+		want, err := v.Render(MinMaxEnvelope(), Channels(Separate))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		b, err := v.MarshalBinary()
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+		j, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		for name, decode := range map[string]func(*Values) error{
+			"binary": func(out *Values) error { return out.UnmarshalBinary(b) },
+			"json":   func(out *Values) error { return json.Unmarshal(j, out) },
+		} {
+			out := new(Values)
+			if err := decode(out); err != nil {
+				t.Fatalf("[%02d] %s: %v", i, name, err)
+			}
+			if !reflect.DeepEqual(out, v) {
+				t.Fatalf("[%02d] %s: unexpected values:\n- got: %+v\n-want: %+v", i, name, out, v)
+			}
+
+			img, err := out.Render(MinMaxEnvelope(), Channels(Separate))
+			if err != nil {
+				t.Fatalf("[%02d] %s: %v", i, name, err)
+			}
+			if !reflect.DeepEqual(img, want) {
+				t.Fatalf("[%02d] %s: rendered image differs after decoding", i, name)
+			}
+		}
+	}
+}
+
+// TestValuesEncodingErrors verifies that invalid or unsupported encodings of
+// Values are rejected.
+func TestValuesEncodingErrors(t *testing.T) {
+	v := &Values{Values: []float64{0.1, 0.2}, stats: make([]sliceStats, 2)}
+	b, err := v.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version := append([]byte(nil), b...)
+	version[3] = valuesVersion + 1
+
+	var tests = []struct {
+		data []byte
+		err  error
+	}{
+		{data: nil, err: ErrValuesFormat},
+		{data: []byte("RIFF\x01\x00"), err: ErrValuesFormat},
+		{data: version, err: ErrValuesVersion},
+		{data: b[:len(b)-1], err: ErrValuesFormat},
+		{data: append(append([]byte(nil), b...), 0), err: ErrValuesFormat},
+	}
+
+	for i, test := range tests {
+		if err := new(Values).UnmarshalBinary(test.data); err != test.err {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, test.err)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"version":2,"values":[0.1]}`), new(Values)); err != ErrValuesVersion {
+		t.Fatalf("unexpected JSON version error: %v != %v", err, ErrValuesVersion)
+	}
+	if err := json.Unmarshal([]byte(`{"version":1,"values":[0.1],"stats":[]}`), new(Values)); err != ErrValuesFormat {
+		t.Fatalf("unexpected JSON stats error: %v != %v", err, ErrValuesFormat)
+	}
+}
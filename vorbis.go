@@ -0,0 +1,81 @@
+package waveform
+
+import (
+	"io"
+
+	"azul3d.org/engine/audio"
+	"github.com/jfreymuth/oggvorbis"
+)
+
+// vorbisMagic is the magic string which begins an Ogg Vorbis stream: the
+// first Ogg page, containing a single segment with the Vorbis identification
+// header.  Other codecs in an Ogg container begin with a different header.
+const vorbisMagic = "OggS" + "????????????????????????" + "\x01vorbis"
+
+func init() {
+	// Register Ogg Vorbis streams with the audio package, so they are detected
+	// by the same format sniffing used for WAV and FLAC
+	audio.RegisterFormat(FormatVorbis, vorbisMagic, newVorbisDecoder)
+}
+
+// vorbisDecoder is an audio.Decoder which decodes an Ogg Vorbis stream to
+// float64 PCM samples, using a pure Go Vorbis decoder.
+type vorbisDecoder struct {
+	r      *oggvorbis.Reader
+	config audio.Config
+
+	// Reused buffer of decoded samples
+	buf []float32
+}
+
+// newVorbisDecoder opens a decoder on an input Ogg Vorbis stream.
+func newVorbisDecoder(r interface{}) (audio.Decoder, error) {
+	rr, ok := r.(io.Reader)
+	if !ok {
+		return nil, audio.ErrInvalidData
+	}
+
+	vr, err := oggvorbis.NewReader(rr)
+	if err != nil {
+		return nil, audio.ErrInvalidData
+	}
+
+	return &vorbisDecoder{
+		r: vr,
+		config: audio.Config{
+			SampleRate: vr.SampleRate(),
+			Channels:   vr.Channels(),
+		},
+	}, nil
+}
+
+// Config returns the audio configuration of the decoded stream.
+func (d *vorbisDecoder) Config() audio.Config {
+	return d.config
+}
+
+// Read decodes samples into b, returning the number of samples read.  On the
+// final read, audio.EOS is returned along with any remaining samples.
+func (d *vorbisDecoder) Read(b audio.Slice) (int, error) {
+	if len(d.buf) < b.Len() {
+		d.buf = make([]float32, b.Len())
+	}
+
+	var n int
+	for n < b.Len() {
+		read, err := d.r.Read(d.buf[:b.Len()-n])
+		for i := 0; i < read; i++ {
+			b.Set(n+i, float64(d.buf[i]))
+		}
+		n += read
+
+		if err == io.EOF {
+			return n, audio.EOS
+		}
+		if err != nil {
+			return n, audio.ErrInvalidData
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,447 @@
+package waveform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"azul3d.org/engine/audio"
+)
+
+const (
+	// wavFormatPCM is the WAV format tag for integer PCM samples
+	wavFormatPCM = 1
+
+	// wavFormatFloat is the WAV format tag for IEEE floating point samples
+	wavFormatFloat = 3
+
+	// wavFormatALaw and wavFormatMuLaw are the WAV format tags for 8-bit G.711
+	// A-law and mu-law samples
+	wavFormatALaw  = 6
+	wavFormatMuLaw = 7
+
+	// wavFormatExtensible is the WAV format tag for an extensible format
+	// chunk, whose sample format is identified by a subformat GUID
+	wavFormatExtensible = 0xfffe
+
+	// wavSubformatGUID is the suffix shared by the subformat GUIDs of all
+	// sample formats which also have a format tag, which is the first two
+	// bytes of the GUID
+	wavSubformatGUID = "\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71"
+
+	// wavPeekSize is the maximum number of bytes peeked from the beginning of
+	// a stream to find the format chunk of a WAV stream
+	wavPeekSize = 512
+
+	// wavDS64Size is the minimum size of the ds64 chunk of an RF64 stream,
+	// which contains the 64-bit sizes of the RIFF and data chunks, and the
+	// number of samples
+	wavDS64Size = 24
+
+	// wavUnknownSize is the size of the sample data written by encoders which
+	// cannot seek back to write its actual size, such as when writing to a pipe
+	wavUnknownSize = 0xffffffff
+
+	// wavMaxHeaderSize is the largest format or ds64 chunk which is read into
+	// memory
+	wavMaxHeaderSize = 1 << 16
+)
+
+// wavMagics are the magic strings of WAV streams, which are either RIFF
+// streams, or RF64 streams whose sizes may exceed 4GB.  BW64 streams are
+// RF64 streams as specified by ITU-R BS.2088.
+var wavMagics = []string{"RIFF????WAVE", "RF64????WAVE", "BW64????WAVE"}
+
+// wavFormat describes the format chunk of a WAV stream.
+type wavFormat struct {
+	tag        uint16
+	channels   uint16
+	sampleRate uint32
+	bits       uint16
+
+	// Whether the format tag was read from the subformat of an extensible
+	// format chunk
+	extensible bool
+}
+
+// pcm reports whether the sample format is integer PCM with a bit depth which
+// can be converted by pcmSample.
+func (f wavFormat) pcm() bool {
+	if f.tag != wavFormatPCM {
+		return false
+	}
+
+	switch f.bits {
+	case 8, 16, 24, 32:
+		return true
+	}
+
+	return false
+}
+
+// float reports whether the sample format is IEEE floating point with a bit
+// depth which can be converted by floatSample.
+func (f wavFormat) float() bool {
+	return f.tag == wavFormatFloat && (f.bits == 32 || f.bits == 64)
+}
+
+// g711 reports whether the sample format is 8-bit G.711 A-law or mu-law, which
+// can be expanded to linear PCM by alawSample or ulawSample.
+func (f wavFormat) g711() bool {
+	return (f.tag == wavFormatALaw || f.tag == wavFormatMuLaw) && f.bits == 8
+}
+
+// native reports whether the sample format is decoded by this package, rather
+// than by the audio package.
+//
+// 8-bit PCM samples are unsigned and centered at 128, unlike all other PCM
+// bit depths, and must be converted accordingly.  24-bit and 32-bit PCM
+// samples, floating point samples, G.711 samples, and all samples described by
+// an extensible format chunk are not decoded by the audio package.  Only 16-bit
+// PCM samples with a plain format chunk are left to the audio package.
+func (f wavFormat) native() bool {
+	if f.float() || f.g711() {
+		return true
+	}
+
+	return f.pcm() && (f.bits != 16 || f.extensible)
+}
+
+// sample converts a single encoded sample to a float64 value, which is in
+// [-1, 1] for integer PCM and G.711 samples.  The sample format must be either
+// integer PCM, floating point, or G.711.
+func (f wavFormat) sample(b []byte) float64 {
+	switch f.tag {
+	case wavFormatFloat:
+		return floatSample(b, f.bits)
+	case wavFormatALaw:
+		return alawSample(b[0])
+	case wavFormatMuLaw:
+		return ulawSample(b[0])
+	}
+
+	return pcmSample(b, f.bits)
+}
+
+// parseWAVFormat parses the body of a WAV format chunk.  The format tag of an
+// extensible format chunk is replaced by the tag of its subformat, if it
+// has one.  An implausible sample rate or number of channels is rejected.
+func parseWAVFormat(b []byte) (wavFormat, error) {
+	if len(b) < 16 {
+		return wavFormat{}, audio.ErrInvalidData
+	}
+
+	f := wavFormat{
+		tag:        binary.LittleEndian.Uint16(b[0:2]),
+		channels:   binary.LittleEndian.Uint16(b[2:4]),
+		sampleRate: binary.LittleEndian.Uint32(b[4:8]),
+		bits:       binary.LittleEndian.Uint16(b[14:16]),
+	}
+	if f.channels > channelsMax || f.sampleRate > sampleRateMax {
+		return wavFormat{}, audio.ErrInvalidData
+	}
+	if f.tag != wavFormatExtensible {
+		return f, nil
+	}
+
+	// The extension is 22 bytes, ending with the subformat GUID
+	if len(b) < 40 {
+		return wavFormat{}, audio.ErrInvalidData
+	}
+	if guid := b[24:40]; string(guid[2:]) == wavSubformatGUID {
+		f.tag = binary.LittleEndian.Uint16(guid[0:2])
+		f.extensible = true
+	}
+
+	return f, nil
+}
+
+// wavRIFF reports whether the input header is the header of a WAV stream, and
+// whether its chunk sizes are stored in a ds64 chunk.
+func wavRIFF(header []byte) (ok bool, rf64 bool) {
+	if len(header) < 12 || string(header[8:12]) != "WAVE" {
+		return false, false
+	}
+
+	switch string(header[0:4]) {
+	case "RIFF":
+		return true, false
+	case "RF64", "BW64":
+		return true, true
+	}
+
+	return false, false
+}
+
+// peekWAVFormat finds and parses the format chunk of a WAV stream, without
+// consuming any input.  If the stream is not a WAV stream, or the format chunk
+// is not found near the beginning of the stream, false is returned.
+func peekWAVFormat(br *bufio.Reader) (wavFormat, bool) {
+	b, _ := br.Peek(wavPeekSize)
+	if ok, _ := wavRIFF(b); !ok {
+		return wavFormat{}, false
+	}
+
+	// Iterate chunks until the format chunk is found
+	for b = b[12:]; len(b) >= 8; {
+		id := string(b[0:4])
+		size := int(binary.LittleEndian.Uint32(b[4:8]))
+		b = b[8:]
+
+		if id == "fmt " {
+			if size > len(b) {
+				return wavFormat{}, false
+			}
+
+			f, err := parseWAVFormat(b[:size])
+			return f, err == nil
+		}
+
+		// Chunks are padded to an even size
+		size += size & 1
+		if size > len(b) {
+			break
+		}
+		b = b[size:]
+	}
+
+	return wavFormat{}, false
+}
+
+// wavDecoder is an audio.Decoder which decodes the samples of a WAV stream.
+type wavDecoder struct {
+	r      io.Reader
+	format wavFormat
+
+	// Size in bytes of a single sample, and bytes of sample data remaining
+	size      int
+	remaining int64
+
+	// Whether the size of the sample data is unknown, in which case the
+	// sample data continues until the end of the stream
+	streaming bool
+
+	buf []byte
+}
+
+// peekWAVNative reports whether a WAV stream must be decoded by this package,
+// rather than by the audio package, without consuming any input.
+//
+// In addition to sample formats which the audio package does not decode, RF64
+// streams are not supported by the audio package, and neither are streams with
+// large chunks before their format chunk, such as the bext chunk of Broadcast
+// Wave (BWF) streams.  The format chunk of such streams is found by reading
+// the header.
+func peekWAVNative(br *bufio.Reader) bool {
+	header, _ := br.Peek(12)
+	ok, rf64 := wavRIFF(header)
+	if !ok {
+		return false
+	}
+	if rf64 {
+		return true
+	}
+
+	f, ok := peekWAVFormat(br)
+	return !ok || f.native()
+}
+
+// newWAVDecoder reads the header of a WAV stream, and returns a decoder which
+// is positioned at the beginning of its sample data.
+func newWAVDecoder(r io.Reader) (audio.Decoder, error) {
+	f, size, err := readWAVHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if !f.pcm() && !f.float() && !f.g711() {
+		return nil, audio.ErrInvalidData
+	}
+
+	d := &wavDecoder{
+		r:         r,
+		format:    f,
+		size:      int(f.bits / 8),
+		remaining: size,
+	}
+	if size == wavUnknownSize {
+		d.remaining = math.MaxInt64
+		d.streaming = true
+	}
+
+	return d, nil
+}
+
+// readWAVHeader reads the header of a WAV stream, up to the beginning of its
+// sample data, and returns its format and the size in bytes of its sample data.
+//
+// The size of the sample data of an RF64 stream is read from its ds64 chunk.
+// All other chunks, such as the bext chunk of a Broadcast Wave stream, are
+// skipped.
+func readWAVHeader(r io.Reader) (wavFormat, int64, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return wavFormat{}, 0, audio.ErrUnexpectedEOS
+	}
+	ok, rf64 := wavRIFF(header[:])
+	if !ok {
+		return wavFormat{}, 0, audio.ErrInvalidData
+	}
+
+	var f wavFormat
+	var haveFormat bool
+	var dataSize int64 = -1
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return wavFormat{}, 0, audio.ErrUnexpectedEOS
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size > wavMaxHeaderSize {
+				return wavFormat{}, 0, audio.ErrInvalidData
+			}
+
+			b := make([]byte, size+size&1)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return wavFormat{}, 0, audio.ErrUnexpectedEOS
+			}
+
+			parsed, err := parseWAVFormat(b)
+			if err != nil {
+				return wavFormat{}, 0, err
+			}
+			f = parsed
+			haveFormat = true
+		case "ds64":
+			if !rf64 || size < wavDS64Size || size > wavMaxHeaderSize {
+				return wavFormat{}, 0, audio.ErrInvalidData
+			}
+
+			b := make([]byte, size+size&1)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return wavFormat{}, 0, audio.ErrUnexpectedEOS
+			}
+
+			// Only the size of the data chunk is needed
+			n := binary.LittleEndian.Uint64(b[8:16])
+			if n > math.MaxInt64 {
+				return wavFormat{}, 0, audio.ErrInvalidData
+			}
+			dataSize = int64(n)
+		case "data":
+			// Sample data must be described by a format chunk
+			if !haveFormat || f.channels == 0 {
+				return wavFormat{}, 0, audio.ErrInvalidData
+			}
+
+			// The data chunk of an RF64 stream must be preceded by its ds64
+			// chunk, which stores its size
+			if rf64 && size == wavUnknownSize {
+				if dataSize < 0 {
+					return wavFormat{}, 0, audio.ErrInvalidData
+				}
+				size = dataSize
+			}
+
+			return f, size, nil
+		default:
+			// Skip all other chunks, which are padded to an even size
+			if _, err := io.CopyN(ioutil.Discard, r, size+size&1); err != nil {
+				return wavFormat{}, 0, audio.ErrUnexpectedEOS
+			}
+		}
+	}
+}
+
+// Config returns the audio configuration of the WAV stream.
+func (d *wavDecoder) Config() audio.Config {
+	return audio.Config{
+		SampleRate: int(d.format.sampleRate),
+		Channels:   int(d.format.channels),
+	}
+}
+
+// Read decodes samples into b, returning the number of samples read.  When
+// the end of the sample data is reached, audio.EOS is returned along with any
+// remaining samples.
+func (d *wavDecoder) Read(b audio.Slice) (int, error) {
+	// Read as many whole samples as fit in b, or remain in the stream
+	count := int64(b.Len())
+	if whole := d.remaining / int64(d.size); count > whole {
+		count = whole
+	}
+
+	need := int(count) * d.size
+	if cap(d.buf) < need {
+		d.buf = make([]byte, need)
+	}
+	buf := d.buf[:need]
+
+	read, err := io.ReadFull(d.r, buf)
+	d.remaining -= int64(read)
+
+	n := read / d.size
+	for i := 0; i < n; i++ {
+		b.Set(i, d.sample(buf[i*d.size:]))
+	}
+
+	// Sample data of an unknown size ends with the stream, and otherwise the
+	// stream ended before all sample data was read.  Any other error of the
+	// underlying stream, such as invalid data in a container, is returned.
+	if err != nil {
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			return n, err
+		}
+		if d.streaming {
+			return n, audio.EOS
+		}
+
+		return n, audio.ErrUnexpectedEOS
+	}
+
+	// No whole samples remain
+	if d.remaining < int64(d.size) {
+		return n, audio.EOS
+	}
+
+	return n, nil
+}
+
+// sample converts a single encoded sample to a float64 value.
+func (d *wavDecoder) sample(b []byte) float64 {
+	return d.format.sample(b)
+}
+
+// pcmSample converts a single little-endian, integer PCM sample with the input
+// bit depth to a float64 value in [-1, 1].
+func pcmSample(b []byte, bits uint16) float64 {
+	switch bits {
+	case 8:
+		// 8-bit samples are unsigned, centered at 128
+		return (float64(b[0]) - 128) / 128
+	case 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case 24:
+		// Sign extend from the most significant byte
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
+
+// floatSample converts a single little-endian, IEEE floating point sample with
+// the input bit depth to a float64 value.  Samples are already normalized, so
+// values beyond [-1, 1] are preserved rather than clipped.
+func floatSample(b []byte, bits uint16) float64 {
+	if bits == 64 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+
+	return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+}
@@ -0,0 +1,446 @@
+package waveform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"azul3d.org/engine/audio"
+)
+
+// TestPeekWAVFormat verifies that peekWAVFormat finds the format chunk of a WAV
+// stream, and reports all sample formats other than 16-bit PCM as decoded by
+// this package.
+func TestPeekWAVFormat(t *testing.T) {
+	var tests = []struct {
+		data   []byte
+		ok     bool
+		native bool
+	}{
+		// Not a WAV stream
+		{[]byte("fLaC\x00\x00\x00\x22"), false, false},
+		// 8-bit PCM
+		{testWAV(1, 1, 8000, 8, []byte{128}), true, true},
+		// 16-bit PCM
+		{testWAV(1, 2, 44100, 16, []byte{0, 0, 0, 0}), true, false},
+		// 8-bit, but not PCM
+		{testWAV(3, 1, 8000, 8, []byte{128}), true, false},
+		// 24-bit and 32-bit PCM
+		{testWAV(1, 1, 8000, 24, []byte{0, 0, 0}), true, true},
+		{testWAV(1, 1, 8000, 32, []byte{0, 0, 0, 0}), true, true},
+		// 32-bit and 64-bit floating point
+		{testWAV(3, 1, 8000, 32, []byte{0, 0, 0, 0}), true, true},
+		{testWAV(3, 1, 8000, 64, make([]byte, 8)), true, true},
+		// 16-bit PCM, in an extensible format chunk
+		{testWAVExtensible(1, 1, 8000, 16, []byte{0, 0}), true, true},
+		// 8-bit A-law and mu-law
+		{testWAV(6, 1, 8000, 8, []byte{0}), true, true},
+		{testWAV(7, 1, 8000, 8, []byte{0}), true, true},
+		// A-law with an invalid bit depth
+		{testWAV(6, 1, 8000, 16, []byte{0, 0}), true, false},
+	}
+
+	for i, test := range tests {
+		br := bufio.NewReader(bytes.NewReader(test.data))
+		f, ok := peekWAVFormat(br)
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected peekWAVFormat result: %v != %v", i, ok, test.ok)
+		}
+		if native := f.native(); native != test.native {
+			t.Fatalf("[%02d] unexpected native result: %v != %v", i, native, test.native)
+		}
+
+		// No input may be consumed
+		if br.Buffered() != len(test.data) {
+			t.Fatalf("[%02d] unexpected buffered length: %v != %v", i, br.Buffered(), len(test.data))
+		}
+	}
+}
+
+// TestWAVDecoderRead verifies that wavDecoder converts unsigned 8-bit samples
+// to signed values, and returns audio.EOS along with the final samples.
+func TestWAVDecoderRead(t *testing.T) {
+	d, err := newWAVDecoder(bytes.NewReader(testWAV(1, 1, 8000, 8, []byte{0, 64, 128, 192, 255})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := d.Config(); c.SampleRate != 8000 || c.Channels != 1 {
+		t.Fatalf("unexpected config: %v", c)
+	}
+
+	b := make(audio.Float64, 4)
+	n, err := d.Read(b)
+	if n != 4 || err != nil {
+		t.Fatalf("unexpected first read: %v, %v", n, err)
+	}
+	want := []float64{-1, -0.5, 0, 0.5}
+	for i := range want {
+		if b[i] != want[i] {
+			t.Fatalf("[%02d] unexpected sample: %v != %v", i, b[i], want[i])
+		}
+	}
+
+	n, err = d.Read(b)
+	if n != 1 || err != audio.EOS {
+		t.Fatalf("unexpected final read: %v, %v", n, err)
+	}
+	if b[0] != 127.0/128 {
+		t.Fatalf("unexpected final sample: %v != %v", b[0], 127.0/128)
+	}
+}
+
+// TestPCMSample verifies that pcmSample converts little-endian, integer PCM
+// samples of each supported bit depth to values in [-1, 1].
+func TestPCMSample(t *testing.T) {
+	var tests = []struct {
+		b    []byte
+		bits uint16
+		out  float64
+	}{
+		{[]byte{0}, 8, -1},
+		{[]byte{192}, 8, 0.5},
+		{[]byte{0x00, 0x80}, 16, -1},
+		{[]byte{0x00, 0x40}, 16, 0.5},
+		{[]byte{0x00, 0x00, 0x80}, 24, -1},
+		{[]byte{0x00, 0x00, 0xc0}, 24, -0.5},
+		{[]byte{0x00, 0x00, 0x40}, 24, 0.5},
+		{[]byte{0x00, 0x00, 0x00, 0x80}, 32, -1},
+		{[]byte{0x00, 0x00, 0x00, 0x40}, 32, 0.5},
+	}
+
+	for i, test := range tests {
+		if out := pcmSample(test.b, test.bits); out != test.out {
+			t.Fatalf("[%02d] unexpected sample: %v != %v", i, out, test.out)
+		}
+	}
+}
+
+// TestFloatSample verifies that floatSample converts little-endian, IEEE
+// floating point samples of each supported bit depth, without clipping them.
+func TestFloatSample(t *testing.T) {
+	var tests = []struct {
+		v    float64
+		bits uint16
+	}{
+		{0.5, 32},
+		{-1, 32},
+		{1.5, 32},
+		{-0.25, 64},
+		{2, 64},
+	}
+
+	for i, test := range tests {
+		b := make([]byte, test.bits/8)
+		if test.bits == 64 {
+			binary.LittleEndian.PutUint64(b, math.Float64bits(test.v))
+		} else {
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(test.v)))
+		}
+
+		if out := floatSample(b, test.bits); out != test.v {
+			t.Fatalf("[%02d] unexpected sample: %v != %v", i, out, test.v)
+		}
+	}
+}
+
+// TestParseWAVFormatExtensible verifies that parseWAVFormat replaces the format
+// tag of an extensible format chunk with the tag of its subformat.
+func TestParseWAVFormatExtensible(t *testing.T) {
+	var tests = []struct {
+		data []byte
+		tag  uint16
+		err  error
+	}{
+		{testWAVFormatChunk(3, 2, 48000, 32, true), wavFormatFloat, nil},
+		{testWAVFormatChunk(1, 2, 48000, 24, true), wavFormatPCM, nil},
+		{testWAVFormatChunk(wavFormatExtensible, 2, 48000, 16, false)[:18], 0, ErrInvalidData},
+	}
+
+	for i, test := range tests {
+		f, err := parseWAVFormat(test.data)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected parseWAVFormat error: %v != %v", i, err, test.err)
+		}
+		if err != nil {
+			continue
+		}
+
+		if f.tag != test.tag || !f.extensible {
+			t.Fatalf("[%02d] unexpected format: %+v", i, f)
+		}
+	}
+
+	// An unknown subformat GUID leaves the extensible format tag in place
+	data := testWAVFormatChunk(1, 2, 48000, 16, true)
+	data[39] ^= 0xff
+
+	f, err := parseWAVFormat(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.tag != wavFormatExtensible || f.native() {
+		t.Fatalf("unexpected format for unknown subformat: %+v", f)
+	}
+}
+
+// TestWaveformComputeWAVSampleFormats verifies that WAV streams with each
+// sample format decoded by this package are normalized correctly.
+func TestWaveformComputeWAVSampleFormats(t *testing.T) {
+	var tests = []struct {
+		data   []byte
+		values []float64
+	}{
+		{testWAV(1, 1, 4, 24, bytes.Repeat([]byte{0x00, 0x00, 0x40}, 4)), []float64{0.5}},
+		{testWAV(1, 2, 2, 32, bytes.Repeat([]byte{0x00, 0x00, 0x00, 0xc0}, 4)), []float64{0.5}},
+		{testWAV(3, 1, 4, 32, testFloat32Samples(0.5, -0.5, 0.5, -0.5)), []float64{0.5}},
+		{testWAV(3, 1, 4, 32, testFloat32Samples(1.5, 1.5, 1.5, 1.5)), []float64{1.5}},
+		{testWAV(3, 1, 2, 64, testFloat64Samples(0.25, -0.25)), []float64{0.25}},
+		{testWAVExtensible(3, 1, 4, 32, testFloat32Samples(0.5, 0.5, 0.5, 0.5)), []float64{0.5}},
+		{testWAVExtensible(1, 1, 2, 16, []byte{0x00, 0x40, 0x00, 0xc0}), []float64{0.5}},
+		{testWAV(6, 1, 2, 8, []byte{0xaa, 0x2a}), []float64{32256.0 / (1 << 15)}},
+		{testWAV(7, 1, 2, 8, []byte{0x80, 0x00}), []float64{32124.0 / (1 << 15)}},
+	}
+
+	for _, test := range tests {
+		testWaveformCompute(t, bytes.NewReader(test.data), nil, test.values, nil)
+	}
+}
+
+// TestWAVDecoderReadErrUnexpectedEOS verifies that wavDecoder returns
+// ErrUnexpectedEOS when the stream ends before its declared sample data.
+func TestWAVDecoderReadErrUnexpectedEOS(t *testing.T) {
+	data := testWAV(1, 1, 8000, 8, []byte{0, 64, 128, 192})
+	d, err := newWAVDecoder(bytes.NewReader(data[:len(data)-2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := make(audio.Float64, 4)
+	n, err := d.Read(b)
+	if n != 2 || err != ErrUnexpectedEOS {
+		t.Fatalf("unexpected read: %v, %v", n, err)
+	}
+}
+
+// TestWaveformComputeWAVRF64AndBWF verifies that RF64 streams, whose sizes are
+// stored in a ds64 chunk, and Broadcast Wave streams, whose bext chunk precedes
+// their format chunk, are decoded by this package, in every PCM bit depth.
+func TestWaveformComputeWAVRF64AndBWF(t *testing.T) {
+	var tests = []struct {
+		data   []byte
+		values []float64
+	}{
+		{testRF64("RF64", 1, 1, 4, 8, []byte{0xc0, 0x40, 0xc0, 0x40}, true), []float64{0.5}},
+		{testRF64("BW64", 1, 1, 2, 16, []byte{0x00, 0x40, 0x00, 0xc0}, true), []float64{0.5}},
+		{testRF64("RF64", 3, 1, 2, 32, testFloat32Samples(0.5, -0.5), false), []float64{0.5}},
+		{testBWF(testWAV(1, 1, 2, 16, []byte{0x00, 0x40, 0x00, 0xc0})), []float64{0.5}},
+		{testBWF(testWAV(1, 1, 4, 24, bytes.Repeat([]byte{0x00, 0x00, 0x40}, 4))), []float64{0.5}},
+	}
+
+	for _, test := range tests {
+		testWaveformCompute(t, bytes.NewReader(test.data), nil, test.values, nil)
+	}
+}
+
+// TestWAVDecoderRF64ErrInvalidData verifies that newWAVDecoder returns
+// ErrInvalidData for RF64 streams without a valid ds64 chunk.
+func TestWAVDecoderRF64ErrInvalidData(t *testing.T) {
+	data := testRF64("RF64", 1, 1, 8000, 8, []byte{0, 64, 128}, true)
+
+	// Without a ds64 chunk, the size of the data chunk is unknown
+	noDS64 := append([]byte(nil), data[:12]...)
+	noDS64 = append(noDS64, data[12+8+wavDS64Size+4:]...)
+
+	// A truncated ds64 chunk
+	short := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(short[16:20], wavDS64Size-8)
+
+	for i, d := range [][]byte{noDS64, short} {
+		if _, err := newWAVDecoder(bytes.NewReader(d)); err != ErrInvalidData {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, ErrInvalidData)
+		}
+	}
+}
+
+// TestWAVDecoderLargeChunkErrInvalidData verifies that newWAVDecoder returns
+// ErrInvalidData for a format or ds64 chunk which is too large to be read into
+// memory, rather than allocating it.
+func TestWAVDecoderLargeChunkErrInvalidData(t *testing.T) {
+	format := testWAV(1, 1, 8000, 8, []byte{0, 64, 128})
+	binary.LittleEndian.PutUint32(format[16:20], 0xfffffffe)
+
+	ds64 := testRF64("RF64", 1, 1, 8000, 8, []byte{0, 64, 128}, true)
+	binary.LittleEndian.PutUint32(ds64[16:20], 0xfffffffe)
+
+	for i, d := range [][]byte{format, ds64} {
+		if _, err := newWAVDecoder(bytes.NewReader(d)); err != ErrInvalidData {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, ErrInvalidData)
+		}
+	}
+}
+
+// TestWAVDecoderImplausibleFormatErrInvalidData verifies that newWAVDecoder
+// returns ErrInvalidData for a format chunk with an implausible sample rate or
+// number of channels, before any samples are read.
+func TestWAVDecoderImplausibleFormatErrInvalidData(t *testing.T) {
+	for i, d := range [][]byte{
+		testWAV(1, channelsMax+1, 8000, 8, make([]byte, channelsMax+1)),
+		testWAV(3, 1, sampleRateMax+1, 32, make([]byte, 4)),
+		testWAV(3, 3, 200000000, 32, make([]byte, 12)),
+	} {
+		if _, err := newWAVDecoder(bytes.NewReader(d)); err != ErrInvalidData {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, ErrInvalidData)
+		}
+	}
+}
+
+// TestWAVDecoderReadUnknownSize verifies that wavDecoder reads sample data of
+// an unknown size until the end of the stream.
+func TestWAVDecoderReadUnknownSize(t *testing.T) {
+	data := testWAV(1, 1, 8000, 8, []byte{0, 64, 128, 192, 255})
+	binary.LittleEndian.PutUint32(data[40:44], wavUnknownSize)
+
+	d, err := newWAVDecoder(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := make(audio.Float64, 8)
+	n, err := d.Read(b)
+	if n != 5 || err != audio.EOS {
+		t.Fatalf("unexpected read: %v, %v", n, err)
+	}
+}
+
+// testWAVExtensible generates a WAV stream with an extensible format chunk,
+// whose subformat has the input format tag.
+func testWAVExtensible(format uint16, channels uint16, sampleRate uint32, bits uint16, data []byte) []byte {
+	fmtChunk := testWAVFormatChunk(format, channels, sampleRate, bits, true)
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(4+8+len(fmtChunk)+8+len(data)))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(len(fmtChunk)))
+	buf.Write(fmtChunk)
+
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+
+	return buf.Bytes()
+}
+
+// testRF64 generates an RF64 stream with the input magic, whose sizes are
+// stored in its ds64 chunk.  If unknown is true, the sizes of the RIFF and data
+// chunks are 0xffffffff, and otherwise they are the actual sizes.
+func testRF64(magic string, format uint16, channels uint16, sampleRate uint32, bits uint16, data []byte, unknown bool) []byte {
+	fmtChunk := testWAVFormatChunk(format, channels, sampleRate, bits, false)
+	riffSize := uint64(4 + 8 + wavDS64Size + 4 + 8 + len(fmtChunk) + 8 + len(data))
+
+	riff32, data32 := uint32(riffSize), uint32(len(data))
+	if unknown {
+		riff32, data32 = wavUnknownSize, wavUnknownSize
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(magic)
+	binary.Write(buf, binary.LittleEndian, riff32)
+	buf.WriteString("WAVE")
+
+	// RIFF and data sizes, sample count, and an empty table of other sizes
+	buf.WriteString("ds64")
+	binary.Write(buf, binary.LittleEndian, uint32(wavDS64Size+4))
+	binary.Write(buf, binary.LittleEndian, riffSize)
+	binary.Write(buf, binary.LittleEndian, uint64(len(data)))
+	binary.Write(buf, binary.LittleEndian, uint64(len(data)/int(channels*bits/8)))
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(len(fmtChunk)))
+	buf.Write(fmtChunk)
+
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, data32)
+	buf.Write(data)
+
+	return buf.Bytes()
+}
+
+// testBWF inserts a bext chunk, of the minimum size of a Broadcast Wave bext
+// chunk, before the format chunk of a WAV stream generated by testWAV.
+func testBWF(wav []byte) []byte {
+	bext := make([]byte, 602)
+	copy(bext, "Field recording")
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, binary.LittleEndian.Uint32(wav[4:8])+8+uint32(len(bext)))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("bext")
+	binary.Write(buf, binary.LittleEndian, uint32(len(bext)))
+	buf.Write(bext)
+
+	buf.Write(wav[12:])
+
+	return buf.Bytes()
+}
+
+// testWAVFormatChunk generates the body of a WAV format chunk.  If extensible
+// is true, the chunk is extensible, and its subformat has the input format tag.
+func testWAVFormatChunk(format uint16, channels uint16, sampleRate uint32, bits uint16, extensible bool) []byte {
+	blockAlign := channels * bits / 8
+
+	tag := format
+	if extensible {
+		tag = wavFormatExtensible
+	}
+
+	buf := bytes.NewBuffer(nil)
+	binary.Write(buf, binary.LittleEndian, tag)
+	binary.Write(buf, binary.LittleEndian, channels)
+	binary.Write(buf, binary.LittleEndian, sampleRate)
+	binary.Write(buf, binary.LittleEndian, sampleRate*uint32(blockAlign))
+	binary.Write(buf, binary.LittleEndian, blockAlign)
+	binary.Write(buf, binary.LittleEndian, bits)
+
+	if extensible {
+		// Extension size, valid bits, and channel mask, followed by the
+		// subformat GUID
+		binary.Write(buf, binary.LittleEndian, uint16(22))
+		binary.Write(buf, binary.LittleEndian, bits)
+		binary.Write(buf, binary.LittleEndian, uint32(0))
+		binary.Write(buf, binary.LittleEndian, format)
+		buf.WriteString(wavSubformatGUID)
+	}
+
+	return buf.Bytes()
+}
+
+// testFloat32Samples encodes the input samples as little-endian, 32-bit IEEE
+// floating point samples.
+func testFloat32Samples(samples ...float64) []byte {
+	b := make([]byte, 4*len(samples))
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(b[i*4:], math.Float32bits(float32(v)))
+	}
+
+	return b
+}
+
+// testFloat64Samples encodes the input samples as little-endian, 64-bit IEEE
+// floating point samples.
+func testFloat64Samples(samples ...float64) []byte {
+	b := make([]byte, 8*len(samples))
+	for i, v := range samples {
+		binary.LittleEndian.PutUint64(b[i*8:], math.Float64bits(v))
+	}
+
+	return b
+}
@@ -2,12 +2,19 @@
 package waveform
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"image"
 	"image/color"
+	"image/draw"
+	"image/jpeg"
 	"io"
 	"math"
+	"time"
 
 	"azul3d.org/engine/audio"
+	"golang.org/x/image/font"
 
 	// Import WAV and FLAC decoders
 	_ "azul3d.org/engine/audio/flac"
@@ -21,6 +28,23 @@ const (
 	// scaleDefault is the default scaling factor used when scaling computed
 	// value and waveform height by the output image's height
 	scaleDefault = 3.00
+
+	// clipThresholdDefault is the default peak magnitude above which a slice
+	// of audio samples is considered clipped by ClipIndicator
+	clipThresholdDefault = 0.99
+
+	// resolutionMin is the lowest resolution, of one value per 100 seconds of
+	// audio, and sliceSamplesMax is the largest number of samples per slice,
+	// which bound the memory used to compute each value
+	resolutionMin   = 0.01
+	sliceSamplesMax = 1 << 25
+
+	// sampleRateMax and channelsMax are the highest sample rate and number of
+	// channels of a plausible audio stream, well above those of any real
+	// recording, so that a corrupt or hostile header cannot request huge
+	// buffers
+	sampleRateMax = 768000 * 8
+	channelsMax   = 255
 )
 
 // Error values from azul3d/engine/audio are wrapped, so that callers do not
@@ -37,6 +61,36 @@ var (
 	// ErrUnexpectedEOS is returned when end-of-stream is encountered in the middle
 	// of a fixed-size block or data structure.
 	ErrUnexpectedEOS = audio.ErrUnexpectedEOS
+
+	// ErrNoSamples is returned when the input audio stream is empty, or is
+	// recognized but contains no audio samples to compute values from.
+	ErrNoSamples = errors.New("waveform: no audio samples in stream")
+
+	// ErrImageFormat is returned when an output image format is requested which
+	// cannot be encoded by this package.
+	ErrImageFormat = errors.New("waveform: unknown image format")
+
+	// ErrLimitExceeded is returned when an input audio stream contains more
+	// samples than permitted by the MaxSamples option.
+	ErrLimitExceeded = errors.New("waveform: audio sample limit exceeded")
+
+	// ErrUnsupportedChannels is matched by the error returned when an input
+	// audio stream contains more channels than permitted by the MaxChannels
+	// option.  The returned error is an *UnsupportedChannelsError, which
+	// reports the number of channels.
+	ErrUnsupportedChannels = errors.New("waveform: unsupported number of audio channels")
+
+	// ErrChannelRange is returned when the Channel option selects a channel
+	// which does not exist in the input audio stream.
+	ErrChannelRange = errors.New("waveform: selected audio channel does not exist")
+
+	// ErrTrackRange is returned when the TrackIndex option selects an audio
+	// track which does not exist in the input container.
+	ErrTrackRange = errors.New("waveform: selected audio track does not exist")
+
+	// ErrStreamUnsupported is returned by Stream when an option is set which
+	// requires all computed values before any column can be drawn.
+	ErrStreamUnsupported = errors.New("waveform: option cannot be used when streaming")
 )
 
 // Waveform is a struct which can be manipulated and used to generate
@@ -44,18 +98,176 @@ var (
 type Waveform struct {
 	r io.Reader
 
-	resolution uint
+	resolution float64
 	sampleFn   SampleReduceFunc
 
+	rawPCM *rawPCMFormat
+
+	resampleRate int
+	maxChannels  uint
+
+	selectChannel bool
+	channel       int
+	separate      bool
+
+	trackIndex int
+
+	execCmd *execCommand
+
 	bgColorFn ColorFunc
 	fgColorFn ColorFunc
 
 	scaleX uint
 	scaleY uint
 
+	height uint
+	width  uint
+
+	maxWidth uint
+
+	dpiX float64
+	dpiY float64
+
+	padTop    uint
+	padRight  uint
+	padBottom uint
+	padLeft   uint
+
+	barWidth  uint
+	barGap    uint
+	barRadius uint
+
+	style       DrawStyle
+	strokeWidth uint
+	dotRadius   uint
+
 	sharpness uint
+	antiAlias uint
 
 	scaleClipping bool
+	normalize     bool
+
+	silenceThreshold float64
+	noiseFloor       float64
+	invertAmplitude  bool
+
+	partialOnError bool
+
+	maxSamples      int64
+	truncateAtLimit bool
+
+	windowStart    time.Duration
+	windowDuration time.Duration
+
+	fastThumbnail bool
+
+	progressFn     ProgressFunc
+	progressTimeFn ProgressTimeFunc
+
+	dualEnvelope bool
+	peakColorFn  ColorFunc
+	rmsColorFn   ColorFunc
+
+	minMaxEnvelope bool
+	mirrored       bool
+
+	stereoSplit  bool
+	leftColorFn  ColorFunc
+	rightColorFn ColorFunc
+
+	laneSepHeight uint
+	laneSepColor  color.Color
+
+	clipColorFn   ColorFunc
+	clipThreshold float64
+
+	overlayValues []float64
+	overlayColor  color.RGBA
+
+	gammaCorrect bool
+
+	palette color.Palette
+	nrgba   bool
+
+	markerColorFn ColorFunc
+
+	axisInterval time.Duration
+	axisColor    color.Color
+	axisFace     font.Face
+	gridStep     float64
+	gridColor    color.Color
+	markers      []Marker
+
+	trimThreshold float64
+
+	sweepFrames     uint
+	sweepDelay      time.Duration
+	playheadColorFn ColorFunc
+
+	tileWidth uint
+
+	quality int
+
+	fftSize  uint
+	colormap Colormap
+
+	radialInner    uint
+	radialRotation float64
+
+	// metadata describes the audio used by the last computation
+	metadata Metadata
+
+	// stats stores additional statistics for each slice of audio samples,
+	// retained from the last computation for drawing modes which require them
+	stats []sliceStats
+
+	// channelValues and channelStats store the values and statistics of each
+	// channel, retained from the last computation when the Separate channel
+	// mode is set
+	channelValues [][]float64
+	channelStats  [][]sliceStats
+
+	// valueFn, if set, receives each computed value and its statistics as
+	// soon as it is computed, in place of retaining them
+	valueFn func(value float64, stats sliceStats) error
+
+	// ctx, if set, stops the computation once it is done
+	ctx context.Context
+
+	// retainStats indicates that statistics are collected and retained by
+	// each computation, even if no drawing mode requires them
+	retainStats bool
+
+	// sliceSamples, if set, is the number of samples per channel in each
+	// slice of audio, in place of the number set by the resolution
+	sliceSamples uint
+
+	// fixedScale and fixedPeakScale, if set, replace the scaling factors
+	// calculated by each layout, so that separate images, such as tiles, are
+	// drawn at the same scale
+	fixedScale     float64
+	fixedPeakScale float64
+}
+
+// sliceStats stores statistics computed from a single slice of audio samples,
+// in addition to the value computed by a SampleReduceFunc.
+type sliceStats struct {
+	peak float64
+	rms  float64
+
+	// Signed minimum and maximum samples
+	min float64
+	max float64
+}
+
+// newSliceStats computes the statistics of a single slice of audio samples.
+func newSliceStats(samples audio.Float64) sliceStats {
+	return sliceStats{
+		peak: PeakF64Samples(samples),
+		rms:  RMSF64Samples(samples),
+		min:  MinF64Samples(samples),
+		max:  MaxF64Samples(samples),
+	}
 }
 
 // Generate immediately opens and reads an input audio stream, computes
@@ -65,14 +277,78 @@ type Waveform struct {
 // Generate is equivalent to calling New, followed by the Compute and Draw
 // methods of a Waveform struct.  In general, Generate should only be used
 // for one-time waveform image generation.
+//
+// If any error occurs, a nil image is returned along with the error, unless
+// the PartialOnError option is set.
 func Generate(r io.Reader, options ...OptionsFunc) (image.Image, error) {
 	w, err := New(r, options...)
 	if err != nil {
 		return nil, err
 	}
 
-	values, err := w.Compute()
-	return w.Draw(values), err
+	return w.drawComputed(w.Compute())
+}
+
+// GenerateFromSamples computes the values required for waveform generation from
+// a slice of interleaved audio samples, and returns a waveform image which is
+// customized by zero or more, variadic, OptionsFunc parameters.
+//
+// Samples must be normalized to the range [-1, 1], and are interpreted using
+// the input sample rate and number of channels, so that options such as
+// Resolution behave as they do for an audio stream.  No decoder is used, so
+// this can be used with audio which is captured or synthesized, rather than
+// read from a file.
+//
+// If samples is empty, ErrNoSamples is returned.  If the sample rate or number
+// of channels is not positive, or is implausibly large, ErrInvalidData is
+// returned.
+func GenerateFromSamples(samples []float64, sampleRate int, channels int, options ...OptionsFunc) (image.Image, error) {
+	if sampleRate <= 0 || channels <= 0 {
+		return nil, ErrInvalidData
+	}
+	if len(samples) == 0 {
+		return nil, ErrNoSamples
+	}
+
+	w, err := New(nil, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	d := newSamplesDecoder(samples, sampleRate, channels)
+	return w.drawComputed(w.computeSamples(d, d.fraction))
+}
+
+// drawComputed draws the input computed values, returning the image along with
+// any error which occurred during computation.  If an error occurred, a nil
+// image is returned, unless the PartialOnError option is set and some values
+// were computed.
+func (w *Waveform) drawComputed(values []float64, err error) (image.Image, error) {
+	if err != nil {
+		// Draw any partial results, if requested
+		if w.partialOnError && len(values) > 0 {
+			return w.drawValues(values), err
+		}
+
+		return nil, err
+	}
+
+	return w.drawValues(values), nil
+}
+
+// drawValues draws the input computed values, or if the Separate channel mode
+// is set, the values of each channel retained by the same computation, split
+// about the center of the image if the StereoSplit option is set.
+func (w *Waveform) drawValues(values []float64) image.Image {
+	if w.separate && len(w.channelValues) > 0 {
+		if w.stereoSplit {
+			return w.DrawStereo()
+		}
+
+		return w.DrawChannels()
+	}
+
+	return w.Draw(values)
 }
 
 // New generates a new Waveform struct, applying any input OptionsFunc
@@ -98,11 +374,33 @@ func New(r io.Reader, options ...OptionsFunc) (*Waveform, error) {
 		scaleX: 1,
 		scaleY: 1,
 
+		// Solid bars with no gaps
+		barWidth: 1,
+		barGap:   0,
+
 		// Normal sharpness
 		sharpness: 1,
 
+		// Outlines of a single pixel
+		strokeWidth: 1,
+
 		// Do not scale clipping values
 		scaleClipping: false,
+
+		// Animate a sweep over 2 seconds
+		sweepFrames: 20,
+		sweepDelay:  100 * time.Millisecond,
+
+		// Draw tiles of 256 pixels
+		tileWidth: 256,
+
+		// Encode lossy images with the default JPEG quality
+		quality: jpeg.DefaultQuality,
+
+		// Compute spectra using windows of 1024 samples, drawn using a heat
+		// colormap
+		fftSize:  1024,
+		colormap: HeatColormap,
 	}
 
 	// Apply any input OptionsFunc on return
@@ -114,6 +412,9 @@ func New(r io.Reader, options ...OptionsFunc) (*Waveform, error) {
 // Compute is typically used once on an audio stream, to read and calculate the values
 // used for subsequent waveform generations.  Its return value can be used with Draw to
 // generate and customize multiple waveform images from a single stream.
+//
+// If the PartialOnError option is set and an error occurs while decoding, any values
+// computed before the error are returned along with the error.
 func (w *Waveform) Compute() ([]float64, error) {
 	return w.readAndComputeSamples()
 }
@@ -123,6 +424,8 @@ func (w *Waveform) Compute() ([]float64, error) {
 // Draw is typically used after a waveform has been computed one time, and a slice
 // of computed values was returned from the first computation.  Subsequent calls to
 // Draw may be used to customize a waveform using the same input values.
+//
+// If the Paletted option is set, the image is an *image.Paletted.
 func (w *Waveform) Draw(values []float64) image.Image {
 	return w.generateImage(values)
 }
@@ -131,35 +434,129 @@ func (w *Waveform) Draw(values []float64) image.Image {
 // to an input function, and returns a slice of computed values and any errors
 // which occurred during the computation.
 func (w *Waveform) readAndComputeSamples() ([]float64, error) {
-	// Validate struct members
-	// These checks are also done when applying options, but verifying them here
-	// will prevent a runtime panic if called on an empty Waveform instance.
-	if w.sampleFn == nil {
-		return nil, errSampleFunctionNil
+	// Validate struct members before reading any input
+	if err := w.validateCompute(); err != nil {
+		return nil, err
+	}
+
+	// Approximate the samples of a seekable stream, if requested and possible
+	if w.fastThumbnail && w.rawPCM == nil {
+		if rs, ok := w.r.(io.ReadSeeker); ok {
+			d, ok, err := newThumbnailDecoder(rs, w.sliceFrames)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return w.computeSamples(d, d.fraction)
+			}
+		}
+	}
+
+	// Seek directly to the start of the window of uncompressed streams, if
+	// possible, reporting progress through the window
+	if w.windowStart > 0 && w.resampleRate == 0 && w.trackIndex == 0 {
+		if rs, ok := w.r.(io.ReadSeeker); ok {
+			d, err := w.seekWindow(rs)
+			if err != nil {
+				return nil, err
+			}
+			if d != nil {
+				return w.computeSamples(d, d.fraction)
+			}
+		}
 	}
-	if w.resolution == 0 {
-		return nil, errResolutionZero
+
+	// Count bytes read from the input stream, if progress should be reported
+	r := w.r
+	var pr *progressReader
+	if w.reportsProgress() {
+		pr = newProgressReader(r)
+		r = pr
 	}
 
 	// Open audio decoder on input stream
-	decoder, _, err := audio.NewDecoder(w.r)
+	br := bufio.NewReader(r)
+	decoder, err := w.openDecoder(br)
 	if err != nil {
-		// Unknown format
-		if err == audio.ErrFormat {
-			return nil, ErrFormat
-		}
+		return nil, err
+	}
 
-		// Invalid data
-		if err == audio.ErrInvalidData {
-			return nil, ErrInvalidData
+	// Report progress by bytes consumed from the input stream, if needed
+	var progress func() float64
+	if pr != nil {
+		progress = func() float64 {
+			return pr.fraction(br)
 		}
+	}
 
-		// Unexpected end-of-stream
-		if err == audio.ErrUnexpectedEOS {
-			return nil, ErrUnexpectedEOS
+	return w.computeSamples(decoder, progress)
+}
+
+// validateCompute verifies the struct members required to compute values.
+// These checks are also done when applying options, but verifying them here
+// will prevent a runtime panic if called on an empty Waveform instance.
+func (w *Waveform) validateCompute() error {
+	if w.sampleFn == nil {
+		return errSampleFunctionNil
+	}
+	if w.resolution <= 0 && w.sliceSamples == 0 {
+		return errResolutionZero
+	}
+
+	return nil
+}
+
+// computeSamples reads all samples from an audio decoder, and returns a slice
+// of computed values and any errors which occurred during the computation.
+// If progress is not nil, it is used to report the fraction of samples read
+// to the ProgressFunc, if one is set.
+//
+// All channels are down-mixed to mono by averaging each frame of samples,
+// before any values are computed, unless a single channel is selected.
+func (w *Waveform) computeSamples(decoder audio.Decoder, progress func() float64) ([]float64, error) {
+	// Progress is only reported if requested
+	if !w.reportsProgress() {
+		progress = nil
+	}
+
+	// Retain any markers and tracks embedded in the stream
+	var markers []time.Duration
+	var tracks []Track
+	if d, ok := decoder.(*markerDecoder); ok {
+		markers = d.markers
+		tracks = d.tracks
+	}
+
+	// Multiple streams are marked at each boundary as they are read
+	multi, _ := decoder.(*multiDecoder)
+
+	// Stop any external decoder process when the computation returns, such as
+	// when it is cancelled, or stops at a sample limit or the end of a window,
+	// before the process has written its entire stream
+	if c, ok := decoder.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	// Resample decoded samples to the target sample rate, if needed
+	if w.resampleRate > 0 && decoder.Config().SampleRate != w.resampleRate {
+		decoder = newResampleDecoder(decoder, w.resampleRate)
+	}
+
+	// Decode only the samples within the window, if set, reporting progress
+	// through the window if its length is known
+	window, _ := decoder.(*windowDecoder)
+	if window == nil && w.windowed() {
+		window = w.newWindowDecoder(decoder, 0)
+		decoder = window
+		if progress != nil && window.length > 0 {
+			progress = window.fraction
 		}
+	}
 
-		// All other errors
+	// Check for an invalid or unsupported number of channels before reading
+	// any samples
+	config := decoder.Config()
+	if err := w.checkChannels(config); err != nil {
 		return nil, err
 	}
 
@@ -167,141 +564,1228 @@ func (w *Waveform) readAndComputeSamples() ([]float64, error) {
 	// slice of audio samples
 	var computed []float64
 
-	// Track the current computed value
-	var value float64
+	// stats is a slice of additional statistics from each slice of audio samples,
+	// only collected when required by a drawing mode
+	var stats []sliceStats
 
-	// samples is a slice of float64 audio samples, used to store decoded values
-	config := decoder.Config()
-	samples := make(audio.Float64, uint(config.SampleRate*config.Channels)/w.resolution)
-	for {
-		// Decode at specified resolution from options
-		// On any error other than end-of-stream, return
-		_, err := decoder.Read(samples)
-		if err != nil && err != audio.EOS {
-			return nil, err
-		}
+	// Track the total number of samples read, and of samples per channel used
+	// to compute values
+	var total int
+	var frames int64
 
-		// Apply SampleReduceFunc over float64 audio samples
-		value = w.sampleFn(samples)
+	// samples is a slice of float64 audio samples, used to store decoded values,
+	// and mono stores the down-mixed samples
+	samples, mono := w.sliceBuffers(config)
 
-		// Store computed value
-		computed = append(computed, value)
+	// Additional statistics are only collected when required by a drawing mode
+	needStats := w.dualEnvelope || w.minMaxEnvelope || w.clipColorFn != nil || w.retainStats
 
-		// On end of stream, stop reading values
-		if err == audio.EOS {
-			break
+	// channels and channelStats are the values and statistics computed from
+	// each channel separately, only when the Separate channel mode is set
+	var channels [][]float64
+	var channelStats [][]sliceStats
+	if w.separate && w.valueFn == nil {
+		channels = make([][]float64, config.Channels)
+		if needStats {
+			channelStats = make([][]sliceStats, config.Channels)
 		}
 	}
 
-	// Return slice of computed values
-	return computed, nil
-}
+	// finish removes any silence from the computed values, and retains their
+	// statistics and metadata for drawing, returning the remaining values
+	finish := func() []float64 {
+		sliceFrames := int64(len(mono))
+		first, last := 0, len(computed)
 
-// generateImage takes a slice of computed values and generates
-// a waveform image from the input.
-func (w *Waveform) generateImage(computed []float64) image.Image {
-	// Store integer scale values
-	intScaleX := int(w.scaleX)
-	intScaleY := int(w.scaleY)
+		// Offsets are relative to the beginning of the stream, rather than
+		// the start of any window
+		var offset int64
+		if window != nil {
+			offset = window.start
+		}
+		if w.trimThreshold > 0 {
+			computed, stats, first, last = w.trimSilence(computed, stats)
+		}
 
-	// Calculate maximum n, x, y, where:
-	//  - n: number of computed values
-	//  - x: number of pixels on X-axis
-	//  - y: number of pixels on Y-axis
-	maxN := len(computed)
-	maxX := maxN * intScaleX
-	maxY := imgYDefault * intScaleY
+		if multi != nil {
+			markers = multi.boundaries
+		}
 
-	// Create output, rectangular image
-	img := image.NewRGBA(image.Rect(0, 0, maxX, maxY))
-	bounds := img.Bounds()
+		// Values of each channel span the same slices as the down-mixed values
+		for c := range channels {
+			channels[c] = channels[c][first:last]
+			if channelStats != nil {
+				channelStats[c] = channelStats[c][first:last]
+			}
+		}
 
-	// Calculate halfway point of Y-axis for image
-	imgHalfY := bounds.Max.Y / 2
+		w.stats = stats
+		w.channelValues = channels
+		w.channelStats = channelStats
+		w.metadata = Metadata{
+			SampleRate:   config.SampleRate,
+			Channels:     config.Channels,
+			SliceSamples: sliceFrames,
+			StartSample:  offset + int64(first)*sliceFrames,
+			EndSample:    offset + minInt64(int64(last)*sliceFrames, frames),
+			TotalSamples: offset + frames,
+			Markers:      markers,
+			Tracks:       tracks,
+		}
 
-	// Calculate a peak value used for smoothing scaled X-axis images
-	peak := int(math.Ceil(float64(w.scaleX)) / 2)
+		return computed
+	}
 
-	// Calculate scaling factor, based upon maximum value computed by a SampleReduceFunc.
-	// If option ScaleClipping is true, when maximum value is above certain thresholds
-	// the scaling factor is reduced to show an accurate waveform with less clipping.
-	imgScale := scaleDefault
-	if w.scaleClipping {
-		// Find maximum value from input slice
-		var maxValue float64
-		for _, c := range computed {
-			if c > maxValue {
-				maxValue = c
+	// computeSlice applies the SampleReduceFunc over a slice of float64 audio
+	// samples, storing the computed value and any additional statistics, or
+	// passing them to valueFn if it is set
+	computeSlice := func(samples audio.Float64) error {
+		// Compute values from each channel separately, before down-mixing
+		for c := range channels {
+			s := extractChannel(mono, samples, config.Channels, c)
+			channels[c] = append(channels[c], w.sampleFn(s))
+			if needStats {
+				channelStats[c] = append(channelStats[c], newSliceStats(s))
 			}
 		}
 
-		// For each 0.05 maximum increment at 0.30 and above, reduce the scaling
-		// factor by 0.25.  This is a rough estimate and may be tweaked in the future.
-		for i := 0.30; i < maxValue; i += 0.05 {
-			imgScale -= 0.25
+		// Down-mix all channels to mono, or use only the selected channel
+		if w.selectChannel {
+			samples = extractChannel(mono, samples, config.Channels, w.channel)
+		} else {
+			samples = downmix(mono, samples, config.Channels)
+		}
+		frames += int64(len(samples))
+
+		value := w.sampleFn(samples)
+
+		// Collect additional statistics, if needed
+		var s sliceStats
+		if needStats {
+			s = newSliceStats(samples)
+		}
+
+		if w.valueFn != nil {
+			return w.valueFn(value, s)
+		}
+
+		// Store computed value, and any additional statistics
+		computed = append(computed, value)
+		if needStats {
+			stats = append(stats, s)
 		}
+
+		return nil
 	}
 
-	// Values to be used for repeated computations
-	var scaleComputed, halfScaleComputed, adjust int
-	intBoundY := int(bounds.Max.Y)
-	f64BoundY := float64(bounds.Max.Y)
-	intSharpness := int(w.sharpness)
+	// fill decodes samples until the buffer is full, or the stream ends, so
+	// that every value is computed from a whole slice of samples, regardless
+	// of how many samples each call to the decoder returns
+	fill := func() (int, error) {
+		var n int
+		for n < len(samples) {
+			read, err := decoder.Read(samples[n:])
+			n += read
+			if err != nil {
+				return n, err
+			}
 
-	// Begin iterating all computed values
-	x := 0
-	for n := range computed {
-		// Scale computed value to an integer, using the height of the image and a constant
-		// scaling factor
-		scaleComputed = int(math.Floor(computed[n] * f64BoundY * imgScale))
-
-		// Calculate the halfway point for the scaled computed value
-		halfScaleComputed = scaleComputed / 2
-
-		// Draw background color down the entire Y-axis
-		for y := 0; y < intBoundY; y++ {
-			// If X-axis is being scaled, draw background over several X coordinates
-			for i := 0; i < intScaleX; i++ {
-				img.Set(x+i, y, w.bgColorFn(n, x+i, y, maxN, maxX, maxY))
+			// Avoid looping forever on a decoder which makes no progress
+			if read == 0 {
+				break
+			}
+		}
+
+		return n, nil
+	}
+
+	for {
+		// Stop reading once the context is done, such as when a client has
+		// disconnected, returning any values computed so far if requested
+		if err := w.ctxErr(); err != nil {
+			if !w.partialOnError || len(computed) == 0 {
+				return nil, err
 			}
+
+			return finish(), err
 		}
 
-		// Iterate image coordinates on the Y-axis, generating a symmetrical waveform
-		// image above and below the center of the image
-		for y := imgHalfY - halfScaleComputed; y < scaleComputed+(imgHalfY-halfScaleComputed); y++ {
-			// If X-axis is being scaled, draw computed value over several X coordinates
-			for i := 0; i < intScaleX; i++ {
-				// When scaled, adjust computed value to be lower on either side of the peak,
-				// so that the image appears more smooth and less "blocky"
-				if i < peak {
-					// Adjust downward
-					adjust = (i - peak) * intSharpness
-				} else if i == peak {
-					// No adjustment at peak
-					adjust = 0
-				} else {
-					// Adjust downward
-					adjust = (peak - i) * intSharpness
+		// Decode at specified resolution from options
+		// On any error other than end-of-stream, return
+		n, err := fill()
+		if err != nil && err != audio.EOS {
+			// Discard all values, unless partial results were requested
+			if !w.partialOnError {
+				return nil, err
+			}
+
+			// Compute a final value from any samples decoded before the error,
+			// and return all values along with the error
+			if n > 0 {
+				if err := computeSlice(samples[:n]); err != nil {
+					return finish(), err
 				}
+			}
+
+			return finish(), err
+		}
+		// Stop decoding once the sample limit is reached, keeping only the
+		// samples within the limit
+		if w.maxSamples > 0 && int64(total+n) > w.maxSamples {
+			keep := int(w.maxSamples - int64(total))
+			if !w.truncateAtLimit && !w.partialOnError {
+				return nil, ErrLimitExceeded
+			}
 
-				// On top half of the image, invert adjustment to create symmetry between
-				// top and bottom halves
-				if y < imgHalfY {
-					adjust = -1 * adjust
+			if keep > 0 {
+				if err := computeSlice(samples[:keep]); err != nil {
+					return finish(), err
 				}
+			}
 
-				// Retrieve and apply color function at specified computed value
-				// count, and X and Y coordinates.
-				// The output color is selected using the function, and is applied to
-				// the resulting image.
-				img.Set(x+i, y+adjust, w.fgColorFn(n, x+i, y+adjust, maxN, maxX, maxY))
+			computed = finish()
+			if !w.truncateAtLimit {
+				return computed, ErrLimitExceeded
+			}
+
+			return computed, nil
+		}
+		total += n
+
+		// If no samples were decoded from the stream at all, stop before
+		// computing any values
+		if total == 0 && err == audio.EOS {
+			return nil, ErrNoSamples
+		}
+
+		// Apply SampleReduceFunc over float64 audio samples, only using those
+		// decoded by this read
+		if n > 0 {
+			// Report progress after each computed value, once more samples
+			// show that it was not the last
+			if progress != nil && len(computed) > 0 {
+				w.reportProgress(progress(), frames, config.SampleRate)
+			}
+
+			if err := computeSlice(samples[:n]); err != nil {
+				return finish(), err
 			}
 		}
 
-		// Increase X by scaling factor, to continue drawing at next loop
-		x += intScaleX
+		// On end of stream, stop reading values
+		if err == audio.EOS {
+			break
+		}
 	}
 
-	// Return generated image
-	return img
+	// Report completion, even if the stream's total length was unknown
+	if progress != nil {
+		w.reportProgress(1, frames, config.SampleRate)
+	}
+
+	// Retain statistics for drawing, and return slice of computed values
+	return finish(), nil
+}
+
+// checkChannels verifies that the sample rate and number of channels of an
+// audio stream are plausible, and that its channels are permitted by the
+// MaxChannels and Channel options.
+func (w *Waveform) checkChannels(config audio.Config) error {
+	if config.Channels <= 0 || config.Channels > channelsMax {
+		return ErrInvalidData
+	}
+	if config.SampleRate <= 0 || config.SampleRate > sampleRateMax {
+		return ErrInvalidData
+	}
+	if w.maxChannels > 0 && config.Channels > int(w.maxChannels) {
+		return &UnsupportedChannelsError{Channels: config.Channels}
+	}
+	if w.selectChannel && w.channel >= config.Channels {
+		return ErrChannelRange
+	}
+
+	return nil
+}
+
+// sliceBuffers returns a buffer for a single slice of decoded audio samples,
+// and a buffer for the same slice once down-mixed to mono.  The length of the
+// slice is a whole number of frames, so that no frame is split across two
+// slices when down-mixed.
+func (w *Waveform) sliceBuffers(config audio.Config) (audio.Float64, audio.Float64) {
+	frames := w.sliceFrames(config)
+
+	return make(audio.Float64, frames*int64(config.Channels)), make(audio.Float64, frames)
+}
+
+// sliceFrames returns the number of frames in each slice of audio of the input
+// configuration, which is set by SliceSamples, or otherwise by the resolution.
+// Each slice contains at least one frame.
+//
+// A slice contains no more than sliceSamplesMax samples, regardless of how it
+// is set.  Samples beyond the MaxSamples limit are never used, so a slice is
+// also no longer than the limit, rounded up to a whole frame.
+func (w *Waveform) sliceFrames(config audio.Config) int64 {
+	frames := int64(w.sliceSamples)
+	if frames == 0 {
+		frames = int64(math.Min(math.Floor(float64(config.SampleRate)/w.resolution), math.MaxInt64))
+	}
+
+	channels := int64(config.Channels)
+	if max := sliceSamplesMax / channels; frames > max {
+		frames = max
+	}
+	if w.maxSamples > 0 {
+		if max := (w.maxSamples + channels - 1) / channels; frames > max {
+			frames = max
+		}
+	}
+	if frames < 1 {
+		return 1
+	}
+
+	return frames
+}
+
+// openDecoder checks for an empty input stream, and opens an audio decoder on
+// it, wrapping any errors from the audio package.  Any ID3v2 or APE tags at
+// the beginning of the stream are skipped.
+func openDecoder(br *bufio.Reader) (audio.Decoder, error) {
+	// Check for an empty input stream before attempting to detect its format
+	if _, err := br.Peek(1); err == io.EOF {
+		return nil, ErrNoSamples
+	}
+
+	// Skip any tags which hide the magic string of the format.  A stream which
+	// contains only tags has no samples.
+	if err := skipLeadingTags(br); err != nil {
+		return nil, err
+	}
+	if _, err := br.Peek(1); err == io.EOF {
+		return nil, ErrNoSamples
+	}
+
+	decoder, err := newDecoder(br)
+	if err != nil {
+		// Unknown format
+		if err == audio.ErrFormat {
+			return nil, ErrFormat
+		}
+
+		// Invalid data
+		if err == audio.ErrInvalidData {
+			return nil, ErrInvalidData
+		}
+
+		// Unexpected end-of-stream
+		if err == audio.ErrUnexpectedEOS {
+			return nil, ErrUnexpectedEOS
+		}
+
+		// All other errors
+		return nil, err
+	}
+
+	return decoder, nil
+}
+
+// openTrackDecoder opens an audio decoder on the audio track with the input
+// zero-based index of a Matroska, WebM, or MP4 container.  Any other stream has
+// only a single track, so ErrTrackRange is returned for it.
+func openTrackDecoder(br *bufio.Reader, index int) (audio.Decoder, error) {
+	if _, err := br.Peek(1); err == io.EOF {
+		return nil, ErrNoSamples
+	}
+	if err := skipLeadingTags(br); err != nil {
+		return nil, err
+	}
+
+	header, _ := br.Peek(8)
+	switch {
+	case matchMagic(header, mkvMagic):
+		return newMKVTrackDecoder(br, index)
+	case matchMagic(header, mp4Magic):
+		return newMP4TrackDecoder(br, index)
+	}
+
+	return nil, ErrTrackRange
+}
+
+// newDecoder opens an audio decoder on the input stream.  Decoders registered
+// using RegisterDecoder are used first.  Sample formats which the audio package
+// does not decode correctly are decoded by this package, and all other formats
+// are decoded by the audio package.
+func newDecoder(br *bufio.Reader) (audio.Decoder, error) {
+	// Registered decoders take priority over all other formats
+	if n := maxRegisteredMagicLen(); n > 0 {
+		header, _ := br.Peek(n)
+		if fn := findRegisteredDecoder(header); fn != nil {
+			return fn(br)
+		}
+	}
+
+	if peekWAVNative(br) {
+		return newWAVDecoder(br)
+	}
+
+	// Read any markers from the metadata of FLAC streams
+	if magic, _ := br.Peek(4); string(magic) == "fLaC" {
+		return newFLACDecoder(br)
+	}
+
+	decoder, _, err := audio.NewDecoder(br)
+	return decoder, err
+}
+
+// generateImage takes a slice of computed values and generates
+// a waveform image from the input.  If the Paletted option is set, the image
+// is an *image.Paletted, if the TransparentBackground option is set, it is
+// an *image.NRGBA, and otherwise it is an *image.RGBA.
+func (w *Waveform) generateImage(computed []float64) image.Image {
+	l := w.newLayout(computed)
+
+	// Create output, rectangular image, supersampled if requested
+	var img draw.Image
+	if w.antiAlias > 1 {
+		img = w.antiAliasImage(computed)
+	} else {
+		img = w.newImage(l.bounds)
+		l.draw(img)
+	}
+
+	// Draw any axes in front of the waveform, at the final size of the image
+	if w.drawsAnnotations() {
+		l.drawAnnotations(img, len(computed))
+	}
+
+	// Return generated image
+	return img
+}
+
+// newImage creates an empty image with the input bounds, using the palette
+// set by the Paletted option, or non-premultiplied alpha if the
+// TransparentBackground option is set.
+func (w *Waveform) newImage(bounds image.Rectangle) draw.Image {
+	if w.palette != nil {
+		return image.NewPaletted(bounds, w.palette)
+	}
+	if w.nrgba {
+		return image.NewNRGBA(bounds)
+	}
+
+	return image.NewRGBA(bounds)
+}
+
+// draw draws a waveform image into img, which must have the bounds of the
+// layout.
+func (l *layout) draw(img draw.Image) {
+	// Draw any padding using the background color
+	if l.padded() {
+		for y := 0; y < l.bounds.Max.Y; y++ {
+			for x := 0; x < l.bounds.Max.X; x++ {
+				if !l.inWaveform(x, y) {
+					img.Set(x, y, l.paddingColor(x, y))
+				}
+			}
+		}
+	}
+
+	// Begin iterating all columns of the image, drawing each span of pixels
+	// in order, so that later spans are drawn on top of earlier ones
+	var spans []span
+	for x := 0; x < l.maxX; x++ {
+		n := l.column(x)
+
+		spans = l.spans(x, spans[:0])
+		for _, s := range spans {
+			for y := s.y0; y < s.y1; y++ {
+				// Retrieve and apply color function at specified computed value
+				// count, and X and Y coordinates.
+				// The output color is selected using the function, and is applied to
+				// the resulting image, within any padding.
+				img.Set(x+l.offset.X, y+l.offset.Y, l.color(s.fn, n, x, y))
+			}
+		}
+	}
+}
+
+// amplitude applies the amplitude transform pipeline to a single computed value,
+// producing the value which is drawn.  Transforms are applied only at draw time,
+// in the following fixed order:
+//   - SilenceThreshold: the value is removed (set to 0) if below the threshold
+//   - NoiseFloor: the floor is subtracted, and the value is clamped to [0, 1]
+//   - InvertAmplitude: the value is clamped to [0, 1] and mapped to 1-v
+//
+// Any scaling factor, including ScaleClipping or Normalize, is applied to the
+// result.
+func (w *Waveform) amplitude(v float64) float64 {
+	if v < w.silenceThreshold {
+		v = 0
+	}
+
+	if w.noiseFloor > 0 {
+		v = math.Min(math.Max(v-w.noiseFloor, 0), 1)
+	}
+
+	if w.invertAmplitude {
+		v = 1 - math.Min(math.Max(v, 0), 1)
+	}
+
+	return v
+}
+
+// span is a vertical run of pixels in a single column of a waveform image,
+// from y0 (inclusive) to y1 (exclusive), colored using a ColorFunc.
+type span struct {
+	y0 int
+	y1 int
+	fn ColorFunc
+}
+
+// layout stores the dimensions and transformed values used to draw a waveform
+// image, so that the spans of any column can be computed independently of
+// all other columns.
+type layout struct {
+	w *Waveform
+
+	// Computed and transformed values, and statistics which correspond to
+	// them, if any
+	computed []float64
+	values   []float64
+	stats    []sliceStats
+
+	// Transformed overlay values, interpolated to the number of computed
+	// values, if an overlay is drawn
+	overlay []float64
+
+	// Whether a marker is drawn at each X coordinate, if markers are drawn
+	markers []bool
+
+	// Calculate maximum n, x, y, where:
+	//  - n: number of computed values
+	//  - x: number of pixels on X-axis
+	//  - y: number of pixels on Y-axis
+	maxN int
+	maxX int
+	maxY int
+
+	// Bounds of the entire image, including any padding, and the offset of
+	// the waveform area within it
+	bounds image.Rectangle
+	offset image.Point
+
+	// Values to be used for repeated computations
+	imgScale  float64
+	peakScale float64
+	imgHalfY  int
+	barPx     int
+	period    int
+	radius    int
+	sharpness int
+	peak      int
+}
+
+// newLayout creates a layout for drawing the input slice of computed values.
+func (w *Waveform) newLayout(computed []float64) *layout {
+	// Store integer scale values
+	intScaleX := int(w.scaleX)
+	intScaleY := int(w.scaleY)
+
+	// Calculate the width in pixels of each bar, and of each bar followed
+	// by its gap, both scaled on the X-axis
+	barPx := int(w.barWidth) * intScaleX
+	period := int(w.barWidth+w.barGap) * intScaleX
+
+	// Number of values computed from the audio, before they are resampled to
+	// fit the width of the image
+	values := len(computed)
+
+	// Resample the computed values, and any statistics which correspond to
+	// them, so that their bars exactly fill an explicit width
+	stats := w.stats
+	if w.width > 0 && len(computed) > 0 {
+		n := (int(w.width) + period - 1) / period
+		if len(stats) == len(computed) {
+			stats = resampleStats(stats, n)
+		}
+		computed = resampleValues(computed, n)
+	}
+
+	// Merge the computed values, and any statistics, into fewer bars if the
+	// image would otherwise exceed its maximum width
+	if w.maxWidth > 0 && w.width == 0 {
+		n := maxInt((int(w.maxWidth)-int(w.padLeft+w.padRight))/period, 1)
+		if len(computed) > n {
+			if len(stats) == len(computed) {
+				stats = resampleStats(stats, n)
+			}
+			computed = resampleValues(computed, n)
+		}
+	}
+
+	l := &layout{
+		w: w,
+
+		maxN: len(computed),
+		maxX: len(computed) * period,
+		maxY: imgYDefault * intScaleY,
+
+		barPx:     barPx,
+		period:    period,
+		sharpness: int(w.sharpness),
+
+		// Corner radius cannot exceed half the width of a bar
+		radius: minInt(int(w.barRadius), barPx/2),
+
+		// Calculate a peak value used for smoothing scaled X-axis images
+		peak: int(math.Ceil(float64(barPx)) / 2),
+	}
+
+	// An explicit height replaces the scaled default height
+	if w.height > 0 {
+		l.maxY = int(w.height)
+	}
+
+	// An explicit width clips the last bar, if the bars do not fill it exactly
+	if w.width > 0 && l.maxN > 0 {
+		l.maxX = int(w.width)
+	}
+
+	// A single bar which is wider than the maximum width is clipped
+	if w.maxWidth > 0 && w.width == 0 {
+		if max := int(w.maxWidth) - int(w.padLeft+w.padRight); l.maxX > max {
+			l.maxX = maxInt(max, 1)
+		}
+	}
+
+	// Calculate halfway point of Y-axis for image
+	l.imgHalfY = l.maxY / 2
+
+	// Surround the waveform area with any padding
+	l.offset = image.Pt(int(w.padLeft), int(w.padTop))
+	l.bounds = image.Rect(0, 0,
+		l.maxX+int(w.padLeft)+int(w.padRight),
+		l.maxY+int(w.padTop)+int(w.padBottom),
+	)
+
+	// Apply amplitude transforms to a copy of the computed values, so that the
+	// values returned by Compute are never modified by drawing
+	l.computed = computed
+	l.values = make([]float64, l.maxN)
+	for n := range computed {
+		l.values[n] = w.amplitude(computed[n])
+	}
+
+	// Interpolate overlay values to align with the computed values, and apply
+	// the same amplitude transforms to them
+	if w.overlayValues != nil {
+		l.overlay = interpolateValues(w.overlayValues, l.maxN)
+		for n := range l.overlay {
+			l.overlay[n] = w.amplitude(l.overlay[n])
+		}
+	}
+
+	// Statistics retained from the last computation can only be used if they
+	// correspond to the input values
+	if len(stats) == l.maxN {
+		l.stats = stats
+	}
+
+	// Markers from the last computation are only drawn if they correspond to
+	// the input values, at their times within any resampled bars
+	if w.markerColorFn != nil && values > 0 && w.metadata.values() == values {
+		l.markers = w.markerColumns(l.maxX, l.period*l.maxN)
+	}
+
+	// Calculate scaling factor, based upon maximum value computed by a SampleReduceFunc.
+	// If option ScaleClipping is true, when maximum value is above certain thresholds
+	// the scaling factor is reduced to show an accurate waveform with less clipping.
+	l.imgScale = scaleDefault
+	if w.scaleClipping {
+		// Find maximum value from input slice
+		var maxValue float64
+		for _, c := range l.values {
+			if c > maxValue {
+				maxValue = c
+			}
+		}
+
+		// For each 0.05 maximum increment at 0.30 and above, reduce the scaling
+		// factor by 0.25.  This is a rough estimate and may be tweaked in the future.
+		for i := 0.30; i < maxValue; i += 0.05 {
+			l.imgScale -= 0.25
+		}
+	}
+
+	// If option Normalize is true, the scaling factors are chosen so that the
+	// peak of the track reaches the full height of the image, in place of any
+	// clipping adjustment
+	l.peakScale = 1
+	if w.normalize {
+		if peak := l.peakValue(); peak > 0 {
+			l.imgScale = 1 / peak
+		}
+		if peak := l.peakSample(); peak > 0 {
+			l.peakScale = 1 / peak
+		}
+	}
+
+	// Fixed scaling factors replace those calculated from the input values
+	if w.fixedScale > 0 {
+		l.imgScale, l.peakScale = w.fixedScale, w.fixedPeakScale
+	}
+
+	return l
+}
+
+// peakValue returns the largest value drawn symmetrically about the center of
+// the image, which is the peak envelope of a dual envelope, if drawn.
+func (l *layout) peakValue() float64 {
+	var peak float64
+	for _, v := range l.values {
+		peak = math.Max(peak, v)
+	}
+
+	if l.w.dualEnvelope && l.stats != nil {
+		for _, s := range l.stats {
+			peak = math.Max(peak, l.w.amplitude(s.peak))
+		}
+	}
+
+	return peak
+}
+
+// peakSample returns the largest magnitude of the minimum and maximum samples
+// drawn by a min/max envelope, or 0 if none are drawn.
+func (l *layout) peakSample() float64 {
+	if !l.w.minMaxEnvelope || l.stats == nil {
+		return 0
+	}
+
+	var peak float64
+	for _, s := range l.stats {
+		peak = math.Max(peak, math.Max(l.w.amplitude(-s.min), l.w.amplitude(s.max)))
+	}
+
+	return peak
+}
+
+// padded reports whether the image includes any padding.
+func (l *layout) padded() bool {
+	return l.bounds.Dx() != l.maxX || l.bounds.Dy() != l.maxY
+}
+
+// inWaveform reports whether the pixel at image coordinates x and y is within
+// the waveform area, rather than its padding.
+func (l *layout) inWaveform(x int, y int) bool {
+	x, y = x-l.offset.X, y-l.offset.Y
+	return x >= 0 && x < l.maxX && y >= 0 && y < l.maxY
+}
+
+// paddingColor returns the background color of the padding pixel at image
+// coordinates x and y.  The background ColorFunc is evaluated relative to the
+// waveform area, at the nearest pixel within it.
+func (l *layout) paddingColor(x int, y int) color.Color {
+	x = minInt(maxInt(x-l.offset.X, 0), maxInt(l.maxX-1, 0))
+	y = minInt(maxInt(y-l.offset.Y, 0), maxInt(l.maxY-1, 0))
+
+	n := 0
+	if l.maxN > 0 {
+		n = l.column(x)
+	}
+
+	return l.color(l.w.bgColorFn, n, x, y)
+}
+
+// column returns the index of the computed value drawn at X coordinate x.
+func (l *layout) column(x int) int {
+	return x / l.period
+}
+
+// spans appends the spans of pixels drawn at X coordinate x to dst, in the
+// order they are drawn, and returns the result.  The first span always
+// covers the entire column with the background ColorFunc.
+func (l *layout) spans(x int, dst []span) []span {
+	start := len(dst)
+	dst = l.columnSpans(x, dst)
+
+	// Draw all spans after the background of a clipped column using the clip
+	// indicator color
+	if l.clipped(l.column(x)) {
+		for i := start + 1; i < len(dst); i++ {
+			dst[i].fn = l.w.clipColorFn
+		}
+	}
+
+	// Draw the overlay in front of all other spans
+	if l.overlay != nil {
+		dst = l.overlaySpans(x, start, dst)
+	}
+
+	// Draw markers in front of the entire column
+	if l.markers != nil && l.markers[x] {
+		dst = append(dst, span{y0: 0, y1: l.maxY, fn: l.w.markerColorFn})
+	}
+
+	return dst
+}
+
+// overlaySpans appends the spans of the overlay at X coordinate x to dst, where
+// the spans of the column begin at index start, and returns the result.
+//
+// Each overlay span is split at the boundaries of the spans beneath it, so that
+// the overlay color is composited over the color of each one.  Because later
+// spans are drawn on top of earlier ones, each pixel is composited over the
+// color of the topmost span beneath it.
+func (l *layout) overlaySpans(x int, start int, dst []span) []span {
+	end := len(dst)
+	if !l.w.style.continuous() && x%l.period >= l.barPx {
+		return dst
+	}
+
+	// Compute the extent of the overlay, and remove it temporarily
+	var extent [2]span
+	value := l.sample(x, func(n int) float64 { return l.overlay[n] })
+	dst = l.valueSpans(dst, x, value, nil)
+	k := copy(extent[:], dst[end:])
+	dst = dst[:end]
+
+	for _, o := range extent[:k] {
+		for i := start; i < end; i++ {
+			s := dst[i]
+			dst = l.appendSpan(dst, maxInt(o.y0, s.y0), minInt(o.y1, s.y1), overColor(l.w.overlayColor, s.fn, l.w.gammaCorrect))
+		}
+	}
+
+	return dst
+}
+
+// clipped reports whether the computed value at index n exceeds the clip
+// indicator threshold, using the peak of its slice of audio samples if
+// statistics are available.
+func (l *layout) clipped(n int) bool {
+	if l.w.clipColorFn == nil {
+		return false
+	}
+
+	peak := l.computed[n]
+	if l.stats != nil {
+		peak = l.stats[n].peak
+	}
+
+	return peak > l.w.clipThreshold
+}
+
+// columnSpans appends the spans of pixels drawn at X coordinate x to dst,
+// using the ColorFunc for each drawing mode, and returns the result.
+func (l *layout) columnSpans(x int, dst []span) []span {
+	w := l.w
+
+	// Draw background color down the entire Y-axis
+	dst = append(dst, span{y0: 0, y1: l.maxY, fn: w.bgColorFn})
+
+	// Dots may be wider than bars, and are drawn into the gaps between them
+	if w.style == dotStyle {
+		return l.dotSpans(dst, x)
+	}
+
+	// Nothing else is drawn in the gap following a bar, unless the gap is
+	// filled as part of a continuous area
+	if !w.style.continuous() && x%l.period >= l.barPx {
+		return dst
+	}
+
+	// When drawing outlines, draw only the edges of the foreground extent
+	if w.style == Line {
+		return l.lineSpans(dst, x)
+	}
+
+	// When drawing a min/max envelope, draw the extent between the minimum and
+	// maximum samples, in place of the peak extent of a dual envelope.  If no
+	// statistics are available for the input values, the computed value is
+	// drawn symmetrically instead.
+	if w.minMaxEnvelope && l.stats != nil {
+		min := l.sample(x, func(n int) float64 { return l.stats[n].min })
+		max := l.sample(x, func(n int) float64 { return l.stats[n].max })
+		if !w.dualEnvelope {
+			return l.minMaxSpans(dst, x, min, max, w.fgColorFn)
+		}
+
+		rms := l.sample(x, func(n int) float64 { return w.amplitude(l.stats[n].rms) })
+		dst = l.minMaxSpans(dst, x, min, max, w.peakColorFn)
+		return l.valueSpans(dst, x, rms, w.rmsColorFn)
+	}
+
+	value := l.sample(x, func(n int) float64 { return l.values[n] })
+
+	// When drawing a dual envelope, draw the peak extent first, and the
+	// shorter RMS extent on top of it.  If no statistics are available for
+	// the input values, the computed value is used for both.
+	if w.dualEnvelope {
+		peak, rms := value, value
+		if l.stats != nil {
+			peak = l.sample(x, func(n int) float64 { return w.amplitude(l.stats[n].peak) })
+			rms = l.sample(x, func(n int) float64 { return w.amplitude(l.stats[n].rms) })
+		}
+
+		dst = l.valueSpans(dst, x, peak, w.peakColorFn)
+		return l.valueSpans(dst, x, rms, w.rmsColorFn)
+	}
+
+	return l.valueSpans(dst, x, value, w.fgColorFn)
+}
+
+// valueSpans appends the spans of pixels used to draw a single value at X
+// coordinate x to dst, using the input ColorFunc, and returns the result.
+func (l *layout) valueSpans(dst []span, x int, value float64, fn ColorFunc) []span {
+	top, bottom := l.valueExtent(value)
+
+	// When scaled, adjust computed value to be lower on either side of the peak,
+	// so that the image appears more smooth and less "blocky"
+	adjust := l.curveAdjust(x % l.period)
+
+	// Round the outer corners of the bar by shortening its extent
+	inset := l.cornerInset(x % l.period)
+
+	// On top half of the image, invert adjustment to create symmetry between
+	// top and bottom halves
+	dst = l.appendSpan(dst, top-adjust+inset, minInt(bottom, l.imgHalfY)-adjust, fn)
+	return l.appendSpan(dst, maxInt(top, l.imgHalfY)+adjust, bottom+adjust-inset, fn)
+}
+
+// valueExtent returns the top (inclusive) and bottom (exclusive) Y coordinates
+// of the extent of a single value, which is symmetrical about the center of
+// the image.
+func (l *layout) valueExtent(value float64) (int, int) {
+	// Scale computed value to an integer, using the height of the image and a constant
+	// scaling factor
+	scaleComputed := int(math.Floor(value * float64(l.maxY) * l.imgScale))
+
+	// Calculate the halfway point for the scaled computed value, and the
+	// extent of a symmetrical waveform above and below the center of the image
+	halfScaleComputed := scaleComputed / 2
+	top := l.imgHalfY - halfScaleComputed
+
+	return top, scaleComputed + top
+}
+
+// curveAdjust returns the adjustment applied to the extent of the pixel
+// column at offset i within a bar, according to the sharpness of the image.
+// The adjustment is never positive, and is 0 at the peak of the bar.
+func (l *layout) curveAdjust(i int) int {
+	// A continuous area has no individual bars to adjust
+	if l.w.style.continuous() {
+		return 0
+	}
+
+	if i < l.peak {
+		// Adjust downward
+		return (i - l.peak) * l.sharpness
+	} else if i == l.peak {
+		// No adjustment at peak
+		return 0
+	}
+
+	// Adjust downward
+	return (l.peak - i) * l.sharpness
+}
+
+// minMaxSpans appends the span of pixels used to draw the extent between a
+// signed minimum and maximum sample at X coordinate x to dst, using the input
+// ColorFunc, and returns the result.
+//
+// Samples are mapped so that full scale samples reach the top and bottom
+// edges of the image, and the upper and lower extents are independent, so
+// asymmetrical signals are drawn accurately, unless the Mirrored option is set.
+func (l *layout) minMaxSpans(dst []span, x int, min float64, max float64, fn ColorFunc) []span {
+	top, bottom := l.minMaxExtent(min, max)
+
+	// Apply curvature and rounded corners to both ends of the extent
+	adjust := l.curveAdjust(x % l.period)
+	inset := l.cornerInset(x % l.period)
+
+	return l.appendSpan(dst, top-adjust+inset, bottom+adjust-inset, fn)
+}
+
+// minMaxExtent returns the top (inclusive) and bottom (exclusive) Y coordinates
+// of the extent between a signed minimum and maximum sample.
+func (l *layout) minMaxExtent(min float64, max float64) (int, int) {
+	// Apply amplitude transforms to sample magnitudes, preserving their signs
+	signed := func(v float64) float64 {
+		if v < 0 {
+			return -l.w.amplitude(-v)
+		}
+
+		return l.w.amplitude(v)
+	}
+
+	// Reflect the larger magnitude about the center, if requested
+	if l.w.mirrored {
+		max = math.Max(math.Abs(max), math.Abs(min))
+		min = -max
+	}
+
+	top := l.imgHalfY - int(math.Floor(signed(max)*l.peakScale*float64(l.imgHalfY)))
+	bottom := l.imgHalfY - int(math.Floor(signed(min)*l.peakScale*float64(l.imgHalfY)))
+
+	return top, bottom
+}
+
+// lineSpans appends the spans of pixels used to draw the upper and lower
+// outlines of the foreground extent at X coordinate x to dst, and returns the
+// result.  Each outline is extended to the midpoints of the outlines of the
+// adjacent columns, so that steep outlines are drawn as connected lines.
+func (l *layout) lineSpans(dst []span, x int) []span {
+	top, bottom := l.lineEdges(x)
+	edges := [2][2]int{{top, top}, {bottom, bottom}}
+
+	for _, nx := range []int{x - 1, x + 1} {
+		if nx < 0 || nx >= l.maxX {
+			continue
+		}
+
+		nTop, nBottom := l.lineEdges(nx)
+		for i, mid := range []int{(top + nTop) / 2, (bottom + nBottom) / 2} {
+			edges[i][0] = minInt(edges[i][0], mid)
+			edges[i][1] = maxInt(edges[i][1], mid)
+		}
+	}
+
+	// Center the stroke on each outline
+	stroke := int(l.w.strokeWidth)
+	for _, e := range edges {
+		dst = l.appendSpan(dst, e[0]-stroke/2, e[1]-stroke/2+stroke, l.w.fgColorFn)
+	}
+
+	return dst
+}
+
+// lineEdges returns the Y coordinates of the upper and lower outlines drawn
+// at X coordinate x, which are the first and last rows of the foreground
+// extent.  An empty extent is drawn as a single line.
+func (l *layout) lineEdges(x int) (int, int) {
+	var top, bottom int
+	if l.w.minMaxEnvelope && l.stats != nil {
+		top, bottom = l.minMaxExtent(
+			l.sample(x, func(n int) float64 { return l.stats[n].min }),
+			l.sample(x, func(n int) float64 { return l.stats[n].max }),
+		)
+	} else {
+		top, bottom = l.valueExtent(l.sample(x, func(n int) float64 { return l.values[n] }))
+	}
+
+	return top, maxInt(bottom-1, top)
+}
+
+// dotSpans appends the span of pixels used to draw the dot of the value at X
+// coordinate x to dst, and returns the result.  Each dot is centered on its
+// bar, at the top of the extent of its value, and is clipped to the width of
+// the bar and the gap which follows it.
+func (l *layout) dotSpans(dst []span, x int) []span {
+	top, _ := l.valueExtent(l.values[l.column(x)])
+
+	// Horizontal distance from the center of the dot to the center of the
+	// pixel column
+	r := float64(l.w.dotRadius)
+	dx := float64(x%l.period) + 0.5 - float64(l.barPx)/2
+	if math.Abs(dx) > r {
+		return dst
+	}
+
+	h := math.Sqrt(r*r - dx*dx)
+	return l.appendSpan(dst, int(math.Floor(float64(top)-h+0.5)), int(math.Floor(float64(top)+h+0.5)), l.w.fgColorFn)
+}
+
+// cornerInset returns the number of pixels by which the pixel column at offset
+// i within a bar is shortened, at both its top and bottom, to round the corners
+// of the bar.
+func (l *layout) cornerInset(i int) int {
+	if l.radius == 0 || l.w.style.continuous() {
+		return 0
+	}
+
+	// Horizontal distance from the center of the corner's arc to the center
+	// of the pixel column, for columns within the radius of either edge
+	r := float64(l.radius)
+	var d float64
+	switch {
+	case i < l.radius:
+		d = r - float64(i) - 0.5
+	case i >= l.barPx-l.radius:
+		d = float64(i-(l.barPx-l.radius)) + 0.5
+	default:
+		return 0
+	}
+
+	return int(r - math.Sqrt(r*r-d*d) + 0.5)
+}
+
+// appendSpan appends a span from y0 to y1 to dst, clipped to the bounds of the
+// image.  Empty spans are not appended.
+func (l *layout) appendSpan(dst []span, y0 int, y1 int, fn ColorFunc) []span {
+	y0, y1 = maxInt(y0, 0), minInt(y1, l.maxY)
+	if y0 >= y1 {
+		return dst
+	}
+
+	return append(dst, span{y0: y0, y1: y1, fn: fn})
+}
+
+// color returns the color produced by the input ColorFunc for the pixel at
+// waveform X coordinate x and Y coordinate y, selected by the value at index
+// n if produced by AmplitudeColor, and blended in linear light if the
+// GammaCorrect option is set.
+func (l *layout) color(fn ColorFunc, n int, x int, y int) color.Color {
+	c := fn(n, x, y, l.maxN, l.maxX, l.maxY)
+	if n >= 0 && n < len(l.values) {
+		c = resolveAmplitude(c, l.values[n])
+	}
+
+	return resolveColor(c, l.w.gammaCorrect)
+}
+
+// overColor generates a ColorFunc which composites color c over the colors of
+// the input ColorFunc, using the alpha of c.  If gamma is true, colors are
+// composited in linear light.
+func overColor(c color.RGBA, below ColorFunc, gamma bool) ColorFunc {
+	r1, g1, b1, a1 := c.RGBA()
+
+	return func(n int, x int, y int, maxN int, maxX int, maxY int) color.Color {
+		if gamma {
+			return overLinear(c, resolveColor(below(n, x, y, maxN, maxX, maxY), true))
+		}
+
+		r2, g2, b2, a2 := below(n, x, y, maxN, maxX, maxY).RGBA()
+
+		// Composite alpha-premultiplied components
+		over := func(c1 uint32, c2 uint32) uint16 {
+			return uint16(c1 + c2*(0xffff-a1)/0xffff)
+		}
+
+		return color.RGBA64{
+			R: over(r1, r2),
+			G: over(g1, g2),
+			B: over(b1, b2),
+			A: over(a1, a2),
+		}
+	}
+}
+
+// overLinear composites color c over color below in linear light, using the
+// alpha of c.
+func overLinear(c color.Color, below color.Color) color.Color {
+	r1, g1, b1, a1 := linearRGBA(c)
+	r2, g2, b2, a2 := linearRGBA(below)
+
+	a := a1 + a2*(1-a1)
+	if a == 0 {
+		return color.RGBA64{}
+	}
+
+	over := func(c1 float64, c2 float64) float64 {
+		return (c1*a1 + c2*a2*(1-a1)) / a
+	}
+
+	return fromLinearRGBA(over(r1, r2), over(g1, g2), over(b1, b2), a)
+}
+
+// resampleValues returns a slice of n values resampled from the input values.
+// When the number of values is reduced, each output value is the maximum of
+// the input values it covers, so that peaks are preserved.  Otherwise, values
+// are linearly interpolated.
+func resampleValues(values []float64, n int) []float64 {
+	if len(values) <= n {
+		return interpolateValues(values, n)
+	}
+
+	out := make([]float64, n)
+	for i := range out {
+		lo, hi := i*len(values)/n, (i+1)*len(values)/n
+		out[i] = values[lo]
+		for _, v := range values[lo+1 : hi] {
+			out[i] = math.Max(out[i], v)
+		}
+	}
+
+	return out
+}
+
+// resampleStats returns a slice of n statistics resampled from the input
+// statistics.  When the number of statistics is reduced, the statistics
+// covered by each output are merged, and otherwise, each output is a copy of
+// the nearest input.
+func resampleStats(stats []sliceStats, n int) []sliceStats {
+	out := make([]sliceStats, n)
+	for i := range out {
+		lo := i * len(stats) / n
+		hi := maxInt((i+1)*len(stats)/n, lo+1)
+
+		s := stats[lo]
+		var squares float64
+		for _, t := range stats[lo:hi] {
+			s.peak = math.Max(s.peak, t.peak)
+			s.min = math.Min(s.min, t.min)
+			s.max = math.Max(s.max, t.max)
+			squares += t.rms * t.rms
+		}
+		s.rms = math.Sqrt(squares / float64(hi-lo))
+
+		out[i] = s
+	}
+
+	return out
+}
+
+// interpolateValues returns a slice of n values, linearly interpolated from the
+// input values, so that the first and last values of both slices are aligned.
+func interpolateValues(values []float64, n int) []float64 {
+	out := make([]float64, n)
+	if len(values) == n {
+		copy(out, values)
+		return out
+	}
+
+	for i := range out {
+		// A single value is aligned with the first input value
+		var pos float64
+		if n > 1 {
+			pos = float64(i) * float64(len(values)-1) / float64(n-1)
+		}
+
+		j := int(pos)
+		if j+1 >= len(values) {
+			out[i] = values[len(values)-1]
+			continue
+		}
+
+		out[i] = values[j] + (values[j+1]-values[j])*(pos-float64(j))
+	}
+
+	return out
+}
+
+// minInt returns the smaller of two integers.
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// minInt64 returns the smaller of two 64-bit integers.
+func minInt64(a int64, b int64) int64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// maxInt returns the larger of two integers.
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
 }
@@ -2,6 +2,8 @@ package waveform
 
 import (
 	"bytes"
+	"encoding/binary"
+	"math"
 	"math/rand"
 	"testing"
 	"time"
@@ -29,6 +31,18 @@ func BenchmarkWaveformComputeFLAC(b *testing.B) {
 	benchmarkWaveformCompute(b, flacFile)
 }
 
+// BenchmarkWaveformComputeLargeWAV checks the performance of the WaveformCompute() function
+// with a large WAV file
+func BenchmarkWaveformComputeLargeWAV(b *testing.B) {
+	benchmarkWaveformCompute(b, largeWAVFile())
+}
+
+// BenchmarkWaveformComputeLargeWAVFastThumbnail checks the performance of the WaveformCompute()
+// function with a large WAV file, using the FastThumbnail option
+func BenchmarkWaveformComputeLargeWAVFastThumbnail(b *testing.B) {
+	benchmarkWaveformCompute(b, largeWAVFile(), FastThumbnail())
+}
+
 // BenchmarkWaveformDraw60 checks the performance of the WaveformDraw() function
 // with approximately 60 seconds of computed values
 func BenchmarkWaveformDraw60(b *testing.B) {
@@ -91,9 +105,10 @@ func benchmarkGenerate(b *testing.B, data []byte) {
 }
 
 // benchmarkWaveformCompute contains common logic for benchmarking Waveform.Compute
-func benchmarkWaveformCompute(b *testing.B, data []byte) {
+func benchmarkWaveformCompute(b *testing.B, data []byte, options ...OptionsFunc) {
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		w, err := New(bytes.NewReader(data))
+		w, err := New(bytes.NewReader(data), options...)
 		if err != nil {
 			panic(err)
 		}
@@ -130,3 +145,25 @@ func benchmarkRMSF64Samples(b *testing.B, count int) {
 		RMSF64Samples(samples)
 	}
 }
+
+// largeWAV stores the WAV file generated by largeWAVFile
+var largeWAV []byte
+
+// largeWAVFile generates a WAV file containing three minutes of a 16-bit, stereo
+// sine wave at 44.1kHz, once for all benchmarks which use it
+func largeWAVFile() []byte {
+	if largeWAV != nil {
+		return largeWAV
+	}
+
+	const rate = 44100
+	data := make([]byte, 3*60*rate*4)
+	for i := 0; i < len(data); i += 4 {
+		v := uint16(int16(16384 * math.Sin(2*math.Pi*440*float64(i/4)/rate)))
+		binary.LittleEndian.PutUint16(data[i:], v)
+		binary.LittleEndian.PutUint16(data[i+2:], v)
+	}
+
+	largeWAV = testWAV(1, 2, rate, 16, data)
+	return largeWAV
+}
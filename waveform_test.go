@@ -2,10 +2,19 @@ package waveform
 
 import (
 	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
+	"reflect"
 	"testing"
+	"testing/iotest"
+
+	"azul3d.org/engine/audio"
 )
 
 var (
@@ -26,6 +35,14 @@ var (
 
 		return file
 	}()
+	wav8File = func() []byte {
+		file, err := ioutil.ReadFile("./test/tone8bit.wav")
+		if err != nil {
+			log.Fatalf("could not open test 8-bit WAV: %v", err)
+		}
+
+		return file
+	}()
 	mp3File = func() []byte {
 		file, err := ioutil.ReadFile("./test/tone16bit.mp3")
 		if err != nil {
@@ -50,17 +67,48 @@ var (
 func TestWaveformComputeWAVOK(t *testing.T) {
 	testWaveformCompute(t, bytes.NewReader(wavFile), nil,
 		[]float64{
-			0.7071166200538482,
-			0.7071166444294603,
-			0.7071166239921965,
-			0.7071165471800284,
-			0.7071166825227931,
-			0.7071166825227931,
+			0.7071166200538408,
+			0.70711664442945,
+			0.7071166239921984,
+			0.7071165471800182,
+			0.7071166825227919,
 		},
 		nil,
 	)
 }
 
+// TestWaveformComputeWAV8BitOK verifies that the Waveform.Compute method produces
+// appropriate computed samples and error for an input audio stream.
+// The input stream is in 8-bit unsigned WAV format, containing a sine wave at
+// three different amplitudes, and no errors should occur.
+func TestWaveformComputeWAV8BitOK(t *testing.T) {
+	w, err := New(bytes.NewReader(wav8File))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Values must follow the envelope of the sine wave, rather than being
+	// drawn as a solid, full height block
+	want := []float64{
+		0.5256953403068287,
+		0.1753588675313427,
+		0.3507977793989723,
+	}
+	if len(values) != len(want) {
+		t.Fatalf("unexpected Compute values length: %v != %v [%v != %v]", len(values), len(want), values, want)
+	}
+	for i := range want {
+		if math.Abs(values[i]-want[i]) > 1e-9 {
+			t.Fatalf("unexpected Compute value at index %d: %v != %v", i, values[i], want[i])
+		}
+	}
+}
+
 // TestWaveformComputeWAVErrInvalidData verifies that the Waveform.Compute method produces
 // appropriate computed samples and error for an input audio stream.
 // The input stream is in WAV format, but contains invalid data.
@@ -91,6 +139,61 @@ func TestWaveformComputeFLACOK(t *testing.T) {
 	)
 }
 
+// TestWaveformComputeChunkedWAV verifies that the Waveform.Compute method
+// produces identical values for a WAV stream, regardless of how its reader
+// chunks data.
+func TestWaveformComputeChunkedWAV(t *testing.T) {
+	testWaveformComputeChunked(t, wavFile)
+}
+
+// TestWaveformComputeChunkedFLAC verifies that the Waveform.Compute method
+// produces identical values for a FLAC stream, regardless of how its reader
+// chunks data.
+func TestWaveformComputeChunkedFLAC(t *testing.T) {
+	testWaveformComputeChunked(t, flacFile)
+}
+
+// TestWaveformComputeShortReads verifies that the Waveform.Compute method
+// produces identical values when a decoder returns fewer samples than
+// requested, including for a final, partial slice of samples.
+func TestWaveformComputeShortReads(t *testing.T) {
+	samples := make([]float64, 350)
+	for i := range samples {
+		samples[i] = float64(i%50) / 50
+	}
+
+	w, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := w.computeSamples(newSamplesDecoder(samples, 100, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One value per second of audio, including the final half second
+	if len(want) != 4 {
+		t.Fatalf("unexpected Compute values length: %v != %v", len(want), 4)
+	}
+
+	for _, size := range []int{1, 7, 99, 1000} {
+		d := &shortDecoder{samplesDecoder: newSamplesDecoder(samples, 100, 1), size: size}
+		got, err := w.computeSamples(d, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("size %d: unexpected Compute values length: %v != %v", size, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("size %d: unexpected Compute value at index %d: %v != %v", size, i, got[i], want[i])
+			}
+		}
+	}
+}
+
 // TestWaveformComputeFLACErrInvalidData verifies that the Waveform.Compute method produces
 // appropriate computed samples and error for an input audio stream.
 // The input stream is in FLAC format, but contains invalid data.
@@ -98,18 +201,1134 @@ func TestWaveformComputeFLACErrInvalidData(t *testing.T) {
 	testWaveformCompute(t, bytes.NewReader([]byte{'f', 'L', 'a', 'C'}), ErrInvalidData, nil, nil)
 }
 
+// TestWaveformComputeMP3OK verifies that the Waveform.Compute method produces
+// appropriate computed samples and error for an input audio stream.
+// The input stream is in MP3 format, and no errors should occur.
+func TestWaveformComputeMP3OK(t *testing.T) {
+	values, err := testComputeValues(bytes.NewReader(mp3File))
+	if err != nil {
+		t.Fatalf("unexpected Compute error: %v", err)
+	}
+
+	// Encoder delay and padding may add a partial slice of samples, but each
+	// whole second of the tone has the same RMS as the WAV stream
+	if len(values) < 5 || len(values) > 6 {
+		t.Fatalf("unexpected Compute values length: %v", len(values))
+	}
+	for i, v := range values[:4] {
+		if math.Abs(v-0.7071) > 0.02 {
+			t.Fatalf("unexpected Compute value at index %d: %v", i, v)
+		}
+	}
+}
+
+// TestWaveformComputeMP3ErrUnexpectedEOS verifies that the Waveform.Compute method
+// produces appropriate computed samples and error for an input audio stream.
+// The input stream is in MP3 format, but is truncated within its ID3v2 tag, and
+// should produce an unexpected end-of-stream error.
+func TestWaveformComputeMP3ErrUnexpectedEOS(t *testing.T) {
+	testWaveformCompute(t, bytes.NewReader(mp3File[:20]), ErrUnexpectedEOS, nil, nil)
+}
+
 // TestWaveformComputeMP3ErrFormat verifies that the Waveform.Compute method produces
 // appropriate computed samples and error for an input audio stream.
-// The input stream is in MP3 format, and should produce an unsupported format error.
+// The input stream is in MP3 format, but its ID3v2 tag is followed by no
+// frames, and should produce an unsupported format error.
 func TestWaveformComputeMP3ErrFormat(t *testing.T) {
-	testWaveformCompute(t, bytes.NewReader(mp3File), ErrFormat, nil, nil)
+	size, err := leadingTagLen(mp3File)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := append(append([]byte(nil), mp3File[:size]...), "not audio"...)
+	testWaveformCompute(t, bytes.NewReader(data), ErrFormat, nil, nil)
 }
 
-// TestWaveformComputeOggVorbisErrFormat verifies that the Waveform.Compute method produces
+// TestWaveformComputeMP3ErrInvalidData verifies that the Waveform.Compute
+// method produces appropriate computed samples and error for an input audio
+// stream.  The input stream is a corrupt MPEG-2 frame, which causes the MP3
+// decoder to panic, and should produce an invalid data error.
+func TestWaveformComputeMP3ErrInvalidData(t *testing.T) {
+	frame := []byte("\xff\xf2\x12L\xdd%\xc8\xfas\x11\xe4\xd7\xde\xfa\x92-\xaa\xe7xfg\xf7\xe96\xcdO$\xab\xf7\xdf")
+	testWaveformCompute(t, bytes.NewReader(frame), ErrInvalidData, nil, nil)
+}
+
+// TestWaveformComputeOggVorbisOK verifies that the Waveform.Compute method produces
 // appropriate computed samples and error for an input audio stream.
-// The input stream is in Ogg Vorbis format, and should produce an unsupported format error.
-func TestWaveformComputeOggVorbisErrFormat(t *testing.T) {
-	testWaveformCompute(t, bytes.NewReader(oggVorbisFile), ErrFormat, nil, nil)
+// The input stream is in Ogg Vorbis format, and no errors should occur.
+func TestWaveformComputeOggVorbisOK(t *testing.T) {
+	values, err := testComputeValues(bytes.NewReader(oggVorbisFile))
+	if err != nil {
+		t.Fatalf("unexpected Compute error: %v", err)
+	}
+
+	// Lossy encoding slightly alters the tone, but each whole second has
+	// nearly the same RMS as the WAV stream
+	if len(values) < 5 || len(values) > 6 {
+		t.Fatalf("unexpected Compute values length: %v", len(values))
+	}
+	for i, v := range values[:4] {
+		if math.Abs(v-0.7071) > 0.02 {
+			t.Fatalf("unexpected Compute value at index %d: %v", i, v)
+		}
+	}
+}
+
+// TestWaveformComputeOggVorbisErrInvalidData verifies that the Waveform.Compute method
+// produces appropriate computed samples and error for an input audio stream.
+// The input stream is in Ogg Vorbis format, but is truncated within its first
+// page, and should produce an invalid data error.
+func TestWaveformComputeOggVorbisErrInvalidData(t *testing.T) {
+	testWaveformCompute(t, bytes.NewReader(oggVorbisFile[:40]), ErrInvalidData, nil, nil)
+}
+
+// TestWaveformComputeOggErrFormat verifies that the Waveform.Compute method produces
+// appropriate computed samples and error for an input audio stream.
+// The input stream is an Ogg container with an unknown codec, and should
+// produce an unsupported format error.
+func TestWaveformComputeOggErrFormat(t *testing.T) {
+	data := append([]byte(nil), oggVorbisFile...)
+	copy(data[28:], "\x01vorbix")
+
+	testWaveformCompute(t, bytes.NewReader(data), ErrFormat, nil, nil)
+}
+
+// TestWaveformComputeEmptyErrNoSamples verifies that the Waveform.Compute method
+// returns ErrNoSamples for an empty input stream.
+func TestWaveformComputeEmptyErrNoSamples(t *testing.T) {
+	testWaveformCompute(t, bytes.NewReader(nil), ErrNoSamples, nil, nil)
+}
+
+// TestWaveformComputeWAVHeaderOnlyErrNoSamples verifies that the Waveform.Compute
+// method returns ErrNoSamples for a valid WAV header with no data frames.
+func TestWaveformComputeWAVHeaderOnlyErrNoSamples(t *testing.T) {
+	testWaveformCompute(t, bytes.NewReader(testWAV(1, 2, 44100, 16, nil)), ErrNoSamples, nil, nil)
+}
+
+// TestGenerateErrNoSamples verifies that Generate returns a nil image along
+// with ErrNoSamples, for both an empty stream and a header-only WAV stream.
+func TestGenerateErrNoSamples(t *testing.T) {
+	for i, r := range []io.Reader{
+		bytes.NewReader(nil),
+		bytes.NewReader(testWAV(1, 2, 44100, 16, nil)),
+	} {
+		img, err := Generate(r)
+		if err != ErrNoSamples {
+			t.Fatalf("[%02d] unexpected Generate error: %v != %v", i, err, ErrNoSamples)
+		}
+		if img != nil {
+			t.Fatalf("[%02d] unexpected non-nil image: %v", i, img.Bounds())
+		}
+	}
+}
+
+// TestWaveformComputeDualEnvelope verifies that the Waveform.Compute method
+// retains peak and RMS statistics for each computed value, when DualEnvelope
+// is enabled.
+func TestWaveformComputeDualEnvelope(t *testing.T) {
+	w, err := New(bytes.NewReader(wavFile), DualEnvelope(SolidColor(red), SolidColor(blue)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(w.stats) != len(values) {
+		t.Fatalf("unexpected statistics length: %v != %v", len(w.stats), len(values))
+	}
+
+	for i, s := range w.stats {
+		if s.rms != values[i] {
+			t.Fatalf("[%02d] unexpected RMS value: %v != %v", i, s.rms, values[i])
+		}
+		if s.peak < s.rms {
+			t.Fatalf("[%02d] peak value less than RMS value: %v < %v", i, s.peak, s.rms)
+		}
+	}
+}
+
+// TestWaveformDrawDualEnvelope verifies that the Waveform.Draw method draws
+// the RMS extent on top of the peak extent, when DualEnvelope is enabled.
+func TestWaveformDrawDualEnvelope(t *testing.T) {
+	w, err := New(nil,
+		BGColorFunction(SolidColor(white)),
+		DualEnvelope(SolidColor(red), SolidColor(blue)),
+		Sharpness(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Peak extent is 76 pixels, RMS extent is 38 pixels, centered at 64
+	w.stats = []sliceStats{{peak: 0.2, rms: 0.1}}
+	img := w.Draw([]float64{0.1})
+
+	var tests = []struct {
+		y     int
+		color color.RGBA
+	}{
+		{10, white},
+		{30, red},
+		{50, blue},
+		{64, blue},
+		{80, blue},
+		{95, red},
+		{120, white},
+	}
+
+	for _, test := range tests {
+		if c := img.At(0, test.y); c != test.color {
+			t.Fatalf("unexpected color at y=%d: %v != %v", test.y, c, test.color)
+		}
+	}
+}
+
+// TestWaveformDrawMinMaxEnvelope verifies that the Waveform.Draw method draws
+// the upper extent to the maximum sample, and the lower extent to the minimum
+// sample, when MinMaxEnvelope is enabled.
+func TestWaveformDrawMinMaxEnvelope(t *testing.T) {
+	w, err := New(nil, MinMaxEnvelope(), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One second of audio with a DC offset, between -0.25 and 0.5
+	samples := make([]float64, 100)
+	for i := range samples {
+		samples[i] = 0.5
+		if i%2 == 1 {
+			samples[i] = -0.25
+		}
+	}
+
+	values, err := w.computeSamples(newSamplesDecoder(samples, 100, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := w.stats[0]; s.min != -0.25 || s.max != 0.5 {
+		t.Fatalf("unexpected minimum and maximum: %v, %v", s.min, s.max)
+	}
+
+	// Upper extent is 32 pixels, lower extent is 16 pixels, from center at 64
+	img := w.Draw(values)
+	for y := 0; y < img.Bounds().Max.Y; y++ {
+		want := white
+		if y >= 32 && y < 80 {
+			want = black
+		}
+
+		if c := img.At(0, y); c != want {
+			t.Fatalf("unexpected color at y=%d: %v != %v", y, c, want)
+		}
+	}
+}
+
+// TestWaveformDrawMinMaxEnvelopeMirrored verifies that the Waveform.Draw
+// method draws both extents to the larger magnitude of the minimum and maximum
+// samples, when MinMaxEnvelope and Mirrored are enabled.
+func TestWaveformDrawMinMaxEnvelopeMirrored(t *testing.T) {
+	w, err := New(nil, MinMaxEnvelope(), Mirrored(), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One second of audio with a DC offset, between -0.25 and 0.5
+	samples := make([]float64, 100)
+	for i := range samples {
+		samples[i] = 0.5
+		if i%2 == 1 {
+			samples[i] = -0.25
+		}
+	}
+
+	values, err := w.computeSamples(newSamplesDecoder(samples, 100, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both extents are 32 pixels, from center at 64
+	img := w.Draw(values)
+	for y := 0; y < img.Bounds().Max.Y; y++ {
+		want := white
+		if y >= 32 && y < 96 {
+			want = black
+		}
+
+		if c := img.At(0, y); c != want {
+			t.Fatalf("unexpected color at y=%d: %v != %v", y, c, want)
+		}
+	}
+}
+
+// TestWaveformDrawMinMaxEnvelopeLine verifies that the Waveform.Draw method
+// draws only the outlines of the extent between the minimum and maximum
+// samples, without filling it, when MinMaxEnvelope and the Line style are
+// enabled.
+func TestWaveformDrawMinMaxEnvelopeLine(t *testing.T) {
+	w, err := New(nil, MinMaxEnvelope(), Style(Line))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One second of audio with a DC offset, between -0.25 and 0.5
+	samples := make([]float64, 100)
+	for i := range samples {
+		samples[i] = 0.5
+		if i%2 == 1 {
+			samples[i] = -0.25
+		}
+	}
+
+	values, err := w.computeSamples(newSamplesDecoder(samples, 100, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Outlines are drawn on the first and last rows of the extent
+	img := w.Draw(values)
+	for y := 0; y < img.Bounds().Max.Y; y++ {
+		want := white
+		if y == 32 || y == 79 {
+			want = black
+		}
+
+		if c := img.At(0, y); c != want {
+			t.Fatalf("unexpected color at y=%d: %v != %v", y, c, want)
+		}
+	}
+}
+
+// TestWaveformDrawNormalize verifies that the Waveform.Draw method scales
+// values so that the peak reaches the full height of the image, when
+// Normalize is enabled, regardless of ScaleClipping, and that the peak is
+// found after the amplitude transform pipeline is applied, in order.
+func TestWaveformDrawNormalize(t *testing.T) {
+	var tests = []struct {
+		options []OptionsFunc
+		values  []float64
+		extents [][2]int
+	}{
+		// The peak fills the image, and half of the peak fills half of the image
+		{
+			options: []OptionsFunc{ScaleClipping()},
+			values:  []float64{0.125, 0.25},
+			extents: [][2]int{{32, 96}, {0, 128}},
+		},
+		// Values of 0.25 and 0.625 above the noise floor are inverted to 0.75
+		// and 0.375, and the larger inverted value fills the image
+		{
+			options: []OptionsFunc{NoiseFloor(0.25), InvertAmplitude()},
+			values:  []float64{0.5, 0.875},
+			extents: [][2]int{{0, 128}, {32, 96}},
+		},
+		// Silence is removed before the noise floor is subtracted, so 0.75 is
+		// kept and inverted to 0.5, while silence is inverted to 1
+		{
+			options: []OptionsFunc{SilenceThreshold(0.7), NoiseFloor(0.25), InvertAmplitude()},
+			values:  []float64{0.5, 0.75},
+			extents: [][2]int{{0, 128}, {32, 96}},
+		},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, append(test.options, Normalize(), Sharpness(0))...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		img := w.Draw(test.values)
+		for x, extent := range test.extents {
+			for y := 0; y < img.Bounds().Max.Y; y++ {
+				want := white
+				if y >= extent[0] && y < extent[1] {
+					want = black
+				}
+
+				if c := img.At(x, y); c != want {
+					t.Fatalf("[%02d] unexpected color at (%d, %d): %v != %v", i, x, y, c, want)
+				}
+			}
+		}
+	}
+}
+
+// TestWaveformDrawNormalizeMinMaxEnvelope verifies that the Waveform.Draw
+// method scales the minimum and maximum samples so that the largest magnitude
+// reaches the edge of the image, when Normalize and MinMaxEnvelope are
+// enabled.
+func TestWaveformDrawNormalizeMinMaxEnvelope(t *testing.T) {
+	w, err := New(nil, Normalize(), MinMaxEnvelope(), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One second of audio between -0.25 and 0.125
+	samples := make([]float64, 100)
+	for i := range samples {
+		samples[i] = 0.125
+		if i%2 == 1 {
+			samples[i] = -0.25
+		}
+	}
+
+	values, err := w.computeSamples(newSamplesDecoder(samples, 100, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Upper extent is 32 pixels, lower extent reaches the bottom of the image
+	img := w.Draw(values)
+	for y := 0; y < img.Bounds().Max.Y; y++ {
+		want := white
+		if y >= 32 {
+			want = black
+		}
+
+		if c := img.At(0, y); c != want {
+			t.Fatalf("unexpected color at y=%d: %v != %v", y, c, want)
+		}
+	}
+}
+
+// TestWaveformDrawClipIndicator verifies that the Waveform.Draw method draws
+// columns whose slice of audio samples clips using the clip indicator color,
+// and all other columns using the foreground color.
+func TestWaveformDrawClipIndicator(t *testing.T) {
+	w, err := New(nil, ClipIndicator(red, 0), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three seconds of a quiet square wave, where only the second second
+	// contains brief peaks at full scale
+	var samples []float64
+	for s := 0; s < 3; s++ {
+		for i := 0; i < 100; i++ {
+			v := 0.1
+			if s == 1 && i < 2 {
+				v = 1.0
+			}
+			if i%2 == 1 {
+				v = -v
+			}
+			samples = append(samples, v)
+		}
+	}
+
+	values, err := w.computeSamples(newSamplesDecoder(samples, 100, 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.Draw(values)
+	y := img.Bounds().Max.Y / 2
+	for x, want := range []color.RGBA{black, red, black} {
+		if c := img.At(x, y); c != want {
+			t.Fatalf("unexpected color at x=%d: %v != %v", x, c, want)
+		}
+	}
+
+	// Background is never drawn using the clip indicator color
+	if c := img.At(1, 0); c != white {
+		t.Fatalf("unexpected background color: %v != %v", c, white)
+	}
+}
+
+// TestWaveformDrawOverlay verifies that the Waveform.Draw method composites
+// a semi-transparent overlay in front of the primary waveform.
+func TestWaveformDrawOverlay(t *testing.T) {
+	// Half-transparent red, with alpha-premultiplied components
+	w, err := New(nil, Overlay([]float64{0.2}, color.RGBA{128, 0, 0, 128}), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Overlay extent is 76 pixels, primary extent is 38 pixels, centered at 64
+	img := w.Draw([]float64{0.1})
+
+	var tests = []struct {
+		y     int
+		color color.RGBA
+	}{
+		{10, white},
+		{30, color.RGBA{255, 127, 127, 255}},
+		{64, color.RGBA{128, 0, 0, 255}},
+		{95, color.RGBA{255, 127, 127, 255}},
+		{120, white},
+	}
+
+	for _, test := range tests {
+		if c := img.At(0, test.y); c != test.color {
+			t.Fatalf("unexpected color at y=%d: %v != %v", test.y, c, test.color)
+		}
+	}
+}
+
+// TestWaveformDrawTransparentBackground verifies that the Waveform.Draw method
+// creates an *image.NRGBA with a transparent background, which preserves the
+// alpha of the foreground color, when TransparentBackground is set.
+func TestWaveformDrawTransparentBackground(t *testing.T) {
+	fg := color.NRGBA{0xff, 0x33, 0x00, 0x80}
+	w, err := New(nil, TransparentBackground(), FGColorFunction(SolidColor(fg)), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, ok := w.Draw([]float64{0.1}).(*image.NRGBA)
+	if !ok {
+		t.Fatal("image is not an *image.NRGBA")
+	}
+
+	if c := img.At(0, 0); c != (color.NRGBA{}) {
+		t.Fatalf("unexpected background color: %v", c)
+	}
+	if c := img.At(0, 64); c != fg {
+		t.Fatalf("unexpected foreground color: %v != %v", c, fg)
+	}
+
+	// Streamed PNG images are also encoded with alpha
+	buf := bytes.NewBuffer(nil)
+	if err := w.DrawTo(buf, FormatPNG, []float64{0.1}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testImagesEqual(t, got, img)
+}
+
+// TestWaveformDrawPadding verifies that the Waveform.Draw method enlarges the
+// image by the padding, drawing the margins using the background color, and
+// the waveform within the inner rectangle.
+func TestWaveformDrawPadding(t *testing.T) {
+	values := []float64{0.1, 0.2, 0.3}
+	checker := CheckerColor(red, blue, 2)
+
+	w, err := New(nil, BGColorFunction(checker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := w.Draw(values)
+
+	if err := w.SetPadding(4, 3, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	img := w.Draw(values)
+
+	if b := img.Bounds(); b.Dx() != 3+3+1 || b.Dy() != imgYDefault+4+2 {
+		t.Fatalf("unexpected image bounds: %v", b)
+	}
+
+	// Corners use the background color of the nearest corner of the waveform
+	max := img.Bounds().Max
+	corners := []struct {
+		x, y   int
+		wx, wy int
+	}{
+		{0, 0, 0, 0},
+		{max.X - 1, 0, 2, 0},
+		{0, max.Y - 1, 0, imgYDefault - 1},
+		{max.X - 1, max.Y - 1, 2, imgYDefault - 1},
+	}
+	for _, c := range corners {
+		if got, want := img.At(c.x, c.y), want.At(c.wx, c.wy); got != want {
+			t.Fatalf("unexpected corner color at (%d,%d): %v != %v", c.x, c.y, got, want)
+		}
+	}
+
+	// Waveform is drawn within the inner rectangle, relative to its origin
+	for y := 0; y < imgYDefault; y++ {
+		for x := 0; x < 3; x++ {
+			if got, want := img.At(x+1, y+4), want.At(x, y); got != want {
+				t.Fatalf("unexpected color at (%d,%d): %v != %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+// TestInterpolateValues verifies that interpolateValues linearly interpolates
+// values to a different length, aligning the first and last values.
+func TestInterpolateValues(t *testing.T) {
+	var tests = []struct {
+		values []float64
+		n      int
+		out    []float64
+	}{
+		{[]float64{0.1, 0.2}, 2, []float64{0.1, 0.2}},
+		{[]float64{0.1, 0.3}, 3, []float64{0.1, 0.2, 0.3}},
+		{[]float64{0.1, 0.2, 0.3, 0.4, 0.5}, 3, []float64{0.1, 0.3, 0.5}},
+		{[]float64{0.4}, 3, []float64{0.4, 0.4, 0.4}},
+		{[]float64{0.1, 0.2}, 1, []float64{0.1}},
+	}
+
+	for i, test := range tests {
+		out := interpolateValues(test.values, test.n)
+		if len(out) != len(test.out) {
+			t.Fatalf("[%02d] unexpected length: %v != %v", i, len(out), len(test.out))
+		}
+
+		for j := range out {
+			if math.Abs(out[j]-test.out[j]) > 1e-9 {
+				t.Fatalf("[%02d] unexpected value at %d: %v != %v", i, j, out[j], test.out[j])
+			}
+		}
+	}
+}
+
+// TestWaveformAmplitudeInvert verifies that the amplitude transform pipeline
+// clamps and inverts values when InvertAmplitude is enabled.
+func TestWaveformAmplitudeInvert(t *testing.T) {
+	var tests = []struct {
+		invert bool
+		in     float64
+		out    float64
+	}{
+		{false, 0.00, 0.00},
+		{false, 0.25, 0.25},
+		{false, 1.50, 1.50},
+		{true, 0.00, 1.00},
+		{true, 0.25, 0.75},
+		{true, 1.00, 0.00},
+		{true, 1.50, 0.00},
+		{true, -0.50, 1.00},
+	}
+
+	for i, test := range tests {
+		w := &Waveform{invertAmplitude: test.invert}
+		if out := w.amplitude(test.in); out != test.out {
+			t.Fatalf("[%02d] unexpected amplitude: %v != %v", i, out, test.out)
+		}
+	}
+}
+
+// TestWaveformAmplitudePipeline verifies that SilenceThreshold, NoiseFloor, and
+// InvertAmplitude are applied in order by the amplitude transform pipeline.
+func TestWaveformAmplitudePipeline(t *testing.T) {
+	var tests = []struct {
+		threshold float64
+		floor     float64
+		invert    bool
+		in        float64
+		out       float64
+	}{
+		// Silence threshold removes values below threshold
+		{0.25, 0, false, 0.125, 0},
+		{0.25, 0, false, 0.5, 0.5},
+		// Noise floor lowers all values, clamped at zero
+		{0, 0.25, false, 0.125, 0},
+		{0, 0.25, false, 0.5, 0.25},
+		{0, 0.25, false, 1.5, 1},
+		// Silence threshold compares the value before the noise floor
+		{0.375, 0.25, false, 0.25, 0},
+		{0.375, 0.25, false, 0.5, 0.25},
+		{0.125, 0.25, false, 0.25, 0},
+		// Inversion is applied last
+		{0.25, 0.125, true, 0.125, 1},
+		{0.25, 0.125, true, 0.5, 0.625},
+	}
+
+	for i, test := range tests {
+		w := &Waveform{
+			silenceThreshold: test.threshold,
+			noiseFloor:       test.floor,
+			invertAmplitude:  test.invert,
+		}
+		if out := w.amplitude(test.in); out != test.out {
+			t.Fatalf("[%02d] unexpected amplitude: %v != %v", i, out, test.out)
+		}
+	}
+}
+
+// TestWaveformDrawInvertAmplitude verifies that the Waveform.Draw method draws
+// silence tall and peaks short when InvertAmplitude is enabled, without
+// modifying the input values.
+func TestWaveformDrawInvertAmplitude(t *testing.T) {
+	w, err := New(nil,
+		BGColorFunction(SolidColor(white)),
+		FGColorFunction(SolidColor(black)),
+		InvertAmplitude(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{0, 1}
+	img := w.Draw(values)
+
+	// Silence should reach the top of the image, full volume should not be drawn
+	if c := img.At(0, 0); c != black {
+		t.Fatalf("unexpected color for silence: %v != %v", c, black)
+	}
+	if c := img.At(1, img.Bounds().Max.Y/2); c != white {
+		t.Fatalf("unexpected color for peak: %v != %v", c, white)
+	}
+
+	if values[0] != 0 || values[1] != 1 {
+		t.Fatalf("input values modified by Draw: %v", values)
+	}
+}
+
+// TestWaveformDrawBars verifies that the Waveform.Draw method draws each computed
+// value as a bar followed by a background gap, with a ColorFunc applied per bar.
+func TestWaveformDrawBars(t *testing.T) {
+	w, err := New(nil,
+		BGColorFunction(SolidColor(white)),
+		FGColorFunction(StripeColor(red, blue)),
+		BarWidth(2),
+		BarGap(1),
+		Scale(2, 1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.Draw([]float64{0.5, 0.5, 0.5})
+	if maxX := img.Bounds().Max.X; maxX != 18 {
+		t.Fatalf("unexpected image width: %v != %v", maxX, 18)
+	}
+
+	// Each bar is 4 pixels wide, followed by a 2 pixel gap
+	y := img.Bounds().Max.Y / 2
+	want := []color.RGBA{
+		red, red, red, red, white, white,
+		blue, blue, blue, blue, white, white,
+		red, red, red, red, white, white,
+	}
+	for x, c := range want {
+		if out := img.At(x, y); out != c {
+			t.Fatalf("unexpected color at x=%d: %v != %v", x, out, c)
+		}
+	}
+}
+
+// TestWaveformDrawBarRadius verifies that the Waveform.Draw method rounds the
+// top and bottom corners of each bar when BarRadius is set, clamping the
+// radius to half the width of a bar.
+func TestWaveformDrawBarRadius(t *testing.T) {
+	var tests = []struct {
+		width  uint
+		radius uint
+		tops   []int
+	}{
+		// Square corners
+		{4, 0, []int{45, 45, 45, 45}},
+		{8, 4, []int{47, 46, 45, 45, 45, 45, 46, 47}},
+		// Radius clamped to 2
+		{4, 10, []int{46, 45, 45, 46}},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, BarWidth(test.width), BarRadius(test.radius), Sharpness(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Extent of 38 pixels, centered at 64
+		img := w.Draw([]float64{0.1})
+
+		tops := testWaveformTops(img)
+		for x := range test.tops {
+			if tops[x] != test.tops[x] {
+				t.Fatalf("[%02d] unexpected waveform tops: %v != %v", i, tops, test.tops)
+			}
+
+			// Bottom corners are rounded symmetrically
+			bottom := 128 - test.tops[x]
+			if r, _, _, _ := img.At(x, bottom-1).RGBA(); r != 0 {
+				t.Fatalf("[%02d] unexpected background at x=%d, y=%d", i, x, bottom-1)
+			}
+			if r, _, _, _ := img.At(x, bottom).RGBA(); r == 0 {
+				t.Fatalf("[%02d] unexpected foreground at x=%d, y=%d", i, x, bottom)
+			}
+		}
+	}
+}
+
+// TestGenerateTruncatedWAV verifies that Generate returns a nil image for a
+// truncated WAV stream by default, and a partial image along with the error
+// when PartialOnError is set.
+func TestGenerateTruncatedWAV(t *testing.T) {
+	// Approximately 2.5 seconds of audio
+	truncated := wavFile[:len(wavFile)/2]
+
+	img, err := Generate(bytes.NewReader(truncated))
+	if err != ErrUnexpectedEOS {
+		t.Fatalf("unexpected Generate error: %v != %v", err, ErrUnexpectedEOS)
+	}
+	if img != nil {
+		t.Fatalf("unexpected non-nil image: %v", img.Bounds())
+	}
+
+	img, err = Generate(bytes.NewReader(truncated), PartialOnError())
+	if err != ErrUnexpectedEOS {
+		t.Fatalf("unexpected Generate error: %v != %v", err, ErrUnexpectedEOS)
+	}
+	if img == nil {
+		t.Fatal("unexpected nil image")
+	}
+
+	// Two complete seconds, and one partial second
+	if maxX := img.Bounds().Max.X; maxX != 3 {
+		t.Fatalf("unexpected image width: %v != %v", maxX, 3)
+	}
+}
+
+// TestWaveformComputeOnProgress verifies that the Waveform.Compute method reports
+// increasing progress for a seekable stream, ending at 1.
+func TestWaveformComputeOnProgress(t *testing.T) {
+	var progress []float64
+	w, err := New(bytes.NewReader(wavFile), OnProgress(func(f float64) {
+		progress = append(progress, f)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One report per value, except the last, followed by a completion report
+	if len(progress) != len(values) {
+		t.Fatalf("unexpected progress reports length: %v != %v", len(progress), len(values))
+	}
+
+	last := 0.0
+	for i, f := range progress {
+		if f < last || f > 1 {
+			t.Fatalf("[%02d] unexpected progress: %v after %v", i, f, last)
+		}
+		last = f
+	}
+	if last != 1 {
+		t.Fatalf("unexpected final progress: %v != %v", last, 1)
+	}
+}
+
+// TestWaveformComputeOnProgressUnknownLength verifies that the Waveform.Compute
+// method reports indeterminate progress for a stream of unknown length.
+func TestWaveformComputeOnProgressUnknownLength(t *testing.T) {
+	var progress []float64
+	r := struct{ io.Reader }{bytes.NewReader(wavFile)}
+	w, err := New(r, OnProgress(func(f float64) {
+		progress = append(progress, f)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(progress) == 0 {
+		t.Fatal("no progress reported")
+	}
+	for i, f := range progress[:len(progress)-1] {
+		if f != -1 {
+			t.Fatalf("[%02d] unexpected progress: %v != %v", i, f, -1)
+		}
+	}
+	if f := progress[len(progress)-1]; f != 1 {
+		t.Fatalf("unexpected final progress: %v != %v", f, 1)
+	}
+}
+
+// TestWaveformComputeProgress verifies that the Waveform.Compute method
+// reports the number of seconds of audio decoded after each value, ending with
+// the total number of seconds.
+func TestWaveformComputeProgress(t *testing.T) {
+	var done, total []float64
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+	w, err := New(bytes.NewReader(wav), Resolution(2), Progress(func(d float64, t float64) {
+		done = append(done, d)
+		total = append(total, t)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Compute(); err != nil {
+		t.Fatal(err)
+	}
+
+	// One report per value, except the last, followed by a completion report
+	if want := []float64{0.5, 1, 1.5, 2}; !reflect.DeepEqual(done, want) {
+		t.Fatalf("unexpected progress:\n- got: %v\n-want: %v", done, want)
+	}
+	for i, tt := range total {
+		if tt <= 0 {
+			t.Fatalf("[%02d] unexpected total: %v", i, tt)
+		}
+	}
+	if tt := total[len(total)-1]; tt != 2 {
+		t.Fatalf("unexpected final total: %v != %v", tt, 2)
+	}
+}
+
+// TestWaveformComputeMaxSamples verifies that the Waveform.Compute method stops
+// decoding promptly once the MaxSamples limit is reached, either returning
+// ErrLimitExceeded, or truncated values.
+func TestWaveformComputeMaxSamples(t *testing.T) {
+	// Test WAV is 16-bit stereo at 44100Hz; limit to 1.5 seconds
+	const limit = 44100 * 2 * 3 / 2
+
+	var tests = []struct {
+		truncate bool
+		values   int
+		err      error
+	}{
+		{false, 0, ErrLimitExceeded},
+		{true, 2, nil},
+	}
+
+	for i, test := range tests {
+		pr := newProgressReader(bytes.NewReader(wavFile))
+		w, err := New(pr, MaxSamples(limit, test.truncate))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := w.Compute()
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected Compute error: %v != %v", i, err, test.err)
+		}
+		if len(values) != test.values {
+			t.Fatalf("[%02d] unexpected Compute values length: %v != %v", i, len(values), test.values)
+		}
+
+		// Only the samples up to the limit, plus buffering, may be read
+		if pr.n >= int64(len(wavFile))/2 {
+			t.Fatalf("[%02d] too many bytes read: %v of %v", i, pr.n, len(wavFile))
+		}
+	}
+}
+
+// TestWaveformDrawHeight verifies that the Waveform.Draw method produces an
+// image of exactly the height set by Height, with values mapped into it.
+func TestWaveformDrawHeight(t *testing.T) {
+	w, err := New(nil, Scale(4, 1), Height(120), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := w.Draw([]float64{0.1, 0.2})
+	if b := img.Bounds(); b.Dx() != 8 || b.Dy() != 120 {
+		t.Fatalf("unexpected image bounds: %v", b)
+	}
+
+	// 0.1 * 120 * 3 = 36 pixels, and 0.2 * 120 * 3 = 72 pixels, centered at 60
+	if tops := testWaveformTops(img); tops[0] != 42 || tops[4] != 24 {
+		t.Fatalf("unexpected waveform tops: %v", tops)
+	}
+}
+
+// TestGenerateFromSamples verifies that GenerateFromSamples computes values
+// from interleaved samples, using the input sample rate to compute one value
+// per second of audio.
+func TestGenerateFromSamples(t *testing.T) {
+	// 3 seconds of a stereo square wave, with amplitudes 0.5, 0.25, and 0.75,
+	// so that each computed RMS value is the amplitude
+	const sampleRate = 100
+	amplitudes := []float64{0.5, 0.25, 0.75}
+
+	var samples []float64
+	for _, a := range amplitudes {
+		for i := 0; i < sampleRate; i++ {
+			v := a
+			if i%2 == 1 {
+				v = -a
+			}
+			samples = append(samples, v, v)
+		}
+	}
+
+	img, err := GenerateFromSamples(samples, sampleRate, 2, Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(nil, Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testImagesEqual(t, img, w.Draw(amplitudes))
+}
+
+// TestGenerateFromSamplesErrors verifies that GenerateFromSamples returns
+// appropriate errors for invalid input.
+func TestGenerateFromSamplesErrors(t *testing.T) {
+	var tests = []struct {
+		samples    []float64
+		sampleRate int
+		channels   int
+		err        error
+	}{
+		{nil, 44100, 2, ErrNoSamples},
+		{[]float64{0}, 0, 2, ErrInvalidData},
+		{[]float64{0}, 44100, 0, ErrInvalidData},
+	}
+
+	for i, test := range tests {
+		img, err := GenerateFromSamples(test.samples, test.sampleRate, test.channels)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected GenerateFromSamples error: %v != %v", i, err, test.err)
+		}
+		if img != nil {
+			t.Fatalf("[%02d] unexpected non-nil image: %v", i, img.Bounds())
+		}
+	}
+}
+
+// TestWaveformDrawDimensions verifies that the Waveform.Draw method creates an
+// image of exactly the dimensions set by Dimensions, for any number of values,
+// and that peaks are preserved when values are reduced.
+func TestWaveformDrawDimensions(t *testing.T) {
+	var tests = []struct {
+		values []float64
+		width  uint
+	}{
+		{values: []float64{0.1, 0.2, 0.3}, width: 10},
+		{values: make([]float64, 1000), width: 10},
+		{values: []float64{0.1, 0.2, 0.3}, width: 9},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, Dimensions(test.width, 16), BarWidth(2), BarGap(1))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if b := w.Draw(test.values).Bounds(); b.Dx() != int(test.width) || b.Dy() != 16 {
+			t.Fatalf("[%02d] unexpected image bounds: %v", i, b)
+		}
+	}
+
+	// Four bars cover 250 values each, and the single peak is drawn in the
+	// third bar
+	values := make([]float64, 1000)
+	values[600] = 0.5
+
+	got := resampleValues(values, 4)
+	if want := []float64{0, 0, 0.5, 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected resampled values: %v != %v", got, want)
+	}
+}
+
+// TestWaveformDrawMaxWidth verifies that the Waveform.Draw method creates an
+// image no wider than the width set by MaxWidth, including any padding, by
+// merging values into fewer bars, and leaves narrower images unchanged.
+func TestWaveformDrawMaxWidth(t *testing.T) {
+	var tests = []struct {
+		options []OptionsFunc
+		values  int
+		width   int
+	}{
+		{options: []OptionsFunc{MaxWidth(10)}, values: 1000, width: 10},
+		{options: []OptionsFunc{MaxWidth(10)}, values: 5, width: 5},
+		{options: []OptionsFunc{MaxWidth(10), BarWidth(2), BarGap(1)}, values: 1000, width: 9},
+		{options: []OptionsFunc{MaxWidth(10), Padding(0, 2, 0, 2)}, values: 1000, width: 10},
+		{options: []OptionsFunc{MaxWidth(10), AntiAlias(2)}, values: 1000, width: 10},
+		{options: []OptionsFunc{MaxWidth(1), BarWidth(2)}, values: 1000, width: 1},
+		{options: []OptionsFunc{MaxWidth(3), BarWidth(2), Padding(0, 1, 0, 1)}, values: 1000, width: 3},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, test.options...)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if b := w.Draw(make([]float64, test.values)).Bounds(); b.Dx() != test.width {
+			t.Fatalf("[%02d] unexpected image width: %d != %d", i, b.Dx(), test.width)
+		}
+	}
+
+	// Merged values are drawn in the same way as values reduced by Dimensions
+	values := make([]float64, 1000)
+	values[600] = 0.5
+
+	w, err := New(nil, MaxWidth(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := w.Draw(values), w.Draw(resampleValues(values, 4)); !reflect.DeepEqual(got, want) {
+		t.Fatal("merged image differs from image of resampled values")
+	}
+}
+
+// TestWaveformDrawSharpnessZero verifies that Sharpness(0) disables curvature,
+// drawing each scaled bar as a flat rectangle.
+func TestWaveformDrawSharpnessZero(t *testing.T) {
+	w, err := New(nil, Scale(10, 10), Sharpness(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tops := testWaveformTops(w.Draw([]float64{0.1, 0.2, 0.15}))
+	for x := range tops {
+		if first := tops[x-x%10]; tops[x] != first {
+			t.Fatalf("unexpected curvature at x=%d: %v != %v", x, tops[x], first)
+		}
+	}
+}
+
+// TestWaveformDrawSharpnessBlockiness verifies that higher Sharpness values
+// reduce the number of hard horizontal edges along the top of a scaled waveform.
+func TestWaveformDrawSharpnessBlockiness(t *testing.T) {
+	values := []float64{0.1, 0.2, 0.15, 0.2, 0.1}
+
+	last := -1
+	for _, sharpness := range []uint{0, 1, 2, 4} {
+		w, err := New(nil, Scale(10, 10), Sharpness(sharpness))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Count adjacent pixel columns with the same top, forming a flat edge
+		tops := testWaveformTops(w.Draw(values))
+		var edges int
+		for x := 1; x < len(tops); x++ {
+			if tops[x] == tops[x-1] {
+				edges++
+			}
+		}
+
+		if last != -1 && edges >= last && edges != 0 {
+			t.Fatalf("sharpness %d did not reduce blockiness: %d >= %d", sharpness, edges, last)
+		}
+		last = edges
+	}
+
+	if last != 0 {
+		t.Fatalf("unexpected hard edges at highest sharpness: %d", last)
+	}
+}
+
+// testWaveformTops is a test helper which returns the Y coordinate of the first
+// foreground pixel in each column of a waveform image, drawn using the default
+// black foreground.
+func testWaveformTops(img image.Image) []int {
+	b := img.Bounds()
+	tops := make([]int, b.Max.X)
+	for x := range tops {
+		tops[x] = b.Max.Y
+		for y := 0; y < b.Max.Y; y++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r == 0 {
+				tops[x] = y
+				break
+			}
+		}
+	}
+
+	return tops
 }
 
 // TestWaveformComputeSampleFuncFunctionNil verifies that the Waveform.Compute method returns an error
@@ -120,6 +1339,123 @@ func TestWaveformComputeSampleFuncFunctionNil(t *testing.T) {
 	}
 }
 
+// TestWaveformComputeSliceSize verifies that the Waveform.Compute method
+// computes each value from the number of samples set by a fractional
+// resolution, or by SliceSamples, and from at least one sample.
+func TestWaveformComputeSliceSize(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+
+	var tests = []struct {
+		option OptionsFunc
+		slice  int64
+		values int
+	}{
+		{option: Resolution(0.5), slice: 8, values: 1},
+		{option: Resolution(1.5), slice: 2, values: 4},
+		{option: Resolution(8), slice: 1, values: 8},
+		{option: Resolution(math.MaxFloat64), slice: 1, values: 8},
+		{option: Resolution(resolutionMin), slice: 400, values: 1},
+		{option: SliceSamples(3), slice: 3, values: 3},
+	}
+
+	for i, test := range tests {
+		w, err := New(bytes.NewReader(wav), test.option)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		values, err := w.Compute()
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if len(values) != test.values {
+			t.Fatalf("[%02d] unexpected number of values: %d != %d", i, len(values), test.values)
+		}
+		if m := w.Metadata(); m.SliceSamples != test.slice {
+			t.Fatalf("[%02d] unexpected samples per slice: %d != %d", i, m.SliceSamples, test.slice)
+		}
+	}
+}
+
+// TestWaveformComputeImplausibleConfig verifies that the Waveform.Compute
+// method returns ErrInvalidData for a stream whose header declares an
+// implausible sample rate or number of channels, rather than allocating a
+// slice of samples of the declared size, even when MaxSamples is set.
+func TestWaveformComputeImplausibleConfig(t *testing.T) {
+	var tests = []struct {
+		channels   uint16
+		sampleRate uint32
+	}{
+		{channels: 3, sampleRate: 200000000},
+		{channels: 64, sampleRate: 1 << 31},
+		{channels: channelsMax + 1, sampleRate: 44100},
+	}
+
+	for i, test := range tests {
+		wav := testWAV(3, test.channels, test.sampleRate, 32, make([]byte, int(test.channels)*4*8))
+
+		w, err := New(bytes.NewReader(wav), MaxSamples(1000, true))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+		if _, err := w.Compute(); err != ErrInvalidData {
+			t.Fatalf("[%02d] unexpected Compute error: %v != %v", i, err, ErrInvalidData)
+		}
+	}
+}
+
+// TestWaveformComputeSliceSizeMaxSamples verifies that the Waveform.Compute
+// method computes a slice of no more than the samples permitted by MaxSamples,
+// so that a high sample rate and low resolution cannot request a slice much
+// larger than the samples which are used.
+func TestWaveformComputeSliceSizeMaxSamples(t *testing.T) {
+	w, err := New(nil, Resolution(resolutionMin), MaxSamples(1001, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := w.computeSamples(newSamplesDecoder(make([]float64, 2000), sampleRateMax, 2), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 1 {
+		t.Fatalf("unexpected number of values: %d != %d", len(values), 1)
+	}
+	if m := w.Metadata(); m.SliceSamples != 501 {
+		t.Fatalf("unexpected samples per slice: %d != %d", m.SliceSamples, 501)
+	}
+}
+
+// TestWaveformSliceFramesMax verifies that the Waveform.sliceFrames method
+// never returns a slice of more than sliceSamplesMax samples across all
+// channels, whether the slice is set by the resolution or by SliceSamples.
+func TestWaveformSliceFramesMax(t *testing.T) {
+	var tests = []struct {
+		option OptionsFunc
+		config audio.Config
+		frames int64
+	}{
+		{option: Resolution(1), config: audio.Config{SampleRate: 44100, Channels: 2}, frames: 44100},
+		{option: Resolution(1), config: audio.Config{SampleRate: sampleRateMax, Channels: channelsMax}, frames: sliceSamplesMax / channelsMax},
+		{option: Resolution(resolutionMin), config: audio.Config{SampleRate: sampleRateMax, Channels: 1}, frames: sliceSamplesMax},
+		{option: Resolution(resolutionMin), config: audio.Config{SampleRate: 48000, Channels: 8}, frames: sliceSamplesMax / 8},
+		{option: SliceSamples(sliceSamplesMax), config: audio.Config{SampleRate: 44100, Channels: 2}, frames: sliceSamplesMax / 2},
+	}
+
+	for i, test := range tests {
+		w, err := New(nil, test.option)
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if frames := w.sliceFrames(test.config); frames != test.frames {
+			t.Fatalf("[%02d] unexpected frames per slice: %d != %d", i, frames, test.frames)
+		}
+	}
+}
+
 // TestWaveformComputeResolutionZero verifies that the Waveform.Compute method returns an error
 // if the resolution member is 0.
 func TestWaveformComputeResolutionZero(t *testing.T) {
@@ -131,6 +1467,75 @@ func TestWaveformComputeResolutionZero(t *testing.T) {
 	}
 }
 
+// testWaveformComputeChunked is a test helper which verifies that computing
+// values from an input audio stream produces identical values when the stream
+// is read through readers which chunk its data in various ways.
+func testWaveformComputeChunked(t *testing.T, file []byte) {
+	want, err := testComputeValues(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name string
+		r    io.Reader
+	}{
+		{"one byte", iotest.OneByteReader(bytes.NewReader(file))},
+		{"half", iotest.HalfReader(bytes.NewReader(file))},
+		{"data and error", iotest.DataErrReader(bytes.NewReader(file))},
+		{"7 bytes", &chunkReader{r: bytes.NewReader(file), size: 7}},
+		{"4099 bytes", &chunkReader{r: bytes.NewReader(file), size: 4099}},
+	}
+
+	for _, test := range tests {
+		got, err := testComputeValues(test.r)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("%s: unexpected Compute values length: %v != %v", test.name, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%s: unexpected Compute value at index %d: %v != %v", test.name, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+// chunkReader is an io.Reader which reads at most size bytes at a time from
+// another reader, as a network socket may.
+type chunkReader struct {
+	r    io.Reader
+	size int
+}
+
+// Read reads at most size bytes into p.
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(p) > r.size {
+		p = p[:r.size]
+	}
+
+	return r.r.Read(p)
+}
+
+// shortDecoder is an audio.Decoder which decodes at most size samples at a
+// time from a samplesDecoder.
+type shortDecoder struct {
+	*samplesDecoder
+	size int
+}
+
+// Read decodes at most size samples into b.
+func (d *shortDecoder) Read(b audio.Slice) (int, error) {
+	if b.Len() > d.size {
+		b = b.Slice(0, d.size)
+	}
+
+	return d.samplesDecoder.Read(b)
+}
+
 // testWaveformCompute is a test helper which verifies that generating a Waveform
 // from an input io.Reader, applying the appropriate OptionsFunc, and calling its
 // Compute method, will produce the appropriate computed values and error.
@@ -159,3 +1564,29 @@ func testWaveformCompute(t *testing.T, r io.Reader, err error, values []float64,
 		}
 	}
 }
+
+// testWAV is a test helper which generates a WAV stream with the input
+// format tag, channel count, sample rate, bits per sample, and raw sample data.
+func testWAV(format uint16, channels uint16, sampleRate uint32, bits uint16, data []byte) []byte {
+	blockAlign := channels * bits / 8
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(36+len(data)))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))
+	binary.Write(buf, binary.LittleEndian, format)
+	binary.Write(buf, binary.LittleEndian, channels)
+	binary.Write(buf, binary.LittleEndian, sampleRate)
+	binary.Write(buf, binary.LittleEndian, sampleRate*uint32(blockAlign))
+	binary.Write(buf, binary.LittleEndian, blockAlign)
+	binary.Write(buf, binary.LittleEndian, bits)
+
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+
+	return buf.Bytes()
+}
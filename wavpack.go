@@ -0,0 +1,281 @@
+//go:build wavpack
+// +build wavpack
+
+package waveform
+
+/*
+#cgo pkg-config: wavpack
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <wavpack/wavpack.h>
+
+// wv_buffer is an in-memory WavPack stream, read by wv_reader.
+typedef struct {
+	const unsigned char *data;
+	uint32_t len;
+	uint32_t pos;
+} wv_buffer;
+
+static int32_t wv_read_bytes(void *id, void *data, int32_t bcount) {
+	wv_buffer *b = id;
+	uint32_t n = b->len - b->pos;
+	if (bcount < 0) {
+		return 0;
+	}
+	if ((uint32_t)bcount < n) {
+		n = bcount;
+	}
+
+	memcpy(data, b->data + b->pos, n);
+	b->pos += n;
+	return n;
+}
+
+static uint32_t wv_get_pos(void *id) {
+	return ((wv_buffer *)id)->pos;
+}
+
+static int wv_set_pos_abs(void *id, uint32_t pos) {
+	wv_buffer *b = id;
+	if (pos > b->len) {
+		return -1;
+	}
+
+	b->pos = pos;
+	return 0;
+}
+
+static int wv_set_pos_rel(void *id, int32_t delta, int mode) {
+	wv_buffer *b = id;
+	int64_t pos = delta;
+	switch (mode) {
+	case SEEK_CUR:
+		pos += b->pos;
+		break;
+	case SEEK_END:
+		pos += b->len;
+		break;
+	}
+	if (pos < 0 || pos > b->len) {
+		return -1;
+	}
+
+	b->pos = pos;
+	return 0;
+}
+
+static int wv_push_back_byte(void *id, int c) {
+	wv_buffer *b = id;
+	if (b->pos == 0) {
+		return EOF;
+	}
+
+	b->pos--;
+	return c;
+}
+
+static uint32_t wv_get_length(void *id) {
+	return ((wv_buffer *)id)->len;
+}
+
+static int wv_can_seek(void *id) {
+	return 1;
+}
+
+static int32_t wv_write_bytes(void *id, void *data, int32_t bcount) {
+	return 0;
+}
+
+static WavpackStreamReader wv_reader = {
+	wv_read_bytes, wv_get_pos, wv_set_pos_abs, wv_set_pos_rel,
+	wv_push_back_byte, wv_get_length, wv_can_seek, wv_write_bytes,
+};
+
+// wv_open opens a WavPack stream from an in-memory buffer, with floating
+// point samples normalized to the range [-1.0, 1.0].
+static WavpackContext *wv_open(wv_buffer *b) {
+	char error[80];
+	return WavpackOpenFileInputEx(&wv_reader, b, NULL, error, OPEN_NORMALIZE, 0);
+}
+*/
+import "C"
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"runtime"
+	"unsafe"
+
+	"azul3d.org/engine/audio"
+)
+
+// wavpackBlockSamples is the maximum number of samples per channel which are
+// unpacked by a single call to libwavpack.
+const wavpackBlockSamples = 4096
+
+// WavPack decoding uses libwavpack via cgo, so it is only built when the
+// wavpack build tag is set.  The default build remains free of cgo.
+func init() {
+	wavpackDecodable = true
+	audio.RegisterFormat(FormatWavPack, wavpackMagic, newWavPackDecoder)
+}
+
+// wavpackDecoder is an audio.Decoder which decodes a WavPack stream to float64
+// PCM samples.
+type wavpackDecoder struct {
+	wpc    *C.WavpackContext
+	data   *C.wv_buffer
+	config audio.Config
+
+	// Floating point samples are returned by libwavpack as the bits of
+	// 32-bit floats, and integer samples are scaled by their bit depth
+	float bool
+	scale float64
+	bits  int
+
+	// Total samples per channel, or math.MaxUint32 if unknown, and samples
+	// per channel unpacked so far
+	total uint32
+	read  uint32
+
+	// Unpacked samples not yet read
+	buf     []C.int32_t
+	pending []C.int32_t
+}
+
+// newWavPackDecoder opens a decoder on an input WavPack stream.
+//
+// The stream is read into memory before decoding begins, so that libwavpack
+// can seek to the end of the stream to find its length.  Hybrid lossy streams
+// are decoded without their correction files.
+func newWavPackDecoder(r interface{}) (audio.Decoder, error) {
+	rr, ok := r.(io.Reader)
+	if !ok {
+		return nil, audio.ErrInvalidData
+	}
+
+	b, err := ioutil.ReadAll(rr)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 || int64(len(b)) > math.MaxUint32 {
+		return nil, audio.ErrInvalidData
+	}
+
+	// Memory must remain valid until the stream is closed
+	buf := (*C.wv_buffer)(C.calloc(1, C.sizeof_wv_buffer))
+	buf.data = (*C.uchar)(C.CBytes(b))
+	buf.len = C.uint32_t(len(b))
+
+	wpc := C.wv_open(buf)
+	if wpc == nil {
+		C.free(unsafe.Pointer(buf.data))
+		C.free(unsafe.Pointer(buf))
+		return nil, audio.ErrInvalidData
+	}
+
+	channels := int(C.WavpackGetNumChannels(wpc))
+	bytesPerSample := int(C.WavpackGetBytesPerSample(wpc))
+	if channels <= 0 || bytesPerSample <= 0 || bytesPerSample > 4 {
+		C.WavpackCloseFile(wpc)
+		C.free(unsafe.Pointer(buf.data))
+		C.free(unsafe.Pointer(buf))
+		return nil, audio.ErrInvalidData
+	}
+
+	d := &wavpackDecoder{
+		wpc:  wpc,
+		data: buf,
+		config: audio.Config{
+			SampleRate: int(C.WavpackGetSampleRate(wpc)),
+			Channels:   channels,
+		},
+		float: C.WavpackGetMode(wpc)&C.MODE_FLOAT != 0,
+		scale: float64(int64(1) << uint(8*bytesPerSample-1)),
+		bits:  int(C.WavpackGetBitsPerSample(wpc)),
+		total: uint32(C.WavpackGetNumSamples(wpc)),
+		buf:   make([]C.int32_t, wavpackBlockSamples*channels),
+	}
+	runtime.SetFinalizer(d, (*wavpackDecoder).close)
+
+	return d, nil
+}
+
+// Config returns the audio configuration of the decoded stream.
+func (d *wavpackDecoder) Config() audio.Config {
+	return d.config
+}
+
+// bitDepth returns the number of bits per sample of the stream.
+func (d *wavpackDecoder) bitDepth() int {
+	return d.bits
+}
+
+// Read decodes samples into b, returning the number of samples read.  On the
+// final read, audio.EOS is returned along with any remaining samples.
+func (d *wavpackDecoder) Read(b audio.Slice) (int, error) {
+	var n int
+	for n < b.Len() {
+		// Unpack the next samples when all pending samples are read
+		if len(d.pending) == 0 {
+			if d.wpc == nil {
+				return n, audio.EOS
+			}
+
+			if err := d.unpack(); err != nil {
+				d.close()
+				return n, err
+			}
+			continue
+		}
+
+		v := d.pending[0]
+		if d.float {
+			b.Set(n, float64(math.Float32frombits(uint32(v))))
+		} else {
+			b.Set(n, float64(v)/d.scale)
+		}
+		d.pending = d.pending[1:]
+		n++
+	}
+
+	return n, nil
+}
+
+// unpack unpacks the next samples into the pending samples.  At the end of the
+// stream, the underlying decoder is released.
+func (d *wavpackDecoder) unpack() error {
+	read := uint32(C.WavpackUnpackSamples(d.wpc, &d.buf[0], wavpackBlockSamples))
+	d.read += read
+	if read > 0 {
+		d.pending = d.buf[:int(read)*d.config.Channels]
+		return nil
+	}
+
+	// Blocks which failed their checksum are decoded as silence by
+	// libwavpack, and a stream which ends early is truncated
+	if C.WavpackGetNumErrors(d.wpc) > 0 {
+		return audio.ErrInvalidData
+	}
+	if d.total != math.MaxUint32 && d.read < d.total {
+		return audio.ErrUnexpectedEOS
+	}
+
+	d.close()
+	return nil
+}
+
+// close releases the underlying decoder.  It is safe to call more than once.
+func (d *wavpackDecoder) close() {
+	if d.wpc != nil {
+		C.WavpackCloseFile(d.wpc)
+		d.wpc = nil
+	}
+	if d.data != nil {
+		C.free(unsafe.Pointer(d.data.data))
+		C.free(unsafe.Pointer(d.data))
+		d.data = nil
+	}
+}
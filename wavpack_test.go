@@ -0,0 +1,24 @@
+//go:build wavpack
+// +build wavpack
+
+package waveform
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestWaveformComputeWavPackErrInvalidData verifies that the Waveform.Compute
+// method returns ErrInvalidData for WavPack streams which cannot be opened.
+func TestWaveformComputeWavPackErrInvalidData(t *testing.T) {
+	for i, data := range [][]byte{
+		// Unsupported stream version
+		testWavPackBlock(0x300, 100),
+		// Truncated block header
+		testWavPackBlock(0x410, 100)[:16],
+	} {
+		if _, err := testComputeValues(bytes.NewReader(data)); err != ErrInvalidData {
+			t.Fatalf("[%02d] unexpected Compute error: %v != %v", i, err, ErrInvalidData)
+		}
+	}
+}
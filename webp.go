@@ -0,0 +1,71 @@
+//go:build webp
+// +build webp
+
+package waveform
+
+/*
+#cgo pkg-config: libwebp
+#include <stdlib.h>
+#include <webp/encode.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"unsafe"
+)
+
+// webpMaxDimension is the maximum width or height of a WebP image.
+const webpMaxDimension = 16383
+
+// errWebPEncode is returned when libwebp cannot encode an image.
+var errWebPEncode = errors.New("waveform: WebP encoding failed")
+
+// WebP encoding uses libwebp via cgo, so it is only built when the webp build
+// tag is set.  The default build remains free of cgo.
+func init() {
+	imageEncoders[FormatWebP] = func(out io.Writer, img image.Image, _ int) error {
+		return encodeWebP(out, img, true, 0)
+	}
+	imageEncoders[FormatWebPLossy] = func(out io.Writer, img image.Image, quality int) error {
+		return encodeWebP(out, img, false, quality)
+	}
+}
+
+// encodeWebP encodes img as a WebP image to an output stream, using lossless
+// compression if lossless is true, and lossy compression with the input
+// quality otherwise.
+func encodeWebP(out io.Writer, img image.Image, lossless bool, quality int) error {
+	b := img.Bounds()
+	if b.Empty() || b.Dx() > webpMaxDimension || b.Dy() > webpMaxDimension {
+		return errWebPEncode
+	}
+
+	// libwebp encodes non-premultiplied RGBA pixels
+	m, ok := img.(*image.NRGBA)
+	if !ok {
+		m = image.NewNRGBA(b)
+		draw.Draw(m, b, img, b.Min, draw.Src)
+	}
+
+	pix := (*C.uint8_t)(unsafe.Pointer(&m.Pix[m.PixOffset(b.Min.X, b.Min.Y)]))
+	width, height, stride := C.int(b.Dx()), C.int(b.Dy()), C.int(m.Stride)
+
+	var output *C.uint8_t
+	var size C.size_t
+	if lossless {
+		size = C.WebPEncodeLosslessRGBA(pix, width, height, stride, &output)
+	} else {
+		size = C.WebPEncodeRGBA(pix, width, height, stride, C.float(quality), &output)
+	}
+	if size == 0 {
+		return errWebPEncode
+	}
+	defer C.WebPFree(unsafe.Pointer(output))
+
+	_, err := out.Write(C.GoBytes(unsafe.Pointer(output), C.int(size)))
+	return err
+}
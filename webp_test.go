@@ -0,0 +1,51 @@
+//go:build webp
+// +build webp
+
+package waveform
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// TestEncodeToWebP verifies that EncodeTo encodes lossless WebP images which
+// decode to the same pixels as the input image, and lossy WebP images with the
+// same dimensions.
+func TestEncodeToWebP(t *testing.T) {
+	w, err := New(nil, FGColorFunction(StripeColor(red, green, blue)), Scale(5, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := w.Draw([]float64{0.25, 1.0, 0.5})
+
+	for _, format := range []string{FormatWebP, FormatWebPLossy} {
+		buf := bytes.NewBuffer(nil)
+		if err := EncodeTo(buf, format, want); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		got, err := webp.Decode(buf)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		if format == FormatWebP {
+			testImagesEqual(t, got, want)
+			continue
+		}
+		if got.Bounds() != want.Bounds() {
+			t.Fatalf("%s: unexpected image bounds: %v != %v", format, got.Bounds(), want.Bounds())
+		}
+	}
+}
+
+// TestEncodeToWebPEmpty verifies that EncodeTo returns an error for an empty
+// image, which cannot be encoded as WebP.
+func TestEncodeToWebPEmpty(t *testing.T) {
+	if err := EncodeTo(bytes.NewBuffer(nil), FormatWebP, image.NewRGBA(image.Rect(0, 0, 0, 0))); err != errWebPEncode {
+		t.Fatalf("unexpected EncodeTo error: %v != %v", err, errWebPEncode)
+	}
+}
@@ -0,0 +1,150 @@
+package waveform
+
+import (
+	"io"
+	"time"
+
+	"azul3d.org/engine/audio"
+)
+
+// windowDecoder is an audio.Decoder which decodes only the samples of another
+// decoder within the Window option, discarding all samples before the start
+// of the window, and ending the stream at the end of the window.
+type windowDecoder struct {
+	audio.Decoder
+	channels int
+
+	// Number of frames preceding the window, which have been seeked past or
+	// are yet to be discarded
+	start int64
+	skip  int64
+
+	// Number of frames in the window, or -1 if it continues to the end of the
+	// stream, and number of frames read so far
+	length int64
+	read   int64
+}
+
+// newWindowDecoder wraps an audio decoder in a windowDecoder, for the window
+// set by the Window option.  The input number of frames have already been
+// seeked past, and are not discarded again.
+func (w *Waveform) newWindowDecoder(decoder audio.Decoder, seeked int64) *windowDecoder {
+	config := decoder.Config()
+	start := durationFrames(w.windowStart, config.SampleRate)
+
+	length := int64(-1)
+	if w.windowDuration > 0 {
+		length = durationFrames(w.windowDuration, config.SampleRate)
+	}
+
+	skip := start - seeked
+	if skip < 0 {
+		skip = 0
+	}
+
+	return &windowDecoder{
+		Decoder:  decoder,
+		channels: config.Channels,
+		start:    start,
+		skip:     skip,
+		length:   length,
+	}
+}
+
+// Read stores the samples within the window in b, returning the number of
+// samples read.  Once the end of the window is reached, audio.EOS is returned
+// along with the final samples.
+func (d *windowDecoder) Read(b audio.Slice) (int, error) {
+	// Discard all samples before the start of the window
+	for d.skip > 0 {
+		want := minInt64(d.skip*int64(d.channels), int64(b.Len()))
+		want -= want % int64(d.channels)
+		if want == 0 {
+			want = int64(d.channels)
+		}
+
+		n, err := d.Decoder.Read(b.Slice(0, int(want)))
+		d.skip -= int64(n / d.channels)
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, audio.EOS
+		}
+	}
+
+	// Stop at the end of the window
+	if d.length >= 0 {
+		remaining := (d.length - d.read) * int64(d.channels)
+		if remaining <= 0 {
+			return 0, audio.EOS
+		}
+		if int64(b.Len()) > remaining {
+			b = b.Slice(0, int(remaining))
+		}
+	}
+
+	n, err := d.Decoder.Read(b)
+	d.read += int64(n / d.channels)
+	if err == nil && d.length >= 0 && d.read >= d.length {
+		err = audio.EOS
+	}
+
+	return n, err
+}
+
+// fraction returns the fraction of the window read so far, or -1 if the
+// window continues to the end of the stream, whose length is unknown.
+func (d *windowDecoder) fraction() float64 {
+	if d.length <= 0 {
+		return -1
+	}
+
+	return float64(d.read) / float64(d.length)
+}
+
+// windowed reports whether the Window option is set.
+func (w *Waveform) windowed() bool {
+	return w.windowStart > 0 || w.windowDuration > 0
+}
+
+// seekWindow seeks a WAV or raw PCM stream directly to the start of the
+// window set by the Window option, and returns a windowDecoder for the
+// remainder of the window.  If the stream cannot be seeked directly, it is
+// returned to its original position, and a nil decoder is returned.
+func (w *Waveform) seekWindow(rs io.ReadSeeker) (*windowDecoder, error) {
+	// Determine the sample rate of the stream, to find the position of the
+	// start of the window
+	var rate int
+	if w.rawPCM != nil {
+		rate = w.rawPCM.sampleRate
+	} else {
+		start, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+
+		f, _, err := readWAVHeader(rs)
+		if _, serr := rs.Seek(start, io.SeekStart); serr != nil {
+			return nil, serr
+		}
+		if err != nil {
+			return nil, nil
+		}
+		rate = int(f.sampleRate)
+	}
+
+	decoder, seeked, err := w.seekDecoder(rs, durationFrames(w.windowStart, rate))
+	if err != nil || decoder == nil {
+		return nil, err
+	}
+
+	return w.newWindowDecoder(decoder, seeked), nil
+}
+
+// durationFrames returns the number of frames in the input duration of audio,
+// at the input sample rate, without overflowing for long durations.
+func durationFrames(d time.Duration, sampleRate int) int64 {
+	rate := int64(sampleRate)
+	return int64(d/time.Second)*rate + int64(d%time.Second)*rate/int64(time.Second)
+}
@@ -0,0 +1,79 @@
+package waveform
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestWaveformComputeWindow verifies that the Waveform.Compute method only
+// computes values from the audio within the Window option, whether the stream
+// is seeked to the start of the window, or the samples before it are decoded
+// and discarded, and that the metadata records the window's offsets.
+func TestWaveformComputeWindow(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+
+	var tests = []struct {
+		start    time.Duration
+		duration time.Duration
+		values   []float64
+		first    int64
+		last     int64
+	}{
+		{start: time.Second, duration: time.Second, values: []float64{0, 127.0 / 128, 1, 0}, first: 4, last: 8},
+		{start: 1500 * time.Millisecond, values: []float64{1, 0}, first: 6, last: 8},
+		{duration: 750 * time.Millisecond, values: []float64{0, 0.5, 0}, first: 0, last: 3},
+	}
+
+	for i, test := range tests {
+		readers := map[string]io.Reader{
+			"seeker":     bytes.NewReader(wav),
+			"non-seeker": struct{ io.Reader }{bytes.NewReader(wav)},
+		}
+
+		for name, r := range readers {
+			w, err := New(r, Resolution(4), Window(test.start, test.duration))
+			if err != nil {
+				t.Fatalf("[%02d] %s: %v", i, name, err)
+			}
+
+			values, err := w.Compute()
+			if err != nil {
+				t.Fatalf("[%02d] %s: %v", i, name, err)
+			}
+			if !reflect.DeepEqual(values, test.values) {
+				t.Fatalf("[%02d] %s: unexpected values: %v != %v", i, name, values, test.values)
+			}
+
+			m := w.Metadata()
+			if m.StartSample != test.first || m.EndSample != test.last || m.TotalSamples != test.last {
+				t.Fatalf("[%02d] %s: unexpected metadata: %+v", i, name, m)
+			}
+		}
+	}
+}
+
+// TestWaveformComputeWindowPastEnd verifies that the Waveform.Compute method
+// returns ErrNoSamples when the Window option starts after the end of the
+// stream.
+func TestWaveformComputeWindowPastEnd(t *testing.T) {
+	wav := testWAV(1, 1, 4, 8, []byte{128, 192, 128, 64, 128, 255, 0, 128})
+
+	readers := []io.Reader{
+		bytes.NewReader(wav),
+		struct{ io.Reader }{bytes.NewReader(wav)},
+	}
+
+	for i, r := range readers {
+		w, err := New(r, Window(time.Minute, 0))
+		if err != nil {
+			t.Fatalf("[%02d] %v", i, err)
+		}
+
+		if _, err := w.Compute(); err != ErrNoSamples {
+			t.Fatalf("[%02d] unexpected error: %v != %v", i, err, ErrNoSamples)
+		}
+	}
+}